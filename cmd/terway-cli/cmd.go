@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"io"
+	"os"
 	"sort"
 	"strings"
 
@@ -28,6 +29,9 @@ func runList(cmd *cobra.Command, args []string) error {
 		}
 
 		result = types.TypeNames
+		if structuredOutput() {
+			return writeDocument(cmd.OutOrStdout(), outputFormat, kindTypeList, typeListOutput{Types: nonNil(result)})
+		}
 	} else {
 		// list resources
 		resource := args[0]
@@ -38,6 +42,9 @@ func runList(cmd *cobra.Command, args []string) error {
 		}
 
 		result = resources.ResourceNames
+		if structuredOutput() {
+			return writeDocument(cmd.OutOrStdout(), outputFormat, kindResourceList, resourceListOutput{Type: resource, Resources: nonNil(result)})
+		}
 	}
 
 	var items []pterm.BulletListItem
@@ -93,6 +100,15 @@ func runShow(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if structuredOutput() {
+		return writeDocument(cmd.OutOrStdout(), outputFormat, kindResource, resourceOutput{
+			Type:   typ,
+			Name:   name,
+			Config: toKeyValueOutput(cfg.Config),
+			Trace:  toKeyValueOutput(trace.Trace),
+		})
+	}
+
 	final := append(cfg.Config, trace.Trace...)
 	err = printPTermTree(final)
 
@@ -106,6 +122,10 @@ func runMapping(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if structuredOutput() {
+		return writeDocument(cmd.OutOrStdout(), outputFormat, kindResourceMapping, toResourceMappingOutput(result.Info))
+	}
+
 	//
 	for i, r := range result.Info {
 		items := []pterm.BulletListItem{
@@ -178,6 +198,7 @@ func runExecute(cmd *cobra.Command, args []string) error {
 	}
 
 	var message *rpc.ResourceExecuteReply
+	result := executeResultOutput{Type: typ, Name: name, Command: command, Messages: []string{}}
 
 	for {
 		message, err = stream.Recv()
@@ -185,14 +206,20 @@ func runExecute(cmd *cobra.Command, args []string) error {
 			break
 		}
 
+		if structuredOutput() {
+			result.Messages = append(result.Messages, message.Message)
+			continue
+		}
 		fmt.Print(message.Message) // print message
 	}
 
-	if err == io.EOF {
-		return nil
+	if err != io.EOF {
+		return err
 	}
-
-	return err
+	if structuredOutput() {
+		return writeDocument(cmd.OutOrStdout(), outputFormat, kindExecuteResult, result)
+	}
+	return nil
 }
 
 const (
@@ -205,26 +232,33 @@ const (
 )
 
 func runMetadata(cmd *cobra.Command, args []string) error {
-	leveledList := pterm.LeveledList{}
-
-	vsw, err := metadata.GetLocalVswitch()
+	m, err := getMetadata()
 	if err != nil {
 		return err
 	}
 
-	leveledList = append(leveledList, pterm.LeveledListItem{
-		Level: metadataLevelVSwitch,
-		Text:  printKV("vswitch", vsw),
-	})
+	if structuredOutput() {
+		return writeDocument(cmd.OutOrStdout(), outputFormat, kindMetadata, m)
+	}
+	return printMetadataTree(m)
+}
+
+// getMetadata collect the vSwitch and enis of this node, the primary eni is the first
+func getMetadata() (*metadataOutput, error) {
+	vsw, err := metadata.GetLocalVswitch()
+	if err != nil {
+		return nil, err
+	}
+	m := &metadataOutput{VSwitch: vsw, ENIs: []metadataENIOutput{}}
 
 	enis, err := metadata.GetENIsMAC()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	primaryENI, err := metadata.GetPrimaryENIMAC()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// make primary to the first
@@ -233,92 +267,124 @@ func runMetadata(cmd *cobra.Command, args []string) error {
 	})
 
 	for _, eni := range enis {
-		leveledList = append(leveledList, pterm.LeveledListItem{
-			Level: metadataLevelENI,
-			Text:  printKV("eni", eni),
-		}, pterm.LeveledListItem{
-			Level: metadataLevelAttribute,
-			Text:  printKV("primary", fmt.Sprintf("%t", primaryENI == eni)),
-		})
+		item := metadataENIOutput{
+			MAC:            eni,
+			Primary:        primaryENI == eni,
+			SecondaryIPv4s: []string{},
+			IPv6s:          []string{},
+		}
 
 		// network interface
 		nif, err := getInterfaceByMAC(eni)
 		if err != nil && err.Error() != "not found" {
-			return err
+			return nil, err
+		}
+		if err == nil {
+			item.Interface = nif.Name
 		}
 
-		ifname := ""
+		primaryIP, err := metadata.GetENIPrimaryIP(eni)
 		if err != nil {
-			ifname = pterm.Red("!!!NOT FOUND")
-		} else {
-			ifname = nif.Name
+			return nil, err
 		}
+		item.PrimaryIPv4 = primaryIP.String()
 
-		leveledList = append(leveledList, pterm.LeveledListItem{
-			Level: metadataLevelAttribute,
-			Text:  printKV("interface", ifname),
+		// ipv4
+		ipv4s, err := metadata.GetENIPrivateIPs(eni)
+		if err != nil {
+			return nil, err
+		}
+
+		sort.Slice(ipv4s, func(i, j int) bool {
+			return ipv4s[i].String() < ipv4s[j].String()
 		})
+		for _, ip := range ipv4s {
+			if ip.Equal(primaryIP) {
+				continue
+			}
+			item.SecondaryIPv4s = append(item.SecondaryIPv4s, ip.String())
+		}
 
-		primaryIP, err := metadata.GetENIPrimaryIP(eni)
+		// ipv6
+		ipv6s, err := metadata.GetENIPrivateIPv6IPs(eni)
 		if err != nil {
-			return err
+			if structuredOutput() {
+				_, _ = fmt.Fprintf(os.Stderr, metadataErrorStringIPV6+"\n", eni, err)
+			} else {
+				pterm.Error.Printf(metadataErrorStringIPV6, eni, err)
+			}
+			m.ENIs = append(m.ENIs, item)
+			continue
 		}
 
+		sort.Slice(ipv6s, func(i, j int) bool {
+			return strings.Compare(ipv6s[i].String(), ipv6s[j].String()) < 0
+		})
+		for _, ip := range ipv6s {
+			item.IPv6s = append(item.IPv6s, ip.String())
+		}
+
+		m.ENIs = append(m.ENIs, item)
+	}
+
+	return m, nil
+}
+
+func printMetadataTree(m *metadataOutput) error {
+	leveledList := pterm.LeveledList{}
+
+	leveledList = append(leveledList, pterm.LeveledListItem{
+		Level: metadataLevelVSwitch,
+		Text:  printKV("vswitch", m.VSwitch),
+	})
+
+	for _, eni := range m.ENIs {
 		leveledList = append(leveledList, pterm.LeveledListItem{
+			Level: metadataLevelENI,
+			Text:  printKV("eni", eni.MAC),
+		}, pterm.LeveledListItem{
 			Level: metadataLevelAttribute,
-			Text:  printKV("primary_ipv4", primaryIP.String()),
+			Text:  printKV("primary", fmt.Sprintf("%t", eni.Primary)),
 		})
 
-		// ipv4
-		ipv4s, err := metadata.GetENIPrivateIPs(eni)
-		if err != nil {
-			return err
+		ifname := eni.Interface
+		if ifname == "" {
+			ifname = pterm.Red("!!!NOT FOUND")
 		}
 
-		// when len(ipv4) == 1, only primary ipv4 exists
-		if len(ipv4s) > 1 {
+		leveledList = append(leveledList, pterm.LeveledListItem{
+			Level: metadataLevelAttribute,
+			Text:  printKV("interface", ifname),
+		}, pterm.LeveledListItem{
+			Level: metadataLevelAttribute,
+			Text:  printKV("primary_ipv4", eni.PrimaryIPv4),
+		})
+
+		// only primary ipv4 exists
+		if len(eni.SecondaryIPv4s) > 0 {
 			leveledList = append(leveledList, pterm.LeveledListItem{
 				Level: metadataLevelAttribute,
 				Text:  "secondary_ipv4s",
 			})
 
-			sort.Slice(ipv4s, func(i, j int) bool {
-				return ipv4s[i].String() < ipv4s[j].String()
-			})
-
-			for _, ip := range ipv4s {
-				if ip.Equal(primaryIP) {
-					continue
-				}
-
+			for _, ip := range eni.SecondaryIPv4s {
 				leveledList = append(leveledList, pterm.LeveledListItem{
 					Level: metadataLevelAttributeItem,
-					Text:  pterm.ThemeDefault.WarningMessageStyle.Sprint(ip.String()),
+					Text:  pterm.ThemeDefault.WarningMessageStyle.Sprint(ip),
 				})
 			}
 		}
 
-		// ipv6
-		ipv6s, err := metadata.GetENIPrivateIPv6IPs(eni)
-		if err != nil {
-			pterm.Error.Printf(metadataErrorStringIPV6, eni, err)
-			continue
-		}
-
-		if len(ipv6s) != 0 {
+		if len(eni.IPv6s) != 0 {
 			leveledList = append(leveledList, pterm.LeveledListItem{
 				Level: metadataLevelAttribute,
 				Text:  "ipv6s",
 			})
 
-			sort.Slice(ipv6s, func(i, j int) bool {
-				return strings.Compare(ipv6s[i].String(), ipv6s[j].String()) < 0
-			})
-
-			for _, ip := range ipv6s {
+			for _, ip := range eni.IPv6s {
 				leveledList = append(leveledList, pterm.LeveledListItem{
 					Level: metadataLevelAttributeItem,
-					Text:  pterm.ThemeDefault.WarningMessageStyle.Sprint(ip.String()),
+					Text:  pterm.ThemeDefault.WarningMessageStyle.Sprint(ip),
 				})
 			}
 		}
@@ -349,3 +415,11 @@ func getFirstNameWithType(typ string) (string, error) {
 
 	return resource.ResourceNames[0], nil
 }
+
+// nonNil return an empty slice instead of nil, so the list is never null in the json output
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	"github.com/AliyunContainerService/terway/pkg/utils"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+const dbLockTimeout = time.Second
+
+var (
+	dbPath       string
+	dbExportJSON bool
+	dbOverwrite  bool
+)
+
+// the db commands work on the bolt file directly, so the daemon should be stopped
+var (
+	dbCmd = &cobra.Command{
+		Use:   "db",
+		Short: "inspect, export, import and repair the resource db, the daemon must be stopped.",
+		Long: "inspect, export, import and repair the resource db, the daemon must be stopped.\n" +
+			"The db is migrated to the schema version of this binary when opened by import or delete, other commands open it read only.",
+		// no connection to the daemon is required
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return validateOutputFormat(outputFormat)
+		},
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {},
+	}
+
+	dbListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "list all pod resources in the db.",
+		Args:  cobra.NoArgs,
+		RunE:  runDBList,
+	}
+
+	dbGetCmd = &cobra.Command{
+		Use:   "get <namespace/name>",
+		Short: "show the pod resource.",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runDBGet,
+	}
+
+	dbExportCmd = &cobra.Command{
+		Use:   "export --json",
+		Short: "export all pod resources to stdout.",
+		Args:  cobra.NoArgs,
+		RunE:  runDBExport,
+	}
+
+	dbImportCmd = &cobra.Command{
+		Use:   "import <file>",
+		Short: "import pod resources from the file exported, use - for stdin.",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runDBImport,
+	}
+
+	dbDeleteCmd = &cobra.Command{
+		Use:   "delete <namespace/name>",
+		Short: "delete the pod resource.",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runDBDelete,
+	}
+
+	dbVerifyCmd = &cobra.Command{
+		Use:   "verify",
+		Short: "cross-check the pod resources with the netlink state on this node.",
+		Args:  cobra.NoArgs,
+		RunE:  runDBVerify,
+	}
+)
+
+func init() {
+	dbCmd.PersistentFlags().StringVar(&dbPath, "path", daemon.ResDBPath, "path of the resource db")
+	dbExportCmd.Flags().BoolVar(&dbExportJSON, "json", false, "export in json format")
+	dbImportCmd.Flags().BoolVar(&dbOverwrite, "overwrite", false, "overwrite the pod resource already exist")
+
+	dbCmd.AddCommand(dbListCmd, dbGetCmd, dbExportCmd, dbImportCmd, dbDeleteCmd, dbVerifyCmd)
+	rootCmd.AddCommand(dbCmd)
+}
+
+// openResDB open the db, fail fast if the db is held by the daemon.
+// The db opened read only is not migrated, and can be opened by other readers at the same time.
+func openResDB(path string, readOnly bool) (storage.Storage, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	s, err := daemon.OpenResDB(path, &bolt.Options{Timeout: dbLockTimeout, ReadOnly: readOnly})
+	if err != nil {
+		if errors.Is(err, bolt.ErrTimeout) {
+			return nil, fmt.Errorf("db %s is locked, stop the daemon first", path)
+		}
+		return nil, err
+	}
+	return s, nil
+}
+
+// listPodResources return all pod resources sort by the pod key
+func listPodResources(s storage.Storage) ([]daemon.PodResources, error) {
+	objs, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]daemon.PodResources, 0, len(objs))
+	for _, obj := range objs {
+		res, ok := obj.(daemon.PodResources)
+		if !ok {
+			return nil, fmt.Errorf("unexpected type %T", obj)
+		}
+		result = append(result, res)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return podResourcesKey(result[i]) < podResourcesKey(result[j])
+	})
+	return result, nil
+}
+
+func podResourcesKey(res daemon.PodResources) string {
+	if res.PodInfo == nil {
+		return ""
+	}
+	return utils.PodInfoKey(res.PodInfo.Namespace, res.PodInfo.Name)
+}
+
+// importPodResources put the pod resources into the db, return the count imported
+func importPodResources(s storage.Storage, resources []daemon.PodResources, overwrite bool) (int, error) {
+	imported := 0
+	for _, res := range resources {
+		key := podResourcesKey(res)
+		if key == "" {
+			return imported, fmt.Errorf("pod info is missing")
+		}
+
+		_, err := s.Get(key)
+		if err == nil && !overwrite {
+			pterm.Warning.Printfln("skip %s, already exist", key)
+			continue
+		}
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return imported, err
+		}
+
+		err = s.Put(key, res)
+		if err != nil {
+			return imported, err
+		}
+		imported++
+	}
+	return imported, nil
+}
+
+// dbVerifyResult the problems found for one pod
+type dbVerifyResult struct {
+	Key      string   `json:"key"`
+	Problems []string `json:"problems"`
+}
+
+// verifyPodResources check each pod resource with the check func, only pods have problem is returned
+func verifyPodResources(resources []daemon.PodResources, check func(res daemon.PodResources) []string) []dbVerifyResult {
+	var result []dbVerifyResult
+	for _, res := range resources {
+		problems := check(res)
+		if len(problems) == 0 {
+			continue
+		}
+		result = append(result, dbVerifyResult{Key: podResourcesKey(res), Problems: problems})
+	}
+	return result
+}
+
+func runDBList(cmd *cobra.Command, args []string) error {
+	s, err := openResDB(dbPath, true)
+	if err != nil {
+		return err
+	}
+
+	resources, err := listPodResources(s)
+	if err != nil {
+		return err
+	}
+
+	list := toPodResourceListOutput(resources, time.Now())
+	if structuredOutput() {
+		return writeDocument(cmd.OutOrStdout(), outputFormat, kindPodResourceList, list)
+	}
+
+	data := pterm.TableData{{"POD", "TYPE", "ENI", "IPV4", "IPV6", "NETNS", "RELEASE IN"}}
+	for _, item := range list.Items {
+		data = append(data, []string{item.Pod, item.Type, item.ENI, item.IPv4, item.IPv6, item.NetNs, item.ReleaseIn})
+	}
+
+	return pterm.DefaultTable.WithHasHeader().WithData(data).Render()
+}
+
+func toPodResourceListOutput(resources []daemon.PodResources, now time.Time) podResourceListOutput {
+	list := podResourceListOutput{Items: []podResourceItemOutput{}}
+	for _, res := range resources {
+		netns := ""
+		if res.NetNs != nil {
+			netns = *res.NetNs
+		}
+		// the remaining time the ip is held for the deleted pod
+		releaseIn := ""
+		if res.PodInfo != nil {
+			releaseIn = daemon.IPHoldRemaining(res.PodInfo.IPReleaseAt, now)
+		}
+		for _, item := range res.Resources {
+			list.Items = append(list.Items, podResourceItemOutput{
+				Pod:       podResourcesKey(res),
+				Type:      item.Type,
+				ENI:       item.ENIID,
+				IPv4:      item.IPv4,
+				IPv6:      item.IPv6,
+				NetNs:     netns,
+				ReleaseIn: releaseIn,
+			})
+		}
+	}
+	return list
+}
+
+func runDBGet(cmd *cobra.Command, args []string) error {
+	s, err := openResDB(dbPath, true)
+	if err != nil {
+		return err
+	}
+
+	obj, err := s.Get(args[0])
+	if err != nil {
+		return fmt.Errorf("get %s failed, %w", args[0], err)
+	}
+
+	if structuredOutput() {
+		return writeDocument(cmd.OutOrStdout(), outputFormat, kindPodResource, obj)
+	}
+
+	out, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(cmd.OutOrStdout(), string(out))
+	return err
+}
+
+func runDBExport(cmd *cobra.Command, args []string) error {
+	if !dbExportJSON {
+		return fmt.Errorf("output format is required, only --json is supported")
+	}
+
+	s, err := openResDB(dbPath, true)
+	if err != nil {
+		return err
+	}
+
+	resources, err := listPodResources(s)
+	if err != nil {
+		return err
+	}
+
+	out, err := json.MarshalIndent(resources, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(cmd.OutOrStdout(), string(out))
+	return err
+}
+
+func runDBImport(cmd *cobra.Command, args []string) error {
+	var (
+		in  []byte
+		err error
+	)
+	if args[0] == "-" {
+		in, err = io.ReadAll(cmd.InOrStdin())
+	} else {
+		in, err = os.ReadFile(args[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	var resources []daemon.PodResources
+	err = json.Unmarshal(in, &resources)
+	if err != nil {
+		return fmt.Errorf("error parse %s, %w", args[0], err)
+	}
+
+	s, err := openResDB(dbPath, false)
+	if err != nil {
+		return err
+	}
+
+	imported, err := importPodResources(s, resources, dbOverwrite)
+	if err != nil {
+		return err
+	}
+	pterm.Success.Printfln("imported %d of %d pod resources", imported, len(resources))
+	return nil
+}
+
+func runDBDelete(cmd *cobra.Command, args []string) error {
+	s, err := openResDB(dbPath, false)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.Get(args[0])
+	if err != nil {
+		return fmt.Errorf("get %s failed, %w", args[0], err)
+	}
+
+	err = s.Delete(args[0])
+	if err != nil {
+		return err
+	}
+	pterm.Success.Printfln("deleted %s", args[0])
+	return nil
+}
+
+func runDBVerify(cmd *cobra.Command, args []string) error {
+	s, err := openResDB(dbPath, true)
+	if err != nil {
+		return err
+	}
+
+	resources, err := listPodResources(s)
+	if err != nil {
+		return err
+	}
+
+	result := verifyPodResources(resources, checkNetlinkState)
+	if structuredOutput() {
+		if result == nil {
+			result = []dbVerifyResult{}
+		}
+		err = writeDocument(cmd.OutOrStdout(), outputFormat, kindDBVerifyResult, dbVerifyOutput{Total: len(resources), Inconsistent: result})
+		if err != nil {
+			return err
+		}
+		if len(result) > 0 {
+			return fmt.Errorf("%d of %d pod resources are inconsistent", len(result), len(resources))
+		}
+		return nil
+	}
+	if len(result) == 0 {
+		pterm.Success.Printfln("all %d pod resources are consistent with the node", len(resources))
+		return nil
+	}
+
+	var items []pterm.BulletListItem
+	for _, r := range result {
+		items = append(items, pterm.BulletListItem{
+			Level:       0,
+			Text:        r.Key,
+			BulletStyle: pterm.NewStyle(pterm.FgRed),
+		})
+		for _, p := range r.Problems {
+			items = append(items, pterm.BulletListItem{
+				Level:  1,
+				Text:   p,
+				Bullet: "-",
+			})
+		}
+	}
+	err = pterm.DefaultBulletList.WithItems(items).Render()
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%d of %d pod resources are inconsistent", len(result), len(resources))
+}
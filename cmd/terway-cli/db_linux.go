@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/vishvananda/netlink"
+
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+// checkNetlinkState check the eni is attached, and the pod ip is configured in the pod netns
+func checkNetlinkState(res daemon.PodResources) []string {
+	var problems []string
+
+	var ips []netip.Addr
+	for _, item := range res.Resources {
+		if item.ENIMAC != "" {
+			if _, err := getInterfaceByMAC(item.ENIMAC); err != nil {
+				problems = append(problems, fmt.Sprintf("eni %s (%s) not found on the node", item.ENIID, item.ENIMAC))
+			}
+		}
+		for _, s := range []string{item.IPv4, item.IPv6} {
+			if ip, err := netip.ParseAddr(s); err == nil {
+				ips = append(ips, ip)
+			}
+		}
+	}
+
+	if res.NetNs == nil || *res.NetNs == "" {
+		return problems
+	}
+	if _, err := os.Stat(*res.NetNs); err != nil {
+		return append(problems, fmt.Sprintf("netns %s not found", *res.NetNs))
+	}
+
+	err := ns.WithNetNSPath(*res.NetNs, func(netNS ns.NetNS) error {
+		addrs, err := netlink.AddrList(nil, netlink.FAMILY_ALL)
+		if err != nil {
+			return err
+		}
+		for _, ip := range ips {
+			found := false
+			for _, addr := range addrs {
+				if a, ok := netip.AddrFromSlice(addr.IP); ok && a.Unmap() == ip {
+					found = true
+					break
+				}
+			}
+			if !found {
+				problems = append(problems, fmt.Sprintf("ip %s not found in netns %s", ip, *res.NetNs))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("error check netns %s, %s", *res.NetNs, err))
+	}
+	return problems
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+func newTestPodResources(namespace, name, ipv4 string) daemon.PodResources {
+	return daemon.PodResources{
+		PodInfo: &daemon.PodInfo{Namespace: namespace, Name: name},
+		Resources: []daemon.ResourceItem{
+			{Type: daemon.ResourceTypeENIIP, ENIID: "eni-1", ENIMAC: "00:00:00:00:00:01", IPv4: ipv4},
+		},
+	}
+}
+
+func TestImportAndListPodResources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	s, err := daemon.NewResDB(path)
+	assert.NoError(t, err)
+
+	imported, err := importPodResources(s, []daemon.PodResources{
+		newTestPodResources("default", "b", "192.168.0.2"),
+		newTestPodResources("default", "a", "192.168.0.1"),
+	}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, imported)
+
+	// skip exist
+	imported, err = importPodResources(s, []daemon.PodResources{
+		newTestPodResources("default", "a", "192.168.0.3"),
+	}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, imported)
+
+	resources, err := listPodResources(s)
+	assert.NoError(t, err)
+	assert.Len(t, resources, 2)
+	assert.Equal(t, "a", resources[0].PodInfo.Name)
+	assert.Equal(t, "192.168.0.1", resources[0].Resources[0].IPv4)
+
+	imported, err = importPodResources(s, []daemon.PodResources{
+		newTestPodResources("default", "a", "192.168.0.3"),
+	}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, imported)
+
+	obj, err := s.Get("default/a")
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.0.3", obj.(daemon.PodResources).Resources[0].IPv4)
+
+	_, err = importPodResources(s, []daemon.PodResources{{}}, true)
+	assert.Error(t, err)
+}
+
+func TestOpenResDBLocked(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	_, err := daemon.NewResDB(path)
+	assert.NoError(t, err)
+
+	_, err = openResDB(path, true)
+	assert.ErrorContains(t, err, "stop the daemon first")
+}
+
+func TestVerifyPodResources(t *testing.T) {
+	result := verifyPodResources([]daemon.PodResources{
+		newTestPodResources("default", "a", "192.168.0.1"),
+		newTestPodResources("default", "b", "192.168.0.2"),
+	}, func(res daemon.PodResources) []string {
+		if res.PodInfo.Name == "b" {
+			return []string{"ip not found"}
+		}
+		return nil
+	})
+
+	assert.Equal(t, []dbVerifyResult{{Key: "default/b", Problems: []string{"ip not found"}}}, result)
+}
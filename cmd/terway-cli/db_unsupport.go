@@ -0,0 +1,11 @@
+//go:build !linux
+
+package main
+
+import (
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+func checkNetlinkState(res daemon.PodResources) []string {
+	return []string{"verify is not supported on this platform"}
+}
@@ -0,0 +1,434 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+const (
+	checkPass = "pass"
+	checkFail = "fail"
+	checkSkip = "skip"
+
+	defaultCNIConfDir = "/etc/cni/net.d"
+	terwayCNIConfFile = "10-terway.conflist"
+	// defaultCNIMTU is the mtu used by the terway plugin if not set in the config
+	defaultCNIMTU = 1500
+)
+
+var (
+	diagnoseBundle     string
+	diagnoseCNIConfDir string
+	diagnoseDBPath     string
+
+	diagnoseCmd = &cobra.Command{
+		Use:   "diagnose",
+		Short: "run health checks of the terway network on this node.",
+		Long: "run health checks of the terway network on this node.\n" +
+			"The resource mapping, metadata, host netlink state, cni config and resource db are collected and cross-checked,\n" +
+			"the checks depend on the state failed to collect are skipped.",
+		Args: cobra.NoArgs,
+		RunE: runDiagnose,
+	}
+)
+
+func init() {
+	diagnoseCmd.Flags().StringVar(&diagnoseBundle, "bundle", "", "write the state collected and the report to the tar.gz file")
+	diagnoseCmd.Flags().StringVar(&diagnoseCNIConfDir, "cni-conf-dir", defaultCNIConfDir, "directory of the cni config")
+	diagnoseCmd.Flags().StringVar(&diagnoseDBPath, "db-path", daemon.ResDBPath, "path of the resource db")
+
+	rootCmd.AddCommand(diagnoseCmd)
+}
+
+// diagnoseInput is the state collected for the checks, the error of each source is kept,
+// so the checks depend on it are skipped
+type diagnoseInput struct {
+	mappings    []*rpc.ResourceMapping
+	mappingsErr error
+
+	metadata    *metadataOutput
+	metadataErr error
+
+	cniFiles map[string][]byte
+	cniMTU   int
+	cniErr   error
+
+	pods    []daemon.PodResources
+	podsErr error
+
+	host    *hostNetState
+	hostErr error
+}
+
+// diagnoseCheck is a named check, run return the problems found,
+// or error if the state required is not available
+type diagnoseCheck struct {
+	name        string
+	description string
+	hint        string
+	run         func(in *diagnoseInput) ([]string, error)
+}
+
+func diagnoseChecks() []diagnoseCheck {
+	return append([]diagnoseCheck{
+		{
+			name:        "eni-in-metadata",
+			description: "the enis managed by terway are attached to the instance",
+			hint: "the eni is detached out of band, check the eni in the ecs console, " +
+				"restart terway to resync the enis after it is detached",
+			run: checkENIInMetadata,
+		},
+	}, hostChecks()...)
+}
+
+func runDiagnoseChecks(checks []diagnoseCheck, in *diagnoseInput) []checkResultOutput {
+	result := make([]checkResultOutput, 0, len(checks))
+	for _, c := range checks {
+		r := checkResultOutput{
+			Name:        c.name,
+			Description: c.description,
+			Status:      checkPass,
+			Problems:    []string{},
+		}
+
+		problems, err := c.run(in)
+		switch {
+		case err != nil:
+			r.Status = checkSkip
+			r.Problems = []string{err.Error()}
+		case len(problems) > 0:
+			r.Status = checkFail
+			r.Problems = problems
+			r.Hint = c.hint
+		}
+		result = append(result, r)
+	}
+	return result
+}
+
+// checkENIInMetadata check every eni in the resource mapping is found in metadata
+func checkENIInMetadata(in *diagnoseInput) ([]string, error) {
+	if in.mappingsErr != nil {
+		return nil, in.mappingsErr
+	}
+	if in.metadataErr != nil {
+		return nil, in.metadataErr
+	}
+
+	macs := sets.New[string]()
+	for _, eni := range in.metadata.ENIs {
+		macs.Insert(eni.MAC)
+	}
+
+	var problems []string
+	for _, m := range in.mappings {
+		if m.MAC == "" || macs.Has(m.MAC) {
+			continue
+		}
+		problems = append(problems, fmt.Sprintf("eni %s (%s) is not found in metadata", m.NetworkInterfaceID, m.MAC))
+	}
+	return problems, nil
+}
+
+func gatherDiagnoseInput() *diagnoseInput {
+	in := &diagnoseInput{}
+
+	result, err := client.GetResourceMapping(ctx, &rpc.Placeholder{})
+	if err != nil {
+		in.mappingsErr = fmt.Errorf("error get resource mapping from the daemon, %w", err)
+	} else {
+		in.mappings = result.Info
+	}
+
+	in.metadata, err = getMetadata()
+	if err != nil {
+		in.metadataErr = fmt.Errorf("error get metadata, %w", err)
+	}
+
+	in.cniFiles, in.cniMTU, in.cniErr = loadCNIConfig(diagnoseCNIConfDir)
+	in.pods, in.podsErr = loadPodResourcesSnapshot(diagnoseDBPath)
+	in.host, in.hostErr = gatherHostNetState()
+	return in
+}
+
+// loadCNIConfig read the cni config files in the dir, and the mtu of the terway plugin
+func loadCNIConfig(dir string) (map[string][]byte, int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error read cni config dir, %w", err)
+	}
+
+	files := make(map[string][]byte)
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		switch filepath.Ext(e.Name()) {
+		case ".conf", ".conflist", ".json":
+		default:
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, 0, fmt.Errorf("error read cni config, %w", err)
+		}
+		files[e.Name()] = data
+	}
+
+	data, ok := files[terwayCNIConfFile]
+	if !ok {
+		return files, 0, fmt.Errorf("cni config %s is not found in %s", terwayCNIConfFile, dir)
+	}
+	mtu, err := parseTerwayMTU(data)
+	if err != nil {
+		return files, 0, fmt.Errorf("error parse cni config %s, %w", terwayCNIConfFile, err)
+	}
+	return files, mtu, nil
+}
+
+// parseTerwayMTU return the mtu of the terway plugin in the conflist
+func parseTerwayMTU(data []byte) (int, error) {
+	conf := struct {
+		Plugins []struct {
+			Type string `json:"type"`
+			MTU  int    `json:"mtu"`
+		} `json:"plugins"`
+	}{}
+	err := json.Unmarshal(data, &conf)
+	if err != nil {
+		return 0, err
+	}
+	for _, p := range conf.Plugins {
+		if p.Type != "terway" {
+			continue
+		}
+		if p.MTU == 0 {
+			return defaultCNIMTU, nil
+		}
+		return p.MTU, nil
+	}
+	return 0, fmt.Errorf("terway plugin is not found")
+}
+
+// loadPodResourcesSnapshot read the pod resources from a copy of the db, as the db is locked by the daemon.
+// The copy is opened read only, and refused if it is not the schema version of this terway-cli, so nothing is migrated.
+func loadPodResourcesSnapshot(path string) ([]daemon.PodResources, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error read resource db, %w", err)
+	}
+
+	dir, err := os.MkdirTemp("", "terway-diagnose")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	snapshot := filepath.Join(dir, filepath.Base(path))
+	err = os.WriteFile(snapshot, data, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := storage.ReadSchemaVersion(snapshot, daemon.ResDBName, &bolt.Options{Timeout: dbLockTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("error open resource db, %w", err)
+	}
+	if version != daemon.ResDBSchema.Version() {
+		return nil, fmt.Errorf("%w, resource db is version %d, terway-cli supports version %d, use the terway-cli of the same version as the daemon",
+			storage.ErrSchemaMismatch, version, daemon.ResDBSchema.Version())
+	}
+
+	s, err := openResDB(snapshot, true)
+	if err != nil {
+		return nil, fmt.Errorf("error open resource db, %w", err)
+	}
+	return listPodResources(s)
+}
+
+func runDiagnose(cmd *cobra.Command, args []string) error {
+	in := gatherDiagnoseInput()
+
+	report := diagnoseReportOutput{
+		Checks: runDiagnoseChecks(diagnoseChecks(), in),
+		Bundle: diagnoseBundle,
+	}
+
+	if diagnoseBundle != "" {
+		err := writeDiagnoseBundle(diagnoseBundle, in, report)
+		if err != nil {
+			return fmt.Errorf("error write bundle, %w", err)
+		}
+	}
+
+	failed := 0
+	for _, c := range report.Checks {
+		if c.Status == checkFail {
+			failed++
+		}
+	}
+
+	if structuredOutput() {
+		err := writeDocument(cmd.OutOrStdout(), outputFormat, kindDiagnoseReport, report)
+		if err != nil {
+			return err
+		}
+	} else {
+		err := printDiagnoseReport(report)
+		if err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(report.Checks))
+	}
+	return nil
+}
+
+func printDiagnoseReport(report diagnoseReportOutput) error {
+	for _, c := range report.Checks {
+		switch c.Status {
+		case checkPass:
+			pterm.Success.Printfln("%s: %s", c.Name, c.Description)
+			continue
+		case checkSkip:
+			pterm.Warning.Printfln("%s: skipped", c.Name)
+		default:
+			pterm.Error.Printfln("%s: %s", c.Name, c.Description)
+		}
+
+		var items []pterm.BulletListItem
+		for _, p := range c.Problems {
+			items = append(items, pterm.BulletListItem{
+				Level:  1,
+				Text:   p,
+				Bullet: "-",
+			})
+		}
+		if c.Hint != "" {
+			items = append(items, pterm.BulletListItem{
+				Level:       1,
+				Text:        "hint: " + c.Hint,
+				BulletStyle: pterm.NewStyle(pterm.FgYellow),
+			})
+		}
+		err := pterm.DefaultBulletList.WithItems(items).Render()
+		if err != nil {
+			return err
+		}
+	}
+
+	if report.Bundle != "" {
+		pterm.Info.Printfln("bundle is written to %s", report.Bundle)
+	}
+	return nil
+}
+
+// writeDiagnoseBundle write the report and the state collected to the tar.gz file,
+// the error of the state is written to <name>.error
+func writeDiagnoseBundle(path string, in *diagnoseInput, report diagnoseReportOutput) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+
+	files := make(map[string][]byte)
+	addDocument := func(name, kind string, spec interface{}, specErr error) error {
+		if specErr != nil {
+			files[name+".error"] = []byte(specErr.Error() + "\n")
+			return nil
+		}
+		out, err := json.MarshalIndent(document{APIVersion: outputAPIVersion, Kind: kind, Spec: spec}, "", "  ")
+		if err != nil {
+			return err
+		}
+		files[name+".json"] = append(out, '\n')
+		return nil
+	}
+
+	err = addDocument("report", kindDiagnoseReport, report, nil)
+	if err != nil {
+		return err
+	}
+	err = addDocument("mapping", kindResourceMapping, toResourceMappingOutput(in.mappings), in.mappingsErr)
+	if err != nil {
+		return err
+	}
+	err = addDocument("metadata", kindMetadata, in.metadata, in.metadataErr)
+	if err != nil {
+		return err
+	}
+	// same as the db export, so it can be imported
+	if in.podsErr != nil {
+		files["db.error"] = []byte(in.podsErr.Error() + "\n")
+	} else {
+		out, err := json.MarshalIndent(in.pods, "", "  ")
+		if err != nil {
+			return err
+		}
+		files["db.json"] = append(out, '\n')
+	}
+
+	for name, data := range in.cniFiles {
+		files[filepath.Join("cni", name)] = data
+	}
+	if in.cniErr != nil {
+		files[filepath.Join("cni", "config.error")] = []byte(in.cniErr.Error() + "\n")
+	}
+	for name, data := range hostDumps(in.host) {
+		files[filepath.Join("host", name)] = data
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	now := time.Now()
+	for _, name := range names {
+		err = tw.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0600,
+			Size:    int64(len(files[name])),
+			ModTime: now,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = tw.Write(files[name])
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tw.Close()
+	if err != nil {
+		return err
+	}
+	err = gw.Close()
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+
+	"github.com/vishvananda/netlink"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/AliyunContainerService/terway/pkg/link"
+	"github.com/AliyunContainerService/terway/pkg/utils"
+	"github.com/AliyunContainerService/terway/plugin/datapath"
+	driverutils "github.com/AliyunContainerService/terway/plugin/driver/utils"
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+const (
+	defaultIfName = "eth0"
+)
+
+// hostNetState is the netlink state in host netns
+type hostNetState struct {
+	links []netlink.Link
+	rules []netlink.Rule
+	// filters on the egress of the link, keyed by the link index
+	filters map[int][]netlink.Filter
+}
+
+func (h *hostNetState) linkByMAC(mac string) (netlink.Link, bool) {
+	for _, l := range h.links {
+		if l.Attrs().HardwareAddr != nil && l.Attrs().HardwareAddr.String() == mac {
+			return l, true
+		}
+	}
+	return nil, false
+}
+
+func (h *hostNetState) linkByName(name string) (netlink.Link, bool) {
+	for _, l := range h.links {
+		if l.Attrs().Name == name {
+			return l, true
+		}
+	}
+	return nil, false
+}
+
+func gatherHostNetState() (*hostNetState, error) {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return nil, fmt.Errorf("error list links, %w", err)
+	}
+	rules, err := netlink.RuleList(netlink.FAMILY_ALL)
+	if err != nil {
+		return nil, fmt.Errorf("error list rules, %w", err)
+	}
+
+	h := &hostNetState{links: links, rules: rules, filters: make(map[int][]netlink.Filter)}
+	for _, l := range links {
+		if _, ok := l.(*netlink.Device); !ok {
+			continue
+		}
+		filters, err := netlink.FilterList(l, netlink.HANDLE_MIN_EGRESS)
+		if err != nil {
+			// no clsact qdisc on the link
+			continue
+		}
+		h.filters[l.Attrs().Index] = filters
+	}
+	return h, nil
+}
+
+// podNetwork is a network of the pod, parsed from the net conf
+type podNetwork struct {
+	pod       string
+	name      string
+	namespace string
+	ifName    string
+	ip        *types.IPSet
+	eniMAC    string
+	trunk     bool
+	vid       uint32
+}
+
+// podNetworks return the networks of the pods, the pods without the net conf is skipped
+func podNetworks(pods []daemon.PodResources) []podNetwork {
+	var result []podNetwork
+	for _, pod := range pods {
+		if pod.PodInfo == nil || pod.NetConf == "" {
+			continue
+		}
+		var netConfs []*rpc.NetConf
+		if json.Unmarshal([]byte(pod.NetConf), &netConfs) != nil {
+			continue
+		}
+		for _, netConf := range netConfs {
+			if netConf.GetBasicInfo().GetPodIP() == nil || netConf.GetENIInfo().GetMAC() == "" {
+				continue
+			}
+			ip, err := types.ToIPSet(netConf.GetBasicInfo().GetPodIP())
+			if err != nil {
+				continue
+			}
+			ifName := netConf.GetIfName()
+			if ifName == "" {
+				ifName = defaultIfName
+			}
+			result = append(result, podNetwork{
+				pod:       utils.PodInfoKey(pod.PodInfo.Namespace, pod.PodInfo.Name),
+				name:      pod.PodInfo.Name,
+				namespace: pod.PodInfo.Namespace,
+				ifName:    ifName,
+				ip:        ip,
+				eniMAC:    netConf.GetENIInfo().GetMAC(),
+				trunk:     netConf.GetENIInfo().GetTrunk(),
+				vid:       netConf.GetENIInfo().GetVid(),
+			})
+		}
+	}
+	return result
+}
+
+func hostChecks() []diagnoseCheck {
+	return []diagnoseCheck{
+		{
+			name:        "pod-ip-rule",
+			description: "the ip rules of the pods in policy route datapath are present",
+			hint:        "the rules are restored by the datapath reconciler of the daemon, or recreate the pod",
+			run:         checkPodIPRules,
+		},
+		{
+			name:        "orphan-veth",
+			description: "every host veth belongs to a pod in the resource db",
+			hint:        "the pod is deleted without the cni DEL, remove the link by `ip link del <name>`",
+			run:         checkOrphanVeth,
+		},
+		{
+			name:        "mtu",
+			description: "the mtu of the enis and host veths match the cni config",
+			hint:        "the mtu is set when the pod is created, recreate the pods after the mtu in the cni config changed",
+			run:         checkMTU,
+		},
+		{
+			name:        "tc-filter",
+			description: "the ipvlan redirect and vlan tag filters of the pods are present",
+			hint:        "the filters are restored by cni CHECK and the datapath reconciler of the daemon, or recreate the pod",
+			run:         checkTCFilters,
+		},
+	}
+}
+
+// checkPodIPRules check the rules in host netns for the pods use the policy route datapath.
+// The pods whose eni or host veth is not found is skipped, as they use other datapath.
+func checkPodIPRules(in *diagnoseInput) ([]string, error) {
+	if in.podsErr != nil {
+		return nil, in.podsErr
+	}
+	if in.hostErr != nil {
+		return nil, in.hostErr
+	}
+
+	actual := sets.New[string]()
+	for i := range in.host.rules {
+		actual.Insert(diagnoseRuleKey(&in.host.rules[i]))
+	}
+
+	var problems []string
+	for _, n := range podNetworks(in.pods) {
+		eni, ok := in.host.linkByMAC(n.eniMAC)
+		if !ok {
+			continue
+		}
+		if _, ok = in.host.linkByName(datapath.IPVlanSlaveName(eni.Attrs().Index)); ok {
+			continue
+		}
+		vethName, err := link.VethNameForPod(n.name, n.namespace, n.ifName, link.HostVethPrefix)
+		if err != nil {
+			continue
+		}
+		if _, ok = in.host.linkByName(vethName); !ok {
+			continue
+		}
+
+		table := driverutils.GetRouteTableID(eni.Attrs().Index)
+		for _, ip := range []net.IP{n.ip.IPv4, n.ip.IPv6} {
+			if ip == nil {
+				continue
+			}
+			for _, rule := range datapath.HostRulesForPolicy(&net.IPNet{IP: ip}, table) {
+				key := diagnoseRuleKey(rule)
+				if !actual.Has(key) {
+					problems = append(problems, fmt.Sprintf("pod %s ip %s has no rule \"%s\"", n.pod, ip, key))
+				}
+			}
+		}
+	}
+	return problems, nil
+}
+
+// checkOrphanVeth check every host veth is created for a pod in the db
+func checkOrphanVeth(in *diagnoseInput) ([]string, error) {
+	if in.podsErr != nil {
+		return nil, in.podsErr
+	}
+	if in.hostErr != nil {
+		return nil, in.hostErr
+	}
+
+	expected := sets.New[string]()
+	for _, pod := range in.pods {
+		if pod.PodInfo == nil {
+			continue
+		}
+		ifNames := sets.New[string](defaultIfName)
+		for _, item := range pod.Resources {
+			if item.IfName != "" {
+				ifNames.Insert(item.IfName)
+			}
+		}
+		for _, ifName := range sets.List(ifNames) {
+			name, err := link.VethNameForPod(pod.PodInfo.Name, pod.PodInfo.Namespace, ifName, link.HostVethPrefix)
+			if err == nil {
+				expected.Insert(name)
+			}
+		}
+	}
+	for _, n := range podNetworks(in.pods) {
+		name, err := link.VethNameForPod(n.name, n.namespace, n.ifName, link.HostVethPrefix)
+		if err == nil {
+			expected.Insert(name)
+		}
+	}
+
+	var problems []string
+	for _, l := range in.host.links {
+		if _, ok := l.(*netlink.Veth); !ok {
+			continue
+		}
+		name := l.Attrs().Name
+		if !strings.HasPrefix(name, link.HostVethPrefix) || expected.Has(name) {
+			continue
+		}
+		problems = append(problems, fmt.Sprintf("veth %s (index %d) does not belong to any pod", name, l.Attrs().Index))
+	}
+	return problems, nil
+}
+
+// checkMTU check the mtu of the secondary enis and the host veths
+func checkMTU(in *diagnoseInput) ([]string, error) {
+	if in.cniErr != nil {
+		return nil, in.cniErr
+	}
+	if in.metadataErr != nil {
+		return nil, in.metadataErr
+	}
+	if in.hostErr != nil {
+		return nil, in.hostErr
+	}
+
+	var problems []string
+	for _, eni := range in.metadata.ENIs {
+		if eni.Primary {
+			continue
+		}
+		l, ok := in.host.linkByMAC(eni.MAC)
+		if !ok {
+			continue
+		}
+		if l.Attrs().MTU != in.cniMTU {
+			problems = append(problems, fmt.Sprintf("eni %s (%s) mtu %d, expected %d", l.Attrs().Name, eni.MAC, l.Attrs().MTU, in.cniMTU))
+		}
+	}
+	for _, l := range in.host.links {
+		if _, ok := l.(*netlink.Veth); !ok || !strings.HasPrefix(l.Attrs().Name, link.HostVethPrefix) {
+			continue
+		}
+		if l.Attrs().MTU != in.cniMTU {
+			problems = append(problems, fmt.Sprintf("veth %s mtu %d, expected %d", l.Attrs().Name, l.Attrs().MTU, in.cniMTU))
+		}
+	}
+	return problems, nil
+}
+
+// checkTCFilters check the ipvlan redirect filters and the vlan tag filters on the eni for the pods
+func checkTCFilters(in *diagnoseInput) ([]string, error) {
+	if in.podsErr != nil {
+		return nil, in.podsErr
+	}
+	if in.hostErr != nil {
+		return nil, in.hostErr
+	}
+
+	var problems []string
+	for _, n := range podNetworks(in.pods) {
+		if n.ip.IPv4 == nil {
+			continue
+		}
+		eni, ok := in.host.linkByMAC(n.eniMAC)
+		if !ok {
+			continue
+		}
+		filters := in.host.filters[eni.Attrs().Index]
+
+		if slave, ok := in.host.linkByName(datapath.IPVlanSlaveName(eni.Attrs().Index)); ok {
+			found := false
+			for _, f := range filters {
+				dst, slaveIndex, ok := datapath.ParseIPVlanRedirectFilter(f)
+				if ok && dst.IP.Equal(n.ip.IPv4) && slaveIndex == slave.Attrs().Index {
+					found = true
+					break
+				}
+			}
+			if !found {
+				problems = append(problems, fmt.Sprintf("pod %s ip %s has no ipvlan redirect filter on %s", n.pod, n.ip.IPv4, eni.Attrs().Name))
+			}
+		}
+
+		if n.trunk && n.vid > 0 {
+			found := false
+			for _, f := range filters {
+				ip, vid, ok := datapath.ParseVlanTagFilter(f)
+				if ok && ip.Equal(n.ip.IPv4) && uint32(vid) == n.vid {
+					found = true
+					break
+				}
+			}
+			if !found {
+				problems = append(problems, fmt.Sprintf("pod %s ip %s has no vlan %d tag filter on %s", n.pod, n.ip.IPv4, n.vid, eni.Attrs().Name))
+			}
+		}
+	}
+	return problems, nil
+}
+
+func diagnoseRuleKey(rule *netlink.Rule) string {
+	src, dst := "all", "all"
+	if rule.Src != nil {
+		src = rule.Src.String()
+	}
+	if rule.Dst != nil {
+		dst = rule.Dst.String()
+	}
+	return fmt.Sprintf("%d: from %s to %s lookup %d", rule.Priority, src, dst, rule.Table)
+}
+
+// hostDumps return the output of ip and tc commands for the bundle
+func hostDumps(h *hostNetState) map[string][]byte {
+	commands := map[string][]string{
+		"links.txt":       {"ip", "-d", "link", "show"},
+		"addrs.txt":       {"ip", "addr", "show"},
+		"rules.txt":       {"ip", "rule", "show"},
+		"rules-ipv6.txt":  {"ip", "-6", "rule", "show"},
+		"routes.txt":      {"ip", "route", "show", "table", "all"},
+		"routes-ipv6.txt": {"ip", "-6", "route", "show", "table", "all"},
+	}
+	if h != nil {
+		for index := range h.filters {
+			for _, l := range h.links {
+				if l.Attrs().Index != index {
+					continue
+				}
+				commands[fmt.Sprintf("tc-filter-%s.txt", l.Attrs().Name)] = []string{"tc", "filter", "show", "dev", l.Attrs().Name, "egress"}
+			}
+		}
+	}
+
+	result := make(map[string][]byte, len(commands))
+	for name, args := range commands {
+		out, err := exec.Command(args[0], args[1:]...).CombinedOutput()
+		if err != nil {
+			out = append(out, []byte(fmt.Sprintf("\nerror run %s, %s\n", strings.Join(args, " "), err))...)
+		}
+		result[name] = out
+	}
+	return result
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/AliyunContainerService/terway/pkg/link"
+	"github.com/AliyunContainerService/terway/plugin/datapath"
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+func newTestNetConfPod(t *testing.T, name, ipv4, mac string, vid uint32) daemon.PodResources {
+	netConf, err := json.Marshal([]*rpc.NetConf{{
+		BasicInfo: &rpc.BasicInfo{PodIP: &rpc.IPSet{IPv4: ipv4}},
+		ENIInfo:   &rpc.ENIInfo{MAC: mac, Trunk: vid > 0, Vid: vid},
+		IfName:    "eth0",
+	}})
+	require.NoError(t, err)
+	return daemon.PodResources{
+		PodInfo: &daemon.PodInfo{Namespace: "default", Name: name},
+		NetConf: string(netConf),
+	}
+}
+
+func newTestVeth(t *testing.T, name string, index, mtu int) *netlink.Veth {
+	vethName, err := link.VethNameForPod(name, "default", "eth0", link.HostVethPrefix)
+	require.NoError(t, err)
+	return &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: vethName, Index: index, MTU: mtu}}
+}
+
+func newTestENI(name string, index, mtu int, mac string) *netlink.Device {
+	hw, _ := net.ParseMAC(mac)
+	return &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: name, Index: index, MTU: mtu, HardwareAddr: hw}}
+}
+
+func TestCheckPodIPRules(t *testing.T) {
+	eni := newTestENI("eth1", 3, 1500, "00:00:00:00:00:01")
+	in := &diagnoseInput{
+		pods: []daemon.PodResources{
+			newTestNetConfPod(t, "a", "192.168.0.1", "00:00:00:00:00:01", 0),
+			newTestNetConfPod(t, "b", "192.168.0.2", "00:00:00:00:00:01", 0),
+			// the veth is not found
+			newTestNetConfPod(t, "c", "192.168.0.3", "00:00:00:00:00:01", 0),
+		},
+		host: &hostNetState{
+			links: []netlink.Link{eni, newTestVeth(t, "a", 10, 1500), newTestVeth(t, "b", 11, 1500)},
+		},
+	}
+	for _, r := range datapath.HostRulesForPolicy(&net.IPNet{IP: net.ParseIP("192.168.0.1")}, 1003) {
+		in.host.rules = append(in.host.rules, *r)
+	}
+	in.host.rules = append(in.host.rules, *datapath.HostRulesForPolicy(&net.IPNet{IP: net.ParseIP("192.168.0.2")}, 1003)[0])
+
+	problems, err := checkPodIPRules(in)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`pod default/b ip 192.168.0.2 has no rule "2048: from 192.168.0.2/32 to all lookup 1003"`}, problems)
+}
+
+func TestCheckOrphanVeth(t *testing.T) {
+	orphan := newTestVeth(t, "deleted", 12, 1500)
+	in := &diagnoseInput{
+		pods: []daemon.PodResources{
+			newTestNetConfPod(t, "a", "192.168.0.1", "00:00:00:00:00:01", 0),
+			// the pod without net conf
+			newTestPodResources("default", "b", "192.168.0.2"),
+		},
+		host: &hostNetState{
+			links: []netlink.Link{
+				newTestVeth(t, "a", 10, 1500),
+				newTestVeth(t, "b", 11, 1500),
+				orphan,
+				&netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "lxc123", Index: 13}},
+			},
+		},
+	}
+
+	problems, err := checkOrphanVeth(in)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"veth " + orphan.Name + " (index 12) does not belong to any pod"}, problems)
+}
+
+func TestCheckMTU(t *testing.T) {
+	in := &diagnoseInput{
+		cniMTU: 1500,
+		metadata: &metadataOutput{ENIs: []metadataENIOutput{
+			{MAC: "00:00:00:00:00:00", Primary: true},
+			{MAC: "00:00:00:00:00:01"},
+			{MAC: "00:00:00:00:00:02"},
+		}},
+		host: &hostNetState{
+			links: []netlink.Link{
+				newTestENI("eth0", 2, 9000, "00:00:00:00:00:00"),
+				newTestENI("eth1", 3, 1500, "00:00:00:00:00:01"),
+				newTestENI("eth2", 4, 9000, "00:00:00:00:00:02"),
+				newTestVeth(t, "a", 10, 1400),
+			},
+		},
+	}
+
+	problems, err := checkMTU(in)
+	assert.NoError(t, err)
+	assert.Len(t, problems, 2)
+	assert.Equal(t, "eni eth2 (00:00:00:00:00:02) mtu 9000, expected 1500", problems[0])
+}
+
+func TestCheckTCFilters(t *testing.T) {
+	ipvlanENI := newTestENI("eth1", 3, 1500, "00:00:00:00:00:01")
+	trunkENI := newTestENI("eth2", 4, 1500, "00:00:00:00:00:02")
+	slave := &netlink.IPVlan{LinkAttrs: netlink.LinkAttrs{Name: datapath.IPVlanSlaveName(3), Index: 20}}
+
+	redirect, err := datapath.IPVlanRedirectFilter(3, &net.IPNet{IP: net.ParseIP("192.168.0.1"), Mask: net.CIDRMask(32, 32)}, 20)
+	require.NoError(t, err)
+	vlanAct := netlink.NewVlanKeyAction()
+	vlanAct.Vid = 100
+	vlanTag := &netlink.U32{
+		FilterAttrs: netlink.FilterAttrs{LinkIndex: 4, Priority: datapath.VlanTagFilterPriority, Protocol: unix.ETH_P_IP},
+		Sel: &netlink.TcU32Sel{
+			Keys: []netlink.TcU32Key{{Off: 12, Val: binary.BigEndian.Uint32(net.ParseIP("192.168.0.3").To4()), Mask: 0xffffffff}},
+		},
+		Actions: []netlink.Action{vlanAct},
+	}
+
+	in := &diagnoseInput{
+		pods: []daemon.PodResources{
+			newTestNetConfPod(t, "a", "192.168.0.1", "00:00:00:00:00:01", 0),
+			newTestNetConfPod(t, "b", "192.168.0.2", "00:00:00:00:00:01", 0),
+			newTestNetConfPod(t, "c", "192.168.0.3", "00:00:00:00:00:02", 100),
+			newTestNetConfPod(t, "d", "192.168.0.4", "00:00:00:00:00:02", 101),
+		},
+		host: &hostNetState{
+			links: []netlink.Link{ipvlanENI, trunkENI, slave},
+			filters: map[int][]netlink.Filter{
+				3: {redirect},
+				4: {vlanTag},
+			},
+		},
+	}
+
+	problems, err := checkTCFilters(in)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"pod default/b ip 192.168.0.2 has no ipvlan redirect filter on eth1",
+		"pod default/d ip 192.168.0.4 has no vlan 101 tag filter on eth2",
+	}, problems)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+func TestRunDiagnoseChecks(t *testing.T) {
+	checks := []diagnoseCheck{
+		{name: "pass", run: func(in *diagnoseInput) ([]string, error) { return nil, nil }},
+		{name: "fail", hint: "fix it", run: func(in *diagnoseInput) ([]string, error) { return []string{"broken"}, nil }},
+		{name: "skip", hint: "fix it", run: func(in *diagnoseInput) ([]string, error) { return nil, errors.New("no state") }},
+	}
+
+	result := runDiagnoseChecks(checks, &diagnoseInput{})
+	assert.Equal(t, []checkResultOutput{
+		{Name: "pass", Status: checkPass, Problems: []string{}},
+		{Name: "fail", Status: checkFail, Problems: []string{"broken"}, Hint: "fix it"},
+		{Name: "skip", Status: checkSkip, Problems: []string{"no state"}},
+	}, result)
+}
+
+func TestCheckENIInMetadata(t *testing.T) {
+	in := &diagnoseInput{
+		mappings: []*rpc.ResourceMapping{
+			{NetworkInterfaceID: "eni-1", MAC: "00:00:00:00:00:01"},
+			{NetworkInterfaceID: "eni-2", MAC: "00:00:00:00:00:02"},
+			{NetworkInterfaceID: "eni-3"},
+		},
+		metadata: &metadataOutput{ENIs: []metadataENIOutput{{MAC: "00:00:00:00:00:01"}}},
+	}
+	problems, err := checkENIInMetadata(in)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"eni eni-2 (00:00:00:00:00:02) is not found in metadata"}, problems)
+
+	in.mappingsErr = errors.New("daemon is down")
+	_, err = checkENIInMetadata(in)
+	assert.Error(t, err)
+}
+
+func TestLoadCNIConfig(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, terwayCNIConfFile), []byte(`{"plugins":[{"type":"terway","mtu":9000},{"type":"cilium-cni"}]}`), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "README"), []byte("foo"), 0644))
+
+	files, mtu, err := loadCNIConfig(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 9000, mtu)
+	assert.Len(t, files, 1)
+
+	mtu, err = parseTerwayMTU([]byte(`{"plugins":[{"type":"terway"}]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, defaultCNIMTU, mtu)
+
+	_, err = parseTerwayMTU([]byte(`{"plugins":[{"type":"bridge"}]}`))
+	assert.Error(t, err)
+
+	_, _, err = loadCNIConfig(t.TempDir())
+	assert.Error(t, err)
+}
+
+func TestLoadPodResourcesSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	s, err := daemon.NewResDB(path)
+	assert.NoError(t, err)
+	_, err = importPodResources(s, []daemon.PodResources{
+		newTestPodResources("default", "a", "192.168.0.1"),
+	}, false)
+	assert.NoError(t, err)
+
+	// the db is held by s
+	resources, err := loadPodResourcesSnapshot(path)
+	assert.NoError(t, err)
+	assert.Len(t, resources, 1)
+	assert.Equal(t, "a", resources[0].PodInfo.Name)
+
+	// the db without schema version is not migrated
+	legacy := filepath.Join(t.TempDir(), "legacy.db")
+	s, err = storage.NewDiskStorage(daemon.ResDBName, legacy, daemon.SerializePodResources, daemon.DeserializePodResources)
+	assert.NoError(t, err)
+	_ = s.Put("default/a", newTestPodResources("default", "a", "192.168.0.1"))
+	_, err = loadPodResourcesSnapshot(legacy)
+	assert.ErrorIs(t, err, storage.ErrSchemaMismatch)
+}
+
+func TestWriteDiagnoseBundle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	in := &diagnoseInput{
+		mappings:    []*rpc.ResourceMapping{{NetworkInterfaceID: "eni-1"}},
+		metadataErr: errors.New("metadata is not available"),
+		cniFiles:    map[string][]byte{terwayCNIConfFile: []byte("{}")},
+		pods:        []daemon.PodResources{newTestPodResources("default", "a", "192.168.0.1")},
+		hostErr:     errors.New("not supported"),
+	}
+	err := writeDiagnoseBundle(path, in, diagnoseReportOutput{Checks: []checkResultOutput{}, Bundle: path})
+	assert.NoError(t, err)
+
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	assert.NoError(t, err)
+	tr := tar.NewReader(gr)
+
+	files := make(map[string]string)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		data, err := io.ReadAll(tr)
+		assert.NoError(t, err)
+		files[hdr.Name] = string(data)
+	}
+
+	assert.Contains(t, files, "report.json")
+	assert.Contains(t, files, "mapping.json")
+	assert.Contains(t, files, "db.json")
+	assert.Contains(t, files, filepath.Join("cni", terwayCNIConfFile))
+	assert.Equal(t, "metadata is not available\n", files["metadata.error"])
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+type hostNetState struct{}
+
+func gatherHostNetState() (*hostNetState, error) {
+	return nil, errors.New("host netlink state is not supported on this platform")
+}
+
+func hostChecks() []diagnoseCheck {
+	return nil
+}
+
+func hostDumps(h *hostNetState) map[string][]byte {
+	return nil
+}
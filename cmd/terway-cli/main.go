@@ -32,6 +32,11 @@ var (
 		Use:   "terway-cli",
 		Short: "terway-cil is a command tool for diagnosing terway & network internal status.",
 		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			err := validateOutputFormat(outputFormat)
+			if err != nil {
+				return err
+			}
+
 			// create connection and grpc client
 			ctx, contextCancel = context.WithTimeout(context.Background(), connTimeout)
 			conn, err := grpc.DialContext(ctx, defaultSocketPath, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithContextDialer(
@@ -83,7 +88,9 @@ var (
 	executeCmd = &cobra.Command{
 		Use:   "execute <type> <resource> <command> [args...]",
 		Short: "send command to the given resource.",
-		RunE:  runExecute,
+		Long: "send command to the given resource.\n" +
+			"Run `execute network_service default probe <namespace/name>` to probe the gateway, dns, service and node from the pod netns.",
+		RunE: runExecute,
 	}
 
 	metadataCmd = &cobra.Command{
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/AliyunContainerService/terway/rpc"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+
+	// outputAPIVersion is the version of the json and yaml schema.
+	// Fields can be added in the same version, bump it if any field is removed or changed.
+	outputAPIVersion = "terway-cli/v1"
+)
+
+// the kind of the document
+const (
+	kindTypeList        = "TypeList"
+	kindResourceList    = "ResourceList"
+	kindResource        = "Resource"
+	kindResourceMapping = "ResourceMapping"
+	kindExecuteResult   = "ExecuteResult"
+	kindMetadata        = "Metadata"
+	kindPodResourceList = "PodResourceList"
+	kindPodResource     = "PodResource"
+	kindDBVerifyResult  = "DBVerifyResult"
+	kindDiagnoseReport  = "DiagnoseReport"
+
+	kindPodNetworkingMatch = "PodNetworkingMatch"
+)
+
+var outputFormat string
+
+func init() {
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", outputTable, "output format, one of table, json or yaml")
+}
+
+// document is the envelope of the json and yaml output
+type document struct {
+	APIVersion string      `json:"apiVersion"`
+	Kind       string      `json:"kind"`
+	Spec       interface{} `json:"spec"`
+}
+
+type typeListOutput struct {
+	Types []string `json:"types"`
+}
+
+type resourceListOutput struct {
+	Type      string   `json:"type"`
+	Resources []string `json:"resources"`
+}
+
+type keyValueOutput struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type resourceOutput struct {
+	Type   string           `json:"type"`
+	Name   string           `json:"name"`
+	Config []keyValueOutput `json:"config"`
+	Trace  []keyValueOutput `json:"trace"`
+}
+
+type mappingOutput struct {
+	Slot                 int      `json:"slot"`
+	NetworkInterfaceID   string   `json:"networkInterfaceID"`
+	MAC                  string   `json:"mac"`
+	Status               string   `json:"status"`
+	Type                 string   `json:"type"`
+	AllocInhibitExpireAt string   `json:"allocInhibitExpireAt"`
+	Info                 []string `json:"info"`
+}
+
+type resourceMappingOutput struct {
+	Mappings []mappingOutput `json:"mappings"`
+}
+
+type executeResultOutput struct {
+	Type     string   `json:"type"`
+	Name     string   `json:"name"`
+	Command  string   `json:"command"`
+	Messages []string `json:"messages"`
+}
+
+type metadataENIOutput struct {
+	MAC            string   `json:"mac"`
+	Primary        bool     `json:"primary"`
+	Interface      string   `json:"interface"` // empty if the interface is not found
+	PrimaryIPv4    string   `json:"primaryIPv4"`
+	SecondaryIPv4s []string `json:"secondaryIPv4s"`
+	IPv6s          []string `json:"ipv6s"`
+}
+
+type metadataOutput struct {
+	VSwitch string              `json:"vSwitch"`
+	ENIs    []metadataENIOutput `json:"enis"`
+}
+
+type podResourceItemOutput struct {
+	Pod       string `json:"pod"`
+	Type      string `json:"type"`
+	ENI       string `json:"eni"`
+	IPv4      string `json:"ipv4"`
+	IPv6      string `json:"ipv6"`
+	NetNs     string `json:"netns"`
+	ReleaseIn string `json:"releaseIn"` // the remaining time the ip is held for the deleted pod
+}
+
+type podResourceListOutput struct {
+	Items []podResourceItemOutput `json:"items"`
+}
+
+type dbVerifyOutput struct {
+	Total        int              `json:"total"`
+	Inconsistent []dbVerifyResult `json:"inconsistent"`
+}
+
+type checkResultOutput struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Status      string   `json:"status"` // one of pass, fail or skip
+	Problems    []string `json:"problems"`
+	Hint        string   `json:"hint"` // how to fix, only set if failed
+}
+
+type diagnoseReportOutput struct {
+	Checks []checkResultOutput `json:"checks"`
+	Bundle string              `json:"bundle"` // path of the bundle, empty if not created
+}
+
+type podNetworkingMatchItemOutput struct {
+	Pod           string   `json:"pod"`
+	PodNetworking string   `json:"podNetworking"` // the PodNetworking would be used, empty if none is matched
+	Current       string   `json:"current"`       // the PodNetworking the pod is using
+	Others        []string `json:"others"`        // matched but with lower priority
+}
+
+type podNetworkingMatchOutput struct {
+	Items []podNetworkingMatchItemOutput `json:"items"`
+}
+
+func validateOutputFormat(format string) error {
+	switch format {
+	case outputTable, outputJSON, outputYAML:
+		return nil
+	}
+	return fmt.Errorf("unsupported output format %s, should be one of table, json or yaml", format)
+}
+
+// structuredOutput return true if the output is json or yaml
+func structuredOutput() bool {
+	return outputFormat == outputJSON || outputFormat == outputYAML
+}
+
+// writeDocument write the spec in the json or yaml schema
+func writeDocument(w io.Writer, format, kind string, spec interface{}) error {
+	doc := document{
+		APIVersion: outputAPIVersion,
+		Kind:       kind,
+		Spec:       spec,
+	}
+
+	var (
+		out []byte
+		err error
+	)
+	switch format {
+	case outputJSON:
+		out, err = json.MarshalIndent(doc, "", "  ")
+		out = append(out, '\n')
+	case outputYAML:
+		out, err = yaml.Marshal(doc)
+	default:
+		return fmt.Errorf("unsupported output format %s", format)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+func toKeyValueOutput(entries []*rpc.MapKeyValueEntry) []keyValueOutput {
+	result := make([]keyValueOutput, 0, len(entries))
+	for _, e := range entries {
+		result = append(result, keyValueOutput{Key: e.Key, Value: e.Value})
+	}
+	return result
+}
+
+func toResourceMappingOutput(mappings []*rpc.ResourceMapping) resourceMappingOutput {
+	result := resourceMappingOutput{Mappings: make([]mappingOutput, 0, len(mappings))}
+	for i, m := range mappings {
+		info := m.Info
+		if info == nil {
+			info = []string{}
+		}
+		result.Mappings = append(result.Mappings, mappingOutput{
+			Slot:                 i,
+			NetworkInterfaceID:   m.NetworkInterfaceID,
+			MAC:                  m.MAC,
+			Status:               m.Status,
+			Type:                 m.Type,
+			AllocInhibitExpireAt: m.AllocInhibitExpireAt,
+			Info:                 info,
+		})
+	}
+	return result
+}
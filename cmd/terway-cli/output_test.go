@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files")
+
+// assertGolden compare the output with testdata/<name>.golden, run with -update to regenerate
+func assertGolden(t *testing.T, name string, out []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+	if *updateGolden {
+		assert.NoError(t, os.MkdirAll("testdata", 0755))
+		assert.NoError(t, os.WriteFile(path, out, 0644))
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, string(expected), string(out))
+}
+
+func TestWriteDocumentGolden(t *testing.T) {
+	netns := "/var/run/netns/cni-1"
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name string
+		kind string
+		spec interface{}
+	}{
+		{
+			name: "type_list",
+			kind: kindTypeList,
+			spec: typeListOutput{Types: []string{"network_service", "eni_manager"}},
+		},
+		{
+			name: "resource_list",
+			kind: kindResourceList,
+			spec: resourceListOutput{Type: "network_service", Resources: nonNil(nil)},
+		},
+		{
+			name: "resource",
+			kind: kindResource,
+			spec: resourceOutput{
+				Type: "network_service",
+				Name: "default",
+				Config: toKeyValueOutput([]*rpc.MapKeyValueEntry{
+					{Key: "name", Value: "default"},
+					{Key: "daemon_mode", Value: "ENIMultiIP"},
+				}),
+				Trace: toKeyValueOutput([]*rpc.MapKeyValueEntry{
+					{Key: "pods/default/sts-0/resources", Value: "(eniIp)00:00:00:00:00:01.192.0.2.1"},
+					{Key: "pods/default/sts-0/ip_release_in", Value: "4m30s"},
+				}),
+			},
+		},
+		{
+			name: "resource_mapping",
+			kind: kindResourceMapping,
+			spec: toResourceMappingOutput([]*rpc.ResourceMapping{
+				{NetworkInterfaceID: "eni-1", MAC: "00:00:00:00:00:01", Type: "secondary", Status: "inUse", Info: []string{"192.0.2.1 default/sts-0"}},
+				{NetworkInterfaceID: "eni-2", MAC: "00:00:00:00:00:02", Type: "trunk", Status: "inUse"},
+			}),
+		},
+		{
+			name: "execute_result",
+			kind: kindExecuteResult,
+			spec: executeResultOutput{Type: "network_service", Name: "default", Command: "mapping", Messages: []string{"mapping: [], err: %!s(<nil>)\n"}},
+		},
+		{
+			name: "metadata",
+			kind: kindMetadata,
+			spec: &metadataOutput{
+				VSwitch: "vsw-1",
+				ENIs: []metadataENIOutput{
+					{MAC: "00:00:00:00:00:01", Primary: true, Interface: "eth0", PrimaryIPv4: "192.0.2.100", SecondaryIPv4s: []string{"192.0.2.1"}, IPv6s: []string{}},
+					{MAC: "00:00:00:00:00:02", PrimaryIPv4: "192.0.2.200", SecondaryIPv4s: []string{}, IPv6s: []string{"fd00::1"}},
+				},
+			},
+		},
+		{
+			name: "pod_resource_list",
+			kind: kindPodResourceList,
+			spec: toPodResourceListOutput([]daemon.PodResources{
+				{
+					PodInfo:   &daemon.PodInfo{Namespace: "default", Name: "sts-0", IPReleaseAt: now.Add(time.Minute)},
+					Resources: []daemon.ResourceItem{{Type: daemon.ResourceTypeENIIP, ENIID: "eni-1", IPv4: "192.0.2.1"}},
+					NetNs:     &netns,
+				},
+			}, now),
+		},
+		{
+			name: "db_verify",
+			kind: kindDBVerifyResult,
+			spec: dbVerifyOutput{Total: 2, Inconsistent: []dbVerifyResult{{Key: "default/sts-0", Problems: []string{"ip not found"}}}},
+		},
+	}
+
+	for _, c := range cases {
+		for _, format := range []string{outputJSON, outputYAML} {
+			t.Run(c.name+"_"+format, func(t *testing.T) {
+				buf := &bytes.Buffer{}
+				err := writeDocument(buf, format, c.kind, c.spec)
+				assert.NoError(t, err)
+				assertGolden(t, c.name+"."+format, buf.Bytes())
+			})
+		}
+	}
+}
+
+func TestValidateOutputFormat(t *testing.T) {
+	for _, format := range []string{outputTable, outputJSON, outputYAML} {
+		assert.NoError(t, validateOutputFormat(format))
+	}
+	assert.Error(t, validateOutputFormat("xml"))
+
+	err := writeDocument(&bytes.Buffer{}, outputTable, kindTypeList, typeListOutput{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	ctrl "sigs.k8s.io/controller-runtime"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/pkg/controller/common"
+	"github.com/AliyunContainerService/terway/types"
+)
+
+var (
+	kubeconfig             string
+	podNetworkingNamespace string
+)
+
+// the podnetworking commands talk to the apiserver, the daemon is not required
+var (
+	podNetworkingCmd = &cobra.Command{
+		Use:   "podnetworking",
+		Short: "inspect the PodNetworking in the cluster.",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return validateOutputFormat(outputFormat)
+		},
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {},
+	}
+
+	podNetworkingMatchCmd = &cobra.Command{
+		Use:   "match",
+		Short: "dry run, show which PodNetworking each existing pod would match.",
+		Long: "dry run, show which PodNetworking each existing pod would match if it is created now.\n" +
+			"The current column is the PodNetworking the pod is using, the others column is the matched PodNetworking with lower priority.",
+		Args: cobra.NoArgs,
+		RunE: runPodNetworkingMatch,
+	}
+)
+
+func init() {
+	podNetworkingCmd.PersistentFlags().StringVar(&kubeconfig, "kubeconfig", "", "path to the kubeconfig, use the in-cluster config if not set")
+	podNetworkingMatchCmd.Flags().StringVarP(&podNetworkingNamespace, "namespace", "n", "", "only show the pods in the namespace")
+
+	podNetworkingCmd.AddCommand(podNetworkingMatchCmd)
+	rootCmd.AddCommand(podNetworkingCmd)
+}
+
+func newK8sClient() (k8sclient.Client, error) {
+	var (
+		restConfig *rest.Config
+		err        error
+	)
+	if kubeconfig != "" {
+		restConfig, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
+	} else {
+		restConfig, err = ctrl.GetConfig()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := runtime.NewScheme()
+	err = clientgoscheme.AddToScheme(scheme)
+	if err != nil {
+		return nil, err
+	}
+	err = v1beta1.AddToScheme(scheme)
+	if err != nil {
+		return nil, err
+	}
+	return k8sclient.New(restConfig, k8sclient.Options{Scheme: scheme})
+}
+
+func runPodNetworkingMatch(cmd *cobra.Command, args []string) error {
+	c, err := newK8sClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), connTimeout)
+	defer cancel()
+
+	podNetworkings := &v1beta1.PodNetworkingList{}
+	err = c.List(ctx, podNetworkings)
+	if err != nil {
+		return fmt.Errorf("error list podNetworking, %w", err)
+	}
+	namespaces := &corev1.NamespaceList{}
+	err = c.List(ctx, namespaces)
+	if err != nil {
+		return fmt.Errorf("error list namespace, %w", err)
+	}
+	pods := &corev1.PodList{}
+	err = c.List(ctx, pods, k8sclient.InNamespace(podNetworkingNamespace))
+	if err != nil {
+		return fmt.Errorf("error list pod, %w", err)
+	}
+
+	result, err := matchPodNetworkings(pods.Items, namespaces.Items, podNetworkings.Items)
+	if err != nil {
+		return err
+	}
+	if structuredOutput() {
+		return writeDocument(cmd.OutOrStdout(), outputFormat, kindPodNetworkingMatch, result)
+	}
+
+	data := pterm.TableData{{"POD", "PODNETWORKING", "CURRENT", "OTHERS"}}
+	for _, item := range result.Items {
+		data = append(data, []string{item.Pod, item.PodNetworking, item.Current, strings.Join(item.Others, ",")})
+	}
+	return pterm.DefaultTable.WithHasHeader().WithData(data).Render()
+}
+
+// matchPodNetworkings match the pods with the rule of the webhook, pods not managed by terway are skipped
+func matchPodNetworkings(pods []corev1.Pod, namespaces []corev1.Namespace, podNetworkings []v1beta1.PodNetworking) (podNetworkingMatchOutput, error) {
+	nsByName := make(map[string]*corev1.Namespace, len(namespaces))
+	for i := range namespaces {
+		nsByName[namespaces[i].Name] = &namespaces[i]
+	}
+
+	result := podNetworkingMatchOutput{Items: []podNetworkingMatchItemOutput{}}
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Spec.HostNetwork || types.IgnoredByTerway(pod.Labels) {
+			continue
+		}
+		item := podNetworkingMatchItemOutput{
+			Pod:     pod.Namespace + "/" + pod.Name,
+			Current: pod.Annotations[types.PodNetworking],
+			Others:  []string{},
+		}
+		ns, ok := nsByName[pod.Namespace]
+		if !ok {
+			ns = &corev1.Namespace{}
+		}
+		matched, err := common.MatchPodNetworkings(pod, ns, podNetworkings)
+		if err != nil {
+			return result, fmt.Errorf("error match pod %s, %w", item.Pod, err)
+		}
+		for j, pn := range matched {
+			if j == 0 {
+				item.PodNetworking = pn.Name
+				continue
+			}
+			item.Others = append(item.Others, pn.Name)
+		}
+		result.Items = append(result.Items, item)
+	}
+
+	sort.Slice(result.Items, func(i, j int) bool {
+		return result.Items[i].Pod < result.Items[j].Pod
+	})
+	return result, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/types"
+)
+
+func TestMatchPodNetworkings(t *testing.T) {
+	newPodNetworking := func(name string, priority int32, nsLabels map[string]string) v1beta1.PodNetworking {
+		return v1beta1.PodNetworking{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1beta1.PodNetworkingSpec{
+				Selector: v1beta1.Selector{NamespaceSelector: &metav1.LabelSelector{MatchLabels: nsLabels}},
+				Priority: priority,
+			},
+			Status: v1beta1.PodNetworkingStatus{Status: v1beta1.NetworkingStatusReady},
+		}
+	}
+	newPod := func(namespace, name string) corev1.Pod {
+		return corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace:       namespace,
+			Name:            name,
+			OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "rs"}},
+		}}
+	}
+
+	namespaces := []corev1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "prod", Labels: map[string]string{"env": "prod"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "dev"}},
+	}
+	podNetworkings := []v1beta1.PodNetworking{
+		newPodNetworking("all", 0, map[string]string{}),
+		newPodNetworking("prod", 1, map[string]string{"env": "prod"}),
+	}
+	current := newPod("prod", "b")
+	current.Annotations = map[string]string{types.PodNetworking: "all"}
+	hostNetwork := newPod("prod", "host")
+	hostNetwork.Spec.HostNetwork = true
+
+	result, err := matchPodNetworkings([]corev1.Pod{current, newPod("dev", "a"), hostNetwork}, namespaces, podNetworkings)
+	require.NoError(t, err)
+	assert.Equal(t, []podNetworkingMatchItemOutput{
+		{Pod: "dev/a", PodNetworking: "all", Others: []string{}},
+		{Pod: "prod/b", PodNetworking: "prod", Current: "all", Others: []string{"all"}},
+	}, result.Items)
+}
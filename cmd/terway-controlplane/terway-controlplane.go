@@ -63,6 +63,8 @@ func init() {
 	utilruntime.Must(networkv1beta1.AddToScheme(scheme))
 
 	metrics.Registry.MustRegister(metric.OpenAPILatency)
+	metrics.Registry.MustRegister(metric.VSwitchAvailableIPCount)
+	metrics.Registry.MustRegister(metric.VSwitchExhaustSeconds)
 }
 
 func main() {
@@ -114,6 +116,11 @@ func main() {
 		log.Error(err, "unable sync crd")
 		os.Exit(1)
 	}
+	err = crds.CreateOrUpdateCRD(ctx, directClient, crds.CRDIPReservation)
+	if err != nil {
+		log.Error(err, "unable sync crd")
+		os.Exit(1)
+	}
 
 	ws := wh.NewServer(wh.Options{
 		Port:    cfg.WebhookPort,
@@ -170,7 +177,7 @@ func main() {
 
 	if !cfg.DisableWebhook {
 		mgr.GetWebhookServer().Register("/mutating", webhook.MutatingHook(mgr.GetClient()))
-		mgr.GetWebhookServer().Register("/validate", webhook.ValidateHook())
+		mgr.GetWebhookServer().Register("/validate", webhook.ValidateHook(mgr.GetClient(), aliyunClient))
 	}
 
 	vSwitchCtrl, err := vswitch.NewSwitchPool(cfg.VSwitchPoolSize, cfg.VSwitchCacheTTL)
@@ -2,6 +2,8 @@ package daemon
 
 import (
 	"context"
+	"fmt"
+	"net/netip"
 
 	"github.com/AliyunContainerService/terway/pkg/aliyun/client"
 	"github.com/AliyunContainerService/terway/pkg/aliyun/instance"
@@ -27,10 +29,21 @@ func getDynamicConfig(ctx context.Context, k8s k8s.Kubernetes) (string, string,
 
 func getENIConfig(cfg *daemon.Config) *types.ENIConfig {
 	vswitchSelectionPolicy := vswitch.VSwitchSelectionPolicyRandom
-	switch cfg.VSwitchSelectionPolicy {
-	case "ordered":
+	switch policy, _ := vswitch.ParseSelectionPolicy(cfg.VSwitchSelectionPolicy); policy {
+	case vswitch.VSwitchSelectionPolicyOrdered:
 		// keep the previous behave
 		vswitchSelectionPolicy = vswitch.VSwitchSelectionPolicyMost
+	case vswitch.VSwitchSelectionPolicyWeighted, vswitch.VSwitchSelectionPolicyLeastUsedByNode, vswitch.VSwitchSelectionPolicyPreferCIDR:
+		vswitchSelectionPolicy = policy
+	}
+
+	var preferCIDRs []netip.Prefix
+	for _, cidr := range cfg.VSwitchPreferCIDRs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			continue
+		}
+		preferCIDRs = append(preferCIDRs, prefix.Masked())
 	}
 
 	eniSelectionPolicy := types.EniSelectionPolicyMostIPs
@@ -47,6 +60,8 @@ func getENIConfig(cfg *daemon.Config) *types.ENIConfig {
 		InstanceID:             instance.GetInstanceMeta().InstanceID,
 		VSwitchSelectionPolicy: vswitchSelectionPolicy,
 		EniSelectionPolicy:     eniSelectionPolicy,
+		VSwitchWeights:         cfg.VSwitchWeights,
+		VSwitchPreferCIDRs:     preferCIDRs,
 		ResourceGroupID:        cfg.ResourceGroupID,
 		EniTypeAttr:            0,
 	}
@@ -140,6 +155,12 @@ func getPoolConfig(cfg *daemon.Config, daemonMode string, limit *client.Limits)
 			ipPerENI--
 		}
 
+		if cfg.EnableIPv4Prefix && !utils.IsWindowsOS() {
+			// the primary ip take one slot, the rest slots are used by /28 prefix
+			poolConfig.IPv4PrefixPerENI = limit.IPv4PerAdapter - 1
+			ipPerENI = 1 + poolConfig.IPv4PrefixPerENI*16
+		}
+
 		capacity = maxENI * ipPerENI
 		if cfg.MaxPoolSize > capacity {
 			poolConfig.MaxPoolSize = capacity
@@ -159,15 +180,76 @@ func getPoolConfig(cfg *daemon.Config, daemonMode string, limit *client.Limits)
 		maxMemberENI = limit.MemberAdapterLimit
 
 		poolConfig.MaxIPPerENI = ipPerENI
+		if poolConfig.IPv4PrefixPerENI > 0 {
+			// keep the per ip limit for eni not using prefix
+			poolConfig.MaxIPPerENI = limit.IPv4PerAdapter
+		}
 
 		if cfg.EnableERDMA {
 			poolConfig.ERdmaCapacity = limit.ERdmaAdapters * limit.IPv4PerAdapter
 		}
 	}
 
+	if cfg.WarmENITarget < 0 || cfg.WarmIPTarget < 0 || cfg.MinimumIPTarget < 0 {
+		return nil, fmt.Errorf("warm_eni_target, warm_ip_target and minimum_ip_target must not be negative")
+	}
+
+	if cfg.WarmENITarget > 0 || cfg.WarmIPTarget > 0 || cfg.MinimumIPTarget > 0 {
+		if daemonMode != daemon.ModeENIMultiIP {
+			return nil, fmt.Errorf("warm_eni_target, warm_ip_target and minimum_ip_target is only supported in %s mode", daemon.ModeENIMultiIP)
+		}
+		if cfg.WarmENITarget > maxENI {
+			return nil, fmt.Errorf("warm_eni_target %d exceed the max eni %d", cfg.WarmENITarget, maxENI)
+		}
+		if cfg.WarmIPTarget > capacity {
+			return nil, fmt.Errorf("warm_ip_target %d exceed the capacity %d", cfg.WarmIPTarget, capacity)
+		}
+		if cfg.MinimumIPTarget > capacity {
+			return nil, fmt.Errorf("minimum_ip_target %d exceed the capacity %d", cfg.MinimumIPTarget, capacity)
+		}
+
+		// warm_ip_target take precedence over min_pool_size and min_eni
+		if cfg.WarmIPTarget > 0 {
+			poolConfig.MinPoolSize = cfg.WarmIPTarget
+			if poolConfig.MaxPoolSize < cfg.WarmIPTarget {
+				poolConfig.MaxPoolSize = cfg.WarmIPTarget
+			}
+		}
+		// the primary ip of the warm eni is idle, keep them in the pool
+		if poolConfig.MaxPoolSize < cfg.WarmENITarget {
+			poolConfig.MaxPoolSize = cfg.WarmENITarget
+		}
+
+		poolConfig.WarmENITarget = cfg.WarmENITarget
+		poolConfig.MinimumIPTarget = cfg.MinimumIPTarget
+	}
+
+	for _, pool := range cfg.SecurityGroupPools {
+		if daemonMode != daemon.ModeENIMultiIP {
+			return nil, fmt.Errorf("security_group_pools is only supported in %s mode", daemon.ModeENIMultiIP)
+		}
+		if len(pool.SecurityGroupIDs) == 0 {
+			return nil, fmt.Errorf("security_group_ids is required in security_group_pools")
+		}
+		if pool.MinPoolSize < 0 || pool.MaxPoolSize < 0 || pool.MinPoolSize > pool.MaxPoolSize {
+			return nil, fmt.Errorf("invalid pool size of security groups %v, min %d max %d", pool.SecurityGroupIDs, pool.MinPoolSize, pool.MaxPoolSize)
+		}
+		if pool.MaxPoolSize > capacity {
+			return nil, fmt.Errorf("max_pool_size %d of security groups %v exceed the capacity %d", pool.MaxPoolSize, pool.SecurityGroupIDs, capacity)
+		}
+		poolConfig.SecurityGroupPools = append(poolConfig.SecurityGroupPools, types.SecurityGroupPoolConfig{
+			SecurityGroupIDs: pool.SecurityGroupIDs,
+			MaxPoolSize:      pool.MaxPoolSize,
+			MinPoolSize:      pool.MinPoolSize,
+		})
+	}
+
 	if cfg.IPAMType == types.IPAMTypeCRD {
+		poolConfig.SecurityGroupPools = nil
 		poolConfig.MaxPoolSize = 0
 		poolConfig.MinPoolSize = 0
+		poolConfig.WarmENITarget = 0
+		poolConfig.MinimumIPTarget = 0
 	}
 
 	poolConfig.Capacity = capacity
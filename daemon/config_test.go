@@ -1,6 +1,7 @@
 package daemon
 
 import (
+	"net/netip"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -105,3 +106,131 @@ func TestGetENIConfig(t *testing.T) {
 	assert.Equal(t, "rgID", eniConfig.ResourceGroupID)
 	assert.Equal(t, types.Feat(3), eniConfig.EniTypeAttr)
 }
+
+func TestGetENIConfigVSwitchSelection(t *testing.T) {
+	cfg := &daemon.Config{
+		VSwitchSelectionPolicy: "prefer-cidr",
+		VSwitchWeights:         map[string]int{"vswitch1": 2},
+		VSwitchPreferCIDRs:     []string{"192.168.1.1/16"},
+	}
+
+	eniConfig := getENIConfig(cfg)
+	assert.Equal(t, vswitch.VSwitchSelectionPolicyPreferCIDR, eniConfig.VSwitchSelectionPolicy)
+	assert.Equal(t, map[string]int{"vswitch1": 2}, eniConfig.VSwitchWeights)
+	assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("192.168.0.0/16")}, eniConfig.VSwitchPreferCIDRs)
+}
+
+func TestGetPoolConfigWithIPv4Prefix(t *testing.T) {
+	cfg := &daemon.Config{
+		MaxPoolSize:      500,
+		MinENI:           1,
+		EniCapRatio:      1,
+		RegionID:         "foo",
+		EnableIPv4Prefix: true,
+	}
+	limit := &client.Limits{
+		Adapters:           3,
+		IPv4PerAdapter:     10,
+		MemberAdapterLimit: 5,
+	}
+	poolConfig, err := getPoolConfig(cfg, "ENIMultiIP", limit)
+	assert.NoError(t, err)
+	assert.Equal(t, 9, poolConfig.IPv4PrefixPerENI)
+	assert.Equal(t, 10, poolConfig.MaxIPPerENI)
+	assert.Equal(t, 2*(1+9*16), poolConfig.Capacity)
+	assert.Equal(t, 2*(1+9*16), poolConfig.MaxPoolSize)
+	assert.Equal(t, 1+9*16, poolConfig.MinPoolSize)
+}
+
+func TestGetPoolConfigWithWarmTarget(t *testing.T) {
+	limit := &client.Limits{
+		Adapters:           4,
+		IPv4PerAdapter:     10,
+		MemberAdapterLimit: 5,
+	}
+
+	cfg := &daemon.Config{
+		MaxPoolSize:     5,
+		MinPoolSize:     1,
+		EniCapRatio:     1,
+		RegionID:        "foo",
+		WarmENITarget:   1,
+		WarmIPTarget:    8,
+		MinimumIPTarget: 20,
+	}
+	poolConfig, err := getPoolConfig(cfg, "ENIMultiIP", limit)
+	assert.NoError(t, err)
+	assert.Equal(t, 8, poolConfig.MinPoolSize)
+	assert.Equal(t, 8, poolConfig.MaxPoolSize)
+	assert.Equal(t, 1, poolConfig.WarmENITarget)
+	assert.Equal(t, 20, poolConfig.MinimumIPTarget)
+
+	cfg.IPAMType = types.IPAMTypeCRD
+	poolConfig, err = getPoolConfig(cfg, "ENIMultiIP", limit)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, poolConfig.WarmENITarget)
+	assert.Equal(t, 0, poolConfig.MinimumIPTarget)
+}
+
+func TestGetPoolConfigWithInvalidWarmTarget(t *testing.T) {
+	limit := &client.Limits{
+		Adapters:           4,
+		IPv4PerAdapter:     10,
+		MemberAdapterLimit: 5,
+	}
+
+	tests := []struct {
+		name string
+		mode string
+		cfg  *daemon.Config
+	}{
+		{name: "negative", mode: "ENIMultiIP", cfg: &daemon.Config{EniCapRatio: 1, WarmIPTarget: -1}},
+		{name: "unsupported mode", mode: "VPC", cfg: &daemon.Config{EniCapRatio: 1, WarmENITarget: 1}},
+		{name: "warm eni exceed max eni", mode: "ENIMultiIP", cfg: &daemon.Config{EniCapRatio: 1, WarmENITarget: 4}},
+		{name: "warm ip exceed capacity", mode: "ENIMultiIP", cfg: &daemon.Config{EniCapRatio: 1, WarmIPTarget: 31}},
+		{name: "minimum ip exceed capacity", mode: "ENIMultiIP", cfg: &daemon.Config{EniCapRatio: 1, MinimumIPTarget: 31}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := getPoolConfig(tt.cfg, tt.mode, limit)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestGetPoolConfigWithSecurityGroupPools(t *testing.T) {
+	limit := &client.Limits{
+		Adapters:           4,
+		IPv4PerAdapter:     10,
+		MemberAdapterLimit: 5,
+	}
+
+	cfg := &daemon.Config{
+		EniCapRatio: 1,
+		SecurityGroupPools: []daemon.SecurityGroupPool{
+			{SecurityGroupIDs: []string{"sg-1", "sg-2"}, MinPoolSize: 2, MaxPoolSize: 5},
+		},
+	}
+	poolConfig, err := getPoolConfig(cfg, "ENIMultiIP", limit)
+	assert.NoError(t, err)
+	assert.Equal(t, []types.SecurityGroupPoolConfig{
+		{SecurityGroupIDs: []string{"sg-1", "sg-2"}, MinPoolSize: 2, MaxPoolSize: 5},
+	}, poolConfig.SecurityGroupPools)
+
+	tests := []struct {
+		name string
+		mode string
+		pool daemon.SecurityGroupPool
+	}{
+		{name: "unsupported mode", mode: "VPC", pool: daemon.SecurityGroupPool{SecurityGroupIDs: []string{"sg-1"}}},
+		{name: "no security group", mode: "ENIMultiIP", pool: daemon.SecurityGroupPool{MaxPoolSize: 1}},
+		{name: "min exceed max", mode: "ENIMultiIP", pool: daemon.SecurityGroupPool{SecurityGroupIDs: []string{"sg-1"}, MinPoolSize: 2, MaxPoolSize: 1}},
+		{name: "max exceed capacity", mode: "ENIMultiIP", pool: daemon.SecurityGroupPool{SecurityGroupIDs: []string{"sg-1"}, MaxPoolSize: 31}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := getPoolConfig(&daemon.Config{EniCapRatio: 1, SecurityGroupPools: []daemon.SecurityGroupPool{tt.pool}}, tt.mode, limit)
+			assert.Error(t, err)
+		})
+	}
+}
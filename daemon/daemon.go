@@ -2,11 +2,9 @@ package daemon
 
 import (
 	"context"
-	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net"
 	"net/netip"
 	"os"
 	"strconv"
@@ -14,8 +12,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/vishvananda/netlink"
-	"k8s.io/apimachinery/pkg/util/sets"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/AliyunContainerService/terway/deviceplugin"
@@ -23,6 +19,7 @@ import (
 	"github.com/AliyunContainerService/terway/pkg/aliyun/credential"
 	eni2 "github.com/AliyunContainerService/terway/pkg/aliyun/eni"
 	"github.com/AliyunContainerService/terway/pkg/aliyun/instance"
+	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
 	"github.com/AliyunContainerService/terway/pkg/backoff"
 	"github.com/AliyunContainerService/terway/pkg/eni"
 	"github.com/AliyunContainerService/terway/pkg/factory"
@@ -30,6 +27,7 @@ import (
 	"github.com/AliyunContainerService/terway/pkg/k8s"
 	"github.com/AliyunContainerService/terway/pkg/metric"
 	"github.com/AliyunContainerService/terway/pkg/storage"
+	"github.com/AliyunContainerService/terway/pkg/telemetry"
 	"github.com/AliyunContainerService/terway/pkg/tracing"
 	"github.com/AliyunContainerService/terway/pkg/utils"
 	"github.com/AliyunContainerService/terway/pkg/utils/nodecap"
@@ -41,8 +39,12 @@ import (
 	"github.com/samber/lo"
 	corev1 "k8s.io/api/core/v1"
 	k8sErr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/client-go/util/retry"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
@@ -57,6 +59,7 @@ const (
 
 	commandMapping = "mapping"
 	commandResDB   = "resdb"
+	commandProbe   = "probe"
 
 	IfEth0 = "eth0"
 
@@ -72,15 +75,30 @@ type networkService struct {
 
 	eniMgr      *eni.Manager
 	pendingPods sync.Map
+	prewarm     *prewarmer
 	sync.RWMutex
 
 	enableIPv4, enableIPv6 bool
 
 	ipamType types.IPAMType
 
+	// securityGroupIDs the security groups of the eni in the default pool
+	securityGroupIDs []string
+	// securityGroupPools the key of the security_group_pools, the pod annotation may only pick one of them
+	securityGroupPools sets.Set[string]
+
 	wg sync.WaitGroup
 
-	gcRulesOnce sync.Once
+	datapathReconciler *datapathReconciler
+
+	// ipReleaser release the ip held for the deleted stateful pod
+	ipReleaser *ipReleaser
+
+	// eventHub publish the allocate, release and gc events to the watchers
+	eventHub *eventHub
+
+	// shutdownTelemetry flush the spans of the allocations on exit
+	shutdownTelemetry telemetry.ShutdownFunc
 
 	rpc.UnimplementedTerwayBackendServer
 }
@@ -180,6 +198,7 @@ func (n *networkService) AllocIP(ctx context.Context, r *rpc.AllocIPRequest) (*r
 	}
 
 	var resourceRequests []eni.ResourceRequest
+	var reservation *v1beta1.IPReservation
 
 	var netConf []*rpc.NetConf
 	// 3. Allocate network resource for pod
@@ -190,17 +209,39 @@ func (n *networkService) AllocIP(ctx context.Context, r *rpc.AllocIPRequest) (*r
 		if pod.PodENI {
 			resourceRequests = append(resourceRequests, &eni.RemoteIPRequest{})
 		} else {
-			req := &eni.LocalIPRequest{}
-			if pod.ERdma {
-				req.LocalIPType = eni.LocalIPTypeERDMA
+			pod.SecurityGroups, err = n.podSecurityGroups(ctx, pod)
+			if err != nil {
+				return nil, &types.Error{
+					Code: types.ErrInternalError,
+					Msg:  err.Error(),
+					R:    err,
+				}
 			}
-			if len(oldRes.GetResourceItemByType(daemon.ResourceTypeENIIP)) == 1 {
-				old := oldRes.GetResourceItemByType(daemon.ResourceTypeENIIP)[0]
 
-				setRequest(req, old)
+			var reqs []eni.ResourceRequest
+			reqs, err = podNetworkRequests(pod, oldRes)
+			if err != nil {
+				return nil, &types.Error{
+					Code: types.ErrInvalidArgsErrCode,
+					Msg:  err.Error(),
+					R:    err,
+				}
 			}
 
-			resourceRequests = append(resourceRequests, req)
+			var reservedIP *eni.ReservedIP
+			reservation, reservedIP, err = n.podReservedIP(ctx, pod)
+			if err != nil {
+				return nil, &types.Error{
+					Code: types.ErrInternalError,
+					Msg:  err.Error(),
+					R:    err,
+				}
+			}
+			if reservedIP != nil {
+				reqs[0].(*eni.LocalIPRequest).ReservedIP = reservedIP
+			}
+
+			resourceRequests = append(resourceRequests, reqs...)
 		}
 	case daemon.PodNetworkTypeVPCENI:
 		reply.IPType = rpc.IPType_TypeVPCENI
@@ -232,6 +273,9 @@ func (n *networkService) AllocIP(ctx context.Context, r *rpc.AllocIPRequest) (*r
 	resp, err := n.eniMgr.Allocate(ctx, cni, &eni.AllocRequest{
 		ResourceRequests: resourceRequests,
 	})
+	if err == nil && reservation != nil {
+		err = n.updateIPReservation(ctx, reservation, pod, resp)
+	}
 	if err != nil {
 		_ = n.eniMgr.Release(ctx, cni, &eni.ReleaseRequest{
 			NetworkResources: resp,
@@ -257,6 +301,9 @@ func (n *networkService) AllocIP(ctx context.Context, r *rpc.AllocIPRequest) (*r
 			Egress:          pod.TcEgress,
 			NetworkPriority: pod.NetworkPriority,
 		}
+		if len(c.ExtraRoutes) == 0 {
+			c.ExtraRoutes = podNetworkRoutes(pod, c.IfName)
+		}
 	}
 
 	err = defaultForNetConf(netConf)
@@ -290,6 +337,10 @@ func (n *networkService) AllocIP(ctx context.Context, r *rpc.AllocIPRequest) (*r
 	if err != nil {
 		return nil, err
 	}
+	if n.ipReleaser != nil {
+		n.ipReleaser.cancel(podID)
+	}
+	n.publishPodEvent(rpc.PodEventType_PodEventAllocate, newRes)
 
 	reply.NetConfs = netConf
 	reply.Success = true
@@ -382,6 +433,15 @@ func (n *networkService) ReleaseIP(ctx context.Context, r *rpc.ReleaseIPRequest)
 		if err != nil {
 			return nil, fmt.Errorf("error delete pod resource: %w", err)
 		}
+		if len(oldRes.Resources) > 0 {
+			n.publishPodEvent(rpc.PodEventType_PodEventRelease, oldRes)
+		}
+	} else if len(oldRes.Resources) > 0 && oldRes.PodInfo != nil {
+		// the ip is released by the timer if the pod is not back in time
+		err = n.holdPodIP(podID, oldRes, pod.IPStickTime)
+		if err != nil {
+			return nil, fmt.Errorf("error hold pod ip: %w", err)
+		}
 	}
 
 	return reply, nil
@@ -533,14 +593,9 @@ func (n *networkService) gcPods(ctx context.Context) error {
 	}
 	exist := make(map[string]bool)
 
-	existIPs := sets.Set[string]{}
-
 	for _, pod := range pods {
 		if !pod.SandboxExited {
 			exist[utils.PodInfoKey(pod.Namespace, pod.Name)] = true
-			if pod.PodIPs.IPv4 != nil {
-				existIPs.Insert(pod.PodIPs.IPv4.String())
-			}
 		}
 	}
 
@@ -551,12 +606,6 @@ func (n *networkService) gcPods(ctx context.Context) error {
 	podResources := getPodResources(objList)
 
 	for _, podRes := range podResources {
-		if podRes.PodInfo != nil {
-			if podRes.PodInfo.PodIPs.IPv4 != nil {
-				existIPs.Insert(podRes.PodInfo.PodIPs.IPv4.String())
-			}
-		}
-
 		podID := utils.PodInfoKey(podRes.PodInfo.Namespace, podRes.PodInfo.Name)
 		if _, ok := exist[podID]; ok {
 			continue
@@ -567,73 +616,34 @@ func (n *networkService) gcPods(ctx context.Context) error {
 			continue
 		}
 
-		// that is old logic ... keep it
 		if podRes.PodInfo.IPStickTime != 0 {
-			podRes.PodInfo.IPStickTime = 0
-
-			err = n.resourceDB.Put(podID, podRes)
-			if err != nil {
-				return err
-			}
-			continue
-		}
-
-		for _, resource := range podRes.Resources {
-			res := parseNetworkResource(resource)
-			if res == nil {
+			if podRes.PodInfo.IPReleaseAt.IsZero() {
+				// the pod is deleted without cni del, start holding the ip now
+				err = n.holdPodIP(podID, podRes, podRes.PodInfo.IPStickTime)
+				if err != nil {
+					return err
+				}
 				continue
 			}
-			err = n.eniMgr.Release(ctx, &daemon.CNI{
-				PodName:      podRes.PodInfo.Name,
-				PodNamespace: podRes.PodInfo.Namespace,
-				PodID:        podID,
-				PodUID:       podRes.PodInfo.PodUID,
-			}, &eni.ReleaseRequest{
-				NetworkResources: []eni.NetworkResource{res},
-			})
-			if err != nil {
-				return err
+			if time.Now().Before(podRes.PodInfo.IPReleaseAt) {
+				// restore the timer after the daemon restarted
+				if n.ipReleaser != nil {
+					n.ipReleaser.schedule(podID, podRes.PodInfo.IPReleaseAt)
+				}
+				continue
 			}
 		}
 
-		err = n.deletePodResource(podRes.PodInfo)
+		err = n.releasePodResources(ctx, podID, podRes)
 		if err != nil {
 			return err
 		}
 		serviceLog.Info("removed pod", "pod", podID)
 	}
 
-	if os.Getenv("TERWAY_GC_RULES") == "true" {
-		n.gcRulesOnce.Do(func() {
-			gcLeakedRules(existIPs)
-		})
-	}
-
 	return nil
 }
 
-func gcLeakedRules(existIP sets.Set[string]) {
-	links, err := netlink.LinkList()
-	if err != nil {
-		serviceLog.Error(err, "error list links")
-		return
-	}
-
-	ipvlLinks := lo.Filter(links, func(item netlink.Link, index int) bool {
-		_, ok := item.(*netlink.IPVlan)
-		return ok
-	})
-
-	gcRoutes(ipvlLinks, existIP)
-
-	normalLinks := lo.Filter(links, func(item netlink.Link, index int) bool {
-		_, ok := item.(*netlink.Device)
-		return ok
-	})
-
-	gcTCFilters(normalLinks, existIP)
-}
-
 // tracing
 func (n *networkService) Config() []tracing.MapKeyValueEntry {
 	// name, daemon_mode, configFilePath, kubeconfig, master
@@ -656,6 +666,18 @@ func (n *networkService) Trace() []tracing.MapKeyValueEntry {
 	trace := []tracing.MapKeyValueEntry{
 		{Key: tracingKeyPendingPodsCount, Value: fmt.Sprint(count)},
 	}
+	if n.prewarm != nil {
+		trace = append(trace, n.prewarm.trace()...)
+	}
+	if n.datapathReconciler != nil {
+		trace = append(trace, n.datapathReconciler.trace()...)
+	}
+	if n.eventHub != nil {
+		trace = append(trace, n.eventHub.trace()...)
+	}
+	if n.ipReleaser != nil {
+		trace = append(trace, tracing.MapKeyValueEntry{Key: tracingKeyIPReleaseScheduled, Value: fmt.Sprint(n.ipReleaser.scheduled())})
+	}
 	resList, err := n.resourceDB.List()
 	if err != nil {
 		trace = append(trace, tracing.MapKeyValueEntry{Key: "error", Value: err.Error()})
@@ -673,12 +695,26 @@ func (n *networkService) Trace() []tracing.MapKeyValueEntry {
 
 		key := fmt.Sprintf("pods/%s/%s/resources", res.PodInfo.Namespace, res.PodInfo.Name)
 		trace = append(trace, tracing.MapKeyValueEntry{Key: key, Value: strings.Join(resources, " ")})
+
+		if !res.PodInfo.IPReleaseAt.IsZero() {
+			key = fmt.Sprintf("pods/%s/%s/ip_release_in", res.PodInfo.Namespace, res.PodInfo.Name)
+			trace = append(trace, tracing.MapKeyValueEntry{Key: key, Value: daemon.IPHoldRemaining(res.PodInfo.IPReleaseAt, time.Now())})
+		}
+
+		if len(res.PodInfo.HostPorts) > 0 {
+			var hostPorts []string
+			for _, h := range res.PodInfo.HostPorts {
+				hostPorts = append(hostPorts, h.String())
+			}
+			key = fmt.Sprintf("pods/%s/%s/hostports", res.PodInfo.Namespace, res.PodInfo.Name)
+			trace = append(trace, tracing.MapKeyValueEntry{Key: key, Value: strings.Join(hostPorts, " ")})
+		}
 	}
 
 	return trace
 }
 
-func (n *networkService) Execute(cmd string, _ []string, message chan<- string) {
+func (n *networkService) Execute(cmd string, args []string, message chan<- string) {
 	switch cmd {
 	case commandMapping:
 		mapping, err := n.GetResourceMapping()
@@ -693,7 +729,8 @@ func (n *networkService) Execute(cmd string, _ []string, message chan<- string)
 			out, _ := json.Marshal(objList)
 			message <- string(out)
 		}
-
+	case commandProbe:
+		n.probePod(args, message)
 	default:
 		message <- "can't recognize command\n"
 	}
@@ -754,6 +791,11 @@ func newNetworkService(ctx context.Context, configFilePath, daemonMode string) (
 
 	serviceLog.Info("got config", "config", fmt.Sprintf("%+v", config))
 
+	netSrv.shutdownTelemetry, err = telemetry.Setup(ctx, "terway-daemon", config.OTLPEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
 	backoff.OverrideBackoff(config.BackoffOverride)
 	_ = netSrv.k8s.SetCustomStatefulWorkloadKinds(config.CustomStatefulWorkloadKinds)
 	netSrv.ipamType = config.IPAMType
@@ -803,6 +845,11 @@ func newNetworkService(ctx context.Context, configFilePath, daemonMode string) (
 	netSrv.enableIPv4 = enableIPv4
 	netSrv.enableIPv6 = enableIPv6
 
+	if config.EnableIPv4Prefix && (daemonMode != daemon.ModeENIMultiIP || enableIPv6 || config.IPAMType == types.IPAMTypeCRD) {
+		serviceLog.Info("ipv4 prefix is only supported in ipv4 ENIMultiIP mode, disable it")
+		config.EnableIPv4Prefix = false
+	}
+
 	eniConfig := getENIConfig(config)
 	eniConfig.EnableIPv4 = enableIPv4
 	eniConfig.EnableIPv6 = enableIPv6
@@ -818,6 +865,7 @@ func newNetworkService(ctx context.Context, configFilePath, daemonMode string) (
 		}
 		eniConfig.SecurityGroupIDs = enis[0].SecurityGroupIDs
 	}
+	netSrv.securityGroupIDs = eniConfig.SecurityGroupIDs
 
 	// get pool config
 	poolConfig, err := getPoolConfig(config, daemonMode, limit)
@@ -843,7 +891,7 @@ func newNetworkService(ctx context.Context, configFilePath, daemonMode string) (
 	}
 
 	if config.EnableENITrunking {
-		trunkENIID, err = initTrunk(config, poolConfig, netSrv.k8s, factory)
+		trunkENIID, err = initTrunk(ctx, config, poolConfig, netSrv.k8s, factory)
 		if err != nil {
 			return nil, err
 		}
@@ -896,15 +944,16 @@ func newNetworkService(ctx context.Context, configFilePath, daemonMode string) (
 		return nil, fmt.Errorf("error patch node annotations, %w", err)
 	}
 
-	netSrv.resourceDB, err = storage.NewDiskStorage(
-		resDBName, utils.NormalizePath(resDBPath), json.Marshal, func(bytes []byte) (interface{}, error) {
-			resourceRel := &daemon.PodResources{}
-			err = json.Unmarshal(bytes, resourceRel)
-			if err != nil {
-				return nil, err
-			}
-			return *resourceRel, nil
-		})
+	netSrv.resourceDB, err = daemon.NewResDB(utils.NormalizePath(daemon.ResDBPath))
+	if err != nil {
+		return nil, err
+	}
+
+	eventDB, err := newEventDB(netSrv.resourceDB)
+	if err != nil {
+		return nil, fmt.Errorf("error open event journal, %w", err)
+	}
+	netSrv.eventHub, err = newEventHub(eventDB)
 	if err != nil {
 		return nil, err
 	}
@@ -988,7 +1037,9 @@ func newNetworkService(ctx context.Context, configFilePath, daemonMode string) (
 				eniList = append(eniList, eni.NewLocal(ni, "erdma", factory, poolConfig))
 			} else {
 				normalENICount++
-				eniList = append(eniList, eni.NewLocal(ni, "secondary", factory, poolConfig))
+				lo := eni.NewLocal(ni, "secondary", factory, poolConfig)
+				lo.SetNetwork(podNetworkOfENI(ni, eniConfig))
+				eniList = append(eniList, lo)
 			}
 		}
 		normalENINeeded := poolConfig.MaxENI - normalENICount
@@ -1005,6 +1056,12 @@ func newNetworkService(ctx context.Context, configFilePath, daemonMode string) (
 	}
 
 	eniManager := eni.NewManager(poolConfig.MinPoolSize, poolConfig.MaxPoolSize, poolConfig.Capacity, 30*time.Second, eniList, eniConfig.EniSelectionPolicy, netSrv.k8s)
+	eniManager.SetWarmTarget(poolConfig.WarmENITarget, poolConfig.MinimumIPTarget)
+	eniManager.SetSecurityGroupPools(poolConfig.SecurityGroupPools)
+	netSrv.securityGroupPools = sets.New[string]()
+	for _, pool := range poolConfig.SecurityGroupPools {
+		netSrv.securityGroupPools.Insert(eni.SecurityGroupKey(pool.SecurityGroupIDs))
+	}
 	netSrv.eniMgr = eniManager
 	err = eniManager.Run(ctx, &netSrv.wg, podResources)
 	if err != nil {
@@ -1012,10 +1069,34 @@ func newNetworkService(ctx context.Context, configFilePath, daemonMode string) (
 	}
 
 	if config.IPAMType != types.IPAMTypeCRD {
+		netSrv.ipReleaser = newIPReleaser(func(podID string) {
+			netSrv.releaseExpiredPodIP(ctx, podID)
+		})
 		//start gc loop
 		go netSrv.startGarbageCollectionLoop(ctx)
 	}
 
+	if config.EnablePodPrewarm && daemonMode == daemon.ModeENIMultiIP && config.IPAMType != types.IPAMTypeCRD {
+		netSrv.prewarm = &prewarmer{}
+		go netSrv.startPrewarmLoop(ctx)
+	}
+
+	datapathReconcileMode := config.DatapathReconcile
+	if datapathReconcileMode == "" && os.Getenv("TERWAY_GC_RULES") == "true" {
+		// the previous gc of the leaked rules is replaced by the reconcile, see docs/datapath-reconcile.md
+		serviceLog.Info("TERWAY_GC_RULES is deprecated, use datapath_reconcile in eni-config instead", "mode", daemon.DatapathReconcileEnforce)
+		datapathReconcileMode = daemon.DatapathReconcileEnforce
+	}
+	if datapathReconcileMode != "" && daemonMode != daemon.ModeVPC {
+		netSrv.datapathReconciler = &datapathReconciler{mode: datapathReconcileMode}
+		go netSrv.startDatapathReconcileLoop(ctx)
+	}
+
+	// apply the bandwidth and priority changed on the running pods
+	if config.EnablePodQoSUpdate {
+		netSrv.k8s.WatchPodQoS(ctx, netSrv.updatePodQoS)
+	}
+
 	// register for tracing
 	_ = tracing.Register(tracing.ResourceTypeNetworkService, "default", netSrv)
 	tracing.RegisterResourceMapping(netSrv)
@@ -1067,7 +1148,7 @@ func checkInstance(limit *client.Limits, daemonMode string, config *daemon.Confi
 }
 
 // initTrunk to ensure trunk eni is present. Return eni id if found.
-func initTrunk(config *daemon.Config, poolConfig *types.PoolConfig, k8sClient k8s.Kubernetes, f factory.Factory) (string, error) {
+func initTrunk(ctx context.Context, config *daemon.Config, poolConfig *types.PoolConfig, k8sClient k8s.Kubernetes, f factory.Factory) (string, error) {
 	var err error
 
 	// get eni id form node annotation
@@ -1125,7 +1206,7 @@ func initTrunk(config *daemon.Config, poolConfig *types.PoolConfig, k8sClient k8
 	if poolConfig.EnableIPv6 {
 		v6 = 1
 	}
-	trunk, _, _, err := f.CreateNetworkInterface(1, v6, "trunk")
+	trunk, _, _, err := f.CreateNetworkInterface(ctx, 1, v6, "trunk")
 	if err != nil {
 		if trunk != nil {
 			_ = f.DeleteNetworkInterface(trunk.ID)
@@ -1181,6 +1262,7 @@ func parseNetworkResource(item daemon.ResourceItem) eni.NetworkResource {
 		}
 
 		return &eni.LocalIPResource{
+			IfName: item.IfName,
 			ENI: daemon.ENI{
 				ID:  item.ENIID,
 				MAC: item.ENIMAC,
@@ -1208,6 +1290,220 @@ func setRequest(req *eni.LocalIPRequest, old daemon.ResourceItem) {
 	req.NetworkInterfaceID = eniID
 }
 
+// podNetworkOfENI return the pod network the attached eni created for, nil if the eni is in the default network
+func podNetworkOfENI(ni *daemon.ENI, cfg *types.ENIConfig) *factory.NetworkInterfaceOptions {
+	inVSwitch := len(cfg.VSwitchOptions) == 0 || lo.Contains(cfg.VSwitchOptions, ni.VSwitchID)
+	// the security groups is unknown if not described
+	inSecurityGroup := len(ni.SecurityGroupIDs) == 0 || eni.SecurityGroupKey(ni.SecurityGroupIDs) == eni.SecurityGroupKey(cfg.SecurityGroupIDs)
+	if inVSwitch && inSecurityGroup {
+		return nil
+	}
+	network := &factory.NetworkInterfaceOptions{SecurityGroupIDs: ni.SecurityGroupIDs}
+	if !inVSwitch {
+		network.VSwitchOptions = []string{ni.VSwitchID}
+	}
+	return network
+}
+
+// podSecurityGroups return the security groups of the shared eni the pod ip allocated from,
+// from the pod annotation or the podNetworking, nil for the default pool.
+// The pod annotation is set by the user, so it is only accepted if it is one of the security_group_pools
+// or the security groups of the podNetworking, which is created by the cluster admin.
+func (n *networkService) podSecurityGroups(ctx context.Context, pod *daemon.PodInfo) ([]string, error) {
+	var pnSGs []string
+	if pod.PodNetworking != "" {
+		pn, err := n.k8s.GetPodNetworking(ctx, pod.PodNetworking)
+		if err != nil {
+			return nil, fmt.Errorf("error get podNetworking %s, %w", pod.PodNetworking, err)
+		}
+		pnSGs = pn.Spec.SecurityGroupIDs
+	}
+
+	sgs := pod.SecurityGroups
+	key := eni.SecurityGroupKey(sgs)
+	switch {
+	case len(sgs) == 0:
+		sgs = pnSGs
+	case key == eni.SecurityGroupKey(n.securityGroupIDs), n.securityGroupPools.Has(key):
+	case len(pnSGs) > 0 && key == eni.SecurityGroupKey(pnSGs):
+	default:
+		return nil, fmt.Errorf("security groups %s is not allowed, only the security_group_pools or the security groups of the podNetworking can be used", key)
+	}
+
+	if eni.SecurityGroupKey(sgs) == eni.SecurityGroupKey(n.securityGroupIDs) {
+		return nil, nil
+	}
+	return sgs, nil
+}
+
+// podReservedIP return the IPReservation of the pod's StatefulSet and the ip reserved for the pod ordinal.
+// Nil is returned if the pod has no reservation.
+func (n *networkService) podReservedIP(ctx context.Context, pod *daemon.PodInfo) (*v1beta1.IPReservation, *eni.ReservedIP, error) {
+	if pod.StatefulSet == "" || pod.ERdma || len(pod.SecurityGroups) > 0 || n.enableIPv6 {
+		return nil, nil, nil
+	}
+
+	list := &v1beta1.IPReservationList{}
+	err := n.k8s.GetClient().List(ctx, list, k8sclient.InNamespace(pod.Namespace))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error list ipReservation, %w", err)
+	}
+	for i := range list.Items {
+		if list.Items[i].Spec.WorkloadName != pod.StatefulSet {
+			continue
+		}
+		reservedIP, err := reservedIPOf(&list.Items[i], pod)
+		if err != nil || reservedIP == nil {
+			return nil, nil, err
+		}
+		return &list.Items[i], reservedIP, nil
+	}
+	return nil, nil, nil
+}
+
+// reservedIPOf return the ip reserved for the pod ordinal, nil if the ordinal is not in the reservation
+func reservedIPOf(reservation *v1beta1.IPReservation, pod *daemon.PodInfo) (*eni.ReservedIP, error) {
+	ordinal, err := strconv.Atoi(strings.TrimPrefix(pod.Name, pod.StatefulSet+"-"))
+	if err != nil {
+		return nil, nil
+	}
+	if !lo.ContainsBy(reservation.Spec.Reservations, func(item v1beta1.Reservation) bool {
+		return item.Ordinal == ordinal
+	}) {
+		return nil, nil
+	}
+
+	status, ok := lo.Find(reservation.Status.Reservations, func(item v1beta1.ReservationStatus) bool {
+		return item.Ordinal == ordinal
+	})
+	if !ok || status.IPv4 == "" || status.NetworkInterfaceID == "" {
+		return nil, fmt.Errorf("ip of ordinal %d is not reserved yet by %s", ordinal, reservation.Name)
+	}
+	ip, err := netip.ParseAddr(status.IPv4)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ip reserved by %s, %w", reservation.Name, err)
+	}
+
+	return &eni.ReservedIP{
+		IPv4:               ip,
+		VSwitchID:          reservation.Spec.VSwitchID,
+		NetworkInterfaceID: status.NetworkInterfaceID,
+		Holder:             reservation.Status.NetworkInterfaceID,
+	}, nil
+}
+
+// updateIPReservation record the eni and node the reserved ip moved to
+func (n *networkService) updateIPReservation(ctx context.Context, reservation *v1beta1.IPReservation, pod *daemon.PodInfo, resp []eni.NetworkResource) error {
+	var res *eni.LocalIPResource
+	for _, r := range resp {
+		if v, ok := r.(*eni.LocalIPResource); ok && v.Reserved {
+			res = v
+			break
+		}
+	}
+	if res == nil {
+		return nil
+	}
+
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		update := &v1beta1.IPReservation{}
+		err := n.k8s.GetClient().Get(ctx, k8sclient.ObjectKeyFromObject(reservation), update)
+		if err != nil {
+			return err
+		}
+		for i := range update.Status.Reservations {
+			status := &update.Status.Reservations[i]
+			if status.IPv4 != res.IP.IPv4.String() {
+				continue
+			}
+			if status.NetworkInterfaceID == res.ENI.ID && status.NodeName == os.Getenv("NODE_NAME") && status.MissingSince == nil {
+				return nil
+			}
+			status.NetworkInterfaceID = res.ENI.ID
+			status.NodeName = os.Getenv("NODE_NAME")
+			status.MissingSince = nil
+			update.Status.UpdateAt = metav1.Now()
+
+			serviceLog.Info("reserved ip moved", "pod", utils.PodInfoKey(pod.Namespace, pod.Name), "ipv4", status.IPv4, "eni", res.ENI.ID)
+			return n.k8s.GetClient().Status().Update(ctx, update)
+		}
+		return fmt.Errorf("reserved ip %s not found in %s", res.IP.IPv4, reservation.Name)
+	})
+}
+
+// podNetworkRequests build one request for each network of the pod, the default network is the first.
+// Each network is backed by an eni in the vSwitches and security groups of the network.
+func podNetworkRequests(pod *daemon.PodInfo, oldRes daemon.PodResources) ([]eni.ResourceRequest, error) {
+	defaultReq := &eni.LocalIPRequest{}
+	if pod.ERdma {
+		defaultReq.LocalIPType = eni.LocalIPTypeERDMA
+	}
+	if len(pod.SecurityGroups) > 0 {
+		if pod.ERdma {
+			return nil, fmt.Errorf("security groups is not supported for erdma pod")
+		}
+		defaultReq.Network = &factory.NetworkInterfaceOptions{SecurityGroupIDs: pod.SecurityGroups}
+	}
+	reqs := []*eni.LocalIPRequest{defaultReq}
+
+	ifNames := map[string]struct{}{}
+	for _, n := range pod.PodNetworks {
+		var network *factory.NetworkInterfaceOptions
+		if len(n.VSwitchOptions) > 0 || len(n.SecurityGroupIDs) > 0 {
+			network = &factory.NetworkInterfaceOptions{
+				VSwitchOptions:   n.VSwitchOptions,
+				SecurityGroupIDs: n.SecurityGroupIDs,
+			}
+		}
+		if defaultIf(n.Interface) {
+			if network != nil && pod.ERdma {
+				return nil, fmt.Errorf("pod network is not supported for erdma pod")
+			}
+			if network != nil {
+				defaultReq.Network = network
+			}
+			continue
+		}
+		if _, ok := ifNames[n.Interface]; ok {
+			return nil, fmt.Errorf("interface %s is duplicated in pod networks", n.Interface)
+		}
+		ifNames[n.Interface] = struct{}{}
+
+		reqs = append(reqs, &eni.LocalIPRequest{
+			IfName:  n.Interface,
+			Network: network,
+		})
+	}
+
+	var result []eni.ResourceRequest
+	old := oldRes.GetResourceItemByType(daemon.ResourceTypeENIIP)
+	for _, req := range reqs {
+		matched := lo.Filter(old, func(item daemon.ResourceItem, _ int) bool {
+			return item.IfName == req.IfName
+		})
+		if len(matched) == 1 {
+			setRequest(req, matched[0])
+		}
+		result = append(result, req)
+	}
+	return result, nil
+}
+
+// podNetworkRoutes return the extra routes of the pod network for the interface
+func podNetworkRoutes(pod *daemon.PodInfo, ifName string) []*rpc.Route {
+	for _, n := range pod.PodNetworks {
+		if n.Interface != ifName && !(defaultIf(n.Interface) && defaultIf(ifName)) {
+			continue
+		}
+		var routes []*rpc.Route
+		for _, dst := range n.ExtraRoutes {
+			routes = append(routes, &rpc.Route{Dst: dst})
+		}
+		return routes
+	}
+	return nil
+}
+
 func toRPCMapping(res eni.Status) *rpc.ResourceMapping {
 	rMapping := rpc.ResourceMapping{
 		NetworkInterfaceID:   res.NetworkInterfaceID,
@@ -1320,84 +1616,3 @@ func filterENINotFound(podResources []daemon.PodResources, attachedENIID map[str
 	}
 	return podResources
 }
-
-func gcRoutes(links []netlink.Link, existIP sets.Set[string]) {
-	for _, link := range links {
-		routes, err := netlink.RouteList(link, netlink.FAMILY_V4)
-		if err != nil {
-			serviceLog.Error(err, "gc list route", "link", link)
-			return
-		}
-		for _, route := range routes {
-			if route.Dst == nil {
-				continue
-			}
-			// if not found
-			if existIP.Has(route.Dst.IP.String()) {
-				continue
-			}
-
-			serviceLog.Info("gc del route", "route", route)
-			err = netlink.RouteDel(&route)
-			if err != nil {
-				serviceLog.Error(err, "gc del route", "route", route)
-				return
-			}
-		}
-	}
-}
-
-func gcTCFilters(links []netlink.Link, existIP sets.Set[string]) {
-	// map ip to u32
-	toU32List := lo.Map(existIP.UnsortedList(), func(item string, index int) uint32 {
-		ip := net.ParseIP(item)
-		if ip == nil {
-			return 0
-		}
-		return binary.BigEndian.Uint32(ip.To4())
-	})
-	u32IPMap := lo.SliceToMap(toU32List, func(item uint32) (uint32, struct{}) {
-		return item, struct{}{}
-	})
-
-	for _, link := range links {
-		filters, err := netlink.FilterList(link, netlink.HANDLE_MIN_EGRESS)
-		if err != nil {
-			serviceLog.Error(err, "gc list filter", "link", link)
-			continue
-		}
-		for _, filter := range filters {
-			u32, ok := filter.(*netlink.U32)
-			if !ok {
-				continue
-			}
-			if u32.Priority != 50001 {
-				continue
-			}
-
-			if u32.Sel == nil || len(u32.Sel.Keys) != 1 {
-				continue
-			}
-			if len(u32.Actions) != 1 {
-				continue
-			}
-			_, ok = u32.Actions[0].(*netlink.VlanAction)
-			if !ok {
-				continue
-			}
-			if u32.Sel.Keys[0].Off != 12 {
-				continue
-			}
-			_, ok = u32IPMap[u32.Sel.Keys[0].Val]
-			if ok {
-				continue
-			}
-
-			serviceLog.Info("gc tc filter", "filter", filter)
-			err = netlink.FilterDel(filter)
-			if err != nil {
-				serviceLog.Error(err, "gc list filter", "link", link)
-			}
-		}
-	}
-}
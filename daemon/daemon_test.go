@@ -1,10 +1,13 @@
 package daemon
 
 import (
+	"context"
 	"net/netip"
 	"testing"
 
 	"github.com/AliyunContainerService/terway/pkg/aliyun/client"
+	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/pkg/eni"
 	factorymocks "github.com/AliyunContainerService/terway/pkg/factory/mocks"
 	k8smocks "github.com/AliyunContainerService/terway/pkg/k8s/mocks"
 	"github.com/AliyunContainerService/terway/pkg/utils/nodecap"
@@ -12,6 +15,8 @@ import (
 	"github.com/AliyunContainerService/terway/types/daemon"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"k8s.io/apimachinery/pkg/util/sets"
 )
 
 func Test_checkInstance1(t *testing.T) {
@@ -112,7 +117,7 @@ func Test_initTrunk(t *testing.T) {
 			},
 			preStart: func(args args) {
 				args.k8sClient.On("GetTrunkID").Return("")
-				args.f.On("CreateNetworkInterface", 1, 0, "trunk").Return(&daemon.ENI{
+				args.f.On("CreateNetworkInterface", mock.Anything, 1, 0, "trunk").Return(&daemon.ENI{
 					ID:               "eni-1",
 					MAC:              "",
 					SecurityGroupIDs: nil,
@@ -245,7 +250,7 @@ func Test_initTrunk(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.preStart(tt.args)
 
-			got, err := initTrunk(tt.args.config, tt.args.poolConfig, tt.args.k8sClient, tt.args.f)
+			got, err := initTrunk(context.Background(), tt.args.config, tt.args.poolConfig, tt.args.k8sClient, tt.args.f)
 			if !tt.wantErr(t, err) {
 				return
 			}
@@ -351,3 +356,141 @@ func TestFilterENINotFound(t *testing.T) {
 		}
 	}
 }
+
+func Test_podNetworkRequests(t *testing.T) {
+	pod := &daemon.PodInfo{
+		PodNetworks: []daemon.PodNetwork{
+			{Interface: "eth0", SecurityGroupIDs: []string{"sg-1"}},
+			{Interface: "eth1", VSwitchOptions: []string{"vsw-1"}, ExtraRoutes: []string{"10.0.0.0/8"}},
+		},
+	}
+	oldRes := daemon.PodResources{
+		Resources: []daemon.ResourceItem{
+			{Type: daemon.ResourceTypeENIIP, ENIID: "eni-0", IPv4: "192.0.2.1"},
+			{Type: daemon.ResourceTypeENIIP, ENIID: "eni-1", IPv4: "192.0.2.2", IfName: "eth1"},
+		},
+	}
+
+	reqs, err := podNetworkRequests(pod, oldRes)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(reqs))
+
+	req0 := reqs[0].(*eni.LocalIPRequest)
+	assert.Equal(t, "", req0.IfName)
+	assert.Equal(t, "eni-0", req0.NetworkInterfaceID)
+	assert.Equal(t, []string{"sg-1"}, req0.Network.SecurityGroupIDs)
+
+	req1 := reqs[1].(*eni.LocalIPRequest)
+	assert.Equal(t, "eth1", req1.IfName)
+	assert.Equal(t, "eni-1", req1.NetworkInterfaceID)
+	assert.Equal(t, "192.0.2.2", req1.IPv4.String())
+	assert.Equal(t, []string{"vsw-1"}, req1.Network.VSwitchOptions)
+
+	routes := podNetworkRoutes(pod, "eth1")
+	assert.Equal(t, 1, len(routes))
+	assert.Equal(t, "10.0.0.0/8", routes[0].Dst)
+	assert.Nil(t, podNetworkRoutes(pod, ""))
+
+	pod.PodNetworks = append(pod.PodNetworks, daemon.PodNetwork{Interface: "eth1"})
+	_, err = podNetworkRequests(pod, oldRes)
+	assert.Error(t, err)
+}
+
+func Test_podNetworkRequestsSecurityGroups(t *testing.T) {
+	pod := &daemon.PodInfo{SecurityGroups: []string{"sg-2"}}
+	reqs, err := podNetworkRequests(pod, daemon.PodResources{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(reqs))
+	assert.Equal(t, []string{"sg-2"}, reqs[0].(*eni.LocalIPRequest).Network.SecurityGroupIDs)
+
+	pod.ERdma = true
+	_, err = podNetworkRequests(pod, daemon.PodResources{})
+	assert.Error(t, err)
+}
+
+func Test_podSecurityGroups(t *testing.T) {
+	k8sMock := &k8smocks.Kubernetes{}
+	k8sMock.On("GetPodNetworking", mock.Anything, "pn").Return(&v1beta1.PodNetworking{
+		Spec: v1beta1.PodNetworkingSpec{SecurityGroupIDs: []string{"sg-4"}},
+	}, nil)
+	n := &networkService{
+		k8s:                k8sMock,
+		securityGroupIDs:   []string{"sg-1", "sg-2"},
+		securityGroupPools: sets.New[string]("sg-3"),
+	}
+
+	sgs, err := n.podSecurityGroups(context.Background(), &daemon.PodInfo{})
+	assert.NoError(t, err)
+	assert.Nil(t, sgs)
+
+	sgs, err = n.podSecurityGroups(context.Background(), &daemon.PodInfo{SecurityGroups: []string{"sg-2", "sg-1"}})
+	assert.NoError(t, err)
+	assert.Nil(t, sgs)
+
+	sgs, err = n.podSecurityGroups(context.Background(), &daemon.PodInfo{SecurityGroups: []string{"sg-3"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sg-3"}, sgs)
+
+	sgs, err = n.podSecurityGroups(context.Background(), &daemon.PodInfo{PodNetworking: "pn"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sg-4"}, sgs)
+
+	sgs, err = n.podSecurityGroups(context.Background(), &daemon.PodInfo{PodNetworking: "pn", SecurityGroups: []string{"sg-4"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sg-4"}, sgs)
+
+	// not configured by the admin
+	_, err = n.podSecurityGroups(context.Background(), &daemon.PodInfo{SecurityGroups: []string{"sg-5"}})
+	assert.Error(t, err)
+	_, err = n.podSecurityGroups(context.Background(), &daemon.PodInfo{PodNetworking: "pn", SecurityGroups: []string{"sg-5"}})
+	assert.Error(t, err)
+}
+
+func Test_podNetworkOfENI(t *testing.T) {
+	cfg := &types.ENIConfig{
+		VSwitchOptions:   []string{"vsw-1"},
+		SecurityGroupIDs: []string{"sg-1"},
+	}
+	assert.Nil(t, podNetworkOfENI(&daemon.ENI{VSwitchID: "vsw-1"}, cfg))
+	assert.Nil(t, podNetworkOfENI(&daemon.ENI{VSwitchID: "vsw-1", SecurityGroupIDs: []string{"sg-1"}}, cfg))
+
+	network := podNetworkOfENI(&daemon.ENI{VSwitchID: "vsw-2", SecurityGroupIDs: []string{"sg-1"}}, cfg)
+	assert.Equal(t, []string{"vsw-2"}, network.VSwitchOptions)
+
+	network = podNetworkOfENI(&daemon.ENI{VSwitchID: "vsw-1", SecurityGroupIDs: []string{"sg-2"}}, cfg)
+	assert.Equal(t, []string{"sg-2"}, network.SecurityGroupIDs)
+}
+
+func Test_reservedIPOf(t *testing.T) {
+	reservation := &v1beta1.IPReservation{
+		Spec: v1beta1.IPReservationSpec{
+			WorkloadName: "web",
+			VSwitchID:    "vsw-1",
+			Reservations: []v1beta1.Reservation{{Ordinal: 0}, {Ordinal: 1}},
+		},
+		Status: v1beta1.IPReservationStatus{
+			NetworkInterfaceID: "eni-holder",
+			Reservations: []v1beta1.ReservationStatus{
+				{Ordinal: 0, IPv4: "192.0.2.10", NetworkInterfaceID: "eni-holder"},
+			},
+		},
+	}
+
+	ip, err := reservedIPOf(reservation, &daemon.PodInfo{Name: "web-0", StatefulSet: "web"})
+	assert.NoError(t, err)
+	assert.Equal(t, &eni.ReservedIP{
+		IPv4:               netip.MustParseAddr("192.0.2.10"),
+		VSwitchID:          "vsw-1",
+		NetworkInterfaceID: "eni-holder",
+		Holder:             "eni-holder",
+	}, ip)
+
+	// not reserved yet
+	_, err = reservedIPOf(reservation, &daemon.PodInfo{Name: "web-1", StatefulSet: "web"})
+	assert.Error(t, err)
+
+	// ordinal not in the reservation
+	ip, err = reservedIPOf(reservation, &daemon.PodInfo{Name: "web-2", StatefulSet: "web"})
+	assert.NoError(t, err)
+	assert.Nil(t, ip)
+}
@@ -0,0 +1,406 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	"github.com/AliyunContainerService/terway/pkg/tracing"
+	"github.com/AliyunContainerService/terway/pkg/utils"
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+const (
+	// eventDBName is the bucket of the event journal, in the same db of the pod resources
+	eventDBName = "events"
+	// eventJournalSize the count of the events kept for the watcher to resume
+	eventJournalSize = 512
+	// eventWatcherBuffer the events buffered for each watcher, the watcher is dropped once full
+	eventWatcherBuffer = 128
+
+	tracingKeyEventWatchers    = "event_watchers"
+	tracingKeyEventResumeToken = "event_resume_token"
+)
+
+var errResumeTokenExpired = errors.New("resume token expired, watch again without the token")
+
+type journalEntry struct {
+	seq   uint64
+	event *rpc.PodEvent
+}
+
+// eventWatcher receive the events published, ch is closed if the watcher is too slow
+type eventWatcher struct {
+	ch chan *rpc.PodEvent
+}
+
+// eventHub publish the allocate, release and gc events to the watchers.
+// The events are kept in the resource db, so the watcher can resume with the token after the daemon restarted.
+// The db is written in background, the publish is not blocked by the disk.
+type eventHub struct {
+	sync.Mutex
+
+	db       storage.Storage
+	seq      uint64
+	journal  []journalEntry
+	watchers map[*eventWatcher]struct{}
+
+	// pending the events not persisted yet, persisting is true while the persist worker writing the db
+	persistLock sync.Mutex
+	persistCond *sync.Cond
+	pending     []journalEntry
+	persisting  bool
+	notify      chan struct{}
+}
+
+// newEventDB open the event journal in the resource db, memory storage is used if the resource db is not on disk
+func newEventDB(resourceDB storage.Storage) (storage.Storage, error) {
+	d, ok := resourceDB.(*storage.DiskStorage)
+	if !ok {
+		return storage.NewMemoryStorage(), nil
+	}
+	return d.Bucket(eventDBName, serializePodEvent, deserializePodEvent)
+}
+
+func serializePodEvent(item interface{}) ([]byte, error) {
+	return proto.Marshal(item.(*rpc.PodEvent))
+}
+
+func deserializePodEvent(data []byte) (interface{}, error) {
+	ev := &rpc.PodEvent{}
+	err := proto.Unmarshal(data, ev)
+	if err != nil {
+		return nil, err
+	}
+	return ev, nil
+}
+
+func eventKey(seq uint64) string {
+	return fmt.Sprintf("%020d", seq)
+}
+
+func newEventHub(db storage.Storage) (*eventHub, error) {
+	objs, err := db.List()
+	if err != nil {
+		return nil, err
+	}
+
+	h := &eventHub{
+		db:       db,
+		watchers: make(map[*eventWatcher]struct{}),
+		notify:   make(chan struct{}, 1),
+	}
+	h.persistCond = sync.NewCond(&h.persistLock)
+	for _, obj := range objs {
+		ev := obj.(*rpc.PodEvent)
+		seq, err := strconv.ParseUint(ev.ResumeToken, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid event %s in db, %w", ev.ResumeToken, err)
+		}
+		h.journal = append(h.journal, journalEntry{seq: seq, event: ev})
+	}
+	sort.Slice(h.journal, func(i, j int) bool {
+		return h.journal[i].seq < h.journal[j].seq
+	})
+	if len(h.journal) > 0 {
+		h.seq = h.journal[len(h.journal)-1].seq
+	}
+	for len(h.journal) > eventJournalSize {
+		err = db.Delete(eventKey(h.journal[0].seq))
+		if err != nil {
+			return nil, fmt.Errorf("error delete event %d, %w", h.journal[0].seq, err)
+		}
+		h.journal = h.journal[1:]
+	}
+
+	go h.persist()
+
+	return h, nil
+}
+
+// publish assign the resume token to the event, and send it to all watchers
+func (h *eventHub) publish(ev *rpc.PodEvent) {
+	h.Lock()
+	defer h.Unlock()
+
+	h.seq++
+	ev.ResumeToken = strconv.FormatUint(h.seq, 10)
+	if ev.Timestamp == 0 {
+		ev.Timestamp = time.Now().Unix()
+	}
+
+	entry := journalEntry{seq: h.seq, event: ev}
+	h.journal = append(h.journal, entry)
+	if len(h.journal) > eventJournalSize {
+		h.journal = h.journal[len(h.journal)-eventJournalSize:]
+	}
+	h.enqueue(entry)
+
+	for w := range h.watchers {
+		select {
+		case w.ch <- ev:
+		default:
+			// the watcher resume with the last token received
+			close(w.ch)
+			delete(h.watchers, w)
+		}
+	}
+}
+
+// enqueue the event to persist, the ones out of the journal are not persisted
+func (h *eventHub) enqueue(entry journalEntry) {
+	h.persistLock.Lock()
+	h.pending = append(h.pending, entry)
+	if len(h.pending) > eventJournalSize {
+		h.pending = h.pending[len(h.pending)-eventJournalSize:]
+	}
+	h.persistLock.Unlock()
+
+	select {
+	case h.notify <- struct{}{}:
+	default:
+	}
+}
+
+// persist write the pending events to the db, and delete the one out of the journal
+func (h *eventHub) persist() {
+	for range h.notify {
+		h.persistLock.Lock()
+		pending := h.pending
+		h.pending = nil
+		h.persisting = true
+		h.persistLock.Unlock()
+
+		for _, e := range pending {
+			err := h.db.Put(eventKey(e.seq), e.event)
+			if err != nil {
+				serviceLog.Error(err, "error persist event", "token", e.event.ResumeToken)
+			}
+			if e.seq > eventJournalSize {
+				err = h.db.Delete(eventKey(e.seq - eventJournalSize))
+				if err != nil {
+					serviceLog.Error(err, "error delete event", "seq", e.seq-eventJournalSize)
+				}
+			}
+		}
+
+		h.persistLock.Lock()
+		h.persisting = false
+		h.persistCond.Broadcast()
+		h.persistLock.Unlock()
+	}
+}
+
+// flush wait the events published are persisted
+func (h *eventHub) flush() {
+	h.persistLock.Lock()
+	defer h.persistLock.Unlock()
+
+	for len(h.pending) > 0 || h.persisting {
+		h.persistCond.Wait()
+	}
+}
+
+// watch register a watcher, return the events after the token and the current token.
+// Without the token, the events returned are taken by the snapshot, which is called with the watcher registered
+// under the same lock, so no event is lost or sent twice in between.
+func (h *eventHub) watch(token string, snapshot func(token string) ([]*rpc.PodEvent, error)) ([]*rpc.PodEvent, string, *eventWatcher, error) {
+	h.Lock()
+	defer h.Unlock()
+
+	var replay []*rpc.PodEvent
+	if token == "" && snapshot != nil {
+		var err error
+		replay, err = snapshot(strconv.FormatUint(h.seq, 10))
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
+	if token != "" {
+		from, err := strconv.ParseUint(token, 10, 64)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("invalid resume token %s", token)
+		}
+		// the journal is trimmed, or the db is recreated
+		oldest := h.seq + 1
+		if len(h.journal) > 0 {
+			oldest = h.journal[0].seq
+		}
+		if from > h.seq || from+1 < oldest {
+			return nil, "", nil, errResumeTokenExpired
+		}
+		for _, e := range h.journal {
+			if e.seq > from {
+				replay = append(replay, e.event)
+			}
+		}
+	}
+
+	w := &eventWatcher{ch: make(chan *rpc.PodEvent, eventWatcherBuffer)}
+	h.watchers[w] = struct{}{}
+
+	return replay, strconv.FormatUint(h.seq, 10), w, nil
+}
+
+func (h *eventHub) unwatch(w *eventWatcher) {
+	h.Lock()
+	defer h.Unlock()
+
+	delete(h.watchers, w)
+}
+
+func (h *eventHub) trace() []tracing.MapKeyValueEntry {
+	h.Lock()
+	defer h.Unlock()
+
+	return []tracing.MapKeyValueEntry{
+		{Key: tracingKeyEventWatchers, Value: fmt.Sprint(len(h.watchers))},
+		{Key: tracingKeyEventResumeToken, Value: strconv.FormatUint(h.seq, 10)},
+	}
+}
+
+// newPodEvent build the event from the pod resources stored
+func newPodEvent(typ rpc.PodEventType, res daemon.PodResources) *rpc.PodEvent {
+	ev := &rpc.PodEvent{
+		Type: typ,
+	}
+	if res.PodInfo != nil {
+		ev.K8SPodName = res.PodInfo.Name
+		ev.K8SPodNamespace = res.PodInfo.Namespace
+	}
+
+	var netConf []*rpc.NetConf
+	if res.NetConf != "" && json.Unmarshal([]byte(res.NetConf), &netConf) == nil {
+		for _, c := range netConf {
+			network := &rpc.PodEventNetwork{IfName: c.IfName}
+			if c.BasicInfo != nil {
+				network.PodIP = c.BasicInfo.PodIP
+			}
+			if c.ENIInfo != nil {
+				network.MAC = c.ENIInfo.MAC
+				network.Vid = c.ENIInfo.Vid
+			}
+			ev.Networks = append(ev.Networks, network)
+		}
+		return ev
+	}
+
+	// the net conf is not recorded, build from the resources
+	for _, item := range res.Resources {
+		if item.IPv4 == "" && item.IPv6 == "" {
+			continue
+		}
+		ev.Networks = append(ev.Networks, &rpc.PodEventNetwork{
+			IfName: item.IfName,
+			PodIP:  &rpc.IPSet{IPv4: item.IPv4, IPv6: item.IPv6},
+			MAC:    item.ENIMAC,
+		})
+	}
+	return ev
+}
+
+// publishPodEvent publish the event of the pod resources, nothing is done if the event hub is not enabled
+func (n *networkService) publishPodEvent(typ rpc.PodEventType, res daemon.PodResources) {
+	if n.eventHub == nil {
+		return
+	}
+	n.eventHub.publish(newPodEvent(typ, res))
+}
+
+// podEventsSnapshot return the allocate events of the pods currently have resources
+func (n *networkService) podEventsSnapshot(token string) ([]*rpc.PodEvent, error) {
+	objList, err := n.resourceDB.List()
+	if err != nil {
+		return nil, err
+	}
+	podResources := getPodResources(objList)
+	sort.Slice(podResources, func(i, j int) bool {
+		return podEventKey(podResources[i]) < podEventKey(podResources[j])
+	})
+
+	now := time.Now().Unix()
+	events := make([]*rpc.PodEvent, 0, len(podResources))
+	for _, res := range podResources {
+		ev := newPodEvent(rpc.PodEventType_PodEventAllocate, res)
+		ev.ResumeToken = token
+		ev.Timestamp = now
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+func podEventKey(res daemon.PodResources) string {
+	if res.PodInfo == nil {
+		return ""
+	}
+	return utils.PodInfoKey(res.PodInfo.Namespace, res.PodInfo.Name)
+}
+
+// watchEvents register the watcher, the snapshot of the current pods is returned if no token is given.
+// The lock of the service is taken before the one of the event hub, same as the publishers.
+func (n *networkService) watchEvents(token string) ([]*rpc.PodEvent, *eventWatcher, error) {
+	var snapshot func(token string) ([]*rpc.PodEvent, error)
+	if token == "" {
+		n.RLock()
+		defer n.RUnlock()
+		snapshot = n.podEventsSnapshot
+	}
+
+	replay, _, w, err := n.eventHub.watch(token, snapshot)
+	if err != nil {
+		if errors.Is(err, errResumeTokenExpired) {
+			return nil, nil, status.Error(codes.OutOfRange, err.Error())
+		}
+		if token == "" {
+			return nil, nil, status.Error(codes.Internal, err.Error())
+		}
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return replay, w, nil
+}
+
+// WatchEvents stream the allocate, release and gc events.
+// Without the resume token, the allocate events of the current pods are sent first.
+func (n *networkService) WatchEvents(r *rpc.WatchEventsRequest, stream rpc.TerwayBackend_WatchEventsServer) error {
+	if n.eventHub == nil {
+		return status.Error(codes.Unimplemented, "watch events is not supported")
+	}
+
+	replay, w, err := n.watchEvents(r.ResumeToken)
+	if err != nil {
+		return err
+	}
+	defer n.eventHub.unwatch(w)
+
+	for _, ev := range replay {
+		err = stream.Send(ev)
+		if err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case ev, ok := <-w.ch:
+			if !ok {
+				return status.Error(codes.Aborted, "too slow to receive the events, watch again with the last resume token")
+			}
+			err = stream.Send(ev)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
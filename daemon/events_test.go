@@ -0,0 +1,225 @@
+package daemon
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+func newTestPodResources(name, ipv4 string) daemon.PodResources {
+	return daemon.PodResources{
+		PodInfo: &daemon.PodInfo{Namespace: "default", Name: name},
+		Resources: []daemon.ResourceItem{
+			{Type: daemon.ResourceTypeENIIP, ENIID: "eni-1", ENIMAC: "00:00:00:00:00:01", IPv4: ipv4},
+		},
+	}
+}
+
+func TestEventHub_Resume(t *testing.T) {
+	resDB, err := daemon.NewResDB(filepath.Join(t.TempDir(), "test.db"))
+	assert.NoError(t, err)
+	db, err := newEventDB(resDB)
+	assert.NoError(t, err)
+
+	h, err := newEventHub(db)
+	assert.NoError(t, err)
+	h.publish(newPodEvent(rpc.PodEventType_PodEventAllocate, newTestPodResources("a", "192.0.2.1")))
+	h.publish(newPodEvent(rpc.PodEventType_PodEventAllocate, newTestPodResources("b", "192.0.2.2")))
+	h.publish(newPodEvent(rpc.PodEventType_PodEventRelease, newTestPodResources("a", "192.0.2.1")))
+	h.flush()
+
+	// the journal is loaded from the db
+	h, err = newEventHub(db)
+	assert.NoError(t, err)
+
+	replay, token, w, err := h.watch("1", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "3", token)
+	assert.Len(t, replay, 2)
+	assert.Equal(t, "b", replay[0].K8SPodName)
+	assert.Equal(t, rpc.PodEventType_PodEventRelease, replay[1].Type)
+	assert.Equal(t, "3", replay[1].ResumeToken)
+
+	h.publish(newPodEvent(rpc.PodEventType_PodEventGC, newTestPodResources("b", "192.0.2.2")))
+	ev := <-w.ch
+	assert.Equal(t, "4", ev.ResumeToken)
+	assert.Equal(t, rpc.PodEventType_PodEventGC, ev.Type)
+
+	_, _, _, err = h.watch("5", nil)
+	assert.ErrorIs(t, err, errResumeTokenExpired)
+
+	_, _, _, err = h.watch("foo", nil)
+	assert.Error(t, err)
+}
+
+func TestEventHub_Trim(t *testing.T) {
+	h, err := newEventHub(storage.NewMemoryStorage())
+	assert.NoError(t, err)
+	for i := 0; i < eventJournalSize+2; i++ {
+		h.publish(&rpc.PodEvent{})
+	}
+	h.flush()
+
+	items, err := h.db.List()
+	assert.NoError(t, err)
+	assert.Len(t, items, eventJournalSize)
+
+	_, _, _, err = h.watch("1", nil)
+	assert.ErrorIs(t, err, errResumeTokenExpired)
+
+	replay, _, _, err := h.watch("2", nil)
+	assert.NoError(t, err)
+	assert.Len(t, replay, eventJournalSize)
+}
+
+func TestEventHub_SlowWatcher(t *testing.T) {
+	h, err := newEventHub(storage.NewMemoryStorage())
+	assert.NoError(t, err)
+
+	_, _, w, err := h.watch("", nil)
+	assert.NoError(t, err)
+	for i := 0; i < eventWatcherBuffer+1; i++ {
+		h.publish(&rpc.PodEvent{})
+	}
+
+	for i := 0; i < eventWatcherBuffer; i++ {
+		<-w.ch
+	}
+	_, ok := <-w.ch
+	assert.False(t, ok)
+	assert.Len(t, h.watchers, 0)
+}
+
+// blockingStorage block the Put until released
+type blockingStorage struct {
+	storage.Storage
+	release chan struct{}
+}
+
+func (b *blockingStorage) Put(key string, value interface{}) error {
+	<-b.release
+	return b.Storage.Put(key, value)
+}
+
+func TestEventHub_PersistInBackground(t *testing.T) {
+	db := &blockingStorage{Storage: storage.NewMemoryStorage(), release: make(chan struct{})}
+	h, err := newEventHub(db)
+	assert.NoError(t, err)
+
+	_, _, w, err := h.watch("", nil)
+	assert.NoError(t, err)
+	h.publish(&rpc.PodEvent{})
+	h.publish(&rpc.PodEvent{})
+	// sent before persisted
+	assert.Equal(t, "1", (<-w.ch).ResumeToken)
+	assert.Equal(t, "2", (<-w.ch).ResumeToken)
+
+	close(db.release)
+	h.flush()
+	items, err := db.List()
+	assert.NoError(t, err)
+	assert.Len(t, items, 2)
+}
+
+func TestEventHub_WatchSnapshot(t *testing.T) {
+	h, err := newEventHub(storage.NewMemoryStorage())
+	assert.NoError(t, err)
+	h.publish(&rpc.PodEvent{})
+	h.publish(&rpc.PodEvent{})
+
+	replay, token, w, err := h.watch("", func(token string) ([]*rpc.PodEvent, error) {
+		// taken under the same lock, right before the watcher is registered
+		assert.Len(t, h.watchers, 0)
+		return []*rpc.PodEvent{{K8SPodName: "a", ResumeToken: token}}, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "2", token)
+	assert.Len(t, replay, 1)
+	assert.Equal(t, "2", replay[0].ResumeToken)
+
+	h.publish(&rpc.PodEvent{})
+	assert.Equal(t, "3", (<-w.ch).ResumeToken)
+}
+
+func TestNewPodEvent(t *testing.T) {
+	res := newTestPodResources("a", "192.0.2.1")
+	ev := newPodEvent(rpc.PodEventType_PodEventAllocate, res)
+	assert.Equal(t, []*rpc.PodEventNetwork{{PodIP: &rpc.IPSet{IPv4: "192.0.2.1"}, MAC: "00:00:00:00:00:01"}}, ev.Networks)
+
+	// prefer the net conf, which has the vlan
+	res.NetConf = `[{"BasicInfo":{"PodIP":{"IPv4":"192.0.2.1"}},"ENIInfo":{"MAC":"00:00:00:00:00:02","Trunk":true,"Vid":100},"IfName":"eth0"}]`
+	ev = newPodEvent(rpc.PodEventType_PodEventAllocate, res)
+	assert.Equal(t, "default", ev.K8SPodNamespace)
+	assert.Equal(t, "a", ev.K8SPodName)
+	assert.Len(t, ev.Networks, 1)
+	assert.Equal(t, "eth0", ev.Networks[0].IfName)
+	assert.Equal(t, "192.0.2.1", ev.Networks[0].PodIP.IPv4)
+	assert.Equal(t, "00:00:00:00:00:02", ev.Networks[0].MAC)
+	assert.Equal(t, uint32(100), ev.Networks[0].Vid)
+}
+
+type fakeWatchEventsServer struct {
+	grpc.ServerStream
+
+	ctx    context.Context
+	events chan *rpc.PodEvent
+}
+
+func (f *fakeWatchEventsServer) Context() context.Context {
+	return f.ctx
+}
+
+func (f *fakeWatchEventsServer) Send(ev *rpc.PodEvent) error {
+	f.events <- ev
+	return nil
+}
+
+func TestWatchEvents(t *testing.T) {
+	h, err := newEventHub(storage.NewMemoryStorage())
+	assert.NoError(t, err)
+	n := &networkService{
+		resourceDB: storage.NewMemoryStorage(),
+		eventHub:   h,
+	}
+	_ = n.resourceDB.Put("default/a", newTestPodResources("a", "192.0.2.1"))
+	n.publishPodEvent(rpc.PodEventType_PodEventAllocate, newTestPodResources("a", "192.0.2.1"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &fakeWatchEventsServer{ctx: ctx, events: make(chan *rpc.PodEvent, 10)}
+	done := make(chan error)
+	go func() {
+		done <- n.WatchEvents(&rpc.WatchEventsRequest{}, stream)
+	}()
+
+	// the current pods
+	ev := <-stream.events
+	assert.Equal(t, rpc.PodEventType_PodEventAllocate, ev.Type)
+	assert.Equal(t, "a", ev.K8SPodName)
+	assert.Equal(t, "1", ev.ResumeToken)
+
+	assert.Eventually(t, func() bool {
+		h.Lock()
+		defer h.Unlock()
+		return len(h.watchers) == 1
+	}, time.Second, 10*time.Millisecond)
+	n.publishPodEvent(rpc.PodEventType_PodEventRelease, newTestPodResources("a", "192.0.2.1"))
+	ev = <-stream.events
+	assert.Equal(t, rpc.PodEventType_PodEventRelease, ev.Type)
+	assert.Equal(t, "2", ev.ResumeToken)
+
+	cancel()
+	assert.NoError(t, <-done)
+
+	err = n.WatchEvents(&rpc.WatchEventsRequest{ResumeToken: "10"}, stream)
+	assert.Equal(t, codes.OutOfRange, status.Code(err))
+}
@@ -0,0 +1,174 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/AliyunContainerService/terway/pkg/eni"
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	"github.com/AliyunContainerService/terway/pkg/tracing"
+	"github.com/AliyunContainerService/terway/pkg/utils"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+const (
+	prewarmPeriod = 5 * time.Second
+	// prewarmMaxBatch the max ip count allocated in one round
+	prewarmMaxBatch = 10
+	prewarmTimeout  = 2 * time.Minute
+
+	tracingKeyPrewarmLastRun    = "prewarm_last_run"
+	tracingKeyPrewarmPredicted  = "prewarm_predicted_pods"
+	tracingKeyPrewarmAllocating = "prewarm_allocating"
+	tracingKeyPrewarmAllocated  = "prewarm_allocated_total"
+	tracingKeyPrewarmError      = "prewarm_error"
+)
+
+// prewarmer record the prediction of the pending pods, which pods are scheduled to this node
+// but the sandbox is not created yet.
+type prewarmer struct {
+	sync.Mutex
+
+	lastRun    time.Time
+	predicted  []string
+	allocating int
+	allocated  int
+	lastErr    error
+}
+
+func (p *prewarmer) trace() []tracing.MapKeyValueEntry {
+	p.Lock()
+	defer p.Unlock()
+
+	errMsg := ""
+	if p.lastErr != nil {
+		errMsg = p.lastErr.Error()
+	}
+	return []tracing.MapKeyValueEntry{
+		{Key: tracingKeyPrewarmLastRun, Value: p.lastRun.String()},
+		{Key: tracingKeyPrewarmPredicted, Value: strings.Join(p.predicted, " ")},
+		{Key: tracingKeyPrewarmAllocating, Value: fmt.Sprint(p.allocating)},
+		{Key: tracingKeyPrewarmAllocated, Value: fmt.Sprint(p.allocated)},
+		{Key: tracingKeyPrewarmError, Value: errMsg},
+	}
+}
+
+func (n *networkService) startPrewarmLoop(ctx context.Context) {
+	wait.JitterUntil(func() {
+		n.prewarmPendingPods(ctx)
+	}, prewarmPeriod, 0.2, true, ctx.Done())
+}
+
+// predictPendingPods return the pods which will call cni add soon.
+// Pods already in processing (pendingPods) or already have resource in db is excluded.
+func (n *networkService) predictPendingPods(pods []*daemon.PodInfo) ([]string, error) {
+	var predicted []string
+	for _, pod := range pods {
+		if !pod.SandboxPending {
+			continue
+		}
+		// resource is not allocated from local pool
+		if pod.PodENI || pod.ERdma || !n.verifyPodNetworkType(pod.PodNetworkType) {
+			continue
+		}
+
+		podID := utils.PodInfoKey(pod.Namespace, pod.Name)
+		if _, ok := n.pendingPods.Load(podID); ok {
+			continue
+		}
+
+		_, err := n.resourceDB.Get(podID)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, storage.ErrNotFound) {
+			return nil, err
+		}
+
+		predicted = append(predicted, podID)
+	}
+	sort.Strings(predicted)
+	return predicted, nil
+}
+
+// prewarmPendingPods allocate ip to the pool for the pending pods, before the cni add is called
+func (n *networkService) prewarmPendingPods(ctx context.Context) {
+	predicted, toAdd, err := n.prewarmPredict()
+
+	n.prewarm.Lock()
+	n.prewarm.lastRun = time.Now()
+	n.prewarm.predicted = predicted
+	n.prewarm.allocating = toAdd
+	n.prewarm.lastErr = err
+	n.prewarm.Unlock()
+
+	if err != nil {
+		serviceLog.Error(err, "prewarm predict failed")
+		return
+	}
+	if toAdd <= 0 {
+		return
+	}
+
+	serviceLog.Info("prewarm for pending pods", "pods", len(predicted), "toAdd", toAdd)
+
+	ctx, cancel := context.WithTimeout(ctx, prewarmTimeout)
+	defer cancel()
+
+	// the allocation may take minutes, don't block the cni requests by holding the lock
+	n.RLock()
+	eniMgr := n.eniMgr
+	n.RUnlock()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < toAdd; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := eniMgr.Allocate(ctx, &daemon.CNI{}, &eni.AllocRequest{
+				ResourceRequests: []eni.ResourceRequest{
+					&eni.LocalIPRequest{NoCache: true},
+				},
+			})
+
+			n.prewarm.Lock()
+			defer n.prewarm.Unlock()
+			n.prewarm.allocating--
+			if err != nil {
+				n.prewarm.lastErr = err
+				return
+			}
+			n.prewarm.allocated++
+		}()
+	}
+	wg.Wait()
+}
+
+// prewarmPredict return the predicted pods and the ip count need to allocate
+func (n *networkService) prewarmPredict() ([]string, int, error) {
+	pods, err := n.k8s.GetLocalPods()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	n.RLock()
+	defer n.RUnlock()
+
+	predicted, err := n.predictPendingPods(pods)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	idles, _, available := n.eniMgr.Usage()
+
+	toAdd := min(len(predicted)-idles, available, prewarmMaxBatch)
+	return predicted, max(toAdd, 0), nil
+}
@@ -0,0 +1,63 @@
+package daemon
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/AliyunContainerService/terway/pkg/eni"
+	k8smocks "github.com/AliyunContainerService/terway/pkg/k8s/mocks"
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+func TestPredictPendingPods(t *testing.T) {
+	n := &networkService{
+		daemonMode: daemon.ModeENIMultiIP,
+		resourceDB: storage.NewMemoryStorage(),
+	}
+	n.pendingPods.Store("default/processing", struct{}{})
+	_ = n.resourceDB.Put("default/allocated", daemon.PodResources{})
+
+	pods := []*daemon.PodInfo{
+		{Name: "pending", Namespace: "default", SandboxPending: true, PodNetworkType: daemon.PodNetworkTypeENIMultiIP},
+		{Name: "running", Namespace: "default", PodNetworkType: daemon.PodNetworkTypeENIMultiIP},
+		{Name: "processing", Namespace: "default", SandboxPending: true, PodNetworkType: daemon.PodNetworkTypeENIMultiIP},
+		{Name: "allocated", Namespace: "default", SandboxPending: true, PodNetworkType: daemon.PodNetworkTypeENIMultiIP},
+		{Name: "trunk", Namespace: "default", SandboxPending: true, PodENI: true, PodNetworkType: daemon.PodNetworkTypeENIMultiIP},
+	}
+
+	predicted, err := n.predictPendingPods(pods)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"default/pending"}, predicted)
+}
+
+func TestPrewarmPendingPods(t *testing.T) {
+	k8sClient := k8smocks.NewKubernetes(t)
+	k8sClient.On("GetLocalPods").Return([]*daemon.PodInfo{
+		{Name: "pending-1", Namespace: "default", SandboxPending: true, PodNetworkType: daemon.PodNetworkTypeENIMultiIP},
+		{Name: "pending-2", Namespace: "default", SandboxPending: true, PodNetworkType: daemon.PodNetworkTypeENIMultiIP},
+	}, nil)
+
+	n := &networkService{
+		daemonMode: daemon.ModeENIMultiIP,
+		resourceDB: storage.NewMemoryStorage(),
+		k8s:        k8sClient,
+		eniMgr:     eni.NewManager(0, 0, 1, 0, nil, types.EniSelectionPolicyMostIPs, k8sClient),
+		prewarm:    &prewarmer{},
+	}
+
+	n.prewarmPendingPods(context.Background())
+
+	trace := map[string]string{}
+	for _, v := range n.Trace() {
+		trace[v.Key] = v.Value
+	}
+	assert.Equal(t, "default/pending-1 default/pending-2", trace[tracingKeyPrewarmPredicted])
+	// capacity is limited to 1, and no eni can handle the request
+	assert.Equal(t, "0", trace[tracingKeyPrewarmAllocating])
+	assert.Equal(t, "0", trace[tracingKeyPrewarmAllocated])
+	assert.NotEmpty(t, trace[tracingKeyPrewarmError])
+}
@@ -0,0 +1,209 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+const (
+	probeTimeout = 2 * time.Second
+
+	probePass = "PASS"
+	probeFail = "FAIL"
+	probeSkip = "SKIP"
+
+	probeGatewayNeigh = "gateway-neigh"
+	probeGatewayPing  = "gateway-ping"
+	probeDNS          = "dns"
+	probeService      = "service"
+	probeNodePing     = "node-ping"
+
+	// probeDNSName is queried to the cluster resolver, any response is treated as the resolver is reachable
+	probeDNSName = "kubernetes.default.svc.cluster.local."
+)
+
+// probeResult is the result of a probe run in the pod netns
+type probeResult struct {
+	name    string
+	status  string
+	detail  string
+	elapsed time.Duration
+}
+
+func (r probeResult) String() string {
+	return fmt.Sprintf("[%s] %s: %s (%s)\n", r.status, r.name, r.detail, r.elapsed.Round(time.Microsecond))
+}
+
+// probeTargets is the addresses probed from the pod, resolved in host netns
+type probeTargets struct {
+	// dnsServer is the cluster resolver, the cluster ip of kube-dns
+	dnsServer string
+	// service is the cluster ip and port of the kubernetes service
+	service string
+}
+
+// probePod run the probes in the netns of the pod, the results are sent to message once finished.
+// The probes are implemented in go, so nothing is required in the container image.
+func (n *networkService) probePod(args []string, message chan<- string) {
+	if len(args) != 1 {
+		message <- "usage: probe <namespace/name>\n"
+		return
+	}
+
+	n.RLock()
+	obj, err := n.resourceDB.Get(args[0])
+	n.RUnlock()
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			message <- fmt.Sprintf("pod %s is not found on this node\n", args[0])
+			return
+		}
+		message <- fmt.Sprintf("error get pod %s, %s\n", args[0], err)
+		return
+	}
+	podRes := obj.(daemon.PodResources)
+	if podRes.NetNs == nil || *podRes.NetNs == "" {
+		message <- fmt.Sprintf("netns of pod %s is not recorded\n", args[0])
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	targets := n.probeTargets(ctx)
+	cancel()
+
+	count := make(map[string]int)
+	runPodProbes(*podRes.NetNs, targets, func(r probeResult) {
+		count[r.status]++
+		message <- r.String()
+	})
+	message <- fmt.Sprintf("%d passed, %d failed, %d skipped\n", count[probePass], count[probeFail], count[probeSkip])
+}
+
+// probeTargets look up the kube-dns and the kubernetes service, the target is empty if not found
+func (n *networkService) probeTargets(ctx context.Context) probeTargets {
+	t := probeTargets{}
+	if n.k8s == nil {
+		return t
+	}
+
+	c := n.k8s.GetClient()
+	svc, err := getService(ctx, c, "kube-system", "kube-dns")
+	if err != nil {
+		serviceLog.Error(err, "error get kube-dns service")
+	} else if svc.Spec.ClusterIP != "" && svc.Spec.ClusterIP != corev1.ClusterIPNone {
+		t.dnsServer = net.JoinHostPort(svc.Spec.ClusterIP, "53")
+	}
+
+	svc, err = getService(ctx, c, "default", "kubernetes")
+	if err != nil {
+		serviceLog.Error(err, "error get kubernetes service")
+	} else if svc.Spec.ClusterIP != "" && len(svc.Spec.Ports) > 0 {
+		t.service = net.JoinHostPort(svc.Spec.ClusterIP, strconv.Itoa(int(svc.Spec.Ports[0].Port)))
+	}
+	return t
+}
+
+func getService(ctx context.Context, c k8sclient.Client, namespace, name string) (*corev1.Service, error) {
+	obj := &corev1.Service{}
+	err := c.Get(ctx, k8stypes.NamespacedName{Namespace: namespace, Name: name}, obj, &k8sclient.GetOptions{Raw: &metav1.GetOptions{
+		ResourceVersion: "0",
+	}})
+	return obj, err
+}
+
+// runProbe time the probe, the result is skipped if the target is empty
+func runProbe(name, target string, probe func() (string, error)) probeResult {
+	if target == "" {
+		return probeResult{name: name, status: probeSkip, detail: "target is not found"}
+	}
+
+	start := time.Now()
+	detail, err := probe()
+	r := probeResult{name: name, status: probePass, detail: detail, elapsed: time.Since(start)}
+	if err != nil {
+		r.status = probeFail
+		r.detail = err.Error()
+	}
+	return r
+}
+
+// probeDNSServer send a query to the resolver, any response is ok as the resolver is reachable
+func probeDNSServer(server, name string) (string, error) {
+	id := uint16(rand.Intn(1 << 16))
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, RecursionDesired: true})
+	err := b.StartQuestions()
+	if err != nil {
+		return "", err
+	}
+	qName, err := dnsmessage.NewName(name)
+	if err != nil {
+		return "", err
+	}
+	err = b.Question(dnsmessage.Question{Name: qName, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET})
+	if err != nil {
+		return "", err
+	}
+	query, err := b.Finish()
+	if err != nil {
+		return "", err
+	}
+
+	conn, err := net.DialTimeout("udp", server, probeTimeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(probeTimeout))
+
+	_, err = conn.Write(query)
+	if err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, 512)
+	for {
+		size, err := conn.Read(buf)
+		if err != nil {
+			return "", fmt.Errorf("no response from %s, %w", server, err)
+		}
+
+		var p dnsmessage.Parser
+		h, err := p.Start(buf[:size])
+		if err != nil || h.ID != id || !h.Response {
+			continue
+		}
+		err = p.SkipAllQuestions()
+		if err != nil {
+			return "", err
+		}
+		answers, err := p.AllAnswers()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s responded %s with %d answers for %s", server, h.RCode, len(answers), name), nil
+	}
+}
+
+// probeTCPConnect connect to the address
+func probeTCPConnect(addr string) (string, error) {
+	conn, err := net.DialTimeout("tcp", addr, probeTimeout)
+	if err != nil {
+		return "", err
+	}
+	_ = conn.Close()
+	return fmt.Sprintf("connected to %s", addr), nil
+}
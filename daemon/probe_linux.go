@@ -0,0 +1,155 @@
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+
+	"github.com/AliyunContainerService/terway/plugin/driver/utils"
+)
+
+// neighResolved is the neigh states the lladdr is known
+const neighResolved = netlink.NUD_PERMANENT | netlink.NUD_NOARP | netlink.NUD_REACHABLE | netlink.NUD_STALE | netlink.NUD_DELAY | netlink.NUD_PROBE
+
+// runPodProbes run the probes in the netns, only ipv4 is probed.
+// The sockets are created in the locked thread, so they belong to the pod netns.
+func runPodProbes(netNSPath string, targets probeTargets, report func(r probeResult)) {
+	// the node ip is resolved in host netns
+	nodeIP := ""
+	hostIP, err := utils.GetHostIP(true, false)
+	if err == nil && hostIP.IPv4 != nil {
+		nodeIP = hostIP.IPv4.IP.String()
+	}
+
+	err = ns.WithNetNSPath(netNSPath, func(netNS ns.NetNS) error {
+		gw, linkIndex, err := defaultGateway()
+		if err != nil {
+			report(probeResult{name: probeGatewayNeigh, status: probeFail, detail: err.Error()})
+			report(probeResult{name: probeGatewayPing, status: probeSkip, detail: "gateway is not found"})
+		} else {
+			report(runProbe(probeGatewayNeigh, gw.String(), func() (string, error) {
+				return probeNeigh(gw, linkIndex)
+			}))
+			report(runProbe(probeGatewayPing, gw.String(), func() (string, error) {
+				return probePing(gw)
+			}))
+		}
+
+		report(runProbe(probeDNS, targets.dnsServer, func() (string, error) {
+			return probeDNSServer(targets.dnsServer, probeDNSName)
+		}))
+		report(runProbe(probeService, targets.service, func() (string, error) {
+			return probeTCPConnect(targets.service)
+		}))
+		report(runProbe(probeNodePing, nodeIP, func() (string, error) {
+			return probePing(net.ParseIP(nodeIP))
+		}))
+		return nil
+	})
+	if err != nil {
+		report(probeResult{name: "netns", status: probeFail, detail: err.Error()})
+	}
+}
+
+// defaultGateway return the gateway and the link of the ipv4 default route
+func defaultGateway() (net.IP, int, error) {
+	routes, err := netlink.RouteList(nil, netlink.FAMILY_V4)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error list routes, %w", err)
+	}
+	for _, r := range routes {
+		if r.Gw == nil {
+			continue
+		}
+		if r.Dst != nil {
+			if ones, _ := r.Dst.Mask.Size(); ones != 0 {
+				continue
+			}
+		}
+		return r.Gw, r.LinkIndex, nil
+	}
+	return nil, 0, fmt.Errorf("ipv4 default route is not found")
+}
+
+// probeNeigh check the lladdr of the ip is resolved, a packet is sent to trigger the resolution if not
+func probeNeigh(ip net.IP, linkIndex int) (string, error) {
+	deadline := time.Now().Add(probeTimeout)
+	triggered := false
+	for {
+		neighs, err := netlink.NeighList(linkIndex, netlink.FAMILY_V4)
+		if err != nil {
+			return "", fmt.Errorf("error list neigh, %w", err)
+		}
+		for _, neigh := range neighs {
+			if neigh.IP.Equal(ip) && neigh.State&neighResolved != 0 && len(neigh.HardwareAddr) > 0 {
+				return fmt.Sprintf("%s lladdr %s", ip, neigh.HardwareAddr), nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("lladdr of %s is not resolved", ip)
+		}
+		if !triggered {
+			triggered = true
+			conn, err := net.Dial("udp", net.JoinHostPort(ip.String(), "9"))
+			if err == nil {
+				_, _ = conn.Write([]byte{0})
+				_ = conn.Close()
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// probePing send an icmp echo to the ip and wait for the reply
+func probePing(ip net.IP) (string, error) {
+	conn, err := icmp.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(probeTimeout))
+
+	id := os.Getpid() & 0xffff
+	req := icmp.Message{
+		Type: ipv4.ICMPTypeEcho,
+		Body: &icmp.Echo{ID: id, Seq: 1, Data: []byte("terway-probe")},
+	}
+	data, err := req.Marshal(nil)
+	if err != nil {
+		return "", err
+	}
+
+	start := time.Now()
+	_, err = conn.WriteTo(data, &net.IPAddr{IP: ip})
+	if err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		size, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			return "", fmt.Errorf("no reply from %s, %w", ip, err)
+		}
+		addr, ok := peer.(*net.IPAddr)
+		if !ok || !addr.IP.Equal(ip) {
+			continue
+		}
+		reply, err := icmp.ParseMessage(ipv4.ICMPTypeEchoReply.Protocol(), buf[:size])
+		if err != nil || reply.Type != ipv4.ICMPTypeEchoReply {
+			continue
+		}
+		echo, ok := reply.Body.(*icmp.Echo)
+		if !ok || echo.ID != id {
+			continue
+		}
+		return fmt.Sprintf("reply from %s in %s", ip, time.Since(start).Round(time.Microsecond)), nil
+	}
+}
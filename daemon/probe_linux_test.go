@@ -0,0 +1,79 @@
+//go:build privileged
+
+package daemon
+
+import (
+	"net"
+	"runtime"
+	"testing"
+
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/containernetworking/plugins/pkg/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink"
+)
+
+func TestRunPodProbes(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	hostNS, err := testutils.NewNS()
+	require.NoError(t, err)
+	containerNS, err := testutils.NewNS()
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, containerNS.Close())
+		assert.NoError(t, testutils.UnmountNS(containerNS))
+		assert.NoError(t, hostNS.Close())
+		assert.NoError(t, testutils.UnmountNS(hostNS))
+	}()
+	require.NoError(t, hostNS.Set())
+
+	// host 192.168.100.1 <-> pod 192.168.100.2, default route via the host
+	err = netlink.LinkAdd(&netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "host"}, PeerName: "eth0"})
+	require.NoError(t, err)
+	host, err := netlink.LinkByName("host")
+	require.NoError(t, err)
+	require.NoError(t, netlink.AddrAdd(host, &netlink.Addr{IPNet: &net.IPNet{IP: net.ParseIP("192.168.100.1"), Mask: net.CIDRMask(24, 32)}}))
+	require.NoError(t, netlink.LinkSetUp(host))
+	lo, err := netlink.LinkByName("lo")
+	require.NoError(t, err)
+	require.NoError(t, netlink.LinkSetUp(lo))
+
+	peer, err := netlink.LinkByName("eth0")
+	require.NoError(t, err)
+	require.NoError(t, netlink.LinkSetNsFd(peer, int(containerNS.Fd())))
+	err = containerNS.Do(func(netNS ns.NetNS) error {
+		eth0, err := netlink.LinkByName("eth0")
+		if err != nil {
+			return err
+		}
+		err = netlink.AddrAdd(eth0, &netlink.Addr{IPNet: &net.IPNet{IP: net.ParseIP("192.168.100.2"), Mask: net.CIDRMask(24, 32)}})
+		if err != nil {
+			return err
+		}
+		err = netlink.LinkSetUp(eth0)
+		if err != nil {
+			return err
+		}
+		return netlink.RouteAdd(&netlink.Route{LinkIndex: eth0.Attrs().Index, Gw: net.ParseIP("192.168.100.1")})
+	})
+	require.NoError(t, err)
+
+	l, err := net.Listen("tcp", "192.168.100.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	results := make(map[string]probeResult)
+	runPodProbes(containerNS.Path(), probeTargets{service: l.Addr().String()}, func(r probeResult) {
+		results[r.name] = r
+	})
+
+	assert.Equal(t, probePass, results[probeGatewayNeigh].status, results[probeGatewayNeigh].detail)
+	assert.Equal(t, probePass, results[probeGatewayPing].status, results[probeGatewayPing].detail)
+	assert.Equal(t, probeSkip, results[probeDNS].status)
+	assert.Equal(t, probePass, results[probeService].status, results[probeService].detail)
+	// no default route in host netns
+	assert.Equal(t, probeSkip, results[probeNodePing].status)
+}
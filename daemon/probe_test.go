@@ -0,0 +1,131 @@
+package daemon
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	k8smocks "github.com/AliyunContainerService/terway/pkg/k8s/mocks"
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+func collectMessages(f func(message chan<- string)) []string {
+	ch := make(chan string, 10)
+	f(ch)
+	close(ch)
+
+	var result []string
+	for m := range ch {
+		result = append(result, m)
+	}
+	return result
+}
+
+func TestProbePod_Invalid(t *testing.T) {
+	n := &networkService{resourceDB: storage.NewMemoryStorage()}
+	_ = n.resourceDB.Put("default/no-netns", daemon.PodResources{PodInfo: &daemon.PodInfo{Namespace: "default", Name: "no-netns"}})
+	netNs := "/proc/not-exist/ns/net"
+	_ = n.resourceDB.Put("default/netns-gone", daemon.PodResources{PodInfo: &daemon.PodInfo{Namespace: "default", Name: "netns-gone"}, NetNs: &netNs})
+
+	assert.Equal(t, []string{"usage: probe <namespace/name>\n"}, collectMessages(func(message chan<- string) {
+		n.probePod(nil, message)
+	}))
+	assert.Equal(t, []string{"pod default/foo is not found on this node\n"}, collectMessages(func(message chan<- string) {
+		n.probePod([]string{"default/foo"}, message)
+	}))
+	assert.Equal(t, []string{"netns of pod default/no-netns is not recorded\n"}, collectMessages(func(message chan<- string) {
+		n.probePod([]string{"default/no-netns"}, message)
+	}))
+
+	messages := collectMessages(func(message chan<- string) {
+		n.probePod([]string{"default/netns-gone"}, message)
+	})
+	require.Len(t, messages, 2)
+	assert.Equal(t, "0 passed, 1 failed, 0 skipped\n", messages[1])
+}
+
+func TestProbeTargets(t *testing.T) {
+	c := fake.NewClientBuilder().WithObjects(
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "kube-dns"},
+			Spec:       corev1.ServiceSpec{ClusterIP: "10.96.0.10"},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "kubernetes"},
+			Spec:       corev1.ServiceSpec{ClusterIP: "10.96.0.1", Ports: []corev1.ServicePort{{Port: 443}}},
+		},
+	).Build()
+	k8sClient := k8smocks.NewKubernetes(t)
+	k8sClient.On("GetClient").Return(c)
+
+	n := &networkService{k8s: k8sClient}
+	assert.Equal(t, probeTargets{dnsServer: "10.96.0.10:53", service: "10.96.0.1:443"}, n.probeTargets(context.Background()))
+}
+
+func TestRunProbe(t *testing.T) {
+	r := runProbe(probeDNS, "", nil)
+	assert.Equal(t, probeSkip, r.status)
+	assert.Equal(t, "[SKIP] dns: target is not found (0s)\n", r.String())
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	r = runProbe(probeService, l.Addr().String(), func() (string, error) {
+		return probeTCPConnect(l.Addr().String())
+	})
+	assert.Equal(t, probePass, r.status)
+	assert.Equal(t, "connected to "+l.Addr().String(), r.detail)
+
+	addr := l.Addr().String()
+	_ = l.Close()
+	r = runProbe(probeService, addr, func() (string, error) {
+		return probeTCPConnect(addr)
+	})
+	assert.Equal(t, probeFail, r.status)
+}
+
+func TestProbeDNSServer(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// reply NXDOMAIN for any query
+	go func() {
+		buf := make([]byte, 512)
+		size, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		var p dnsmessage.Parser
+		h, err := p.Start(buf[:size])
+		if err != nil {
+			return
+		}
+		q, err := p.Question()
+		if err != nil {
+			return
+		}
+
+		b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: h.ID, Response: true, RCode: dnsmessage.RCodeNameError})
+		_ = b.StartQuestions()
+		_ = b.Question(q)
+		resp, err := b.Finish()
+		if err != nil {
+			return
+		}
+		_, _ = conn.WriteTo(resp, peer)
+	}()
+
+	detail, err := probeDNSServer(conn.LocalAddr().String(), probeDNSName)
+	assert.NoError(t, err)
+	assert.Equal(t, conn.LocalAddr().String()+" responded RCodeNameError with 0 answers for "+probeDNSName, detail)
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package daemon
+
+func runPodProbes(netNSPath string, targets probeTargets, report func(r probeResult)) {
+	report(probeResult{name: "netns", status: probeFail, detail: "probe is not supported on this platform"})
+}
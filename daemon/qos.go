@@ -0,0 +1,145 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	"github.com/AliyunContainerService/terway/pkg/utils"
+	driverutils "github.com/AliyunContainerService/terway/plugin/driver/utils"
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+const (
+	qosPendingInterval = 500 * time.Millisecond
+	qosPendingTimeout  = 10 * time.Second
+
+	eventReasonQoSUpdated      = "QoSUpdated"
+	eventReasonQoSUpdateFailed = "QoSUpdateFailed"
+)
+
+// errPodDatapathNotReady is returned if the cni add is not finished for the pod
+var errPodDatapathNotReady = errors.New("datapath of the pod is not set up")
+
+func podQoSEqual(a, b *daemon.PodInfo) bool {
+	return a.TcIngress == b.TcIngress && a.TcEgress == b.TcEgress && a.NetworkPriority == b.NetworkPriority
+}
+
+func podQoSString(pod *daemon.PodInfo) string {
+	return fmt.Sprintf("ingress %d egress %d priority %q", pod.TcIngress, pod.TcEgress, pod.NetworkPriority)
+}
+
+// podQoSChanged return the pod resources in db if the qos of the running pod is changed
+func (n *networkService) podQoSChanged(podID string, pod *daemon.PodInfo) (daemon.PodResources, bool) {
+	obj, err := n.resourceDB.Get(podID)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			serviceLog.Error(err, "error get pod resource", "pod", podID)
+		}
+		return daemon.PodResources{}, false
+	}
+	podRes := obj.(daemon.PodResources)
+	if podRes.PodInfo == nil || podRes.NetNs == nil || *podRes.NetNs == "" {
+		return daemon.PodResources{}, false
+	}
+	// the pod is recreated, the new sandbox is not created yet
+	if podRes.PodInfo.PodUID != "" && podRes.PodInfo.PodUID != pod.PodUID {
+		return daemon.PodResources{}, false
+	}
+	if podQoSEqual(podRes.PodInfo, pod) {
+		return daemon.PodResources{}, false
+	}
+	return podRes, true
+}
+
+// updatePodQoS apply the bandwidth and network priority changed by the pod annotation to the running pod,
+// the pod info in db is updated after the datapath is updated
+func (n *networkService) updatePodQoS(pod *daemon.PodInfo) {
+	podID := utils.PodInfoKey(pod.Namespace, pod.Name)
+	l := serviceLog.WithValues("pod", podID)
+
+	// the pod is not changed by the alloc or release during the update, other pods is not blocked
+	err := wait.PollUntilContextTimeout(context.Background(), qosPendingInterval, qosPendingTimeout, true, func(ctx context.Context) (bool, error) {
+		_, exist := n.pendingPods.LoadOrStore(podID, struct{}{})
+		return !exist, nil
+	})
+	if err != nil {
+		l.Info("pod is processing, skip the qos update")
+		return
+	}
+	defer n.pendingPods.Delete(podID)
+
+	n.RLock()
+	_, changed := n.podQoSChanged(podID, pod)
+	n.RUnlock()
+	if !changed {
+		return
+	}
+
+	// the datapath of the pod is changed by the cni with the lock held
+	lock, err := driverutils.GrabFileLock(cniLockPath)
+	if err != nil {
+		l.Error(err, "error grab cni lock, skip the qos update")
+		return
+	}
+	defer lock.Close()
+
+	n.RLock()
+	defer n.RUnlock()
+
+	podRes, changed := n.podQoSChanged(podID, pod)
+	if !changed {
+		return
+	}
+
+	l.Info("pod qos changed", "old", podQoSString(podRes.PodInfo), "new", podQoSString(pod))
+
+	err = applyPodQoS(podRes, pod)
+	if errors.Is(err, errPodDatapathNotReady) {
+		l.Info("datapath of the pod is not set up, skip the qos update")
+		return
+	}
+	if err != nil {
+		l.Error(err, "error update pod qos")
+		_ = n.k8s.RecordPodEvent(pod.Name, pod.Namespace, corev1.EventTypeWarning, eventReasonQoSUpdateFailed,
+			fmt.Sprintf("Update %s failed, %s", podQoSString(pod), err))
+		return
+	}
+
+	podRes.PodInfo.TcIngress = pod.TcIngress
+	podRes.PodInfo.TcEgress = pod.TcEgress
+	podRes.PodInfo.NetworkPriority = pod.NetworkPriority
+
+	// the net conf is returned to cni check, keep it consist with the pod info
+	var netConf []*rpc.NetConf
+	err = json.Unmarshal([]byte(podRes.NetConf), &netConf)
+	if err == nil {
+		for _, c := range netConf {
+			if c.Pod == nil {
+				c.Pod = &rpc.Pod{}
+			}
+			c.Pod.Ingress = pod.TcIngress
+			c.Pod.Egress = pod.TcEgress
+			c.Pod.NetworkPriority = pod.NetworkPriority
+		}
+		out, err := json.Marshal(netConf)
+		if err == nil {
+			podRes.NetConf = string(out)
+		}
+	}
+
+	err = n.resourceDB.Put(podID, podRes)
+	if err != nil {
+		l.Error(err, "error update pod resource")
+	}
+
+	_ = n.k8s.RecordPodEvent(pod.Name, pod.Namespace, corev1.EventTypeNormal, eventReasonQoSUpdated,
+		fmt.Sprintf("Updated %s", podQoSString(pod)))
+}
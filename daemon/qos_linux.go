@@ -0,0 +1,198 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/vishvananda/netlink"
+
+	"github.com/AliyunContainerService/terway/pkg/link"
+	"github.com/AliyunContainerService/terway/plugin/datapath"
+	drivertypes "github.com/AliyunContainerService/terway/plugin/driver/types"
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+const defaultContainerIfName = "eth0"
+
+var (
+	cniConfLock sync.Mutex
+	cniConf     *drivertypes.CNIConf
+)
+
+// getCNIConf return the terway plugin config, the config list is written before the daemon started, so it is read once
+func getCNIConf() (*drivertypes.CNIConf, error) {
+	cniConfLock.Lock()
+	defer cniConfLock.Unlock()
+
+	if cniConf != nil {
+		return cniConf, nil
+	}
+	conf, err := loadCNIConf()
+	if err != nil {
+		return nil, err
+	}
+	cniConf = conf
+	return cniConf, nil
+}
+
+// loadCNIConf read the terway plugin config from the cni config list
+func loadCNIConf() (*drivertypes.CNIConf, error) {
+	content, err := os.ReadFile(filepath.Join(tmpCNIConfigPath, cinConfFile))
+	if err != nil {
+		return nil, err
+	}
+	confList := struct {
+		Plugins []json.RawMessage `json:"plugins"`
+	}{}
+	err = json.Unmarshal(content, &confList)
+	if err != nil {
+		return nil, fmt.Errorf("error parse %s, %w", cinConfFile, err)
+	}
+	for _, raw := range confList.Plugins {
+		conf := &drivertypes.CNIConf{}
+		err = json.Unmarshal(raw, conf)
+		if err != nil {
+			return nil, fmt.Errorf("error parse %s, %w", cinConfFile, err)
+		}
+		if conf.Type == "terway" {
+			return conf, nil
+		}
+	}
+	return nil, fmt.Errorf("terway plugin not found in %s", cinConfFile)
+}
+
+// podDataPath return the datapath the cni used for the pod
+func podDataPath(pod *daemon.PodInfo, conf *drivertypes.CNIConf, netConf *rpc.NetConf) drivertypes.DataPath {
+	ipType := rpc.IPType_TypeENIMultiIP
+	switch pod.PodNetworkType {
+	case daemon.PodNetworkTypeVPCIP:
+		ipType = rpc.IPType_TypeVPCIP
+	case daemon.PodNetworkTypeVPCENI:
+		ipType = rpc.IPType_TypeVPCENI
+	}
+
+	dp := drivertypes.GetDataPath(ipType, conf.VlanStripType, netConf.GetENIInfo().GetTrunk())
+	if dp != drivertypes.IPVlan {
+		return dp
+	}
+	if conf.IPVlan() {
+		available, err := datapath.CheckIPVLanAvailable()
+		if err == nil && available {
+			return drivertypes.IPVlan
+		}
+	}
+	return drivertypes.PolicyRoute
+}
+
+// qosSetupConfig build the config for datapath.UpdateQoS, only the qos related fields are set
+func qosSetupConfig(pod *daemon.PodInfo, conf *drivertypes.CNIConf, netConf *rpc.NetConf) (*drivertypes.SetupConfig, error) {
+	cfg := &drivertypes.SetupConfig{
+		DP:                    podDataPath(pod, conf, netConf),
+		ContainerIfName:       netConf.IfName,
+		BandwidthMode:         conf.BandwidthMode,
+		EnableNetworkPriority: conf.EnableNetworkPriority,
+		Ingress:               pod.TcIngress,
+		Egress:                pod.TcEgress,
+		NetworkPriority:       datapath.PrioMap[pod.NetworkPriority],
+	}
+	if cfg.ContainerIfName == "" {
+		cfg.ContainerIfName = defaultContainerIfName
+	}
+
+	var err error
+	if cfg.DP == drivertypes.VPCRoute {
+		// the ip is allocated by the delegate ipam
+		cfg.ContainerIPNet = &types.IPNetSet{}
+		if pod.PodIPs.IPv4 != nil {
+			cfg.ContainerIPNet.IPv4 = &net.IPNet{IP: pod.PodIPs.IPv4, Mask: net.CIDRMask(32, 32)}
+		}
+		if cfg.ContainerIPNet.IPv4 == nil {
+			return nil, fmt.Errorf("pod ip is not found")
+		}
+	} else {
+		cfg.ContainerIPNet, err = types.BuildIPNet(netConf.GetBasicInfo().GetPodIP(), netConf.GetBasicInfo().GetPodCIDR())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if mac := netConf.GetENIInfo().GetMAC(); mac != "" {
+		index, err := link.GetDeviceNumber(mac)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ENIIndex = int(index)
+	}
+
+	cfg.HostVETHName, err = link.VethNameForPod(pod.Name, pod.Namespace, netConf.IfName, link.HostVethPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// applyPodQoS update the bandwidth and network priority in the datapath of the running pod
+func applyPodQoS(podRes daemon.PodResources, pod *daemon.PodInfo) error {
+	conf, err := getCNIConf()
+	if err != nil {
+		return err
+	}
+
+	var netConf []*rpc.NetConf
+	err = json.Unmarshal([]byte(podRes.NetConf), &netConf)
+	if err != nil {
+		return fmt.Errorf("error parse net conf, %w", err)
+	}
+
+	netNS, err := ns.GetNS(*podRes.NetNs)
+	if err != nil {
+		return err
+	}
+	defer netNS.Close()
+
+	// the pod is written to db before the cni set up the datapath
+	err = netNS.Do(func(_ ns.NetNS) error {
+		for _, c := range netConf {
+			if c.GetBasicInfo() == nil {
+				continue
+			}
+			ifName := c.IfName
+			if ifName == "" {
+				ifName = defaultContainerIfName
+			}
+			_, err := netlink.LinkByName(ifName)
+			if err != nil {
+				if _, ok := err.(netlink.LinkNotFoundError); ok {
+					return errPodDatapathNotReady
+				}
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, c := range netConf {
+		if c.GetBasicInfo() == nil {
+			continue
+		}
+		cfg, err := qosSetupConfig(pod, conf, c)
+		if err != nil {
+			return err
+		}
+		err = datapath.UpdateQoS(cfg, netNS)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
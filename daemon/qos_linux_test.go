@@ -0,0 +1,73 @@
+package daemon
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AliyunContainerService/terway/pkg/link"
+	"github.com/AliyunContainerService/terway/plugin/datapath"
+	drivertypes "github.com/AliyunContainerService/terway/plugin/driver/types"
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+func TestQoSSetupConfig(t *testing.T) {
+	conf := &drivertypes.CNIConf{BandwidthMode: drivertypes.BandwidthModeEDT, EnableNetworkPriority: true}
+	pod := &daemon.PodInfo{
+		Name:            "pod-1",
+		Namespace:       "default",
+		PodNetworkType:  daemon.PodNetworkTypeENIMultiIP,
+		PodIPs:          types.IPSet{IPv4: net.ParseIP("192.168.0.10")},
+		TcIngress:       1000,
+		TcEgress:        2000,
+		NetworkPriority: string(types.NetworkPrioGuaranteed),
+	}
+	netConf := &rpc.NetConf{
+		BasicInfo: &rpc.BasicInfo{
+			PodIP:   &rpc.IPSet{IPv4: "192.168.0.10"},
+			PodCIDR: &rpc.IPSet{IPv4: "192.168.0.0/24"},
+		},
+	}
+
+	cfg, err := qosSetupConfig(pod, conf, netConf)
+	require.NoError(t, err)
+	assert.Equal(t, drivertypes.PolicyRoute, cfg.DP)
+	assert.Equal(t, "eth0", cfg.ContainerIfName)
+	assert.Equal(t, "192.168.0.10/24", cfg.ContainerIPNet.IPv4.String())
+	assert.Equal(t, uint64(1000), cfg.Ingress)
+	assert.Equal(t, uint64(2000), cfg.Egress)
+	assert.Equal(t, datapath.PrioMap[string(types.NetworkPrioGuaranteed)], cfg.NetworkPriority)
+	assert.Equal(t, drivertypes.BandwidthModeEDT, cfg.BandwidthMode)
+
+	vethName, err := link.VethNameForPod("pod-1", "default", "", link.HostVethPrefix)
+	require.NoError(t, err)
+	assert.Equal(t, vethName, cfg.HostVETHName)
+
+	// the ip is from the pod status in vpc route
+	pod.PodNetworkType = daemon.PodNetworkTypeVPCIP
+	cfg, err = qosSetupConfig(pod, conf, &rpc.NetConf{BasicInfo: &rpc.BasicInfo{}})
+	require.NoError(t, err)
+	assert.Equal(t, drivertypes.VPCRoute, cfg.DP)
+	assert.Equal(t, "192.168.0.10/32", cfg.ContainerIPNet.IPv4.String())
+
+	pod.PodNetworkType = daemon.PodNetworkTypeVPCENI
+	cfg, err = qosSetupConfig(pod, conf, netConf)
+	require.NoError(t, err)
+	assert.Equal(t, drivertypes.ExclusiveENI, cfg.DP)
+}
+
+func TestPodDataPath(t *testing.T) {
+	conf := &drivertypes.CNIConf{VlanStripType: drivertypes.VlanStripTypeVlan}
+	trunk := &rpc.NetConf{ENIInfo: &rpc.ENIInfo{Trunk: true}}
+
+	assert.Equal(t, drivertypes.VPCRoute, podDataPath(&daemon.PodInfo{PodNetworkType: daemon.PodNetworkTypeVPCIP}, conf, &rpc.NetConf{}))
+	assert.Equal(t, drivertypes.ExclusiveENI, podDataPath(&daemon.PodInfo{PodNetworkType: daemon.PodNetworkTypeVPCENI}, conf, &rpc.NetConf{}))
+	assert.Equal(t, drivertypes.Vlan, podDataPath(&daemon.PodInfo{PodNetworkType: daemon.PodNetworkTypeVPCENI}, conf, trunk))
+	assert.Equal(t, drivertypes.Vlan, podDataPath(&daemon.PodInfo{PodNetworkType: daemon.PodNetworkTypeENIMultiIP}, conf, trunk))
+	// ipvlan is not enabled
+	assert.Equal(t, drivertypes.PolicyRoute, podDataPath(&daemon.PodInfo{PodNetworkType: daemon.PodNetworkTypeENIMultiIP}, conf, &rpc.NetConf{}))
+}
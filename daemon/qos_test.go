@@ -0,0 +1,63 @@
+package daemon
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+
+	k8smocks "github.com/AliyunContainerService/terway/pkg/k8s/mocks"
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	driverutils "github.com/AliyunContainerService/terway/plugin/driver/utils"
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+func TestUpdatePodQoS(t *testing.T) {
+	netNs := "/proc/not-exist/ns/net"
+	netConf, err := json.Marshal([]*rpc.NetConf{
+		{
+			BasicInfo: &rpc.BasicInfo{PodIP: &rpc.IPSet{IPv4: "192.168.0.10"}},
+			Pod:       &rpc.Pod{Ingress: 1000},
+		},
+	})
+	require.NoError(t, err)
+
+	stored := &daemon.PodInfo{Name: "pod-1", Namespace: "default", PodUID: "uid-1", TcIngress: 1000}
+
+	k8sClient := k8smocks.NewKubernetes(t)
+	// the netns is not exist, so the update is failed
+	k8sClient.On("RecordPodEvent", "pod-1", "default", corev1.EventTypeWarning, eventReasonQoSUpdateFailed, mock.Anything).Return(nil).Once()
+
+	cniLockPath = filepath.Join(t.TempDir(), "terway_cni.lock")
+	t.Cleanup(func() {
+		cniLockPath = driverutils.CNILockPath
+	})
+
+	n := &networkService{
+		resourceDB: storage.NewMemoryStorage(),
+		k8s:        k8sClient,
+	}
+	_ = n.resourceDB.Put("default/pod-1", daemon.PodResources{
+		PodInfo: stored,
+		NetNs:   &netNs,
+		NetConf: string(netConf),
+	})
+
+	// not found in db
+	n.updatePodQoS(&daemon.PodInfo{Name: "pod-2", Namespace: "default", TcIngress: 2000})
+	// not changed
+	n.updatePodQoS(&daemon.PodInfo{Name: "pod-1", Namespace: "default", PodUID: "uid-1", TcIngress: 1000})
+	// the pod is recreated
+	n.updatePodQoS(&daemon.PodInfo{Name: "pod-1", Namespace: "default", PodUID: "uid-2", TcIngress: 2000})
+
+	n.updatePodQoS(&daemon.PodInfo{Name: "pod-1", Namespace: "default", PodUID: "uid-1", TcIngress: 2000})
+
+	obj, err := n.resourceDB.Get("default/pod-1")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1000), obj.(daemon.PodResources).PodInfo.TcIngress)
+}
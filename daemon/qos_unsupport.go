@@ -0,0 +1,13 @@
+//go:build !linux
+
+package daemon
+
+import (
+	"fmt"
+
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+func applyPodQoS(podRes daemon.PodResources, pod *daemon.PodInfo) error {
+	return fmt.Errorf("update qos for running pod is not supported")
+}
@@ -0,0 +1,226 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/AliyunContainerService/terway/pkg/metric"
+	"github.com/AliyunContainerService/terway/pkg/tracing"
+	"github.com/AliyunContainerService/terway/pkg/utils"
+	driverutils "github.com/AliyunContainerService/terway/plugin/driver/utils"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+const (
+	datapathReconcilePeriod = time.Minute
+
+	tracingKeyDatapathReconcileMode    = "datapath_reconcile_mode"
+	tracingKeyDatapathReconcileLastRun = "datapath_reconcile_last_run"
+	tracingKeyDatapathReconcileDrift   = "datapath_reconcile_drift"
+	tracingKeyDatapathReconcileError   = "datapath_reconcile_error"
+)
+
+// cniLockPath is the file lock held by the cni while changing the datapath
+var cniLockPath = driverutils.CNILockPath
+
+// datapathChange is one drift between the expected and the actual state in host netns
+type datapathChange struct {
+	kind   string
+	action string
+	desc   string
+	// ip is the pod ip the entry belongs to
+	ip    string
+	apply func() error
+}
+
+func (c datapathChange) String() string {
+	return fmt.Sprintf("%s %s %s", c.action, c.kind, c.desc)
+}
+
+// datapathSnapshot is the pods in db the datapath is diffed against
+type datapathSnapshot struct {
+	podResources []daemon.PodResources
+	// inTransition is the pods in cni processing or being deleted, the entries of them are not added back
+	inTransition sets.Set[string]
+}
+
+// ips return the pod ips in the snapshot, the ips of the pods in transition is excluded if settled is true
+func (s *datapathSnapshot) ips(settled bool) sets.Set[string] {
+	ips := sets.New[string]()
+	for _, podRes := range s.podResources {
+		if podRes.PodInfo == nil {
+			continue
+		}
+		if settled && s.isInTransition(podRes) {
+			continue
+		}
+		ips.Insert(podResourceIPs(podRes)...)
+	}
+	return ips
+}
+
+func (s *datapathSnapshot) isInTransition(podRes daemon.PodResources) bool {
+	return s.inTransition.Has(utils.PodInfoKey(podRes.PodInfo.Namespace, podRes.PodInfo.Name))
+}
+
+// podResourceIPs return the ips recorded for the pod
+func podResourceIPs(podRes daemon.PodResources) []string {
+	var ips []string
+	for _, ip := range []net.IP{podRes.PodInfo.PodIPs.IPv4, podRes.PodInfo.PodIPs.IPv6} {
+		if ip != nil {
+			ips = append(ips, ip.String())
+		}
+	}
+	for _, res := range podRes.Resources {
+		for _, ip := range []string{res.IPv4, res.IPv6} {
+			if parsed := net.ParseIP(ip); parsed != nil {
+				ips = append(ips, parsed.String())
+			}
+		}
+	}
+	return ips
+}
+
+// datapathReconciler record the state of the periodic reconcile for the datapath in host netns.
+// The expected state is derived from the resource db, entries for pods not on this node are removed,
+// and the missing entries for pods on this node are added back.
+type datapathReconciler struct {
+	sync.Mutex
+
+	mode    string
+	lastRun time.Time
+	drift   []string
+	lastErr error
+}
+
+func (r *datapathReconciler) trace() []tracing.MapKeyValueEntry {
+	r.Lock()
+	defer r.Unlock()
+
+	errMsg := ""
+	if r.lastErr != nil {
+		errMsg = r.lastErr.Error()
+	}
+	return []tracing.MapKeyValueEntry{
+		{Key: tracingKeyDatapathReconcileMode, Value: r.mode},
+		{Key: tracingKeyDatapathReconcileLastRun, Value: r.lastRun.String()},
+		{Key: tracingKeyDatapathReconcileDrift, Value: strings.Join(r.drift, "; ")},
+		{Key: tracingKeyDatapathReconcileError, Value: errMsg},
+	}
+}
+
+func (n *networkService) startDatapathReconcileLoop(ctx context.Context) {
+	wait.JitterUntil(func() {
+		err := n.reconcileDatapath()
+		if err != nil {
+			serviceLog.Error(err, "error reconcile datapath")
+		}
+	}, datapathReconcilePeriod, 0.2, true, ctx.Done())
+}
+
+// snapshotDatapath return the pods in db, the pods in cni processing, or deleted and waiting for the ip released are in transition
+func (n *networkService) snapshotDatapath() (*datapathSnapshot, error) {
+	n.RLock()
+	defer n.RUnlock()
+
+	objList, err := n.resourceDB.List()
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &datapathSnapshot{podResources: getPodResources(objList), inTransition: sets.New[string]()}
+	for _, podRes := range snapshot.podResources {
+		if podRes.PodInfo == nil {
+			continue
+		}
+		podID := utils.PodInfoKey(podRes.PodInfo.Namespace, podRes.PodInfo.Name)
+		if _, ok := n.pendingPods.Load(podID); ok || podRes.PodInfo.SandboxExited || !podRes.PodInfo.IPReleaseAt.IsZero() {
+			snapshot.inTransition.Insert(podID)
+		}
+	}
+	return snapshot, nil
+}
+
+// reconcileDatapath diff the state in host netns against the pods in db, the drift is handled by the mode.
+// The lock is only held for the snapshot of the db, so the cni requests is not blocked by the netlink calls.
+func (n *networkService) reconcileDatapath() error {
+	r := n.datapathReconciler
+
+	var changes []datapathChange
+	snapshot, err := n.snapshotDatapath()
+	if err == nil {
+		changes, err = datapathChanges(snapshot)
+	}
+
+	drift := make([]string, 0, len(changes))
+	metric.DatapathDrift.Reset()
+	for _, c := range changes {
+		metric.DatapathDrift.WithLabelValues(c.kind, c.action).Inc()
+		drift = append(drift, c.String())
+
+		if r.mode == daemon.DatapathReconcileDryRun {
+			serviceLog.Info("datapath drift found, dry-run", "kind", c.kind, "action", c.action, "entry", c.desc)
+		}
+	}
+
+	if err == nil && r.mode == daemon.DatapathReconcileEnforce && len(changes) > 0 {
+		err = n.repairDatapath(snapshot, changes)
+	}
+
+	r.Lock()
+	r.lastRun = time.Now()
+	r.drift = drift
+	r.lastErr = err
+	r.Unlock()
+
+	return err
+}
+
+// repairDatapath apply the changes with the cni lock held, as the cni write the db before setting up the datapath,
+// and tear down the datapath before removing the pod from db.
+// The db is read and diffed again under the lock, only the changes still found are applied,
+// and the pods added or removed since the snapshot is left to the next round.
+func (n *networkService) repairDatapath(snapshot *datapathSnapshot, changes []datapathChange) error {
+	l, err := driverutils.GrabFileLock(cniLockPath)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	latest, err := n.snapshotDatapath()
+	if err != nil {
+		return err
+	}
+	changed := snapshot.ips(true).SymmetricDifference(latest.ips(true)).
+		Union(snapshot.ips(false).SymmetricDifference(latest.ips(false)))
+
+	latestChanges, err := datapathChanges(latest)
+	if err != nil {
+		return err
+	}
+
+	found := sets.New[string]()
+	for _, c := range changes {
+		found.Insert(c.String())
+	}
+	for _, c := range latestChanges {
+		if !found.Has(c.String()) || changed.Has(c.ip) {
+			continue
+		}
+		serviceLog.Info("datapath drift found, repair", "kind", c.kind, "action", c.action, "entry", c.desc)
+		applyErr := c.apply()
+		if applyErr != nil {
+			metric.DatapathRepaired.WithLabelValues(c.kind, c.action, metric.DatapathRepairFail).Inc()
+			serviceLog.Error(applyErr, "error repair datapath", "kind", c.kind, "action", c.action, "entry", c.desc)
+			continue
+		}
+		metric.DatapathRepaired.WithLabelValues(c.kind, c.action, metric.DatapathRepairSucceed).Inc()
+	}
+	return nil
+}
@@ -0,0 +1,539 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/AliyunContainerService/terway/pkg/link"
+	"github.com/AliyunContainerService/terway/pkg/metric"
+	"github.com/AliyunContainerService/terway/plugin/datapath"
+	"github.com/AliyunContainerService/terway/plugin/driver/utils"
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/AliyunContainerService/terway/types"
+)
+
+// hostState is the expected state in host netns derived from the pod resources
+type hostState struct {
+	// owned is all the pod ip on this node, entries belong to these ip is never treated as stale
+	owned sets.Set[string]
+
+	rules   []*netlink.Rule
+	routes  []*netlink.Route
+	filters []*netlink.U32
+}
+
+// actualState is the state in host netns
+type actualState struct {
+	rules   []netlink.Rule
+	routes  []netlink.Route
+	neighs  []netlink.Neigh
+	filters []netlink.Filter
+
+	// terwayLinks is the index of the host veth and ipvlan slave links
+	terwayLinks sets.Set[int]
+}
+
+func datapathChanges(snapshot *datapathSnapshot) ([]datapathChange, error) {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return nil, fmt.Errorf("error list links, %w", err)
+	}
+
+	expected := expectedHostState(snapshot, links)
+
+	actual, err := listHostState(links)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []datapathChange
+	changes = append(changes, diffRules(expected, actual)...)
+	changes = append(changes, diffRoutes(expected, actual)...)
+	changes = append(changes, diffNeighs(expected, actual)...)
+	changes = append(changes, diffFilters(expected, actual)...)
+	return changes, nil
+}
+
+// expectedHostState derive the entries in host netns from the net conf of each pod.
+// Only the pods whose host side link is found is taken into account, so the pod not setup by cni yet,
+// or the pod use exclusive eni is skipped. The pods in transition or whose netns is gone is skipped too,
+// the entries of them are removed by the cni del.
+func expectedHostState(snapshot *datapathSnapshot, links []netlink.Link) *hostState {
+	state := &hostState{owned: sets.New[string]()}
+
+	byMAC := make(map[string]netlink.Link)
+	byName := make(map[string]netlink.Link)
+	for _, l := range links {
+		if l.Attrs().HardwareAddr != nil {
+			byMAC[l.Attrs().HardwareAddr.String()] = l
+		}
+		byName[l.Attrs().Name] = l
+	}
+
+	eniRoutes := sets.New[string]()
+	addRoute := func(route *netlink.Route) {
+		key := routeKey(route)
+		if eniRoutes.Has(key) {
+			return
+		}
+		eniRoutes.Insert(key)
+		state.routes = append(state.routes, route)
+	}
+
+	for _, podRes := range snapshot.podResources {
+		if podRes.PodInfo == nil {
+			continue
+		}
+		state.owned.Insert(podResourceIPs(podRes)...)
+
+		if podRes.NetConf == "" || snapshot.isInTransition(podRes) {
+			continue
+		}
+		if podRes.NetNs != nil && *podRes.NetNs != "" {
+			if _, err := os.Stat(*podRes.NetNs); os.IsNotExist(err) {
+				continue
+			}
+		}
+		var netConfs []*rpc.NetConf
+		err := json.Unmarshal([]byte(podRes.NetConf), &netConfs)
+		if err != nil {
+			serviceLog.Error(err, "error parse net conf", "pod", podRes.PodInfo.Namespace+"/"+podRes.PodInfo.Name)
+			continue
+		}
+
+		for _, netConf := range netConfs {
+			if netConf.GetBasicInfo().GetPodIP() == nil || netConf.GetENIInfo().GetMAC() == "" {
+				continue
+			}
+			podIP, err := types.ToIPSet(netConf.GetBasicInfo().GetPodIP())
+			if err != nil {
+				continue
+			}
+			for _, ip := range []net.IP{podIP.IPv4, podIP.IPv6} {
+				if ip != nil {
+					state.owned.Insert(ip.String())
+				}
+			}
+
+			eni, ok := byMAC[netConf.GetENIInfo().GetMAC()]
+			if !ok {
+				continue
+			}
+			eniIndex := eni.Attrs().Index
+
+			slave, ok := byName[datapath.IPVlanSlaveName(eniIndex)]
+			if ok {
+				// ipvlan datapath
+				if podIP.IPv4 != nil {
+					dst := utils.NewIPNetWithMaxMask(&net.IPNet{IP: podIP.IPv4})
+					filter, err := datapath.IPVlanRedirectFilter(eniIndex, dst, slave.Attrs().Index)
+					if err == nil {
+						state.filters = append(state.filters, filter)
+					}
+				}
+				for _, ip := range []net.IP{podIP.IPv4, podIP.IPv6} {
+					if ip == nil {
+						continue
+					}
+					state.routes = append(state.routes, &netlink.Route{
+						LinkIndex: slave.Attrs().Index,
+						Scope:     netlink.SCOPE_LINK,
+						Dst:       utils.NewIPNetWithMaxMask(&net.IPNet{IP: ip}),
+					})
+				}
+				continue
+			}
+
+			// policy route datapath
+			ifName := netConf.GetIfName()
+			if ifName == "" {
+				ifName = IfEth0
+			}
+			vethName, err := link.VethNameForPod(podRes.PodInfo.Name, podRes.PodInfo.Namespace, ifName, link.HostVethPrefix)
+			if err != nil {
+				continue
+			}
+			veth, ok := byName[vethName]
+			if !ok {
+				continue
+			}
+
+			gw := netConf.GetBasicInfo().GetGatewayIP()
+			if netConf.GetENIInfo().GetTrunk() && netConf.GetENIInfo().GetGatewayIP() != nil {
+				gw = netConf.GetENIInfo().GetGatewayIP()
+			}
+			gwIP := &types.IPSet{}
+			if gw != nil {
+				gwIP, err = types.ToIPSet(gw)
+				if err != nil {
+					continue
+				}
+			}
+
+			table := utils.GetRouteTableID(eniIndex)
+			for _, ip := range []net.IP{podIP.IPv4, podIP.IPv6} {
+				if ip == nil {
+					continue
+				}
+				dst := utils.NewIPNetWithMaxMask(&net.IPNet{IP: ip})
+				state.rules = append(state.rules, datapath.HostRulesForPolicy(dst, table)...)
+				state.routes = append(state.routes, &netlink.Route{
+					LinkIndex: veth.Attrs().Index,
+					Scope:     netlink.SCOPE_LINK,
+					Dst:       dst,
+				})
+			}
+
+			if podIP.IPv4 != nil && gwIP.IPv4 != nil {
+				addRoute(&netlink.Route{
+					LinkIndex: eniIndex,
+					Scope:     netlink.SCOPE_UNIVERSE,
+					Table:     table,
+					Dst:       &net.IPNet{IP: net.IPv4zero, Mask: net.CIDRMask(0, 32)},
+					Gw:        gwIP.IPv4,
+					Flags:     int(netlink.FLAG_ONLINK),
+				})
+			}
+			if podIP.IPv6 != nil && gwIP.IPv6 != nil {
+				addRoute(&netlink.Route{
+					LinkIndex: eniIndex,
+					Scope:     netlink.SCOPE_LINK,
+					Dst:       &net.IPNet{IP: gwIP.IPv6, Mask: net.CIDRMask(128, 128)},
+				})
+				addRoute(&netlink.Route{
+					LinkIndex: eniIndex,
+					Scope:     netlink.SCOPE_UNIVERSE,
+					Table:     table,
+					Dst:       &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)},
+					Gw:        gwIP.IPv6,
+					Flags:     int(netlink.FLAG_ONLINK),
+				})
+			}
+		}
+	}
+
+	return state
+}
+
+func listHostState(links []netlink.Link) (*actualState, error) {
+	state := &actualState{terwayLinks: sets.New[int]()}
+
+	var err error
+	state.rules, err = netlink.RuleList(netlink.FAMILY_ALL)
+	if err != nil {
+		return nil, fmt.Errorf("error list rules, %w", err)
+	}
+
+	state.routes, err = netlink.RouteListFiltered(netlink.FAMILY_ALL, &netlink.Route{Table: unix.RT_TABLE_UNSPEC}, netlink.RT_FILTER_TABLE)
+	if err != nil {
+		return nil, fmt.Errorf("error list routes, %w", err)
+	}
+
+	for _, l := range links {
+		name := l.Attrs().Name
+		switch l.(type) {
+		case *netlink.Veth:
+			if !strings.HasPrefix(name, link.HostVethPrefix) {
+				continue
+			}
+			state.terwayLinks.Insert(l.Attrs().Index)
+
+			neighs, err := netlink.NeighList(l.Attrs().Index, netlink.FAMILY_ALL)
+			if err != nil {
+				return nil, fmt.Errorf("error list neigh on %s, %w", name, err)
+			}
+			state.neighs = append(state.neighs, neighs...)
+		case *netlink.IPVlan:
+			if !strings.HasPrefix(name, "ipvl_") {
+				continue
+			}
+			state.terwayLinks.Insert(l.Attrs().Index)
+		case *netlink.Device:
+			filters, err := netlink.FilterList(l, netlink.HANDLE_MIN_EGRESS)
+			if err != nil {
+				// no clsact qdisc on the link
+				continue
+			}
+			state.filters = append(state.filters, filters...)
+		}
+	}
+	return state, nil
+}
+
+// diffRules add the missed rules for pods, and remove the rules for the ip not on this node
+func diffRules(expected *hostState, actual *actualState) []datapathChange {
+	var changes []datapathChange
+
+	expectedKeys := sets.New[string]()
+	expectedIPs := sets.New[string]()
+	for _, rule := range expected.rules {
+		expectedKeys.Insert(ruleKey(rule))
+		ip, _ := datapath.IsHostRuleForPolicy(rule)
+		expectedIPs.Insert(ip.String())
+	}
+	actualKeys := sets.New[string]()
+	for i := range actual.rules {
+		rule := actual.rules[i]
+		key := ruleKey(&rule)
+		actualKeys.Insert(key)
+
+		ip, ok := datapath.IsHostRuleForPolicy(&rule)
+		if !ok || expectedKeys.Has(key) {
+			continue
+		}
+		// the rule for the pod on other eni is stale too
+		if expected.owned.Has(ip.String()) && !expectedIPs.Has(ip.String()) {
+			continue
+		}
+		changes = append(changes, datapathChange{
+			kind:   metric.DatapathKindRule,
+			action: metric.DatapathActionDel,
+			desc:   key,
+			ip:     ip.String(),
+			apply: func() error {
+				return netlink.RuleDel(&rule)
+			},
+		})
+	}
+
+	for _, rule := range expected.rules {
+		key := ruleKey(rule)
+		if actualKeys.Has(key) {
+			continue
+		}
+		r := rule
+		ip, _ := datapath.IsHostRuleForPolicy(r)
+		changes = append(changes, datapathChange{
+			kind:   metric.DatapathKindRule,
+			action: metric.DatapathActionAdd,
+			desc:   key,
+			ip:     ip.String(),
+			apply: func() error {
+				return netlink.RuleAdd(r)
+			},
+		})
+	}
+	return changes
+}
+
+// diffRoutes add the missed routes for pods, and remove the routes to the ip not on this node
+func diffRoutes(expected *hostState, actual *actualState) []datapathChange {
+	var changes []datapathChange
+
+	expectedKeys := sets.New[string]()
+	expectedIPs := sets.New[string]()
+	for _, route := range expected.routes {
+		expectedKeys.Insert(routeKey(route))
+		if isPodRoute(route) {
+			expectedIPs.Insert(route.Dst.IP.String())
+		}
+	}
+	actualKeys := sets.New[string]()
+	for i := range actual.routes {
+		route := actual.routes[i]
+		key := routeKey(&route)
+		actualKeys.Insert(key)
+
+		if !actual.terwayLinks.Has(route.LinkIndex) || !isPodRoute(&route) || expectedKeys.Has(key) {
+			continue
+		}
+		ip := route.Dst.IP.String()
+		// the route for the pod via other link is stale too
+		if expected.owned.Has(ip) && !expectedIPs.Has(ip) {
+			continue
+		}
+		changes = append(changes, datapathChange{
+			kind:   metric.DatapathKindRoute,
+			action: metric.DatapathActionDel,
+			desc:   key,
+			ip:     ip,
+			apply: func() error {
+				return netlink.RouteDel(&route)
+			},
+		})
+	}
+
+	for _, route := range expected.routes {
+		key := routeKey(route)
+		if actualKeys.Has(key) {
+			continue
+		}
+		r := route
+		ip := ""
+		if isPodRoute(r) {
+			ip = r.Dst.IP.String()
+		}
+		changes = append(changes, datapathChange{
+			kind:   metric.DatapathKindRoute,
+			action: metric.DatapathActionAdd,
+			desc:   key,
+			ip:     ip,
+			apply: func() error {
+				return netlink.RouteReplace(r)
+			},
+		})
+	}
+	return changes
+}
+
+// diffNeighs remove the permanent neigh entries on host veth for the ip not on this node
+func diffNeighs(expected *hostState, actual *actualState) []datapathChange {
+	var changes []datapathChange
+	for i := range actual.neighs {
+		neigh := actual.neighs[i]
+		if neigh.State != netlink.NUD_PERMANENT || neigh.IP == nil || !neigh.IP.IsGlobalUnicast() {
+			continue
+		}
+		if expected.owned.Has(neigh.IP.String()) {
+			continue
+		}
+		changes = append(changes, datapathChange{
+			kind:   metric.DatapathKindNeigh,
+			action: metric.DatapathActionDel,
+			desc:   fmt.Sprintf("%s dev %d", neigh.IP, neigh.LinkIndex),
+			ip:     neigh.IP.String(),
+			apply: func() error {
+				return netlink.NeighDel(&neigh)
+			},
+		})
+	}
+	return changes
+}
+
+// diffFilters add the missed ipvlan redirect filters for pods, and remove the redirect filters and
+// vlan tag filters for the ip not on this node
+func diffFilters(expected *hostState, actual *actualState) []datapathChange {
+	var changes []datapathChange
+
+	expectedKeys := sets.New[string]()
+	expectedIPs := sets.New[string]()
+	for _, filter := range expected.filters {
+		dst, slaveIndex, ok := datapath.ParseIPVlanRedirectFilter(filter)
+		if !ok {
+			continue
+		}
+		expectedKeys.Insert(filterKey(filter.LinkIndex, dst, slaveIndex))
+		expectedIPs.Insert(dst.IP.String())
+	}
+
+	actualKeys := sets.New[string]()
+	for _, filter := range actual.filters {
+		f := filter
+		if ip, _, ok := datapath.ParseVlanTagFilter(f); ok {
+			if expected.owned.Has(ip.String()) {
+				continue
+			}
+			changes = append(changes, datapathChange{
+				kind:   metric.DatapathKindFilter,
+				action: metric.DatapathActionDel,
+				desc:   fmt.Sprintf("vlan tag %s dev %d", ip, f.Attrs().LinkIndex),
+				ip:     ip.String(),
+				apply: func() error {
+					return netlink.FilterDel(f)
+				},
+			})
+			continue
+		}
+
+		dst, slaveIndex, ok := datapath.ParseIPVlanRedirectFilter(f)
+		if !ok {
+			continue
+		}
+		key := filterKey(f.Attrs().LinkIndex, dst, slaveIndex)
+		actualKeys.Insert(key)
+		if expectedKeys.Has(key) {
+			continue
+		}
+		ip := dst.IP.String()
+		if expected.owned.Has(ip) && !expectedIPs.Has(ip) {
+			continue
+		}
+		changes = append(changes, datapathChange{
+			kind:   metric.DatapathKindFilter,
+			action: metric.DatapathActionDel,
+			desc:   key,
+			ip:     ip,
+			apply: func() error {
+				return netlink.FilterDel(f)
+			},
+		})
+	}
+
+	for _, filter := range expected.filters {
+		dst, slaveIndex, _ := datapath.ParseIPVlanRedirectFilter(filter)
+		key := filterKey(filter.LinkIndex, dst, slaveIndex)
+		if actualKeys.Has(key) {
+			continue
+		}
+		f := filter
+		changes = append(changes, datapathChange{
+			kind:   metric.DatapathKindFilter,
+			action: metric.DatapathActionAdd,
+			desc:   key,
+			ip:     dst.IP.String(),
+			apply: func() error {
+				parent, err := netlink.LinkByIndex(f.LinkIndex)
+				if err != nil {
+					return err
+				}
+				err = utils.EnsureClsActQdsic(parent)
+				if err != nil {
+					return err
+				}
+				return netlink.FilterAdd(f)
+			},
+		})
+	}
+	return changes
+}
+
+// isPodRoute report whether the route is the route to a single pod ip in main table
+func isPodRoute(route *netlink.Route) bool {
+	if route.Dst == nil || route.Gw != nil || route.Scope != netlink.SCOPE_LINK {
+		return false
+	}
+	if route.Table != 0 && route.Table != unix.RT_TABLE_MAIN {
+		return false
+	}
+	ones, bits := route.Dst.Mask.Size()
+	return ones == bits && bits != 0
+}
+
+func ruleKey(rule *netlink.Rule) string {
+	src, dst := "all", "all"
+	if rule.Src != nil {
+		src = rule.Src.String()
+	}
+	if rule.Dst != nil {
+		dst = rule.Dst.String()
+	}
+	return fmt.Sprintf("%d: from %s to %s lookup %d", rule.Priority, src, dst, rule.Table)
+}
+
+func routeKey(route *netlink.Route) string {
+	table := route.Table
+	if table == 0 {
+		table = unix.RT_TABLE_MAIN
+	}
+	dst := "default"
+	if route.Dst != nil {
+		if ones, _ := route.Dst.Mask.Size(); ones != 0 || !route.Dst.IP.IsUnspecified() {
+			dst = route.Dst.String()
+		}
+	}
+	if route.Gw != nil {
+		return fmt.Sprintf("%s via %s dev %d table %d", dst, route.Gw, route.LinkIndex, table)
+	}
+	return fmt.Sprintf("%s dev %d table %d", dst, route.LinkIndex, table)
+}
+
+func filterKey(linkIndex int, dst *net.IPNet, slaveIndex int) string {
+	return fmt.Sprintf("redirect %s dev %d to %d", dst, linkIndex, slaveIndex)
+}
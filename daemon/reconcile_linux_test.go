@@ -0,0 +1,294 @@
+package daemon
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/AliyunContainerService/terway/pkg/link"
+	"github.com/AliyunContainerService/terway/pkg/metric"
+	"github.com/AliyunContainerService/terway/plugin/datapath"
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+func testPodResources(t *testing.T, name, ip, mac string) daemon.PodResources {
+	netConf, err := json.Marshal([]*rpc.NetConf{
+		{
+			BasicInfo: &rpc.BasicInfo{
+				PodIP:     &rpc.IPSet{IPv4: ip},
+				PodCIDR:   &rpc.IPSet{IPv4: "192.168.0.0/24"},
+				GatewayIP: &rpc.IPSet{IPv4: "192.168.0.253"},
+			},
+			ENIInfo: &rpc.ENIInfo{MAC: mac},
+		},
+	})
+	require.NoError(t, err)
+	return daemon.PodResources{
+		PodInfo: &daemon.PodInfo{
+			Name:      name,
+			Namespace: "default",
+			PodIPs:    types.IPSet{IPv4: net.ParseIP(ip)},
+		},
+		NetConf: string(netConf),
+	}
+}
+
+func testLink(index int, name, mac string) netlink.Link {
+	attrs := netlink.LinkAttrs{Index: index, Name: name}
+	if mac != "" {
+		attrs.HardwareAddr, _ = net.ParseMAC(mac)
+	}
+	return &netlink.Device{LinkAttrs: attrs}
+}
+
+func changesOf(changes []datapathChange, kind, action string) []string {
+	var result []string
+	for _, c := range changes {
+		if c.kind == kind && c.action == action {
+			result = append(result, c.desc)
+		}
+	}
+	return result
+}
+
+func TestExpectedHostStatePolicyRoute(t *testing.T) {
+	vethName, err := link.VethNameForPod("pod-1", "default", "", link.HostVethPrefix)
+	require.NoError(t, err)
+
+	links := []netlink.Link{
+		testLink(3, "eth1", "00:16:3e:00:00:01"),
+		testLink(10, vethName, ""),
+	}
+	podResources := []daemon.PodResources{
+		testPodResources(t, "pod-1", "192.168.0.10", "00:16:3e:00:00:01"),
+		// the host veth is not created
+		testPodResources(t, "pod-2", "192.168.0.11", "00:16:3e:00:00:01"),
+		// the eni is not found
+		testPodResources(t, "pod-3", "192.168.0.12", "00:16:3e:00:00:02"),
+	}
+
+	state := expectedHostState(&datapathSnapshot{podResources: podResources, inTransition: sets.New[string]()}, links)
+
+	assert.Equal(t, sets.New("192.168.0.10", "192.168.0.11", "192.168.0.12"), state.owned)
+	assert.Len(t, state.filters, 0)
+
+	var rules []string
+	for _, rule := range state.rules {
+		rules = append(rules, ruleKey(rule))
+	}
+	assert.ElementsMatch(t, []string{
+		"512: from all to 192.168.0.10/32 lookup 254",
+		"2048: from 192.168.0.10/32 to all lookup 1003",
+	}, rules)
+
+	var routes []string
+	for _, route := range state.routes {
+		routes = append(routes, routeKey(route))
+	}
+	assert.ElementsMatch(t, []string{
+		"192.168.0.10/32 dev 10 table 254",
+		"default via 192.168.0.253 dev 3 table 1003",
+	}, routes)
+}
+
+func TestExpectedHostStateSkipped(t *testing.T) {
+	links := []netlink.Link{
+		testLink(3, "eth1", "00:16:3e:00:00:01"),
+		testLink(11, datapath.IPVlanSlaveName(3), ""),
+	}
+	deleting := testPodResources(t, "pod-1", "192.168.0.10", "00:16:3e:00:00:01")
+	netNsGone := testPodResources(t, "pod-2", "192.168.0.11", "00:16:3e:00:00:01")
+	netNs := filepath.Join(t.TempDir(), "absent")
+	netNsGone.NetNs = &netNs
+
+	state := expectedHostState(&datapathSnapshot{
+		podResources: []daemon.PodResources{deleting, netNsGone},
+		inTransition: sets.New[string]("default/pod-1"),
+	}, links)
+
+	// the entries are not added back, but still owned by the pods
+	assert.Equal(t, sets.New("192.168.0.10", "192.168.0.11"), state.owned)
+	assert.Len(t, state.routes, 0)
+	assert.Len(t, state.filters, 0)
+}
+
+func TestDatapathSnapshotIPs(t *testing.T) {
+	snapshot := &datapathSnapshot{
+		podResources: []daemon.PodResources{
+			testPodResources(t, "pod-1", "192.168.0.10", "00:16:3e:00:00:01"),
+			testPodResources(t, "pod-2", "192.168.0.11", "00:16:3e:00:00:01"),
+		},
+		inTransition: sets.New[string]("default/pod-2"),
+	}
+	assert.Equal(t, sets.New("192.168.0.10", "192.168.0.11"), snapshot.ips(false))
+	assert.Equal(t, sets.New("192.168.0.10"), snapshot.ips(true))
+}
+
+func TestExpectedHostStateIPVlan(t *testing.T) {
+	links := []netlink.Link{
+		testLink(3, "eth1", "00:16:3e:00:00:01"),
+		testLink(11, datapath.IPVlanSlaveName(3), ""),
+	}
+	podResources := []daemon.PodResources{
+		testPodResources(t, "pod-1", "192.168.0.10", "00:16:3e:00:00:01"),
+	}
+
+	state := expectedHostState(&datapathSnapshot{podResources: podResources, inTransition: sets.New[string]()}, links)
+
+	assert.Len(t, state.rules, 0)
+	require.Len(t, state.routes, 1)
+	assert.Equal(t, "192.168.0.10/32 dev 11 table 254", routeKey(state.routes[0]))
+
+	require.Len(t, state.filters, 1)
+	dst, slaveIndex, ok := datapath.ParseIPVlanRedirectFilter(state.filters[0])
+	assert.True(t, ok)
+	assert.Equal(t, "192.168.0.10/32", dst.String())
+	assert.Equal(t, 11, slaveIndex)
+	assert.Equal(t, 3, state.filters[0].LinkIndex)
+}
+
+func TestDiffRules(t *testing.T) {
+	podIP := &net.IPNet{IP: net.ParseIP("192.168.0.10"), Mask: net.CIDRMask(32, 32)}
+	expected := &hostState{
+		owned: sets.New("192.168.0.10", "192.168.0.11"),
+		rules: datapath.HostRulesForPolicy(podIP, 1003),
+	}
+
+	toRules := func(rules []*netlink.Rule) []netlink.Rule {
+		var result []netlink.Rule
+		for _, r := range rules {
+			result = append(result, *r)
+		}
+		return result
+	}
+	actual := &actualState{}
+	// to container rule is missing
+	actual.rules = append(actual.rules, toRules(datapath.HostRulesForPolicy(podIP, 1003))[1])
+	// pod moved to other eni
+	actual.rules = append(actual.rules, toRules(datapath.HostRulesForPolicy(podIP, 1004))[1])
+	// pod is not on this node
+	actual.rules = append(actual.rules, toRules(datapath.HostRulesForPolicy(&net.IPNet{IP: net.ParseIP("192.168.0.20"), Mask: net.CIDRMask(32, 32)}, 1003))...)
+	// the host veth is not found, keep it
+	actual.rules = append(actual.rules, toRules(datapath.HostRulesForPolicy(&net.IPNet{IP: net.ParseIP("192.168.0.11"), Mask: net.CIDRMask(32, 32)}, 1003))...)
+	// not created by terway
+	actual.rules = append(actual.rules, netlink.Rule{Priority: 32766, Table: unix.RT_TABLE_MAIN})
+
+	changes := diffRules(expected, actual)
+
+	assert.Equal(t, []string{"512: from all to 192.168.0.10/32 lookup 254"}, changesOf(changes, metric.DatapathKindRule, metric.DatapathActionAdd))
+	assert.ElementsMatch(t, []string{
+		"2048: from 192.168.0.10/32 to all lookup 1004",
+		"512: from all to 192.168.0.20/32 lookup 254",
+		"2048: from 192.168.0.20/32 to all lookup 1003",
+	}, changesOf(changes, metric.DatapathKindRule, metric.DatapathActionDel))
+}
+
+func TestDiffRoutes(t *testing.T) {
+	hostRoute := func(ip string, index int) *netlink.Route {
+		return &netlink.Route{
+			LinkIndex: index,
+			Scope:     netlink.SCOPE_LINK,
+			Dst:       &net.IPNet{IP: net.ParseIP(ip), Mask: net.CIDRMask(32, 32)},
+		}
+	}
+	defaultRoute := &netlink.Route{
+		LinkIndex: 3,
+		Table:     1003,
+		Gw:        net.ParseIP("192.168.0.253"),
+		Flags:     int(netlink.FLAG_ONLINK),
+	}
+
+	expected := &hostState{
+		owned:  sets.New("192.168.0.10", "192.168.0.11", "192.168.0.12"),
+		routes: []*netlink.Route{hostRoute("192.168.0.10", 10), hostRoute("192.168.0.11", 11), defaultRoute},
+	}
+	actual := &actualState{
+		terwayLinks: sets.New(10, 11, 12, 13),
+		routes: []netlink.Route{
+			*hostRoute("192.168.0.10", 10),
+			// the route via the old veth
+			*hostRoute("192.168.0.11", 12),
+			// the host veth is not found, keep it
+			*hostRoute("192.168.0.12", 13),
+			// the pod is not on this node
+			*hostRoute("192.168.0.20", 13),
+			// not on the terway link
+			*hostRoute("192.168.0.30", 2),
+		},
+	}
+	actual.routes[0].Table = unix.RT_TABLE_MAIN
+
+	changes := diffRoutes(expected, actual)
+
+	assert.ElementsMatch(t, []string{
+		"192.168.0.11/32 dev 11 table 254",
+		"default via 192.168.0.253 dev 3 table 1003",
+	}, changesOf(changes, metric.DatapathKindRoute, metric.DatapathActionAdd))
+	assert.ElementsMatch(t, []string{
+		"192.168.0.11/32 dev 12 table 254",
+		"192.168.0.20/32 dev 13 table 254",
+	}, changesOf(changes, metric.DatapathKindRoute, metric.DatapathActionDel))
+}
+
+func TestDiffNeighs(t *testing.T) {
+	expected := &hostState{owned: sets.New("192.168.0.10")}
+	actual := &actualState{
+		neighs: []netlink.Neigh{
+			{LinkIndex: 10, IP: net.ParseIP("192.168.0.10"), State: netlink.NUD_PERMANENT},
+			{LinkIndex: 10, IP: net.ParseIP("192.168.0.20"), State: netlink.NUD_PERMANENT},
+			{LinkIndex: 10, IP: net.ParseIP("192.168.0.30"), State: netlink.NUD_REACHABLE},
+			{LinkIndex: 10, IP: net.ParseIP("169.254.1.1"), State: netlink.NUD_PERMANENT},
+		},
+	}
+
+	changes := diffNeighs(expected, actual)
+	assert.Equal(t, []string{"192.168.0.20 dev 10"}, changesOf(changes, metric.DatapathKindNeigh, metric.DatapathActionDel))
+}
+
+func TestDiffFilters(t *testing.T) {
+	redirect := func(ip string, slaveIndex int) *netlink.U32 {
+		f, err := datapath.IPVlanRedirectFilter(3, &net.IPNet{IP: net.ParseIP(ip), Mask: net.CIDRMask(32, 32)}, slaveIndex)
+		require.NoError(t, err)
+		return f
+	}
+
+	vlanTag := func(ip string) *netlink.U32 {
+		return &netlink.U32{
+			FilterAttrs: netlink.FilterAttrs{LinkIndex: 3, Priority: datapath.VlanTagFilterPriority},
+			Sel: &netlink.TcU32Sel{
+				Keys: []netlink.TcU32Key{{Off: 12, Val: binary.BigEndian.Uint32(net.ParseIP(ip).To4()), Mask: 0xffffffff}},
+			},
+			Actions: []netlink.Action{netlink.NewVlanKeyAction()},
+		}
+	}
+
+	expected := &hostState{
+		owned:   sets.New("192.168.0.10", "192.168.0.11"),
+		filters: []*netlink.U32{redirect("192.168.0.10", 11), redirect("192.168.0.11", 11)},
+	}
+	actual := &actualState{
+		filters: []netlink.Filter{
+			redirect("192.168.0.10", 11),
+			redirect("192.168.0.20", 11),
+			vlanTag("192.168.0.10"),
+			vlanTag("192.168.0.21"),
+		},
+	}
+
+	changes := diffFilters(expected, actual)
+	assert.Equal(t, []string{"redirect 192.168.0.11/32 dev 3 to 11"}, changesOf(changes, metric.DatapathKindFilter, metric.DatapathActionAdd))
+	assert.ElementsMatch(t, []string{
+		"redirect 192.168.0.20/32 dev 3 to 11",
+		"vlan tag 192.168.0.21 dev 3",
+	}, changesOf(changes, metric.DatapathKindFilter, metric.DatapathActionDel))
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package daemon
+
+func datapathChanges(snapshot *datapathSnapshot) ([]datapathChange, error) {
+	return nil, nil
+}
@@ -0,0 +1,189 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/eni"
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+const (
+	tracingKeyIPReleaseScheduled = "ip_release_scheduled"
+)
+
+// ipReleaser release the ip held for the deleted pod once the stick time expired.
+// The deadline is stored in the pod info, so the timer is restored by the gc after the daemon restarted.
+type ipReleaser struct {
+	sync.Mutex
+
+	timers  map[string]*time.Timer
+	release func(podID string)
+}
+
+func newIPReleaser(release func(podID string)) *ipReleaser {
+	return &ipReleaser{
+		timers:  make(map[string]*time.Timer),
+		release: release,
+	}
+}
+
+// schedule release the ip of the pod at the time, the previous one of the pod is replaced
+func (r *ipReleaser) schedule(podID string, at time.Time) {
+	r.Lock()
+	defer r.Unlock()
+
+	if t, ok := r.timers[podID]; ok {
+		t.Stop()
+	}
+
+	var t *time.Timer
+	t = time.AfterFunc(time.Until(at), func() {
+		r.Lock()
+		if r.timers[podID] != t {
+			r.Unlock()
+			return
+		}
+		delete(r.timers, podID)
+		r.Unlock()
+
+		r.release(podID)
+	})
+	r.timers[podID] = t
+}
+
+// cancel the release of the pod, the ip is reused by the pod
+func (r *ipReleaser) cancel(podID string) {
+	r.Lock()
+	defer r.Unlock()
+
+	if t, ok := r.timers[podID]; ok {
+		t.Stop()
+		delete(r.timers, podID)
+	}
+}
+
+func (r *ipReleaser) scheduled() int {
+	r.Lock()
+	defer r.Unlock()
+
+	return len(r.timers)
+}
+
+// holdPodIP keep the resources of the deleted pod for a reuse, until the stick time expired
+func (n *networkService) holdPodIP(podID string, podRes daemon.PodResources, stickTime time.Duration) error {
+	info := *podRes.PodInfo
+	info.IPReleaseAt = time.Now().Add(stickTime)
+	podRes.PodInfo = &info
+
+	err := n.resourceDB.Put(podID, podRes)
+	if err != nil {
+		return err
+	}
+
+	if n.ipReleaser != nil {
+		n.ipReleaser.schedule(podID, info.IPReleaseAt)
+	}
+	serviceLog.Info("hold pod ip", "pod", podID, "releaseAt", info.IPReleaseAt)
+	return nil
+}
+
+// expiredPodResources return the resources of the pod if the ip held for the pod is expired
+func (n *networkService) expiredPodResources(podID string) (daemon.PodResources, bool) {
+	obj, err := n.resourceDB.Get(podID)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			serviceLog.Error(err, "error get pod resource", "pod", podID)
+		}
+		return daemon.PodResources{}, false
+	}
+	podRes := obj.(daemon.PodResources)
+	if podRes.PodInfo == nil || podRes.PodInfo.IPReleaseAt.IsZero() {
+		// the ip is reused by the pod
+		return daemon.PodResources{}, false
+	}
+	if time.Now().Before(podRes.PodInfo.IPReleaseAt) {
+		n.ipReleaser.schedule(podID, podRes.PodInfo.IPReleaseAt)
+		return daemon.PodResources{}, false
+	}
+	return podRes, true
+}
+
+// releaseExpiredPodIP release the resources held for the pod, if the pod is not back in time
+func (n *networkService) releaseExpiredPodIP(ctx context.Context, podID string) {
+	n.RLock()
+	podRes, expired := n.expiredPodResources(podID)
+	n.RUnlock()
+	if !expired {
+		return
+	}
+	releaseAt := podRes.PodInfo.IPReleaseAt
+
+	// the api call is done without the lock, so cni requests are not blocked
+	ok, err := n.k8s.PodExist(podRes.PodInfo.Namespace, podRes.PodInfo.Name)
+	if err != nil {
+		// left to the gc
+		serviceLog.Error(err, "error check pod exist", "pod", podID)
+		return
+	}
+
+	n.Lock()
+	defer n.Unlock()
+
+	// the pod may be set up again while checking the pod, check the resource again
+	podRes, expired = n.expiredPodResources(podID)
+	if !expired || !podRes.PodInfo.IPReleaseAt.Equal(releaseAt) {
+		return
+	}
+
+	if ok {
+		// the pod is back on this node, keep the ip for it
+		info := *podRes.PodInfo
+		info.IPReleaseAt = time.Time{}
+		podRes.PodInfo = &info
+		err = n.resourceDB.Put(podID, podRes)
+		if err != nil {
+			serviceLog.Error(err, "error update pod resource", "pod", podID)
+		}
+		return
+	}
+
+	err = n.releasePodResources(ctx, podID, podRes)
+	if err != nil {
+		serviceLog.Error(err, "error release pod resource", "pod", podID)
+		return
+	}
+	serviceLog.Info("released expired pod ip", "pod", podID)
+}
+
+// releasePodResources release all the resources of the pod, and remove the pod from db
+func (n *networkService) releasePodResources(ctx context.Context, podID string, podRes daemon.PodResources) error {
+	for _, resource := range podRes.Resources {
+		res := parseNetworkResource(resource)
+		if res == nil {
+			continue
+		}
+		err := n.eniMgr.Release(ctx, &daemon.CNI{
+			PodName:      podRes.PodInfo.Name,
+			PodNamespace: podRes.PodInfo.Namespace,
+			PodID:        podID,
+			PodUID:       podRes.PodInfo.PodUID,
+		}, &eni.ReleaseRequest{
+			NetworkResources: []eni.NetworkResource{res},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	err := n.deletePodResource(podRes.PodInfo)
+	if err != nil {
+		return err
+	}
+	n.publishPodEvent(rpc.PodEventType_PodEventGC, podRes)
+	return nil
+}
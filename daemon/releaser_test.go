@@ -0,0 +1,147 @@
+package daemon
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/AliyunContainerService/terway/pkg/eni"
+	k8smocks "github.com/AliyunContainerService/terway/pkg/k8s/mocks"
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/AliyunContainerService/terway/types/daemon"
+)
+
+func TestIPReleaser(t *testing.T) {
+	released := make(chan string, 2)
+	r := newIPReleaser(func(podID string) {
+		released <- podID
+	})
+
+	r.schedule("default/a", time.Now().Add(time.Hour))
+	r.schedule("default/b", time.Now().Add(time.Hour))
+	assert.Equal(t, 2, r.scheduled())
+
+	// the later schedule replace the previous one
+	r.schedule("default/a", time.Now().Add(10*time.Millisecond))
+	r.cancel("default/b")
+
+	select {
+	case podID := <-released:
+		assert.Equal(t, "default/a", podID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timer not fired")
+	}
+	assert.Equal(t, 0, r.scheduled())
+	assert.Empty(t, released)
+}
+
+func TestReleaseExpiredPodIP(t *testing.T) {
+	k8sClient := k8smocks.NewKubernetes(t)
+	k8sClient.On("PodExist", "default", "back").Return(true, nil).Once()
+	k8sClient.On("PodExist", "default", "gone").Return(false, nil).Once()
+
+	n := &networkService{
+		resourceDB: storage.NewMemoryStorage(),
+		k8s:        k8sClient,
+		eniMgr:     eni.NewManager(0, 0, 1, 0, nil, types.EniSelectionPolicyMostIPs, k8sClient),
+	}
+	n.ipReleaser = newIPReleaser(func(podID string) {})
+
+	expired := time.Now().Add(-time.Second)
+	for _, name := range []string{"back", "gone"} {
+		_ = n.resourceDB.Put("default/"+name, daemon.PodResources{
+			PodInfo: &daemon.PodInfo{Namespace: "default", Name: name, IPStickTime: time.Minute, IPReleaseAt: expired},
+		})
+	}
+	_ = n.resourceDB.Put("default/holding", daemon.PodResources{
+		PodInfo: &daemon.PodInfo{Namespace: "default", Name: "holding", IPStickTime: time.Minute, IPReleaseAt: time.Now().Add(time.Minute)},
+	})
+
+	n.releaseExpiredPodIP(context.Background(), "default/back")
+	obj, err := n.resourceDB.Get("default/back")
+	assert.NoError(t, err)
+	assert.True(t, obj.(daemon.PodResources).PodInfo.IPReleaseAt.IsZero())
+
+	n.releaseExpiredPodIP(context.Background(), "default/gone")
+	_, err = n.resourceDB.Get("default/gone")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	// not expired yet, wait for the timer
+	n.releaseExpiredPodIP(context.Background(), "default/holding")
+	_, err = n.resourceDB.Get("default/holding")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n.ipReleaser.scheduled())
+}
+
+func TestReleaseExpiredPodIP_SetupWhileChecking(t *testing.T) {
+	k8sClient := k8smocks.NewKubernetes(t)
+	n := &networkService{
+		resourceDB: storage.NewMemoryStorage(),
+		k8s:        k8sClient,
+	}
+	n.ipReleaser = newIPReleaser(func(podID string) {})
+
+	podRes := daemon.PodResources{
+		PodInfo: &daemon.PodInfo{Namespace: "default", Name: "sts-0", IPStickTime: time.Minute, IPReleaseAt: time.Now().Add(-time.Second)},
+	}
+	_ = n.resourceDB.Put("default/sts-0", podRes)
+
+	k8sClient.On("PodExist", "default", "sts-0").Run(func(args mock.Arguments) {
+		// the lock is not held during the api call, cni add take the ip back meanwhile
+		assert.True(t, n.TryLock())
+		defer n.Unlock()
+		info := *podRes.PodInfo
+		info.IPReleaseAt = time.Time{}
+		_ = n.resourceDB.Put("default/sts-0", daemon.PodResources{PodInfo: &info})
+	}).Return(false, nil).Once()
+
+	n.releaseExpiredPodIP(context.Background(), "default/sts-0")
+	obj, err := n.resourceDB.Get("default/sts-0")
+	assert.NoError(t, err)
+	assert.True(t, obj.(daemon.PodResources).PodInfo.IPReleaseAt.IsZero())
+}
+
+func TestGCPodsHoldIP(t *testing.T) {
+	k8sClient := k8smocks.NewKubernetes(t)
+	k8sClient.On("GetLocalPods").Return([]*daemon.PodInfo{}, nil)
+	k8sClient.On("PodExist", "default", "sts-0").Return(false, nil)
+	k8sClient.On("PodExist", "default", "deploy").Return(false, nil)
+
+	n := &networkService{
+		resourceDB: storage.NewMemoryStorage(),
+		k8s:        k8sClient,
+		eniMgr:     eni.NewManager(0, 0, 1, 0, nil, types.EniSelectionPolicyMostIPs, k8sClient),
+	}
+	n.ipReleaser = newIPReleaser(func(podID string) {})
+
+	_ = n.resourceDB.Put("default/sts-0", daemon.PodResources{
+		PodInfo: &daemon.PodInfo{Namespace: "default", Name: "sts-0", IPStickTime: 10 * time.Minute},
+	})
+	_ = n.resourceDB.Put("default/deploy", daemon.PodResources{
+		PodInfo: &daemon.PodInfo{Namespace: "default", Name: "deploy"},
+	})
+
+	start := time.Now()
+	err := n.gcPods(context.Background())
+	assert.NoError(t, err)
+
+	_, err = n.resourceDB.Get("default/deploy")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	obj, err := n.resourceDB.Get("default/sts-0")
+	assert.NoError(t, err)
+	releaseAt := obj.(daemon.PodResources).PodInfo.IPReleaseAt
+	assert.False(t, releaseAt.Before(start.Add(10*time.Minute)))
+	assert.Equal(t, 1, n.ipReleaser.scheduled())
+
+	// the deadline is kept by the next gc
+	err = n.gcPods(context.Background())
+	assert.NoError(t, err)
+	obj, err = n.resourceDB.Get("default/sts-0")
+	assert.NoError(t, err)
+	assert.Equal(t, releaseAt, obj.(daemon.PodResources).PodInfo.IPReleaseAt)
+}
@@ -1,6 +0,0 @@
-package daemon
-
-const (
-	resDBPath = "/var/lib/cni/terway/ResRelation.db"
-	resDBName = "relation"
-)
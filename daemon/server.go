@@ -26,6 +26,7 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 )
 
@@ -76,6 +77,7 @@ func Run(ctx context.Context, socketFilePath, debugSocketListen, configFilePath,
 	}
 
 	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		otelgrpc.UnaryServerInterceptor(),
 		cniInterceptor,
 	))
 	rpc.RegisterTerwayBackendServer(grpcServer, svc)
@@ -111,6 +113,13 @@ func Run(ctx context.Context, socketFilePath, debugSocketListen, configFilePath,
 
 	svc.wg.Wait()
 
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = svc.shutdownTelemetry(shutdownCtx)
+	if err != nil {
+		serviceLog.Error(err, "error flush spans")
+	}
+
 	return nil
 }
 
@@ -211,10 +220,15 @@ func registerPrometheus() {
 	prometheus.MustRegister(metric.ResourcePoolTotal)
 	prometheus.MustRegister(metric.ResourcePoolIdle)
 	prometheus.MustRegister(metric.ResourcePoolDisposed)
+	prometheus.MustRegister(metric.SecurityGroupPoolIdle)
+	prometheus.MustRegister(metric.SecurityGroupPoolInUse)
 	// ENIIP
 	prometheus.MustRegister(metric.ENIIPFactoryIPCount)
 	prometheus.MustRegister(metric.ENIIPFactoryENICount)
 	prometheus.MustRegister(metric.ENIIPFactoryIPAllocCount)
+	// Datapath
+	prometheus.MustRegister(metric.DatapathDrift)
+	prometheus.MustRegister(metric.DatapathRepaired)
 }
 
 func cniInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
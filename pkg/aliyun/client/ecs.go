@@ -2,6 +2,7 @@ package client
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/netip"
 	"strings"
@@ -16,7 +17,6 @@ import (
 	apiErr "github.com/AliyunContainerService/terway/pkg/aliyun/client/errors"
 	"github.com/AliyunContainerService/terway/pkg/aliyun/credential"
 	"github.com/AliyunContainerService/terway/pkg/ip"
-	"github.com/AliyunContainerService/terway/pkg/metric"
 )
 
 var _ VPC = &OpenAPI{}
@@ -60,7 +60,7 @@ func (a *OpenAPI) CreateNetworkInterface(ctx context.Context, opts ...CreateNetw
 		a.MutatingRateLimiter.Accept()
 		start := time.Now()
 		resp, innerErr = a.ClientSet.ECS().CreateNetworkInterface(req)
-		metric.OpenAPILatency.WithLabelValues("CreateNetworkInterface", fmt.Sprint(innerErr != nil)).Observe(metric.MsSince(start))
+		observeAPI(ctx, "CreateNetworkInterface", start, innerErr)
 		if innerErr != nil {
 			innerErr = apiErr.WarpError(innerErr)
 			l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(innerErr)).Error(innerErr, "failed")
@@ -118,7 +118,7 @@ func (a *OpenAPI) DescribeNetworkInterface(ctx context.Context, vpcID string, en
 		a.ReadOnlyRateLimiter.Accept()
 		start := time.Now()
 		resp, err := a.ClientSet.ECS().DescribeNetworkInterfaces(req)
-		metric.OpenAPILatency.WithLabelValues("DescribeNetworkInterfaces", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+		observeAPI(ctx, "DescribeNetworkInterfaces", start, err)
 		if err != nil {
 			err = apiErr.WarpError(err)
 			l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(err)).Error(err, "error describe eni")
@@ -153,7 +153,7 @@ func (a *OpenAPI) AttachNetworkInterface(ctx context.Context, eniID, instanceID,
 	a.MutatingRateLimiter.Accept()
 	start := time.Now()
 	resp, err := a.ClientSet.ECS().AttachNetworkInterface(req)
-	metric.OpenAPILatency.WithLabelValues("AttachNetworkInterface", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	observeAPI(ctx, "AttachNetworkInterface", start, err)
 	if err != nil {
 		err = apiErr.WarpError(err)
 		l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(err)).Error(err, "attach eni failed")
@@ -178,7 +178,7 @@ func (a *OpenAPI) DetachNetworkInterface(ctx context.Context, eniID, instanceID,
 	a.MutatingRateLimiter.Accept()
 	start := time.Now()
 	resp, err := a.ClientSet.ECS().DetachNetworkInterface(req)
-	metric.OpenAPILatency.WithLabelValues("DetachNetworkInterface", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	observeAPI(ctx, "DetachNetworkInterface", start, err)
 	if err != nil {
 		err = apiErr.WarpError(err)
 		if apiErr.ErrorCodeIs(err, apiErr.ErrInvalidENINotFound, apiErr.ErrInvalidEcsIDNotFound) {
@@ -203,7 +203,7 @@ func (a *OpenAPI) DeleteNetworkInterface(ctx context.Context, eniID string) erro
 	a.MutatingRateLimiter.Accept()
 	start := time.Now()
 	resp, err := a.ClientSet.ECS().DeleteNetworkInterface(req)
-	metric.OpenAPILatency.WithLabelValues("DeleteNetworkInterface", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	observeAPI(ctx, "DeleteNetworkInterface", start, err)
 	if err != nil {
 		err = apiErr.WarpError(err)
 		l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(err)).Error(err, "delete eni failed")
@@ -266,7 +266,7 @@ func (a *OpenAPI) AssignPrivateIPAddress(ctx context.Context, opts ...AssignPriv
 		a.MutatingRateLimiter.Accept()
 		start := time.Now()
 		resp, innerErr = a.ClientSet.ECS().AssignPrivateIpAddresses(req)
-		metric.OpenAPILatency.WithLabelValues("AssignPrivateIpAddresses", fmt.Sprint(innerErr != nil)).Observe(metric.MsSince(start))
+		observeAPI(ctx, "AssignPrivateIpAddresses", start, innerErr)
 		if innerErr != nil {
 			innerErr = apiErr.WarpError(innerErr)
 			l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(innerErr)).Error(innerErr, "failed")
@@ -310,7 +310,7 @@ func (a *OpenAPI) UnAssignPrivateIPAddresses(ctx context.Context, eniID string,
 	)
 	start := time.Now()
 	resp, err := a.ClientSet.ECS().UnassignPrivateIpAddresses(req)
-	metric.OpenAPILatency.WithLabelValues("UnassignPrivateIpAddresses", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	observeAPI(ctx, "UnassignPrivateIpAddresses", start, err)
 
 	if err != nil {
 		err = apiErr.WarpError(err)
@@ -326,6 +326,91 @@ func (a *OpenAPI) UnAssignPrivateIPAddresses(ctx context.Context, eniID string,
 	return nil
 }
 
+// AssignPrivateIPv4Prefix assign /28 ipv4 prefixes to eni
+func (a *OpenAPI) AssignPrivateIPv4Prefix(ctx context.Context, opts ...AssignPrivateIPAddressOption) ([]netip.Prefix, error) {
+	option := &AssignPrivateIPAddressOptions{}
+	for _, opt := range opts {
+		opt.ApplyAssignPrivateIPAddress(option)
+	}
+	if option.NetworkInterfaceOptions == nil || option.NetworkInterfaceOptions.IPv4PrefixCount <= 0 {
+		return nil, ErrInvalidArgs
+	}
+
+	req, rollBackFunc, err := option.Finish(a.IdempotentKeyGen)
+	if err != nil {
+		return nil, err
+	}
+	l := LogFields(logf.FromContext(ctx), req)
+
+	var (
+		resp     *ecs.AssignPrivateIpAddressesResponse
+		innerErr error
+	)
+
+	err = wait.ExponentialBackoffWithContext(ctx, *option.Backoff, func(ctx context.Context) (bool, error) {
+		a.MutatingRateLimiter.Accept()
+		start := time.Now()
+		resp, innerErr = a.ClientSet.ECS().AssignPrivateIpAddresses(req)
+		observeAPI(ctx, "AssignPrivateIpAddresses", start, innerErr)
+		if innerErr != nil {
+			innerErr = apiErr.WarpError(innerErr)
+			l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(innerErr)).Error(innerErr, "failed")
+
+			if apiErr.ErrorIs(innerErr, apiErr.IsURLError, apiErr.WarpFn(apiErr.ErrThrottling, apiErr.ErrInternalError)) {
+				return false, nil
+			}
+
+			return true, innerErr
+		}
+
+		return true, nil
+	})
+	if err != nil {
+		rollBackFunc()
+		return nil, err
+	}
+
+	prefixes, err := ip.ToIPPrefixes(resp.AssignedPrivateIpAddressesSet.Ipv4PrefixSet.Ipv4Prefixes)
+	l.WithValues(LogFieldRequestID, resp.RequestId).Info("assign ipv4 prefix", "prefixes", prefixes)
+
+	return prefixes, err
+}
+
+// UnAssignPrivateIPv4Prefix remove ipv4 prefixes from eni
+// return ok if 1. eni is released 2. prefix is already released 3. release success
+func (a *OpenAPI) UnAssignPrivateIPv4Prefix(ctx context.Context, eniID string, prefixes []netip.Prefix) error {
+	if len(prefixes) == 0 {
+		return nil
+	}
+	req := ecs.CreateUnassignPrivateIpAddressesRequest()
+	req.NetworkInterfaceId = eniID
+	str := ip.IPPrefixes2str(prefixes)
+	req.Ipv4Prefix = &str
+
+	l := logf.FromContext(ctx).WithValues(
+		LogFieldAPI, "UnassignPrivateIpAddresses",
+		LogFieldENIID, eniID,
+		LogFieldPrefixes, strings.Join(str, ","),
+	)
+	a.MutatingRateLimiter.Accept()
+	start := time.Now()
+	resp, err := a.ClientSet.ECS().UnassignPrivateIpAddresses(req)
+	observeAPI(ctx, "UnassignPrivateIpAddresses", start, err)
+
+	if err != nil {
+		err = apiErr.WarpError(err)
+		if apiErr.ErrorCodeIs(err, apiErr.ErrInvalidIPIPUnassigned, apiErr.ErrInvalidENINotFound) {
+			l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(err)).Info("success")
+			return nil
+		}
+
+		l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(err)).Error(err, "unassign ipv4 prefix failed")
+		return err
+	}
+	l.WithValues(LogFieldRequestID, resp.RequestId).Info("success")
+	return nil
+}
+
 // AssignIpv6Addresses assign ipv6 address
 func (a *OpenAPI) AssignIpv6Addresses(ctx context.Context, opts ...AssignIPv6AddressesOption) ([]netip.Addr, error) {
 	option := &AssignIPv6AddressesOptions{}
@@ -348,7 +433,7 @@ func (a *OpenAPI) AssignIpv6Addresses(ctx context.Context, opts ...AssignIPv6Add
 		a.MutatingRateLimiter.Accept()
 		start := time.Now()
 		resp, innerErr = a.ClientSet.ECS().AssignIpv6Addresses(req)
-		metric.OpenAPILatency.WithLabelValues("AssignIpv6Addresses", fmt.Sprint(innerErr != nil)).Observe(metric.MsSince(start))
+		observeAPI(ctx, "AssignIpv6Addresses", start, innerErr)
 		if innerErr != nil {
 			innerErr = apiErr.WarpError(innerErr)
 			l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(innerErr)).Error(innerErr, "failed")
@@ -391,7 +476,7 @@ func (a *OpenAPI) UnAssignIpv6Addresses(ctx context.Context, eniID string, ips [
 	)
 	start := time.Now()
 	resp, err := a.ClientSet.ECS().UnassignIpv6Addresses(req)
-	metric.OpenAPILatency.WithLabelValues("UnassignIpv6Addresses", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	observeAPI(ctx, "UnassignIpv6Addresses", start, err)
 
 	if err != nil {
 		err = apiErr.WarpError(err)
@@ -421,7 +506,7 @@ func (a *OpenAPI) DescribeInstanceTypes(ctx context.Context, types []string) ([]
 		}
 		start := time.Now()
 		resp, err := a.ClientSet.ECS().DescribeInstanceTypes(req)
-		metric.OpenAPILatency.WithLabelValues("DescribeInstanceTypes", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+		observeAPI(ctx, "DescribeInstanceTypes", start, err)
 
 		l := logf.FromContext(ctx).WithValues(
 			LogFieldAPI, "DescribeInstanceTypes",
@@ -443,13 +528,41 @@ func (a *OpenAPI) DescribeInstanceTypes(ctx context.Context, types []string) ([]
 	return result, nil
 }
 
+// DescribeSecurityGroups get the security groups in the vpc by ids
+func (a *OpenAPI) DescribeSecurityGroups(ctx context.Context, vpcID string, securityGroupIDs []string) ([]ecs.SecurityGroup, error) {
+	ids, err := json.Marshal(securityGroupIDs)
+	if err != nil {
+		return nil, err
+	}
+	req := ecs.CreateDescribeSecurityGroupsRequest()
+	req.VpcId = vpcID
+	req.SecurityGroupIds = string(ids)
+	req.MaxResults = requests.NewInteger(100)
+
+	l := logf.FromContext(ctx).WithValues(
+		LogFieldAPI, "DescribeSecurityGroups",
+		LogFieldSgID, securityGroupIDs,
+	)
+
+	a.ReadOnlyRateLimiter.Accept()
+	start := time.Now()
+	resp, err := a.ClientSet.ECS().DescribeSecurityGroups(req)
+	observeAPI(ctx, "DescribeSecurityGroups", start, err)
+	if err != nil {
+		err = apiErr.WarpError(err)
+		l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(err)).Error(err, "describe securityGroup failed")
+		return nil, err
+	}
+	return resp.SecurityGroups.SecurityGroup, nil
+}
+
 func (a *OpenAPI) ModifyNetworkInterfaceAttribute(ctx context.Context, eniID string, securityGroupIDs []string) error {
 	req := ecs.CreateModifyNetworkInterfaceAttributeRequest()
 	req.NetworkInterfaceId = eniID
 	req.SecurityGroupId = &securityGroupIDs
 	start := time.Now()
 	resp, err := a.ClientSet.ECS().ModifyNetworkInterfaceAttribute(req)
-	metric.OpenAPILatency.WithLabelValues("ModifyNetworkInterfaceAttribute", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	observeAPI(ctx, "ModifyNetworkInterfaceAttribute", start, err)
 
 	l := logf.FromContext(ctx).WithValues(
 		LogFieldAPI, "ModifyNetworkInterfaceAttribute",
@@ -466,7 +579,7 @@ func (a *OpenAPI) ModifyNetworkInterfaceAttribute(ctx context.Context, eniID str
 // DefaultGetLimit returns the instance limits of a particular instance type. // https://www.alibabacloud.com/help/doc-detail/25620.htm
 // if instanceType is empty will list all instanceType and warm the cache, no error and Limits will return
 func DefaultGetLimit(client interface{}, instanceType string) (*Limits, error) {
-	a, ok := client.(*OpenAPI)
+	a, ok := client.(ECS)
 	if !ok {
 		return nil, fmt.Errorf("unsupported client")
 	}
@@ -15,6 +15,8 @@ type NetworkInterfaceOptions struct {
 	ResourceGroupID       string
 	IPCount               int
 	IPv6Count             int
+	IPv4PrefixCount       int
+	PrivateIPAddress      []string
 	Tags                  map[string]string
 	InstanceID            string
 	InstanceType          string
@@ -113,13 +115,30 @@ func (c *AssignPrivateIPAddressOptions) ApplyAssignPrivateIPAddress(options *Ass
 }
 
 func (c *AssignPrivateIPAddressOptions) Finish(idempotentKeyGen IdempotentKeyGen) (*ecs.AssignPrivateIpAddressesRequest, func(), error) {
-	if c.NetworkInterfaceOptions == nil || c.NetworkInterfaceOptions.NetworkInterfaceID == "" || c.NetworkInterfaceOptions.IPCount <= 0 {
+	if c.NetworkInterfaceOptions == nil || c.NetworkInterfaceOptions.NetworkInterfaceID == "" {
+		return nil, nil, ErrInvalidArgs
+	}
+	// ip, specified ip and prefix can not be assigned in one call
+	n := 0
+	for _, v := range []bool{c.NetworkInterfaceOptions.IPCount > 0, c.NetworkInterfaceOptions.IPv4PrefixCount > 0, len(c.NetworkInterfaceOptions.PrivateIPAddress) > 0} {
+		if v {
+			n++
+		}
+	}
+	if n != 1 {
 		return nil, nil, ErrInvalidArgs
 	}
 
 	req := ecs.CreateAssignPrivateIpAddressesRequest()
 	req.NetworkInterfaceId = c.NetworkInterfaceOptions.NetworkInterfaceID
-	req.SecondaryPrivateIpAddressCount = requests.NewInteger(c.NetworkInterfaceOptions.IPCount)
+	switch {
+	case c.NetworkInterfaceOptions.IPCount > 0:
+		req.SecondaryPrivateIpAddressCount = requests.NewInteger(c.NetworkInterfaceOptions.IPCount)
+	case c.NetworkInterfaceOptions.IPv4PrefixCount > 0:
+		req.Ipv4PrefixCount = requests.NewInteger(c.NetworkInterfaceOptions.IPv4PrefixCount)
+	default:
+		req.PrivateIpAddress = &c.NetworkInterfaceOptions.PrivateIPAddress
+	}
 
 	argsHash := md5Hash(req)
 	req.ClientToken = idempotentKeyGen.GenerateKey(argsHash)
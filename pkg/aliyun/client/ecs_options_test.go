@@ -60,3 +60,61 @@ func TestCreateNetworkInterfaceOptions_Finish(t *testing.T) {
 	// Cleanup
 	cleanup()
 }
+
+func TestAssignPrivateIPAddressOptions_Finish(t *testing.T) {
+	c := &AssignPrivateIPAddressOptions{
+		NetworkInterfaceOptions: &NetworkInterfaceOptions{
+			NetworkInterfaceID: "eni-xxxxxx",
+			IPCount:            2,
+		},
+	}
+	req, cleanup, err := c.Finish(&MockIdempotentKeyGen{generatedKeys: map[string]string{}})
+	assert.NoError(t, err)
+	assert.Equal(t, "2", string(req.SecondaryPrivateIpAddressCount))
+	assert.Equal(t, "", string(req.Ipv4PrefixCount))
+	cleanup()
+
+	c = &AssignPrivateIPAddressOptions{
+		NetworkInterfaceOptions: &NetworkInterfaceOptions{
+			NetworkInterfaceID: "eni-xxxxxx",
+			IPv4PrefixCount:    1,
+		},
+	}
+	req, cleanup, err = c.Finish(&MockIdempotentKeyGen{generatedKeys: map[string]string{}})
+	assert.NoError(t, err)
+	assert.Equal(t, "", string(req.SecondaryPrivateIpAddressCount))
+	assert.Equal(t, "1", string(req.Ipv4PrefixCount))
+	cleanup()
+
+	c = &AssignPrivateIPAddressOptions{
+		NetworkInterfaceOptions: &NetworkInterfaceOptions{
+			NetworkInterfaceID: "eni-xxxxxx",
+			IPCount:            1,
+			IPv4PrefixCount:    1,
+		},
+	}
+	_, _, err = c.Finish(&MockIdempotentKeyGen{generatedKeys: map[string]string{}})
+	assert.ErrorIs(t, err, ErrInvalidArgs)
+
+	c = &AssignPrivateIPAddressOptions{
+		NetworkInterfaceOptions: &NetworkInterfaceOptions{
+			NetworkInterfaceID: "eni-xxxxxx",
+			PrivateIPAddress:   []string{"192.0.2.1"},
+		},
+	}
+	req, cleanup, err = c.Finish(&MockIdempotentKeyGen{generatedKeys: map[string]string{}})
+	assert.NoError(t, err)
+	assert.Equal(t, "", string(req.SecondaryPrivateIpAddressCount))
+	assert.Equal(t, []string{"192.0.2.1"}, *req.PrivateIpAddress)
+	cleanup()
+
+	c = &AssignPrivateIPAddressOptions{
+		NetworkInterfaceOptions: &NetworkInterfaceOptions{
+			NetworkInterfaceID: "eni-xxxxxx",
+			IPCount:            1,
+			PrivateIPAddress:   []string{"192.0.2.1"},
+		},
+	}
+	_, _, err = c.Finish(&MockIdempotentKeyGen{generatedKeys: map[string]string{}})
+	assert.ErrorIs(t, err, ErrInvalidArgs)
+}
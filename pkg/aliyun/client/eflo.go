@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/AliyunContainerService/terway/pkg/metric"
 	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
 	"github.com/aliyun/alibaba-cloud-sdk-go/services/eflo"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -13,7 +12,7 @@ import (
 	apiErr "github.com/AliyunContainerService/terway/pkg/aliyun/client/errors"
 )
 
-func (a *OpenAPI) CreateElasticNetworkInterface(zoneID, nodeID, vSwitchID, securityGroupID string) (string, string, error) {
+func (a *OpenAPI) CreateElasticNetworkInterface(ctx context.Context, zoneID, nodeID, vSwitchID, securityGroupID string) (string, string, error) {
 	req := eflo.CreateCreateElasticNetworkInterfaceRequest()
 	req.ZoneId = zoneID
 	req.NodeId = nodeID
@@ -22,7 +21,7 @@ func (a *OpenAPI) CreateElasticNetworkInterface(zoneID, nodeID, vSwitchID, secur
 
 	start := time.Now()
 	resp, err := a.ClientSet.EFLO().CreateElasticNetworkInterface(req)
-	metric.OpenAPILatency.WithLabelValues("CreateElasticNetworkInterface", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	observeAPI(ctx, "CreateElasticNetworkInterface", start, err)
 	if err != nil {
 		return "", "", err
 	}
@@ -40,7 +39,7 @@ func (a *OpenAPI) DeleteElasticNetworkInterface(ctx context.Context, eniID strin
 
 	start := time.Now()
 	resp, err := a.ClientSet.EFLO().DeleteElasticNetworkInterface(req)
-	metric.OpenAPILatency.WithLabelValues("DeleteElasticNetworkInterface", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	observeAPI(ctx, "DeleteElasticNetworkInterface", start, err)
 	if err != nil {
 		err = apiErr.WarpError(err)
 		l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(err)).Error(err, "failed")
@@ -63,7 +62,7 @@ func (a *OpenAPI) AssignLeniPrivateIPAddress(ctx context.Context, eniID, prefer
 
 	start := time.Now()
 	resp, err := a.ClientSet.EFLO().AssignLeniPrivateIpAddress(req)
-	metric.OpenAPILatency.WithLabelValues("AssignLeniPrivateIPAddress", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	observeAPI(ctx, "AssignLeniPrivateIPAddress", start, err)
 	if err != nil {
 		err = apiErr.WarpError(err)
 		l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(err)).Error(err, "failed")
@@ -89,7 +88,7 @@ func (a *OpenAPI) UnassignLeniPrivateIPAddress(ctx context.Context, eniID, ipNam
 
 	start := time.Now()
 	resp, err := a.ClientSet.EFLO().UnassignLeniPrivateIpAddress(req)
-	metric.OpenAPILatency.WithLabelValues("UnassignLeniPrivateIpAddress", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	observeAPI(ctx, "UnassignLeniPrivateIpAddress", start, err)
 	if err != nil {
 		err = apiErr.WarpError(err)
 		l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(err)).Error(err, "failed")
@@ -101,13 +100,13 @@ func (a *OpenAPI) UnassignLeniPrivateIPAddress(ctx context.Context, eniID, ipNam
 	return nil
 }
 
-func (a *OpenAPI) GetElasticNetworkInterface(eniID string) (*eflo.Content, error) {
+func (a *OpenAPI) GetElasticNetworkInterface(ctx context.Context, eniID string) (*eflo.Content, error) {
 	req := eflo.CreateGetElasticNetworkInterfaceRequest()
 	req.ElasticNetworkInterfaceId = eniID
 
 	start := time.Now()
 	resp, err := a.ClientSet.EFLO().GetElasticNetworkInterface(req)
-	metric.OpenAPILatency.WithLabelValues("GetElasticNetworkInterface", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	observeAPI(ctx, "GetElasticNetworkInterface", start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -129,7 +128,7 @@ func (a *OpenAPI) ListLeniPrivateIPAddresses(ctx context.Context, eniID, ipName,
 
 	start := time.Now()
 	resp, err := a.ClientSet.EFLO().ListLeniPrivateIpAddresses(req)
-	metric.OpenAPILatency.WithLabelValues("ListLeniPrivateIpAddresses", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	observeAPI(ctx, "ListLeniPrivateIpAddresses", start, err)
 	if err != nil {
 		err = apiErr.WarpError(err)
 		l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(err)).Error(err, "failed")
@@ -155,7 +154,7 @@ func (a *OpenAPI) ListElasticNetworkInterfaces(ctx context.Context, zoneID, node
 
 	start := time.Now()
 	resp, err := a.ClientSet.EFLO().ListElasticNetworkInterfaces(req)
-	metric.OpenAPILatency.WithLabelValues("ListElasticNetworkInterfaces", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	observeAPI(ctx, "ListElasticNetworkInterfaces", start, err)
 	if err != nil {
 		err = apiErr.WarpError(err)
 		l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(err)).Error(err, "failed")
@@ -179,7 +178,7 @@ func (a *OpenAPI) GetNodeInfoForPod(ctx context.Context, nodeID string) (*eflo.C
 
 	start := time.Now()
 	resp, err := a.ClientSet.EFLO().GetNodeInfoForPod(req)
-	metric.OpenAPILatency.WithLabelValues("GetNodeInfoForPod", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	observeAPI(ctx, "GetNodeInfoForPod", start, err)
 	if err != nil {
 		return nil, err
 	}
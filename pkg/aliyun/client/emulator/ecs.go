@@ -0,0 +1,566 @@
+//go:build default_build
+
+package emulator
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sort"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun/client"
+	apiErr "github.com/AliyunContainerService/terway/pkg/aliyun/client/errors"
+)
+
+// transient status of the eni
+const (
+	statusAttaching = "Attaching"
+	statusDetaching = "Detaching"
+)
+
+// retry run the api within the backoff, as the client does the throttling and internal errors are retried
+func retry(ctx context.Context, bo *wait.Backoff, fn func() error) error {
+	return wait.ExponentialBackoffWithContext(ctx, *bo, func(ctx context.Context) (bool, error) {
+		err := fn()
+		if err != nil {
+			if apiErr.ErrorIs(err, apiErr.WarpFn(apiErr.ErrThrottling, apiErr.ErrInternalError)) {
+				return false, nil
+			}
+			return true, err
+		}
+		return true, nil
+	})
+}
+
+func intValue(in requests.Integer) int {
+	v, _ := in.GetValue()
+	return v
+}
+
+func (e *Emulator) CreateNetworkInterface(ctx context.Context, opts ...client.CreateNetworkInterfaceOption) (*client.NetworkInterface, error) {
+	option := &client.CreateNetworkInterfaceOptions{}
+	for _, opt := range opts {
+		opt.ApplyCreateNetworkInterface(option)
+	}
+	req, rollBackFunc, err := option.Finish(e.keyGen)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *client.NetworkInterface
+	err = retry(ctx, option.Backoff, func() error {
+		return e.call("CreateNetworkInterface", func() error {
+			sw, ok := e.vSwitches[req.VSwitchId]
+			if !ok {
+				return ServerError(ErrInvalidVSwitchIDNotFound, "The specified VSwitchId does not exist.")
+			}
+			eni, err := e.newENILocked(sw, req.InstanceType, 1+intValue(req.SecondaryPrivateIpAddressCount), intValue(req.Ipv6AddressCount))
+			if err != nil {
+				return err
+			}
+			eni.trafficMode = req.NetworkInterfaceTrafficMode
+			eni.securityGroupIDs = append([]string(nil), (*req.SecurityGroupIds)...)
+			eni.resourceGroupID = req.ResourceGroupId
+			if req.Tag != nil {
+				for _, tag := range *req.Tag {
+					eni.tags[tag.Key] = tag.Value
+				}
+			}
+
+			result = e.toNetworkInterfaceLocked(eni)
+			return nil
+		})
+	})
+	if err != nil {
+		rollBackFunc()
+		return nil, err
+	}
+	return result, nil
+}
+
+// DescribeNetworkInterface list the eni match all the filters, the eni created within the DescribeDelay is not listed
+func (e *Emulator) DescribeNetworkInterface(ctx context.Context, vpcID string, eniID []string, instanceID string, instanceType string, status string, tags map[string]string) ([]*client.NetworkInterface, error) {
+	var result []*client.NetworkInterface
+	err := e.call("DescribeNetworkInterfaces", func() error {
+		ids := sets.New[string](eniID...)
+		now := e.now()
+		for _, eni := range e.enis {
+			if now.Before(eni.created.Add(e.cfg.DescribeDelay)) {
+				continue
+			}
+			if vpcID != "" && e.vSwitches[eni.vSwitchID].vsw.VpcId != vpcID {
+				continue
+			}
+			if ids.Len() > 0 && !ids.Has(eni.id) {
+				continue
+			}
+			if instanceID != "" && eni.instanceID != instanceID {
+				continue
+			}
+			if instanceType != "" && eni.eniType != instanceType {
+				continue
+			}
+			if status != "" && eni.status != status {
+				continue
+			}
+			matched := true
+			for k, v := range tags {
+				if eni.tags[k] != v {
+					matched = false
+				}
+			}
+			if !matched {
+				continue
+			}
+			result = append(result, e.toNetworkInterfaceLocked(eni))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].NetworkInterfaceID < result[j].NetworkInterfaceID
+	})
+	return result, nil
+}
+
+// AttachNetworkInterface attach the eni to the instance, or to the trunk eni as the member eni.
+// The eni is Attaching until the StatusDelay passed.
+func (e *Emulator) AttachNetworkInterface(ctx context.Context, eniID, instanceID, trunkENIID string) error {
+	return e.call("AttachNetworkInterface", func() error {
+		eni, ok := e.enis[eniID]
+		if !ok {
+			return ServerError(apiErr.ErrInvalidENINotFound, "The specified NetworkInterfaceId does not exist.")
+		}
+		ins, ok := e.instances[instanceID]
+		if !ok {
+			return ServerError(apiErr.ErrInvalidEcsIDNotFound, "The specified InstanceId does not exist.")
+		}
+		if eni.status != client.ENIStatusAvailable {
+			return ServerError(apiErr.ErrInvalidENIState, fmt.Sprintf("The eni is %s.", eni.status))
+		}
+		if e.vSwitches[eni.vSwitchID].vsw.ZoneId != ins.zoneID {
+			return ServerError("InvalidVSwitchId.ZoneMismatch", "The eni and the instance are in different zones.")
+		}
+
+		insType := e.instanceTypes[ins.instanceType]
+		attached, members := 0, 0
+		index := sets.New[int]()
+		for _, other := range e.enis {
+			if other.instanceID != instanceID {
+				continue
+			}
+			if other.trunkENIID != "" {
+				members++
+				continue
+			}
+			attached++
+			index.Insert(other.deviceIndex)
+		}
+
+		if trunkENIID != "" {
+			trunk, ok := e.enis[trunkENIID]
+			if !ok || trunk.instanceID != instanceID || trunk.eniType != client.ENITypeTrunk {
+				return ServerError(apiErr.ErrInvalidENINotFound, "The specified TrunkNetworkInstanceId does not exist.")
+			}
+			if members >= insType.EniTotalQuantity-insType.EniQuantity {
+				return ServerError(ErrMemberEniLimitExceeded, "The member eni of the instance exceeds the limit.")
+			}
+			eni.eniType = client.ENITypeMember
+			eni.trunkENIID = trunkENIID
+		} else {
+			if attached >= insType.EniQuantity {
+				return ServerError(apiErr.ErrEniPerInstanceLimitExceeded, "The eni of the instance exceeds the limit.")
+			}
+			for index.Has(eni.deviceIndex) {
+				eni.deviceIndex++
+			}
+		}
+		eni.instanceID = instanceID
+		e.transitLocked(eni, statusAttaching, client.ENIStatusInUse)
+		return nil
+	})
+}
+
+// DetachNetworkInterface detach the eni, the eni is Detaching until the StatusDelay passed.
+// Detach the available eni is a no-op.
+func (e *Emulator) DetachNetworkInterface(ctx context.Context, eniID, instanceID, trunkENIID string) error {
+	err := e.call("DetachNetworkInterface", func() error {
+		eni, ok := e.enis[eniID]
+		if !ok {
+			return ServerError(apiErr.ErrInvalidENINotFound, "The specified NetworkInterfaceId does not exist.")
+		}
+		if _, ok = e.instances[instanceID]; !ok {
+			return ServerError(apiErr.ErrInvalidEcsIDNotFound, "The specified InstanceId does not exist.")
+		}
+		if eni.status == client.ENIStatusAvailable {
+			return nil
+		}
+		if eni.eniType == client.ENITypePrimary || eni.instanceID != instanceID || eni.status != client.ENIStatusInUse {
+			return ServerError(apiErr.ErrInvalidENIState, fmt.Sprintf("The eni is %s on %s.", eni.status, eni.instanceID))
+		}
+		e.transitLocked(eni, statusDetaching, client.ENIStatusAvailable)
+		return nil
+	})
+	if apiErr.ErrorCodeIs(err, apiErr.ErrInvalidENINotFound, apiErr.ErrInvalidEcsIDNotFound) {
+		return nil
+	}
+	return err
+}
+
+// DeleteNetworkInterface delete the available eni, the addresses are back to the vSwitch
+func (e *Emulator) DeleteNetworkInterface(ctx context.Context, eniID string) error {
+	return e.call("DeleteNetworkInterface", func() error {
+		eni, ok := e.enis[eniID]
+		if !ok {
+			return ServerError(apiErr.ErrInvalidENINotFound, "The specified NetworkInterfaceId does not exist.")
+		}
+		if eni.status != client.ENIStatusAvailable || eni.eniType == client.ENITypePrimary {
+			return ServerError(apiErr.ErrInvalidENIState, fmt.Sprintf("The eni is %s.", eni.status))
+		}
+		eni.release(e.vSwitches[eni.vSwitchID])
+		delete(e.enis, eniID)
+		return nil
+	})
+}
+
+// WaitForNetworkInterface poll the eni until the status is reached, as the client does
+func (e *Emulator) WaitForNetworkInterface(ctx context.Context, eniID string, status string, backoff wait.Backoff, ignoreNotExist bool) (*client.NetworkInterface, error) {
+	var eniInfo *client.NetworkInterface
+	if eniID == "" {
+		return nil, fmt.Errorf("eniID not set")
+	}
+	err := wait.ExponentialBackoffWithContext(ctx, backoff, func(ctx context.Context) (bool, error) {
+		enis, err := e.DescribeNetworkInterface(ctx, "", []string{eniID}, "", "", "", nil)
+		if err != nil {
+			return false, nil
+		}
+		if len(enis) == 0 && ignoreNotExist {
+			return true, apiErr.ErrNotFound
+		}
+		if len(enis) == 1 {
+			if status != "" && status != enis[0].Status {
+				return false, nil
+			}
+			eniInfo = enis[0]
+			return true, nil
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error wait for eni %v to status %s, %w", eniID, status, err)
+	}
+	return eniInfo, nil
+}
+
+func (e *Emulator) AssignPrivateIPAddress(ctx context.Context, opts ...client.AssignPrivateIPAddressOption) ([]netip.Addr, error) {
+	option := &client.AssignPrivateIPAddressOptions{}
+	for _, opt := range opts {
+		opt.ApplyAssignPrivateIPAddress(option)
+	}
+	req, rollBackFunc, err := option.Finish(e.keyGen)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []netip.Addr
+	err = retry(ctx, option.Backoff, func() error {
+		return e.call("AssignPrivateIpAddresses", func() error {
+			eni, sw, err := e.getENILocked(req.NetworkInterfaceId)
+			if err != nil {
+				return err
+			}
+
+			var ips []netip.Addr
+			if req.PrivateIpAddress != nil && len(*req.PrivateIpAddress) > 0 {
+				for _, s := range *req.PrivateIpAddress {
+					addr, err := netip.ParseAddr(s)
+					if err != nil || !sw.usable(addr) {
+						return ServerError("InvalidParam.PrivateIpAddress", fmt.Sprintf("The ip %s is invalid.", s))
+					}
+					if _, ok := sw.used[addr]; ok {
+						return ServerError("InvalidPrivateIpAddress.Duplicated", fmt.Sprintf("The ip %s is used.", s))
+					}
+					ips = append(ips, addr)
+				}
+			}
+			count := intValue(req.SecondaryPrivateIpAddressCount)
+			if len(ips) > 0 {
+				count = len(ips)
+			}
+			if limit := e.ipv4LimitLocked(eni); limit > 0 && eni.ipCount()+len(eni.prefixes.items())+count > limit {
+				return ServerError(ErrIPv4CountExceeded, "The ipv4 of the eni exceeds the limit.")
+			}
+
+			if len(ips) > 0 {
+				for _, addr := range ips {
+					sw.used[addr] = struct{}{}
+				}
+				sw.vsw.AvailableIpAddressCount -= int64(len(ips))
+			} else {
+				ips, err = sw.allocIPv4(count)
+				if err != nil {
+					return err
+				}
+			}
+			for _, addr := range ips {
+				eni.ipv4.add(addr, e.now())
+			}
+			result = ips
+			return nil
+		})
+	})
+	if err != nil {
+		rollBackFunc()
+		return nil, err
+	}
+	return result, nil
+}
+
+// UnAssignPrivateIPAddresses remove the ips from the eni, the ip not assigned is ignored as the client does
+func (e *Emulator) UnAssignPrivateIPAddresses(ctx context.Context, eniID string, ips []netip.Addr) error {
+	if len(ips) == 0 {
+		return nil
+	}
+	err := e.call("UnassignPrivateIpAddresses", func() error {
+		eni, sw, err := e.getENILocked(eniID)
+		if err != nil {
+			return err
+		}
+		unassigned := false
+		for _, addr := range ips {
+			if !eni.ipv4.remove(addr, e.now()) {
+				unassigned = true
+				continue
+			}
+			sw.releaseIPv4(addr)
+		}
+		if unassigned {
+			return ServerError(apiErr.ErrInvalidIPIPUnassigned, "The ip is not assigned to the eni.")
+		}
+		return nil
+	})
+	if apiErr.ErrorCodeIs(err, apiErr.ErrInvalidIPIPUnassigned, apiErr.ErrInvalidENINotFound) {
+		return nil
+	}
+	return err
+}
+
+func (e *Emulator) AssignPrivateIPv4Prefix(ctx context.Context, opts ...client.AssignPrivateIPAddressOption) ([]netip.Prefix, error) {
+	option := &client.AssignPrivateIPAddressOptions{}
+	for _, opt := range opts {
+		opt.ApplyAssignPrivateIPAddress(option)
+	}
+	req, rollBackFunc, err := option.Finish(e.keyGen)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []netip.Prefix
+	err = retry(ctx, option.Backoff, func() error {
+		return e.call("AssignPrivateIpAddresses", func() error {
+			eni, sw, err := e.getENILocked(req.NetworkInterfaceId)
+			if err != nil {
+				return err
+			}
+			count := intValue(req.Ipv4PrefixCount)
+			if limit := e.ipv4LimitLocked(eni); limit > 0 && eni.ipCount()+len(eni.prefixes.items())+count > limit {
+				return ServerError(ErrIPv4CountExceeded, "The ipv4 of the eni exceeds the limit.")
+			}
+			prefixes, err := sw.allocPrefix(count)
+			if err != nil {
+				return err
+			}
+			for _, prefix := range prefixes {
+				eni.prefixes.add(prefix, e.now())
+			}
+			result = prefixes
+			return nil
+		})
+	})
+	if err != nil {
+		rollBackFunc()
+		return nil, err
+	}
+	return result, nil
+}
+
+func (e *Emulator) UnAssignPrivateIPv4Prefix(ctx context.Context, eniID string, prefixes []netip.Prefix) error {
+	if len(prefixes) == 0 {
+		return nil
+	}
+	err := e.call("UnassignPrivateIpAddresses", func() error {
+		eni, sw, err := e.getENILocked(eniID)
+		if err != nil {
+			return err
+		}
+		unassigned := false
+		for _, prefix := range prefixes {
+			if !eni.prefixes.remove(prefix, e.now()) {
+				unassigned = true
+				continue
+			}
+			sw.releasePrefix(prefix)
+		}
+		if unassigned {
+			return ServerError(apiErr.ErrInvalidIPIPUnassigned, "The prefix is not assigned to the eni.")
+		}
+		return nil
+	})
+	if apiErr.ErrorCodeIs(err, apiErr.ErrInvalidIPIPUnassigned, apiErr.ErrInvalidENINotFound) {
+		return nil
+	}
+	return err
+}
+
+func (e *Emulator) AssignIpv6Addresses(ctx context.Context, opts ...client.AssignIPv6AddressesOption) ([]netip.Addr, error) {
+	option := &client.AssignIPv6AddressesOptions{}
+	for _, opt := range opts {
+		opt.ApplyAssignIPv6Addresses(option)
+	}
+	req, rollBackFunc, err := option.Finish(e.keyGen)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []netip.Addr
+	err = retry(ctx, option.Backoff, func() error {
+		return e.call("AssignIpv6Addresses", func() error {
+			eni, sw, err := e.getENILocked(req.NetworkInterfaceId)
+			if err != nil {
+				return err
+			}
+			count := intValue(req.Ipv6AddressCount)
+			if limit := e.ipv6LimitLocked(eni); limit > 0 && len(eni.ipv6.items())+count > limit {
+				return ServerError(ErrIPv6CountExceeded, "The ipv6 of the eni exceeds the limit.")
+			}
+			ips, err := sw.allocIPv6(count)
+			if err != nil {
+				return err
+			}
+			for _, addr := range ips {
+				eni.ipv6.add(addr, e.now())
+			}
+			result = ips
+			return nil
+		})
+	})
+	if err != nil {
+		rollBackFunc()
+		return nil, err
+	}
+	return result, nil
+}
+
+func (e *Emulator) UnAssignIpv6Addresses(ctx context.Context, eniID string, ips []netip.Addr) error {
+	if len(ips) == 0 {
+		return nil
+	}
+	err := e.call("UnassignIpv6Addresses", func() error {
+		eni, _, err := e.getENILocked(eniID)
+		if err != nil {
+			return err
+		}
+		unassigned := false
+		for _, addr := range ips {
+			if !eni.ipv6.remove(addr, e.now()) {
+				unassigned = true
+			}
+		}
+		if unassigned {
+			return ServerError(apiErr.ErrInvalidIPIPUnassigned, "The ip is not assigned to the eni.")
+		}
+		return nil
+	})
+	if apiErr.ErrorCodeIs(err, apiErr.ErrInvalidIPIPUnassigned, apiErr.ErrInvalidENINotFound) {
+		return nil
+	}
+	return err
+}
+
+func (e *Emulator) ModifyNetworkInterfaceAttribute(ctx context.Context, eniID string, securityGroupIDs []string) error {
+	return e.call("ModifyNetworkInterfaceAttribute", func() error {
+		eni, _, err := e.getENILocked(eniID)
+		if err != nil {
+			return err
+		}
+		eni.securityGroupIDs = append([]string(nil), securityGroupIDs...)
+		return nil
+	})
+}
+
+func (e *Emulator) DescribeInstanceTypes(ctx context.Context, types []string) ([]ecs.InstanceType, error) {
+	var result []ecs.InstanceType
+	err := e.call("DescribeInstanceTypes", func() error {
+		want := sets.New[string](types...)
+		for id, t := range e.instanceTypes {
+			if types != nil && !want.Has(id) {
+				continue
+			}
+			result = append(result, t)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].InstanceTypeId < result[j].InstanceTypeId
+	})
+	return result, nil
+}
+
+// DescribeSecurityGroups the security groups are not modeled, every one is found in the vpc
+func (e *Emulator) DescribeSecurityGroups(ctx context.Context, vpcID string, securityGroupIDs []string) ([]ecs.SecurityGroup, error) {
+	var result []ecs.SecurityGroup
+	err := e.call("DescribeSecurityGroups", func() error {
+		for _, id := range securityGroupIDs {
+			result = append(result, ecs.SecurityGroup{SecurityGroupId: id, VpcId: vpcID})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (e *Emulator) getENILocked(eniID string) (*networkInterface, *vSwitch, error) {
+	eni, ok := e.enis[eniID]
+	if !ok {
+		return nil, nil, ServerError(apiErr.ErrInvalidENINotFound, "The specified NetworkInterfaceId does not exist.")
+	}
+	return eni, e.vSwitches[eni.vSwitchID], nil
+}
+
+// transitLocked set the transient status, the eni is the next status after the StatusDelay
+func (e *Emulator) transitLocked(eni *networkInterface, transient, next string) {
+	eni.status = transient
+	eni.next = next
+	eni.settle = e.now().Add(e.cfg.StatusDelay)
+	e.settleLocked()
+}
+
+// ipv4LimitLocked is the ipv4 quota of the eni, 0 if the eni is not attached
+func (e *Emulator) ipv4LimitLocked(eni *networkInterface) int {
+	ins, ok := e.instances[eni.instanceID]
+	if !ok {
+		return 0
+	}
+	return e.instanceTypes[ins.instanceType].EniPrivateIpAddressQuantity
+}
+
+// ipv6LimitLocked is the ipv6 quota of the eni, 0 if the eni is not attached
+func (e *Emulator) ipv6LimitLocked(eni *networkInterface) int {
+	ins, ok := e.instances[eni.instanceID]
+	if !ok {
+		return 0
+	}
+	return e.instanceTypes[ins.instanceType].EniIpv6AddressQuantity
+}
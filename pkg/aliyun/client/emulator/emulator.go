@@ -0,0 +1,531 @@
+//go:build default_build
+
+// Package emulator is an in-process ECS and VPC openAPI, it keeps the eni, ip and vSwitch state as the cloud does.
+// Quotas, eventual consistency and throttling are emulated, so the eni factory and the controllers can be tested
+// without the cloud.
+package emulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"sort"
+	"sync"
+	"time"
+
+	sdkErr "github.com/aliyun/alibaba-cloud-sdk-go/sdk/errors"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/vpc"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun/client"
+	apiErr "github.com/AliyunContainerService/terway/pkg/aliyun/client/errors"
+)
+
+var (
+	_ client.ECS = &Emulator{}
+	_ client.VPC = &Emulator{}
+)
+
+// error codes returned by the openAPI, terway has no special handling for them
+const (
+	ErrIPv4CountExceeded        = "InvalidOperation.Ipv4CountExceeded"
+	ErrIPv6CountExceeded        = "InvalidOperation.Ipv6CountExceeded"
+	ErrInvalidVSwitchIDNotFound = "InvalidVSwitchId.NotFound"
+	ErrMemberEniLimitExceeded   = "InvalidOperation.MemberEniLimitExceeded"
+)
+
+// prefixBits is the size of the ipv4 prefix assigned to the eni
+const prefixBits = 28
+
+// Config the behavior of the emulator, the zero value is a consistent cloud
+type Config struct {
+	// StatusDelay is the time an attaching or detaching eni takes to be InUse or Available
+	StatusDelay time.Duration
+	// DescribeDelay is the time a created eni takes to show up in DescribeNetworkInterfaces
+	DescribeDelay time.Duration
+	// MetadataDelay is the time an assigned or unassigned address takes to show up in the metadata
+	MetadataDelay time.Duration
+}
+
+// Emulator implement client.ECS and client.VPC with the state in memory
+type Emulator struct {
+	cfg Config
+
+	lock          sync.Mutex
+	seq           int
+	vSwitches     map[string]*vSwitch
+	instanceTypes map[string]ecs.InstanceType
+	instances     map[string]*instance
+	enis          map[string]*networkInterface
+	faults        map[string][]error
+	calls         map[string]int
+
+	keyGen client.IdempotentKeyGen
+	now    func() time.Time
+}
+
+// New create an emulator without any resource
+func New(cfg Config) *Emulator {
+	return &Emulator{
+		cfg:           cfg,
+		vSwitches:     make(map[string]*vSwitch),
+		instanceTypes: make(map[string]ecs.InstanceType),
+		instances:     make(map[string]*instance),
+		enis:          make(map[string]*networkInterface),
+		faults:        make(map[string][]error),
+		calls:         make(map[string]int),
+		keyGen:        client.NewIdempotentKeyGenerator(),
+		now:           time.Now,
+	}
+}
+
+type vSwitch struct {
+	vsw  vpc.VSwitch
+	cidr netip.Prefix
+	v6   netip.Prefix
+	used map[netip.Addr]struct{}
+	next netip.Addr
+}
+
+type instance struct {
+	id           string
+	instanceType string
+	zoneID       string
+	primaryENI   string
+}
+
+type networkInterface struct {
+	id, mac     string
+	vSwitchID   string
+	eniType     string
+	trafficMode string
+	created     time.Time
+
+	// status turns to next at settle
+	status string
+	next   string
+	settle time.Time
+
+	instanceID  string
+	trunkENIID  string
+	deviceIndex int
+
+	securityGroupIDs []string
+	resourceGroupID  string
+	tags             map[string]string
+
+	primaryIP netip.Addr
+	ipv4      *addrSet[netip.Addr]
+	ipv6      *addrSet[netip.Addr]
+	prefixes  *addrSet[netip.Prefix]
+}
+
+// AddVSwitch add the vSwitch, the available ip count is calculated from the cidr.
+// Like the cloud, the first and the last three addresses are reserved.
+func (e *Emulator) AddVSwitch(in vpc.VSwitch) error {
+	cidr, err := netip.ParsePrefix(in.CidrBlock)
+	if err != nil {
+		return fmt.Errorf("error parse cidr of vSwitch %s, %w", in.VSwitchId, err)
+	}
+	sw := &vSwitch{
+		vsw:  in,
+		cidr: cidr.Masked(),
+		used: make(map[netip.Addr]struct{}),
+	}
+	if in.Ipv6CidrBlock != "" {
+		sw.v6, err = netip.ParsePrefix(in.Ipv6CidrBlock)
+		if err != nil {
+			return fmt.Errorf("error parse ipv6 cidr of vSwitch %s, %w", in.VSwitchId, err)
+		}
+		sw.v6 = sw.v6.Masked()
+		sw.next = sw.v6.Addr().Next()
+	}
+	if sw.vsw.Status == "" {
+		sw.vsw.Status = "Available"
+	}
+	sw.vsw.AvailableIpAddressCount = int64(1<<(cidr.Addr().BitLen()-cidr.Bits())) - 4
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.vSwitches[in.VSwitchId] = sw
+	return nil
+}
+
+// AddInstanceType add the instance type, the eni and ip quotas of the instances are read from it
+func (e *Emulator) AddInstanceType(in ecs.InstanceType) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.instanceTypes[in.InstanceTypeId] = in
+}
+
+// AddInstance add an ecs instance, the primary eni is created in the vSwitch
+func (e *Emulator) AddInstance(instanceID, instanceType, vSwitchID string) (*client.NetworkInterface, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if _, ok := e.instanceTypes[instanceType]; !ok {
+		return nil, fmt.Errorf("instance type %s is not found", instanceType)
+	}
+	sw, ok := e.vSwitches[vSwitchID]
+	if !ok {
+		return nil, fmt.Errorf("vSwitch %s is not found", vSwitchID)
+	}
+	eni, err := e.newENILocked(sw, client.ENITypePrimary, 1, 0)
+	if err != nil {
+		return nil, err
+	}
+	eni.status = client.ENIStatusInUse
+	eni.instanceID = instanceID
+
+	e.instances[instanceID] = &instance{
+		id:           instanceID,
+		instanceType: instanceType,
+		zoneID:       sw.vsw.ZoneId,
+		primaryENI:   eni.id,
+	}
+	return e.toNetworkInterfaceLocked(eni), nil
+}
+
+// Inject queue the errors, they are returned by the following calls of the api instead of running it.
+// The api is the openAPI action name, e.g. AssignPrivateIpAddresses.
+func (e *Emulator) Inject(api string, errs ...error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.faults[api] = append(e.faults[api], errs...)
+}
+
+// Calls return the count of the calls to the api, including the failed ones
+func (e *Emulator) Calls(api string) int {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.calls[api]
+}
+
+// ENI return the current state of the eni, the DescribeDelay is not applied
+func (e *Emulator) ENI(eniID string) (*client.NetworkInterface, bool) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.settleLocked()
+
+	eni, ok := e.enis[eniID]
+	if !ok {
+		return nil, false
+	}
+	return e.toNetworkInterfaceLocked(eni), true
+}
+
+// ServerError build the error as the sdk does for the failed request
+func ServerError(code, message string) error {
+	content, _ := json.Marshal(map[string]string{
+		"Code":      code,
+		"Message":   message,
+		"RequestId": "emulator",
+	})
+	return sdkErr.NewServerError(400, string(content), "")
+}
+
+// ThrottlingError build the error for the throttled request
+func ThrottlingError() error {
+	return ServerError(apiErr.ErrThrottling, "Request was denied due to request throttling.")
+}
+
+// call run the api with the lock held, the injected error is returned instead if any
+func (e *Emulator) call(api string, fn func() error) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.calls[api]++
+	if faults := e.faults[api]; len(faults) > 0 {
+		e.faults[api] = faults[1:]
+		return apiErr.WarpError(faults[0])
+	}
+	e.settleLocked()
+	return apiErr.WarpError(fn())
+}
+
+// settleLocked move the eni to the next status once the StatusDelay is passed
+func (e *Emulator) settleLocked() {
+	now := e.now()
+	for _, eni := range e.enis {
+		if eni.next == "" || now.Before(eni.settle) {
+			continue
+		}
+		eni.status = eni.next
+		eni.next = ""
+		if eni.status == client.ENIStatusAvailable {
+			eni.instanceID, eni.trunkENIID, eni.deviceIndex = "", "", 0
+			if eni.eniType == client.ENITypeMember {
+				eni.eniType = client.ENITypeSecondary
+			}
+		}
+	}
+}
+
+// newENILocked create the available eni, the first ipv4 address is the primary ip
+func (e *Emulator) newENILocked(sw *vSwitch, eniType string, ipv4, ipv6 int) (*networkInterface, error) {
+	v6, err := sw.allocIPv6(ipv6)
+	if err != nil {
+		return nil, err
+	}
+	v4, err := sw.allocIPv4(ipv4)
+	if err != nil {
+		return nil, err
+	}
+
+	e.seq++
+	eni := &networkInterface{
+		id:        fmt.Sprintf("eni-emu%08d", e.seq),
+		mac:       fmt.Sprintf("02:00:00:%02x:%02x:%02x", e.seq>>16&0xff, e.seq>>8&0xff, e.seq&0xff),
+		vSwitchID: sw.vsw.VSwitchId,
+		eniType:   eniType,
+		status:    client.ENIStatusAvailable,
+		created:   e.now(),
+		primaryIP: v4[0],
+		tags:      map[string]string{},
+		ipv4:      newAddrSet[netip.Addr](),
+		ipv6:      newAddrSet[netip.Addr](),
+		prefixes:  newAddrSet[netip.Prefix](),
+	}
+	for _, addr := range v4[1:] {
+		eni.ipv4.add(addr, eni.created)
+	}
+	for _, addr := range v6 {
+		eni.ipv6.add(addr, eni.created)
+	}
+	e.enis[eni.id] = eni
+	return eni, nil
+}
+
+func (e *Emulator) toNetworkInterfaceLocked(eni *networkInterface) *client.NetworkInterface {
+	sw := e.vSwitches[eni.vSwitchID]
+
+	r := &client.NetworkInterface{
+		Status:                      eni.status,
+		MacAddress:                  eni.mac,
+		NetworkInterfaceID:          eni.id,
+		VSwitchID:                   eni.vSwitchID,
+		PrivateIPAddress:            eni.primaryIP.String(),
+		ZoneID:                      sw.vsw.ZoneId,
+		SecurityGroupIDs:            append([]string(nil), eni.securityGroupIDs...),
+		ResourceGroupID:             eni.resourceGroupID,
+		Type:                        eni.eniType,
+		InstanceID:                  eni.instanceID,
+		TrunkNetworkInterfaceID:     eni.trunkENIID,
+		NetworkInterfaceTrafficMode: eni.trafficMode,
+		DeviceIndex:                 eni.deviceIndex,
+		CreationTime:                eni.created.UTC().Format(time.RFC3339),
+	}
+
+	r.PrivateIPSets = append(r.PrivateIPSets, ecs.PrivateIpSet{PrivateIpAddress: eni.primaryIP.String(), Primary: true})
+	for _, addr := range eni.ipv4.items() {
+		r.PrivateIPSets = append(r.PrivateIPSets, ecs.PrivateIpSet{PrivateIpAddress: addr.String()})
+	}
+	for _, addr := range eni.ipv6.items() {
+		r.IPv6Set = append(r.IPv6Set, ecs.Ipv6Set{Ipv6Address: addr.String()})
+	}
+	for _, prefix := range eni.prefixes.items() {
+		r.IPv4PrefixSets = append(r.IPv4PrefixSets, ecs.Ipv4PrefixSet{Ipv4Prefix: prefix.String()})
+	}
+
+	keys := make([]string, 0, len(eni.tags))
+	for k := range eni.tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		r.Tags = append(r.Tags, ecs.Tag{Key: k, Value: eni.tags[k], TagKey: k, TagValue: eni.tags[k]})
+	}
+	return r
+}
+
+// ipCount is the count of the ipv4 addresses, the primary ip is included
+func (eni *networkInterface) ipCount() int {
+	return 1 + len(eni.ipv4.items())
+}
+
+// release put back all addresses of the eni to the vSwitch
+func (eni *networkInterface) release(sw *vSwitch) {
+	sw.releaseIPv4(eni.primaryIP)
+	for _, addr := range eni.ipv4.items() {
+		sw.releaseIPv4(addr)
+	}
+	for _, prefix := range eni.prefixes.items() {
+		sw.releasePrefix(prefix)
+	}
+}
+
+// allocIPv4 allocate the addresses from the vSwitch cidr, all or nothing
+func (sw *vSwitch) allocIPv4(count int) ([]netip.Addr, error) {
+	if int64(count) > sw.vsw.AvailableIpAddressCount {
+		return nil, ServerError(apiErr.InvalidVSwitchIDIPNotEnough, "The specified VSwitch has not enough IpAddress.")
+	}
+	var result []netip.Addr
+	for addr := sw.cidr.Addr().Next(); sw.usable(addr) && len(result) < count; addr = addr.Next() {
+		if _, ok := sw.used[addr]; ok {
+			continue
+		}
+		result = append(result, addr)
+	}
+	if len(result) < count {
+		return nil, ServerError(apiErr.InvalidVSwitchIDIPNotEnough, "The specified VSwitch has not enough IpAddress.")
+	}
+	for _, addr := range result {
+		sw.used[addr] = struct{}{}
+	}
+	sw.vsw.AvailableIpAddressCount -= int64(count)
+	return result, nil
+}
+
+// allocPrefix allocate the aligned /28 prefixes, none of the addresses in them is used
+func (sw *vSwitch) allocPrefix(count int) ([]netip.Prefix, error) {
+	size := 1 << (32 - prefixBits)
+
+	var result []netip.Prefix
+	for addr := sw.cidr.Addr(); sw.cidr.Contains(addr) && len(result) < count; {
+		prefix := netip.PrefixFrom(addr, prefixBits)
+		free := true
+		next := addr
+		for i := 0; i < size; i++ {
+			if _, ok := sw.used[next]; ok || !sw.usable(next) {
+				free = false
+			}
+			next = next.Next()
+		}
+		if free {
+			result = append(result, prefix)
+		}
+		addr = next
+	}
+	if len(result) < count {
+		return nil, ServerError(apiErr.InvalidVSwitchIDIPNotEnough, "The specified VSwitch has not enough IpAddress.")
+	}
+	for _, prefix := range result {
+		for addr, i := prefix.Addr(), 0; i < size; addr, i = addr.Next(), i+1 {
+			sw.used[addr] = struct{}{}
+		}
+	}
+	sw.vsw.AvailableIpAddressCount -= int64(count * size)
+	return result, nil
+}
+
+// allocIPv6 allocate the addresses from the vSwitch ipv6 cidr
+func (sw *vSwitch) allocIPv6(count int) ([]netip.Addr, error) {
+	if count == 0 {
+		return nil, nil
+	}
+	if !sw.v6.IsValid() {
+		return nil, ServerError("InvalidParam.Ipv6AddressCount", "The specified VSwitch has no ipv6 cidr.")
+	}
+	var result []netip.Addr
+	for i := 0; i < count; i++ {
+		result = append(result, sw.next)
+		sw.next = sw.next.Next()
+	}
+	return result, nil
+}
+
+func (sw *vSwitch) releaseIPv4(addr netip.Addr) {
+	if _, ok := sw.used[addr]; !ok {
+		return
+	}
+	delete(sw.used, addr)
+	sw.vsw.AvailableIpAddressCount++
+}
+
+func (sw *vSwitch) releasePrefix(prefix netip.Prefix) {
+	for addr := prefix.Addr(); prefix.Contains(addr); addr = addr.Next() {
+		sw.releaseIPv4(addr)
+	}
+}
+
+// usable return false for the reserved network address and the last three addresses
+func (sw *vSwitch) usable(addr netip.Addr) bool {
+	if !sw.cidr.Contains(addr) || addr == sw.cidr.Addr() {
+		return false
+	}
+	last := sw.lastAddr()
+	for i := 0; i < 3; i++ {
+		if addr == last {
+			return false
+		}
+		last = last.Prev()
+	}
+	return true
+}
+
+func (sw *vSwitch) lastAddr() netip.Addr {
+	return lastAddr(sw.cidr)
+}
+
+// gateway is the third address from the end, e.g. 192.168.0.253 for 192.168.0.0/24
+func (sw *vSwitch) gateway() netip.Addr {
+	return sw.lastAddr().Prev().Prev()
+}
+
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	b := prefix.Addr().AsSlice()
+	for i := prefix.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 1 << (7 - i%8)
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+// addrSet keep the addresses in the assigned order, the change is visible in metadata after the delay
+type addrSet[T comparable] struct {
+	list []T
+	// added and removed record the time of the change
+	added   map[T]time.Time
+	removed map[T]time.Time
+}
+
+func newAddrSet[T comparable]() *addrSet[T] {
+	return &addrSet[T]{
+		added:   make(map[T]time.Time),
+		removed: make(map[T]time.Time),
+	}
+}
+
+func (s *addrSet[T]) add(v T, now time.Time) {
+	s.list = append(s.list, v)
+	s.added[v] = now
+	delete(s.removed, v)
+}
+
+func (s *addrSet[T]) remove(v T, now time.Time) bool {
+	for i, item := range s.list {
+		if item != v {
+			continue
+		}
+		s.list = append(s.list[:i], s.list[i+1:]...)
+		delete(s.added, v)
+		s.removed[v] = now
+		return true
+	}
+	return false
+}
+
+func (s *addrSet[T]) has(v T) bool {
+	_, ok := s.added[v]
+	return ok
+}
+
+func (s *addrSet[T]) items() []T {
+	return append([]T(nil), s.list...)
+}
+
+// visible return the addresses shown in metadata, the added one is hidden and the removed one is kept until the delay passed
+func (s *addrSet[T]) visible(now time.Time, delay time.Duration) []T {
+	var result []T
+	for _, v := range s.list {
+		if !now.Before(s.added[v].Add(delay)) {
+			result = append(result, v)
+		}
+	}
+	for v, t := range s.removed {
+		if now.Before(t.Add(delay)) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
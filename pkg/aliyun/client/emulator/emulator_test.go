@@ -0,0 +1,291 @@
+//go:build default_build
+
+package emulator
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/vpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun/client"
+	apiErr "github.com/AliyunContainerService/terway/pkg/aliyun/client/errors"
+	"github.com/AliyunContainerService/terway/pkg/aliyun/metadata"
+)
+
+func newTestEmulator(t *testing.T, cfg Config) *Emulator {
+	e := New(cfg)
+	require.NoError(t, e.AddVSwitch(vpc.VSwitch{
+		VSwitchId:     "vsw-1",
+		VpcId:         "vpc-1",
+		ZoneId:        "zone-a",
+		CidrBlock:     "192.168.0.0/24",
+		Ipv6CidrBlock: "fd00::/64",
+	}))
+	e.AddInstanceType(ecs.InstanceType{
+		InstanceTypeId:              "ecs.g7.large",
+		EniQuantity:                 3,
+		EniTotalQuantity:            6,
+		EniPrivateIpAddressQuantity: 4,
+		EniIpv6AddressQuantity:      2,
+		EniTrunkSupported:           true,
+	})
+	_, err := e.AddInstance("i-1", "ecs.g7.large", "vsw-1")
+	require.NoError(t, err)
+	return e
+}
+
+func createENI(t *testing.T, e *Emulator, ipCount int) *client.NetworkInterface {
+	eni, err := e.CreateNetworkInterface(context.Background(), &client.CreateNetworkInterfaceOptions{
+		NetworkInterfaceOptions: &client.NetworkInterfaceOptions{
+			VSwitchID:        "vsw-1",
+			SecurityGroupIDs: []string{"sg-1"},
+			IPCount:          ipCount,
+			Tags:             map[string]string{"foo": "bar"},
+		},
+	})
+	require.NoError(t, err)
+	return eni
+}
+
+func availableIP(t *testing.T, e *Emulator) int64 {
+	vsw, err := e.DescribeVSwitchByID(context.Background(), "vsw-1")
+	require.NoError(t, err)
+	return vsw.AvailableIpAddressCount
+}
+
+func TestENILifecycle(t *testing.T) {
+	e := newTestEmulator(t, Config{StatusDelay: 50 * time.Millisecond})
+	ctx := context.Background()
+	// 256 - 4 reserved - primary eni of the instance
+	assert.Equal(t, int64(251), availableIP(t, e))
+
+	eni := createENI(t, e, 3)
+	assert.Equal(t, client.ENIStatusAvailable, eni.Status)
+	assert.Equal(t, "192.168.0.2", eni.PrivateIPAddress)
+	assert.Len(t, eni.PrivateIPSets, 3)
+	assert.Equal(t, int64(248), availableIP(t, e))
+
+	require.NoError(t, e.AttachNetworkInterface(ctx, eni.NetworkInterfaceID, "i-1", ""))
+	got, _ := e.ENI(eni.NetworkInterfaceID)
+	assert.Equal(t, statusAttaching, got.Status)
+	err := e.DeleteNetworkInterface(ctx, eni.NetworkInterfaceID)
+	assert.True(t, apiErr.ErrorCodeIs(err, apiErr.ErrInvalidENIState))
+
+	got, err = e.WaitForNetworkInterface(ctx, eni.NetworkInterfaceID, client.ENIStatusInUse, wait.Backoff{Duration: 20 * time.Millisecond, Factor: 1, Steps: 10}, false)
+	require.NoError(t, err)
+	assert.Equal(t, "i-1", got.InstanceID)
+	assert.Equal(t, 1, got.DeviceIndex)
+
+	require.NoError(t, e.DetachNetworkInterface(ctx, eni.NetworkInterfaceID, "i-1", ""))
+	_, err = e.WaitForNetworkInterface(ctx, eni.NetworkInterfaceID, client.ENIStatusAvailable, wait.Backoff{Duration: 20 * time.Millisecond, Factor: 1, Steps: 10}, false)
+	require.NoError(t, err)
+	require.NoError(t, e.DeleteNetworkInterface(ctx, eni.NetworkInterfaceID))
+	assert.Equal(t, int64(251), availableIP(t, e))
+
+	// eni is gone
+	assert.NoError(t, e.DetachNetworkInterface(ctx, eni.NetworkInterfaceID, "i-1", ""))
+	err = e.DeleteNetworkInterface(ctx, eni.NetworkInterfaceID)
+	assert.True(t, apiErr.ErrorCodeIs(err, apiErr.ErrInvalidENINotFound))
+}
+
+func TestQuota(t *testing.T) {
+	e := newTestEmulator(t, Config{})
+	ctx := context.Background()
+
+	eni1, eni2, eni3 := createENI(t, e, 1), createENI(t, e, 1), createENI(t, e, 1)
+	require.NoError(t, e.AttachNetworkInterface(ctx, eni1.NetworkInterfaceID, "i-1", ""))
+	require.NoError(t, e.AttachNetworkInterface(ctx, eni2.NetworkInterfaceID, "i-1", ""))
+	err := e.AttachNetworkInterface(ctx, eni3.NetworkInterfaceID, "i-1", "")
+	assert.True(t, apiErr.ErrorCodeIs(err, apiErr.ErrEniPerInstanceLimitExceeded), err)
+
+	ips, err := e.AssignPrivateIPAddress(ctx, &client.AssignPrivateIPAddressOptions{NetworkInterfaceOptions: &client.NetworkInterfaceOptions{
+		NetworkInterfaceID: eni1.NetworkInterfaceID,
+		IPCount:            3,
+	}})
+	require.NoError(t, err)
+	assert.Len(t, ips, 3)
+	_, err = e.AssignPrivateIPAddress(ctx, &client.AssignPrivateIPAddressOptions{NetworkInterfaceOptions: &client.NetworkInterfaceOptions{
+		NetworkInterfaceID: eni1.NetworkInterfaceID,
+		IPCount:            1,
+	}})
+	assert.True(t, apiErr.ErrorCodeIs(err, ErrIPv4CountExceeded), err)
+
+	_, err = e.AssignIpv6Addresses(ctx, &client.AssignIPv6AddressesOptions{NetworkInterfaceOptions: &client.NetworkInterfaceOptions{
+		NetworkInterfaceID: eni1.NetworkInterfaceID,
+		IPv6Count:          3,
+	}})
+	assert.True(t, apiErr.ErrorCodeIs(err, ErrIPv6CountExceeded), err)
+
+	// unassign the ip not assigned is ignored
+	require.NoError(t, e.UnAssignPrivateIPAddresses(ctx, eni1.NetworkInterfaceID, append(ips, netip.MustParseAddr("192.168.0.200"))))
+	got, _ := e.ENI(eni1.NetworkInterfaceID)
+	assert.Len(t, got.PrivateIPSets, 1)
+}
+
+func TestVSwitchIPNotEnough(t *testing.T) {
+	e := New(Config{})
+	require.NoError(t, e.AddVSwitch(vpc.VSwitch{VSwitchId: "vsw-1", ZoneId: "zone-a", CidrBlock: "192.168.0.0/29"}))
+
+	_, err := e.CreateNetworkInterface(context.Background(), &client.CreateNetworkInterfaceOptions{
+		NetworkInterfaceOptions: &client.NetworkInterfaceOptions{VSwitchID: "vsw-1", SecurityGroupIDs: []string{"sg-1"}, IPCount: 5},
+	})
+	assert.True(t, apiErr.ErrorCodeIs(err, apiErr.InvalidVSwitchIDIPNotEnough), err)
+
+	eni := createENI(t, e, 4)
+	assert.Equal(t, []ecs.PrivateIpSet{
+		{PrivateIpAddress: "192.168.0.1", Primary: true},
+		{PrivateIpAddress: "192.168.0.2"},
+		{PrivateIpAddress: "192.168.0.3"},
+		{PrivateIpAddress: "192.168.0.4"},
+	}, eni.PrivateIPSets)
+	assert.Equal(t, int64(0), availableIP(t, e))
+}
+
+func TestPrefix(t *testing.T) {
+	e := newTestEmulator(t, Config{})
+	ctx := context.Background()
+	eni := createENI(t, e, 1)
+
+	prefixes, err := e.AssignPrivateIPv4Prefix(ctx, &client.AssignPrivateIPAddressOptions{NetworkInterfaceOptions: &client.NetworkInterfaceOptions{
+		NetworkInterfaceID: eni.NetworkInterfaceID,
+		IPv4PrefixCount:    2,
+	}})
+	require.NoError(t, err)
+	// 192.168.0.0/28 has the reserved and the used addresses
+	assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("192.168.0.16/28"), netip.MustParsePrefix("192.168.0.32/28")}, prefixes)
+	assert.Equal(t, int64(250-32), availableIP(t, e))
+
+	require.NoError(t, e.UnAssignPrivateIPv4Prefix(ctx, eni.NetworkInterfaceID, prefixes[:1]))
+	assert.Equal(t, int64(250-16), availableIP(t, e))
+}
+
+func TestThrottling(t *testing.T) {
+	e := newTestEmulator(t, Config{})
+	ctx := context.Background()
+
+	e.Inject("CreateNetworkInterface", ThrottlingError(), ThrottlingError())
+	eni, err := e.CreateNetworkInterface(ctx, &client.CreateNetworkInterfaceOptions{
+		NetworkInterfaceOptions: &client.NetworkInterfaceOptions{VSwitchID: "vsw-1", SecurityGroupIDs: []string{"sg-1"}},
+		Backoff:                 &wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 3},
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, eni.NetworkInterfaceID)
+	assert.Equal(t, 3, e.Calls("CreateNetworkInterface"))
+
+	// not retried
+	e.Inject("AttachNetworkInterface", ThrottlingError())
+	err = e.AttachNetworkInterface(ctx, eni.NetworkInterfaceID, "i-1", "")
+	assert.True(t, apiErr.ErrorCodeIs(err, apiErr.ErrThrottling))
+	assert.Contains(t, err.Error(), "errCode: Throttling")
+
+	e.Inject("DescribeVSwitches", ServerError(apiErr.ErrForbidden, "denied"))
+	_, err = e.DescribeVSwitchByID(ctx, "vsw-1")
+	assert.True(t, apiErr.ErrorCodeIs(err, apiErr.ErrForbidden))
+	_, err = e.DescribeVSwitchByID(ctx, "vsw-1")
+	assert.NoError(t, err)
+}
+
+func TestDescribeNetworkInterface(t *testing.T) {
+	e := newTestEmulator(t, Config{DescribeDelay: 50 * time.Millisecond})
+	ctx := context.Background()
+	eni := createENI(t, e, 1)
+
+	enis, err := e.DescribeNetworkInterface(ctx, "vpc-1", nil, "", client.ENITypeSecondary, "", nil)
+	require.NoError(t, err)
+	assert.Empty(t, enis)
+
+	time.Sleep(50 * time.Millisecond)
+	enis, err = e.DescribeNetworkInterface(ctx, "vpc-1", nil, "", client.ENITypeSecondary, "", map[string]string{"foo": "bar"})
+	require.NoError(t, err)
+	require.Len(t, enis, 1)
+	assert.Equal(t, eni.NetworkInterfaceID, enis[0].NetworkInterfaceID)
+	assert.Equal(t, []ecs.Tag{{Key: "foo", Value: "bar", TagKey: "foo", TagValue: "bar"}}, enis[0].Tags)
+
+	enis, err = e.DescribeNetworkInterface(ctx, "", nil, "i-1", "", "", nil)
+	require.NoError(t, err)
+	require.Len(t, enis, 1)
+	assert.Equal(t, client.ENITypePrimary, enis[0].Type)
+}
+
+func TestMetadata(t *testing.T) {
+	e := newTestEmulator(t, Config{MetadataDelay: 50 * time.Millisecond})
+	ctx := context.Background()
+
+	transport := http.DefaultClient.Transport
+	http.DefaultClient.Transport = e.MetadataTransport("i-1")
+	defer func() {
+		http.DefaultClient.Transport = transport
+	}()
+
+	id, err := metadata.GetLocalInstanceID()
+	require.NoError(t, err)
+	assert.Equal(t, "i-1", id)
+
+	eni := createENI(t, e, 1)
+	require.NoError(t, e.AttachNetworkInterface(ctx, eni.NetworkInterfaceID, "i-1", ""))
+	macs, err := metadata.GetENIsMAC()
+	require.NoError(t, err)
+	assert.Len(t, macs, 2)
+	gw, err := metadata.GetENIGatewayAddr(eni.MacAddress)
+	require.NoError(t, err)
+	assert.Equal(t, "192.168.0.253", gw.String())
+
+	ips, err := e.AssignPrivateIPAddress(ctx, &client.AssignPrivateIPAddressOptions{NetworkInterfaceOptions: &client.NetworkInterfaceOptions{
+		NetworkInterfaceID: eni.NetworkInterfaceID,
+		IPCount:            1,
+	}})
+	require.NoError(t, err)
+	exists, err := metadata.GetIPv4ByMac(eni.MacAddress)
+	require.NoError(t, err)
+	assert.Equal(t, []netip.Addr{netip.MustParseAddr(eni.PrivateIPAddress)}, exists)
+
+	time.Sleep(50 * time.Millisecond)
+	exists, err = metadata.GetIPv4ByMac(eni.MacAddress)
+	require.NoError(t, err)
+	assert.Equal(t, []netip.Addr{netip.MustParseAddr(eni.PrivateIPAddress), ips[0]}, exists)
+
+	v6, err := metadata.GetIPv6ByMac(eni.MacAddress)
+	require.NoError(t, err)
+	assert.Empty(t, v6)
+	_, err = metadata.GetENIPrimaryAddr("02:ff:ff:ff:ff:ff")
+	assert.ErrorIs(t, err, apiErr.ErrNotFound)
+}
+
+func TestDescribeVSwitches(t *testing.T) {
+	e := newTestEmulator(t, Config{})
+	require.NoError(t, e.AddVSwitch(vpc.VSwitch{
+		VSwitchId: "vsw-2",
+		VpcId:     "vpc-1",
+		ZoneId:    "zone-a",
+		CidrBlock: "192.168.1.0/24",
+		Tags:      vpc.TagsInDescribeVSwitches{Tag: []vpc.Tag{{Key: "reserve", Value: "true"}}},
+	}))
+	require.NoError(t, e.AddVSwitch(vpc.VSwitch{
+		VSwitchId: "vsw-3",
+		VpcId:     "vpc-2",
+		ZoneId:    "zone-a",
+		CidrBlock: "192.168.2.0/24",
+		Tags:      vpc.TagsInDescribeVSwitches{Tag: []vpc.Tag{{Key: "reserve", Value: "true"}}},
+	}))
+
+	ctx := context.Background()
+	all, err := e.DescribeVSwitches(ctx, "vpc-1", nil)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, "vsw-1", all[0].VSwitchId)
+	assert.Equal(t, "vsw-2", all[1].VSwitchId)
+
+	tagged, err := e.DescribeVSwitches(ctx, "vpc-1", map[string]string{"reserve": "true"})
+	require.NoError(t, err)
+	require.Len(t, tagged, 1)
+	assert.Equal(t, "vsw-2", tagged[0].VSwitchId)
+	assert.Equal(t, int64(252), tagged[0].AvailableIpAddressCount)
+}
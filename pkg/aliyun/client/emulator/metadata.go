@@ -0,0 +1,151 @@
+//go:build default_build
+
+package emulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun/client"
+)
+
+const metadataPrefix = "/latest/meta-data/"
+
+// Metadata serve the instance metadata of the instance, the path is the same as the metadata server.
+// Only the eni InUse on the instance is shown, the addresses are changed after the MetadataDelay.
+func (e *Emulator) Metadata(instanceID string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value, ok := e.metadata(instanceID, strings.TrimPrefix(r.URL.Path, metadataPrefix))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = fmt.Fprint(w, value)
+	})
+}
+
+// MetadataTransport serve the request with the Metadata handler, without listening on any address.
+// Set it as the transport of the http.DefaultClient, so the metadata package reads from the emulator.
+func (e *Emulator) MetadataTransport(instanceID string) http.RoundTripper {
+	return &handlerTransport{handler: e.Metadata(instanceID)}
+}
+
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t *handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	w := httptest.NewRecorder()
+	t.handler.ServeHTTP(w, req)
+	resp := w.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+func (e *Emulator) metadata(instanceID, path string) (string, bool) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.settleLocked()
+
+	ins, ok := e.instances[instanceID]
+	if !ok {
+		return "", false
+	}
+	primary := e.enis[ins.primaryENI]
+	sw := e.vSwitches[primary.vSwitchID]
+
+	switch path {
+	case "instance-id":
+		return ins.id, true
+	case "instance/instance-type":
+		return ins.instanceType, true
+	case "zone-id":
+		return ins.zoneID, true
+	case "vswitch-id":
+		return sw.vsw.VSwitchId, true
+	case "vpc-id":
+		return sw.vsw.VpcId, true
+	case "mac":
+		return primary.mac, true
+	case "network/interfaces/macs/", "network/interfaces/macs":
+		var macs []string
+		for _, eni := range e.enis {
+			if e.inMetadataLocked(eni, instanceID) {
+				macs = append(macs, eni.mac+"/")
+			}
+		}
+		return strings.Join(macs, "\n"), true
+	}
+
+	mac, key, ok := strings.Cut(strings.TrimPrefix(path, "network/interfaces/macs/"), "/")
+	if !ok {
+		return "", false
+	}
+	var eni *networkInterface
+	for _, v := range e.enis {
+		if v.mac == mac && e.inMetadataLocked(v, instanceID) {
+			eni = v
+		}
+	}
+	if eni == nil {
+		return "", false
+	}
+	sw = e.vSwitches[eni.vSwitchID]
+	now, delay := e.now(), e.cfg.MetadataDelay
+
+	switch key {
+	case "network-interface-id":
+		return eni.id, true
+	case "primary-ip-address":
+		return eni.primaryIP.String(), true
+	case "gateway":
+		return sw.gateway().String(), true
+	case "vswitch-id":
+		return sw.vsw.VSwitchId, true
+	case "vswitch-cidr-block":
+		return sw.cidr.String(), true
+	case "private-ipv4s":
+		ips := []string{eni.primaryIP.String()}
+		for _, addr := range eni.ipv4.visible(now, delay) {
+			ips = append(ips, addr.String())
+		}
+		out, _ := json.Marshal(ips)
+		return string(out), true
+	case "ipv4-prefixes":
+		prefixes := eni.prefixes.visible(now, delay)
+		if len(prefixes) == 0 {
+			return "", false
+		}
+		out, _ := json.Marshal(prefixes)
+		return string(out), true
+	case "ipv6s":
+		ips := eni.ipv6.visible(now, delay)
+		if len(ips) == 0 {
+			return "", false
+		}
+		var str []string
+		for _, addr := range ips {
+			str = append(str, addr.String())
+		}
+		return "[" + strings.Join(str, ",") + "]", true
+	case "vswitch-ipv6-cidr-block":
+		if !sw.v6.IsValid() {
+			return "", false
+		}
+		return sw.v6.String(), true
+	case "ipv6-gateway":
+		if !sw.v6.IsValid() {
+			return "", false
+		}
+		return lastAddr(sw.v6).Prev().Prev().String(), true
+	}
+	return "", false
+}
+
+// inMetadataLocked the eni attached to the instance is shown, except the member eni
+func (e *Emulator) inMetadataLocked(eni *networkInterface, instanceID string) bool {
+	return eni.instanceID == instanceID && eni.status == client.ENIStatusInUse && eni.trunkENIID == ""
+}
@@ -0,0 +1,72 @@
+//go:build default_build
+
+package emulator
+
+import (
+	"context"
+	"sort"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/vpc"
+
+	apiErr "github.com/AliyunContainerService/terway/pkg/aliyun/client/errors"
+)
+
+// DescribeVSwitchByID return the vSwitch, the AvailableIpAddressCount is the current value
+func (e *Emulator) DescribeVSwitchByID(ctx context.Context, vSwitchID string) (*vpc.VSwitch, error) {
+	var result *vpc.VSwitch
+	err := e.call("DescribeVSwitches", func() error {
+		sw, ok := e.vSwitches[vSwitchID]
+		if ok {
+			vsw := sw.vsw
+			result = &vsw
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, apiErr.ErrNotFound
+	}
+	return result, nil
+}
+
+// DescribeVSwitches return the vSwitches of the vpc with all the tags, sorted by id
+func (e *Emulator) DescribeVSwitches(ctx context.Context, vpcID string, tags map[string]string) ([]vpc.VSwitch, error) {
+	var result []vpc.VSwitch
+	err := e.call("DescribeVSwitches", func() error {
+		for _, sw := range e.vSwitches {
+			if vpcID != "" && sw.vsw.VpcId != vpcID {
+				continue
+			}
+			if !hasTags(sw.vsw.Tags.Tag, tags) {
+				continue
+			}
+			result = append(result, sw.vsw)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].VSwitchId < result[j].VSwitchId
+	})
+	return result, nil
+}
+
+func hasTags(got []vpc.Tag, want map[string]string) bool {
+	for k, v := range want {
+		found := false
+		for _, tag := range got {
+			if tag.Key == k && tag.Value == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
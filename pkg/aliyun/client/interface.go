@@ -12,6 +12,7 @@ import (
 
 type VPC interface {
 	DescribeVSwitchByID(ctx context.Context, vSwitchID string) (*vpc.VSwitch, error)
+	DescribeVSwitches(ctx context.Context, vpcID string, tags map[string]string) ([]vpc.VSwitch, error)
 }
 
 type EFLO interface {
@@ -21,8 +21,11 @@ type ECS interface {
 	WaitForNetworkInterface(ctx context.Context, eniID string, status string, backoff wait.Backoff, ignoreNotExist bool) (*NetworkInterface, error)
 	AssignPrivateIPAddress(ctx context.Context, opts ...AssignPrivateIPAddressOption) ([]netip.Addr, error)
 	UnAssignPrivateIPAddresses(ctx context.Context, eniID string, ips []netip.Addr) error
+	AssignPrivateIPv4Prefix(ctx context.Context, opts ...AssignPrivateIPAddressOption) ([]netip.Prefix, error)
+	UnAssignPrivateIPv4Prefix(ctx context.Context, eniID string, prefixes []netip.Prefix) error
 	AssignIpv6Addresses(ctx context.Context, opts ...AssignIPv6AddressesOption) ([]netip.Addr, error)
 	UnAssignIpv6Addresses(ctx context.Context, eniID string, ips []netip.Addr) error
 	ModifyNetworkInterfaceAttribute(ctx context.Context, eniID string, securityGroupIDs []string) error
 	DescribeInstanceTypes(ctx context.Context, types []string) ([]ecs.InstanceType, error)
+	DescribeSecurityGroups(ctx context.Context, vpcID string, securityGroupIDs []string) ([]ecs.SecurityGroup, error)
 }
@@ -44,6 +44,36 @@ func (_m *VPC) DescribeVSwitchByID(ctx context.Context, vSwitchID string) (*vpc.
 	return r0, r1
 }
 
+// DescribeVSwitches provides a mock function with given fields: ctx, vpcID, tags
+func (_m *VPC) DescribeVSwitches(ctx context.Context, vpcID string, tags map[string]string) ([]vpc.VSwitch, error) {
+	ret := _m.Called(ctx, vpcID, tags)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DescribeVSwitches")
+	}
+
+	var r0 []vpc.VSwitch
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, map[string]string) ([]vpc.VSwitch, error)); ok {
+		return rf(ctx, vpcID, tags)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, map[string]string) []vpc.VSwitch); ok {
+		r0 = rf(ctx, vpcID, tags)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]vpc.VSwitch)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, map[string]string) error); ok {
+		r1 = rf(ctx, vpcID, tags)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewVPC creates a new instance of VPC. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewVPC(t interface {
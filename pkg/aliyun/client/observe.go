@@ -0,0 +1,20 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/AliyunContainerService/terway/pkg/metric"
+	"github.com/AliyunContainerService/terway/pkg/telemetry"
+)
+
+// observeAPI record the latency of the open api call, and the call as a span in the trace of ctx
+func observeAPI(ctx context.Context, api string, start time.Time, err error) {
+	metric.OpenAPILatency.WithLabelValues(api, fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+
+	_, span := telemetry.Start(ctx, "OpenAPI "+api, trace.WithTimestamp(start), trace.WithSpanKind(trace.SpanKindClient))
+	telemetry.End(span, err)
+}
@@ -19,6 +19,7 @@ const (
 	LogFieldSecondaryIPCount = "secondaryIPCount"
 	LogFieldENIID            = "eni"
 	LogFieldIPs              = "ips"
+	LogFieldPrefixes         = "prefixes"
 	LogFieldEIPID            = "eip"
 	LogFieldPrivateIP        = "privateIP"
 	LogFieldVSwitchID        = "vSwitchID"
@@ -30,6 +31,8 @@ const (
 	eniNamePrefix     = "eni-cni-"
 	eniDescription    = "interface create by terway"
 	maxSinglePageSize = 500
+
+	describeVSwitchesPageSize = 50
 )
 
 // status for eni
@@ -53,17 +56,18 @@ const EIPInstanceTypeNetworkInterface = "NetworkInterface"
 
 // NetworkInterface openAPI result for ecs.CreateNetworkInterfaceResponse and ecs.NetworkInterfaceSet
 type NetworkInterface struct {
-	Status             string             `json:"status,omitempty"`
-	MacAddress         string             `json:"mac_address,omitempty"`
-	NetworkInterfaceID string             `json:"network_interface_id,omitempty"`
-	VSwitchID          string             `json:"v_switch_id,omitempty"`
-	PrivateIPAddress   string             `json:"private_ip_address,omitempty"`
-	PrivateIPSets      []ecs.PrivateIpSet `json:"private_ip_sets"`
-	ZoneID             string             `json:"zone_id,omitempty"`
-	SecurityGroupIDs   []string           `json:"security_group_ids,omitempty"`
-	ResourceGroupID    string             `json:"resource_group_id,omitempty"`
-	IPv6Set            []ecs.Ipv6Set      `json:"ipv6_set,omitempty"`
-	Tags               []ecs.Tag          `json:"tags,omitempty"`
+	Status             string              `json:"status,omitempty"`
+	MacAddress         string              `json:"mac_address,omitempty"`
+	NetworkInterfaceID string              `json:"network_interface_id,omitempty"`
+	VSwitchID          string              `json:"v_switch_id,omitempty"`
+	PrivateIPAddress   string              `json:"private_ip_address,omitempty"`
+	PrivateIPSets      []ecs.PrivateIpSet  `json:"private_ip_sets"`
+	ZoneID             string              `json:"zone_id,omitempty"`
+	SecurityGroupIDs   []string            `json:"security_group_ids,omitempty"`
+	ResourceGroupID    string              `json:"resource_group_id,omitempty"`
+	IPv6Set            []ecs.Ipv6Set       `json:"ipv6_set,omitempty"`
+	IPv4PrefixSets     []ecs.Ipv4PrefixSet `json:"ipv4_prefix_sets,omitempty"`
+	Tags               []ecs.Tag           `json:"tags,omitempty"`
 
 	// fields for DescribeNetworkInterface
 	Type                        string `json:"type,omitempty"`
@@ -85,6 +89,7 @@ func FromCreateResp(in *ecs.CreateNetworkInterfaceResponse) *NetworkInterface {
 		ZoneID:             in.ZoneId,
 		SecurityGroupIDs:   in.SecurityGroupIds.SecurityGroupId,
 		IPv6Set:            in.Ipv6Sets.Ipv6Set,
+		IPv4PrefixSets:     in.Ipv4PrefixSets.Ipv4PrefixSet,
 		Tags:               in.Tags.Tag,
 		Type:               in.Type,
 		ResourceGroupID:    in.ResourceGroupId,
@@ -108,6 +113,7 @@ func FromDescribeResp(in *ecs.NetworkInterfaceSet) *NetworkInterface {
 		SecurityGroupIDs:            in.SecurityGroupIds.SecurityGroupId,
 		IPv6Set:                     in.Ipv6Sets.Ipv6Set,
 		PrivateIPSets:               in.PrivateIpSets.PrivateIpSet,
+		IPv4PrefixSets:              in.Ipv4PrefixSets.Ipv4PrefixSet,
 		Tags:                        in.Tags.Tag,
 		TrunkNetworkInterfaceID:     in.Attachment.TrunkNetworkInterfaceId,
 		NetworkInterfaceTrafficMode: in.NetworkInterfaceTrafficMode,
@@ -4,14 +4,13 @@ package client
 
 import (
 	"context"
-	"fmt"
 	"time"
 
+	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
 	"github.com/aliyun/alibaba-cloud-sdk-go/services/vpc"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	apiErr "github.com/AliyunContainerService/terway/pkg/aliyun/client/errors"
-	"github.com/AliyunContainerService/terway/pkg/metric"
 )
 
 // DescribeVSwitchByID get vsw by id
@@ -26,7 +25,7 @@ func (a *OpenAPI) DescribeVSwitchByID(ctx context.Context, vSwitchID string) (*v
 
 	start := time.Now()
 	resp, err := a.ClientSet.VPC().DescribeVSwitches(req)
-	metric.OpenAPILatency.WithLabelValues("DescribeVSwitches", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	observeAPI(ctx, "DescribeVSwitches", start, err)
 	if err != nil {
 		err = apiErr.WarpError(err)
 		l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(err)).Error(err, "DescribeVSwitches failed")
@@ -40,3 +39,44 @@ func (a *OpenAPI) DescribeVSwitchByID(ctx context.Context, vSwitchID string) (*v
 	}
 	return nil, err
 }
+
+// DescribeVSwitches list the vSwitches of the vpc with all the tags
+func (a *OpenAPI) DescribeVSwitches(ctx context.Context, vpcID string, tags map[string]string) ([]vpc.VSwitch, error) {
+	var vpcTags []vpc.DescribeVSwitchesTag
+	for k, v := range tags {
+		vpcTags = append(vpcTags, vpc.DescribeVSwitchesTag{
+			Key:   k,
+			Value: v,
+		})
+	}
+
+	l := logf.FromContext(ctx).WithValues(
+		LogFieldAPI, "DescribeVSwitches",
+	)
+
+	var result []vpc.VSwitch
+	for page := 1; ; page++ {
+		req := vpc.CreateDescribeVSwitchesRequest()
+		req.VpcId = vpcID
+		if len(vpcTags) > 0 {
+			req.Tag = &vpcTags
+		}
+		req.PageNumber = requests.NewInteger(page)
+		req.PageSize = requests.NewInteger(describeVSwitchesPageSize)
+
+		start := time.Now()
+		resp, err := a.ClientSet.VPC().DescribeVSwitches(req)
+		observeAPI(ctx, "DescribeVSwitches", start, err)
+		if err != nil {
+			err = apiErr.WarpError(err)
+			l.WithValues(LogFieldRequestID, apiErr.ErrRequestID(err)).Error(err, "DescribeVSwitches failed")
+			return nil, err
+		}
+		result = append(result, resp.VSwitches.VSwitch...)
+
+		if len(resp.VSwitches.VSwitch) < describeVSwitchesPageSize || len(result) >= resp.TotalCount {
+			break
+		}
+	}
+	return result, nil
+}
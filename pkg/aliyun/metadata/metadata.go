@@ -27,6 +27,7 @@ const (
 	eniV6GatewayPath       = "network/interfaces/macs/%s/ipv6-gateway"
 	eniPrivateIPs          = "network/interfaces/macs/%s/private-ipv4s"
 	eniPrivateV6IPs        = "network/interfaces/macs/%s/ipv6s"
+	eniIPv4Prefixes        = "network/interfaces/macs/%s/ipv4-prefixes"
 	eniVSwitchPath         = "network/interfaces/macs/%s/vswitch-id"
 	eniVSwitchCIDRPath     = "network/interfaces/macs/%s/vswitch-cidr-block"
 	eniVSwitchIPv6CIDRPath = "network/interfaces/macs/%s/vswitch-ipv6-cidr-block"
@@ -203,6 +204,25 @@ func GetIPv4ByMac(mac string) ([]netip.Addr, error) {
 	return ip.ToIPAddrs(*addressStrList)
 }
 
+// GetIPv4PrefixByMac by mac return ["192.168.0.16/28"]
+func GetIPv4PrefixByMac(mac string) ([]netip.Prefix, error) {
+	prefixStr, err := getValue(fmt.Sprintf(metadataBase+eniIPv4Prefixes, mac))
+	if err != nil {
+		// metadata return 404 when no prefix is allocated
+		if errors.Is(err, apiErr.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	prefixStrList := &[]string{}
+	err = json.Unmarshal([]byte(prefixStr), prefixStrList)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse prefix, %s, %w", prefixStr, err)
+	}
+
+	return ip.ToIPPrefixes(*prefixStrList)
+}
+
 // GetENIPrivateIPv6IPs by mac return [2408::28eb]
 func GetENIPrivateIPv6IPs(mac string) ([]net.IP, error) {
 	ipsStr, err := getValue(fmt.Sprintf(metadataBase+eniPrivateV6IPs, mac))
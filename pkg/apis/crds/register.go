@@ -21,6 +21,7 @@ var log = ctrl.Log.WithName("crd")
 const (
 	CRDPodENI        = "podenis.network.alibabacloud.com"
 	CRDPodNetworking = "podnetworkings.network.alibabacloud.com"
+	CRDIPReservation = "ipreservations.network.alibabacloud.com"
 
 	crdVersionKey = "crd.network.alibabacloud.com/version"
 )
@@ -31,6 +32,9 @@ var (
 
 	//go:embed network.alibabacloud.com_podnetworkings.yaml
 	crdsPodNetworking []byte
+
+	//go:embed network.alibabacloud.com_ipreservations.yaml
+	crdsIPReservation []byte
 )
 
 func getCRD(name string) apiextensionsv1.CustomResourceDefinition {
@@ -42,6 +46,8 @@ func getCRD(name string) apiextensionsv1.CustomResourceDefinition {
 		version = "v0.1.0"
 	case CRDPodNetworking:
 		crdBytes = crdsPodNetworking
+	case CRDIPReservation:
+		crdBytes = crdsIPReservation
 	default:
 		panic(fmt.Sprintf("crd %s name not exist", name))
 	}
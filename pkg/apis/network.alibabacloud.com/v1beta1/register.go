@@ -51,6 +51,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&PodENIList{},
 		&PodNetworking{},
 		&PodNetworkingList{},
+		&IPReservation{},
+		&IPReservationList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
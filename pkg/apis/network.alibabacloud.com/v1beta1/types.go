@@ -67,6 +67,21 @@ type PodENIStatus struct {
 	PodLastSeen metav1.Time `json:"podLastSeen,omitempty"`
 	// ENIInfos is the status after eni is attached, it is indexed by eni id
 	ENIInfos map[string]ENIInfo `json:"eniInfos,omitempty"`
+	// Migrations is the latest zone migrations of the pod, the oldest is dropped first
+	Migrations []ZoneMigration `json:"migrations,omitempty"`
+}
+
+// ZoneMigration record the pod moved to another zone, the eni is recreated in the new zone
+type ZoneMigration struct {
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+	// PreviousIPv4 and IPv4 is the ip of the primary interface before and after the migration
+	PreviousIPv4 string `json:"previousIPv4,omitempty"`
+	IPv4         string `json:"ipv4,omitempty"`
+	PreviousIPv6 string `json:"previousIPv6,omitempty"`
+	IPv6         string `json:"ipv6,omitempty"`
+	// MigratedAt the time the new eni is allocated
+	MigratedAt metav1.Time `json:"migratedAt,omitempty"`
 }
 
 // Allocation for eni record
@@ -103,6 +118,8 @@ type AllocationType struct {
 	Type            IPAllocType     `json:"type,omitempty"`
 	ReleaseStrategy ReleaseStrategy `json:"releaseStrategy,omitempty"`
 	ReleaseAfter    string          `json:"releaseAfter,omitempty"` // go type 5m0s
+	// ZoneMigration whether the Fixed ip pod can be moved to another zone, default is Never
+	ZoneMigration ZoneMigrationPolicy `json:"zoneMigration,omitempty"`
 }
 
 type Phase string
@@ -200,6 +217,20 @@ const (
 	ReleaseStrategyNever = "Never"
 )
 
+// +kubebuilder:validation:Enum=Never;Allowed
+
+// ZoneMigrationPolicy is the policy to move the Fixed ip pod across zones
+type ZoneMigrationPolicy string
+
+// ZoneMigrationPolicy
+const (
+	// ZoneMigrationNever the pod is pinned to the zone of the first allocation
+	ZoneMigrationNever = "Never"
+	// ZoneMigrationAllowed the pod prefers the previous zone, if it is scheduled to another zone the eni is
+	// released and a new ip is allocated from the vSwitch of the new zone
+	ZoneMigrationAllowed = "Allowed"
+)
+
 // +genclient
 // +genclient:nonNamespaced
 // +k8s:openapi-gen=true
@@ -207,6 +238,11 @@ const (
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.status`
+// +kubebuilder:printcolumn:name="Available IPs",type=integer,JSONPath=`.status.availableIPCount`
+// +kubebuilder:printcolumn:name="Pods",type=integer,JSONPath=`.status.pods`
+// +kubebuilder:printcolumn:name="Failures",type=integer,JSONPath=`.status.allocationFailures`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // PodNetworking is the Schema for the PodNetworking API
 type PodNetworking struct {
@@ -232,9 +268,26 @@ type PodNetworkingSpec struct {
 	AllocationType AllocationType `json:"allocationType,omitempty"`
 
 	Selector Selector `json:"selector,omitempty"`
+	// Priority decide which PodNetworking is used when the selectors of several PodNetworking match the pod, the higher wins.
+	// PodNetworking with overlapped selectors must have different priority
+	Priority int32 `json:"priority,omitempty"`
 
 	SecurityGroupIDs []string `json:"securityGroupIDs,omitempty"`
 	VSwitchOptions   []string `json:"vSwitchOptions,omitempty"`
+
+	// VSwitchSelectOptions how the vSwitch is picked from the VSwitchOptions
+	VSwitchSelectOptions VSwitchSelectOptions `json:"vSwitchSelectOptions,omitempty"`
+}
+
+// VSwitchSelectOptions the vSwitch selection policy and its parameters
+type VSwitchSelectOptions struct {
+	// VSwitchSelectionPolicy the policy to pick the vSwitch, default is ordered
+	// +kubebuilder:validation:Enum=ordered;random;most;weighted;least-used-by-node;prefer-cidr
+	VSwitchSelectionPolicy string `json:"vSwitchSelectionPolicy,omitempty"`
+	// VSwitchWeights the weight of the vSwitch for the weighted policy, default is 1
+	VSwitchWeights map[string]int `json:"vSwitchWeights,omitempty"`
+	// PreferCIDRs the vSwitch inside the cidrs is picked first by the prefer-cidr policy
+	PreferCIDRs []string `json:"preferCIDRs,omitempty"`
 }
 
 // PodNetworkingStatus defines the observed state of PodNetworking
@@ -247,12 +300,34 @@ type PodNetworkingStatus struct {
 	UpdateAt metav1.Time `json:"updateAt,omitempty"`
 	// Message for the status
 	Message string `json:"message,omitempty"`
+	// AvailableIPCount the available ip count of all vSwitches
+	AvailableIPCount int64 `json:"availableIPCount,omitempty"`
+	// Pods the count of the pods matched the PodNetworking and using eni
+	Pods int `json:"pods,omitempty"`
+	// AllocationFailures the count of the eni allocation failed in the last window
+	AllocationFailures int `json:"allocationFailures,omitempty"`
+	// Conditions the latest observations of the PodNetworking
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
+// PodNetworking condition types
+const (
+	// ConditionVSwitchExhausting is true if any vSwitch of the PodNetworking is projected to run out of ip
+	ConditionVSwitchExhausting = "VSwitchExhausting"
+)
+
 // VSwitch VSwitch info
 type VSwitch struct {
 	ID   string `json:"id,omitempty"`
 	Zone string `json:"zone,omitempty"`
+
+	// AvailableIPCount the available ip count of the vSwitch
+	AvailableIPCount int64 `json:"availableIPCount,omitempty"`
+	// Pods the count of the pods matched the PodNetworking and using eni in the vSwitch
+	Pods int `json:"pods,omitempty"`
+	// AllocationFailures the count of the eni allocation failed on the vSwitch in the last window
+	AllocationFailures int `json:"allocationFailures,omitempty"`
 }
 
 // NetworkingStatus the status for the resource
@@ -269,3 +344,73 @@ type Selector struct {
 	PodSelector       *metav1.LabelSelector `json:"podSelector,omitempty"`
 	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
 }
+
+// +genclient
+// +k8s:openapi-gen=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+
+// IPReservation is the Schema for the IPReservation API
+type IPReservation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   IPReservationSpec   `json:"spec,omitempty"`
+	Status IPReservationStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+
+// IPReservationList contains a list of IPReservation
+type IPReservationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []IPReservation `json:"items"`
+}
+
+// IPReservationSpec defines the desired state of IPReservation
+type IPReservationSpec struct {
+	// WorkloadName is the StatefulSet in the same namespace the ips reserved for
+	WorkloadName string `json:"workloadName"`
+	// VSwitchID is the vSwitch the ips belong to
+	VSwitchID string `json:"vSwitchID"`
+	// SecurityGroupIDs for the eni holding the ips not used by any pod
+	SecurityGroupIDs []string `json:"securityGroupIDs"`
+	// Reservations map the ordinal of the workload to the ip
+	Reservations []Reservation `json:"reservations,omitempty"`
+}
+
+// Reservation pin an ip to the pod of the ordinal
+type Reservation struct {
+	// +kubebuilder:validation:Minimum=0
+	Ordinal int `json:"ordinal"`
+	// IPv4 is the ip reserved, a random one in the vSwitch is reserved if empty
+	IPv4 string `json:"ipv4,omitempty"`
+}
+
+// IPReservationStatus defines the observed state of IPReservation
+type IPReservationStatus struct {
+	// NetworkInterfaceID is the eni holding the ips not used by any pod
+	NetworkInterfaceID string `json:"networkInterfaceID,omitempty"`
+	// Reservations is the ip reserved for each ordinal
+	Reservations []ReservationStatus `json:"reservations,omitempty"`
+	// UpdateAt the time status updated
+	UpdateAt metav1.Time `json:"updateAt,omitempty"`
+	// Message for the status
+	Message string `json:"message,omitempty"`
+}
+
+// ReservationStatus is the ip reserved for the ordinal and where it is assigned
+type ReservationStatus struct {
+	Ordinal int    `json:"ordinal"`
+	IPv4    string `json:"ipv4,omitempty"`
+	// NetworkInterfaceID is the eni the ip currently assigned to
+	NetworkInterfaceID string `json:"networkInterfaceID,omitempty"`
+	// NodeName is the node the ip is used on, empty if the ip is held by the controller
+	NodeName string `json:"nodeName,omitempty"`
+	// MissingSince is the time the ip is found not assigned to the eni, the ip may be moving between the enis.
+	// The controller holds the ip again only if it is still missing after a while.
+	MissingSince *metav1.Time `json:"missingSince,omitempty"`
+}
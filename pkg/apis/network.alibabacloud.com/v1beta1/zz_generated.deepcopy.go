@@ -104,6 +104,113 @@ func (in *ENIInfo) DeepCopy() *ENIInfo {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPReservation) DeepCopyInto(out *IPReservation) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPReservation.
+func (in *IPReservation) DeepCopy() *IPReservation {
+	if in == nil {
+		return nil
+	}
+	out := new(IPReservation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IPReservation) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPReservationList) DeepCopyInto(out *IPReservationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]IPReservation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPReservationList.
+func (in *IPReservationList) DeepCopy() *IPReservationList {
+	if in == nil {
+		return nil
+	}
+	out := new(IPReservationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IPReservationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPReservationSpec) DeepCopyInto(out *IPReservationSpec) {
+	*out = *in
+	if in.SecurityGroupIDs != nil {
+		in, out := &in.SecurityGroupIDs, &out.SecurityGroupIDs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Reservations != nil {
+		in, out := &in.Reservations, &out.Reservations
+		*out = make([]Reservation, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPReservationSpec.
+func (in *IPReservationSpec) DeepCopy() *IPReservationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IPReservationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPReservationStatus) DeepCopyInto(out *IPReservationStatus) {
+	*out = *in
+	if in.Reservations != nil {
+		in, out := &in.Reservations, &out.Reservations
+		*out = make([]ReservationStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.UpdateAt.DeepCopyInto(&out.UpdateAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPReservationStatus.
+func (in *IPReservationStatus) DeepCopy() *IPReservationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(IPReservationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PodENI) DeepCopyInto(out *PodENI) {
 	*out = *in
@@ -196,6 +303,13 @@ func (in *PodENIStatus) DeepCopyInto(out *PodENIStatus) {
 			(*out)[key] = val
 		}
 	}
+	if in.Migrations != nil {
+		in, out := &in.Migrations, &out.Migrations
+		*out = make([]ZoneMigration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodENIStatus.
@@ -282,6 +396,7 @@ func (in *PodNetworkingSpec) DeepCopyInto(out *PodNetworkingSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	in.VSwitchSelectOptions.DeepCopyInto(&out.VSwitchSelectOptions)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodNetworkingSpec.
@@ -303,6 +418,13 @@ func (in *PodNetworkingStatus) DeepCopyInto(out *PodNetworkingStatus) {
 		copy(*out, *in)
 	}
 	in.UpdateAt.DeepCopyInto(&out.UpdateAt)
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodNetworkingStatus.
@@ -315,6 +437,40 @@ func (in *PodNetworkingStatus) DeepCopy() *PodNetworkingStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Reservation) DeepCopyInto(out *Reservation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Reservation.
+func (in *Reservation) DeepCopy() *Reservation {
+	if in == nil {
+		return nil
+	}
+	out := new(Reservation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReservationStatus) DeepCopyInto(out *ReservationStatus) {
+	*out = *in
+	if in.MissingSince != nil {
+		in, out := &in.MissingSince, &out.MissingSince
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservationStatus.
+func (in *ReservationStatus) DeepCopy() *ReservationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReservationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Route) DeepCopyInto(out *Route) {
 	*out = *in
@@ -369,3 +525,46 @@ func (in *VSwitch) DeepCopy() *VSwitch {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSwitchSelectOptions) DeepCopyInto(out *VSwitchSelectOptions) {
+	*out = *in
+	if in.VSwitchWeights != nil {
+		in, out := &in.VSwitchWeights, &out.VSwitchWeights
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PreferCIDRs != nil {
+		in, out := &in.PreferCIDRs, &out.PreferCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSwitchSelectOptions.
+func (in *VSwitchSelectOptions) DeepCopy() *VSwitchSelectOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(VSwitchSelectOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZoneMigration) DeepCopyInto(out *ZoneMigration) {
+	*out = *in
+	in.MigratedAt.DeepCopyInto(&out.MigratedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZoneMigration.
+func (in *ZoneMigration) DeepCopy() *ZoneMigration {
+	if in == nil {
+		return nil
+	}
+	out := new(ZoneMigration)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -20,7 +20,9 @@ package all
 import (
 	// register all controllers
 	_ "github.com/AliyunContainerService/terway/pkg/controller/endpoint"
+	_ "github.com/AliyunContainerService/terway/pkg/controller/ip-reservation"
 	_ "github.com/AliyunContainerService/terway/pkg/controller/pod"
 	_ "github.com/AliyunContainerService/terway/pkg/controller/pod-eni"
 	_ "github.com/AliyunContainerService/terway/pkg/controller/pod-networking"
+	_ "github.com/AliyunContainerService/terway/pkg/controller/vswitch-forecast"
 )
@@ -0,0 +1,141 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/pkg/utils"
+)
+
+// MatchPodNetworkings return the Ready podNetworking matched the pod, sorted by the priority from high to low and then by the name.
+// The first one is used by the pod. For stateless pod Fixed ip config is never matched
+func MatchPodNetworkings(pod *corev1.Pod, ns *corev1.Namespace, podNetworkings []v1beta1.PodNetworking) ([]v1beta1.PodNetworking, error) {
+	podLabels := labels.Set(pod.Labels)
+	nsLabels := labels.Set(ns.Labels)
+
+	var matched []v1beta1.PodNetworking
+	for _, podNetworking := range podNetworkings {
+		if podNetworking.Status.Status != v1beta1.NetworkingStatusReady {
+			continue
+		}
+		if !utils.IsFixedNamePod(pod) {
+			// for fixed ip , only match sts pod
+			if podNetworking.Spec.AllocationType.Type == v1beta1.IPAllocTypeFixed {
+				continue
+			}
+		}
+
+		matchOne := false
+		if podNetworking.Spec.Selector.PodSelector != nil {
+			ok, err := PodMatchSelector(podNetworking.Spec.Selector.PodSelector, podLabels)
+			if err != nil {
+				return nil, fmt.Errorf("error match pod selector, %w", err)
+			}
+			if !ok {
+				continue
+			}
+			matchOne = true
+		}
+		if podNetworking.Spec.Selector.NamespaceSelector != nil {
+			ok, err := PodMatchSelector(podNetworking.Spec.Selector.NamespaceSelector, nsLabels)
+			if err != nil {
+				return nil, fmt.Errorf("error match namespace selector, %w", err)
+			}
+			if !ok {
+				continue
+			}
+			matchOne = true
+		}
+		if matchOne {
+			matched = append(matched, podNetworking)
+		}
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		if matched[i].Spec.Priority != matched[j].Spec.Priority {
+			return matched[i].Spec.Priority > matched[j].Spec.Priority
+		}
+		return matched[i].Name < matched[j].Name
+	})
+	return matched, nil
+}
+
+// PodMatchSelector pod is selected by selector
+func PodMatchSelector(labelSelector *metav1.LabelSelector, l labels.Set) (bool, error) {
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return false, err
+	}
+	return selector.Matches(l), nil
+}
+
+// SelectorOverlap return true if some label set can be selected by both selectors.
+// The nil selector put no constraint on the labels, same as the PodNetworking does.
+func SelectorOverlap(a, b *metav1.LabelSelector) (bool, error) {
+	type constraint struct {
+		in        sets.Set[string] // nil if not constrained
+		notIn     sets.Set[string]
+		exists    bool
+		notExists bool
+	}
+	constraints := map[string]*constraint{}
+
+	for _, s := range []*metav1.LabelSelector{a, b} {
+		if s == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(s)
+		if err != nil {
+			return false, err
+		}
+		requirements, _ := selector.Requirements()
+		for _, r := range requirements {
+			c, ok := constraints[r.Key()]
+			if !ok {
+				c = &constraint{notIn: sets.New[string]()}
+				constraints[r.Key()] = c
+			}
+			switch r.Operator() {
+			case selection.In, selection.Equals, selection.DoubleEquals:
+				values := sets.New[string](r.Values().UnsortedList()...)
+				if c.in == nil {
+					c.in = values
+				} else {
+					c.in = c.in.Intersection(values)
+				}
+			case selection.NotIn, selection.NotEquals:
+				c.notIn.Insert(r.Values().UnsortedList()...)
+			case selection.Exists:
+				c.exists = true
+			case selection.DoesNotExist:
+				c.notExists = true
+			}
+		}
+	}
+
+	for _, c := range constraints {
+		if c.notExists && (c.exists || c.in != nil) {
+			return false, nil
+		}
+		if c.in != nil && c.in.Difference(c.notIn).Len() == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// PodNetworkingOverlap return true if some pod can be selected by both PodNetworking
+func PodNetworkingOverlap(a, b *v1beta1.Selector) (bool, error) {
+	ok, err := SelectorOverlap(a.PodSelector, b.PodSelector)
+	if err != nil || !ok {
+		return false, err
+	}
+	return SelectorOverlap(a.NamespaceSelector, b.NamespaceSelector)
+}
@@ -0,0 +1,152 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+)
+
+func TestMatchPodNetworkings(t *testing.T) {
+	newPodNetworking := func(name string, priority int32, allocType v1beta1.IPAllocType) v1beta1.PodNetworking {
+		return v1beta1.PodNetworking{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1beta1.PodNetworkingSpec{
+				Selector: v1beta1.Selector{
+					PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "a"}},
+					NamespaceSelector: &metav1.LabelSelector{},
+				},
+				Priority:       priority,
+				AllocationType: v1beta1.AllocationType{Type: allocType},
+			},
+			Status: v1beta1.PodNetworkingStatus{Status: v1beta1.NetworkingStatusReady},
+		}
+	}
+	notReady := newPodNetworking("not-ready", 10, v1beta1.IPAllocTypeElastic)
+	notReady.Status.Status = v1beta1.NetworkingStatusFail
+	podNetworkings := []v1beta1.PodNetworking{
+		newPodNetworking("b", 0, v1beta1.IPAllocTypeElastic),
+		newPodNetworking("a", 0, v1beta1.IPAllocTypeElastic),
+		newPodNetworking("high", 1, v1beta1.IPAllocTypeElastic),
+		newPodNetworking("fixed", 2, v1beta1.IPAllocTypeFixed),
+		notReady,
+	}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace:       "default",
+		Name:            "pod",
+		Labels:          map[string]string{"app": "a"},
+		OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "rs"}},
+	}}
+
+	matched, err := MatchPodNetworkings(pod, ns, podNetworkings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, pn := range matched {
+		names = append(names, pn.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"high", "a", "b"}) {
+		t.Errorf("MatchPodNetworkings() = %v", names)
+	}
+
+	// the fixed ip config is matched by the sts pod
+	pod.OwnerReferences = []metav1.OwnerReference{{Kind: "StatefulSet", Name: "sts"}}
+	matched, err = MatchPodNetworkings(pod, ns, podNetworkings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matched) != 4 || matched[0].Name != "fixed" {
+		t.Errorf("MatchPodNetworkings() = %v, want fixed first", matched)
+	}
+
+	pod.Labels["app"] = "b"
+	matched, err = MatchPodNetworkings(pod, ns, podNetworkings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matched) != 0 {
+		t.Errorf("MatchPodNetworkings() = %v, want none", matched)
+	}
+}
+
+func TestSelectorOverlap(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b *metav1.LabelSelector
+		want bool
+	}{
+		{
+			name: "nil selects everything",
+			a:    nil,
+			b:    &metav1.LabelSelector{MatchLabels: map[string]string{"app": "a"}},
+			want: true,
+		},
+		{
+			name: "different value",
+			a:    &metav1.LabelSelector{MatchLabels: map[string]string{"app": "a"}},
+			b:    &metav1.LabelSelector{MatchLabels: map[string]string{"app": "b"}},
+			want: false,
+		},
+		{
+			name: "different key",
+			a:    &metav1.LabelSelector{MatchLabels: map[string]string{"app": "a"}},
+			b:    &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "web"}},
+			want: true,
+		},
+		{
+			name: "in and not in",
+			a: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "app", Operator: metav1.LabelSelectorOpIn, Values: []string{"a", "b"}},
+			}},
+			b: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "app", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"a", "b"}},
+			}},
+			want: false,
+		},
+		{
+			name: "in intersect",
+			a: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "app", Operator: metav1.LabelSelectorOpIn, Values: []string{"a", "b"}},
+			}},
+			b:    &metav1.LabelSelector{MatchLabels: map[string]string{"app": "b"}},
+			want: true,
+		},
+		{
+			name: "exist and not exist",
+			a: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "app", Operator: metav1.LabelSelectorOpExists},
+			}},
+			b: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "app", Operator: metav1.LabelSelectorOpDoesNotExist},
+			}},
+			want: false,
+		},
+		{
+			name: "not in and not exist",
+			a: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "app", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"a"}},
+			}},
+			b: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "app", Operator: metav1.LabelSelectorOpDoesNotExist},
+			}},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SelectorOverlap(tt.a, tt.b)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			got, err = SelectorOverlap(tt.b, tt.a)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
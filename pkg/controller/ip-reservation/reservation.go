@@ -0,0 +1,428 @@
+/*
+Copyright 2024 Terway Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipreservation
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"time"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	aliyunClient "github.com/AliyunContainerService/terway/pkg/aliyun/client"
+	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/pkg/backoff"
+	register "github.com/AliyunContainerService/terway/pkg/controller"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/AliyunContainerService/terway/types/controlplane"
+)
+
+const controllerName = "ip-reservation"
+
+// resyncPeriod is the period to check the ips released by the daemon
+const resyncPeriod = time.Minute
+
+// moveTimeout is the time to wait for the daemon moving the ip, the ip missing longer is held again
+const moveTimeout = 5 * time.Minute
+
+func init() {
+	register.Add(controllerName, func(mgr manager.Manager, ctrlCtx *register.ControllerCtx) error {
+		c, err := controller.New(controllerName, mgr, controller.Options{
+			Reconciler:              NewReconcileIPReservation(mgr, ctrlCtx.AliyunClient),
+			MaxConcurrentReconciles: 1,
+		})
+		if err != nil {
+			return err
+		}
+
+		return c.Watch(
+			source.Kind(mgr.GetCache(), &v1beta1.IPReservation{}),
+			&handler.EnqueueRequestForObject{},
+			&predicate.GenerationChangedPredicate{},
+		)
+	}, true)
+}
+
+// ReconcileIPReservation implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileIPReservation{}
+
+// ReconcileIPReservation hold the reserved ips on an eni until the pod of the ordinal use it.
+// The daemon moves the ip to the eni of the node when the pod is created, and gives it back when the pod is deleted,
+// the ip given back is held again by the controller.
+type ReconcileIPReservation struct {
+	client       client.Client
+	aliyunClient register.Interface
+
+	//record event recorder
+	record record.EventRecorder
+}
+
+// NewReconcileIPReservation create the reconciler for IPReservation
+func NewReconcileIPReservation(mgr manager.Manager, aliyunClient register.Interface) *ReconcileIPReservation {
+	return &ReconcileIPReservation{
+		client:       mgr.GetClient(),
+		record:       mgr.GetEventRecorderFor("IPReservation"),
+		aliyunClient: aliyunClient,
+	}
+}
+
+// Reconcile hold the ips reserved and not used by pod
+func (m *ReconcileIPReservation) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	l := log.FromContext(ctx)
+	l.Info("Reconcile")
+
+	old := &v1beta1.IPReservation{}
+	err := m.client.Get(ctx, request.NamespacedName, old)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			l.Info("not found")
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	if !old.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, m.delete(ctx, old)
+	}
+
+	if !controllerutil.ContainsFinalizer(old, types.FinalizerIPReservation) {
+		update := old.DeepCopy()
+		controllerutil.AddFinalizer(update, types.FinalizerIPReservation)
+		err = m.client.Patch(ctx, update, client.MergeFrom(old))
+		return reconcile.Result{Requeue: true}, err
+	}
+
+	update := old.DeepCopy()
+	requeue, err := m.sync(ctx, update)
+	update.Status.UpdateAt = metav1.Now()
+	if err == nil {
+		update.Status.Message = ""
+		// the periodic resync is silent, only the ips assigned, given back or moved are recorded
+		if reservationsChanged(&old.Status, &update.Status) {
+			m.record.Eventf(update, corev1.EventTypeNormal, types.EventSyncIPReservationSucceed, "Reservations updated")
+		}
+	} else {
+		update.Status.Message = err.Error()
+		m.record.Eventf(update, corev1.EventTypeWarning, types.EventSyncIPReservationFailed, "Sync failed %s", err.Error())
+	}
+
+	err2 := m.client.Status().Update(ctx, update)
+	if err2 != nil {
+		return reconcile.Result{}, err2
+	}
+	if requeue {
+		return reconcile.Result{Requeue: true}, nil
+	}
+	return reconcile.Result{RequeueAfter: resyncPeriod}, nil
+}
+
+// sync create the eni holding the ips, and assign the ips not used by any node to it.
+// The eni is persisted in the status before any ip is assigned to it, true is returned to sync again after that.
+func (m *ReconcileIPReservation) sync(ctx context.Context, r *v1beta1.IPReservation) (bool, error) {
+	if r.Status.NetworkInterfaceID == "" {
+		eniID, err := m.holderNetworkInterface(ctx, r)
+		if err != nil {
+			return false, err
+		}
+		r.Status.NetworkInterfaceID = eniID
+		return true, nil
+	}
+	holder := r.Status.NetworkInterfaceID
+
+	// the ips currently assigned to each eni
+	eniIDs := lo.Uniq(append([]string{holder}, lo.FilterMap(r.Status.Reservations, func(item v1beta1.ReservationStatus, _ int) (string, bool) {
+		return item.NetworkInterfaceID, item.NetworkInterfaceID != ""
+	})...))
+	enis, err := m.aliyunClient.DescribeNetworkInterface(ctx, "", eniIDs, "", "", "", nil)
+	if err != nil {
+		return false, err
+	}
+	assigned := make(map[string]map[string]bool)
+	var held []string
+	for _, eni := range enis {
+		assigned[eni.NetworkInterfaceID] = make(map[string]bool)
+		for _, ip := range eni.PrivateIPSets {
+			assigned[eni.NetworkInterfaceID][ip.PrivateIpAddress] = true
+			if eni.NetworkInterfaceID == holder && !ip.Primary {
+				held = append(held, ip.PrivateIpAddress)
+			}
+		}
+	}
+	if _, ok := assigned[holder]; !ok {
+		// the eni is deleted, create it in next reconcile
+		r.Status.NetworkInterfaceID = ""
+		return false, fmt.Errorf("eni %s holding the ips not found", holder)
+	}
+
+	// the ips held but not recorded, they are assigned before the status is persisted
+	known := sets.New[string]()
+	for _, res := range r.Spec.Reservations {
+		known.Insert(res.IPv4)
+	}
+	for _, res := range r.Status.Reservations {
+		known.Insert(res.IPv4)
+	}
+	unrecorded := lo.Filter(held, func(item string, _ int) bool {
+		return !known.Has(item)
+	})
+
+	var errs []error
+	var status []v1beta1.ReservationStatus
+	for _, res := range r.Spec.Reservations {
+		current, ok := lo.Find(r.Status.Reservations, func(item v1beta1.ReservationStatus) bool {
+			return item.Ordinal == res.Ordinal
+		})
+		if ok && res.IPv4 != "" && current.IPv4 != res.IPv4 {
+			// the ip of the ordinal is changed, give back the previous one if it is held
+			if current.NetworkInterfaceID == holder && assigned[holder][current.IPv4] {
+				err = m.unassign(ctx, holder, current.IPv4)
+				if err != nil {
+					errs = append(errs, err)
+					status = append(status, current)
+					continue
+				}
+			}
+			ok = false
+		}
+
+		ip := res.IPv4
+		if ok && current.IPv4 != "" {
+			switch {
+			case assigned[current.NetworkInterfaceID][current.IPv4]:
+				// the ip is used by the node, or already held
+				current.MissingSince = nil
+				status = append(status, current)
+				continue
+			case assigned[holder][current.IPv4]:
+				// the ip is given back by the node
+				status = append(status, v1beta1.ReservationStatus{
+					Ordinal:            res.Ordinal,
+					IPv4:               current.IPv4,
+					NetworkInterfaceID: holder,
+				})
+				continue
+			case current.MissingSince == nil || time.Since(current.MissingSince.Time) < moveTimeout:
+				// the ip may be moving by the daemon, wait for the move to finish
+				if current.MissingSince == nil {
+					now := metav1.Now()
+					current.MissingSince = &now
+				}
+				status = append(status, current)
+				continue
+			}
+			// the ip is released by the node, hold it again
+			ip = current.IPv4
+		}
+
+		if ip != "" && assigned[holder][ip] {
+			// already held
+			status = append(status, v1beta1.ReservationStatus{
+				Ordinal:            res.Ordinal,
+				IPv4:               ip,
+				NetworkInterfaceID: holder,
+			})
+			continue
+		}
+		if ip == "" && len(unrecorded) > 0 {
+			// take the ip assigned in a previous sync
+			status = append(status, v1beta1.ReservationStatus{
+				Ordinal:            res.Ordinal,
+				IPv4:               unrecorded[0],
+				NetworkInterfaceID: holder,
+			})
+			unrecorded = unrecorded[1:]
+			continue
+		}
+
+		ip, err = m.assign(ctx, holder, ip)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error reserve ip for ordinal %d, %w", res.Ordinal, err))
+			if ok {
+				status = append(status, current)
+			}
+			continue
+		}
+		status = append(status, v1beta1.ReservationStatus{
+			Ordinal:            res.Ordinal,
+			IPv4:               ip,
+			NetworkInterfaceID: holder,
+		})
+	}
+
+	// the ordinal no longer reserved
+	for _, current := range r.Status.Reservations {
+		if lo.ContainsBy(r.Spec.Reservations, func(item v1beta1.Reservation) bool {
+			return item.Ordinal == current.Ordinal
+		}) {
+			continue
+		}
+		if current.NetworkInterfaceID != holder && assigned[current.NetworkInterfaceID][current.IPv4] {
+			// wait for the pod release the ip
+			status = append(status, current)
+			continue
+		}
+		if assigned[holder][current.IPv4] {
+			err = m.unassign(ctx, holder, current.IPv4)
+			if err != nil {
+				errs = append(errs, err)
+				status = append(status, current)
+			}
+		}
+	}
+
+	// the ips not taken by any ordinal
+	for _, ip := range unrecorded {
+		err = m.unassign(ctx, holder, ip)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	r.Status.Reservations = status
+
+	if len(errs) > 0 {
+		return false, fmt.Errorf("%v", errs)
+	}
+	return false, nil
+}
+
+// reservationsChanged return true if the holder eni or the ip held for any ordinal is changed
+func reservationsChanged(old, update *v1beta1.IPReservationStatus) bool {
+	if old.NetworkInterfaceID != update.NetworkInterfaceID || len(old.Reservations) != len(update.Reservations) {
+		return true
+	}
+	key := func(item v1beta1.ReservationStatus, _ int) string {
+		return fmt.Sprintf("%d/%s/%s", item.Ordinal, item.IPv4, item.NetworkInterfaceID)
+	}
+	return !sets.New(lo.Map(old.Reservations, key)...).Equal(sets.New(lo.Map(update.Reservations, key)...))
+}
+
+// holderNetworkInterface return the eni holding the ips, the eni is looked up by the tag first,
+// so the one created before the status is persisted is not leaked.
+func (m *ReconcileIPReservation) holderNetworkInterface(ctx context.Context, r *v1beta1.IPReservation) (string, error) {
+	enis, err := m.aliyunClient.DescribeNetworkInterface(ctx, "", nil, "", "", "", map[string]string{
+		types.TagIPReservation: r.Namespace + "/" + r.Name,
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, eni := range enis {
+		if eni.VSwitchID == r.Spec.VSwitchID && eni.InstanceID == "" {
+			log.FromContext(ctx).Info("eni found", "eni", eni.NetworkInterfaceID)
+			return eni.NetworkInterfaceID, nil
+		}
+	}
+	return m.createNetworkInterface(ctx, r)
+}
+
+func (m *ReconcileIPReservation) createNetworkInterface(ctx context.Context, r *v1beta1.IPReservation) (string, error) {
+	deleteENIOnECSRelease := false
+	bo := backoff.Backoff(backoff.ENICreate)
+	eni, err := m.aliyunClient.CreateNetworkInterface(ctx, &aliyunClient.CreateNetworkInterfaceOptions{
+		NetworkInterfaceOptions: &aliyunClient.NetworkInterfaceOptions{
+			VSwitchID:        r.Spec.VSwitchID,
+			SecurityGroupIDs: r.Spec.SecurityGroupIDs,
+			IPCount:          1,
+			Tags: map[string]string{
+				types.TagKeyClusterID:               controlplane.GetConfig().ClusterID,
+				types.NetworkInterfaceTagCreatorKey: types.TagTerwayIPReservation,
+				types.TagIPReservation:              r.Namespace + "/" + r.Name,
+			},
+			DeleteENIOnECSRelease: &deleteENIOnECSRelease,
+		},
+		Backoff: &bo,
+	})
+	if err != nil {
+		return "", fmt.Errorf("create eni with openAPI err, %w", err)
+	}
+	log.FromContext(ctx).Info("eni created", "eni", eni.NetworkInterfaceID)
+	return eni.NetworkInterfaceID, nil
+}
+
+// assign the ip to the eni, a random ip is assigned if empty
+func (m *ReconcileIPReservation) assign(ctx context.Context, eniID, ip string) (string, error) {
+	opts := &aliyunClient.NetworkInterfaceOptions{
+		NetworkInterfaceID: eniID,
+	}
+	if ip == "" {
+		opts.IPCount = 1
+	} else {
+		opts.PrivateIPAddress = []string{ip}
+	}
+	bo := backoff.Backoff(backoff.ENIIPOps)
+	ips, err := m.aliyunClient.AssignPrivateIPAddress(ctx, &aliyunClient.AssignPrivateIPAddressOptions{
+		NetworkInterfaceOptions: opts,
+		Backoff:                 &bo,
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(ips) == 0 {
+		return "", fmt.Errorf("no ip assigned to eni %s", eniID)
+	}
+	return ips[0].String(), nil
+}
+
+func (m *ReconcileIPReservation) unassign(ctx context.Context, eniID, ip string) error {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return err
+	}
+	return m.aliyunClient.UnAssignPrivateIPAddresses(ctx, eniID, []netip.Addr{addr})
+}
+
+// delete the eni holding the ips, the ips used by pods are released by the daemon
+func (m *ReconcileIPReservation) delete(ctx context.Context, r *v1beta1.IPReservation) error {
+	if !controllerutil.ContainsFinalizer(r, types.FinalizerIPReservation) {
+		return nil
+	}
+
+	if r.Status.NetworkInterfaceID != "" {
+		err := m.aliyunClient.DeleteNetworkInterface(ctx, r.Status.NetworkInterfaceID)
+		if err != nil {
+			return fmt.Errorf("error delete eni, %w", err)
+		}
+	}
+
+	update := r.DeepCopy()
+	controllerutil.RemoveFinalizer(update, types.FinalizerIPReservation)
+	err := m.client.Patch(ctx, update, client.MergeFrom(r))
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// NeedLeaderElection need election
+func (m *ReconcileIPReservation) NeedLeaderElection() bool {
+	return true
+}
@@ -0,0 +1,155 @@
+package ipreservation
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	aliyunClient "github.com/AliyunContainerService/terway/pkg/aliyun/client"
+	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/pkg/controller/mocks"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/AliyunContainerService/terway/types/controlplane"
+)
+
+func networkInterface(id string, ips ...string) *aliyunClient.NetworkInterface {
+	ni := &aliyunClient.NetworkInterface{NetworkInterfaceID: id}
+	for i, ip := range ips {
+		ni.PrivateIPSets = append(ni.PrivateIPSets, ecs.PrivateIpSet{PrivateIpAddress: ip, Primary: i == 0})
+	}
+	return ni
+}
+
+func TestReconcileIPReservation_sync(t *testing.T) {
+	controlplane.SetConfig(&controlplane.Config{})
+
+	api := &mocks.Interface{}
+	api.On("DescribeNetworkInterface", mock.Anything, "", []string(nil), "", "", "", map[string]string{types.TagIPReservation: "default/web"}).Return(nil, nil).Once()
+	api.On("CreateNetworkInterface", mock.Anything, mock.Anything).Return(networkInterface("eni-holder", "192.0.2.100"), nil).Once()
+	api.On("DescribeNetworkInterface", mock.Anything, "", []string{"eni-holder"}, "", "", "", map[string]string(nil)).Return([]*aliyunClient.NetworkInterface{
+		networkInterface("eni-holder", "192.0.2.100"),
+	}, nil).Once()
+	api.On("AssignPrivateIPAddress", mock.Anything, mock.MatchedBy(func(opt *aliyunClient.AssignPrivateIPAddressOptions) bool {
+		return len(opt.NetworkInterfaceOptions.PrivateIPAddress) == 1 && opt.NetworkInterfaceOptions.PrivateIPAddress[0] == "192.0.2.10"
+	})).Return([]netip.Addr{netip.MustParseAddr("192.0.2.10")}, nil).Once()
+	api.On("AssignPrivateIPAddress", mock.Anything, mock.MatchedBy(func(opt *aliyunClient.AssignPrivateIPAddressOptions) bool {
+		return opt.NetworkInterfaceOptions.IPCount == 1
+	})).Return([]netip.Addr{netip.MustParseAddr("192.0.2.11")}, nil).Once()
+
+	m := &ReconcileIPReservation{aliyunClient: api}
+	r := &v1beta1.IPReservation{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+		Spec: v1beta1.IPReservationSpec{
+			WorkloadName:     "web",
+			VSwitchID:        "vsw-1",
+			SecurityGroupIDs: []string{"sg-1"},
+			Reservations: []v1beta1.Reservation{
+				{Ordinal: 0, IPv4: "192.0.2.10"},
+				{Ordinal: 1},
+			},
+		},
+	}
+
+	// the eni is persisted before the ips are assigned
+	requeue, err := m.sync(context.Background(), r)
+	assert.NoError(t, err)
+	assert.True(t, requeue)
+	assert.Equal(t, "eni-holder", r.Status.NetworkInterfaceID)
+	assert.Empty(t, r.Status.Reservations)
+
+	requeue, err = m.sync(context.Background(), r)
+	assert.NoError(t, err)
+	assert.False(t, requeue)
+	assert.Equal(t, []v1beta1.ReservationStatus{
+		{Ordinal: 0, IPv4: "192.0.2.10", NetworkInterfaceID: "eni-holder"},
+		{Ordinal: 1, IPv4: "192.0.2.11", NetworkInterfaceID: "eni-holder"},
+	}, r.Status.Reservations)
+	api.AssertExpectations(t)
+}
+
+func TestReconcileIPReservation_syncReleased(t *testing.T) {
+	api := &mocks.Interface{}
+	api.On("DescribeNetworkInterface", mock.Anything, "", []string{"eni-holder", "eni-node-1", "eni-node-2", "eni-node-3", "eni-node-4"}, "", "", "", map[string]string(nil)).Return([]*aliyunClient.NetworkInterface{
+		networkInterface("eni-holder", "192.0.2.100", "192.0.2.12", "192.0.2.14", "192.0.2.15"),
+		networkInterface("eni-node-1", "192.0.2.1", "192.0.2.10"),
+		networkInterface("eni-node-2", "192.0.2.2"),
+		networkInterface("eni-node-3", "192.0.2.3"),
+		networkInterface("eni-node-4", "192.0.2.4"),
+	}, nil).Once()
+	// the ip released by node 2 is held again
+	api.On("AssignPrivateIPAddress", mock.Anything, mock.MatchedBy(func(opt *aliyunClient.AssignPrivateIPAddressOptions) bool {
+		return opt.NetworkInterfaceOptions.NetworkInterfaceID == "eni-holder" && opt.NetworkInterfaceOptions.PrivateIPAddress[0] == "192.0.2.11"
+	})).Return([]netip.Addr{netip.MustParseAddr("192.0.2.11")}, nil).Once()
+	// the ordinal no longer reserved is given back
+	api.On("UnAssignPrivateIPAddresses", mock.Anything, "eni-holder", []netip.Addr{netip.MustParseAddr("192.0.2.12")}).Return(nil).Once()
+	// the ip not recorded is given back
+	api.On("UnAssignPrivateIPAddresses", mock.Anything, "eni-holder", []netip.Addr{netip.MustParseAddr("192.0.2.15")}).Return(nil).Once()
+
+	m := &ReconcileIPReservation{aliyunClient: api}
+	missing := metav1.NewTime(time.Now().Add(-2 * moveTimeout))
+	r := &v1beta1.IPReservation{
+		Spec: v1beta1.IPReservationSpec{
+			Reservations: []v1beta1.Reservation{
+				{Ordinal: 0},
+				{Ordinal: 1},
+				{Ordinal: 3},
+				{Ordinal: 4},
+			},
+		},
+		Status: v1beta1.IPReservationStatus{
+			NetworkInterfaceID: "eni-holder",
+			Reservations: []v1beta1.ReservationStatus{
+				{Ordinal: 0, IPv4: "192.0.2.10", NetworkInterfaceID: "eni-node-1", NodeName: "node-1"},
+				{Ordinal: 1, IPv4: "192.0.2.11", NetworkInterfaceID: "eni-node-2", NodeName: "node-2", MissingSince: &missing},
+				{Ordinal: 2, IPv4: "192.0.2.12", NetworkInterfaceID: "eni-holder"},
+				{Ordinal: 3, IPv4: "192.0.2.13", NetworkInterfaceID: "eni-node-3", NodeName: "node-3"},
+				{Ordinal: 4, IPv4: "192.0.2.14", NetworkInterfaceID: "eni-node-4", NodeName: "node-4"},
+			},
+		},
+	}
+
+	_, err := m.sync(context.Background(), r)
+	assert.NoError(t, err)
+	require.Len(t, r.Status.Reservations, 4)
+	assert.Equal(t, v1beta1.ReservationStatus{Ordinal: 0, IPv4: "192.0.2.10", NetworkInterfaceID: "eni-node-1", NodeName: "node-1"}, r.Status.Reservations[0])
+	assert.Equal(t, v1beta1.ReservationStatus{Ordinal: 1, IPv4: "192.0.2.11", NetworkInterfaceID: "eni-holder"}, r.Status.Reservations[1])
+	// the ip missing is not held until the move is timeout
+	assert.Equal(t, "eni-node-3", r.Status.Reservations[2].NetworkInterfaceID)
+	assert.NotNil(t, r.Status.Reservations[2].MissingSince)
+	// the ip given back to the holder by the node
+	assert.Equal(t, v1beta1.ReservationStatus{Ordinal: 4, IPv4: "192.0.2.14", NetworkInterfaceID: "eni-holder"}, r.Status.Reservations[3])
+	api.AssertExpectations(t)
+}
+
+func TestReservationsChanged(t *testing.T) {
+	old := &v1beta1.IPReservationStatus{
+		NetworkInterfaceID: "eni-holder",
+		Reservations: []v1beta1.ReservationStatus{
+			{Ordinal: 0, IPv4: "192.0.2.10", NetworkInterfaceID: "eni-holder"},
+			{Ordinal: 1, IPv4: "192.0.2.11", NetworkInterfaceID: "eni-node"},
+		},
+	}
+
+	// the resync without change
+	update := old.DeepCopy()
+	now := metav1.Now()
+	update.Reservations[1].MissingSince = &now
+	assert.False(t, reservationsChanged(old, update))
+
+	// the ip is given back
+	update = old.DeepCopy()
+	update.Reservations[1].NetworkInterfaceID = "eni-holder"
+	assert.True(t, reservationsChanged(old, update))
+
+	// the ordinal is no longer reserved
+	update = old.DeepCopy()
+	update.Reservations = update.Reservations[:1]
+	assert.True(t, reservationsChanged(old, update))
+}
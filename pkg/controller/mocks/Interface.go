@@ -97,6 +97,43 @@ func (_m *Interface) AssignPrivateIPAddress(ctx context.Context, opts ...client.
 	return r0, r1
 }
 
+// AssignPrivateIPv4Prefix provides a mock function with given fields: ctx, opts
+func (_m *Interface) AssignPrivateIPv4Prefix(ctx context.Context, opts ...client.AssignPrivateIPAddressOption) ([]netip.Prefix, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AssignPrivateIPv4Prefix")
+	}
+
+	var r0 []netip.Prefix
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, ...client.AssignPrivateIPAddressOption) ([]netip.Prefix, error)); ok {
+		return rf(ctx, opts...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, ...client.AssignPrivateIPAddressOption) []netip.Prefix); ok {
+		r0 = rf(ctx, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]netip.Prefix)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, ...client.AssignPrivateIPAddressOption) error); ok {
+		r1 = rf(ctx, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // AttachNetworkInterface provides a mock function with given fields: ctx, eniID, instanceID, trunkENIID
 func (_m *Interface) AttachNetworkInterface(ctx context.Context, eniID string, instanceID string, trunkENIID string) error {
 	ret := _m.Called(ctx, eniID, instanceID, trunkENIID)
@@ -230,6 +267,36 @@ func (_m *Interface) DescribeNetworkInterface(ctx context.Context, vpcID string,
 	return r0, r1
 }
 
+// DescribeSecurityGroups provides a mock function with given fields: ctx, vpcID, securityGroupIDs
+func (_m *Interface) DescribeSecurityGroups(ctx context.Context, vpcID string, securityGroupIDs []string) ([]ecs.SecurityGroup, error) {
+	ret := _m.Called(ctx, vpcID, securityGroupIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DescribeSecurityGroups")
+	}
+
+	var r0 []ecs.SecurityGroup
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string) ([]ecs.SecurityGroup, error)); ok {
+		return rf(ctx, vpcID, securityGroupIDs)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string) []ecs.SecurityGroup); ok {
+		r0 = rf(ctx, vpcID, securityGroupIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ecs.SecurityGroup)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, []string) error); ok {
+		r1 = rf(ctx, vpcID, securityGroupIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // DescribeVSwitchByID provides a mock function with given fields: ctx, vSwitchID
 func (_m *Interface) DescribeVSwitchByID(ctx context.Context, vSwitchID string) (*vpc.VSwitch, error) {
 	ret := _m.Called(ctx, vSwitchID)
@@ -260,6 +327,36 @@ func (_m *Interface) DescribeVSwitchByID(ctx context.Context, vSwitchID string)
 	return r0, r1
 }
 
+// DescribeVSwitches provides a mock function with given fields: ctx, vpcID, tags
+func (_m *Interface) DescribeVSwitches(ctx context.Context, vpcID string, tags map[string]string) ([]vpc.VSwitch, error) {
+	ret := _m.Called(ctx, vpcID, tags)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DescribeVSwitches")
+	}
+
+	var r0 []vpc.VSwitch
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, map[string]string) ([]vpc.VSwitch, error)); ok {
+		return rf(ctx, vpcID, tags)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, map[string]string) []vpc.VSwitch); ok {
+		r0 = rf(ctx, vpcID, tags)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]vpc.VSwitch)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, map[string]string) error); ok {
+		r1 = rf(ctx, vpcID, tags)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // DetachNetworkInterface provides a mock function with given fields: ctx, eniID, instanceID, trunkENIID
 func (_m *Interface) DetachNetworkInterface(ctx context.Context, eniID string, instanceID string, trunkENIID string) error {
 	ret := _m.Called(ctx, eniID, instanceID, trunkENIID)
@@ -332,6 +429,24 @@ func (_m *Interface) UnAssignPrivateIPAddresses(ctx context.Context, eniID strin
 	return r0
 }
 
+// UnAssignPrivateIPv4Prefix provides a mock function with given fields: ctx, eniID, prefixes
+func (_m *Interface) UnAssignPrivateIPv4Prefix(ctx context.Context, eniID string, prefixes []netip.Prefix) error {
+	ret := _m.Called(ctx, eniID, prefixes)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UnAssignPrivateIPv4Prefix")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []netip.Prefix) error); ok {
+		r0 = rf(ctx, eniID, prefixes)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // WaitForNetworkInterface provides a mock function with given fields: ctx, eniID, status, backoff, ignoreNotExist
 func (_m *Interface) WaitForNetworkInterface(ctx context.Context, eniID string, status string, backoff wait.Backoff, ignoreNotExist bool) (*client.NetworkInterface, error) {
 	ret := _m.Called(ctx, eniID, status, backoff, ignoreNotExist)
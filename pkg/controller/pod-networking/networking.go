@@ -18,6 +18,8 @@ package podnetworking
 
 import (
 	"context"
+	"fmt"
+	"reflect"
 	"time"
 
 	aliyunClient "github.com/AliyunContainerService/terway/pkg/aliyun/client"
@@ -29,6 +31,8 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -42,10 +46,35 @@ import (
 
 const controllerName = "pod-networking"
 
+// podNetworkingIndex index the pods by the podNetworking annotation
+const podNetworkingIndex = "podNetworking"
+
+func podNetworkingIndexFunc(o client.Object) []string {
+	name := o.GetAnnotations()[types.PodNetworking]
+	if name == "" {
+		return nil
+	}
+	return []string{name}
+}
+
 func init() {
 	register.Add(controllerName, func(mgr manager.Manager, ctrlCtx *register.ControllerCtx) error {
+		syncPeriod, err := time.ParseDuration(ctrlCtx.Config.PodNetworkingSyncPeriod)
+		if err != nil {
+			return fmt.Errorf("error parse podNetworkingSyncPeriod, %w", err)
+		}
+		failureWindow, err := time.ParseDuration(ctrlCtx.Config.AllocationFailureWindow)
+		if err != nil {
+			return fmt.Errorf("error parse allocationFailureWindow, %w", err)
+		}
+
+		err = mgr.GetFieldIndexer().IndexField(context.Background(), &corev1.Pod{}, podNetworkingIndex, podNetworkingIndexFunc)
+		if err != nil {
+			return err
+		}
+
 		c, err := controller.New(controllerName, mgr, controller.Options{
-			Reconciler:              NewReconcilePodNetworking(mgr, ctrlCtx.AliyunClient, ctrlCtx.VSwitchPool),
+			Reconciler:              NewReconcilePodNetworking(mgr, ctrlCtx.AliyunClient, ctrlCtx.VSwitchPool, syncPeriod, failureWindow),
 			MaxConcurrentReconciles: 1,
 		})
 		if err != nil {
@@ -70,25 +99,32 @@ type ReconcilePodNetworking struct {
 	aliyunClient aliyunClient.VPC
 	swPool       *vswitch.SwitchPool
 
+	// syncPeriod the period the capacity in status is refreshed, zero for not refreshed
+	syncPeriod time.Duration
+	// failureWindow the allocation failures in the window are counted
+	failureWindow time.Duration
+
 	//record event recorder
 	record record.EventRecorder
 }
 
 // NewReconcilePodNetworking watch pod lifecycle events and sync to podENI resource
-func NewReconcilePodNetworking(mgr manager.Manager, aliyunClient aliyunClient.VPC, swPool *vswitch.SwitchPool) *ReconcilePodNetworking {
+func NewReconcilePodNetworking(mgr manager.Manager, aliyunClient aliyunClient.VPC, swPool *vswitch.SwitchPool, syncPeriod, failureWindow time.Duration) *ReconcilePodNetworking {
 	r := &ReconcilePodNetworking{
-		client:       mgr.GetClient(),
-		record:       mgr.GetEventRecorderFor("PodNetworking"),
-		aliyunClient: aliyunClient,
-		swPool:       swPool,
+		client:        mgr.GetClient(),
+		record:        mgr.GetEventRecorderFor("PodNetworking"),
+		aliyunClient:  aliyunClient,
+		swPool:        swPool,
+		syncPeriod:    syncPeriod,
+		failureWindow: failureWindow,
 	}
 	return r
 }
 
-// Reconcile podNetworking when user create or vSwitch fields changed
+// Reconcile podNetworking when user create or vSwitch fields changed, the capacity in status is refreshed periodically
 func (m *ReconcilePodNetworking) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
 	l := log.FromContext(ctx)
-	l.Info("Reconcile")
+	l.V(5).Info("Reconcile")
 
 	old := &v1beta1.PodNetworking{}
 	err := m.client.Get(ctx, request.NamespacedName, old)
@@ -100,43 +136,115 @@ func (m *ReconcilePodNetworking) Reconcile(ctx context.Context, request reconcil
 		return reconcile.Result{}, err
 	}
 
-	if !changed(old) && old.Status.Status == v1beta1.NetworkingStatusReady {
-		return reconcile.Result{}, nil
-	}
-
 	update := old.DeepCopy()
-	update.Status.UpdateAt = metav1.Now()
-
-	var statusVSW []v1beta1.VSwitch
-	err = func() error {
-		for _, id := range old.Spec.VSwitchOptions {
-			sw, innerErr := m.swPool.GetByID(ctx, m.aliyunClient, id)
-			if innerErr != nil {
-				return innerErr
+
+	if changed(old) || old.Status.Status != v1beta1.NetworkingStatusReady {
+		var statusVSW []v1beta1.VSwitch
+		err = func() error {
+			for _, id := range old.Spec.VSwitchOptions {
+				sw, innerErr := m.swPool.GetByID(ctx, m.aliyunClient, id)
+				if innerErr != nil {
+					return innerErr
+				}
+				statusVSW = append(statusVSW, v1beta1.VSwitch{
+					ID:   sw.ID,
+					Zone: sw.Zone,
+				})
 			}
-			statusVSW = append(statusVSW, v1beta1.VSwitch{
-				ID:   sw.ID,
-				Zone: sw.Zone,
-			})
+			return nil
+		}()
+		if err != nil {
+			update.Status.UpdateAt = metav1.Now()
+			update.Status.Status = v1beta1.NetworkingStatusFail
+			update.Status.Message = err.Error()
+			m.record.Eventf(update, corev1.EventTypeWarning, types.EventSyncPodNetworkingFailed, "Sync failed %s", err.Error())
+
+			err2 := m.client.Status().Update(ctx, update)
+			if err2 != nil {
+				l.Error(err2, "error update status")
+			}
+			return reconcile.Result{RequeueAfter: 30 * time.Second}, nil
 		}
-		return nil
-	}()
-	if err == nil {
+
 		update.Status.VSwitches = statusVSW
 		update.Status.Status = v1beta1.NetworkingStatusReady
 		update.Status.Message = ""
 		m.record.Eventf(update, corev1.EventTypeNormal, types.EventSyncPodNetworkingSucceed, "Synced")
-	} else {
-		update.Status.Status = v1beta1.NetworkingStatusFail
-		update.Status.Message = err.Error()
-		m.record.Eventf(update, corev1.EventTypeWarning, types.EventSyncPodNetworkingFailed, "Sync failed %s", err.Error())
 	}
 
-	err2 := m.client.Status().Update(ctx, update)
+	if m.syncPeriod > 0 {
+		err = m.refresh(ctx, update)
+		if err != nil {
+			l.Error(err, "error refresh capacity")
+		}
+	}
+
+	if !reflect.DeepEqual(old.Status, update.Status) {
+		update.Status.UpdateAt = metav1.Now()
+		err = m.client.Status().Update(ctx, update)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	return reconcile.Result{RequeueAfter: m.syncPeriod}, nil
+}
+
+// refresh the available ip, pods and allocation failures of each vSwitch in status
+func (m *ReconcilePodNetworking) refresh(ctx context.Context, pn *v1beta1.PodNetworking) error {
+	pods, err := m.podsByVSwitch(ctx, pn.Name)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	pn.Status.AvailableIPCount, pn.Status.Pods, pn.Status.AllocationFailures = 0, 0, 0
+	for i := range pn.Status.VSwitches {
+		vsw := &pn.Status.VSwitches[i]
+
+		// shared with the forecast controller, so the vSwitch is described once in the period
+		resp, err := m.swPool.Capacity(ctx, m.aliyunClient, vsw.ID, m.syncPeriod)
+		if err != nil {
+			// keep the last seen
+			errs = append(errs, err)
+		} else {
+			vsw.AvailableIPCount = resp.AvailableIPCount
+		}
+		vsw.Pods = pods[vsw.ID]
+		vsw.AllocationFailures = m.swPool.Failures(vsw.ID, m.failureWindow)
+
+		pn.Status.AvailableIPCount += vsw.AvailableIPCount
+		pn.Status.Pods += vsw.Pods
+		pn.Status.AllocationFailures += vsw.AllocationFailures
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// podsByVSwitch count the pods using the podNetworking by the vSwitch of the eni
+func (m *ReconcilePodNetworking) podsByVSwitch(ctx context.Context, name string) (map[string]int, error) {
+	pods := &corev1.PodList{}
+	err := m.client.List(ctx, pods, client.MatchingFields{podNetworkingIndex: name})
 	if err != nil {
-		return reconcile.Result{RequeueAfter: 30 * time.Second}, nil
+		return nil, fmt.Errorf("error list pods, %w", err)
+	}
+
+	r := make(map[string]int)
+	for _, pod := range pods.Items {
+		podENI := &v1beta1.PodENI{}
+		err = m.client.Get(ctx, k8stypes.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, podENI)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("error get podENI, %w", err)
+		}
+		if podENI.DeletionTimestamp != nil {
+			continue
+		}
+		for _, alloc := range podENI.Spec.Allocations {
+			r[alloc.ENI.VSwitchID]++
+		}
 	}
-	return reconcile.Result{}, err2
+	return r, nil
 }
 
 // NeedLeaderElection need election
@@ -0,0 +1,117 @@
+package podnetworking
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/vpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	networkv1beta1 "github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/pkg/controller/mocks"
+	vswpool "github.com/AliyunContainerService/terway/pkg/vswitch"
+	terwayTypes "github.com/AliyunContainerService/terway/types"
+)
+
+func podENI(name, vsw string) *networkv1beta1.PodENI {
+	return &networkv1beta1.PodENI{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Spec: networkv1beta1.PodENISpec{
+			Allocations: []networkv1beta1.Allocation{{ENI: networkv1beta1.ENI{VSwitchID: vsw}}},
+		},
+	}
+}
+
+func pod(name, podNetworking string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        name,
+			Annotations: map[string]string{terwayTypes.PodNetworking: podNetworking},
+		},
+	}
+}
+
+func TestReconcilePodNetworking_refresh(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, networkv1beta1.AddToScheme(scheme))
+
+	pn := &networkv1beta1.PodNetworking{
+		ObjectMeta: metav1.ObjectMeta{Name: "pn"},
+		Spec:       networkv1beta1.PodNetworkingSpec{VSwitchOptions: []string{"vsw-1", "vsw-2"}},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(pn).WithIndex(&corev1.Pod{}, podNetworkingIndex, podNetworkingIndexFunc).WithObjects(pn,
+		podENI("pod-1", "vsw-1"), pod("pod-1", "pn"),
+		podENI("pod-2", "vsw-1"), pod("pod-2", "pn"),
+		podENI("pod-3", "vsw-2"), pod("pod-3", "pn"),
+		// pod of other podNetworking
+		podENI("pod-4", "vsw-2"), pod("pod-4", "other"),
+		// the pod is gone
+		podENI("pod-5", "vsw-2"),
+		// the eni is not created yet
+		pod("pod-6", "pn"),
+	).Build()
+
+	available := map[string]int64{"vsw-1": 100, "vsw-2": 50}
+	api := &mocks.Interface{}
+	api.On("DescribeVSwitchByID", mock.Anything, mock.Anything).Return(func(_ context.Context, id string) (*vpc.VSwitch, error) {
+		return &vpc.VSwitch{VSwitchId: id, ZoneId: "zone-a", AvailableIpAddressCount: available[id]}, nil
+	})
+
+	switchPool, err := vswpool.NewSwitchPool(100, "10m")
+	require.NoError(t, err)
+	switchPool.RecordFailure("vsw-2")
+
+	r := &ReconcilePodNetworking{
+		client:        c,
+		aliyunClient:  api,
+		swPool:        switchPool,
+		syncPeriod:    time.Minute,
+		failureWindow: 10 * time.Minute,
+		record:        record.NewFakeRecorder(10),
+	}
+	ctx := context.Background()
+	result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "pn"}})
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, result.RequeueAfter)
+
+	got := &networkv1beta1.PodNetworking{}
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Name: "pn"}, got))
+	assert.Equal(t, networkv1beta1.NetworkingStatusReady, got.Status.Status)
+	assert.Equal(t, []networkv1beta1.VSwitch{
+		{ID: "vsw-1", Zone: "zone-a", AvailableIPCount: 100, Pods: 2},
+		{ID: "vsw-2", Zone: "zone-a", AvailableIPCount: 50, Pods: 1, AllocationFailures: 1},
+	}, got.Status.VSwitches)
+	assert.Equal(t, int64(150), got.Status.AvailableIPCount)
+	assert.Equal(t, 3, got.Status.Pods)
+	assert.Equal(t, 1, got.Status.AllocationFailures)
+
+	// described within the sync period is reused
+	available["vsw-1"] = 90
+	_, err = r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "pn"}})
+	require.NoError(t, err)
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Name: "pn"}, got))
+	assert.Equal(t, int64(100), got.Status.VSwitches[0].AvailableIPCount)
+
+	// refreshed on the next sync
+	switchPool.Del("vsw-1")
+	_, err = r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "pn"}})
+	require.NoError(t, err)
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Name: "pn"}, got))
+	assert.Equal(t, int64(90), got.Status.VSwitches[0].AvailableIPCount)
+	assert.Equal(t, int64(140), got.Status.AvailableIPCount)
+	// synced event is only sent once
+	assert.Len(t, r.record.(*record.FakeRecorder).Events, 1)
+}
@@ -19,6 +19,7 @@ package pod
 import (
 	"context"
 	"fmt"
+	"net/netip"
 	"strings"
 	"time"
 
@@ -55,10 +56,26 @@ import (
 const controllerName = "pod"
 const defaultInterface = "eth0"
 
+// podENIInstanceIndex index the podENIs by the instance the eni attached to
+const podENIInstanceIndex = "status.instanceID"
+
+func podENIInstanceIndexFunc(o client.Object) []string {
+	podENI, ok := o.(*v1beta1.PodENI)
+	if !ok || podENI.Status.InstanceID == "" {
+		return nil
+	}
+	return []string{podENI.Status.InstanceID}
+}
+
 func init() {
 	register.Add(controllerName, func(mgr manager.Manager, ctrlCtx *register.ControllerCtx) error {
 		crdMode := controlplane.GetConfig().IPAMType == types.IPAMTypeCRD
 
+		err := mgr.GetFieldIndexer().IndexField(context.Background(), &v1beta1.PodENI{}, podENIInstanceIndex, podENIInstanceIndexFunc)
+		if err != nil {
+			return err
+		}
+
 		c, err := controller.NewUnmanaged(controllerName, mgr, controller.Options{
 			Reconciler:              NewReconcilePod(mgr, ctrlCtx.AliyunClient, ctrlCtx.VSwitchPool, crdMode),
 			MaxConcurrentReconciles: controlplane.GetConfig().PodMaxConcurrent,
@@ -210,6 +227,13 @@ func (m *ReconcilePod) podCreate(ctx context.Context, pod *corev1.Pod) (reconcil
 		}
 		switch prePodENI.Status.Phase {
 		case v1beta1.ENIPhaseUnbind:
+			migrate, err := needZoneMigration(pod, node, prePodENI)
+			if err != nil {
+				return reconcile.Result{}, err
+			}
+			if migrate {
+				return m.migrateZone(ctx, pod, node, prePodENI)
+			}
 			return m.reConfig(ctx, pod, prePodENI)
 		case v1beta1.ENIPhaseBind:
 			// check pod uid
@@ -435,10 +459,15 @@ func (m *ReconcilePod) parse(ctx context.Context, pod *corev1.Pod, node *corev1.
 			return nil, nil, nil, fmt.Errorf("error get podNetworking %s, %w", podNetwokingName, err)
 		}
 		var vsw *vswitch.Switch
-		vsw, err = m.swPool.GetOne(ctx, m.aliyun, nodeInfo.ZoneID, podNetworking.Spec.VSwitchOptions)
+		selectOptions, err := m.vSwitchSelectOptions(ctx, nodeInfo, &podNetworking.Spec.VSwitchSelectOptions)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		vsw, err = m.swPool.GetOne(ctx, m.aliyun, nodeInfo.ZoneID, podNetworking.Spec.VSwitchOptions, selectOptions)
 		if err != nil {
 			return nil, nil, nil, fmt.Errorf("can not found available vSwitch for zone %s, %w", nodeInfo.ZoneID, err)
 		}
+		log.FromContext(ctx).V(4).Info("vSwitch selected", "vSwitch", vsw.ID, "reason", vsw.Reason)
 
 		allocs = append(allocs, &v1beta1.Allocation{
 			ENI: v1beta1.ENI{
@@ -467,6 +496,44 @@ func (m *ReconcilePod) parse(ctx context.Context, pod *corev1.Pod, node *corev1.
 	return nodeInfo, allocType, allocs, nil
 }
 
+// vSwitchSelectOptions convert the select options of the podNetworking,
+// the eni count of each vSwitch on the node is counted from the podENIs for the least-used-by-node policy
+func (m *ReconcilePod) vSwitchSelectOptions(ctx context.Context, nodeInfo *common.NodeInfo, opts *v1beta1.VSwitchSelectOptions) (*vswitch.SelectOptions, error) {
+	r := &vswitch.SelectOptions{
+		Weights: opts.VSwitchWeights,
+	}
+	if opts.VSwitchSelectionPolicy != "" {
+		policy, ok := vswitch.ParseSelectionPolicy(opts.VSwitchSelectionPolicy)
+		if !ok {
+			return nil, fmt.Errorf("unknown vSwitchSelectionPolicy %s", opts.VSwitchSelectionPolicy)
+		}
+		r.VSwitchSelectPolicy = policy
+	}
+	for _, cidr := range opts.PreferCIDRs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("error parse preferCIDRs %s, %w", cidr, err)
+		}
+		r.PreferCIDRs = append(r.PreferCIDRs, prefix.Masked())
+	}
+	if r.VSwitchSelectPolicy != vswitch.VSwitchSelectionPolicyLeastUsedByNode {
+		return r, nil
+	}
+
+	podENIs := &v1beta1.PodENIList{}
+	err := m.client.List(ctx, podENIs, client.MatchingFields{podENIInstanceIndex: nodeInfo.InstanceID})
+	if err != nil {
+		return nil, fmt.Errorf("error list podENIs, %w", err)
+	}
+	r.NodeUsage = map[string]int{}
+	for _, podENI := range podENIs.Items {
+		for _, alloc := range podENI.Spec.Allocations {
+			r.NodeUsage[alloc.ENI.VSwitchID]++
+		}
+	}
+	return r, nil
+}
+
 // reConfig this phase will re-config the eni if possible
 // 1. update pod uid
 // 2. re-generate the target spec
@@ -654,6 +721,7 @@ func (m *ReconcilePod) createENI(ctx context.Context, allocs *[]*v1beta1.Allocat
 			}
 			eni, err := m.aliyun.CreateNetworkInterface(ctx, option)
 			if err != nil {
+				m.swPool.RecordFailure(alloc.ENI.VSwitchID)
 				return fmt.Errorf("create eni with openAPI err, %w", err)
 			}
 
@@ -0,0 +1,51 @@
+//go:build default_build
+
+package pod
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/pkg/controller/common"
+	"github.com/AliyunContainerService/terway/pkg/vswitch"
+)
+
+func TestReconcilePod_vSwitchSelectOptionsLeastUsedByNode(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1beta1.AddToScheme(scheme))
+
+	newPodENI := func(name, instanceID, vsw string) *v1beta1.PodENI {
+		return &v1beta1.PodENI{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec: v1beta1.PodENISpec{
+				Allocations: []v1beta1.Allocation{{ENI: v1beta1.ENI{ID: "eni-" + name, VSwitchID: vsw}}},
+			},
+			Status: v1beta1.PodENIStatus{InstanceID: instanceID},
+		}
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(
+			newPodENI("pod-1", "i-a", "vsw-1"),
+			newPodENI("pod-2", "i-a", "vsw-1"),
+			newPodENI("pod-3", "i-a", "vsw-2"),
+			newPodENI("pod-4", "i-b", "vsw-2"),
+		).
+		WithIndex(&v1beta1.PodENI{}, podENIInstanceIndex, podENIInstanceIndexFunc).
+		Build()
+
+	m := &ReconcilePod{client: c}
+	opts, err := m.vSwitchSelectOptions(context.Background(), &common.NodeInfo{InstanceID: "i-a"}, &v1beta1.VSwitchSelectOptions{
+		VSwitchSelectionPolicy: string(vswitch.VSwitchSelectionPolicyLeastUsedByNode),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"vsw-1": 2, "vsw-2": 1}, opts.NodeUsage)
+}
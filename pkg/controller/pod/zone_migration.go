@@ -0,0 +1,153 @@
+package pod
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/pkg/controller/common"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/AliyunContainerService/terway/types/controlplane"
+)
+
+// maxZoneMigrations is the count of migrations kept in the podENI status
+const maxZoneMigrations = 5
+
+// needZoneMigration return true if the fixed ip pod is scheduled to another zone and the migration is allowed
+func needZoneMigration(pod *corev1.Pod, node *corev1.Node, podENI *v1beta1.PodENI) (bool, error) {
+	if !podENI.Spec.HaveFixedIP() || podENI.Spec.Zone == "" {
+		return false, nil
+	}
+	nodeInfo, err := common.NewNodeInfo(node)
+	if err != nil {
+		return false, err
+	}
+	if nodeInfo.ZoneID == podENI.Spec.Zone {
+		return false, nil
+	}
+	allocType, err := controlplane.ParsePodIPTypeFromAnnotation(pod)
+	if err != nil {
+		return false, err
+	}
+	return allocType.ZoneMigration == v1beta1.ZoneMigrationAllowed, nil
+}
+
+// migrateZone move the fixed ip pod to the zone of the node.
+// The enis are recreated from the vSwitch of the new zone, and the previous enis are released after the podENI is updated.
+// The podENI is kept, so the name of the pod and the identity in the dns and service is not changed.
+func (m *ReconcilePod) migrateZone(ctx context.Context, pod *corev1.Pod, node *corev1.Node, prePodENI *v1beta1.PodENI) (result reconcile.Result, err error) {
+	l := log.FromContext(ctx).WithName("zone-migration")
+
+	defer func() {
+		if err != nil {
+			m.record.Eventf(prePodENI, corev1.EventTypeWarning, types.EventZoneMigrationFailed, err.Error())
+		}
+	}()
+
+	nodeInfo, allocType, allocs, err := m.parse(ctx, pod, node)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("error parse config, %w", err)
+	}
+
+	update := prePodENI.DeepCopy()
+	update.Spec.Allocations = nil
+	update.Spec.Zone = nodeInfo.ZoneID
+	if update.Labels == nil {
+		update.Labels = make(map[string]string)
+	}
+	update.Labels[types.ENIRelatedNodeName] = nodeInfo.NodeName
+	if update.Annotations == nil {
+		update.Annotations = make(map[string]string)
+	}
+	update.Annotations[types.PodUID] = string(pod.UID)
+
+	l.Info("migrating", "from", prePodENI.Spec.Zone, "to", nodeInfo.ZoneID)
+
+	err = m.createENI(ctx, &allocs, allocType, pod, update)
+	if err != nil {
+		innerErr := m.deleteAllENI(ctx, update)
+		if innerErr != nil {
+			l.Error(innerErr, "error delete eni")
+		}
+		return reconcile.Result{}, err
+	}
+
+	status := update.Status.DeepCopy()
+	_, err = common.UpdatePodENI(ctx, m.client, update)
+	if err != nil {
+		innerErr := m.deleteAllENI(ctx, update)
+		if innerErr != nil {
+			l.Error(innerErr, "error delete eni")
+		}
+		return reconcile.Result{}, err
+	}
+
+	// the eni in the previous zone is detached, the leaked one is collected by the podENI gc
+	for _, alloc := range prePodENI.Spec.Allocations {
+		if alloc.ENI.ID == "" {
+			continue
+		}
+		innerErr := m.aliyun.DeleteNetworkInterface(common.WithCtx(ctx, &alloc), alloc.ENI.ID)
+		if innerErr != nil {
+			l.Error(innerErr, "error delete eni", "eni", alloc.ENI.ID)
+			m.record.Eventf(update, corev1.EventTypeWarning, types.EventDeleteENIFailed, "delete eni %s, %s", alloc.ENI.ID, innerErr.Error())
+		}
+	}
+
+	migration := newZoneMigration(prePodENI, update, time.Now())
+	update.Status = *status
+	update.Status.Phase = v1beta1.ENIPhaseUnbind
+	update.Status.InstanceID = ""
+	update.Status.TrunkENIID = ""
+	update.Status.ENIInfos = nil
+	update.Status.Migrations = append(update.Status.Migrations, migration)
+	if len(update.Status.Migrations) > maxZoneMigrations {
+		update.Status.Migrations = update.Status.Migrations[len(update.Status.Migrations)-maxZoneMigrations:]
+	}
+	_, err = common.UpdatePodENIStatus(ctx, m.client, update)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	m.record.Eventf(update, corev1.EventTypeNormal, types.EventZoneMigrationSucceed, "migrated from zone %s to %s, ip %s -> %s",
+		migration.From, migration.To, migration.PreviousIPv4, migration.IPv4)
+	// bind the new eni in next reconcile
+	return reconcile.Result{Requeue: true}, nil
+}
+
+func newZoneMigration(prev, cur *v1beta1.PodENI, now time.Time) v1beta1.ZoneMigration {
+	migration := v1beta1.ZoneMigration{
+		From:       prev.Spec.Zone,
+		To:         cur.Spec.Zone,
+		MigratedAt: metav1.NewTime(now),
+	}
+	if alloc := primaryAllocation(prev); alloc != nil {
+		migration.PreviousIPv4 = alloc.IPv4
+		migration.PreviousIPv6 = alloc.IPv6
+	}
+	if alloc := primaryAllocation(cur); alloc != nil {
+		migration.IPv4 = alloc.IPv4
+		migration.IPv6 = alloc.IPv6
+	}
+	return migration
+}
+
+// primaryAllocation return the allocation of the default interface
+func primaryAllocation(podENI *v1beta1.PodENI) *v1beta1.Allocation {
+	for i := range podENI.Spec.Allocations {
+		alloc := &podENI.Spec.Allocations[i]
+		if alloc.Interface == "" || alloc.Interface == defaultInterface {
+			return alloc
+		}
+	}
+	if len(podENI.Spec.Allocations) > 0 {
+		return &podENI.Spec.Allocations[0]
+	}
+	return nil
+}
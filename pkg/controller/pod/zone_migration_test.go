@@ -0,0 +1,139 @@
+//go:build default_build
+
+package pod
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/vpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	aliyunClient "github.com/AliyunContainerService/terway/pkg/aliyun/client"
+	"github.com/AliyunContainerService/terway/pkg/aliyun/client/emulator"
+	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/pkg/vswitch"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/AliyunContainerService/terway/types/controlplane"
+)
+
+func TestReconcilePod_migrateZone(t *testing.T) {
+	controlplane.SetConfig(&controlplane.Config{ClusterID: "c1"})
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1beta1.AddToScheme(scheme))
+
+	e := emulator.New(emulator.Config{})
+	require.NoError(t, e.AddVSwitch(vpc.VSwitch{VSwitchId: "vsw-a", ZoneId: "zone-a", CidrBlock: "192.168.0.0/24"}))
+	require.NoError(t, e.AddVSwitch(vpc.VSwitch{VSwitchId: "vsw-b", ZoneId: "zone-b", CidrBlock: "192.168.1.0/24"}))
+	ctx := context.Background()
+	prevENI, err := e.CreateNetworkInterface(ctx, &aliyunClient.CreateNetworkInterfaceOptions{
+		NetworkInterfaceOptions: &aliyunClient.NetworkInterfaceOptions{VSwitchID: "vsw-a", SecurityGroupIDs: []string{"sg-1"}, IPCount: 1},
+	})
+	require.NoError(t, err)
+
+	allocType := `{"type":"Fixed","releaseStrategy":"Never","zoneMigration":"Allowed"}`
+	pn := &v1beta1.PodNetworking{
+		ObjectMeta: metav1.ObjectMeta{Name: "fixed"},
+		Spec: v1beta1.PodNetworkingSpec{
+			AllocationType:   v1beta1.AllocationType{Type: v1beta1.IPAllocTypeFixed, ReleaseStrategy: v1beta1.ReleaseStrategyNever, ZoneMigration: v1beta1.ZoneMigrationAllowed},
+			VSwitchOptions:   []string{"vsw-a", "vsw-b"},
+			SecurityGroupIDs: []string{"sg-1"},
+		},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "web-0",
+			UID:       "uid-2",
+			Annotations: map[string]string{
+				types.PodENI:        "true",
+				types.PodNetworking: "fixed",
+				types.PodAllocType:  allocType,
+			},
+		},
+		Spec: corev1.PodSpec{NodeName: "node-b"},
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-b", Labels: map[string]string{corev1.LabelTopologyZone: "zone-b"}},
+		Spec:       corev1.NodeSpec{ProviderID: "cn-hangzhou.i-b"},
+	}
+	podENI := &v1beta1.PodENI{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "web-0",
+			Labels:      map[string]string{types.ENIRelatedNodeName: "node-a"},
+			Annotations: map[string]string{types.PodUID: "uid-1"},
+		},
+		Spec: v1beta1.PodENISpec{
+			Zone: "zone-a",
+			Allocations: []v1beta1.Allocation{{
+				AllocationType: v1beta1.AllocationType{Type: v1beta1.IPAllocTypeFixed, ReleaseStrategy: v1beta1.ReleaseStrategyNever},
+				ENI:            v1beta1.ENI{ID: prevENI.NetworkInterfaceID, VSwitchID: "vsw-a", Zone: "zone-a"},
+				IPv4:           prevENI.PrivateIPAddress,
+			}},
+		},
+		Status: v1beta1.PodENIStatus{
+			Phase:      v1beta1.ENIPhaseUnbind,
+			InstanceID: "i-a",
+			ENIInfos:   map[string]v1beta1.ENIInfo{prevENI.NetworkInterfaceID: {ID: prevENI.NetworkInterfaceID, Status: v1beta1.ENIStatusUnBind}},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pn, pod, node, podENI).WithStatusSubresource(podENI).Build()
+
+	swPool, err := vswitch.NewSwitchPool(100, "10m")
+	require.NoError(t, err)
+	recorder := record.NewFakeRecorder(10)
+	m := &ReconcilePod{client: c, scheme: scheme, aliyun: e, swPool: swPool, record: recorder, crdMode: true}
+
+	migrate, err := needZoneMigration(pod, node, podENI)
+	require.NoError(t, err)
+	assert.True(t, migrate)
+
+	_, err = m.podCreate(ctx, pod)
+	require.NoError(t, err)
+
+	got := &v1beta1.PodENI{}
+	require.NoError(t, c.Get(ctx, k8stypes.NamespacedName{Namespace: "default", Name: "web-0"}, got))
+	assert.Equal(t, "zone-b", got.Spec.Zone)
+	assert.Equal(t, "node-b", got.Labels[types.ENIRelatedNodeName])
+	assert.Equal(t, "uid-2", got.Annotations[types.PodUID])
+	require.Len(t, got.Spec.Allocations, 1)
+	assert.Equal(t, "vsw-b", got.Spec.Allocations[0].ENI.VSwitchID)
+	assert.Equal(t, v1beta1.ZoneMigrationAllowed, string(got.Spec.Allocations[0].AllocationType.ZoneMigration))
+
+	assert.Equal(t, v1beta1.Phase(v1beta1.ENIPhaseUnbind), got.Status.Phase)
+	assert.Empty(t, got.Status.ENIInfos)
+	require.Len(t, got.Status.Migrations, 1)
+	assert.Equal(t, "zone-a", got.Status.Migrations[0].From)
+	assert.Equal(t, "zone-b", got.Status.Migrations[0].To)
+	assert.Equal(t, prevENI.PrivateIPAddress, got.Status.Migrations[0].PreviousIPv4)
+	assert.Equal(t, got.Spec.Allocations[0].IPv4, got.Status.Migrations[0].IPv4)
+
+	// the eni in the previous zone is released
+	_, ok := e.ENI(prevENI.NetworkInterfaceID)
+	assert.False(t, ok)
+
+	// create and migrated
+	assert.Len(t, recorder.Events, 2)
+
+	// not migrated again
+	migrate, err = needZoneMigration(pod, node, got)
+	require.NoError(t, err)
+	assert.False(t, migrate)
+
+	// the policy is not set
+	pod.Annotations[types.PodAllocType] = `{"type":"Fixed","releaseStrategy":"Never"}`
+	migrate, err = needZoneMigration(pod, node, podENI)
+	require.NoError(t, err)
+	assert.False(t, migrate)
+}
@@ -0,0 +1,482 @@
+/*
+Copyright 2024 Terway Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vswitchforecast
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8sErr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	register "github.com/AliyunContainerService/terway/pkg/controller"
+	"github.com/AliyunContainerService/terway/pkg/metric"
+	"github.com/AliyunContainerService/terway/pkg/vswitch"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/AliyunContainerService/terway/types/controlplane"
+)
+
+var log = ctrl.Log.WithName(controllerName)
+
+const controllerName = "vswitch-forecast"
+
+const (
+	eniConfigNamespace = "kube-system"
+	eniConfigName      = "eni-config"
+	eniConfigKey       = "eni_conf"
+)
+
+// condition reasons
+const (
+	ReasonProjectedExhaustion = "ProjectedExhaustion"
+	ReasonCapacitySufficient  = "CapacitySufficient"
+)
+
+func init() {
+	register.Add(controllerName, func(mgr manager.Manager, ctrlCtx *register.ControllerCtx) error {
+		r, err := New(mgr.GetClient(), mgr.GetEventRecorderFor("TerwayVSwitchForecast"), ctrlCtx.AliyunClient, ctrlCtx.VSwitchPool, ctrlCtx.Config)
+		if err != nil {
+			return err
+		}
+		return mgr.Add(r)
+	}, true)
+}
+
+var _ manager.Runnable = &Forecast{}
+
+// Forecast track the available ip of the vSwitches in use, mark the PodNetworking with the exhausting vSwitch
+// and append the spare vSwitch in the same zone if auto expand is enabled
+type Forecast struct {
+	client     client.Client
+	record     record.EventRecorder
+	vpc        register.Interface
+	swPool     *vswitch.SwitchPool
+	forecaster *vswitch.Forecaster
+
+	interval    time.Duration
+	threshold   time.Duration
+	vpcID       string
+	reserveTags map[string]string
+	autoExpand  bool
+
+	// notified the exhausting vSwitches of the eni-config already noticed
+	notified sets.Set[string]
+	// observed the zone of the vSwitches tracked in the last sync, the metrics are dropped once not in use
+	observed map[string]string
+
+	now func() time.Time
+}
+
+// New create the forecast runnable
+func New(c client.Client, recorder record.EventRecorder, vpc register.Interface, swPool *vswitch.SwitchPool, cfg *controlplane.Config) (*Forecast, error) {
+	interval, err := time.ParseDuration(cfg.VSwitchForecastInterval)
+	if err != nil {
+		return nil, fmt.Errorf("error parse vSwitchForecastInterval, %w", err)
+	}
+	window, err := time.ParseDuration(cfg.VSwitchForecastWindow)
+	if err != nil {
+		return nil, fmt.Errorf("error parse vSwitchForecastWindow, %w", err)
+	}
+	threshold, err := time.ParseDuration(cfg.VSwitchExhaustThreshold)
+	if err != nil {
+		return nil, fmt.Errorf("error parse vSwitchExhaustThreshold, %w", err)
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("vSwitchForecastInterval must be positive")
+	}
+
+	return &Forecast{
+		client:      c,
+		record:      recorder,
+		vpc:         vpc,
+		swPool:      swPool,
+		forecaster:  vswitch.NewForecaster(window),
+		interval:    interval,
+		threshold:   threshold,
+		vpcID:       cfg.VPCID,
+		reserveTags: cfg.VSwitchReserveTags,
+		autoExpand:  cfg.VSwitchAutoExpand,
+		notified:    sets.New[string](),
+		observed:    map[string]string{},
+		now:         time.Now,
+	}, nil
+}
+
+// Start the forecast loop
+func (f *Forecast) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		err := f.sync(ctx)
+		if err != nil {
+			log.Error(err, "error sync vSwitch forecast")
+		}
+	}, f.interval)
+	return nil
+}
+
+// NeedLeaderElection need election
+func (f *Forecast) NeedLeaderElection() bool {
+	return true
+}
+
+// eniConfig the vSwitches of the eni-config, other fields are kept as is
+type eniConfig struct {
+	cm        *corev1.ConfigMap
+	raw       map[string]json.RawMessage
+	vSwitches map[string][]string
+}
+
+func (f *Forecast) sync(ctx context.Context) error {
+	pnList := &v1beta1.PodNetworkingList{}
+	err := f.client.List(ctx, pnList)
+	if err != nil {
+		return fmt.Errorf("error list podNetworking, %w", err)
+	}
+	eniConf, err := f.getENIConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	// collect all vSwitches in use
+	inUse := sets.New[string]()
+	for _, pn := range pnList.Items {
+		inUse.Insert(pn.Spec.VSwitchOptions...)
+	}
+	if eniConf != nil {
+		for _, ids := range eniConf.vSwitches {
+			inUse.Insert(ids...)
+		}
+	}
+
+	vSwitches := make(map[string]*vswitch.Switch, inUse.Len())
+	reserve := f.listReserve(ctx)
+	for _, vsw := range reserve {
+		inUse.Insert(vsw.ID)
+		vSwitches[vsw.ID] = vsw
+	}
+
+	now := f.now()
+	exhausting := make(map[string]vswitch.Forecast)
+	for _, id := range sets.List(inUse) {
+		vsw, ok := vSwitches[id]
+		if !ok {
+			// shared with the pod-networking controller, so the vSwitch is described once in the interval
+			vsw, err = f.swPool.Capacity(ctx, f.vpc, id, f.interval)
+			if err != nil {
+				log.Error(err, "error describe vSwitch", "vsw", id)
+				continue
+			}
+			vSwitches[id] = vsw
+		}
+		f.observed[id] = vsw.Zone
+
+		f.forecaster.Observe(id, now, vsw.AvailableIPCount)
+		r, _ := f.forecaster.Forecast(id)
+
+		metric.VSwitchAvailableIPCount.WithLabelValues(id, vsw.Zone).Set(float64(r.Available))
+		if r.ConsumePerHour > 0 {
+			metric.VSwitchExhaustSeconds.WithLabelValues(id, vsw.Zone).Set(r.ExhaustIn.Seconds())
+		} else {
+			metric.VSwitchExhaustSeconds.WithLabelValues(id, vsw.Zone).Set(-1)
+		}
+
+		if r.Exhausting(f.threshold) {
+			exhausting[id] = r
+		}
+	}
+	f.forget(inUse)
+
+	spare := make([]string, 0, len(reserve))
+	for _, vsw := range reserve {
+		spare = append(spare, vsw.ID)
+	}
+
+	for i := range pnList.Items {
+		err = f.syncPodNetworking(ctx, &pnList.Items[i], spare, vSwitches, exhausting)
+		if err != nil {
+			log.Error(err, "error sync podNetworking", "name", pnList.Items[i].Name)
+		}
+	}
+	if eniConf != nil {
+		err = f.syncENIConfig(ctx, eniConf, spare, vSwitches, exhausting)
+		if err != nil {
+			log.Error(err, "error sync eni-config")
+		}
+	}
+	return nil
+}
+
+// listReserve list the spare vSwitches by the tags, nothing is reserved if no tag is configured
+func (f *Forecast) listReserve(ctx context.Context) []*vswitch.Switch {
+	if len(f.reserveTags) == 0 {
+		return nil
+	}
+	resp, err := f.vpc.DescribeVSwitches(ctx, f.vpcID, f.reserveTags)
+	if err != nil {
+		log.Error(err, "error list reserve vSwitches", "tags", f.reserveTags)
+		return nil
+	}
+	reserve := make([]*vswitch.Switch, 0, len(resp))
+	for i := range resp {
+		reserve = append(reserve, f.swPool.Update(&resp[i]))
+	}
+	sort.Slice(reserve, func(i, j int) bool {
+		return reserve[i].ID < reserve[j].ID
+	})
+	return reserve
+}
+
+// forget drop the metrics and samples of the vSwitches no longer in use
+func (f *Forecast) forget(inUse sets.Set[string]) {
+	for id, zone := range f.observed {
+		if inUse.Has(id) {
+			continue
+		}
+		metric.VSwitchAvailableIPCount.DeleteLabelValues(id, zone)
+		metric.VSwitchExhaustSeconds.DeleteLabelValues(id, zone)
+		f.forecaster.Forget(id)
+		delete(f.observed, id)
+	}
+}
+
+func (f *Forecast) syncPodNetworking(ctx context.Context, pn *v1beta1.PodNetworking, spare []string, vSwitches map[string]*vswitch.Switch, exhausting map[string]vswitch.Forecast) error {
+	byZone := make(map[string][]string)
+	for _, id := range pn.Spec.VSwitchOptions {
+		vsw, ok := vSwitches[id]
+		if !ok {
+			continue
+		}
+		byZone[vsw.Zone] = append(byZone[vsw.Zone], id)
+	}
+
+	if f.autoExpand {
+		added := expand(byZone, spare, vSwitches, exhausting)
+		if len(added) > 0 {
+			update := pn.DeepCopy()
+			update.Spec.VSwitchOptions = append(update.Spec.VSwitchOptions, added...)
+			err := f.client.Patch(ctx, update, client.MergeFrom(pn))
+			if err != nil {
+				return fmt.Errorf("error append vSwitches %v, %w", added, err)
+			}
+			*pn = *update
+			f.record.Eventf(pn, corev1.EventTypeNormal, types.EventVSwitchExpanded, "vSwitches %s appended", strings.Join(added, ","))
+		}
+	}
+
+	var msgs []string
+	for _, id := range pn.Spec.VSwitchOptions {
+		r, ok := exhausting[id]
+		if !ok {
+			continue
+		}
+		msgs = append(msgs, exhaustMessage(r))
+	}
+
+	cond := metav1.Condition{
+		Type:               v1beta1.ConditionVSwitchExhausting,
+		Status:             metav1.ConditionFalse,
+		Reason:             ReasonCapacitySufficient,
+		Message:            "all vSwitches have enough ip",
+		ObservedGeneration: pn.Generation,
+		LastTransitionTime: metav1.NewTime(f.now()),
+	}
+	if len(msgs) > 0 {
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = ReasonProjectedExhaustion
+		cond.Message = strings.Join(msgs, "; ")
+	}
+
+	update := pn.DeepCopy()
+	meta.SetStatusCondition(&update.Status.Conditions, cond)
+	if reflect.DeepEqual(update.Status.Conditions, pn.Status.Conditions) {
+		return nil
+	}
+	err := f.client.Status().Update(ctx, update)
+	if err != nil {
+		return fmt.Errorf("error update status, %w", err)
+	}
+
+	// only notice on transition
+	if cond.Status == metav1.ConditionTrue && !meta.IsStatusConditionTrue(pn.Status.Conditions, cond.Type) {
+		f.record.Event(update, corev1.EventTypeWarning, types.EventVSwitchExhausting, cond.Message)
+	}
+	return nil
+}
+
+// syncENIConfig notice the vSwitches of the eni-config start exhausting, and append the spare vSwitches
+// to the vswitches of the zone if auto expand is enabled. The terway daemon read the eni-config at startup,
+// so the vSwitches appended are used after the daemon restarted.
+func (f *Forecast) syncENIConfig(ctx context.Context, eniConf *eniConfig, spare []string, vSwitches map[string]*vswitch.Switch, exhausting map[string]vswitch.Forecast) error {
+	// the eni-config has no condition to record it, only notice the vSwitch start exhausting
+	var msgs []string
+	notified := sets.New[string]()
+	for _, zone := range sortedKeys(eniConf.vSwitches) {
+		for _, id := range eniConf.vSwitches[zone] {
+			r, ok := exhausting[id]
+			if !ok {
+				continue
+			}
+			notified.Insert(id)
+			if !f.notified.Has(id) {
+				msgs = append(msgs, exhaustMessage(r))
+			}
+		}
+	}
+	f.notified = notified
+
+	// the key of the eni-config is the zone
+	added := expand(eniConf.vSwitches, spare, vSwitches, exhausting)
+	if !f.autoExpand {
+		if len(msgs) > 0 && len(added) > 0 {
+			msgs = append(msgs, fmt.Sprintf("spare vSwitches %s can be added to eni-config", strings.Join(added, ",")))
+		}
+		added = nil
+	}
+	if len(msgs) > 0 {
+		f.record.Event(eniConf.cm, corev1.EventTypeWarning, types.EventVSwitchExhausting, strings.Join(msgs, "; "))
+	}
+	if len(added) == 0 {
+		return nil
+	}
+
+	byZone := make(map[string][]string, len(eniConf.vSwitches))
+	for zone, ids := range eniConf.vSwitches {
+		byZone[zone] = append([]string{}, ids...)
+	}
+	for _, id := range added {
+		zone := vSwitches[id].Zone
+		byZone[zone] = append(byZone[zone], id)
+	}
+
+	out, err := json.Marshal(byZone)
+	if err != nil {
+		return err
+	}
+	raw := make(map[string]json.RawMessage, len(eniConf.raw))
+	for k, v := range eniConf.raw {
+		raw[k] = v
+	}
+	raw["vswitches"] = out
+	conf, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	update := eniConf.cm.DeepCopy()
+	update.Data[eniConfigKey] = string(conf)
+	err = f.client.Patch(ctx, update, client.MergeFrom(eniConf.cm))
+	if err != nil {
+		return fmt.Errorf("error append vSwitches %v, %w", added, err)
+	}
+	eniConf.cm, eniConf.raw, eniConf.vSwitches = update, raw, byZone
+	f.record.Eventf(update, corev1.EventTypeNormal, types.EventVSwitchExpanded, "vSwitches %s appended, restart terway to use them", strings.Join(added, ","))
+	return nil
+}
+
+// expand pick one spare vSwitch for each zone if all the vSwitches in the zone are exhausting
+func expand(byZone map[string][]string, spare []string, vSwitches map[string]*vswitch.Switch, exhausting map[string]vswitch.Forecast) []string {
+	var added []string
+	for _, zone := range sortedKeys(byZone) {
+		ids := byZone[zone]
+		if len(ids) == 0 {
+			continue
+		}
+		allExhausting := true
+		for _, id := range ids {
+			if _, ok := exhausting[id]; !ok {
+				allExhausting = false
+				break
+			}
+		}
+		if !allExhausting {
+			continue
+		}
+
+		used := sets.New[string](ids...)
+		for _, id := range spare {
+			vsw, ok := vSwitches[id]
+			if !ok || vsw.Zone != zone || used.Has(id) {
+				continue
+			}
+			if _, ok = exhausting[id]; ok {
+				continue
+			}
+			added = append(added, id)
+			break
+		}
+	}
+	return added
+}
+
+func (f *Forecast) getENIConfig(ctx context.Context) (*eniConfig, error) {
+	cm := &corev1.ConfigMap{}
+	err := f.client.Get(ctx, k8stypes.NamespacedName{Namespace: eniConfigNamespace, Name: eniConfigName}, cm)
+	if err != nil {
+		if k8sErr.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error get configmap %s/%s, %w", eniConfigNamespace, eniConfigName, err)
+	}
+	content, ok := cm.Data[eniConfigKey]
+	if !ok {
+		return nil, nil
+	}
+
+	raw := map[string]json.RawMessage{}
+	err = json.Unmarshal([]byte(content), &raw)
+	if err != nil {
+		return nil, fmt.Errorf("error parse eni-config, %w", err)
+	}
+	var vSwitches map[string][]string
+	if v, ok := raw["vswitches"]; ok {
+		err = json.Unmarshal(v, &vSwitches)
+		if err != nil {
+			return nil, fmt.Errorf("error parse vswitches of eni-config, %w", err)
+		}
+	}
+	return &eniConfig{cm: cm, raw: raw, vSwitches: vSwitches}, nil
+}
+
+func exhaustMessage(r vswitch.Forecast) string {
+	if r.Available <= 0 {
+		return fmt.Sprintf("vSwitch %s has no available ip", r.ID)
+	}
+	return fmt.Sprintf("vSwitch %s has %d available ip, projected to run out in %s", r.ID, r.Available, r.ExhaustIn.Round(time.Minute))
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,175 @@
+package vswitchforecast
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/vpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/pkg/controller/mocks"
+	"github.com/AliyunContainerService/terway/pkg/vswitch"
+	"github.com/AliyunContainerService/terway/types/controlplane"
+)
+
+func TestForecast_sync(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1beta1.AddToScheme(scheme))
+
+	pn := &v1beta1.PodNetworking{
+		ObjectMeta: metav1.ObjectMeta{Name: "pn"},
+		Spec:       v1beta1.PodNetworkingSpec{VSwitchOptions: []string{"vsw-a"}},
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "eni-config"},
+		Data: map[string]string{
+			"eni_conf": `{"version":"1","vswitches":{"zone-a":["vsw-a"],"zone-b":["vsw-b"]}}`,
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pn, cm).WithStatusSubresource(pn).Build()
+
+	availableIP := map[string]int64{"vsw-a": 100, "vsw-b": 1000, "vsw-spare-a": 1000, "vsw-spare-b": 1000}
+	zones := map[string]string{"vsw-a": "zone-a", "vsw-b": "zone-b", "vsw-spare-a": "zone-a", "vsw-spare-b": "zone-b"}
+	api := &mocks.Interface{}
+	api.On("DescribeVSwitchByID", mock.Anything, mock.Anything).Return(func(_ context.Context, id string) (*vpc.VSwitch, error) {
+		return &vpc.VSwitch{VSwitchId: id, ZoneId: zones[id], AvailableIpAddressCount: availableIP[id]}, nil
+	})
+	api.On("DescribeVSwitches", mock.Anything, "vpc-1", map[string]string{"reserve": "true"}).Return(func(_ context.Context, _ string, _ map[string]string) ([]vpc.VSwitch, error) {
+		var result []vpc.VSwitch
+		for _, id := range []string{"vsw-spare-b", "vsw-spare-a"} {
+			result = append(result, vpc.VSwitch{VSwitchId: id, ZoneId: zones[id], AvailableIpAddressCount: availableIP[id]})
+		}
+		return result, nil
+	})
+
+	switchPool, err := vswitch.NewSwitchPool(100, "10m")
+	require.NoError(t, err)
+	// the ip is consumed after the vSwitches are described
+	consume := func(id string, available int64) {
+		availableIP[id] = available
+		switchPool.Del(id)
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	f, err := New(c, recorder, api, switchPool, &controlplane.Config{
+		VSwitchForecastInterval: "5m",
+		VSwitchForecastWindow:   "6h",
+		VSwitchExhaustThreshold: "24h",
+		VPCID:                   "vpc-1",
+		VSwitchReserveTags:      map[string]string{"reserve": "true"},
+		VSwitchAutoExpand:       true,
+	})
+	require.NoError(t, err)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	f.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	require.NoError(t, f.sync(ctx))
+
+	got := &v1beta1.PodNetworking{}
+	require.NoError(t, c.Get(ctx, k8stypes.NamespacedName{Name: "pn"}, got))
+	assert.Equal(t, []string{"vsw-a"}, got.Spec.VSwitchOptions)
+	cond := meta.FindStatusCondition(got.Status.Conditions, v1beta1.ConditionVSwitchExhausting)
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionFalse, cond.Status)
+	assert.Len(t, recorder.Events, 0)
+
+	// vsw-a consume 10 ip per hour, run out in 9h
+	now = now.Add(time.Hour)
+	consume("vsw-a", 90)
+	require.NoError(t, f.sync(ctx))
+
+	require.NoError(t, c.Get(ctx, k8stypes.NamespacedName{Name: "pn"}, got))
+	assert.Equal(t, []string{"vsw-a", "vsw-spare-a"}, got.Spec.VSwitchOptions)
+	cond = meta.FindStatusCondition(got.Status.Conditions, v1beta1.ConditionVSwitchExhausting)
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionTrue, cond.Status)
+	assert.Equal(t, ReasonProjectedExhaustion, cond.Reason)
+	assert.Contains(t, cond.Message, "vsw-a")
+
+	// the spare vSwitch is appended to the zone of the eni-config, other fields are kept
+	gotCM := &corev1.ConfigMap{}
+	require.NoError(t, c.Get(ctx, k8stypes.NamespacedName{Namespace: "kube-system", Name: "eni-config"}, gotCM))
+	eniConf := struct {
+		Version   string              `json:"version"`
+		VSwitches map[string][]string `json:"vswitches"`
+	}{}
+	require.NoError(t, json.Unmarshal([]byte(gotCM.Data["eni_conf"]), &eniConf))
+	assert.Equal(t, "1", eniConf.Version)
+	assert.Equal(t, map[string][]string{"zone-a": {"vsw-a", "vsw-spare-a"}, "zone-b": {"vsw-b"}}, eniConf.VSwitches)
+
+	// expanded and exhausting on podNetworking, exhausting and expanded on eni-config
+	require.Len(t, recorder.Events, 4)
+	var events []string
+	for i := 0; i < 4; i++ {
+		events = append(events, <-recorder.Events)
+	}
+	assert.Contains(t, events[2], "vsw-a")
+	assert.Contains(t, events[3], "vsw-spare-a")
+	assert.Contains(t, events[3], "restart terway")
+
+	// the condition is kept, no more event
+	now = now.Add(time.Hour)
+	consume("vsw-a", 80)
+	require.NoError(t, f.sync(ctx))
+	require.NoError(t, c.Get(ctx, k8stypes.NamespacedName{Name: "pn"}, got))
+	assert.Equal(t, []string{"vsw-a", "vsw-spare-a"}, got.Spec.VSwitchOptions)
+	assert.Len(t, recorder.Events, 0)
+
+	// vsw-b is no longer in use once the eni-config is removed
+	require.NoError(t, c.Delete(ctx, gotCM))
+	require.NoError(t, f.sync(ctx))
+	assert.NotContains(t, f.observed, "vsw-b")
+	_, ok := f.forecaster.Forecast("vsw-b")
+	assert.False(t, ok)
+	_, ok = f.forecaster.Forecast("vsw-a")
+	assert.True(t, ok)
+}
+
+func TestForecast_syncENIConfigWithoutAutoExpand(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "eni-config"},
+		Data: map[string]string{
+			"eni_conf": `{"vswitches":{"zone-a":["vsw-a"]}}`,
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cm).Build()
+	recorder := record.NewFakeRecorder(10)
+	f := &Forecast{client: c, record: recorder, notified: sets.New[string]()}
+
+	ctx := context.Background()
+	eniConf, err := f.getENIConfig(ctx)
+	require.NoError(t, err)
+
+	vSwitches := map[string]*vswitch.Switch{
+		"vsw-a":       {ID: "vsw-a", Zone: "zone-a"},
+		"vsw-spare-a": {ID: "vsw-spare-a", Zone: "zone-a"},
+	}
+	exhausting := map[string]vswitch.Forecast{"vsw-a": {ID: "vsw-a"}}
+	require.NoError(t, f.syncENIConfig(ctx, eniConf, []string{"vsw-spare-a"}, vSwitches, exhausting))
+
+	// the eni-config is kept as is, the spare vSwitch is suggested
+	gotCM := &corev1.ConfigMap{}
+	require.NoError(t, c.Get(ctx, k8stypes.NamespacedName{Namespace: "kube-system", Name: "eni-config"}, gotCM))
+	assert.Equal(t, cm.Data, gotCM.Data)
+	require.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, "spare vSwitches vsw-spare-a")
+}
@@ -22,11 +22,9 @@ import (
 	"net/http"
 	"strconv"
 
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
-
 	"github.com/AliyunContainerService/terway/deviceplugin"
 	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/pkg/controller/common"
 	"github.com/AliyunContainerService/terway/pkg/utils"
 	"github.com/AliyunContainerService/terway/types"
 	"github.com/AliyunContainerService/terway/types/controlplane"
@@ -116,6 +114,7 @@ func podWebhook(ctx context.Context, req *webhook.AdmissionRequest, client clien
 
 	prevZone := sets.NewString()
 	vSwitchZone := sets.NewString()
+	preferredZone := ""
 
 	if len(networks.PodNetworks) == 0 {
 		// get pn
@@ -183,7 +182,12 @@ func podWebhook(ctx context.Context, req *webhook.AdmissionRequest, client clien
 
 		// only set prev zone for fixed ip
 		if alloc.Type == v1beta1.IPAllocTypeFixed && needPreviousZoneForAnnotation(previousZone, n) {
-			prevZone.Insert(previousZone)
+			if alloc.ZoneMigration == v1beta1.ZoneMigrationAllowed {
+				// the pod can be moved to another zone, keep it in the previous zone if possible
+				preferredZone = previousZone
+			} else {
+				prevZone.Insert(previousZone)
+			}
 		}
 	}
 
@@ -223,6 +227,7 @@ func podWebhook(ctx context.Context, req *webhook.AdmissionRequest, client clien
 	setResourceRequest(pod, resName, len(networks.PodNetworks))
 
 	setNodeAffinityByZones(pod, prevZone.List(), vSwitchZone.List())
+	setPreferredNodeAffinityByZone(pod, preferredZone)
 
 	podPatched, err := json.Marshal(pod)
 	if err != nil {
@@ -296,45 +301,17 @@ func matchOnePodNetworking(ctx context.Context, namespace string, client client.
 		return nil, fmt.Errorf("error get namespace, %w", err)
 	}
 
-	podLabels := labels.Set(pod.Labels)
-	nsLabels := labels.Set(ns.Labels)
-	for _, podNetworking := range podNetworkings.Items {
-		if podNetworking.Status.Status != v1beta1.NetworkingStatusReady {
-			continue
-		}
-		if !utils.IsFixedNamePod(pod) {
-			// for fixed ip , only match sts pod
-			if podNetworking.Spec.AllocationType.Type == v1beta1.IPAllocTypeFixed {
-				continue
-			}
-		}
-
-		matchOne := false
-		if podNetworking.Spec.Selector.PodSelector != nil {
-			ok, err := PodMatchSelector(podNetworking.Spec.Selector.PodSelector, podLabels)
-			if err != nil {
-				return nil, fmt.Errorf("error match pod selector, %w", err)
-			}
-			if !ok {
-				continue
-			}
-			matchOne = true
-		}
-		if podNetworking.Spec.Selector.NamespaceSelector != nil {
-			ok, err := PodMatchSelector(podNetworking.Spec.Selector.NamespaceSelector, nsLabels)
-			if err != nil {
-				return nil, fmt.Errorf("error match namespace selector, %w", err)
-			}
-			if !ok {
-				continue
-			}
-			matchOne = true
-		}
-		if matchOne {
-			return &podNetworking, nil
-		}
+	matched, err := common.MatchPodNetworkings(pod, ns, podNetworkings.Items)
+	if err != nil {
+		return nil, err
 	}
-	return nil, nil
+	if len(matched) == 0 {
+		return nil, nil
+	}
+	if len(matched) > 1 && matched[0].Spec.Priority == matched[1].Spec.Priority {
+		log.Info("multiple podNetworking matched with same priority, use the first one by name", "pod", k8stypes.NamespacedName{Namespace: namespace, Name: pod.Name}.String(), "podNetworking", matched[0].Name, "other", matched[1].Name)
+	}
+	return &matched[0], nil
 }
 
 func getPreviousZone(ctx context.Context, client client.Client, pod *corev1.Pod) (string, error) {
@@ -425,11 +402,28 @@ func setNodeAffinityByZones(pod *corev1.Pod, zones ...[]string) {
 	}
 }
 
-// PodMatchSelector pod is selected by selector
-func PodMatchSelector(labelSelector *metav1.LabelSelector, l labels.Set) (bool, error) {
-	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
-	if err != nil {
-		return false, err
+// setPreferredNodeAffinityByZone prefer the nodes in the zone, the pod is still scheduled to other zones if the zone is not available
+func setPreferredNodeAffinityByZone(pod *corev1.Pod, zone string) {
+	if utils.IsDaemonSetPod(pod) || zone == "" {
+		return
+	}
+
+	if pod.Spec.Affinity == nil {
+		pod.Spec.Affinity = &corev1.Affinity{}
+	}
+	if pod.Spec.Affinity.NodeAffinity == nil {
+		pod.Spec.Affinity.NodeAffinity = &corev1.NodeAffinity{}
 	}
-	return selector.Matches(l), nil
+	pod.Spec.Affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(pod.Spec.Affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution, corev1.PreferredSchedulingTerm{
+		Weight: 100,
+		Preference: corev1.NodeSelectorTerm{
+			MatchExpressions: []corev1.NodeSelectorRequirement{
+				{
+					Key:      corev1.LabelTopologyZone,
+					Operator: corev1.NodeSelectorOpIn,
+					Values:   []string{zone},
+				},
+			},
+		},
+	})
 }
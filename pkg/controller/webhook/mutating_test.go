@@ -301,3 +301,25 @@ func Test_setNodeAffinityByZones(t *testing.T) {
 		})
 	}
 }
+
+func Test_setPreferredNodeAffinityByZone(t *testing.T) {
+	pod := &corev1.Pod{}
+	setPreferredNodeAffinityByZone(pod, "")
+	if pod.Spec.Affinity != nil {
+		t.Errorf("setPreferredNodeAffinityByZone() = %v, want nil", pod.Spec.Affinity)
+	}
+
+	setPreferredNodeAffinityByZone(pod, "zone-a")
+	want := []corev1.PreferredSchedulingTerm{{
+		Weight: 100,
+		Preference: corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{
+			{Key: corev1.LabelTopologyZone, Operator: corev1.NodeSelectorOpIn, Values: []string{"zone-a"}},
+		}},
+	}}
+	if !reflect.DeepEqual(pod.Spec.Affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution, want) {
+		t.Errorf("setPreferredNodeAffinityByZone() = %v, want %v", pod.Spec.Affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution, want)
+	}
+	if pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+		t.Errorf("the pod should not be pinned to the zone")
+	}
+}
@@ -2,14 +2,26 @@ package webhook
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/netip"
 	"time"
 
+	"github.com/AliyunContainerService/terway/pkg/aliyun/client"
+	apiErr "github.com/AliyunContainerService/terway/pkg/aliyun/client/errors"
 	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	register "github.com/AliyunContainerService/terway/pkg/controller"
+	"github.com/AliyunContainerService/terway/pkg/controller/common"
+	"github.com/AliyunContainerService/terway/pkg/vswitch"
+	"github.com/AliyunContainerService/terway/types/controlplane"
 
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/sets"
 	ctrl "sigs.k8s.io/controller-runtime"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
@@ -17,7 +29,7 @@ import (
 var validateLog = ctrl.Log.WithName("validate-webhook")
 
 // ValidateHook ValidateHook
-func ValidateHook() *webhook.Admission {
+func ValidateHook(c k8sclient.Client, aliyunClient register.Interface) *webhook.Admission {
 	return &webhook.Admission{
 		Handler: admission.HandlerFunc(func(ctx context.Context, req webhook.AdmissionRequest) webhook.AdmissionResponse {
 			validateLog.Info("obj in", "kind", req.Kind.Kind, "name", req.Name, "res", req.Resource.String())
@@ -32,7 +44,16 @@ func ValidateHook() *webhook.Admission {
 			if err != nil {
 				return admission.Errored(http.StatusInternalServerError, fmt.Errorf("failed decoding podNetworking: %s, %w", string(original), err))
 			}
-			l := log.WithName(podNetworking.Name)
+			var old *v1beta1.PodNetworking
+			if req.Operation == admissionv1.Update && len(req.OldObject.Raw) > 0 {
+				old = &v1beta1.PodNetworking{}
+				err = json.Unmarshal(req.OldObject.Raw, old)
+				if err != nil {
+					return admission.Errored(http.StatusInternalServerError, fmt.Errorf("failed decoding podNetworking: %s, %w", string(req.OldObject.Raw), err))
+				}
+			}
+
+			l := validateLog.WithName(podNetworking.Name)
 			l.Info("checking podNetworking")
 			if podNetworking.Spec.Selector.PodSelector == nil && podNetworking.Spec.Selector.NamespaceSelector == nil {
 				return admission.Denied("neither the PodSelector nor the NamespaceSelector is set")
@@ -47,15 +68,149 @@ func ValidateHook() *webhook.Admission {
 				return admission.Denied("security group can not more than 5")
 			}
 
-			if podNetworking.Spec.AllocationType.ReleaseStrategy == v1beta1.IPAllocTypeFixed {
-				if podNetworking.Spec.AllocationType.ReleaseStrategy == v1beta1.ReleaseStrategyTTL {
-					_, err = time.ParseDuration(podNetworking.Spec.AllocationType.ReleaseAfter)
-					if err != nil {
-						return webhook.Denied(fmt.Sprintf("invalid releaseAfter %s", podNetworking.Spec.AllocationType.ReleaseAfter))
-					}
+			allocType := podNetworking.Spec.AllocationType
+			if allocType.ReleaseAfter != "" || (allocType.Type == v1beta1.IPAllocTypeFixed && allocType.ReleaseStrategy == v1beta1.ReleaseStrategyTTL) {
+				d, err := time.ParseDuration(allocType.ReleaseAfter)
+				if err != nil || d < 0 {
+					return webhook.Denied(fmt.Sprintf("invalid releaseAfter %s", allocType.ReleaseAfter))
+				}
+			}
+
+			if allocType.ZoneMigration == v1beta1.ZoneMigrationAllowed && allocType.Type != v1beta1.IPAllocTypeFixed {
+				return webhook.Denied("zoneMigration is only supported by the Fixed ip")
+			}
+
+			if msg := checkSelectOptions(&podNetworking.Spec.VSwitchSelectOptions); msg != "" {
+				return webhook.Denied(msg)
+			}
+
+			// the spec is patched by the controllers too, only check the changes
+			if old == nil || !equality.Semantic.DeepEqual(old.Spec.Selector, podNetworking.Spec.Selector) || old.Spec.Priority != podNetworking.Spec.Priority {
+				msg, err := checkOverlap(ctx, c, podNetworking)
+				if err != nil {
+					l.Error(err, "error check podNetworking overlap")
+					return webhook.Errored(http.StatusInternalServerError, err)
+				}
+				if msg != "" {
+					return webhook.Denied(msg)
 				}
 			}
+
+			vSwitches := sets.New[string](podNetworking.Spec.VSwitchOptions...)
+			if old != nil {
+				vSwitches.Delete(old.Spec.VSwitchOptions...)
+			}
+			msg, err := checkVSwitches(ctx, aliyunClient, sets.List(vSwitches))
+			if err != nil {
+				l.Error(err, "error check vSwitches")
+				return webhook.Errored(http.StatusInternalServerError, err)
+			}
+			if msg != "" {
+				return webhook.Denied(msg)
+			}
+
+			securityGroups := sets.New[string](podNetworking.Spec.SecurityGroupIDs...)
+			if old != nil {
+				securityGroups.Delete(old.Spec.SecurityGroupIDs...)
+			}
+			msg, err = checkSecurityGroups(ctx, aliyunClient, sets.List(securityGroups))
+			if err != nil {
+				l.Error(err, "error check security groups")
+				return webhook.Errored(http.StatusInternalServerError, err)
+			}
+			if msg != "" {
+				return webhook.Denied(msg)
+			}
 			return webhook.Allowed("checked")
 		}),
 	}
 }
+
+// checkOverlap return the reason if the podNetworking select same pods as others with the same priority
+func checkOverlap(ctx context.Context, c k8sclient.Client, podNetworking *v1beta1.PodNetworking) (string, error) {
+	podNetworkings := &v1beta1.PodNetworkingList{}
+	err := c.List(ctx, podNetworkings)
+	if err != nil {
+		return "", fmt.Errorf("error list podNetworking, %w", err)
+	}
+	for _, other := range podNetworkings.Items {
+		if other.Name == podNetworking.Name || other.Spec.Priority != podNetworking.Spec.Priority {
+			continue
+		}
+		overlap, err := common.PodNetworkingOverlap(&podNetworking.Spec.Selector, &other.Spec.Selector)
+		if err != nil {
+			return fmt.Sprintf("invalid selector, %s", err), nil
+		}
+		if overlap {
+			return fmt.Sprintf("selector overlaps with podNetworking %s, set a different priority to decide which one is used", other.Name), nil
+		}
+	}
+	return "", nil
+}
+
+// checkSelectOptions return the reason if the vSwitch select options is invalid
+func checkSelectOptions(opts *v1beta1.VSwitchSelectOptions) string {
+	if opts.VSwitchSelectionPolicy != "" {
+		if _, ok := vswitch.ParseSelectionPolicy(opts.VSwitchSelectionPolicy); !ok {
+			return fmt.Sprintf("unknown vSwitchSelectionPolicy %s", opts.VSwitchSelectionPolicy)
+		}
+	}
+	for id, w := range opts.VSwitchWeights {
+		if w < 0 {
+			return fmt.Sprintf("invalid weight %d of vSwitch %s, the weight can not be negative", w, id)
+		}
+	}
+	for _, cidr := range opts.PreferCIDRs {
+		_, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return fmt.Sprintf("invalid preferCIDRs %s", cidr)
+		}
+	}
+	return ""
+}
+
+// checkVSwitches return the reason if the vSwitch is not found in the cluster vpc
+func checkVSwitches(ctx context.Context, vpcClient client.VPC, ids []string) (string, error) {
+	vpcID := clusterVPCID()
+	for _, id := range ids {
+		vsw, err := vpcClient.DescribeVSwitchByID(ctx, id)
+		if err != nil {
+			if errors.Is(err, apiErr.ErrNotFound) {
+				return fmt.Sprintf("vSwitch %s is not found", id), nil
+			}
+			return "", err
+		}
+		if vpcID != "" && vsw.VpcId != vpcID {
+			return fmt.Sprintf("vSwitch %s belongs to vpc %s, not the cluster vpc %s", id, vsw.VpcId, vpcID), nil
+		}
+	}
+	return "", nil
+}
+
+// checkSecurityGroups return the reason if the security group is not found in the cluster vpc
+func checkSecurityGroups(ctx context.Context, ecsClient client.ECS, ids []string) (string, error) {
+	if len(ids) == 0 {
+		return "", nil
+	}
+	securityGroups, err := ecsClient.DescribeSecurityGroups(ctx, clusterVPCID(), ids)
+	if err != nil {
+		return "", err
+	}
+	found := sets.New[string]()
+	for _, sg := range securityGroups {
+		found.Insert(sg.SecurityGroupId)
+	}
+	for _, id := range ids {
+		if !found.Has(id) {
+			return fmt.Sprintf("security group %s is not found in the cluster vpc", id), nil
+		}
+	}
+	return "", nil
+}
+
+func clusterVPCID() string {
+	if cfg := controlplane.GetConfig(); cfg != nil {
+		return cfg.VPCID
+	}
+	return ""
+}
@@ -0,0 +1,178 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/vpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	apiErr "github.com/AliyunContainerService/terway/pkg/aliyun/client/errors"
+	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/pkg/controller/mocks"
+	"github.com/AliyunContainerService/terway/types/controlplane"
+)
+
+func TestValidateHook(t *testing.T) {
+	controlplane.SetConfig(&controlplane.Config{VPCID: "vpc-1"})
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1beta1.AddToScheme(scheme))
+
+	newPodNetworking := func(name string, priority int32, podLabels map[string]string) *v1beta1.PodNetworking {
+		return &v1beta1.PodNetworking{
+			TypeMeta:   metav1.TypeMeta{Kind: "PodNetworking", APIVersion: v1beta1.SchemeGroupVersion.String()},
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1beta1.PodNetworkingSpec{
+				Selector:         v1beta1.Selector{PodSelector: &metav1.LabelSelector{MatchLabels: podLabels}},
+				Priority:         priority,
+				SecurityGroupIDs: []string{"sg-1"},
+				VSwitchOptions:   []string{"vsw-1"},
+			},
+		}
+	}
+	existing := newPodNetworking("existing", 0, map[string]string{"app": "a"})
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(existing).Build()
+
+	api := &mocks.Interface{}
+	api.On("DescribeVSwitchByID", mock.Anything, mock.Anything).Return(func(_ context.Context, id string) (*vpc.VSwitch, error) {
+		switch id {
+		case "vsw-1", "vsw-2":
+			return &vpc.VSwitch{VSwitchId: id, VpcId: "vpc-1"}, nil
+		case "vsw-other":
+			return &vpc.VSwitch{VSwitchId: id, VpcId: "vpc-2"}, nil
+		}
+		return nil, apiErr.ErrNotFound
+	})
+	api.On("DescribeSecurityGroups", mock.Anything, "vpc-1", mock.Anything).Return(func(_ context.Context, _ string, ids []string) ([]ecs.SecurityGroup, error) {
+		var result []ecs.SecurityGroup
+		for _, id := range ids {
+			if id != "sg-absent" {
+				result = append(result, ecs.SecurityGroup{SecurityGroupId: id, VpcId: "vpc-1"})
+			}
+		}
+		return result, nil
+	})
+
+	hook := ValidateHook(c, api)
+	validate := func(pn, old *v1beta1.PodNetworking) admission.Response {
+		req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Create,
+			Name:      pn.Name,
+			Kind:      metav1.GroupVersionKind{Kind: "PodNetworking"},
+		}}
+		req.Object.Raw, _ = json.Marshal(pn)
+		if old != nil {
+			req.Operation = admissionv1.Update
+			req.OldObject.Raw, _ = json.Marshal(old)
+		}
+		return hook.Handle(context.Background(), req)
+	}
+
+	resp := validate(newPodNetworking("new", 0, map[string]string{"app": "b"}), nil)
+	assert.True(t, resp.Allowed, resp.Result.Message)
+
+	resp = validate(newPodNetworking("new", 0, map[string]string{"app": "a", "tier": "web"}), nil)
+	assert.False(t, resp.Allowed)
+	assert.Contains(t, resp.Result.Message, "existing")
+
+	resp = validate(newPodNetworking("new", 1, map[string]string{"app": "a", "tier": "web"}), nil)
+	assert.True(t, resp.Allowed, resp.Result.Message)
+
+	// the object itself is not checked on update
+	resp = validate(newPodNetworking("existing", 0, map[string]string{"app": "a", "tier": "web"}), existing)
+	assert.True(t, resp.Allowed, resp.Result.Message)
+
+	pn := newPodNetworking("new", 0, map[string]string{"app": "b"})
+	pn.Spec.SecurityGroupIDs = []string{"sg-1", "sg-2", "sg-3", "sg-4", "sg-5", "sg-6"}
+	resp = validate(pn, nil)
+	assert.False(t, resp.Allowed)
+
+	pn = newPodNetworking("new", 0, map[string]string{"app": "b"})
+	pn.Spec.AllocationType = v1beta1.AllocationType{Type: v1beta1.IPAllocTypeFixed, ReleaseStrategy: v1beta1.ReleaseStrategyTTL, ReleaseAfter: "1x"}
+	resp = validate(pn, nil)
+	assert.False(t, resp.Allowed)
+	assert.Contains(t, resp.Result.Message, "releaseAfter")
+
+	pn.Spec.AllocationType.ReleaseAfter = "10m"
+	resp = validate(pn, nil)
+	assert.True(t, resp.Allowed, resp.Result.Message)
+
+	pn.Spec.AllocationType = v1beta1.AllocationType{Type: v1beta1.IPAllocTypeElastic, ZoneMigration: v1beta1.ZoneMigrationAllowed}
+	resp = validate(pn, nil)
+	assert.False(t, resp.Allowed)
+	assert.Contains(t, resp.Result.Message, "zoneMigration")
+
+	pn = newPodNetworking("new", 0, map[string]string{"app": "b"})
+	pn.Spec.VSwitchOptions = []string{"vsw-1", "vsw-absent"}
+	resp = validate(pn, nil)
+	assert.False(t, resp.Allowed)
+	assert.Contains(t, resp.Result.Message, "vsw-absent")
+
+	pn.Spec.VSwitchOptions = []string{"vsw-other"}
+	resp = validate(pn, nil)
+	assert.False(t, resp.Allowed)
+	assert.Contains(t, resp.Result.Message, "vpc-2")
+
+	pn = newPodNetworking("new", 0, map[string]string{"app": "b"})
+	pn.Spec.SecurityGroupIDs = []string{"sg-1", "sg-absent"}
+	resp = validate(pn, nil)
+	assert.False(t, resp.Allowed)
+	assert.Contains(t, resp.Result.Message, "sg-absent")
+
+	pn = newPodNetworking("new", 0, map[string]string{"app": "b"})
+	pn.Spec.VSwitchSelectOptions = v1beta1.VSwitchSelectOptions{
+		VSwitchSelectionPolicy: "weighted",
+		VSwitchWeights:         map[string]int{"vsw-1": -1},
+	}
+	resp = validate(pn, nil)
+	assert.False(t, resp.Allowed)
+	assert.Contains(t, resp.Result.Message, "weight")
+
+	pn.Spec.VSwitchSelectOptions = v1beta1.VSwitchSelectOptions{
+		VSwitchSelectionPolicy: "prefer-cidr",
+		PreferCIDRs:            []string{"192.168.0.0/33"},
+	}
+	resp = validate(pn, nil)
+	assert.False(t, resp.Allowed)
+	assert.Contains(t, resp.Result.Message, "preferCIDRs")
+
+	pn.Spec.VSwitchSelectOptions.PreferCIDRs = []string{"192.168.0.0/16"}
+	resp = validate(pn, nil)
+	assert.True(t, resp.Allowed, resp.Result.Message)
+
+	// the spec patched by the controllers, only the appended vSwitch is checked
+	api.Calls = nil
+	update := existing.DeepCopy()
+	update.Spec.VSwitchOptions = append(update.Spec.VSwitchOptions, "vsw-2")
+	resp = validate(update, existing)
+	assert.True(t, resp.Allowed, resp.Result.Message)
+	api.AssertCalled(t, "DescribeVSwitchByID", mock.Anything, "vsw-2")
+	api.AssertNotCalled(t, "DescribeVSwitchByID", mock.Anything, "vsw-1")
+	api.AssertNotCalled(t, "DescribeSecurityGroups", mock.Anything, mock.Anything, mock.Anything)
+
+	update.Spec.VSwitchOptions = append(update.Spec.VSwitchOptions, "vsw-absent")
+	resp = validate(update, existing)
+	assert.False(t, resp.Allowed)
+	assert.Contains(t, resp.Result.Message, "vsw-absent")
+
+	// the security group changed is checked
+	api.Calls = nil
+	update = existing.DeepCopy()
+	update.Spec.SecurityGroupIDs = []string{"sg-1", "sg-2"}
+	resp = validate(update, existing)
+	assert.True(t, resp.Allowed, resp.Result.Message)
+	api.AssertCalled(t, "DescribeSecurityGroups", mock.Anything, "vpc-1", []string{"sg-2"})
+	api.AssertNotCalled(t, "DescribeVSwitchByID", mock.Anything, mock.Anything)
+}
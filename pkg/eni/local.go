@@ -11,6 +11,9 @@ import (
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/time/rate"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -19,6 +22,8 @@ import (
 
 	apiErr "github.com/AliyunContainerService/terway/pkg/aliyun/client/errors"
 	"github.com/AliyunContainerService/terway/pkg/factory"
+	"github.com/AliyunContainerService/terway/pkg/ip"
+	"github.com/AliyunContainerService/terway/pkg/telemetry"
 	"github.com/AliyunContainerService/terway/pkg/tracing"
 	"github.com/AliyunContainerService/terway/rpc"
 	"github.com/AliyunContainerService/terway/types"
@@ -29,6 +34,8 @@ import (
 
 var _ NetworkInterface = &Local{}
 var _ Usage = &Local{}
+var _ Warmer = &Local{}
+var _ PoolMember = &Local{}
 var _ ReportStatus = &Trunk{}
 
 type eniStatus int
@@ -44,6 +51,9 @@ const (
 	LocalIPTypeERDMA = "ERDMA"
 )
 
+// ipv4PrefixSize is the ip count in a /28 ipv4 prefix
+const ipv4PrefixSize = 16
+
 var rateLimit = rate.Every(1 * time.Minute / 10)
 
 var _ ResourceRequest = &LocalIPRequest{}
@@ -54,9 +64,29 @@ type LocalIPRequest struct {
 	IPv4               netip.Addr
 	IPv6               netip.Addr
 
+	// IfName is the interface in the pod, empty for the default interface
+	IfName string
+	// Network restrict the eni to the vSwitches and security groups, nil for the default network
+	Network *factory.NetworkInterfaceOptions
+
+	// ReservedIP is the ip reserved for the pod, it is moved to the eni if not assigned yet
+	ReservedIP *ReservedIP
+
 	NoCache bool // do not use cached ip
 }
 
+// ReservedIP is the ipv4 reserved for the pod and the eni it currently assigned to
+type ReservedIP struct {
+	IPv4               netip.Addr
+	VSwitchID          string
+	NetworkInterfaceID string
+	// Holder is the eni the ip is given back to once released
+	Holder string
+
+	// createENI is set by the manager if no eni in the vSwitch can take the ip
+	createENI bool
+}
+
 func (l *LocalIPRequest) ResourceType() ResourceType {
 	return ResourceTypeLocalIP
 }
@@ -66,9 +96,17 @@ var _ NetworkResource = &LocalIPResource{}
 type LocalIPResource struct {
 	PodID string
 
+	// IfName is the interface in the pod, empty for the default interface
+	IfName string
+
 	ENI daemon.ENI
 
 	IP types.IPSet2
+
+	// Reserved is true if the ipv4 is reserved for the pod
+	Reserved bool
+	// Holder is the eni the reserved ipv4 is given back to
+	Holder string
 }
 
 func (l *LocalIPResource) ResourceType() ResourceType {
@@ -80,12 +118,16 @@ func (l *LocalIPResource) ToStore() []daemon.ResourceItem {
 		return nil
 	}
 	r := daemon.ResourceItem{
-		Type:   daemon.ResourceTypeENIIP,
-		ID:     fmt.Sprintf("%s.%s", l.ENI.MAC, l.IP.String()),
-		ENIID:  l.ENI.ID,
-		ENIMAC: l.ENI.MAC,
-		IPv4:   l.IP.GetIPv4(),
-		IPv6:   l.IP.GetIPv6(),
+		Type:     daemon.ResourceTypeENIIP,
+		ID:       fmt.Sprintf("%s.%s", l.ENI.MAC, l.IP.String()),
+		ENIID:    l.ENI.ID,
+		ENIMAC:   l.ENI.MAC,
+		IPv4:     l.IP.GetIPv4(),
+		IPv6:     l.IP.GetIPv6(),
+		IfName:   l.IfName,
+		Reserved: l.Reserved,
+
+		ReservedHolder: l.Holder,
 	}
 
 	return []daemon.ResourceItem{r}
@@ -106,9 +148,9 @@ func (l *LocalIPResource) ToRPC() []*rpc.NetConf {
 			GatewayIP: l.ENI.GatewayIP.ToRPC(),
 		},
 		Pod:          nil,
-		IfName:       "",
+		IfName:       l.IfName,
 		ExtraRoutes:  nil,
-		DefaultRoute: true,
+		DefaultRoute: l.IfName == "",
 	}
 
 	return []*rpc.NetConf{cfg}
@@ -119,13 +161,19 @@ type Local struct {
 
 	cap                        int
 	allocatingV4, allocatingV6 int
+	// movingV4 is the reserved ips moving to the eni
+	movingV4 int
 
 	eni                    *daemon.ENI
 	ipAllocInhibitExpireAt time.Time
 
+	// network the eni belong to, nil for the default network
+	network *factory.NetworkInterfaceOptions
+
 	eniType string
 
 	enableIPv4, enableIPv6                 bool
+	enableIPv4Prefix                       bool
 	ipv4, ipv6                             Set
 	rateLimitEni, rateLimitv4, rateLimitv6 *rate.Limiter
 
@@ -133,6 +181,9 @@ type Local struct {
 
 	status eniStatus
 
+	// pendingSpans is the spans of the allocWorkers waiting for the ip from the factory
+	pendingSpans []trace.SpanContext
+
 	factory factory.Factory
 }
 
@@ -154,9 +205,24 @@ func NewLocal(eni *daemon.ENI, eniType string, factory factory.Factory, poolConf
 		rateLimitv6:  rate.NewLimiter(rateLimit, 2),
 	}
 
+	// prefix is only used for the secondary eni
+	if poolConfig.EnableIPv4 && poolConfig.IPv4PrefixPerENI > 0 && eniType == "secondary" {
+		l.enableIPv4Prefix = true
+		// the primary ip is still allocatable
+		l.cap = 1 + poolConfig.IPv4PrefixPerENI*ipv4PrefixSize
+	}
+
 	return l
 }
 
+// SetNetwork mark the eni belong to the pod network, the eni only serve the request of the network
+func (l *Local) SetNetwork(network *factory.NetworkInterfaceOptions) {
+	l.cond.L.Lock()
+	defer l.cond.L.Unlock()
+
+	l.network = network
+}
+
 // Run initialize the local eni
 func (l *Local) Run(ctx context.Context, podResources []daemon.PodResources, wg *sync.WaitGroup) error {
 	err := l.load(podResources)
@@ -214,6 +280,18 @@ func (l *Local) load(podResources []daemon.PodResources) error {
 	metric.ResourcePoolIdle.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv6)).Add(float64(len(ipv6)))
 	metric.ResourcePoolTotal.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv6)).Add(float64(len(ipv6)))
 
+	if l.enableIPv4Prefix {
+		prefixes, err := l.factory.LoadNetworkInterfacePrefix(l.eni.MAC)
+		if err != nil {
+			return err
+		}
+		logf.Log.Info("load eni prefix", "eni", l.eni.ID, "prefixes", prefixes)
+
+		l.ipv4.PutValidPrefix(prefixes...)
+		metric.ResourcePoolIdle.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv4)).Add(float64(len(prefixes) * ipv4PrefixSize))
+		metric.ResourcePoolTotal.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv4)).Add(float64(len(prefixes) * ipv4PrefixSize))
+	}
+
 	l.status = statusInUse
 
 	// allocate to previous pods
@@ -304,6 +382,8 @@ func (l *Local) load(podResources []daemon.PodResources) error {
 						continue
 					}
 					v.Allocate(podID)
+					v.reserved = res.Reserved
+					v.holder = res.ReservedHolder
 					metric.ResourcePoolIdle.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv4)).Dec()
 				}
 				if res.IPv6 != "" {
@@ -368,6 +448,17 @@ func (l *Local) sync() {
 		return
 	}
 
+	if l.enableIPv4Prefix {
+		prefixes, err := l.factory.LoadNetworkInterfacePrefix(l.eni.MAC)
+		if err != nil {
+			_ = tracing.RecordNodeEvent(corev1.EventTypeWarning, string(types.ErrOpenAPIErr), fmt.Sprintf("failed to sync eni prefix, %s", err.Error()))
+			return
+		}
+		for _, prefix := range prefixes {
+			ipv4 = append(ipv4, ip.PrefixAddrs(prefix)...)
+		}
+	}
+
 	syncIPLocked(l.ipv4, ipv4)
 	syncIPLocked(l.ipv6, ipv6)
 
@@ -400,6 +491,18 @@ func (l *Local) Allocate(ctx context.Context, cni *daemon.CNI, request ResourceR
 		return nil, []Trace{{Condition: NetworkInterfaceMismatch}}
 	}
 
+	if lo.ReservedIP != nil {
+		return l.allocateReservedLocked(ctx, cni, lo)
+	}
+
+	if !l.matchNetworkLocked(lo) {
+		return nil, []Trace{{Condition: NetworkInterfaceMismatch}}
+	}
+	if l.eni == nil && l.status == statusInit && l.allocatingV4 <= 0 && l.allocatingV6 <= 0 {
+		// the eni will be created in the network of the request
+		l.network = lo.Network
+	}
+
 	log := logf.FromContext(ctx)
 	log.Info(fmt.Sprintf("local request %v", lo))
 
@@ -462,6 +565,227 @@ func (l *Local) Allocate(ctx context.Context, cni *daemon.CNI, request ResourceR
 	return respCh, nil
 }
 
+// allocateReservedLocked allocate the reserved ip of the request, the ip is moved to the eni if it is not assigned yet.
+// An eni in the vSwitch of the reservation is created if the manager asks to.
+// Only the default network of ipv4 only stack is supported.
+func (l *Local) allocateReservedLocked(ctx context.Context, cni *daemon.CNI, lo *LocalIPRequest) (chan *AllocResp, []Trace) {
+	reserved := lo.ReservedIP
+	if l.eniType != "secondary" || l.enableIPv6 {
+		return nil, []Trace{{Condition: NetworkInterfaceMismatch}}
+	}
+	// the eni created for the reservation has the default security groups
+	if l.network != nil && (len(l.network.SecurityGroupIDs) > 0 || !sets.New[string](l.network.VSwitchOptions...).Has(reserved.VSwitchID)) {
+		return nil, []Trace{{Condition: NetworkInterfaceMismatch}}
+	}
+
+	log := logf.FromContext(ctx)
+
+	if l.eni == nil {
+		if !reserved.createENI || l.status != statusInit || l.allocatingV4 > 0 || l.allocatingV6 > 0 {
+			return nil, []Trace{{Condition: NetworkInterfaceMismatch}}
+		}
+		if l.ipAllocInhibitExpireAt.After(time.Now()) {
+			return nil, []Trace{{Condition: InsufficientVSwitchIP, Reason: fmt.Sprintf("alloc inhibit, expire at %s", l.ipAllocInhibitExpireAt.String())}}
+		}
+
+		// create the eni with the primary ip only, the reserved ip is moved to it once created
+		l.network = &factory.NetworkInterfaceOptions{VSwitchOptions: []string{reserved.VSwitchID}}
+		l.allocatingV4++
+		l.movingV4++
+		l.cond.Broadcast()
+
+		log.Info("local request reserved ip", "ipv4", reserved.IPv4.String(), "from", reserved.NetworkInterfaceID, "vsw", reserved.VSwitchID, "create", true)
+
+		respCh := make(chan *AllocResp)
+		go l.reservedAllocWorker(ctx, cni, lo, respCh, true)
+		return respCh, nil
+	}
+
+	if l.status != statusInUse || l.eni.VSwitchID != reserved.VSwitchID {
+		return nil, []Trace{{Condition: NetworkInterfaceMismatch}}
+	}
+
+	ipv4, ok := l.ipv4[reserved.IPv4]
+	if ok {
+		if ipv4.InUse() && ipv4.podID != cni.PodID {
+			return nil, []Trace{{Condition: NetworkInterfaceMismatch, Reason: fmt.Sprintf("reserved ip %s is used by %s", ipv4.ip, ipv4.podID)}}
+		}
+		if !ipv4.Valid() {
+			return nil, []Trace{{Condition: NetworkInterfaceMismatch, Reason: fmt.Sprintf("reserved ip %s is not valid", ipv4.ip)}}
+		}
+	} else {
+		if len(l.ipv4)+l.allocatingV4+l.movingV4 >= l.cap {
+			return nil, []Trace{{Condition: Full}}
+		}
+		l.movingV4++
+	}
+
+	log.Info("local request reserved ip", "ipv4", reserved.IPv4.String(), "from", reserved.NetworkInterfaceID, "move", !ok)
+
+	respCh := make(chan *AllocResp)
+
+	go l.reservedAllocWorker(ctx, cni, lo, respCh, !ok)
+
+	return respCh, nil
+}
+
+// reservedAllocWorker started with each Allocate call of reserved ip.
+// It waits for the eni created, and moves the ip to the eni if the ip is not on it.
+func (l *Local) reservedAllocWorker(ctx context.Context, cni *daemon.CNI, request *LocalIPRequest, respCh chan *AllocResp, move bool) {
+	done := make(chan struct{})
+	defer close(done)
+
+	l.cond.L.Lock()
+	defer l.cond.L.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			l.cond.L.Lock()
+			l.cond.Broadcast()
+			l.cond.L.Unlock()
+		case <-done:
+		}
+	}()
+
+	log := logf.FromContext(ctx)
+	reserved := request.ReservedIP
+
+	for l.eni == nil || l.status != statusInUse {
+		if ctx.Err() != nil {
+			if move {
+				l.movingV4 = max(l.movingV4-1, 0)
+			}
+			if l.eni == nil {
+				l.allocatingV4 = max(l.allocatingV4-1, 0)
+			}
+			close(respCh)
+			return
+		}
+		l.cond.Wait()
+	}
+
+	if _, ok := l.ipv4[reserved.IPv4]; ok && move {
+		// moved by another request of the pod
+		move = false
+		l.movingV4 = max(l.movingV4-1, 0)
+	}
+
+	if move {
+		eniID, mac := l.eni.ID, l.eni.MAC
+
+		l.cond.L.Unlock()
+		err := l.rateLimitv4.Wait(ctx)
+		if err == nil {
+			err = l.factory.MoveNIPv4(reserved.NetworkInterfaceID, eniID, []netip.Addr{reserved.IPv4}, mac)
+		}
+		l.cond.L.Lock()
+
+		l.movingV4 = max(l.movingV4-1, 0)
+
+		if err != nil {
+			log.Error(err, "move reserved ip failed", "ipv4", reserved.IPv4.String(), "from", reserved.NetworkInterfaceID)
+			l.cond.Broadcast()
+
+			select {
+			case <-ctx.Done():
+			case respCh <- &AllocResp{Err: err}:
+			}
+			return
+		}
+
+		l.ipv4.Add(NewValidIP(reserved.IPv4, false))
+		metric.ResourcePoolIdle.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv4)).Inc()
+		metric.ResourcePoolTotal.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv4)).Inc()
+
+		l.cond.Broadcast()
+	}
+
+	ipv4, ok := l.ipv4[reserved.IPv4]
+	if !ok || l.eni == nil {
+		select {
+		case <-ctx.Done():
+		case respCh <- &AllocResp{Err: fmt.Errorf("reserved ip %s not found", reserved.IPv4)}:
+		}
+		return
+	}
+	ipv4.reserved = true
+	ipv4.holder = reserved.Holder
+
+	res := &LocalIPResource{
+		ENI:      *l.eni,
+		IP:       types.IPSet2{IPv4: ipv4.ip},
+		IfName:   request.IfName,
+		Reserved: true,
+		Holder:   reserved.Holder,
+	}
+
+	log.Info("reservedAllocWorker got ip", "eni", l.eni.ID, "ipv4", ipv4.ip.String())
+
+	select {
+	case <-ctx.Done():
+		// the ip moved for the pod is not kept on the eni
+		if !ipv4.InUse() {
+			l.giveBackLocked(ctx, ipv4)
+		}
+	case respCh <- &AllocResp{NetworkConfigs: NetworkResources{res}}:
+		if !ipv4.InUse() && cni.PodID != "" {
+			metric.ResourcePoolIdle.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv4)).Dec()
+		}
+		ipv4.Allocate(cni.PodID)
+	}
+}
+
+// giveBackLocked give the reserved ip back to the holder eni, the ip is released if the holder is unknown.
+// The ip is moved by the factoryDisposeWorker.
+func (l *Local) giveBackLocked(ctx context.Context, ipv4 *IP) {
+	ipv4.Dispose()
+
+	metric.ResourcePoolIdle.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv4)).Dec()
+	metric.ResourcePoolTotal.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv4)).Dec()
+
+	logf.FromContext(ctx).Info("give back reserved ipv4", "ipv4", ipv4.ip.String(), "holder", ipv4.holder)
+	l.cond.Broadcast()
+}
+
+// matchNetworkLocked check whether the eni can serve the pod network of the request
+func (l *Local) matchNetworkLocked(lo *LocalIPRequest) bool {
+	if lo.Network != nil && l.eniType != "secondary" {
+		return false
+	}
+
+	if l.eni == nil {
+		if l.status == statusInit && l.allocatingV4 <= 0 && l.allocatingV6 <= 0 {
+			return true
+		}
+		// the eni is creating for a network
+		return networkEqual(l.network, lo.Network)
+	}
+
+	if lo.NetworkInterfaceID != "" {
+		// the ip is allocated before
+		return true
+	}
+	if lo.Network == nil {
+		return l.network == nil
+	}
+	if len(lo.Network.VSwitchOptions) > 0 && !sets.New[string](lo.Network.VSwitchOptions...).Has(l.eni.VSwitchID) {
+		return false
+	}
+	if len(lo.Network.SecurityGroupIDs) > 0 && !sets.New[string](l.eni.SecurityGroupIDs...).Equal(sets.New[string](lo.Network.SecurityGroupIDs...)) {
+		return false
+	}
+	return true
+}
+
+func networkEqual(a, b *factory.NetworkInterfaceOptions) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return sets.New[string](a.VSwitchOptions...).Equal(sets.New[string](b.VSwitchOptions...)) &&
+		sets.New[string](a.SecurityGroupIDs...).Equal(sets.New[string](b.SecurityGroupIDs...))
+}
+
 // Release take the cni Del request and release resource to pool
 func (l *Local) Release(ctx context.Context, cni *daemon.CNI, request NetworkResource) bool {
 	if request.ResourceType() != ResourceTypeLocalIP {
@@ -485,6 +809,11 @@ func (l *Local) Release(ctx context.Context, cni *daemon.CNI, request NetworkRes
 		metric.ResourcePoolIdle.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv4)).Inc()
 
 		log.Info("release ipv4", "ipv4", res.IP.IPv4)
+
+		// the reserved ip is given back, and held by the controller until the pod is back
+		if v, ok := l.ipv4[res.IP.IPv4]; ok && v.Reserved() && !v.InUse() {
+			l.giveBackLocked(ctx, v)
+		}
 	}
 	if res.IP.IPv6.IsValid() {
 		l.ipv6.Release(cni.PodID, res.IP.IPv6)
@@ -524,11 +853,62 @@ func (l *Local) Priority() int {
 	return prio
 }
 
+// startQueueWaitLocked start the span of waiting for the factory, the span of the allocWorker is queued,
+// so the factory batch is traced in it. The started span is returned as is.
+func (l *Local) startQueueWaitLocked(ctx context.Context, started trace.Span) trace.Span {
+	if started != nil {
+		return started
+	}
+	_, span := telemetry.Start(ctx, "Local.queueWait")
+	l.pendingSpans = append(l.pendingSpans, trace.SpanContextFromContext(ctx))
+	return span
+}
+
+// startBatchSpanLocked start the span of the factory batch, it is the child of the first queued allocWorker
+// and linked to the others.
+func (l *Local) startBatchSpanLocked(ctx context.Context) (context.Context, trace.Span) {
+	opts := []trace.SpanStartOption{trace.WithAttributes(
+		attribute.String("eniType", l.eniType),
+		attribute.Int("allocatingV4", l.allocatingV4),
+		attribute.Int("allocatingV6", l.allocatingV6))}
+	if l.eni != nil {
+		opts = append(opts, trace.WithAttributes(attribute.String("eni", l.eni.ID)))
+	}
+
+	var parent trace.SpanContext
+	for _, sc := range l.pendingSpans {
+		if !sc.IsValid() {
+			continue
+		}
+		if !parent.IsValid() {
+			parent = sc
+			continue
+		}
+		opts = append(opts, trace.WithLinks(trace.Link{SpanContext: sc}))
+	}
+	l.pendingSpans = nil
+
+	if parent.IsValid() {
+		ctx = trace.ContextWithSpanContext(ctx, parent)
+	}
+	return telemetry.Start(ctx, "Local.factoryAllocWorker", opts...)
+}
+
 // allocWorker started with each Allocate call
 func (l *Local) allocWorker(ctx context.Context, cni *daemon.CNI, request *LocalIPRequest, respCh chan *AllocResp, onErrLocked func()) {
 	done := make(chan struct{})
 	defer close(done)
 
+	ctx, span := telemetry.Start(ctx, "Local.allocWorker", trace.WithAttributes(attribute.String("pod", cni.PodID)))
+	defer span.End()
+	// queueWait is the span of waiting for the ip assigned by the factory
+	var queueWait trace.Span
+	defer func() {
+		if queueWait != nil {
+			queueWait.End()
+		}
+	}()
+
 	l.cond.L.Lock()
 	defer l.cond.L.Unlock()
 
@@ -549,6 +929,7 @@ func (l *Local) allocWorker(ctx context.Context, cni *daemon.CNI, request *Local
 			// parent cancel the context, so close the ch
 			onErrLocked()
 
+			span.SetStatus(codes.Error, ctx.Err().Error())
 			close(respCh)
 			return
 		default:
@@ -561,6 +942,7 @@ func (l *Local) allocWorker(ctx context.Context, cni *daemon.CNI, request *Local
 		if l.enableIPv4 {
 			ipv4 = l.ipv4.PeekAvailable(cni.PodID)
 			if ipv4 == nil {
+				queueWait = l.startQueueWaitLocked(ctx, queueWait)
 				l.cond.Wait()
 				continue
 			}
@@ -569,18 +951,28 @@ func (l *Local) allocWorker(ctx context.Context, cni *daemon.CNI, request *Local
 		if l.enableIPv6 {
 			ipv6 = l.ipv6.PeekAvailable(cni.PodID)
 			if ipv6 == nil {
+				queueWait = l.startQueueWaitLocked(ctx, queueWait)
 				l.cond.Wait()
 				continue
 			}
 			ip.IPv6 = ipv6.ip
 		}
+		if queueWait != nil {
+			queueWait.End()
+			queueWait = nil
+		}
 
-		resp.NetworkConfigs = append(resp.NetworkConfigs, &LocalIPResource{
+		res := &LocalIPResource{
 			ENI: *l.eni,
 			IP:  ip,
-		})
+		}
+		if request != nil {
+			res.IfName = request.IfName
+		}
+		resp.NetworkConfigs = append(resp.NetworkConfigs, res)
 
 		log.Info("allocWorker got ip", "eni", l.eni.ID, "ipv4", ip.IPv4.String(), "ipv6", ip.IPv6.String())
+		span.SetAttributes(attribute.String("eni", l.eni.ID), attribute.String("ipv4", ip.IPv4.String()), attribute.String("ipv6", ip.IPv6.String()))
 
 		select {
 		case <-ctx.Done():
@@ -643,142 +1035,200 @@ func (l *Local) factoryAllocWorker(ctx context.Context) {
 		time.Sleep(300 * time.Millisecond)
 		l.cond.L.Lock()
 
-		if l.eni == nil {
-			// create eni
-			v4Count := min(l.batchSize, max(l.allocatingV4, 1))
-			v6Count := min(l.batchSize, l.allocatingV6)
+		batchCtx, span := l.startBatchSpanLocked(ctx)
+		err := l.allocBatchLocked(batchCtx)
+		telemetry.End(span, err)
+		if err != nil {
+			continue
+		}
 
-			l.status = statusCreating
-			l.cond.L.Unlock()
+		l.cond.Broadcast()
+	}
+}
 
-			err := l.rateLimitEni.Wait(ctx)
-			if err != nil {
-				log.Error(err, "wait for rate limit failed")
-				l.cond.L.Lock()
-				continue
-			}
-			eni, ipv4Set, ipv6Set, err := l.factory.CreateNetworkInterface(v4Count, v6Count, l.eniType)
-			if err == nil {
-				err = setupENICompartment(eni)
-			}
+// allocBatchLocked create the eni or assign ips to it for the allocating requests.
+// The lock is released while calling the factory, the error is returned after the lock is held again.
+func (l *Local) allocBatchLocked(ctx context.Context) error {
+	log := logf.FromContext(ctx)
 
-			if err != nil {
-				log.Error(err, "create eni failed")
+	if l.eni == nil {
+		// create eni
+		v4Count := min(l.batchSize, max(l.allocatingV4, 1))
+		if l.enableIPv4Prefix {
+			// create eni with primary ip only, prefix is assigned later
+			v4Count = 1
+		}
+		v6Count := min(l.batchSize, l.allocatingV6)
 
-				l.cond.L.Lock()
-				l.errorHandleLocked(err)
+		network := l.network
+		l.status = statusCreating
+		l.cond.L.Unlock()
 
-				l.eni = eni
+		err := l.rateLimitEni.Wait(ctx)
+		if err != nil {
+			log.Error(err, "wait for rate limit failed")
+			l.cond.L.Lock()
+			return err
+		}
+		var (
+			eni              *daemon.ENI
+			ipv4Set, ipv6Set []netip.Addr
+		)
+		if network != nil {
+			eni, ipv4Set, ipv6Set, err = l.factory.CreateNetworkInterfaceWithOptions(ctx, v4Count, v6Count, l.eniType, network)
+		} else {
+			eni, ipv4Set, ipv6Set, err = l.factory.CreateNetworkInterface(ctx, v4Count, v6Count, l.eniType)
+		}
+		if err == nil {
+			err = setupENICompartment(eni)
+		}
 
-				// if create failed, mark eni as deleting
-				if eni != nil {
-					log.Info("mark eni as deleting", "eni", eni.ID)
-					l.status = statusDeleting
-					l.cond.Broadcast()
-				} else {
-					l.status = statusInit
-				}
-				continue
-			}
+		if err != nil {
+			log.Error(err, "create eni failed")
 
 			l.cond.L.Lock()
+			l.errorHandleLocked(err)
 
 			l.eni = eni
 
-			l.allocatingV4 -= v4Count
-			l.allocatingV6 -= v6Count
+			// if create failed, mark eni as deleting
+			if eni != nil {
+				log.Info("mark eni as deleting", "eni", eni.ID)
+				l.status = statusDeleting
+				l.cond.Broadcast()
+			} else {
+				l.status = statusInit
+			}
+			return err
+		}
 
-			l.allocatingV4 = max(l.allocatingV4, 0)
-			l.allocatingV6 = max(l.allocatingV6, 0)
+		l.cond.L.Lock()
 
-			primary, err := netip.ParseAddr(eni.PrimaryIP.IPv4.String())
-			if err == nil {
-				for _, v := range ipv4Set {
-					l.ipv4.Add(NewValidIP(v, netip.MustParseAddr(v.String()) == primary))
+		l.eni = eni
 
-					metric.ResourcePoolIdle.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv4)).Inc()
-					metric.ResourcePoolTotal.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv4)).Inc()
-				}
-			}
+		l.allocatingV4 -= v4Count
+		l.allocatingV6 -= v6Count
 
-			l.ipv6.PutValid(ipv6Set...)
+		l.allocatingV4 = max(l.allocatingV4, 0)
+		l.allocatingV6 = max(l.allocatingV6, 0)
 
-			metric.ResourcePoolIdle.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv6)).Add(float64(len(ipv6Set)))
-			metric.ResourcePoolTotal.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv6)).Add(float64(len(ipv6Set)))
+		primary, err := netip.ParseAddr(eni.PrimaryIP.IPv4.String())
+		if err == nil {
+			for _, v := range ipv4Set {
+				l.ipv4.Add(NewValidIP(v, netip.MustParseAddr(v.String()) == primary))
 
-			l.status = statusInUse
-		} else {
-			eniID := l.eni.ID
-			v4Count := min(l.batchSize, l.allocatingV4)
-			v6Count := min(l.batchSize, l.allocatingV6)
+				metric.ResourcePoolIdle.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv4)).Inc()
+				metric.ResourcePoolTotal.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv4)).Inc()
+			}
+		}
 
-			if v4Count > 0 {
-				l.cond.L.Unlock()
+		l.ipv6.PutValid(ipv6Set...)
 
-				err := l.rateLimitv4.Wait(ctx)
-				if err != nil {
-					log.Error(err, "wait for rate limit failed")
-					l.cond.L.Lock()
-					continue
-				}
-				ipv4Set, err := l.factory.AssignNIPv4(eniID, v4Count, l.eni.MAC)
+		metric.ResourcePoolIdle.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv6)).Add(float64(len(ipv6Set)))
+		metric.ResourcePoolTotal.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv6)).Add(float64(len(ipv6Set)))
+
+		l.status = statusInUse
+	} else {
+		eniID := l.eni.ID
+		v4Count := min(l.batchSize, l.allocatingV4)
+		v6Count := min(l.batchSize, l.allocatingV6)
 
+		if v4Count > 0 && l.enableIPv4Prefix {
+			l.cond.L.Unlock()
+
+			err := l.rateLimitv4.Wait(ctx)
+			if err != nil {
+				log.Error(err, "wait for rate limit failed")
 				l.cond.L.Lock()
+				return err
+			}
+			prefixCount := (v4Count + ipv4PrefixSize - 1) / ipv4PrefixSize
+			prefixes, err := l.factory.AssignIPv4Prefix(ctx, eniID, prefixCount, l.eni.MAC)
 
-				if err != nil {
-					log.Error(err, "assign ipv4 failed", "eni", eniID)
-					l.ipv4.PutDeleting(ipv4Set...)
+			l.cond.L.Lock()
 
-					l.errorHandleLocked(err)
+			if err != nil {
+				log.Error(err, "assign ipv4 prefix failed", "eni", eniID)
+				l.ipv4.PutDeletingPrefix(prefixes...)
 
-					continue
-				}
+				l.errorHandleLocked(err)
 
-				l.allocatingV4 -= len(ipv4Set)
-				l.allocatingV4 = max(l.allocatingV4, 0)
+				return err
+			}
 
-				l.ipv4.PutValid(ipv4Set...)
+			l.allocatingV4 -= len(prefixes) * ipv4PrefixSize
+			l.allocatingV4 = max(l.allocatingV4, 0)
+
+			l.ipv4.PutValidPrefix(prefixes...)
 
-				metric.ResourcePoolIdle.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv4)).Add(float64(len(ipv4Set)))
-				metric.ResourcePoolTotal.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv4)).Add(float64(len(ipv4Set)))
+			metric.ResourcePoolIdle.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv4)).Add(float64(len(prefixes) * ipv4PrefixSize))
+			metric.ResourcePoolTotal.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv4)).Add(float64(len(prefixes) * ipv4PrefixSize))
+		} else if v4Count > 0 {
+			l.cond.L.Unlock()
 
+			err := l.rateLimitv4.Wait(ctx)
+			if err != nil {
+				log.Error(err, "wait for rate limit failed")
+				l.cond.L.Lock()
+				return err
 			}
+			ipv4Set, err := l.factory.AssignNIPv4(ctx, eniID, v4Count, l.eni.MAC)
 
-			if v6Count > 0 {
-				l.cond.L.Unlock()
+			l.cond.L.Lock()
 
-				err := l.rateLimitv6.Wait(ctx)
-				if err != nil {
-					log.Error(err, "wait for rate limit failed")
-					l.cond.L.Lock()
-					continue
-				}
-				ipv6Set, err := l.factory.AssignNIPv6(eniID, v6Count, l.eni.MAC)
+			if err != nil {
+				log.Error(err, "assign ipv4 failed", "eni", eniID)
+				l.ipv4.PutDeleting(ipv4Set...)
 
-				l.cond.L.Lock()
+				l.errorHandleLocked(err)
 
-				if err != nil {
-					log.Error(err, "assign ipv6 failed", "eni", eniID)
+				return err
+			}
 
-					l.ipv6.PutDeleting(ipv6Set...)
+			l.allocatingV4 -= len(ipv4Set)
+			l.allocatingV4 = max(l.allocatingV4, 0)
 
-					l.errorHandleLocked(err)
+			l.ipv4.PutValid(ipv4Set...)
 
-					continue
-				}
+			metric.ResourcePoolIdle.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv4)).Add(float64(len(ipv4Set)))
+			metric.ResourcePoolTotal.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv4)).Add(float64(len(ipv4Set)))
 
-				l.allocatingV6 -= len(ipv6Set)
-				l.allocatingV6 = max(l.allocatingV6, 0)
+		}
 
-				l.ipv6.PutValid(ipv6Set...)
+		if v6Count > 0 {
+			l.cond.L.Unlock()
 
-				metric.ResourcePoolIdle.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv6)).Add(float64(len(ipv6Set)))
-				metric.ResourcePoolTotal.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv6)).Add(float64(len(ipv6Set)))
+			err := l.rateLimitv6.Wait(ctx)
+			if err != nil {
+				log.Error(err, "wait for rate limit failed")
+				l.cond.L.Lock()
+				return err
 			}
-		}
+			ipv6Set, err := l.factory.AssignNIPv6(ctx, eniID, v6Count, l.eni.MAC)
 
-		l.cond.Broadcast()
+			l.cond.L.Lock()
+
+			if err != nil {
+				log.Error(err, "assign ipv6 failed", "eni", eniID)
+
+				l.ipv6.PutDeleting(ipv6Set...)
+
+				l.errorHandleLocked(err)
+
+				return err
+			}
+
+			l.allocatingV6 -= len(ipv6Set)
+			l.allocatingV6 = max(l.allocatingV6, 0)
+
+			l.ipv6.PutValid(ipv6Set...)
+
+			metric.ResourcePoolIdle.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv6)).Add(float64(len(ipv6Set)))
+			metric.ResourcePoolTotal.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv6)).Add(float64(len(ipv6Set)))
+		}
 	}
+
+	return nil
 }
 
 func (l *Local) Dispose(n int) int {
@@ -821,11 +1271,22 @@ func (l *Local) Dispose(n int) int {
 	}
 
 	// 3. dispose idle
-	left := min(len(l.ipv4.Idles()), n)
+	disposedPrefix := 0
+	if l.enableIPv4Prefix {
+		disposedPrefix = l.disposeIPv4PrefixLocked(n)
+	}
+
+	idles := 0
+	for _, v := range l.ipv4.Idles() {
+		if !v.prefix.IsValid() {
+			idles++
+		}
+	}
+	left := min(idles, n-disposedPrefix)
 
 	for i := 0; i < left; i++ {
 		for _, v := range l.ipv4 {
-			if v.InUse() || v.Deleting() {
+			if v.InUse() || v.Deleting() || v.prefix.IsValid() {
 				continue
 			}
 			v.Dispose() // small problem for primary ip
@@ -851,7 +1312,29 @@ func (l *Local) Dispose(n int) int {
 		}
 	}
 
-	return max(left, left6)
+	return max(left+disposedPrefix, left6)
+}
+
+// disposeIPv4PrefixLocked mark the whole idle prefix as deleting.
+// Prefix is disposed only when all ip in it is idle and n is enough to hold the prefix.
+func (l *Local) disposeIPv4PrefixLocked(n int) int {
+	disposed := 0
+	for _, prefix := range l.ipv4.IdlePrefixes() {
+		if disposed+ipv4PrefixSize > n {
+			break
+		}
+
+		logf.Log.Info("dispose ipv4 prefix", "eni", l.eni.ID, "prefix", prefix.String())
+		for _, v := range l.ipv4.Prefixes()[prefix] {
+			v.Dispose()
+		}
+		disposed += ipv4PrefixSize
+
+		metric.ResourcePoolIdle.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv4)).Sub(ipv4PrefixSize)
+		metric.ResourcePoolTotal.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv4)).Sub(ipv4PrefixSize)
+		metric.ResourcePoolDisposed.WithLabelValues(metric.ResourcePoolTypeLocal, string(types.IPStackIPv4)).Add(ipv4PrefixSize)
+	}
+	return disposed
 }
 
 func (l *Local) factoryDisposeWorker(ctx context.Context) {
@@ -894,6 +1377,7 @@ func (l *Local) factoryDisposeWorker(ctx context.Context) {
 			}
 
 			l.eni = nil
+			l.network = nil
 			l.ipv4 = make(Set)
 			l.ipv6 = make(Set)
 			l.status = statusInit
@@ -905,12 +1389,38 @@ func (l *Local) factoryDisposeWorker(ctx context.Context) {
 
 		toDelete4 := l.ipv4.Deleting()
 		toDelete6 := l.ipv6.Deleting()
+		toGiveBack := l.ipv4.GivingBack()
+
+		var toDeletePrefix []netip.Prefix
+		if l.enableIPv4Prefix {
+			toDeletePrefix = l.ipv4.DeletingPrefixes()
+		}
 
-		if toDelete4 == nil && toDelete6 == nil {
+		if toDelete4 == nil && toDelete6 == nil && toDeletePrefix == nil && toGiveBack == nil {
 			l.cond.Wait()
 			continue
 		}
 
+		for holder, ips := range toGiveBack {
+			eniID := l.eni.ID
+			l.cond.L.Unlock()
+			// the holder eni is not attached to the node, the metadata is not checked
+			err := l.factory.MoveNIPv4(eniID, holder, ips, "")
+			l.cond.L.Lock()
+
+			if err != nil {
+				// release the ip, the controller holds it again
+				log.Error(err, "give back reserved ip failed", "holder", holder, "ips", ips)
+				for _, v := range ips {
+					if ip, ok := l.ipv4[v]; ok {
+						ip.holder = ""
+					}
+				}
+				continue
+			}
+			l.ipv4.Delete(ips...)
+		}
+
 		if len(toDelete4) > l.batchSize {
 			toDelete4 = toDelete4[:l.batchSize]
 		}
@@ -937,6 +1447,16 @@ func (l *Local) factoryDisposeWorker(ctx context.Context) {
 				l.ipv6.Delete(toDelete6...)
 			}
 		}
+
+		if len(toDeletePrefix) > 0 {
+			l.cond.L.Unlock()
+			err := l.factory.UnAssignIPv4Prefix(l.eni.ID, toDeletePrefix, l.eni.MAC)
+			l.cond.L.Lock()
+
+			if err == nil {
+				l.ipv4.DeletePrefix(toDeletePrefix...)
+			}
+		}
 	}
 }
 
@@ -971,7 +1491,11 @@ func (l *Local) Usage() (int, int, error) {
 	}
 
 	var idles, inUse int
-	if l.enableIPv4 {
+	if l.enableIPv4Prefix {
+		// ip in a deleting prefix is not counted as idle
+		idles = len(l.ipv4.Allocatable())
+		inUse = len(l.ipv4.InUse())
+	} else if l.enableIPv4 {
 		idles = len(l.ipv4.Idles())
 		inUse = len(l.ipv4.InUse())
 	} else if l.enableIPv6 {
@@ -981,6 +1505,66 @@ func (l *Local) Usage() (int, int, error) {
 	return idles, inUse, nil
 }
 
+// PoolKey return the key of the pool the eni belong to, the eni in a pod network is pooled by the security groups
+func (l *Local) PoolKey() string {
+	l.cond.L.Lock()
+	defer l.cond.L.Unlock()
+
+	if l.network == nil {
+		return ""
+	}
+	key := SecurityGroupKey(l.network.SecurityGroupIDs)
+	if len(l.network.VSwitchOptions) > 0 {
+		key += "@" + strings.Join(sets.List(sets.New[string](l.network.VSwitchOptions...)), ",")
+	}
+	return key
+}
+
+// Warm report whether the eni is ready (or being created) and has no ip in use
+func (l *Local) Warm() bool {
+	l.cond.L.Lock()
+	defer l.cond.L.Unlock()
+
+	if l.eniType != "secondary" {
+		return false
+	}
+
+	switch l.status {
+	case statusCreating:
+		return true
+	case statusInUse:
+		if l.eni.Trunk {
+			return false
+		}
+		return len(l.ipv4.InUse()) == 0 && len(l.ipv6.InUse()) == 0
+	}
+	return false
+}
+
+// Prewarm create the eni with primary ip only, no secondary ip is assigned.
+// Return false if the eni is already created or the alloc is inhibited.
+func (l *Local) Prewarm() bool {
+	l.cond.L.Lock()
+	defer l.cond.L.Unlock()
+
+	if l.eniType != "secondary" || l.eni != nil || l.status != statusInit {
+		return false
+	}
+	if l.allocatingV4 > 0 || l.allocatingV6 > 0 {
+		return false
+	}
+	if l.ipAllocInhibitExpireAt.After(time.Now()) {
+		return false
+	}
+
+	// the primary ip is always assigned when create the eni
+	l.allocatingV4 = 1
+	l.network = nil
+
+	l.cond.Broadcast()
+	return true
+}
+
 func (l *Local) Status() Status {
 	l.cond.L.Lock()
 	defer l.cond.L.Unlock()
@@ -9,9 +9,11 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"golang.org/x/time/rate"
 
 	"github.com/AliyunContainerService/terway/pkg/factory"
+	factorymocks "github.com/AliyunContainerService/terway/pkg/factory/mocks"
 	"github.com/AliyunContainerService/terway/types"
 	"github.com/AliyunContainerService/terway/types/daemon"
 )
@@ -266,6 +268,95 @@ func TestLocal_Allocate_ERDMA(t *testing.T) {
 	assert.Equal(t, ResourceTypeMismatch, resp[0].Condition)
 }
 
+func TestLocal_Allocate_ReservedIP(t *testing.T) {
+	f := factorymocks.NewFactory(t)
+	f.On("MoveNIPv4", "eni-holder", "eni-1", []netip.Addr{netip.MustParseAddr("192.0.2.10")}, "mac-1").Return(nil).Once()
+
+	local := NewLocalTest(&daemon.ENI{ID: "eni-1", MAC: "mac-1", VSwitchID: "vsw-1"}, f, &types.PoolConfig{MaxIPPerENI: 2, EnableIPv4: true}, "secondary")
+	local.status = statusInUse
+	local.ipv4.Add(NewValidIP(netip.MustParseAddr("192.0.2.1"), true))
+
+	cni := &daemon.CNI{PodID: "default/sts-0"}
+	request := &LocalIPRequest{ReservedIP: &ReservedIP{
+		IPv4:               netip.MustParseAddr("192.0.2.10"),
+		VSwitchID:          "vsw-2",
+		NetworkInterfaceID: "eni-holder",
+		Holder:             "eni-holder",
+	}}
+
+	_, traces := local.Allocate(context.Background(), cni, request)
+	assert.Equal(t, 1, len(traces))
+	assert.Equal(t, NetworkInterfaceMismatch, traces[0].Condition)
+
+	request.ReservedIP.VSwitchID = "vsw-1"
+	respCh, traces := local.Allocate(context.Background(), cni, request)
+	assert.Nil(t, traces)
+
+	resp := <-respCh
+	assert.NoError(t, resp.Err)
+	assert.Equal(t, 1, len(resp.NetworkConfigs))
+	res := resp.NetworkConfigs[0].(*LocalIPResource)
+	assert.True(t, res.Reserved)
+	assert.Equal(t, "192.0.2.10", res.IP.IPv4.String())
+
+	local.cond.L.Lock()
+	ip := local.ipv4[netip.MustParseAddr("192.0.2.10")]
+	assert.True(t, ip.InUse())
+	assert.True(t, ip.Reserved())
+	assert.Equal(t, 1, len(local.ipv4.Allocatable()))
+	local.cond.L.Unlock()
+
+	// the reserved ip is given back to the holder after release
+	local.Release(context.Background(), cni, res)
+	assert.True(t, local.ipv4[netip.MustParseAddr("192.0.2.10")].Deleting())
+	assert.Nil(t, local.ipv4.Deleting())
+
+	f.On("MoveNIPv4", "eni-1", "eni-holder", []netip.Addr{netip.MustParseAddr("192.0.2.10")}, "").Return(nil).Once()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go local.factoryDisposeWorker(ctx)
+
+	assert.Eventually(t, func() bool {
+		local.cond.L.Lock()
+		defer local.cond.L.Unlock()
+		_, ok := local.ipv4[netip.MustParseAddr("192.0.2.10")]
+		return !ok
+	}, 5*time.Second, 100*time.Millisecond)
+}
+
+func TestLocal_Allocate_ReservedIPCanceled(t *testing.T) {
+	moved := make(chan struct{})
+	f := factorymocks.NewFactory(t)
+	f.On("MoveNIPv4", "eni-holder", "eni-1", []netip.Addr{netip.MustParseAddr("192.0.2.10")}, "mac-1").Return(nil).Run(func(args mock.Arguments) {
+		close(moved)
+	}).Once()
+
+	local := NewLocalTest(&daemon.ENI{ID: "eni-1", MAC: "mac-1", VSwitchID: "vsw-1"}, f, &types.PoolConfig{MaxIPPerENI: 2, EnableIPv4: true}, "secondary")
+	local.status = statusInUse
+
+	request := &LocalIPRequest{ReservedIP: &ReservedIP{
+		IPv4:               netip.MustParseAddr("192.0.2.10"),
+		VSwitchID:          "vsw-1",
+		NetworkInterfaceID: "eni-holder",
+		Holder:             "eni-holder",
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, traces := local.Allocate(ctx, &daemon.CNI{PodID: "default/sts-0"}, request)
+	assert.Nil(t, traces)
+
+	// the pod is gone before the ip is taken
+	<-moved
+	cancel()
+
+	assert.Eventually(t, func() bool {
+		local.cond.L.Lock()
+		defer local.cond.L.Unlock()
+		return len(local.ipv4.GivingBack()["eni-holder"]) == 1 && local.movingV4 == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
 func TestLocal_Allocate_Inhibit(t *testing.T) {
 	local := NewLocalTest(&daemon.ENI{ID: "eni-1"}, nil, &types.PoolConfig{MaxIPPerENI: 2, EnableIPv4: true}, "")
 
@@ -309,3 +400,157 @@ func Test_parseResourceID(t *testing.T) {
 		})
 	}
 }
+
+func TestLocal_DisposeIPv4Prefix(t *testing.T) {
+	local := NewLocalTest(&daemon.ENI{ID: "eni-1"}, nil, &types.PoolConfig{EnableIPv4: true}, "secondary")
+	local.enableIPv4Prefix = true
+	local.status = statusInUse
+	local.ipv4.Add(NewValidIP(netip.MustParseAddr("192.0.2.1"), true))
+	local.ipv4[netip.MustParseAddr("192.0.2.1")].Allocate("pod-1")
+	local.ipv4.PutValidPrefix(netip.MustParsePrefix("192.0.2.16/28"), netip.MustParsePrefix("192.0.2.32/28"))
+	local.ipv4[netip.MustParseAddr("192.0.2.17")].Allocate("pod-2")
+
+	// not enough to release a whole prefix
+	n := local.Dispose(10)
+	assert.Equal(t, 0, n)
+	assert.Nil(t, local.ipv4.DeletingPrefixes())
+
+	n = local.Dispose(20)
+	assert.Equal(t, 16, n)
+	assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("192.0.2.32/28")}, local.ipv4.DeletingPrefixes())
+	assert.Nil(t, local.ipv4.Deleting())
+
+	idles, inUse, err := local.Usage()
+	assert.NoError(t, err)
+	assert.Equal(t, 15, idles)
+	assert.Equal(t, 2, inUse)
+}
+
+func TestLocal_FactoryAllocWorker_IPv4Prefix(t *testing.T) {
+	f := factorymocks.NewFactory(t)
+	f.On("AssignIPv4Prefix", mock.Anything, "eni-1", 1, "mac-1").Return([]netip.Prefix{netip.MustParsePrefix("192.0.2.16/28")}, nil).Once()
+
+	local := NewLocalTest(&daemon.ENI{ID: "eni-1", MAC: "mac-1"}, f, &types.PoolConfig{EnableIPv4: true, BatchSize: 10}, "secondary")
+	local.enableIPv4Prefix = true
+	local.cap = 1 + 16
+	local.status = statusInUse
+	local.allocatingV4 = 3
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go local.factoryAllocWorker(ctx)
+
+	assert.Eventually(t, func() bool {
+		local.cond.L.Lock()
+		defer local.cond.L.Unlock()
+		return len(local.ipv4) == 16 && local.allocatingV4 == 0
+	}, 5*time.Second, 100*time.Millisecond)
+}
+
+func TestLocal_Prewarm(t *testing.T) {
+	local := NewLocalTest(nil, nil, &types.PoolConfig{EnableIPv4: true}, "secondary")
+
+	assert.False(t, local.Warm())
+	assert.True(t, local.Prewarm())
+	assert.Equal(t, 1, local.allocatingV4)
+
+	// already warming
+	assert.False(t, local.Prewarm())
+
+	erdma := NewLocalTest(nil, nil, &types.PoolConfig{EnableIPv4: true}, "erdma")
+	assert.False(t, erdma.Prewarm())
+}
+
+func TestLocal_Warm(t *testing.T) {
+	local := NewLocalTest(&daemon.ENI{ID: "eni-1"}, nil, &types.PoolConfig{EnableIPv4: true}, "secondary")
+	local.status = statusInUse
+	local.ipv4.Add(NewValidIP(netip.MustParseAddr("192.0.2.1"), true))
+
+	assert.True(t, local.Warm())
+	assert.False(t, local.Prewarm())
+
+	local.ipv4[netip.MustParseAddr("192.0.2.1")].Allocate("pod-1")
+	assert.False(t, local.Warm())
+}
+
+func TestLocal_Allocate_Network(t *testing.T) {
+	network := &factory.NetworkInterfaceOptions{VSwitchOptions: []string{"vsw-2"}, SecurityGroupIDs: []string{"sg-2"}}
+	cni := &daemon.CNI{PodID: "pod-1"}
+
+	local := NewLocalTest(&daemon.ENI{ID: "eni-1", VSwitchID: "vsw-1", SecurityGroupIDs: []string{"sg-1"}}, nil, &types.PoolConfig{MaxIPPerENI: 2, EnableIPv4: true}, "secondary")
+	_, resp := local.Allocate(context.Background(), cni, &LocalIPRequest{NoCache: true, Network: network})
+	assert.Equal(t, 1, len(resp))
+	assert.Equal(t, NetworkInterfaceMismatch, resp[0].Condition)
+
+	// the eni in the network
+	local = NewLocalTest(&daemon.ENI{ID: "eni-2", VSwitchID: "vsw-2", SecurityGroupIDs: []string{"sg-2"}}, nil, &types.PoolConfig{MaxIPPerENI: 0, EnableIPv4: true}, "secondary")
+	local.SetNetwork(network)
+	_, resp = local.Allocate(context.Background(), cni, &LocalIPRequest{NoCache: true, Network: network})
+	assert.Equal(t, 1, len(resp))
+	assert.Equal(t, Full, resp[0].Condition)
+
+	// the default request is not served by the eni of the network
+	_, resp = local.Allocate(context.Background(), cni, &LocalIPRequest{NoCache: true})
+	assert.Equal(t, 1, len(resp))
+	assert.Equal(t, NetworkInterfaceMismatch, resp[0].Condition)
+
+	erdma := NewLocalTest(nil, nil, &types.PoolConfig{MaxIPPerENI: 2, EnableIPv4: true}, "erdma")
+	_, resp = erdma.Allocate(context.Background(), cni, &LocalIPRequest{NoCache: true, LocalIPType: LocalIPTypeERDMA, Network: network})
+	assert.Equal(t, 1, len(resp))
+	assert.Equal(t, NetworkInterfaceMismatch, resp[0].Condition)
+}
+
+func TestLocal_Allocate_NetworkCreating(t *testing.T) {
+	network := &factory.NetworkInterfaceOptions{VSwitchOptions: []string{"vsw-2"}}
+	cni := &daemon.CNI{PodID: "pod-1"}
+
+	local := NewLocalTest(nil, nil, &types.PoolConfig{MaxIPPerENI: 2, EnableIPv4: true}, "secondary")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, _ := local.Allocate(ctx, cni, &LocalIPRequest{Network: network})
+	assert.NotNil(t, ch)
+	assert.Equal(t, network, local.network)
+
+	// the eni is creating for the network
+	_, resp := local.Allocate(ctx, cni, &LocalIPRequest{})
+	assert.Equal(t, 1, len(resp))
+	assert.Equal(t, NetworkInterfaceMismatch, resp[0].Condition)
+}
+
+func TestLocal_FactoryAllocWorker_Network(t *testing.T) {
+	network := &factory.NetworkInterfaceOptions{VSwitchOptions: []string{"vsw-2"}}
+
+	f := factorymocks.NewFactory(t)
+	f.On("CreateNetworkInterfaceWithOptions", mock.Anything, 1, 0, "secondary", network).Return(&daemon.ENI{
+		ID:        "eni-2",
+		MAC:       "mac-2",
+		VSwitchID: "vsw-2",
+		PrimaryIP: types.IPSet{IPv4: netip.MustParseAddr("192.0.2.10").AsSlice()},
+	}, []netip.Addr{netip.MustParseAddr("192.0.2.10")}, nil, nil).Once()
+
+	local := NewLocalTest(nil, f, &types.PoolConfig{EnableIPv4: true, BatchSize: 10}, "secondary")
+	local.network = network
+	local.allocatingV4 = 1
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go local.factoryAllocWorker(ctx)
+
+	assert.Eventually(t, func() bool {
+		local.cond.L.Lock()
+		defer local.cond.L.Unlock()
+		return local.status == statusInUse && local.eni.ID == "eni-2"
+	}, 5*time.Second, 100*time.Millisecond)
+}
+
+func TestLocal_PoolKey(t *testing.T) {
+	local := NewLocalTest(&daemon.ENI{ID: "eni-1"}, nil, &types.PoolConfig{EnableIPv4: true}, "secondary")
+	assert.Equal(t, "", local.PoolKey())
+
+	local.SetNetwork(&factory.NetworkInterfaceOptions{SecurityGroupIDs: []string{"sg-2", "sg-1"}})
+	assert.Equal(t, "sg-1,sg-2", local.PoolKey())
+
+	local.SetNetwork(&factory.NetworkInterfaceOptions{VSwitchOptions: []string{"vsw-2", "vsw-1"}, SecurityGroupIDs: []string{"sg-1"}})
+	assert.Equal(t, "sg-1@vsw-1,vsw-2", local.PoolKey())
+}
@@ -4,16 +4,23 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/atomic"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
+	"github.com/AliyunContainerService/terway/pkg/factory"
 	"github.com/AliyunContainerService/terway/pkg/k8s"
+	"github.com/AliyunContainerService/terway/pkg/metric"
+	"github.com/AliyunContainerService/terway/pkg/telemetry"
 	"github.com/AliyunContainerService/terway/types"
 	"github.com/AliyunContainerService/terway/types/daemon"
 )
@@ -73,6 +80,21 @@ type Usage interface {
 type ReportStatus interface {
 	Status() Status
 }
+
+// Warmer is implemented by the NetworkInterface which can be created ahead of the pod
+type Warmer interface {
+	// Warm report the eni is created (or creating) and no ip is in use
+	Warm() bool
+	// Prewarm create the eni without secondary ip, return false if not able to do so
+	Prewarm() bool
+}
+
+// PoolMember is implemented by the NetworkInterface which may belong to a pool other than the default one
+type PoolMember interface {
+	// PoolKey return the key of the pool, empty for the default pool
+	PoolKey() string
+}
+
 type NetworkInterface interface {
 	Allocate(ctx context.Context, cni *daemon.CNI, request ResourceRequest) (chan *AllocResp, []Trace)
 	Release(ctx context.Context, cni *daemon.CNI, request NetworkResource) bool
@@ -102,6 +124,16 @@ type Manager struct {
 	maxIdles int
 	total    int
 
+	// warmENIs the eni count keep created without pod on it
+	warmENIs int
+	// minimumIPs the min ip count (idles and in use) the pool hold
+	minimumIPs int
+
+	// sgPools the idle targets of the eni pools in other security groups, keyed by SecurityGroupKey
+	sgPools map[string]*securityGroupPool
+	// reportedPools the keys of the security group pools the metrics are reported for
+	reportedPools sets.Set[string]
+
 	syncPeriod time.Duration
 
 	k8s k8s.Kubernetes
@@ -129,9 +161,26 @@ func (m *Manager) Run(ctx context.Context, wg *sync.WaitGroup, podResources []da
 	return nil
 }
 
+// securityGroupPool is the idle targets of the eni in the security groups
+type securityGroupPool struct {
+	securityGroupIDs []string
+
+	minIdles int
+	maxIdles int
+}
+
+// SecurityGroupKey return the pool key of the security groups
+func SecurityGroupKey(securityGroupIDs []string) string {
+	return strings.Join(sets.List(sets.New[string](securityGroupIDs...)), ",")
+}
+
 // Allocate find the resource manager and send the request to it.
 // Caller should roll back the allocated resource if any error happen.
 func (m *Manager) Allocate(ctx context.Context, cni *daemon.CNI, req *AllocRequest) (NetworkResources, error) {
+	ctx, span := telemetry.Start(ctx, "Manager.Allocate", trace.WithAttributes(
+		attribute.String("pod", cni.PodID),
+		attribute.Int("requests", len(req.ResourceRequests))))
+
 	result := make([]NetworkResource, 0, len(req.ResourceRequests))
 
 	resultCh := make(chan NetworkResources)
@@ -170,17 +219,30 @@ func (m *Manager) Allocate(ctx context.Context, cni *daemon.CNI, req *AllocReque
 	}
 
 	var err error
-	for _, request := range req.ResourceRequests {
-
-		var ch chan *AllocResp
+	// each request is served by a different network interface, so the pod networks are backed by different eni
+	used := make(map[NetworkInterface]struct{})
+	pick := func(request ResourceRequest) chan *AllocResp {
 		for _, ni := range m.networkInterfaces {
-			var tr []Trace
-			ch, tr = ni.Allocate(ctx, cni, request)
+			if _, ok := used[ni]; ok {
+				continue
+			}
+			ch, tr := ni.Allocate(ctx, cni, request)
 			if ch != nil {
-				break
+				used[ni] = struct{}{}
+				return ch
 			}
 			traces = append(traces, tr...)
 		}
+		return nil
+	}
+	for _, request := range req.ResourceRequests {
+
+		ch := pick(request)
+		if lo, ok := request.(*LocalIPRequest); ok && ch == nil && lo.ReservedIP != nil {
+			// no eni in the vSwitch of the reservation, create one for the reserved ip
+			lo.ReservedIP.createENI = true
+			ch = pick(request)
+		}
 
 		if ch == nil {
 			m.Unlock()
@@ -191,7 +253,9 @@ func (m *Manager) Allocate(ctx context.Context, cni *daemon.CNI, req *AllocReque
 					break
 				}
 			}
-			return nil, fmt.Errorf("no eni can handle the allocation")
+			err = fmt.Errorf("no eni can handle the allocation")
+			telemetry.End(span, err)
+			return nil, err
 		}
 
 		wg.Add(1)
@@ -229,6 +293,7 @@ func (m *Manager) Allocate(ctx context.Context, cni *daemon.CNI, req *AllocReque
 		err = ctx.Err()
 	}
 
+	telemetry.End(span, err)
 	return result, err
 }
 
@@ -279,41 +344,69 @@ func (m *Manager) syncPool(ctx context.Context) {
 		sort.Sort(sort.Reverse(ByPriority(m.networkInterfaces)))
 	}
 
-	var idles, inuses int
-	for _, ni := range m.networkInterfaces {
-		usage, ok := ni.(Usage)
-		if !ok {
-			continue
+	totalIdles, totalInuses := m.usageLocked(m.networkInterfaces)
+
+	toAdds := make(map[string]int)
+	reported := sets.New[string]()
+	for key, pool := range m.poolsLocked() {
+		nis := m.poolInterfacesLocked(key)
+		idles, inuses := m.usageLocked(nis)
+
+		if key != "" {
+			metric.SecurityGroupPoolIdle.WithLabelValues(key).Set(float64(idles))
+			metric.SecurityGroupPoolInUse.WithLabelValues(key).Set(float64(inuses))
+			reported.Insert(key)
 		}
-		idle, inuse, err := usage.Usage()
-		if err != nil {
-			mgrLog.Error(err, "sync pool error")
-			continue
+
+		minimumIPs, warmENIs := 0, 0
+		if key == "" {
+			minimumIPs, warmENIs = m.minimumIPs, m.warmENIs
 		}
-		idles += idle
-		inuses += inuse
-	}
 
-	toDel := idles - m.maxIdles
-	if toDel > 0 {
-		mgrLog.Info("sync pool", "toDel", toDel)
-		for _, ni := range m.networkInterfaces {
-			if toDel <= 0 {
-				break
+		toDel := idles - pool.maxIdles
+		if minimumIPs > 0 {
+			toDel = min(toDel, idles+inuses-minimumIPs)
+		}
+		if toDel > 0 {
+			mgrLog.Info("sync pool", "pool", key, "toDel", toDel)
+			// keep the warm eni untouched
+			protected := warmENIs
+			for _, ni := range nis {
+				if toDel <= 0 {
+					break
+				}
+				if w, ok := ni.(Warmer); ok && protected > 0 && w.Warm() {
+					protected--
+					continue
+				}
+				toDel -= ni.Dispose(toDel)
 			}
-			toDel -= ni.Dispose(toDel)
 		}
-	}
 
-	m.Unlock()
+		if warmENIs > 0 {
+			idles += m.prewarmLocked(nis)
+		}
 
-	if idles+inuses >= m.total {
-		return
+		toAdd := pool.minIdles - idles
+		if minimumIPs > 0 {
+			toAdd = max(toAdd, minimumIPs-idles-inuses)
+		}
+		if toAdd > 0 {
+			toAdds[key] = toAdd
+		}
 	}
 
-	toAdd := m.minIdles - idles
+	// the pool is removed from config and no eni left in it
+	for key := range m.reportedPools.Difference(reported) {
+		deleteSecurityGroupPoolMetrics(key)
+	}
+	m.reportedPools = reported
+
+	sgPools := m.sgPools
+
+	m.Unlock()
 
-	if toAdd <= 0 {
+	if totalIdles+totalInuses >= m.total || len(toAdds) == 0 {
 		return
 	}
 
@@ -322,28 +415,160 @@ func (m *Manager) syncPool(ctx context.Context) {
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
 	defer cancel()
 
-	mgrLog.Info("sync pool", "toAdd", toAdd)
+	for key, toAdd := range toAdds {
+		mgrLog.Info("sync pool", "pool", key, "toAdd", toAdd)
 
-	for i := 0; i < toAdd; i++ {
-		wg.Add(1)
+		var network *factory.NetworkInterfaceOptions
+		if pool, ok := sgPools[key]; ok {
+			network = &factory.NetworkInterfaceOptions{SecurityGroupIDs: pool.securityGroupIDs}
+		}
 
-		go func() {
-			defer wg.Done()
+		for i := 0; i < toAdd; i++ {
+			wg.Add(1)
 
-			_, err := m.Allocate(ctx, &daemon.CNI{}, &AllocRequest{
-				ResourceRequests: []ResourceRequest{
-					&LocalIPRequest{NoCache: true},
-				},
-			})
-			if err != nil {
-				mgrLog.Error(err, "sync pool error")
-			}
-		}()
+			go func() {
+				defer wg.Done()
+
+				_, err := m.Allocate(ctx, &daemon.CNI{}, &AllocRequest{
+					ResourceRequests: []ResourceRequest{
+						&LocalIPRequest{NoCache: true, Network: network},
+					},
+				})
+				if err != nil {
+					mgrLog.Error(err, "sync pool error")
+				}
+			}()
+		}
 	}
 
 	wg.Wait()
 }
 
+// poolsLocked return the idle targets of all the pools.
+// The pools in use but not configured are the eni of the podNetworking, they keep no idle ip.
+func (m *Manager) poolsLocked() map[string]*securityGroupPool {
+	pools := map[string]*securityGroupPool{
+		"": {minIdles: m.minIdles, maxIdles: m.maxIdles},
+	}
+	for key, pool := range m.sgPools {
+		pools[key] = pool
+	}
+	for _, ni := range m.networkInterfaces {
+		member, ok := ni.(PoolMember)
+		if !ok {
+			continue
+		}
+		key := member.PoolKey()
+		if _, ok = pools[key]; !ok {
+			pools[key] = &securityGroupPool{}
+		}
+	}
+	return pools
+}
+
+// poolInterfacesLocked return the network interfaces belong to the pool
+func (m *Manager) poolInterfacesLocked(key string) []NetworkInterface {
+	var result []NetworkInterface
+	for _, ni := range m.networkInterfaces {
+		poolKey := ""
+		if member, ok := ni.(PoolMember); ok {
+			poolKey = member.PoolKey()
+		}
+		if poolKey == key {
+			result = append(result, ni)
+		}
+	}
+	return result
+}
+
+// Usage return the idle and in use ip count of the pool, and the capacity left
+func (m *Manager) Usage() (int, int, int) {
+	m.RLock()
+	defer m.RUnlock()
+
+	idles, inuses := m.usageLocked(m.networkInterfaces)
+	return idles, inuses, max(m.total-idles-inuses, 0)
+}
+
+func (m *Manager) usageLocked(nis []NetworkInterface) (int, int) {
+	var idles, inuses int
+	for _, ni := range nis {
+		usage, ok := ni.(Usage)
+		if !ok {
+			continue
+		}
+		idle, inuse, err := usage.Usage()
+		if err != nil {
+			mgrLog.Error(err, "sync pool error")
+			continue
+		}
+		idles += idle
+		inuses += inuse
+	}
+	return idles, inuses
+}
+
+// prewarmLocked create eni until the warm eni count reach the target.
+// Return the count of eni going to be created.
+func (m *Manager) prewarmLocked(nis []NetworkInterface) int {
+	warms := 0
+	for _, ni := range nis {
+		if w, ok := ni.(Warmer); ok && w.Warm() {
+			warms++
+		}
+	}
+
+	created := 0
+	for _, ni := range nis {
+		if warms >= m.warmENIs {
+			break
+		}
+		w, ok := ni.(Warmer)
+		if !ok {
+			continue
+		}
+		if w.Prewarm() {
+			warms++
+			created++
+		}
+	}
+	if created > 0 {
+		mgrLog.Info("sync pool", "prewarm", created)
+	}
+	return created
+}
+
+// SetWarmTarget set the warm eni count and the min ip count the pool should hold.
+// Should be called before Run.
+func (m *Manager) SetWarmTarget(warmENIs, minimumIPs int) {
+	m.warmENIs = warmENIs
+	m.minimumIPs = minimumIPs
+}
+
+// SetSecurityGroupPools set the idle targets of the eni pools in other security groups.
+// Should be called before Run.
+func (m *Manager) SetSecurityGroupPools(pools []types.SecurityGroupPoolConfig) {
+	sgPools := make(map[string]*securityGroupPool, len(pools))
+	for _, pool := range pools {
+		sgPools[SecurityGroupKey(pool.SecurityGroupIDs)] = &securityGroupPool{
+			securityGroupIDs: pool.SecurityGroupIDs,
+			minIdles:         pool.MinPoolSize,
+			maxIdles:         pool.MaxPoolSize,
+		}
+	}
+	for key := range m.sgPools {
+		if _, ok := sgPools[key]; !ok {
+			deleteSecurityGroupPoolMetrics(key)
+		}
+	}
+	m.sgPools = sgPools
+}
+
+func deleteSecurityGroupPoolMetrics(key string) {
+	metric.SecurityGroupPoolIdle.DeleteLabelValues(key)
+	metric.SecurityGroupPoolInUse.DeleteLabelValues(key)
+}
+
 func NewManager(minIdles, maxIdles, total int, syncPeriod time.Duration, networkInterfaces []NetworkInterface, selectionPolicy types.EniSelectionPolicy, k8s k8s.Kubernetes) *Manager {
 	if syncPeriod < 2*time.Minute && syncPeriod > 0 {
 		syncPeriod = 2 * time.Minute
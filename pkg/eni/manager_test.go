@@ -8,10 +8,21 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+	"github.com/AliyunContainerService/terway/pkg/factory"
+	factorymocks "github.com/AliyunContainerService/terway/pkg/factory/mocks"
+	"github.com/AliyunContainerService/terway/pkg/metric"
 	"github.com/AliyunContainerService/terway/types"
 	"github.com/AliyunContainerService/terway/types/daemon"
 )
@@ -160,6 +171,11 @@ func (f *FakeK8s) GetLocalPods() ([]*daemon.PodInfo, error) {
 	panic("implement me")
 }
 
+func (f *FakeK8s) WatchPodQoS(ctx context.Context, handler func(pod *daemon.PodInfo)) {
+	//TODO implement me
+	panic("implement me")
+}
+
 func (f *FakeK8s) GetPod(ctx context.Context, namespace, name string, cache bool) (*daemon.PodInfo, error) {
 	//TODO implement me
 	panic("implement me")
@@ -230,6 +246,11 @@ func (f *FakeK8s) GetTrunkID() string {
 	panic("implement me")
 }
 
+func (f *FakeK8s) GetPodNetworking(ctx context.Context, name string) (*v1beta1.PodNetworking, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
 func (f *FakeK8s) GetClient() client.Client {
 	//TODO implement me
 	panic("implement me")
@@ -238,3 +259,181 @@ func (f *FakeK8s) GetClient() client.Client {
 func (f *FakeK8s) PodExist(namespace, name string) (bool, error) {
 	panic("implement me")
 }
+
+func TestManagerSyncPoolWarmENI(t *testing.T) {
+	warm := NewLocalTest(&daemon.ENI{ID: "eni-1"}, nil, &types.PoolConfig{EnableIPv4: true}, "secondary")
+	warm.status = statusInUse
+	warm.ipv4.Add(NewValidIP(netip.MustParseAddr("192.0.2.1"), true))
+
+	idle := NewLocalTest(&daemon.ENI{ID: "eni-2"}, nil, &types.PoolConfig{EnableIPv4: true}, "secondary")
+	idle.status = statusInUse
+	idle.ipv4.Add(NewValidIP(netip.MustParseAddr("192.0.2.2"), true))
+
+	empty := NewLocalTest(nil, nil, &types.PoolConfig{EnableIPv4: true}, "secondary")
+
+	manager := NewManager(0, 0, 10, 0, []NetworkInterface{warm, idle, empty}, types.EniSelectionPolicyMostIPs, &FakeK8s{})
+	manager.SetWarmTarget(1, 0)
+
+	manager.syncPool(context.Background())
+
+	// only one warm eni is kept
+	kept := 0
+	for _, l := range []*Local{warm, idle} {
+		if l.status == statusInUse {
+			kept++
+		}
+	}
+	assert.Equal(t, 1, kept)
+	assert.Equal(t, 0, empty.allocatingV4)
+
+	manager.SetWarmTarget(2, 0)
+
+	manager.syncPool(context.Background())
+
+	// new eni is going to be created
+	assert.Equal(t, 1, empty.allocatingV4)
+}
+
+func TestManagerSyncPoolMinimumIPs(t *testing.T) {
+	local := NewLocalTest(&daemon.ENI{ID: "eni-1"}, nil, &types.PoolConfig{EnableIPv4: true}, "secondary")
+	local.status = statusInUse
+	local.ipv4.Add(NewValidIP(netip.MustParseAddr("192.0.2.1"), true))
+	local.ipv4.Add(NewValidIP(netip.MustParseAddr("192.0.2.2"), false))
+
+	manager := NewManager(0, 0, 10, 0, []NetworkInterface{local}, types.EniSelectionPolicyMostIPs, &FakeK8s{})
+	manager.SetWarmTarget(0, 2)
+
+	manager.syncPool(context.Background())
+
+	assert.Equal(t, statusInUse, local.status)
+	assert.Equal(t, 2, len(local.ipv4.Idles()))
+}
+
+func TestManagerSyncPoolSecurityGroup(t *testing.T) {
+	network := &factory.NetworkInterfaceOptions{SecurityGroupIDs: []string{"sg-2", "sg-1"}}
+
+	sg := NewLocalTest(&daemon.ENI{ID: "eni-1", SecurityGroupIDs: []string{"sg-1", "sg-2"}}, nil, &types.PoolConfig{EnableIPv4: true}, "secondary")
+	sg.status = statusInUse
+	sg.network = network
+	sg.ipv4.Add(NewValidIP(netip.MustParseAddr("192.0.2.1"), true))
+	sg.ipv4.Add(NewValidIP(netip.MustParseAddr("192.0.2.2"), false))
+	sg.ipv4.Add(NewValidIP(netip.MustParseAddr("192.0.2.3"), false))
+	sg.ipv4[netip.MustParseAddr("192.0.2.1")].Allocate("pod-1")
+
+	local := NewLocalTest(&daemon.ENI{ID: "eni-2"}, nil, &types.PoolConfig{EnableIPv4: true}, "secondary")
+	local.status = statusInUse
+	local.ipv4.Add(NewValidIP(netip.MustParseAddr("192.0.2.10"), true))
+	local.ipv4.Add(NewValidIP(netip.MustParseAddr("192.0.2.11"), false))
+
+	manager := NewManager(0, 5, 10, 0, []NetworkInterface{sg, local}, types.EniSelectionPolicyMostIPs, &FakeK8s{})
+	manager.SetSecurityGroupPools([]types.SecurityGroupPoolConfig{
+		{SecurityGroupIDs: []string{"sg-1", "sg-2"}, MaxPoolSize: 1},
+	})
+
+	assert.Equal(t, "sg-1,sg-2", sg.PoolKey())
+	assert.Equal(t, "", local.PoolKey())
+
+	manager.syncPool(context.Background())
+
+	// the idle of the security group pool is disposed to its max, the default pool is untouched
+	deleting := func(l *Local) int {
+		n := 0
+		for _, v := range l.ipv4 {
+			if v.Deleting() {
+				n++
+			}
+		}
+		return n
+	}
+	assert.Equal(t, 1, deleting(sg))
+	assert.Equal(t, 0, deleting(local))
+	assert.Equal(t, 1, testutil.CollectAndCount(metric.SecurityGroupPoolIdle))
+
+	// the eni is gone and the pool is removed from config, the metrics of the pool is removed
+	manager.networkInterfaces = []NetworkInterface{local}
+	manager.SetSecurityGroupPools(nil)
+	manager.syncPool(context.Background())
+	assert.Equal(t, 0, testutil.CollectAndCount(metric.SecurityGroupPoolIdle))
+	assert.Equal(t, 0, testutil.CollectAndCount(metric.SecurityGroupPoolInUse))
+}
+
+func TestManagerAllocateTrace(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+	f := factorymocks.NewFactory(t)
+	f.On("AssignNIPv4", mock.Anything, "eni-1", 1, "mac-1").Return([]netip.Addr{netip.MustParseAddr("192.0.2.1")}, nil).Once()
+
+	local := NewLocalTest(&daemon.ENI{ID: "eni-1", MAC: "mac-1"}, f, &types.PoolConfig{EnableIPv4: true, BatchSize: 10, MaxIPPerENI: 10}, "secondary")
+	local.status = statusInUse
+	manager := NewManager(0, 0, 0, 0, []NetworkInterface{local}, types.EniSelectionPolicyMostIPs, &FakeK8s{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go local.factoryAllocWorker(ctx)
+
+	ctx, span := otel.Tracer("test").Start(ctx, "AllocIP")
+	resources, err := manager.Allocate(ctx, &daemon.CNI{PodID: "default/pod-1"}, &AllocRequest{
+		ResourceRequests: []ResourceRequest{&LocalIPRequest{}},
+	})
+	span.End()
+	assert.NoError(t, err)
+	assert.Len(t, resources, 1)
+
+	spans := map[string]tracetest.SpanStub{}
+	assert.Eventually(t, func() bool {
+		for _, s := range exporter.GetSpans() {
+			spans[s.Name] = s
+		}
+		return len(spans) == 5
+	}, 5*time.Second, 100*time.Millisecond)
+
+	traceID := span.SpanContext().TraceID()
+	for _, s := range spans {
+		assert.Equal(t, traceID, s.SpanContext.TraceID(), s.Name)
+	}
+	assert.Equal(t, spans["AllocIP"].SpanContext.SpanID(), spans["Manager.Allocate"].Parent.SpanID())
+	assert.Equal(t, spans["Manager.Allocate"].SpanContext.SpanID(), spans["Local.allocWorker"].Parent.SpanID())
+	assert.Equal(t, spans["Local.allocWorker"].SpanContext.SpanID(), spans["Local.queueWait"].Parent.SpanID())
+	// the factory batch is traced in the waiting allocWorker
+	assert.Equal(t, spans["Local.allocWorker"].SpanContext.SpanID(), spans["Local.factoryAllocWorker"].Parent.SpanID())
+	assert.Contains(t, spans["Local.allocWorker"].Attributes, attribute.String("ipv4", "192.0.2.1"))
+}
+
+func TestManager_Allocate_ReservedIPCreateENI(t *testing.T) {
+	network := &factory.NetworkInterfaceOptions{VSwitchOptions: []string{"vsw-2"}}
+
+	f := factorymocks.NewFactory(t)
+	f.On("CreateNetworkInterfaceWithOptions", mock.Anything, 1, 0, "secondary", network).Return(&daemon.ENI{
+		ID:        "eni-2",
+		MAC:       "mac-2",
+		VSwitchID: "vsw-2",
+		PrimaryIP: types.IPSet{IPv4: netip.MustParseAddr("192.0.2.20").AsSlice()},
+	}, []netip.Addr{netip.MustParseAddr("192.0.2.20")}, nil, nil).Once()
+	f.On("MoveNIPv4", "eni-holder", "eni-2", []netip.Addr{netip.MustParseAddr("192.0.2.21")}, "mac-2").Return(nil).Once()
+
+	inUse := NewLocalTest(&daemon.ENI{ID: "eni-1", MAC: "mac-1", VSwitchID: "vsw-1"}, f, &types.PoolConfig{EnableIPv4: true, BatchSize: 10, MaxIPPerENI: 10}, "secondary")
+	inUse.status = statusInUse
+	inUse.ipv4.Add(NewValidIP(netip.MustParseAddr("192.0.2.1"), true))
+	empty := NewLocalTest(nil, f, &types.PoolConfig{EnableIPv4: true, BatchSize: 10, MaxIPPerENI: 10}, "secondary")
+	manager := NewManager(0, 0, 0, 0, []NetworkInterface{inUse, empty}, types.EniSelectionPolicyMostIPs, &FakeK8s{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go empty.factoryAllocWorker(ctx)
+
+	resources, err := manager.Allocate(ctx, &daemon.CNI{PodID: "default/sts-0"}, &AllocRequest{
+		ResourceRequests: []ResourceRequest{&LocalIPRequest{ReservedIP: &ReservedIP{
+			IPv4:               netip.MustParseAddr("192.0.2.21"),
+			VSwitchID:          "vsw-2",
+			NetworkInterfaceID: "eni-holder",
+			Holder:             "eni-holder",
+		}}},
+	})
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+	res := resources[0].(*LocalIPResource)
+	assert.Equal(t, "eni-2", res.ENI.ID)
+	assert.Equal(t, "192.0.2.21", res.IP.IPv4.String())
+	assert.Equal(t, "eni-holder", res.Holder)
+}
@@ -4,6 +4,7 @@ import (
 	"net/netip"
 	"time"
 
+	"github.com/AliyunContainerService/terway/pkg/ip"
 	"github.com/AliyunContainerService/terway/rpc"
 	"github.com/AliyunContainerService/terway/types/daemon"
 )
@@ -24,8 +25,16 @@ type IP struct {
 	ip      netip.Addr
 	primary bool
 
+	// prefix is the ipv4 prefix this ip is carved from, not valid for secondary ip
+	prefix netip.Prefix
+
 	podID string
 
+	// reserved ip is only for the pod it is reserved for, and is given back once released
+	reserved bool
+	// holder is the eni the reserved ip is given back to, the ip is released if empty
+	holder string
+
 	status ipStatus
 }
 
@@ -56,6 +65,10 @@ func (ip *IP) Primary() bool {
 	return ip.primary
 }
 
+func (ip *IP) Prefix() netip.Prefix {
+	return ip.prefix
+}
+
 func (ip *IP) Valid() bool {
 	return ip.status == ipStatusValid
 }
@@ -90,8 +103,12 @@ func (ip *IP) SetInvalid() {
 	ip.status = ipStatusInvalid
 }
 
+func (ip *IP) Reserved() bool {
+	return ip.reserved
+}
+
 func (ip *IP) Allocatable() bool {
-	return ip.Valid() && !ip.InUse()
+	return ip.Valid() && !ip.InUse() && !ip.reserved
 }
 
 type Set map[any]*IP
@@ -158,6 +175,81 @@ func (s Set) PutDeleting(ip ...netip.Addr) {
 	}
 }
 
+// PutValidPrefix add all ip in the prefix to the set
+func (s Set) PutValidPrefix(prefixes ...netip.Prefix) {
+	for _, prefix := range prefixes {
+		for _, v := range ip.PrefixAddrs(prefix) {
+			s[v] = &IP{ip: v, prefix: prefix, status: ipStatusValid}
+		}
+	}
+}
+
+// PutDeletingPrefix add all ip in the prefix to the set and mark as deleting
+func (s Set) PutDeletingPrefix(prefixes ...netip.Prefix) {
+	for _, prefix := range prefixes {
+		for _, v := range ip.PrefixAddrs(prefix) {
+			s[v] = &IP{ip: v, prefix: prefix, status: ipStatusDeleting}
+		}
+	}
+}
+
+// DeletePrefix remove all ip belong to the prefix
+func (s Set) DeletePrefix(prefixes ...netip.Prefix) {
+	for _, prefix := range prefixes {
+		for _, v := range ip.PrefixAddrs(prefix) {
+			delete(s, v)
+		}
+	}
+}
+
+// Prefixes return ips grouped by the prefix they belong to
+func (s Set) Prefixes() map[netip.Prefix][]*IP {
+	result := make(map[netip.Prefix][]*IP)
+	for _, v := range s {
+		if !v.prefix.IsValid() {
+			continue
+		}
+		result[v.prefix] = append(result[v.prefix], v)
+	}
+	return result
+}
+
+// IdlePrefixes return prefixes that every ip is valid and not in use
+func (s Set) IdlePrefixes() []netip.Prefix {
+	var result []netip.Prefix
+	for prefix, ips := range s.Prefixes() {
+		idle := true
+		for _, v := range ips {
+			if !v.Allocatable() {
+				idle = false
+				break
+			}
+		}
+		if idle {
+			result = append(result, prefix)
+		}
+	}
+	return result
+}
+
+// DeletingPrefixes return prefixes that every ip is marked as deleting
+func (s Set) DeletingPrefixes() []netip.Prefix {
+	var result []netip.Prefix
+	for prefix, ips := range s.Prefixes() {
+		deleting := true
+		for _, v := range ips {
+			if !v.Deleting() {
+				deleting = false
+				break
+			}
+		}
+		if deleting {
+			result = append(result, prefix)
+		}
+	}
+	return result
+}
+
 func (s Set) Delete(ip ...netip.Addr) {
 	for _, v := range ip {
 		delete(s, v)
@@ -171,16 +263,32 @@ func (s Set) Release(podID string, ip netip.Addr) {
 	}
 }
 
+// Deleting return the secondary ip need to be deleted, ip belong to prefix or given back to the holder is excluded
 func (s Set) Deleting() []netip.Addr {
 	var result []netip.Addr
 	for _, v := range s {
-		if v.Deleting() {
+		if v.Deleting() && !v.prefix.IsValid() && v.holder == "" {
 			result = append(result, v.ip)
 		}
 	}
 	return result
 }
 
+// GivingBack return the reserved ip need to be given back, grouped by the holder eni
+func (s Set) GivingBack() map[string][]netip.Addr {
+	var result map[string][]netip.Addr
+	for _, v := range s {
+		if !v.Deleting() || v.holder == "" {
+			continue
+		}
+		if result == nil {
+			result = make(map[string][]netip.Addr)
+		}
+		result[v.holder] = append(result[v.holder], v.ip)
+	}
+	return result
+}
+
 func (s Set) ByPodID(podID string) *IP {
 	for _, v := range s {
 		if v.podID == podID {
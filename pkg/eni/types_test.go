@@ -103,3 +103,42 @@ func TestSet_Allocatable(t *testing.T) {
 		})
 	}
 }
+
+func TestSet_Prefix(t *testing.T) {
+	prefix1 := netip.MustParsePrefix("192.0.2.16/28")
+	prefix2 := netip.MustParsePrefix("192.0.2.32/28")
+
+	s := Set{}
+	s.PutValid(netip.MustParseAddr("192.0.2.1"))
+	s.PutValidPrefix(prefix1, prefix2)
+
+	if len(s) != 33 {
+		t.Fatalf("PutValidPrefix() len = %d, want 33", len(s))
+	}
+	if len(s.Prefixes()) != 2 {
+		t.Fatalf("Prefixes() len = %d, want 2", len(s.Prefixes()))
+	}
+
+	s[netip.MustParseAddr("192.0.2.17")].Allocate("pod-1")
+	idle := s.IdlePrefixes()
+	if !reflect.DeepEqual(idle, []netip.Prefix{prefix2}) {
+		t.Errorf("IdlePrefixes() = %v, want %v", idle, []netip.Prefix{prefix2})
+	}
+
+	for _, v := range s.Prefixes()[prefix2] {
+		v.Dispose()
+	}
+	s[netip.MustParseAddr("192.0.2.1")].Dispose()
+
+	if !reflect.DeepEqual(s.DeletingPrefixes(), []netip.Prefix{prefix2}) {
+		t.Errorf("DeletingPrefixes() = %v, want %v", s.DeletingPrefixes(), []netip.Prefix{prefix2})
+	}
+	if !reflect.DeepEqual(s.Deleting(), []netip.Addr{netip.MustParseAddr("192.0.2.1")}) {
+		t.Errorf("Deleting() = %v, prefix ip should be excluded", s.Deleting())
+	}
+
+	s.DeletePrefix(prefix2)
+	if len(s) != 17 {
+		t.Errorf("DeletePrefix() len = %d, want 17", len(s))
+	}
+}
@@ -49,10 +49,6 @@ var _ NetworkResource = &VethResource{}
 
 var _ NetworkInterface = &Veth{}
 
-const (
-	defaultPrefix = "cali"
-)
-
 type Veth struct{}
 
 func (r *Veth) Allocate(ctx context.Context, cni *daemon.CNI, request ResourceRequest) (chan *AllocResp, []Trace) {
@@ -62,7 +58,7 @@ func (r *Veth) Allocate(ctx context.Context, cni *daemon.CNI, request ResourceRe
 	ch := make(chan *AllocResp)
 
 	go func() {
-		name, _ := link.VethNameForPod(cni.PodName, cni.PodNamespace, "", defaultPrefix)
+		name, _ := link.VethNameForPod(cni.PodName, cni.PodNamespace, "", link.HostVethPrefix)
 		var nfs []NetworkResource
 		nfs = append(nfs, &VethResource{Name: name})
 
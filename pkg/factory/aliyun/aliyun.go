@@ -6,6 +6,8 @@ import (
 	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog/v2"
@@ -16,6 +18,8 @@ import (
 	"github.com/AliyunContainerService/terway/pkg/aliyun/metadata"
 	"github.com/AliyunContainerService/terway/pkg/backoff"
 	"github.com/AliyunContainerService/terway/pkg/factory"
+	"github.com/AliyunContainerService/terway/pkg/ip"
+	"github.com/AliyunContainerService/terway/pkg/telemetry"
 	vswpool "github.com/AliyunContainerService/terway/pkg/vswitch"
 	"github.com/AliyunContainerService/terway/types"
 	"github.com/AliyunContainerService/terway/types/daemon"
@@ -28,6 +32,12 @@ const (
 
 var _ factory.Factory = &Aliyun{}
 
+// OpenAPI is the openAPI used by the factory, it is *client.OpenAPI or an emulator in the test
+type OpenAPI interface {
+	client.ECS
+	client.VPC
+}
+
 // Aliyun the local eni factory impl for aliyun.
 type Aliyun struct {
 	ctx context.Context
@@ -37,14 +47,14 @@ type Aliyun struct {
 	instanceID string
 	zoneID     string
 
-	openAPI interface {
-		client.ECS
-		client.VPC
-	}
-	getter eni.ENIInfoGetter
+	openAPI OpenAPI
+	getter  eni.ENIInfoGetter
 
 	vsw             *vswpool.SwitchPool
 	selectionPolicy vswpool.SelectionPolicy
+	// for the weighted and prefer-cidr selection policy
+	vSwitchWeights     map[string]int
+	vSwitchPreferCIDRs []netip.Prefix
 
 	vSwitchOptions   []string
 	securityGroupIDs []string
@@ -56,7 +66,7 @@ type Aliyun struct {
 	eniTagFilter map[string]string
 }
 
-func NewAliyun(ctx context.Context, openAPI *client.OpenAPI, getter eni.ENIInfoGetter, vsw *vswpool.SwitchPool, cfg *types.ENIConfig) *Aliyun {
+func NewAliyun(ctx context.Context, openAPI OpenAPI, getter eni.ENIInfoGetter, vsw *vswpool.SwitchPool, cfg *types.ENIConfig) *Aliyun {
 
 	return &Aliyun{
 		ctx:              ctx,
@@ -73,13 +83,28 @@ func NewAliyun(ctx context.Context, openAPI *client.OpenAPI, getter eni.ENIInfoG
 		eniTags:          cfg.ENITags,
 		eniTypeAttr:      cfg.EniTypeAttr,
 		selectionPolicy:  cfg.VSwitchSelectionPolicy,
+
+		vSwitchWeights:     cfg.VSwitchWeights,
+		vSwitchPreferCIDRs: cfg.VSwitchPreferCIDRs,
 	}
 }
 
-func (a *Aliyun) CreateNetworkInterface(ipv4, ipv6 int, eniType string) (*daemon.ENI, []netip.Addr, []netip.Addr, error) {
-	ctx, cancel := context.WithTimeout(a.ctx, time.Second*60)
+func (a *Aliyun) CreateNetworkInterface(ctx context.Context, ipv4, ipv6 int, eniType string) (*daemon.ENI, []netip.Addr, []netip.Addr, error) {
+	return a.CreateNetworkInterfaceWithOptions(ctx, ipv4, ipv6, eniType, nil)
+}
+
+func (a *Aliyun) CreateNetworkInterfaceWithOptions(ctx context.Context, ipv4, ipv6 int, eniType string, opts *factory.NetworkInterfaceOptions) (*daemon.ENI, []netip.Addr, []netip.Addr, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*60)
 	defer cancel()
 
+	vSwitchOptions, securityGroupIDs := a.vSwitchOptions, a.securityGroupIDs
+	if opts != nil && len(opts.VSwitchOptions) > 0 {
+		vSwitchOptions = opts.VSwitchOptions
+	}
+	if opts != nil && len(opts.SecurityGroupIDs) > 0 {
+		securityGroupIDs = opts.SecurityGroupIDs
+	}
+
 	// 1. create eni
 	var eni *client.NetworkInterface
 	var vswID string
@@ -93,21 +118,21 @@ func (a *Aliyun) CreateNetworkInterface(ipv4, ipv6 int, eniType string) (*daemon
 	if strings.ToLower(eniType) == "erdma" {
 		erdma = true
 	}
-	err := wait.ExponentialBackoffWithContext(a.ctx, backoff.Backoff(backoff.ENICreate), func(ctx context.Context) (bool, error) {
-		vsw, innerErr := a.vsw.GetOne(ctx, a.openAPI, a.zoneID, a.vSwitchOptions, &vswpool.SelectOptions{
-			VSwitchSelectPolicy: a.selectionPolicy,
-		})
+	err := wait.ExponentialBackoffWithContext(ctx, backoff.Backoff(backoff.ENICreate), func(ctx context.Context) (bool, error) {
+		vsw, innerErr := a.vsw.GetOne(ctx, a.openAPI, a.zoneID, vSwitchOptions, a.selectOptions())
 		if innerErr != nil {
 			return false, innerErr
 		}
 		vswID = vsw.ID
+		klog.V(4).Infof("vSwitch %s selected, %s", vsw.ID, vsw.Reason)
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("vswitch.reason", vsw.Reason))
 
 		bo := backoff.Backoff(backoff.ENICreate)
 		option := &client.CreateNetworkInterfaceOptions{
 			NetworkInterfaceOptions: &client.NetworkInterfaceOptions{
 				Trunk:            trunk,
 				ERDMA:            erdma,
-				SecurityGroupIDs: a.securityGroupIDs,
+				SecurityGroupIDs: securityGroupIDs,
 				IPv6Count:        ipv6,
 				IPCount:          ipv4,
 				VSwitchID:        vswID,
@@ -134,11 +159,12 @@ func (a *Aliyun) CreateNetworkInterface(ipv4, ipv6 int, eniType string) (*daemon
 	}
 
 	r := &daemon.ENI{
-		ID:        eni.NetworkInterfaceID,
-		MAC:       eni.MacAddress,
-		VSwitchID: eni.VSwitchID,
-		Trunk:     trunk,
-		ERdma:     erdma,
+		ID:               eni.NetworkInterfaceID,
+		MAC:              eni.MacAddress,
+		SecurityGroupIDs: eni.SecurityGroupIDs,
+		VSwitchID:        eni.VSwitchID,
+		Trunk:            trunk,
+		ERdma:            erdma,
 	}
 
 	r.PrimaryIP.SetIP(eni.PrivateIPAddress)
@@ -231,7 +257,7 @@ func (a *Aliyun) CreateNetworkInterface(ipv4, ipv6 int, eniType string) (*daemon
 	return r, v4Set, v6Set, nil
 }
 
-func (a *Aliyun) AssignNIPv4(eniID string, count int, mac string) ([]netip.Addr, error) {
+func (a *Aliyun) AssignNIPv4(ctx context.Context, eniID string, count int, mac string) ([]netip.Addr, error) {
 	// 1. assign ip
 	var ips []netip.Addr
 	var err error
@@ -244,14 +270,14 @@ func (a *Aliyun) AssignNIPv4(eniID string, count int, mac string) ([]netip.Addr,
 			IPCount:            count,
 		}}
 
-	ips, err = a.openAPI.AssignPrivateIPAddress(a.ctx, option)
+	ips, err = a.openAPI.AssignPrivateIPAddress(ctx, option)
 	if err != nil {
 		return nil, err
 	}
 
 	// 2. wait ip ready in metadata
 	// TODO: support rollback single ip
-	err = validateIPInMetadata(a.ctx, ips, func() []netip.Addr {
+	err = validateIPInMetadata(ctx, ips, func() []netip.Addr {
 		exists, err := metadata.GetIPv4ByMac(mac)
 		if err != nil {
 			klog.Errorf("metadata: error get eni private ip: %v", err)
@@ -262,7 +288,7 @@ func (a *Aliyun) AssignNIPv4(eniID string, count int, mac string) ([]netip.Addr,
 	return ips, err
 }
 
-func (a *Aliyun) AssignNIPv6(eniID string, count int, mac string) ([]netip.Addr, error) {
+func (a *Aliyun) AssignNIPv6(ctx context.Context, eniID string, count int, mac string) ([]netip.Addr, error) {
 	// 1. assign ip
 	var ips []netip.Addr
 	var err error
@@ -275,14 +301,14 @@ func (a *Aliyun) AssignNIPv6(eniID string, count int, mac string) ([]netip.Addr,
 			IPv6Count:          count,
 		}}
 
-	ips, err = a.openAPI.AssignIpv6Addresses(a.ctx, option)
+	ips, err = a.openAPI.AssignIpv6Addresses(ctx, option)
 	if err != nil {
 		return nil, err
 	}
 
 	// 2. wait ip ready in metadata
 	// TODO: support rollback single ip
-	err = validateIPInMetadata(a.ctx, ips, func() []netip.Addr {
+	err = validateIPInMetadata(ctx, ips, func() []netip.Addr {
 		exists, err := metadata.GetIPv6ByMac(mac)
 		if err != nil {
 			klog.Errorf("metadata: error get eni private ip: %v", err)
@@ -327,6 +353,55 @@ func (a *Aliyun) UnAssignNIPv4(eniID string, ips []netip.Addr, mac string) error
 	return err
 }
 
+// MoveNIPv4 unassign the ips from the source eni and assign them to the eni.
+// The source eni may be attached to another instance, so the metadata is only checked for the eni,
+// and is not checked if the mac is empty as the eni is not attached to the node.
+func (a *Aliyun) MoveNIPv4(fromENIID, eniID string, ips []netip.Addr, mac string) error {
+	if fromENIID != "" && fromENIID != eniID {
+		var innerErr error
+		err := wait.ExponentialBackoffWithContext(a.ctx, backoff.Backoff(backoff.ENIIPOps), func(ctx context.Context) (bool, error) {
+			innerErr = a.openAPI.UnAssignPrivateIPAddresses(ctx, fromENIID, ips)
+			if innerErr != nil {
+				if apiErr.ErrAssert(apiErr.ErrForbidden, innerErr) {
+					return true, innerErr
+				}
+				return false, nil
+			}
+			return true, nil
+		})
+		if err != nil {
+			if innerErr != nil {
+				return innerErr
+			}
+			return err
+		}
+	}
+
+	bo := backoff.Backoff(backoff.ENIIPOps)
+	option := &client.AssignPrivateIPAddressOptions{
+		Backoff: &bo,
+		NetworkInterfaceOptions: &client.NetworkInterfaceOptions{
+			NetworkInterfaceID: eniID,
+			PrivateIPAddress:   ip.IPAddrs2str(ips),
+		}}
+
+	_, err := a.openAPI.AssignPrivateIPAddress(a.ctx, option)
+	if err != nil {
+		return err
+	}
+	if mac == "" {
+		return nil
+	}
+
+	return validateIPInMetadata(a.ctx, ips, func() []netip.Addr {
+		exists, err := metadata.GetIPv4ByMac(mac)
+		if err != nil {
+			klog.Errorf("metadata: error get eni private ip: %v", err)
+		}
+		return exists
+	})
+}
+
 func (a *Aliyun) UnAssignNIPv6(eniID string, ips []netip.Addr, mac string) error {
 	var err, innerErr error
 
@@ -361,6 +436,67 @@ func (a *Aliyun) UnAssignNIPv6(eniID string, ips []netip.Addr, mac string) error
 	return err
 }
 
+func (a *Aliyun) AssignIPv4Prefix(ctx context.Context, eniID string, count int, mac string) ([]netip.Prefix, error) {
+	// 1. assign prefix
+	bo := backoff.Backoff(backoff.ENIIPOps)
+	option := &client.AssignPrivateIPAddressOptions{
+		Backoff: &bo,
+		NetworkInterfaceOptions: &client.NetworkInterfaceOptions{
+			NetworkInterfaceID: eniID,
+			IPv4PrefixCount:    count,
+		}}
+
+	prefixes, err := a.openAPI.AssignPrivateIPv4Prefix(ctx, option)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. wait prefix ready in metadata
+	err = validatePrefixInMetadata(ctx, prefixes, func() []netip.Prefix {
+		exists, err := metadata.GetIPv4PrefixByMac(mac)
+		if err != nil {
+			klog.Errorf("metadata: error get eni ipv4 prefix: %v", err)
+		}
+		return exists
+	})
+
+	return prefixes, err
+}
+
+func (a *Aliyun) UnAssignIPv4Prefix(eniID string, prefixes []netip.Prefix, mac string) error {
+	var err, innerErr error
+
+	err = wait.ExponentialBackoffWithContext(a.ctx, backoff.Backoff(backoff.ENIIPOps), func(ctx context.Context) (bool, error) {
+		innerErr = a.openAPI.UnAssignPrivateIPv4Prefix(ctx, eniID, prefixes)
+		if innerErr != nil {
+			if apiErr.ErrAssert(apiErr.ErrForbidden, innerErr) {
+				return true, innerErr
+			}
+			return false, nil
+		}
+		return true, nil
+	})
+
+	if err != nil {
+		if innerErr != nil {
+			return innerErr
+		}
+		return err
+	}
+
+	err = validatePrefixNotInMetadata(a.ctx, prefixes, func() []netip.Prefix {
+		var exists []netip.Prefix
+		exists, innerErr = metadata.GetIPv4PrefixByMac(mac)
+		if innerErr != nil {
+			klog.Errorf("metadata: error get eni ipv4 prefix: %v", innerErr)
+			return prefixes
+		}
+		return exists
+	})
+
+	return err
+}
+
 func (a *Aliyun) DeleteNetworkInterface(eniID string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
 	defer cancel()
@@ -390,6 +526,13 @@ func (a *Aliyun) LoadNetworkInterface(mac string) (ipv4Set []netip.Addr, ipv6Set
 	return
 }
 
+func (a *Aliyun) LoadNetworkInterfacePrefix(mac string) ([]netip.Prefix, error) {
+	if !a.enableIPv4 {
+		return nil, nil
+	}
+	return metadata.GetIPv4PrefixByMac(mac)
+}
+
 func (a *Aliyun) GetAttachedNetworkInterface(trunkENIID string) ([]*daemon.ENI, error) {
 	enis, err := a.getter.GetENIs(false)
 	if err != nil {
@@ -443,6 +586,7 @@ func (a *Aliyun) GetAttachedNetworkInterface(trunkENIID string) ([]*daemon.ENI,
 			}
 			e.Trunk = eni.Type == client.ENITypeTrunk
 			e.ERdma = eni.NetworkInterfaceTrafficMode == client.ENITrafficModeRDMA
+			e.SecurityGroupIDs = eni.SecurityGroupIDs
 
 			// take to intersect
 			result = append(result, e)
@@ -454,17 +598,74 @@ func (a *Aliyun) GetAttachedNetworkInterface(trunkENIID string) ([]*daemon.ENI,
 	return result, nil
 }
 
+// selectOptions the options to pick the vSwitch, the eni count of each vSwitch on the node is read from metadata
+// for the least-used-by-node policy
+func (a *Aliyun) selectOptions() *vswpool.SelectOptions {
+	opts := &vswpool.SelectOptions{
+		VSwitchSelectPolicy: a.selectionPolicy,
+		Weights:             a.vSwitchWeights,
+		PreferCIDRs:         a.vSwitchPreferCIDRs,
+	}
+	if a.selectionPolicy != vswpool.VSwitchSelectionPolicyLeastUsedByNode {
+		return opts
+	}
+
+	macs, err := metadata.GetENIsMAC()
+	if err != nil {
+		klog.Errorf("metadata: error get eni macs: %v", err)
+		return opts
+	}
+	opts.NodeUsage = make(map[string]int, len(macs))
+	for _, mac := range macs {
+		id, err := metadata.GetENIVSwitchID(mac)
+		if err != nil {
+			klog.Errorf("metadata: error get vSwitch of eni %s: %v", mac, err)
+			continue
+		}
+		opts.NodeUsage[id]++
+	}
+	return opts
+}
+
 func validateIPInMetadata(ctx context.Context, expect []netip.Addr, getExist func() []netip.Addr) error {
-	return wait.PollUntilContextTimeout(ctx, metadataPollInterval, metadataWaitTimeout, false, func(ctx context.Context) (bool, error) {
+	return pollMetadata(ctx, "WaitIPReady", func() bool {
 		exists := getExist()
-		return sets.New[netip.Addr](exists...).HasAll(expect...), nil
+		return sets.New[netip.Addr](exists...).HasAll(expect...)
 	})
 }
 
 func validateIPNotInMetadata(ctx context.Context, gone []netip.Addr, getExist func() []netip.Addr) error {
-	return wait.PollUntilContextTimeout(ctx, metadataPollInterval, metadataWaitTimeout, false, func(ctx context.Context) (bool, error) {
+	return pollMetadata(ctx, "WaitIPGone", func() bool {
+		exists := getExist()
+
+		return !sets.New[netip.Addr](exists...).HasAny(gone...)
+	})
+}
+
+func validatePrefixInMetadata(ctx context.Context, expect []netip.Prefix, getExist func() []netip.Prefix) error {
+	return pollMetadata(ctx, "WaitPrefixReady", func() bool {
+		exists := getExist()
+		return sets.New[netip.Prefix](exists...).HasAll(expect...)
+	})
+}
+
+func validatePrefixNotInMetadata(ctx context.Context, gone []netip.Prefix, getExist func() []netip.Prefix) error {
+	return pollMetadata(ctx, "WaitPrefixGone", func() bool {
 		exists := getExist()
 
-		return !sets.New[netip.Addr](exists...).HasAny(gone...), nil
+		return !sets.New[netip.Prefix](exists...).HasAny(gone...)
 	})
 }
+
+// pollMetadata wait until the condition is met, the polls are traced as a span of ctx
+func pollMetadata(ctx context.Context, name string, condition func() bool) error {
+	ctx, span := telemetry.Start(ctx, "metadata "+name)
+	polls := 0
+	err := wait.PollUntilContextTimeout(ctx, metadataPollInterval, metadataWaitTimeout, false, func(ctx context.Context) (bool, error) {
+		polls++
+		return condition(), nil
+	})
+	span.SetAttributes(attribute.Int("polls", polls))
+	telemetry.End(span, err)
+	return err
+}
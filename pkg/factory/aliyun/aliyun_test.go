@@ -0,0 +1,126 @@
+//go:build default_build
+
+package aliyun
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/ecs"
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/vpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun/client"
+	"github.com/AliyunContainerService/terway/pkg/aliyun/client/emulator"
+	apiErr "github.com/AliyunContainerService/terway/pkg/aliyun/client/errors"
+	"github.com/AliyunContainerService/terway/pkg/backoff"
+	vswpool "github.com/AliyunContainerService/terway/pkg/vswitch"
+	"github.com/AliyunContainerService/terway/types"
+)
+
+// newTestAliyun return the factory backed by the emulator, the metadata is served by the emulator too
+func newTestAliyun(t *testing.T, cfg emulator.Config, vSwitches ...vpc.VSwitch) (*Aliyun, *emulator.Emulator) {
+	backoff.OverrideBackoff(map[string]wait.Backoff{
+		backoff.ENICreate: {Duration: 10 * time.Millisecond, Factor: 1, Steps: 2},
+		backoff.ENIIPOps:  {Duration: 10 * time.Millisecond, Factor: 1, Steps: 3},
+	})
+
+	e := emulator.New(cfg)
+	for _, vsw := range vSwitches {
+		require.NoError(t, e.AddVSwitch(vsw))
+	}
+	e.AddInstanceType(ecs.InstanceType{
+		InstanceTypeId:              "ecs.g7.large",
+		EniQuantity:                 3,
+		EniTotalQuantity:            3,
+		EniPrivateIpAddressQuantity: 10,
+	})
+	_, err := e.AddInstance("i-1", "ecs.g7.large", vSwitches[0].VSwitchId)
+	require.NoError(t, err)
+
+	transport := http.DefaultClient.Transport
+	http.DefaultClient.Transport = e.MetadataTransport("i-1")
+	t.Cleanup(func() {
+		http.DefaultClient.Transport = transport
+	})
+
+	var ids []string
+	for _, vsw := range vSwitches {
+		ids = append(ids, vsw.VSwitchId)
+	}
+	pool, err := vswpool.NewSwitchPool(100, "10m")
+	require.NoError(t, err)
+
+	return NewAliyun(context.Background(), e, nil, pool, &types.ENIConfig{
+		ZoneID:           "zone-a",
+		VSwitchOptions:   ids,
+		SecurityGroupIDs: []string{"sg-1"},
+		InstanceID:       "i-1",
+		EnableIPv4:       true,
+	}), e
+}
+
+func TestAliyun_ENILifecycle(t *testing.T) {
+	a, e := newTestAliyun(t, emulator.Config{
+		StatusDelay:   100 * time.Millisecond,
+		MetadataDelay: 500 * time.Millisecond,
+	}, vpc.VSwitch{VSwitchId: "vsw-1", ZoneId: "zone-a", CidrBlock: "192.168.0.0/24"})
+	ctx := context.Background()
+
+	eni, v4, _, err := a.CreateNetworkInterface(ctx, 2, 0, "secondary")
+	require.NoError(t, err)
+	assert.Len(t, v4, 2)
+	assert.Equal(t, "192.168.0.0/24", eni.VSwitchCIDR.IPv4.String())
+	assert.Equal(t, "192.168.0.253", eni.GatewayIP.IPv4.String())
+
+	got, ok := e.ENI(eni.ID)
+	require.True(t, ok)
+	assert.Equal(t, client.ENIStatusInUse, got.Status)
+	assert.Equal(t, "i-1", got.InstanceID)
+
+	// throttled request is retried by the client
+	e.Inject("AssignPrivateIpAddresses", emulator.ThrottlingError())
+	ips, err := a.AssignNIPv4(ctx, eni.ID, 3, eni.MAC)
+	require.NoError(t, err)
+	assert.Len(t, ips, 3)
+	assert.Equal(t, 2, e.Calls("AssignPrivateIpAddresses"))
+
+	require.NoError(t, a.UnAssignNIPv4(eni.ID, ips, eni.MAC))
+	got, _ = e.ENI(eni.ID)
+	assert.Len(t, got.PrivateIPSets, 2)
+
+	require.NoError(t, a.DeleteNetworkInterface(eni.ID))
+	_, ok = e.ENI(eni.ID)
+	assert.False(t, ok)
+}
+
+func TestAliyun_VSwitchIPNotEnough(t *testing.T) {
+	a, e := newTestAliyun(t, emulator.Config{},
+		vpc.VSwitch{VSwitchId: "vsw-1", ZoneId: "zone-a", CidrBlock: "192.168.0.0/29"},
+		vpc.VSwitch{VSwitchId: "vsw-2", ZoneId: "zone-a", CidrBlock: "192.168.1.0/24"})
+	ctx := context.Background()
+
+	eni, _, _, err := a.CreateNetworkInterface(ctx, 1, 0, "secondary")
+	require.NoError(t, err)
+	assert.Equal(t, "vsw-1", eni.VSwitchID)
+
+	// the rest ip of vsw-1 is used by others, the cached vSwitch is stale
+	_, err = e.CreateNetworkInterface(ctx, &client.CreateNetworkInterfaceOptions{
+		NetworkInterfaceOptions: &client.NetworkInterfaceOptions{VSwitchID: "vsw-1", SecurityGroupIDs: []string{"sg-1"}, IPCount: 2},
+	})
+	require.NoError(t, err)
+
+	_, err = a.AssignNIPv4(ctx, eni.ID, 1, eni.MAC)
+	assert.True(t, apiErr.ErrorCodeIs(err, apiErr.InvalidVSwitchIDIPNotEnough), err)
+
+	// vsw-1 is blocked and the next is used
+	eni, _, _, err = a.CreateNetworkInterface(ctx, 1, 0, "secondary")
+	require.NoError(t, err)
+	assert.Equal(t, "vsw-2", eni.VSwitchID)
+	// 2 created by the factory, 1 by others and 1 failed on vsw-1
+	assert.Equal(t, 4, e.Calls("CreateNetworkInterface"))
+}
@@ -35,6 +35,9 @@ type Eflo struct {
 	resourceGroupID  string
 	vsw              *vswpool.SwitchPool
 	selectionPolicy  vswpool.SelectionPolicy
+
+	vSwitchWeights     map[string]int
+	vSwitchPreferCIDRs []netip.Prefix
 }
 
 func NewEflo(ctx context.Context, openAPI *client.OpenAPI, vsw *vswpool.SwitchPool, cfg *types.ENIConfig) *Eflo {
@@ -50,29 +53,47 @@ func NewEflo(ctx context.Context, openAPI *client.OpenAPI, vsw *vswpool.SwitchPo
 		securityGroupIDs: cfg.SecurityGroupIDs,
 		resourceGroupID:  cfg.ResourceGroupID,
 		selectionPolicy:  cfg.VSwitchSelectionPolicy,
+
+		vSwitchWeights:     cfg.VSwitchWeights,
+		vSwitchPreferCIDRs: cfg.VSwitchPreferCIDRs,
 	}
 }
 
-func (p *Eflo) CreateNetworkInterface(ipv4, ipv6 int, eniType string) (*daemon.ENI, []netip.Addr, []netip.Addr, error) {
-	ctx, cancel := context.WithTimeout(p.ctx, time.Second*60)
+func (p *Eflo) CreateNetworkInterface(ctx context.Context, ipv4, ipv6 int, eniType string) (*daemon.ENI, []netip.Addr, []netip.Addr, error) {
+	return p.CreateNetworkInterfaceWithOptions(ctx, ipv4, ipv6, eniType, nil)
+}
+
+func (p *Eflo) CreateNetworkInterfaceWithOptions(ctx context.Context, ipv4, ipv6 int, eniType string, opts *factory.NetworkInterfaceOptions) (*daemon.ENI, []netip.Addr, []netip.Addr, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*60)
 	defer cancel()
 
-	vsw, innerErr := p.vsw.GetOne(ctx, p.api, p.zoneID, p.vSwitchOptions, &vswpool.SelectOptions{
+	vSwitchOptions, securityGroupIDs := p.vSwitchOptions, p.securityGroupIDs
+	if opts != nil && len(opts.VSwitchOptions) > 0 {
+		vSwitchOptions = opts.VSwitchOptions
+	}
+	if opts != nil && len(opts.SecurityGroupIDs) > 0 {
+		securityGroupIDs = opts.SecurityGroupIDs
+	}
+
+	vsw, innerErr := p.vsw.GetOne(ctx, p.api, p.zoneID, vSwitchOptions, &vswpool.SelectOptions{
 		VSwitchSelectPolicy: p.selectionPolicy,
+		Weights:             p.vSwitchWeights,
+		PreferCIDRs:         p.vSwitchPreferCIDRs,
 	})
 	if innerErr != nil {
 		return nil, nil, nil, innerErr
 	}
 
-	klog.Infof("CreateNetworkInterface %s %s %s %s", p.zoneID, p.instanceID, vsw.ID, p.securityGroupIDs[0])
+	klog.Infof("CreateNetworkInterface %s %s %s %s", p.zoneID, p.instanceID, vsw.ID, securityGroupIDs[0])
 
-	_, eniID, err := p.api.CreateElasticNetworkInterface(p.zoneID, p.instanceID, vsw.ID, p.securityGroupIDs[0])
+	_, eniID, err := p.api.CreateElasticNetworkInterface(ctx, p.zoneID, p.instanceID, vsw.ID, securityGroupIDs[0])
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
 	eni := &daemon.ENI{
-		ID: eniID,
+		ID:               eniID,
+		SecurityGroupIDs: securityGroupIDs[:1],
 	}
 
 	var resp *eflo.Content
@@ -84,7 +105,7 @@ func (p *Eflo) CreateNetworkInterface(ipv4, ipv6 int, eniType string) (*daemon.E
 	}, func(err error) bool {
 		return true
 	}, func() error {
-		resp, err = p.api.GetElasticNetworkInterface(eniID)
+		resp, err = p.api.GetElasticNetworkInterface(ctx, eniID)
 		if err != nil {
 			return err
 		}
@@ -139,8 +160,8 @@ func (p *Eflo) CreateNetworkInterface(ipv4, ipv6 int, eniType string) (*daemon.E
 	return eni, ipv4Slice, ipv6Slice, nil
 }
 
-func (p *Eflo) AssignNIPv4(eniID string, count int, mac string) ([]netip.Addr, error) {
-	ipName, err := p.api.AssignLeniPrivateIPAddress(p.ctx, eniID, "")
+func (p *Eflo) AssignNIPv4(ctx context.Context, eniID string, count int, mac string) ([]netip.Addr, error) {
+	ipName, err := p.api.AssignLeniPrivateIPAddress(ctx, eniID, "")
 	if err != nil {
 		return nil, err
 	}
@@ -154,7 +175,7 @@ func (p *Eflo) AssignNIPv4(eniID string, count int, mac string) ([]netip.Addr, e
 	}, func(err error) bool {
 		return true
 	}, func() error {
-		content, err := p.api.ListLeniPrivateIPAddresses(p.ctx, "", ipName, "")
+		content, err := p.api.ListLeniPrivateIPAddresses(ctx, "", ipName, "")
 		if err != nil {
 			klog.Infof("failed to find ip %s", ipName)
 			return err
@@ -173,7 +194,7 @@ func (p *Eflo) AssignNIPv4(eniID string, count int, mac string) ([]netip.Addr, e
 	return re, err
 }
 
-func (p *Eflo) AssignNIPv6(eniID string, count int, mac string) ([]netip.Addr, error) {
+func (p *Eflo) AssignNIPv6(ctx context.Context, eniID string, count int, mac string) ([]netip.Addr, error) {
 	return nil, nil
 }
 
@@ -200,6 +221,18 @@ func (p *Eflo) UnAssignNIPv6(eniID string, ips []netip.Addr, mac string) error {
 	return nil
 }
 
+func (p *Eflo) MoveNIPv4(fromENIID, eniID string, ips []netip.Addr, mac string) error {
+	return fmt.Errorf("move ip is not supported on eflo")
+}
+
+func (p *Eflo) AssignIPv4Prefix(ctx context.Context, eniID string, count int, mac string) ([]netip.Prefix, error) {
+	return nil, fmt.Errorf("ipv4 prefix is not supported on eflo")
+}
+
+func (p *Eflo) UnAssignIPv4Prefix(eniID string, prefixes []netip.Prefix, mac string) error {
+	return nil
+}
+
 func (p *Eflo) DeleteNetworkInterface(eniID string) error {
 	return p.api.DeleteElasticNetworkInterface(p.ctx, eniID)
 }
@@ -247,6 +280,10 @@ func (p *Eflo) LoadNetworkInterface(mac string) ([]netip.Addr, []netip.Addr, err
 	return nil, nil, nil
 }
 
+func (p *Eflo) LoadNetworkInterfacePrefix(mac string) ([]netip.Prefix, error) {
+	return nil, nil
+}
+
 func (p *Eflo) GetAttachedNetworkInterface(preferTrunkID string) ([]*daemon.ENI, error) {
 	content, err := p.api.ListElasticNetworkInterfaces(p.ctx, p.zoneID, p.instanceID, "")
 	if err != nil {
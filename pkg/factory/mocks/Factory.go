@@ -3,8 +3,12 @@
 package mocks
 
 import (
+	context "context"
+
 	daemon "github.com/AliyunContainerService/terway/types/daemon"
 
+	factory "github.com/AliyunContainerService/terway/pkg/factory"
+
 	mock "github.com/stretchr/testify/mock"
 
 	netip "net/netip"
@@ -15,9 +19,39 @@ type Factory struct {
 	mock.Mock
 }
 
-// AssignNIPv4 provides a mock function with given fields: eniID, count, mac
-func (_m *Factory) AssignNIPv4(eniID string, count int, mac string) ([]netip.Addr, error) {
-	ret := _m.Called(eniID, count, mac)
+// AssignIPv4Prefix provides a mock function with given fields: ctx, eniID, count, mac
+func (_m *Factory) AssignIPv4Prefix(ctx context.Context, eniID string, count int, mac string) ([]netip.Prefix, error) {
+	ret := _m.Called(ctx, eniID, count, mac)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AssignIPv4Prefix")
+	}
+
+	var r0 []netip.Prefix
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, string) ([]netip.Prefix, error)); ok {
+		return rf(ctx, eniID, count, mac)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, string) []netip.Prefix); ok {
+		r0 = rf(ctx, eniID, count, mac)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]netip.Prefix)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int, string) error); ok {
+		r1 = rf(ctx, eniID, count, mac)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AssignNIPv4 provides a mock function with given fields: ctx, eniID, count, mac
+func (_m *Factory) AssignNIPv4(ctx context.Context, eniID string, count int, mac string) ([]netip.Addr, error) {
+	ret := _m.Called(ctx, eniID, count, mac)
 
 	if len(ret) == 0 {
 		panic("no return value specified for AssignNIPv4")
@@ -25,19 +59,19 @@ func (_m *Factory) AssignNIPv4(eniID string, count int, mac string) ([]netip.Add
 
 	var r0 []netip.Addr
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string, int, string) ([]netip.Addr, error)); ok {
-		return rf(eniID, count, mac)
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, string) ([]netip.Addr, error)); ok {
+		return rf(ctx, eniID, count, mac)
 	}
-	if rf, ok := ret.Get(0).(func(string, int, string) []netip.Addr); ok {
-		r0 = rf(eniID, count, mac)
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, string) []netip.Addr); ok {
+		r0 = rf(ctx, eniID, count, mac)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]netip.Addr)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(string, int, string) error); ok {
-		r1 = rf(eniID, count, mac)
+	if rf, ok := ret.Get(1).(func(context.Context, string, int, string) error); ok {
+		r1 = rf(ctx, eniID, count, mac)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -45,9 +79,9 @@ func (_m *Factory) AssignNIPv4(eniID string, count int, mac string) ([]netip.Add
 	return r0, r1
 }
 
-// AssignNIPv6 provides a mock function with given fields: eniID, count, mac
-func (_m *Factory) AssignNIPv6(eniID string, count int, mac string) ([]netip.Addr, error) {
-	ret := _m.Called(eniID, count, mac)
+// AssignNIPv6 provides a mock function with given fields: ctx, eniID, count, mac
+func (_m *Factory) AssignNIPv6(ctx context.Context, eniID string, count int, mac string) ([]netip.Addr, error) {
+	ret := _m.Called(ctx, eniID, count, mac)
 
 	if len(ret) == 0 {
 		panic("no return value specified for AssignNIPv6")
@@ -55,19 +89,19 @@ func (_m *Factory) AssignNIPv6(eniID string, count int, mac string) ([]netip.Add
 
 	var r0 []netip.Addr
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string, int, string) ([]netip.Addr, error)); ok {
-		return rf(eniID, count, mac)
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, string) ([]netip.Addr, error)); ok {
+		return rf(ctx, eniID, count, mac)
 	}
-	if rf, ok := ret.Get(0).(func(string, int, string) []netip.Addr); ok {
-		r0 = rf(eniID, count, mac)
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, string) []netip.Addr); ok {
+		r0 = rf(ctx, eniID, count, mac)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]netip.Addr)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(string, int, string) error); ok {
-		r1 = rf(eniID, count, mac)
+	if rf, ok := ret.Get(1).(func(context.Context, string, int, string) error); ok {
+		r1 = rf(ctx, eniID, count, mac)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -75,9 +109,9 @@ func (_m *Factory) AssignNIPv6(eniID string, count int, mac string) ([]netip.Add
 	return r0, r1
 }
 
-// CreateNetworkInterface provides a mock function with given fields: ipv4, ipv6, eniType
-func (_m *Factory) CreateNetworkInterface(ipv4 int, ipv6 int, eniType string) (*daemon.ENI, []netip.Addr, []netip.Addr, error) {
-	ret := _m.Called(ipv4, ipv6, eniType)
+// CreateNetworkInterface provides a mock function with given fields: ctx, ipv4, ipv6, eniType
+func (_m *Factory) CreateNetworkInterface(ctx context.Context, ipv4 int, ipv6 int, eniType string) (*daemon.ENI, []netip.Addr, []netip.Addr, error) {
+	ret := _m.Called(ctx, ipv4, ipv6, eniType)
 
 	if len(ret) == 0 {
 		panic("no return value specified for CreateNetworkInterface")
@@ -87,35 +121,83 @@ func (_m *Factory) CreateNetworkInterface(ipv4 int, ipv6 int, eniType string) (*
 	var r1 []netip.Addr
 	var r2 []netip.Addr
 	var r3 error
-	if rf, ok := ret.Get(0).(func(int, int, string) (*daemon.ENI, []netip.Addr, []netip.Addr, error)); ok {
-		return rf(ipv4, ipv6, eniType)
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, string) (*daemon.ENI, []netip.Addr, []netip.Addr, error)); ok {
+		return rf(ctx, ipv4, ipv6, eniType)
 	}
-	if rf, ok := ret.Get(0).(func(int, int, string) *daemon.ENI); ok {
-		r0 = rf(ipv4, ipv6, eniType)
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, string) *daemon.ENI); ok {
+		r0 = rf(ctx, ipv4, ipv6, eniType)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*daemon.ENI)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(int, int, string) []netip.Addr); ok {
-		r1 = rf(ipv4, ipv6, eniType)
+	if rf, ok := ret.Get(1).(func(context.Context, int, int, string) []netip.Addr); ok {
+		r1 = rf(ctx, ipv4, ipv6, eniType)
 	} else {
 		if ret.Get(1) != nil {
 			r1 = ret.Get(1).([]netip.Addr)
 		}
 	}
 
-	if rf, ok := ret.Get(2).(func(int, int, string) []netip.Addr); ok {
-		r2 = rf(ipv4, ipv6, eniType)
+	if rf, ok := ret.Get(2).(func(context.Context, int, int, string) []netip.Addr); ok {
+		r2 = rf(ctx, ipv4, ipv6, eniType)
 	} else {
 		if ret.Get(2) != nil {
 			r2 = ret.Get(2).([]netip.Addr)
 		}
 	}
 
-	if rf, ok := ret.Get(3).(func(int, int, string) error); ok {
-		r3 = rf(ipv4, ipv6, eniType)
+	if rf, ok := ret.Get(3).(func(context.Context, int, int, string) error); ok {
+		r3 = rf(ctx, ipv4, ipv6, eniType)
+	} else {
+		r3 = ret.Error(3)
+	}
+
+	return r0, r1, r2, r3
+}
+
+// CreateNetworkInterfaceWithOptions provides a mock function with given fields: ctx, ipv4, ipv6, eniType, opts
+func (_m *Factory) CreateNetworkInterfaceWithOptions(ctx context.Context, ipv4 int, ipv6 int, eniType string, opts *factory.NetworkInterfaceOptions) (*daemon.ENI, []netip.Addr, []netip.Addr, error) {
+	ret := _m.Called(ctx, ipv4, ipv6, eniType, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateNetworkInterfaceWithOptions")
+	}
+
+	var r0 *daemon.ENI
+	var r1 []netip.Addr
+	var r2 []netip.Addr
+	var r3 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, string, *factory.NetworkInterfaceOptions) (*daemon.ENI, []netip.Addr, []netip.Addr, error)); ok {
+		return rf(ctx, ipv4, ipv6, eniType, opts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, string, *factory.NetworkInterfaceOptions) *daemon.ENI); ok {
+		r0 = rf(ctx, ipv4, ipv6, eniType, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*daemon.ENI)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int, string, *factory.NetworkInterfaceOptions) []netip.Addr); ok {
+		r1 = rf(ctx, ipv4, ipv6, eniType, opts)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]netip.Addr)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, int, int, string, *factory.NetworkInterfaceOptions) []netip.Addr); ok {
+		r2 = rf(ctx, ipv4, ipv6, eniType, opts)
+	} else {
+		if ret.Get(2) != nil {
+			r2 = ret.Get(2).([]netip.Addr)
+		}
+	}
+
+	if rf, ok := ret.Get(3).(func(context.Context, int, int, string, *factory.NetworkInterfaceOptions) error); ok {
+		r3 = rf(ctx, ipv4, ipv6, eniType, opts)
 	} else {
 		r3 = ret.Error(3)
 	}
@@ -210,6 +292,72 @@ func (_m *Factory) LoadNetworkInterface(mac string) ([]netip.Addr, []netip.Addr,
 	return r0, r1, r2
 }
 
+// LoadNetworkInterfacePrefix provides a mock function with given fields: mac
+func (_m *Factory) LoadNetworkInterfacePrefix(mac string) ([]netip.Prefix, error) {
+	ret := _m.Called(mac)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LoadNetworkInterfacePrefix")
+	}
+
+	var r0 []netip.Prefix
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) ([]netip.Prefix, error)); ok {
+		return rf(mac)
+	}
+	if rf, ok := ret.Get(0).(func(string) []netip.Prefix); ok {
+		r0 = rf(mac)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]netip.Prefix)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(mac)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MoveNIPv4 provides a mock function with given fields: fromENIID, eniID, ips, mac
+func (_m *Factory) MoveNIPv4(fromENIID string, eniID string, ips []netip.Addr, mac string) error {
+	ret := _m.Called(fromENIID, eniID, ips, mac)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MoveNIPv4")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, []netip.Addr, string) error); ok {
+		r0 = rf(fromENIID, eniID, ips, mac)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UnAssignIPv4Prefix provides a mock function with given fields: eniID, prefixes, mac
+func (_m *Factory) UnAssignIPv4Prefix(eniID string, prefixes []netip.Prefix, mac string) error {
+	ret := _m.Called(eniID, prefixes, mac)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UnAssignIPv4Prefix")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, []netip.Prefix, string) error); ok {
+		r0 = rf(eniID, prefixes, mac)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UnAssignNIPv4 provides a mock function with given fields: eniID, ips, mac
 func (_m *Factory) UnAssignNIPv4(eniID string, ips []netip.Addr, mac string) error {
 	ret := _m.Called(eniID, ips, mac)
@@ -3,23 +3,43 @@
 package factory
 
 import (
+	"context"
 	"net/netip"
 
 	"github.com/AliyunContainerService/terway/types/daemon"
 )
 
+// NetworkInterfaceOptions is the network the eni created in, the configured one is used if the field is empty
+type NetworkInterfaceOptions struct {
+	VSwitchOptions   []string
+	SecurityGroupIDs []string
+}
+
+// Factory manage the enis and ips of the node. The ctx of the allocation carries the trace of the allocation.
 type Factory interface {
-	CreateNetworkInterface(ipv4, ipv6 int, eniType string) (*daemon.ENI, []netip.Addr, []netip.Addr, error)
-	AssignNIPv4(eniID string, count int, mac string) ([]netip.Addr, error)
-	AssignNIPv6(eniID string, count int, mac string) ([]netip.Addr, error)
+	CreateNetworkInterface(ctx context.Context, ipv4, ipv6 int, eniType string) (*daemon.ENI, []netip.Addr, []netip.Addr, error)
+	// CreateNetworkInterfaceWithOptions create eni in the vSwitches and security groups given by opts
+	CreateNetworkInterfaceWithOptions(ctx context.Context, ipv4, ipv6 int, eniType string, opts *NetworkInterfaceOptions) (*daemon.ENI, []netip.Addr, []netip.Addr, error)
+	AssignNIPv4(ctx context.Context, eniID string, count int, mac string) ([]netip.Addr, error)
+	AssignNIPv6(ctx context.Context, eniID string, count int, mac string) ([]netip.Addr, error)
 
 	// UnAssignNIPv4 unassign ip from eni, the primary ip is not allowed to unassign
 	UnAssignNIPv4(eniID string, ips []netip.Addr, mac string) error
 	UnAssignNIPv6(eniID string, ips []netip.Addr, mac string) error
 
+	// MoveNIPv4 move the ips from the source eni to the eni, the source is skipped if empty.
+	// The mac is empty if the eni is not attached to the node.
+	MoveNIPv4(fromENIID, eniID string, ips []netip.Addr, mac string) error
+
+	// AssignIPv4Prefix assign /28 ipv4 prefixes to eni
+	AssignIPv4Prefix(ctx context.Context, eniID string, count int, mac string) ([]netip.Prefix, error)
+	// UnAssignIPv4Prefix unassign the whole prefixes from eni
+	UnAssignIPv4Prefix(eniID string, prefixes []netip.Prefix, mac string) error
+
 	DeleteNetworkInterface(eniID string) error
 
 	LoadNetworkInterface(mac string) ([]netip.Addr, []netip.Addr, error)
+	LoadNetworkInterfacePrefix(mac string) ([]netip.Prefix, error)
 
 	GetAttachedNetworkInterface(preferTrunkID string) ([]*daemon.ENI, error)
 }
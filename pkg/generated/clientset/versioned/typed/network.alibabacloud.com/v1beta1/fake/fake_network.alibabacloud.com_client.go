@@ -27,6 +27,10 @@ type FakeNetworkV1beta1 struct {
 	*testing.Fake
 }
 
+func (c *FakeNetworkV1beta1) IPReservations(namespace string) v1beta1.IPReservationInterface {
+	return &FakeIPReservations{c, namespace}
+}
+
 func (c *FakeNetworkV1beta1) PodENIs(namespace string) v1beta1.PodENIInterface {
 	return &FakePodENIs{c, namespace}
 }
@@ -17,6 +17,8 @@ limitations under the License.
 
 package v1beta1
 
+type IPReservationExpansion interface{}
+
 type PodENIExpansion interface{}
 
 type PodNetworkingExpansion interface{}
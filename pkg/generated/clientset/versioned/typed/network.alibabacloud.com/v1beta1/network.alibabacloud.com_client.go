@@ -27,6 +27,7 @@ import (
 
 type NetworkV1beta1Interface interface {
 	RESTClient() rest.Interface
+	IPReservationsGetter
 	PodENIsGetter
 	PodNetworkingsGetter
 }
@@ -36,6 +37,10 @@ type NetworkV1beta1Client struct {
 	restClient rest.Interface
 }
 
+func (c *NetworkV1beta1Client) IPReservations(namespace string) IPReservationInterface {
+	return newIPReservations(c, namespace)
+}
+
 func (c *NetworkV1beta1Client) PodENIs(namespace string) PodENIInterface {
 	return newPodENIs(c, namespace)
 }
@@ -52,6 +52,8 @@ func (f *genericInformer) Lister() cache.GenericLister {
 func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource) (GenericInformer, error) {
 	switch resource {
 	// Group=network.alibabacloud.com, Version=v1beta1
+	case v1beta1.SchemeGroupVersion.WithResource("ipreservations"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Network().V1beta1().IPReservations().Informer()}, nil
 	case v1beta1.SchemeGroupVersion.WithResource("podenis"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Network().V1beta1().PodENIs().Informer()}, nil
 	case v1beta1.SchemeGroupVersion.WithResource("podnetworkings"):
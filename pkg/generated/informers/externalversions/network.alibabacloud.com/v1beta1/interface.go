@@ -23,6 +23,8 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// IPReservations returns a IPReservationInformer.
+	IPReservations() IPReservationInformer
 	// PodENIs returns a PodENIInformer.
 	PodENIs() PodENIInformer
 	// PodNetworkings returns a PodNetworkingInformer.
@@ -40,6 +42,11 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// IPReservations returns a IPReservationInformer.
+func (v *version) IPReservations() IPReservationInformer {
+	return &iPReservationInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // PodENIs returns a PodENIInformer.
 func (v *version) PodENIs() PodENIInformer {
 	return &podENIInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
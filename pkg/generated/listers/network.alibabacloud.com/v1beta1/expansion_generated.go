@@ -17,6 +17,14 @@ limitations under the License.
 
 package v1beta1
 
+// IPReservationListerExpansion allows custom methods to be added to
+// IPReservationLister.
+type IPReservationListerExpansion interface{}
+
+// IPReservationNamespaceListerExpansion allows custom methods to be added to
+// IPReservationNamespaceLister.
+type IPReservationNamespaceListerExpansion interface{}
+
 // PodENIListerExpansion allows custom methods to be added to
 // PodENILister.
 type PodENIListerExpansion interface{}
@@ -29,6 +29,28 @@ func ToIPAddrs(addrs []string) ([]netip.Addr, error) {
 	return result, nil
 }
 
+func ToIPPrefixes(prefixes []string) ([]netip.Prefix, error) {
+	var result []netip.Prefix
+	for _, prefix := range prefixes {
+		p, err := netip.ParsePrefix(prefix)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, p.Masked())
+	}
+	return result, nil
+}
+
+// PrefixAddrs return all the addresses in the prefix
+func PrefixAddrs(prefix netip.Prefix) []netip.Addr {
+	var result []netip.Addr
+	prefix = prefix.Masked()
+	for addr := prefix.Addr(); addr.IsValid() && prefix.Contains(addr); addr = addr.Next() {
+		result = append(result, addr)
+	}
+	return result
+}
+
 func IPv6(ip net.IP) bool {
 	return ip.To4() == nil
 }
@@ -49,6 +71,14 @@ func IPAddrs2str(ips []netip.Addr) []string {
 	return result
 }
 
+func IPPrefixes2str(prefixes []netip.Prefix) []string {
+	var result []string
+	for _, prefix := range prefixes {
+		result = append(result, prefix.String())
+	}
+	return result
+}
+
 // IPsIntersect return is 2 set is intersect
 func IPsIntersect(a []net.IP, b []net.IP) bool {
 	return sets.NewString(IPs2str(a)...).HasAny(IPs2str(b)...)
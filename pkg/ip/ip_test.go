@@ -2,6 +2,7 @@ package ip
 
 import (
 	"net"
+	"net/netip"
 	"testing"
 )
 
@@ -53,3 +54,28 @@ func Test_ipIntersect(t *testing.T) {
 		})
 	}
 }
+
+func TestPrefixAddrs(t *testing.T) {
+	addrs := PrefixAddrs(netip.MustParsePrefix("192.168.0.17/28"))
+	if len(addrs) != 16 {
+		t.Fatalf("PrefixAddrs() got %d addrs, want 16", len(addrs))
+	}
+	if addrs[0] != netip.MustParseAddr("192.168.0.16") || addrs[15] != netip.MustParseAddr("192.168.0.31") {
+		t.Errorf("PrefixAddrs() = %v, unexpected range", addrs)
+	}
+}
+
+func TestToIPPrefixes(t *testing.T) {
+	prefixes, err := ToIPPrefixes([]string{"192.168.0.16/28", "10.0.0.1/28"})
+	if err != nil {
+		t.Fatalf("ToIPPrefixes() error = %v", err)
+	}
+	if prefixes[1] != netip.MustParsePrefix("10.0.0.0/28") {
+		t.Errorf("ToIPPrefixes() = %v, want masked prefix", prefixes[1])
+	}
+
+	_, err = ToIPPrefixes([]string{"foo"})
+	if err == nil {
+		t.Errorf("ToIPPrefixes() expect error")
+	}
+}
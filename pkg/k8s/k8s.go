@@ -20,25 +20,33 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	k8stypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	coreinformers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	typedv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/AliyunContainerService/terway/deviceplugin"
+	"github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
 	"github.com/AliyunContainerService/terway/pkg/backoff"
+	"github.com/AliyunContainerService/terway/pkg/generated/informers/externalversions"
+	networklisters "github.com/AliyunContainerService/terway/pkg/generated/listers/network.alibabacloud.com/v1beta1"
 	"github.com/AliyunContainerService/terway/pkg/storage"
 	"github.com/AliyunContainerService/terway/pkg/tracing"
 	"github.com/AliyunContainerService/terway/pkg/utils"
 	"github.com/AliyunContainerService/terway/pkg/utils/k8sclient"
 	"github.com/AliyunContainerService/terway/pkg/version"
 	"github.com/AliyunContainerService/terway/types"
+	"github.com/AliyunContainerService/terway/types/controlplane"
 	"github.com/AliyunContainerService/terway/types/daemon"
 )
 
@@ -52,8 +60,6 @@ const (
 	podIngressBandwidth = "k8s.aliyun.com/ingress-bandwidth" //deprecated
 	podEgressBandwidth  = "k8s.aliyun.com/egress-bandwidth"  //deprecated
 
-	defaultStickTimeForSts = 5 * time.Minute
-
 	dbPath = "/var/lib/cni/terway/pod.db"
 	dbName = "pods"
 
@@ -83,6 +89,7 @@ var (
 // Kubernetes operation set
 type Kubernetes interface {
 	GetLocalPods() ([]*daemon.PodInfo, error)
+	WatchPodQoS(ctx context.Context, handler func(pod *daemon.PodInfo))
 	GetPod(ctx context.Context, namespace, name string, cache bool) (*daemon.PodInfo, error)
 	PodExist(namespace, name string) (bool, error)
 
@@ -102,6 +109,9 @@ type Kubernetes interface {
 
 	GetTrunkID() string
 
+	// GetPodNetworking get the podNetworking from the informer cache
+	GetPodNetworking(ctx context.Context, name string) (*v1beta1.PodNetworking, error)
+
 	GetClient() client.Client
 }
 
@@ -170,6 +180,7 @@ func NewK8S(daemonMode string, globalConfig *daemon.Config) (Kubernetes, error)
 		storage:         storage,
 		broadcaster:     broadcaster,
 		recorder:        recorder,
+		ipStickTime:     globalConfig.GetIPStickTime(),
 		Locker:          &sync.RWMutex{},
 	}
 
@@ -209,6 +220,16 @@ type k8s struct {
 	node                    *corev1.Node
 	svcCIDR                 *types.IPNetSet
 	statefulWorkloadKindSet sets.Set[string]
+	ipStickTime             time.Duration
+
+	// podNetworkingInformer is started on the first use, the crd is not installed in all clusters
+	podNetworkingOnce   sync.Once
+	podNetworkingSynced cache.InformerSynced
+	podNetworkingLister networklisters.PodNetworkingLister
+
+	// podInformer is the pods on this node, shared by the pod list and the qos watch
+	podInformerOnce sync.Once
+	podInformer     coreinformers.PodInformer
 
 	sync.Locker
 }
@@ -357,6 +378,20 @@ func (k *k8s) GetClient() client.Client {
 	return k.client
 }
 
+func (k *k8s) GetPodNetworking(ctx context.Context, name string) (*v1beta1.PodNetworking, error) {
+	k.podNetworkingOnce.Do(func() {
+		factory := externalversions.NewSharedInformerFactory(k8sclient.NetworkClient, 0)
+		informer := factory.Network().V1beta1().PodNetworkings()
+		k.podNetworkingSynced = informer.Informer().HasSynced
+		k.podNetworkingLister = informer.Lister()
+		factory.Start(wait.NeverStop)
+	})
+	if !cache.WaitForCacheSync(ctx.Done(), k.podNetworkingSynced) {
+		return nil, fmt.Errorf("error wait podNetworking cache synced")
+	}
+	return k.podNetworkingLister.Get(name)
+}
+
 func (k *k8s) GetPod(ctx context.Context, namespace, name string, cache bool) (*daemon.PodInfo, error) {
 	pod, err := getPod(ctx, k.client, namespace, name, cache)
 	key := utils.PodInfoKey(namespace, name)
@@ -374,7 +409,7 @@ func (k *k8s) GetPod(ctx context.Context, namespace, name string, cache bool) (*
 		}
 		return nil, err
 	}
-	podInfo := convertPod(k.mode, k.statefulWorkloadKindSet, pod)
+	podInfo := convertPod(k.mode, k.statefulWorkloadKindSet, k.ipStickTime, pod)
 	item := &storageItem{
 		Pod: podInfo,
 	}
@@ -404,7 +439,39 @@ func (k *k8s) GetNodeCidr() *types.IPNetSet {
 	return k.nodeCIDR
 }
 
+// localPodInformer return the informer of the pods on this node, the informer is started on the first use
+func (k *k8s) localPodInformer() coreinformers.PodInformer {
+	k.podInformerOnce.Do(func() {
+		factory := informers.NewSharedInformerFactoryWithOptions(k8sclient.K8sClient, 0,
+			informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+				options.FieldSelector = fields.OneTermEqualSelector("spec.nodeName", k.nodeName).String()
+			}))
+		k.podInformer = factory.Core().V1().Pods()
+		// register the informer before the factory is started
+		k.podInformer.Informer()
+		factory.Start(wait.NeverStop)
+	})
+	return k.podInformer
+}
+
 func (k *k8s) GetLocalPods() ([]*daemon.PodInfo, error) {
+	informer := k.localPodInformer()
+	if informer.Informer().HasSynced() {
+		pods, err := informer.Lister().List(labels.Everything())
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving pod list for '%s': %w", k.nodeName, err)
+		}
+		var ret []*daemon.PodInfo
+		for _, pod := range pods {
+			if types.IgnoredByTerway(pod.Labels) {
+				continue
+			}
+			ret = append(ret, convertPod(k.mode, k.statefulWorkloadKindSet, k.ipStickTime, pod))
+		}
+		return ret, nil
+	}
+
+	// the cache is not synced yet
 	options := metav1.ListOptions{
 		FieldSelector:   fields.OneTermEqualSelector("spec.nodeName", k.nodeName).String(),
 		ResourceVersion: "0",
@@ -421,13 +488,68 @@ func (k *k8s) GetLocalPods() ([]*daemon.PodInfo, error) {
 			continue
 		}
 
-		podInfo := convertPod(k.mode, k.statefulWorkloadKindSet, &pod)
+		podInfo := convertPod(k.mode, k.statefulWorkloadKindSet, k.ipStickTime, &pod)
 		ret = append(ret, podInfo)
 	}
 
 	return ret, nil
 }
 
+// WatchPodQoS watch the pods on this node, the handler is called when the bandwidth or the network priority
+// annotation is changed. Pods already on the node are also passed to the handler once the watch started.
+func (k *k8s) WatchPodQoS(ctx context.Context, handler func(pod *daemon.PodInfo)) {
+	informer := k.localPodInformer().Informer()
+
+	onPod := func(pod *corev1.Pod) {
+		if pod.Spec.HostNetwork || types.IgnoredByTerway(pod.Labels) {
+			return
+		}
+		handler(convertPod(k.mode, k.statefulWorkloadKindSet, k.ipStickTime, pod))
+	}
+	registration, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			pod, ok := obj.(*corev1.Pod)
+			if !ok {
+				return
+			}
+			onPod(pod)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldPod, ok := oldObj.(*corev1.Pod)
+			if !ok {
+				return
+			}
+			newPod, ok := newObj.(*corev1.Pod)
+			if !ok {
+				return
+			}
+			if !podQoSChanged(oldPod, newPod) {
+				return
+			}
+			onPod(newPod)
+		},
+	})
+	if err != nil {
+		klog.Errorf("error watch pod qos, %v", err)
+		return
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = informer.RemoveEventHandler(registration)
+	}()
+}
+
+// podQoSChanged return true if the annotation related to the bandwidth or network priority is changed
+func podQoSChanged(oldPod, newPod *corev1.Pod) bool {
+	for _, key := range []string{podIngressBandwidth, podEgressBandwidth, types.NetworkPriority} {
+		if oldPod.Annotations[key] != newPod.Annotations[key] {
+			return true
+		}
+	}
+	return false
+}
+
 func (k *k8s) GetServiceCIDR() *types.IPNetSet {
 	return k.svcCIDR
 }
@@ -572,7 +694,7 @@ func podNetworkType(daemonMode string, pod *corev1.Pod) string {
 	panic(fmt.Errorf("unknown daemon mode %s", daemonMode))
 }
 
-func convertPod(daemonMode string, statefulWorkloadKindSet sets.Set[string], pod *corev1.Pod) *daemon.PodInfo {
+func convertPod(daemonMode string, statefulWorkloadKindSet sets.Set[string], ipStickTime time.Duration, pod *corev1.Pod) *daemon.PodInfo {
 	pi := &daemon.PodInfo{
 		Name:      pod.Name,
 		Namespace: pod.Namespace,
@@ -606,6 +728,7 @@ func convertPod(daemonMode string, statefulWorkloadKindSet sets.Set[string], pod
 	}
 
 	pi.SandboxExited = pod.Status.Phase == corev1.PodFailed || pod.Status.Phase == corev1.PodSucceeded
+	pi.SandboxPending = pod.Status.Phase == corev1.PodPending && !pod.Spec.HostNetwork && len(pod.Status.PodIPs) == 0 && pod.Status.PodIP == ""
 
 	if podENI, ok := podAnnotation[types.PodENI]; ok {
 		var err error
@@ -628,16 +751,74 @@ func convertPod(daemonMode string, statefulWorkloadKindSet sets.Set[string], pod
 
 	pi.ERdma = isERDMA(pod)
 
-	// determine whether pod's IP will stick 5 minutes for a reuse, priorities as below,
+	pi.PodNetworking = podAnnotation[types.PodNetworking]
+	if sgs, ok := podAnnotation[types.PodSecurityGroups]; ok {
+		for _, sg := range strings.Split(sgs, ",") {
+			sg = strings.TrimSpace(sg)
+			if sg != "" {
+				pi.SecurityGroups = append(pi.SecurityGroups, sg)
+			}
+		}
+	}
+
+	if _, ok := podAnnotation[types.PodNetworks]; ok {
+		networks, err := controlplane.ParsePodNetworksFromAnnotation(pod)
+		if err != nil {
+			_ = tracing.RecordPodEvent(pod.Name, pod.Namespace, eventTypeWarning,
+				"ParseFailed", fmt.Sprintf("Parse pod annotation %s failed.", types.PodNetworks))
+		} else {
+			for _, n := range networks.PodNetworks {
+				network := daemon.PodNetwork{
+					Interface:        n.Interface,
+					VSwitchOptions:   n.VSwitchOptions,
+					SecurityGroupIDs: n.SecurityGroupIDs,
+				}
+				for _, r := range n.ExtraRoutes {
+					network.ExtraRoutes = append(network.ExtraRoutes, r.Dst)
+				}
+				pi.PodNetworks = append(pi.PodNetworks, network)
+			}
+		}
+	}
+
+	if !pod.Spec.HostNetwork {
+		for _, c := range pod.Spec.Containers {
+			for _, port := range c.Ports {
+				if port.HostPort <= 0 {
+					continue
+				}
+				pi.HostPorts = append(pi.HostPorts, daemon.HostPortMapping{
+					HostPort:      port.HostPort,
+					ContainerPort: port.ContainerPort,
+					Protocol:      string(port.Protocol),
+					HostIP:        port.HostIP,
+				})
+			}
+		}
+	}
+
+	if owner := metav1.GetControllerOf(pod); owner != nil && owner.Kind == "StatefulSet" {
+		pi.StatefulSet = owner.Name
+	}
+
+	// the time the ip held for a reuse, the pod-ip-stick-time annotation override the configured one
+	if v, ok := pod.Annotations[types.PodIPStickTime]; ok {
+		d, err := time.ParseDuration(v)
+		if err == nil && d >= 0 {
+			ipStickTime = d
+		}
+	}
+
+	// determine whether pod's IP will stick for a reuse, priorities as below,
 	// 1. pod has a positive pod-ip-reservation annotation
 	// 2. pod is owned by a known stateful workload
 	switch {
 	case parseBool(pod.Annotations[types.PodIPReservation]):
-		pi.IPStickTime = defaultStickTimeForSts
+		pi.IPStickTime = ipStickTime
 	case len(pod.OwnerReferences) > 0:
 		for i := range pod.OwnerReferences {
 			if statefulWorkloadKindSet.Has(strings.ToLower(pod.OwnerReferences[i].Kind)) {
-				pi.IPStickTime = defaultStickTimeForSts
+				pi.IPStickTime = ipStickTime
 				break
 			}
 		}
@@ -13,6 +13,8 @@ import (
 
 	types "github.com/AliyunContainerService/terway/types"
 
+	v1beta1 "github.com/AliyunContainerService/terway/pkg/apis/network.alibabacloud.com/v1beta1"
+
 	v1 "k8s.io/api/core/v1"
 )
 
@@ -167,6 +169,36 @@ func (_m *Kubernetes) GetPod(ctx context.Context, namespace string, name string,
 	return r0, r1
 }
 
+// GetPodNetworking provides a mock function with given fields: ctx, name
+func (_m *Kubernetes) GetPodNetworking(ctx context.Context, name string) (*v1beta1.PodNetworking, error) {
+	ret := _m.Called(ctx, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPodNetworking")
+	}
+
+	var r0 *v1beta1.PodNetworking
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*v1beta1.PodNetworking, error)); ok {
+		return rf(ctx, name)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *v1beta1.PodNetworking); ok {
+		r0 = rf(ctx, name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*v1beta1.PodNetworking)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetServiceCIDR provides a mock function with given fields:
 func (_m *Kubernetes) GetServiceCIDR() *types.IPNetSet {
 	ret := _m.Called()
@@ -374,6 +406,11 @@ func (_m *Kubernetes) WaitTrunkReady() (string, error) {
 	return r0, r1
 }
 
+// WatchPodQoS provides a mock function with given fields: ctx, handler
+func (_m *Kubernetes) WatchPodQoS(ctx context.Context, handler func(*daemon.PodInfo)) {
+	_m.Called(ctx, handler)
+}
+
 // NewKubernetes creates a new instance of Kubernetes. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewKubernetes(t interface {
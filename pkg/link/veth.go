@@ -6,6 +6,9 @@ import (
 	"fmt"
 )
 
+// HostVethPrefix is the prefix of the host-side veth name for pod
+const HostVethPrefix = "cali"
+
 // VethNameForPod return host-side veth name for pod
 // max veth length is 15
 func VethNameForPod(name, namespace, ifName, prefix string) (string, error) {
@@ -0,0 +1,38 @@
+package metric
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	// DatapathDrift amount of drift found in host netns by the last datapath reconcile
+	DatapathDrift = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "terway_datapath_drift_count",
+			Help: "amount of drift found in host netns by the last datapath reconcile",
+		},
+		// kind in "rule", "route", "neigh" or "filter", action in "add" or "del"
+		[]string{"kind", "action"},
+	)
+
+	// DatapathRepaired counter of the drift repaired by the datapath reconcile
+	DatapathRepaired = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "terway_datapath_repaired_count",
+			Help: "counter of the drift repaired by the datapath reconcile",
+		},
+		// status in "succeed" or "fail"
+		[]string{"kind", "action", "status"},
+	)
+)
+
+const (
+	DatapathKindRule   = "rule"
+	DatapathKindRoute  = "route"
+	DatapathKindNeigh  = "neigh"
+	DatapathKindFilter = "filter"
+
+	DatapathActionAdd = "add"
+	DatapathActionDel = "del"
+
+	DatapathRepairSucceed = "succeed"
+	DatapathRepairFail    = "fail"
+)
@@ -44,4 +44,22 @@ var (
 		},
 		[]string{"type"},
 	)
+
+	// SecurityGroupPoolIdle the idle ip count of the eni pool in the security groups
+	SecurityGroupPoolIdle = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "terway_security_group_pool_idle_count",
+			Help: "terway amount of idle ip in the eni pool of the security groups",
+		},
+		[]string{"security_groups"},
+	)
+
+	// SecurityGroupPoolInUse the in use ip count of the eni pool in the security groups
+	SecurityGroupPoolInUse = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "terway_security_group_pool_in_use_count",
+			Help: "terway amount of in use ip in the eni pool of the security groups",
+		},
+		[]string{"security_groups"},
+	)
 )
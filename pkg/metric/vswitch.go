@@ -0,0 +1,23 @@
+package metric
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	// VSwitchAvailableIPCount available ip count of the vSwitch
+	VSwitchAvailableIPCount = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "terway_vswitch_available_ip_count",
+			Help: "available ip count of the vSwitch",
+		},
+		[]string{"vsw", "zone"},
+	)
+
+	// VSwitchExhaustSeconds projected seconds before the vSwitch runs out of ip, -1 if the ip is not consumed
+	VSwitchExhaustSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "terway_vswitch_exhaust_seconds",
+			Help: "projected seconds before the vSwitch runs out of ip, -1 if the ip is not consumed",
+		},
+		[]string{"vsw", "zone"},
+	)
+)
@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/boltdb/bolt"
+)
+
+// schemaBucket keep the schema version of each data bucket
+const schemaBucket = "_schema"
+
+// ErrSchemaDowngrade the db is written by a newer version which is not readable
+var ErrSchemaDowngrade = errors.New("schema downgrade is not supported")
+
+// ErrSchemaMismatch the db is not in the schema version expected by the reader
+var ErrSchemaMismatch = errors.New("schema version mismatch")
+
+// ErrQuarantine is returned by the MigrateFunc if the record can't be migrated, but should not fail the whole migration.
+// The record is moved to the quarantine bucket, so it can be inspected and recovered manually.
+var ErrQuarantine = errors.New("record is quarantined")
+
+// QuarantineBucket return the bucket keep the records quarantined during the migration of the data bucket
+func QuarantineBucket(name string) string {
+	return name + "_quarantine"
+}
+
+// MigrateFunc convert the raw value of a key to the next version.
+// Return nil to delete the key, or ErrQuarantine to move the key to the quarantine bucket.
+type MigrateFunc func(key string, value []byte) ([]byte, error)
+
+// Migration upgrade the data bucket to Version
+type Migration struct {
+	Version int
+	Name    string
+	Migrate MigrateFunc
+}
+
+// Schema describe the versioned data layout of a bucket
+type Schema struct {
+	migrations []Migration
+}
+
+// NewSchema return a schema with no migration registered, the version is 0
+func NewSchema() *Schema {
+	return &Schema{}
+}
+
+// Register add a migration to the schema.
+// Version must be registered in order, starting from 1.
+func (s *Schema) Register(version int, name string, fn MigrateFunc) *Schema {
+	if version != s.Version()+1 {
+		panic(fmt.Sprintf("migration %s version %d is not continuous, current %d", name, version, s.Version()))
+	}
+	s.migrations = append(s.migrations, Migration{
+		Version: version,
+		Name:    name,
+		Migrate: fn,
+	})
+	return s
+}
+
+// Version return the latest version the schema support
+func (s *Schema) Version() int {
+	if len(s.migrations) == 0 {
+		return 0
+	}
+	return s.migrations[len(s.migrations)-1].Version
+}
+
+// pending return the migrations need to apply for the current version
+func (s *Schema) pending(current int) []Migration {
+	idx := sort.Search(len(s.migrations), func(i int) bool {
+		return s.migrations[i].Version > current
+	})
+	return s.migrations[idx:]
+}
+
+// getSchemaVersion return the version recorded for the bucket.
+// Bucket without version is treated as version 0, which is written before the schema is introduced.
+func getSchemaVersion(tx *bolt.Tx, name string) (int, bool, error) {
+	b := tx.Bucket([]byte(schemaBucket))
+	if b == nil {
+		return 0, false, nil
+	}
+	v := b.Get([]byte(name))
+	if v == nil {
+		return 0, false, nil
+	}
+	version, err := strconv.Atoi(string(v))
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid schema version %q for %s, %w", string(v), name, err)
+	}
+	return version, true, nil
+}
+
+// ReadSchemaVersion return the version recorded for the bucket in the db file, the db is opened read only
+func ReadSchemaVersion(path, name string, options *bolt.Options) (int, error) {
+	opts := bolt.Options{}
+	if options != nil {
+		opts = *options
+	}
+	opts.ReadOnly = true
+
+	db, err := bolt.Open(path, 0600, &opts)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	var version int
+	err = db.View(func(tx *bolt.Tx) error {
+		version, _, err = getSchemaVersion(tx, name)
+		return err
+	})
+	return version, err
+}
+
+func putSchemaVersion(tx *bolt.Tx, name string, version int) error {
+	b, err := tx.CreateBucketIfNotExists([]byte(schemaBucket))
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(name), []byte(strconv.Itoa(version)))
+}
+
+// migrate apply the migrations to the bucket in one transaction, so the data is untouched if any migration failed
+func migrate(tx *bolt.Tx, name string, migrations []Migration) error {
+	b := tx.Bucket([]byte(name))
+
+	for _, m := range migrations {
+		log.Infof("migrate %s to version %d (%s)", name, m.Version, m.Name)
+
+		updated := make(map[string][]byte)
+		quarantined := make(map[string][]byte)
+		err := b.ForEach(func(k, v []byte) error {
+			out, err := m.Migrate(string(k), v)
+			if err != nil {
+				if errors.Is(err, ErrQuarantine) {
+					log.Warnf("migration %s quarantine key %s, %s", m.Name, string(k), err)
+					// the value is only valid in the transaction
+					quarantined[string(k)] = append([]byte(nil), v...)
+					updated[string(k)] = nil
+					return nil
+				}
+				return fmt.Errorf("migration %s failed on key %s, %w", m.Name, string(k), err)
+			}
+			updated[string(k)] = out
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(quarantined) > 0 {
+			qb, err := tx.CreateBucketIfNotExists([]byte(QuarantineBucket(name)))
+			if err != nil {
+				return err
+			}
+			for k, v := range quarantined {
+				err = qb.Put([]byte(k), v)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		for k, v := range updated {
+			if v == nil {
+				err = b.Delete([]byte(k))
+			} else {
+				err = b.Put([]byte(k), v)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		err = putSchemaVersion(tx, name, m.Version)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
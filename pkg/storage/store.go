@@ -2,6 +2,7 @@ package storage
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
@@ -86,17 +87,32 @@ type DiskStorage struct {
 	db           *bolt.DB
 	name         string
 	memory       *MemoryStorage
+	schema       *Schema
 	serializer   Serializer
 	deserializer Deserializer
 }
 
 // NewDiskStorage return new disk storage
 func NewDiskStorage(name string, path string, serializer Serializer, deserializer Deserializer) (Storage, error) {
-	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
-		return nil, err
+	return NewDiskStorageWithSchema(name, path, nil, serializer, deserializer)
+}
+
+// NewDiskStorageWithSchema return new disk storage, the data is migrated to the latest schema version before load.
+// The db file is backup before any migration applied.
+func NewDiskStorageWithSchema(name string, path string, schema *Schema, serializer Serializer, deserializer Deserializer) (Storage, error) {
+	return NewDiskStorageWithOptions(name, path, schema, nil, serializer, deserializer)
+}
+
+// NewDiskStorageWithOptions return new disk storage, the db is opened with the options.
+// If the db is opened read only, the data is migrated in memory, the db file is not changed and the storage can't be written.
+func NewDiskStorageWithOptions(name string, path string, schema *Schema, options *bolt.Options, serializer Serializer, deserializer Deserializer) (Storage, error) {
+	if options == nil || !options.ReadOnly {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, err
+		}
 	}
 
-	db, err := bolt.Open(path, 0600, nil)
+	db, err := bolt.Open(path, 0600, options)
 	if err != nil {
 		return nil, err
 	}
@@ -105,6 +121,7 @@ func NewDiskStorage(name string, path string, serializer Serializer, deserialize
 		db:           db,
 		name:         name,
 		memory:       NewMemoryStorage(),
+		schema:       schema,
 		serializer:   serializer,
 		deserializer: deserializer,
 	}
@@ -112,12 +129,33 @@ func NewDiskStorage(name string, path string, serializer Serializer, deserialize
 	err = diskstorage.load()
 
 	if err != nil {
+		_ = db.Close()
 		return nil, err
 	}
 
 	return diskstorage, nil
 }
 
+// Bucket return the storage of another bucket in the same db, so the data can be kept without opening the db again
+func (d *DiskStorage) Bucket(name string, serializer Serializer, deserializer Deserializer) (Storage, error) {
+	if name == d.name {
+		return nil, fmt.Errorf("bucket %s is already used", name)
+	}
+
+	bucket := &DiskStorage{
+		db:           d.db,
+		name:         name,
+		memory:       NewMemoryStorage(),
+		serializer:   serializer,
+		deserializer: deserializer,
+	}
+	err := bucket.load()
+	if err != nil {
+		return nil, err
+	}
+	return bucket, nil
+}
+
 // Put somethings into disk storage
 func (d *DiskStorage) Put(key string, value interface{}) error {
 	data, err := d.serializer(value)
@@ -137,14 +175,33 @@ func (d *DiskStorage) Put(key string, value interface{}) error {
 
 // load all data from disk db
 func (d *DiskStorage) load() error {
+	if d.db.IsReadOnly() {
+		return d.loadReadOnly()
+	}
+
 	err := d.db.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists([]byte(d.name))
-		return err
+		if tx.Bucket([]byte(d.name)) != nil {
+			return nil
+		}
+		_, err := tx.CreateBucket([]byte(d.name))
+		if err != nil {
+			return err
+		}
+		// new created bucket is always the latest version
+		if d.schema != nil {
+			return putSchemaVersion(tx, d.name, d.schema.Version())
+		}
+		return nil
 	})
 	if err != nil {
 		return err
 	}
 
+	err = d.upgrade()
+	if err != nil {
+		return err
+	}
+
 	err = d.db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(d.name))
 		cursor := b.Cursor()
@@ -164,6 +221,89 @@ func (d *DiskStorage) load() error {
 	return err
 }
 
+// loadReadOnly load the data from the db opened read only, the data is migrated to the latest schema version in memory
+func (d *DiskStorage) loadReadOnly() error {
+	return d.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(d.name))
+		if b == nil {
+			return nil
+		}
+
+		var pending []Migration
+		if d.schema != nil {
+			current, _, err := getSchemaVersion(tx, d.name)
+			if err != nil {
+				return err
+			}
+			latest := d.schema.Version()
+			if current > latest {
+				return fmt.Errorf("%w, %s is version %d, the max supported version is %d", ErrSchemaDowngrade, d.name, current, latest)
+			}
+			pending = d.schema.pending(current)
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			for _, m := range pending {
+				var err error
+				v, err = m.Migrate(string(k), v)
+				if errors.Is(err, ErrQuarantine) {
+					log.Warnf("migration %s skip key %s, %s", m.Name, string(k), err)
+					return nil
+				}
+				if err != nil {
+					return fmt.Errorf("migration %s failed on key %s, %w", m.Name, string(k), err)
+				}
+				if v == nil {
+					return nil
+				}
+			}
+			obj, err := d.deserializer(v)
+			if err != nil {
+				return err
+			}
+			return d.memory.Put(string(k), obj)
+		})
+	})
+}
+
+// upgrade migrate the bucket to the latest schema version
+func (d *DiskStorage) upgrade() error {
+	if d.schema == nil {
+		return nil
+	}
+
+	var current int
+	err := d.db.View(func(tx *bolt.Tx) error {
+		var err error
+		current, _, err = getSchemaVersion(tx, d.name)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	latest := d.schema.Version()
+	if current > latest {
+		return fmt.Errorf("%w, %s is version %d, the max supported version is %d", ErrSchemaDowngrade, d.name, current, latest)
+	}
+	if current == latest {
+		return nil
+	}
+
+	backup := fmt.Sprintf("%s.v%d.bak", d.db.Path(), current)
+	err = d.db.View(func(tx *bolt.Tx) error {
+		return tx.CopyFile(backup, 0600)
+	})
+	if err != nil {
+		return fmt.Errorf("error backup db to %s, %w", backup, err)
+	}
+	log.Infof("backup db to %s before migrate %s from version %d to %d", backup, d.name, current, latest)
+
+	return d.db.Update(func(tx *bolt.Tx) error {
+		return migrate(tx, d.name, d.schema.pending(current))
+	})
+}
+
 // Get value in disk storage
 func (d *DiskStorage) Get(key string) (interface{}, error) {
 	return d.memory.Get(key)
@@ -185,3 +325,8 @@ func (d *DiskStorage) Delete(key string) error {
 	}
 	return d.memory.Delete(key)
 }
+
+// Close the db, the storages of other buckets in the same db is closed too
+func (d *DiskStorage) Close() error {
+	return d.db.Close()
+}
@@ -0,0 +1,231 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/stretchr/testify/assert"
+)
+
+func bytesSerializer(v interface{}) ([]byte, error) {
+	return []byte(v.(string)), nil
+}
+
+func bytesDeserializer(b []byte) (interface{}, error) {
+	return string(b), nil
+}
+
+func openTestStorage(t *testing.T, path string, schema *Schema) (*DiskStorage, error) {
+	s, err := NewDiskStorageWithSchema("test", path, schema, bytesSerializer, bytesDeserializer)
+	if err != nil {
+		return nil, err
+	}
+	d := s.(*DiskStorage)
+	t.Cleanup(func() {
+		_ = d.db.Close()
+	})
+	return d, nil
+}
+
+func schemaVersion(t *testing.T, d *DiskStorage) int {
+	var version int
+	err := d.db.View(func(tx *bolt.Tx) error {
+		var err error
+		version, _, err = getSchemaVersion(tx, d.name)
+		return err
+	})
+	assert.NoError(t, err)
+	return version
+}
+
+func upperMigration(key string, value []byte) ([]byte, error) {
+	return bytes.ToUpper(value), nil
+}
+
+func TestDiskStorage_NewWithSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	d, err := openTestStorage(t, path, NewSchema().Register(1, "upper", upperMigration))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, schemaVersion(t, d))
+
+	_, err = os.Stat(path + ".v0.bak")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDiskStorage_MigrateLegacy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	legacy, err := openTestStorage(t, path, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, legacy.Put("foo", "bar"))
+	assert.NoError(t, legacy.Put("deleted", "bar"))
+	assert.NoError(t, legacy.db.Close())
+
+	schema := NewSchema().
+		Register(1, "upper", upperMigration).
+		Register(2, "delete", func(key string, value []byte) ([]byte, error) {
+			if key == "deleted" {
+				return nil, nil
+			}
+			return value, nil
+		})
+	d, err := openTestStorage(t, path, schema)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, schemaVersion(t, d))
+
+	v, err := d.Get("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "BAR", v)
+
+	_, err = d.Get("deleted")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// the backup keep the legacy data
+	_, err = os.Stat(path + ".v0.bak")
+	assert.NoError(t, err)
+}
+
+func TestDiskStorage_MigrateFailed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	legacy, err := openTestStorage(t, path, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, legacy.Put("foo", "bar"))
+	assert.NoError(t, legacy.db.Close())
+
+	schema := NewSchema().
+		Register(1, "upper", upperMigration).
+		Register(2, "failed", func(key string, value []byte) ([]byte, error) {
+			return nil, errors.New("failed")
+		})
+	_, err = openTestStorage(t, path, schema)
+	assert.Error(t, err)
+
+	// nothing is changed
+	d, err := openTestStorage(t, path, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, schemaVersion(t, d))
+	v, err := d.Get("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", v)
+}
+
+func TestDiskStorage_MigrateQuarantine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	legacy, err := openTestStorage(t, path, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, legacy.Put("foo", "bar"))
+	assert.NoError(t, legacy.Put("broken", "bar"))
+	assert.NoError(t, legacy.db.Close())
+
+	schema := NewSchema().Register(1, "quarantine", func(key string, value []byte) ([]byte, error) {
+		if key == "broken" {
+			return nil, ErrQuarantine
+		}
+		return bytes.ToUpper(value), nil
+	})
+	d, err := openTestStorage(t, path, schema)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, schemaVersion(t, d))
+
+	v, err := d.Get("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "BAR", v)
+	_, err = d.Get("broken")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// the raw record is kept in the quarantine bucket
+	quarantined, err := d.Bucket(QuarantineBucket("test"), bytesSerializer, bytesDeserializer)
+	assert.NoError(t, err)
+	v, err = quarantined.Get("broken")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", v)
+}
+
+func TestDiskStorage_ReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	legacy, err := openTestStorage(t, path, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, legacy.Put("foo", "bar"))
+	assert.NoError(t, legacy.db.Close())
+
+	schema := NewSchema().Register(1, "upper", upperMigration)
+	options := &bolt.Options{ReadOnly: true, Timeout: time.Second}
+	s, err := NewDiskStorageWithOptions("test", path, schema, options, bytesSerializer, bytesDeserializer)
+	assert.NoError(t, err)
+	d := s.(*DiskStorage)
+	defer d.db.Close()
+
+	// migrated in memory
+	v, err := d.Get("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "BAR", v)
+	assert.Error(t, d.Put("foo", "baz"))
+
+	// shared with other readers
+	other, err := NewDiskStorageWithOptions("test", path, schema, options, bytesSerializer, bytesDeserializer)
+	assert.NoError(t, err)
+	assert.NoError(t, other.(*DiskStorage).db.Close())
+
+	// the db file is not changed
+	assert.Equal(t, 0, schemaVersion(t, d))
+	_, err = os.Stat(path + ".v0.bak")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDiskStorage_RefuseDowngrade(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	d, err := openTestStorage(t, path, NewSchema().Register(1, "upper", upperMigration).Register(2, "upper", upperMigration))
+	assert.NoError(t, err)
+	assert.NoError(t, d.db.Close())
+
+	_, err = openTestStorage(t, path, NewSchema().Register(1, "upper", upperMigration))
+	assert.ErrorIs(t, err, ErrSchemaDowngrade)
+}
+
+func TestSchema_Register(t *testing.T) {
+	assert.Panics(t, func() {
+		NewSchema().Register(2, "skip", upperMigration)
+	})
+
+	schema := NewSchema().Register(1, "a", upperMigration).Register(2, "b", upperMigration)
+	assert.Equal(t, 2, schema.Version())
+	assert.Len(t, schema.pending(0), 2)
+	assert.Len(t, schema.pending(1), 1)
+	assert.Len(t, schema.pending(2), 0)
+}
+
+func TestDiskStorage_Bucket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	d, err := openTestStorage(t, path, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, d.Put("a", "1"))
+
+	_, err = d.Bucket("test", bytesSerializer, bytesDeserializer)
+	assert.Error(t, err)
+
+	b, err := d.Bucket("other", bytesSerializer, bytesDeserializer)
+	assert.NoError(t, err)
+	assert.NoError(t, b.Put("b", "2"))
+
+	items, err := d.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"1"}, items)
+
+	// reload from the db
+	b, err = d.Bucket("other", bytesSerializer, bytesDeserializer)
+	assert.NoError(t, err)
+	v, err := b.Get("b")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", v)
+}
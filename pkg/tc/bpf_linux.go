@@ -0,0 +1,295 @@
+package tc
+
+import (
+	"fmt"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// bpf syscall commands
+const (
+	bpfMapCreate     = 0
+	bpfMapLookupElem = 1
+	bpfMapUpdateElem = 2
+	bpfMapDeleteElem = 3
+	bpfProgLoad      = 5
+	bpfObjPin        = 6
+	bpfObjGet        = 7
+
+	bpfMapTypeHash       = 1
+	bpfProgTypeSchedCLS  = 3
+	bpfPseudoMapFD       = 1
+	bpfVerifierLogLength = 64 * 1024
+)
+
+// bpf instruction classes, sizes, modes and ops
+const (
+	bpfLDX   = 0x01
+	bpfST    = 0x02
+	bpfSTX   = 0x03
+	bpfJMP   = 0x05
+	bpfALU64 = 0x07
+
+	bpfW  = 0x00
+	bpfDW = 0x18
+
+	bpfIMM = 0x00
+	bpfMEM = 0x60
+
+	bpfK = 0x00
+	bpfX = 0x08
+
+	bpfADD = 0x00
+	bpfSUB = 0x10
+	bpfMUL = 0x20
+	bpfDIV = 0x30
+	bpfMOV = 0xb0
+
+	bpfJA   = 0x00
+	bpfJEQ  = 0x10
+	bpfJGT  = 0x20
+	bpfJNE  = 0x50
+	bpfCALL = 0x80
+	bpfEXIT = 0x90
+)
+
+// bpf helper functions
+const (
+	bpfFuncMapLookupElem = 1
+	bpfFuncKtimeGetNs    = 5
+	bpfFuncSkbLoadBytes  = 26
+)
+
+// bpf registers
+const (
+	r0 uint8 = iota
+	r1
+	r2
+	r3
+	r4
+	r5
+	r6
+	r7
+	r8
+	r9
+	r10
+)
+
+// bpfInsn is the bpf instruction, see struct bpf_insn in linux/bpf.h
+type bpfInsn struct {
+	Code uint8
+	Regs uint8
+	Off  int16
+	Imm  int32
+}
+
+// bpfAsm is a minimal assembler for the bpf program, jumps are resolved by labels
+type bpfAsm struct {
+	insns  []bpfInsn
+	labels map[string]int
+	jumps  map[int]string
+}
+
+func newBPFAsm() *bpfAsm {
+	return &bpfAsm{
+		labels: make(map[string]int),
+		jumps:  make(map[int]string),
+	}
+}
+
+func (a *bpfAsm) emit(code, dst, src uint8, off int16, imm int32) *bpfAsm {
+	a.insns = append(a.insns, bpfInsn{Code: code, Regs: src<<4 | dst&0x0f, Off: off, Imm: imm})
+	return a
+}
+
+func (a *bpfAsm) label(name string) *bpfAsm {
+	a.labels[name] = len(a.insns)
+	return a
+}
+
+func (a *bpfAsm) movReg(dst, src uint8) *bpfAsm {
+	return a.emit(bpfALU64|bpfMOV|bpfX, dst, src, 0, 0)
+}
+
+func (a *bpfAsm) movImm(dst uint8, imm int32) *bpfAsm {
+	return a.emit(bpfALU64|bpfMOV|bpfK, dst, 0, 0, imm)
+}
+
+func (a *bpfAsm) aluImm(op, dst uint8, imm int32) *bpfAsm {
+	return a.emit(bpfALU64|op|bpfK, dst, 0, 0, imm)
+}
+
+func (a *bpfAsm) aluReg(op, dst, src uint8) *bpfAsm {
+	return a.emit(bpfALU64|op|bpfX, dst, src, 0, 0)
+}
+
+func (a *bpfAsm) ldx(size, dst, src uint8, off int16) *bpfAsm {
+	return a.emit(bpfLDX|size|bpfMEM, dst, src, off, 0)
+}
+
+func (a *bpfAsm) stx(size, dst, src uint8, off int16) *bpfAsm {
+	return a.emit(bpfSTX|size|bpfMEM, dst, src, off, 0)
+}
+
+func (a *bpfAsm) st(size, dst uint8, off int16, imm int32) *bpfAsm {
+	return a.emit(bpfST|size|bpfMEM, dst, 0, off, imm)
+}
+
+// ldMapFD load the map fd into dst, the fd is replaced with the map by the kernel
+func (a *bpfAsm) ldMapFD(dst uint8, fd int) *bpfAsm {
+	a.emit(bpfDW|bpfIMM, dst, bpfPseudoMapFD, 0, int32(fd))
+	return a.emit(0, 0, 0, 0, 0)
+}
+
+func (a *bpfAsm) jmpImm(op, dst uint8, imm int32, target string) *bpfAsm {
+	a.jumps[len(a.insns)] = target
+	return a.emit(bpfJMP|op|bpfK, dst, 0, 0, imm)
+}
+
+func (a *bpfAsm) jmpReg(op, dst, src uint8, target string) *bpfAsm {
+	a.jumps[len(a.insns)] = target
+	return a.emit(bpfJMP|op|bpfX, dst, src, 0, 0)
+}
+
+func (a *bpfAsm) ja(target string) *bpfAsm {
+	return a.jmpImm(bpfJA, 0, 0, target)
+}
+
+func (a *bpfAsm) call(fn int32) *bpfAsm {
+	return a.emit(bpfJMP|bpfCALL, 0, 0, 0, fn)
+}
+
+func (a *bpfAsm) exit() *bpfAsm {
+	return a.emit(bpfJMP|bpfEXIT, 0, 0, 0, 0)
+}
+
+// assemble resolve the jump offset
+func (a *bpfAsm) assemble() ([]bpfInsn, error) {
+	for pc, target := range a.jumps {
+		to, ok := a.labels[target]
+		if !ok {
+			return nil, fmt.Errorf("label %s not found", target)
+		}
+		a.insns[pc].Off = int16(to - pc - 1)
+	}
+	return a.insns, nil
+}
+
+func bpfSyscall(cmd int, attr unsafe.Pointer, size uintptr) (int, error) {
+	r, _, errno := unix.Syscall(unix.SYS_BPF, uintptr(cmd), uintptr(attr), size)
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(r), nil
+}
+
+type bpfMapCreateAttr struct {
+	MapType    uint32
+	KeySize    uint32
+	ValueSize  uint32
+	MaxEntries uint32
+	MapFlags   uint32
+}
+
+func bpfCreateMap(mapType, keySize, valueSize, maxEntries uint32) (int, error) {
+	attr := bpfMapCreateAttr{
+		MapType:    mapType,
+		KeySize:    keySize,
+		ValueSize:  valueSize,
+		MaxEntries: maxEntries,
+	}
+	return bpfSyscall(bpfMapCreate, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+}
+
+type bpfMapElemAttr struct {
+	MapFD uint32
+	_     uint32
+	Key   uint64
+	Value uint64
+	Flags uint64
+}
+
+func bpfMapElem(cmd int, fd int, key, value unsafe.Pointer) error {
+	attr := bpfMapElemAttr{
+		MapFD: uint32(fd),
+		Key:   uint64(uintptr(key)),
+		Value: uint64(uintptr(value)),
+	}
+	_, err := bpfSyscall(cmd, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	runtime.KeepAlive(key)
+	runtime.KeepAlive(value)
+	return err
+}
+
+type bpfObjAttr struct {
+	Pathname  uint64
+	BpfFD     uint32
+	FileFlags uint32
+}
+
+func bpfPin(fd int, path string) error {
+	p, err := unix.BytePtrFromString(path)
+	if err != nil {
+		return err
+	}
+	attr := bpfObjAttr{
+		Pathname: uint64(uintptr(unsafe.Pointer(p))),
+		BpfFD:    uint32(fd),
+	}
+	_, err = bpfSyscall(bpfObjPin, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	runtime.KeepAlive(p)
+	return err
+}
+
+func bpfGetPinned(path string) (int, error) {
+	p, err := unix.BytePtrFromString(path)
+	if err != nil {
+		return -1, err
+	}
+	attr := bpfObjAttr{
+		Pathname: uint64(uintptr(unsafe.Pointer(p))),
+	}
+	fd, err := bpfSyscall(bpfObjGet, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	runtime.KeepAlive(p)
+	return fd, err
+}
+
+type bpfProgLoadAttr struct {
+	ProgType    uint32
+	InsnCnt     uint32
+	Insns       uint64
+	License     uint64
+	LogLevel    uint32
+	LogSize     uint32
+	LogBuf      uint64
+	KernVersion uint32
+	_           uint32
+}
+
+// bpfLoadProg load the program, the verifier log is returned in the error
+func bpfLoadProg(progType uint32, insns []bpfInsn, license string) (int, error) {
+	lic, err := unix.BytePtrFromString(license)
+	if err != nil {
+		return -1, err
+	}
+	logBuf := make([]byte, bpfVerifierLogLength)
+	attr := bpfProgLoadAttr{
+		ProgType: progType,
+		InsnCnt:  uint32(len(insns)),
+		Insns:    uint64(uintptr(unsafe.Pointer(&insns[0]))),
+		License:  uint64(uintptr(unsafe.Pointer(lic))),
+		LogLevel: 1,
+		LogSize:  uint32(len(logBuf)),
+		LogBuf:   uint64(uintptr(unsafe.Pointer(&logBuf[0]))),
+	}
+	fd, err := bpfSyscall(bpfProgLoad, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	runtime.KeepAlive(insns)
+	runtime.KeepAlive(lic)
+	runtime.KeepAlive(logBuf)
+	if err != nil {
+		return -1, fmt.Errorf("load bpf program error, %w, verifier log: %s", err, unix.ByteSliceToString(logBuf))
+	}
+	return fd, nil
+}
@@ -0,0 +1,337 @@
+package tc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+	"unsafe"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// EDTDirection is the direction of the pod traffic shaped by edt
+type EDTDirection int
+
+const (
+	// EDTEgress shape the traffic from the pod, match by the src ip
+	EDTEgress EDTDirection = iota
+	// EDTIngress shape the traffic to the pod, match by the dst ip
+	EDTIngress
+)
+
+func (d EDTDirection) String() string {
+	if d == EDTIngress {
+		return "ingress"
+	}
+	return "egress"
+}
+
+const (
+	// EDTPinPath is the bpffs dir the rate maps pinned at, so the rate can be updated by other process
+	EDTPinPath = "/sys/fs/bpf/terway"
+
+	edtMapMaxEntries = 65536
+	// edtFilterPriority run after the ipvlan redirect filter(40000), so the traffic between pods on the node
+	// is not counted, and before the vlan tag filter(50001) which stop the classify
+	edtFilterPriority = 45000
+	// edtHorizon the packet wait longer than it is dropped
+	edtHorizon = 2 * time.Second
+
+	// offset in struct __sk_buff
+	skbLenOff      = 0
+	skbProtocolOff = 16
+	skbTstampOff   = 152
+
+	ethHeaderLen = 14
+
+	tcActUnspec = -1
+	tcActShot   = 2
+)
+
+// edtValue is the value in the rate map, the last departure time is updated by the bpf program
+type edtValue struct {
+	// Rate in bytes per second
+	Rate uint64
+	// TLast the departure time of the last packet in ns
+	TLast uint64
+}
+
+// edtKey the ip is stored as ipv6, ipv4 is mapped to ::ffff:a.b.c.d
+func edtKey(ip net.IP) ([16]byte, error) {
+	var key [16]byte
+	v6 := ip.To16()
+	if v6 == nil {
+		return key, fmt.Errorf("invalid ip %s", ip)
+	}
+	copy(key[:], v6)
+	return key, nil
+}
+
+func htons(v uint16) int32 {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, v)
+	return int32(binary.LittleEndian.Uint16(b))
+}
+
+// edtProgram stamp the departure time of the packet by the rate of the pod, the fq qdisc send the packet
+// at the departure time. Packet not belong to any pod is passed to the next filter.
+func edtProgram(dir EDTDirection, mapFD int) ([]bpfInsn, error) {
+	// offset of the ip in the packet
+	ipv4Off, ipv6Off := int32(ethHeaderLen+12), int32(ethHeaderLen+8)
+	if dir == EDTIngress {
+		ipv4Off, ipv6Off = ethHeaderLen+16, ethHeaderLen+24
+	}
+
+	a := newBPFAsm()
+	a.movReg(r6, r1).
+		ldx(bpfW, r2, r6, skbProtocolOff).
+		jmpImm(bpfJEQ, r2, htons(unix.ETH_P_IP), "ipv4").
+		jmpImm(bpfJEQ, r2, htons(unix.ETH_P_IPV6), "ipv6").
+		ja("pass")
+
+	// key is at r10-16, the ipv4 is mapped to ::ffff:a.b.c.d
+	a.label("ipv4").
+		st(bpfDW, r10, -16, 0).
+		st(bpfW, r10, -8, int32(binary.LittleEndian.Uint32([]byte{0, 0, 0xff, 0xff}))).
+		movReg(r1, r6).
+		movImm(r2, ipv4Off).
+		movReg(r3, r10).
+		aluImm(bpfADD, r3, -4).
+		movImm(r4, 4).
+		call(bpfFuncSkbLoadBytes).
+		jmpImm(bpfJNE, r0, 0, "pass").
+		ja("lookup")
+
+	a.label("ipv6").
+		movReg(r1, r6).
+		movImm(r2, ipv6Off).
+		movReg(r3, r10).
+		aluImm(bpfADD, r3, -16).
+		movImm(r4, 16).
+		call(bpfFuncSkbLoadBytes).
+		jmpImm(bpfJNE, r0, 0, "pass")
+
+	a.label("lookup").
+		ldMapFD(r1, mapFD).
+		movReg(r2, r10).
+		aluImm(bpfADD, r2, -16).
+		call(bpfFuncMapLookupElem).
+		jmpImm(bpfJEQ, r0, 0, "pass").
+		movReg(r7, r0).
+		// r8 = rate
+		ldx(bpfDW, r8, r7, 0).
+		jmpImm(bpfJEQ, r8, 0, "pass").
+		call(bpfFuncKtimeGetNs).
+		// r9 = now
+		movReg(r9, r0).
+		// r1 = delay of this packet
+		ldx(bpfW, r1, r6, skbLenOff).
+		aluImm(bpfMUL, r1, int32(time.Second)).
+		aluReg(bpfDIV, r1, r8).
+		// r2 = the departure time
+		ldx(bpfDW, r2, r7, 8).
+		aluReg(bpfADD, r2, r1).
+		jmpReg(bpfJGT, r2, r9, "delay").
+		// under the rate, send now
+		stx(bpfDW, r7, r9, 8).
+		ja("pass")
+
+	a.label("delay").
+		movReg(r3, r2).
+		aluReg(bpfSUB, r3, r9).
+		jmpImm(bpfJGT, r3, int32(edtHorizon), "drop").
+		stx(bpfDW, r7, r2, 8).
+		stx(bpfDW, r6, r2, skbTstampOff)
+
+	a.label("pass").
+		movImm(r0, tcActUnspec).
+		exit()
+
+	a.label("drop").
+		movImm(r0, tcActShot).
+		exit()
+
+	return a.assemble()
+}
+
+func edtMapPath(dir EDTDirection) string {
+	return filepath.Join(EDTPinPath, "edt_"+dir.String())
+}
+
+// openEDTMap return the fd of the pinned rate map, the map is created if create is true
+func openEDTMap(dir EDTDirection, create bool) (int, error) {
+	path := edtMapPath(dir)
+	fd, err := bpfGetPinned(path)
+	if err == nil {
+		return fd, nil
+	}
+	if !errors.Is(err, unix.ENOENT) || !create {
+		return -1, err
+	}
+
+	err = os.MkdirAll(EDTPinPath, 0700)
+	if err != nil {
+		return -1, err
+	}
+	fd, err = bpfCreateMap(bpfMapTypeHash, 16, uint32(unsafe.Sizeof(edtValue{})), edtMapMaxEntries)
+	if err != nil {
+		return -1, fmt.Errorf("create edt map error, %w", err)
+	}
+	err = bpfPin(fd, path)
+	if err != nil {
+		_ = unix.Close(fd)
+		if errors.Is(err, unix.EEXIST) {
+			return bpfGetPinned(path)
+		}
+		return -1, fmt.Errorf("pin edt map at %s error, is bpffs mounted? %w", path, err)
+	}
+	return fd, nil
+}
+
+// SetEDTRate set the rate in bytes for the ip, take effect on the next packet
+func SetEDTRate(dir EDTDirection, ip net.IP, rate uint64) error {
+	key, err := edtKey(ip)
+	if err != nil {
+		return err
+	}
+	fd, err := openEDTMap(dir, true)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	value := edtValue{Rate: rate}
+	err = bpfMapElem(bpfMapUpdateElem, fd, unsafe.Pointer(&key), unsafe.Pointer(&value))
+	if err != nil {
+		return fmt.Errorf("update edt %s rate for %s error, %w", dir, ip, err)
+	}
+	return nil
+}
+
+// GetEDTRate return the rate for the ip, false if no rate is set
+func GetEDTRate(dir EDTDirection, ip net.IP) (uint64, bool, error) {
+	key, err := edtKey(ip)
+	if err != nil {
+		return 0, false, err
+	}
+	fd, err := openEDTMap(dir, false)
+	if err != nil {
+		if errors.Is(err, unix.ENOENT) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	defer unix.Close(fd)
+
+	value := edtValue{}
+	err = bpfMapElem(bpfMapLookupElem, fd, unsafe.Pointer(&key), unsafe.Pointer(&value))
+	if err != nil {
+		if errors.Is(err, unix.ENOENT) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("lookup edt %s rate for %s error, %w", dir, ip, err)
+	}
+	return value.Rate, true, nil
+}
+
+// DelEDTRate remove the rate for the ip
+func DelEDTRate(dir EDTDirection, ip net.IP) error {
+	key, err := edtKey(ip)
+	if err != nil {
+		return err
+	}
+	fd, err := openEDTMap(dir, false)
+	if err != nil {
+		if errors.Is(err, unix.ENOENT) {
+			return nil
+		}
+		return err
+	}
+	defer unix.Close(fd)
+
+	err = bpfMapElem(bpfMapDeleteElem, fd, unsafe.Pointer(&key), nil)
+	if err != nil && !errors.Is(err, unix.ENOENT) {
+		return fmt.Errorf("delete edt %s rate for %s error, %w", dir, ip, err)
+	}
+	return nil
+}
+
+func edtFilterName(dir EDTDirection) string {
+	return "terway_edt_" + dir.String()
+}
+
+// EnsureEDTFilter attach the edt program at the egress of the link, the clsact qdisc is required,
+// return changed and err
+func EnsureEDTFilter(link netlink.Link, dir EDTDirection) (bool, error) {
+	filters, err := netlink.FilterList(link, netlink.HANDLE_MIN_EGRESS)
+	if err != nil {
+		return false, fmt.Errorf("list egress filter for %s error, %w", link.Attrs().Name, err)
+	}
+	for _, filter := range filters {
+		bpf, ok := filter.(*netlink.BpfFilter)
+		if !ok || bpf.Priority != edtFilterPriority {
+			continue
+		}
+		if bpf.Name == edtFilterName(dir) && bpf.DirectAction {
+			return false, nil
+		}
+	}
+
+	mapFD, err := openEDTMap(dir, true)
+	if err != nil {
+		return false, err
+	}
+	defer unix.Close(mapFD)
+
+	insns, err := edtProgram(dir, mapFD)
+	if err != nil {
+		return false, err
+	}
+	progFD, err := bpfLoadProg(bpfProgTypeSchedCLS, insns, "Apache-2.0")
+	if err != nil {
+		return false, err
+	}
+	// the filter hold the reference of the program
+	defer unix.Close(progFD)
+
+	filter := &netlink.BpfFilter{
+		FilterAttrs: netlink.FilterAttrs{
+			LinkIndex: link.Attrs().Index,
+			Parent:    netlink.HANDLE_MIN_EGRESS,
+			Priority:  edtFilterPriority,
+			Protocol:  unix.ETH_P_ALL,
+		},
+		Fd:           progFD,
+		Name:         edtFilterName(dir),
+		DirectAction: true,
+	}
+	err = netlink.FilterReplace(filter)
+	if err != nil {
+		return true, fmt.Errorf("attach edt program to %s error, %w", link.Attrs().Name, err)
+	}
+	return true, nil
+}
+
+// DelEDTFilter detach the edt program from the link
+func DelEDTFilter(link netlink.Link) error {
+	filters, err := netlink.FilterList(link, netlink.HANDLE_MIN_EGRESS)
+	if err != nil {
+		return fmt.Errorf("list egress filter for %s error, %w", link.Attrs().Name, err)
+	}
+	for _, filter := range filters {
+		bpf, ok := filter.(*netlink.BpfFilter)
+		if !ok || bpf.Priority != edtFilterPriority {
+			continue
+		}
+		err = netlink.FilterDel(filter)
+		if err != nil {
+			return fmt.Errorf("delete edt filter on %s error, %w", link.Attrs().Name, err)
+		}
+	}
+	return nil
+}
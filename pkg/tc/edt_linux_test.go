@@ -0,0 +1,96 @@
+package tc
+
+import (
+	"net"
+	"testing"
+	"unsafe"
+)
+
+func TestBPFAsmAssemble(t *testing.T) {
+	a := newBPFAsm()
+	a.jmpImm(bpfJEQ, r1, 0, "out").
+		movImm(r0, 1).
+		ldMapFD(r1, 3).
+		ja("out").
+		label("out").
+		exit()
+
+	insns, err := a.assemble()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(insns) != 6 {
+		t.Fatalf("expected 6 insns, got %d", len(insns))
+	}
+	// the ld imm64 take two slots
+	if insns[0].Off != 4 {
+		t.Errorf("expected offset 4 for the first jump, got %d", insns[0].Off)
+	}
+	if insns[4].Off != 0 {
+		t.Errorf("expected offset 0 for ja, got %d", insns[4].Off)
+	}
+	if insns[2].Regs != bpfPseudoMapFD<<4|r1 || insns[2].Imm != 3 {
+		t.Errorf("unexpected ld map fd insn %+v", insns[2])
+	}
+
+	_, err = newBPFAsm().ja("missing").assemble()
+	if err == nil {
+		t.Errorf("expected error for missing label")
+	}
+}
+
+func TestBPFInsnSize(t *testing.T) {
+	if unsafe.Sizeof(bpfInsn{}) != 8 {
+		t.Errorf("bpf insn should be 8 bytes, got %d", unsafe.Sizeof(bpfInsn{}))
+	}
+	if unsafe.Sizeof(edtValue{}) != 16 {
+		t.Errorf("edt value should be 16 bytes, got %d", unsafe.Sizeof(edtValue{}))
+	}
+}
+
+func TestEDTKey(t *testing.T) {
+	key, err := edtKey(net.ParseIP("192.168.0.10"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [16]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 192, 168, 0, 10}
+	if key != expected {
+		t.Errorf("unexpected key %v", key)
+	}
+
+	key, err = edtKey(net.ParseIP("fd00::10"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = [16]byte{0xfd, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x10}
+	if key != expected {
+		t.Errorf("unexpected key %v", key)
+	}
+
+	_, err = edtKey(nil)
+	if err == nil {
+		t.Errorf("expected error for nil ip")
+	}
+}
+
+func TestEDTProgram(t *testing.T) {
+	for _, dir := range []EDTDirection{EDTEgress, EDTIngress} {
+		insns, err := edtProgram(dir, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		last := insns[len(insns)-1]
+		if last.Code != bpfJMP|bpfEXIT {
+			t.Errorf("%s: program should end with exit", dir)
+		}
+		for pc, insn := range insns {
+			if insn.Code&0x07 != bpfJMP || insn.Code == bpfJMP|bpfCALL || insn.Code == bpfJMP|bpfEXIT {
+				continue
+			}
+			to := pc + 1 + int(insn.Off)
+			if to <= pc || to >= len(insns) {
+				t.Errorf("%s: jump at %d to %d out of range", dir, pc, to)
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+//go:build privileged
+
+package tc
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestEDTProgramVerifier(t *testing.T) {
+	for _, dir := range []EDTDirection{EDTEgress, EDTIngress} {
+		t.Run(dir.String(), func(t *testing.T) {
+			mapFD, err := bpfCreateMap(bpfMapTypeHash, 16, uint32(unsafe.Sizeof(edtValue{})), edtMapMaxEntries)
+			if err != nil {
+				t.Fatalf("create edt map error, %v", err)
+			}
+			defer unix.Close(mapFD)
+
+			insns, err := edtProgram(dir, mapFD)
+			if err != nil {
+				t.Fatal(err)
+			}
+			progFD, err := bpfLoadProg(bpfProgTypeSchedCLS, insns, "Apache-2.0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = unix.Close(progFD)
+		})
+	}
+}
+
+func TestEDTMapElem(t *testing.T) {
+	mapFD, err := bpfCreateMap(bpfMapTypeHash, 16, uint32(unsafe.Sizeof(edtValue{})), edtMapMaxEntries)
+	if err != nil {
+		t.Fatalf("create edt map error, %v", err)
+	}
+	defer unix.Close(mapFD)
+
+	key, err := edtKey(net.ParseIP("192.168.0.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := edtValue{Rate: 1 << 20}
+	err = bpfMapElem(bpfMapUpdateElem, mapFD, unsafe.Pointer(&key), unsafe.Pointer(&value))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := edtValue{}
+	err = bpfMapElem(bpfMapLookupElem, mapFD, unsafe.Pointer(&key), unsafe.Pointer(&got))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Rate != value.Rate {
+		t.Fatalf("expect rate %d, got %d", value.Rate, got.Rate)
+	}
+
+	err = bpfMapElem(bpfMapDeleteElem, mapFD, unsafe.Pointer(&key), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = bpfMapElem(bpfMapLookupElem, mapFD, unsafe.Pointer(&key), unsafe.Pointer(&got))
+	if !errors.Is(err, unix.ENOENT) {
+		t.Fatalf("expect ENOENT after delete, got %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestExporter() *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	return exporter
+}
+
+func TestSetup_NoEndpoint(t *testing.T) {
+	shutdown, err := Setup(context.Background(), "test", "")
+	assert.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+	assert.NotNil(t, otel.GetTextMapPropagator())
+}
+
+func TestEnd(t *testing.T) {
+	exporter := newTestExporter()
+
+	_, span := Start(context.Background(), "ok")
+	End(span, nil)
+	_, span = Start(context.Background(), "failed")
+	End(span, errors.New("foo"))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, codes.Unset, spans[0].Status.Code)
+	assert.Equal(t, codes.Error, spans[1].Status.Code)
+	assert.Equal(t, "foo", spans[1].Status.Description)
+	assert.Len(t, spans[1].Events, 1)
+}
+
+// TestGRPCPropagation the span of the server is the child of the client, as the cni and the daemon
+func TestGRPCPropagation(t *testing.T) {
+	exporter := newTestExporter()
+	_, err := Setup(context.Background(), "test", "")
+	require.NoError(t, err)
+
+	l := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(grpc.UnaryInterceptor(otelgrpc.UnaryServerInterceptor()))
+	grpc_health_v1.RegisterHealthServer(server, health.NewServer())
+	go func() {
+		_ = server.Serve(l)
+	}()
+	defer server.Stop()
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+			return l.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, span := Start(context.Background(), "cmdAdd")
+	_, err = grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+	span.End()
+
+	spans := exporter.GetSpans()
+	byKind := map[trace.SpanKind]tracetest.SpanStub{}
+	for _, s := range spans {
+		byKind[s.SpanKind] = s
+	}
+	client, srv := byKind[trace.SpanKindClient], byKind[trace.SpanKindServer]
+	assert.Equal(t, span.SpanContext().TraceID(), client.SpanContext.TraceID())
+	assert.Equal(t, span.SpanContext().SpanID(), client.Parent.SpanID())
+	assert.Equal(t, client.SpanContext.TraceID(), srv.SpanContext.TraceID())
+	assert.Equal(t, client.SpanContext.SpanID(), srv.Parent.SpanID())
+	assert.True(t, srv.Parent.IsRemote())
+}
@@ -0,0 +1,58 @@
+package vswitch
+
+import (
+	"sync"
+	"time"
+)
+
+// maxFailures the failures kept for each vSwitch, the older one is dropped
+const maxFailures = 1024
+
+// failures the time of the allocation failed on the vSwitches
+type failures struct {
+	lock sync.Mutex
+	at   map[string][]time.Time
+}
+
+func (f *failures) record(id string, at time.Time) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.at == nil {
+		f.at = make(map[string][]time.Time)
+	}
+	times := append(f.at[id], at)
+	if len(times) > maxFailures {
+		times = times[len(times)-maxFailures:]
+	}
+	f.at[id] = times
+}
+
+func (f *failures) count(id string, since time.Time) int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	times := f.at[id]
+	// drop the failures out of the window
+	start := 0
+	for start < len(times) && times[start].Before(since) {
+		start++
+	}
+	times = times[start:]
+	if len(times) == 0 {
+		delete(f.at, id)
+	} else {
+		f.at[id] = times
+	}
+	return len(times)
+}
+
+// RecordFailure record an allocation failed on the vSwitch
+func (s *SwitchPool) RecordFailure(id string) {
+	s.failures.record(id, time.Now())
+}
+
+// Failures return the count of allocation failed on the vSwitch in the window
+func (s *SwitchPool) Failures(id string, window time.Duration) int {
+	return s.failures.count(id, time.Now().Add(-window))
+}
@@ -0,0 +1,108 @@
+package vswitch
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Forecast the projected capacity of the vSwitch
+type Forecast struct {
+	ID string
+	// Available is the latest available ip count
+	Available int64
+	// ConsumePerHour is the ip consumed per hour in the window, negative if the ip is released
+	ConsumePerHour float64
+	// ExhaustIn is the time left before the vSwitch runs out of ip, math.MaxInt64 if the ip is not consumed
+	ExhaustIn time.Duration
+}
+
+// Exhausting return true if the vSwitch is empty or runs out of ip within the threshold
+func (f *Forecast) Exhausting(threshold time.Duration) bool {
+	return f.Available <= 0 || f.ExhaustIn <= threshold
+}
+
+type sample struct {
+	at        time.Time
+	available int64
+}
+
+// Forecaster record the available ip of the vSwitches over time, the consumption is the linear fit of the samples in the window
+type Forecaster struct {
+	lock    sync.Mutex
+	window  time.Duration
+	samples map[string][]sample
+}
+
+// NewForecaster create a forecaster keeping the samples in the window
+func NewForecaster(window time.Duration) *Forecaster {
+	return &Forecaster{
+		window:  window,
+		samples: make(map[string][]sample),
+	}
+}
+
+// Observe record the available ip count of the vSwitch at the time
+func (f *Forecaster) Observe(id string, at time.Time, available int64) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	samples := append(f.samples[id], sample{at: at, available: available})
+	// drop the samples out of window
+	start := 0
+	for start < len(samples)-1 && at.Sub(samples[start].at) > f.window {
+		start++
+	}
+	f.samples[id] = samples[start:]
+}
+
+// Forget drop the samples of the vSwitch
+func (f *Forecaster) Forget(id string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	delete(f.samples, id)
+}
+
+// Forecast project the capacity of the vSwitch, false if the vSwitch is never observed
+func (f *Forecaster) Forecast(id string) (Forecast, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	samples := f.samples[id]
+	if len(samples) == 0 {
+		return Forecast{}, false
+	}
+	last := samples[len(samples)-1]
+	r := Forecast{
+		ID:        id,
+		Available: last.available,
+		ExhaustIn: time.Duration(math.MaxInt64),
+	}
+	if last.available <= 0 {
+		r.ExhaustIn = 0
+	}
+
+	// least squares fit of available = a + b*hours
+	n := float64(len(samples))
+	var sumX, sumY, sumXY, sumXX float64
+	for _, s := range samples {
+		x := s.at.Sub(samples[0].at).Hours()
+		y := float64(s.available)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return r, true
+	}
+	slope := (n*sumXY - sumX*sumY) / denominator
+	r.ConsumePerHour = -slope
+
+	if r.ConsumePerHour > 0 && last.available > 0 {
+		r.ExhaustIn = time.Duration(float64(last.available) / r.ConsumePerHour * float64(time.Hour))
+	}
+	return r, true
+}
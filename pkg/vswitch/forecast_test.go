@@ -0,0 +1,47 @@
+package vswitch
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForecaster(t *testing.T) {
+	f := NewForecaster(3 * time.Hour)
+	_, ok := f.Forecast("vsw-1")
+	assert.False(t, ok)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	f.Observe("vsw-1", start, 100)
+	r, ok := f.Forecast("vsw-1")
+	assert.True(t, ok)
+	assert.Equal(t, int64(100), r.Available)
+	assert.Equal(t, time.Duration(math.MaxInt64), r.ExhaustIn)
+
+	// 10 ip per hour
+	f.Observe("vsw-1", start.Add(time.Hour), 90)
+	f.Observe("vsw-1", start.Add(2*time.Hour), 80)
+	r, _ = f.Forecast("vsw-1")
+	assert.InDelta(t, 10, r.ConsumePerHour, 0.001)
+	assert.InDelta(t, float64(8*time.Hour), float64(r.ExhaustIn), float64(time.Second))
+	assert.True(t, r.Exhausting(8*time.Hour))
+	assert.False(t, r.Exhausting(7*time.Hour))
+
+	// the samples out of window are dropped, the ip is released since
+	f.Observe("vsw-1", start.Add(5*time.Hour), 100)
+	f.Observe("vsw-1", start.Add(6*time.Hour), 100)
+	r, _ = f.Forecast("vsw-1")
+	assert.InDelta(t, 0, r.ConsumePerHour, 0.001)
+	assert.Equal(t, time.Duration(math.MaxInt64), r.ExhaustIn)
+
+	f.Observe("vsw-1", start.Add(7*time.Hour), 0)
+	r, _ = f.Forecast("vsw-1")
+	assert.Equal(t, time.Duration(0), r.ExhaustIn)
+	assert.True(t, r.Exhausting(0))
+
+	f.Forget("vsw-1")
+	_, ok = f.Forecast("vsw-1")
+	assert.False(t, ok)
+}
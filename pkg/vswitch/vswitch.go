@@ -20,10 +20,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"math/rand"
+	"net/netip"
 	"sort"
 	"time"
 
+	"github.com/aliyun/alibaba-cloud-sdk-go/services/vpc"
 	"k8s.io/apimachinery/pkg/util/cache"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
@@ -40,6 +43,12 @@ type Switch struct {
 	AvailableIPCount int64 // for ipv4
 	IPv4CIDR         string
 	IPv6CIDR         string
+
+	// Reason why the vSwitch is picked, only set on the one returned by GetOne
+	Reason string
+
+	// UpdatedAt when the vSwitch is described from openAPI, zero if the AvailableIPCount is not the one described
+	UpdatedAt time.Time
 }
 
 type ByAvailableIP []Switch
@@ -52,6 +61,8 @@ func (a ByAvailableIP) Less(i, j int) bool { return a[i].AvailableIPCount > a[j]
 type SwitchPool struct {
 	cache *cache.LRUExpireCache
 	ttl   time.Duration
+
+	failures failures
 }
 
 // NewSwitchPool create pool and set vSwitches to pool
@@ -64,16 +75,23 @@ func NewSwitchPool(size int, ttl string) (*SwitchPool, error) {
 	return &SwitchPool{cache: cache.NewLRUExpireCache(size), ttl: t}, nil
 }
 
-// GetOne get one vSwitch by zone and limit in ids
+// GetOne get one vSwitch by zone and limit in ids, the Reason of the returned vSwitch record why it is picked
 func (s *SwitchPool) GetOne(ctx context.Context, client client.VPC, zone string, ids []string, opts ...SelectOption) (*Switch, error) {
 	var fallBackSwitches []*Switch
 
 	selectOptions := &SelectOptions{}
 	selectOptions.ApplyOptions(opts)
 
+	reason := func(_ *Switch) string {
+		return fmt.Sprintf("first available vSwitch in zone %s", zone)
+	}
+
 	switch selectOptions.VSwitchSelectPolicy {
 	case VSwitchSelectionPolicyRandom:
 		rand.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+		reason = func(_ *Switch) string {
+			return fmt.Sprintf("random vSwitch in zone %s", zone)
+		}
 	case VSwitchSelectionPolicyMost:
 		// lookup all vsw in cache and get one matched
 		// try sort the vsw
@@ -102,6 +120,43 @@ func (s *SwitchPool) GetOne(ctx context.Context, client client.VPC, zone string,
 			newOrder = append(newOrder, vsw.ID)
 		}
 		ids = newOrder
+		reason = func(vsw *Switch) string {
+			return fmt.Sprintf("most available ip (%d) in zone %s", vsw.AvailableIPCount, zone)
+		}
+	case VSwitchSelectionPolicyWeighted:
+		ids = weightedOrder(ids, selectOptions.Weights)
+		reason = func(vsw *Switch) string {
+			return fmt.Sprintf("weighted pick with weight %d in zone %s", weightOf(selectOptions.Weights, vsw.ID), zone)
+		}
+	case VSwitchSelectionPolicyLeastUsedByNode:
+		ids = append([]string{}, ids...)
+		sort.SliceStable(ids, func(i, j int) bool {
+			return selectOptions.NodeUsage[ids[i]] < selectOptions.NodeUsage[ids[j]]
+		})
+		reason = func(vsw *Switch) string {
+			return fmt.Sprintf("least used by the node with %d eni in zone %s", selectOptions.NodeUsage[vsw.ID], zone)
+		}
+	case VSwitchSelectionPolicyPreferCIDR:
+		var preferred, others []string
+		for _, id := range ids {
+			vsw, err := s.GetByID(ctx, client, id)
+			if err != nil {
+				log.FromContext(ctx).Error(err, "get vSwitch", "id", id)
+				continue
+			}
+			if _, ok := inPrefixes(vsw.IPv4CIDR, selectOptions.PreferCIDRs); ok {
+				preferred = append(preferred, id)
+			} else {
+				others = append(others, id)
+			}
+		}
+		ids = append(preferred, others...)
+		reason = func(vsw *Switch) string {
+			if prefix, ok := inPrefixes(vsw.IPv4CIDR, selectOptions.PreferCIDRs); ok {
+				return fmt.Sprintf("cidr %s inside the preferred %s in zone %s", vsw.IPv4CIDR, prefix, zone)
+			}
+			return fmt.Sprintf("no available vSwitch inside the preferred cidrs %v in zone %s", selectOptions.PreferCIDRs, zone)
+		}
 	}
 
 	// lookup all vsw in cache and get one matched
@@ -121,19 +176,82 @@ func (s *SwitchPool) GetOne(ctx context.Context, client client.VPC, zone string,
 		if vsw.AvailableIPCount == 0 {
 			continue
 		}
-		return vsw, nil
+		return picked(ctx, vsw, fmt.Sprintf("%s: %s", policyName(selectOptions.VSwitchSelectPolicy), reason(vsw))), nil
 	}
 
 	for _, vsw := range fallBackSwitches {
 		if vsw.AvailableIPCount == 0 {
 			continue
 		}
-		return vsw, nil
+		return picked(ctx, vsw, fmt.Sprintf("no available vSwitch in zone %s, fall back to zone %s", zone, vsw.Zone)), nil
 	}
 
 	return nil, fmt.Errorf("no available vSwitch for zone %s, vswList %v, %w", zone, ids, ErrNoAvailableVSwitch)
 }
 
+// picked return a copy of the cached vSwitch with the reason
+func picked(ctx context.Context, vsw *Switch, reason string) *Switch {
+	sw := *vsw
+	sw.Reason = reason
+	log.FromContext(ctx).V(4).Info("vSwitch selected", "id", sw.ID, "reason", reason)
+	return &sw
+}
+
+func policyName(policy SelectionPolicy) string {
+	if policy == "" {
+		return string(VSwitchSelectionPolicyOrdered)
+	}
+	return string(policy)
+}
+
+func weightOf(weights map[string]int, id string) int {
+	w, ok := weights[id]
+	if !ok {
+		return 1
+	}
+	return w
+}
+
+// weightedOrder is the weighted random sampling without replacement, the vSwitch with higher weight is more likely in front.
+// The vSwitch not in weights has weight 1, the vSwitch with weight <= 0 is kept at the end.
+func weightedOrder(ids []string, weights map[string]int) []string {
+	type keyed struct {
+		id  string
+		key float64
+	}
+	keys := make([]keyed, 0, len(ids))
+	for _, id := range ids {
+		w := weightOf(weights, id)
+		key := -1.0
+		if w > 0 {
+			key = math.Pow(rand.Float64(), 1/float64(w))
+		}
+		keys = append(keys, keyed{id: id, key: key})
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].key > keys[j].key
+	})
+	result := make([]string, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, k.id)
+	}
+	return result
+}
+
+// inPrefixes return the prefix contains the cidr
+func inPrefixes(cidr string, prefixes []netip.Prefix) (netip.Prefix, bool) {
+	p, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	for _, prefix := range prefixes {
+		if prefix.Bits() <= p.Bits() && prefix.Contains(p.Addr()) {
+			return prefix, true
+		}
+	}
+	return netip.Prefix{}, false
+}
+
 // GetByID will get vSwitch info from local store or openAPI
 func (s *SwitchPool) GetByID(ctx context.Context, client client.VPC, id string) (*Switch, error) {
 	v, ok := s.cache.Get(id)
@@ -142,20 +260,43 @@ func (s *SwitchPool) GetByID(ctx context.Context, client client.VPC, id string)
 		if err != nil {
 			return nil, fmt.Errorf("error get vSwitch %s, %w", id, err)
 		}
-		sw := &Switch{
-			ID:               resp.VSwitchId,
-			Zone:             resp.ZoneId,
-			AvailableIPCount: resp.AvailableIpAddressCount,
-			IPv4CIDR:         resp.CidrBlock,
-			IPv6CIDR:         resp.Ipv6CidrBlock,
-		}
-		s.cache.Add(resp.VSwitchId, sw, s.ttl)
-		return sw, nil
+		return s.Update(resp), nil
 	}
 	sw := v.(*Switch)
 	return sw, nil
 }
 
+// Capacity get the vSwitch described from openAPI within the maxAge.
+// The controllers tracking the available ip share it, so the vSwitch is described once in the maxAge.
+func (s *SwitchPool) Capacity(ctx context.Context, client client.VPC, id string, maxAge time.Duration) (*Switch, error) {
+	v, ok := s.cache.Get(id)
+	if ok {
+		sw := v.(*Switch)
+		if !sw.UpdatedAt.IsZero() && time.Since(sw.UpdatedAt) < maxAge {
+			return sw, nil
+		}
+	}
+	resp, err := client.DescribeVSwitchByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("error get vSwitch %s, %w", id, err)
+	}
+	return s.Update(resp), nil
+}
+
+// Update cache the vSwitch described from openAPI
+func (s *SwitchPool) Update(resp *vpc.VSwitch) *Switch {
+	sw := &Switch{
+		ID:               resp.VSwitchId,
+		Zone:             resp.ZoneId,
+		AvailableIPCount: resp.AvailableIpAddressCount,
+		IPv4CIDR:         resp.CidrBlock,
+		IPv6CIDR:         resp.Ipv6CidrBlock,
+		UpdatedAt:        time.Now(),
+	}
+	s.cache.Add(resp.VSwitchId, sw, s.ttl)
+	return sw
+}
+
 func (s *SwitchPool) Block(id string) {
 	v, ok := s.cache.Get(id)
 	if !ok {
@@ -163,6 +304,7 @@ func (s *SwitchPool) Block(id string) {
 	}
 	vsw := *(v.(*Switch))
 	vsw.AvailableIPCount = 0
+	vsw.UpdatedAt = time.Time{}
 	s.cache.Add(id, &vsw, s.ttl)
 }
 
@@ -183,8 +325,25 @@ const (
 	VSwitchSelectionPolicyOrdered SelectionPolicy = "ordered"
 	VSwitchSelectionPolicyRandom  SelectionPolicy = "random"
 	VSwitchSelectionPolicyMost    SelectionPolicy = "most"
+	// VSwitchSelectionPolicyWeighted pick the vSwitch randomly by the Weights
+	VSwitchSelectionPolicyWeighted SelectionPolicy = "weighted"
+	// VSwitchSelectionPolicyLeastUsedByNode pick the vSwitch with the least eni on the node by the NodeUsage,
+	// so the enis of one node are spread across the vSwitches
+	VSwitchSelectionPolicyLeastUsedByNode SelectionPolicy = "least-used-by-node"
+	// VSwitchSelectionPolicyPreferCIDR pick the vSwitch inside the PreferCIDRs first
+	VSwitchSelectionPolicyPreferCIDR SelectionPolicy = "prefer-cidr"
 )
 
+// ParseSelectionPolicy return the policy by name, false if the policy is unknown
+func ParseSelectionPolicy(name string) (SelectionPolicy, bool) {
+	switch p := SelectionPolicy(name); p {
+	case VSwitchSelectionPolicyOrdered, VSwitchSelectionPolicyRandom, VSwitchSelectionPolicyMost,
+		VSwitchSelectionPolicyWeighted, VSwitchSelectionPolicyLeastUsedByNode, VSwitchSelectionPolicyPreferCIDR:
+		return p, true
+	}
+	return "", false
+}
+
 type SelectOption interface {
 	// Apply applies this configuration to the given select options.
 	Apply(*SelectOptions)
@@ -195,6 +354,13 @@ type SelectOptions struct {
 	IgnoreZone bool
 
 	VSwitchSelectPolicy SelectionPolicy
+
+	// Weights the weight of the vSwitch for the weighted policy, default is 1
+	Weights map[string]int
+	// NodeUsage the eni count of the vSwitch on the node for the least-used-by-node policy
+	NodeUsage map[string]int
+	// PreferCIDRs for the prefer-cidr policy
+	PreferCIDRs []netip.Prefix
 }
 
 // ApplyOptions applies the given select options on these options
@@ -213,4 +379,13 @@ func (o *SelectOptions) Apply(so *SelectOptions) {
 	if o.VSwitchSelectPolicy != "" {
 		so.VSwitchSelectPolicy = o.VSwitchSelectPolicy
 	}
+	if o.Weights != nil {
+		so.Weights = o.Weights
+	}
+	if o.NodeUsage != nil {
+		so.NodeUsage = o.NodeUsage
+	}
+	if o.PreferCIDRs != nil {
+		so.PreferCIDRs = o.PreferCIDRs
+	}
 }
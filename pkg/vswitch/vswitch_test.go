@@ -18,7 +18,9 @@ package vswitch
 
 import (
 	"context"
+	"net/netip"
 	"testing"
+	"time"
 
 	"github.com/aliyun/alibaba-cloud-sdk-go/services/vpc"
 	"github.com/stretchr/testify/assert"
@@ -69,3 +71,148 @@ func TestSwitchPool_GetOne(t *testing.T) {
 
 	assert.Equal(t, 2, len(ids))
 }
+
+func newTestPool(t *testing.T, vSwitches ...*Switch) *SwitchPool {
+	switchPool, err := NewSwitchPool(100, "100m")
+	assert.NoError(t, err)
+	for _, vsw := range vSwitches {
+		switchPool.Add(vsw)
+	}
+	return switchPool
+}
+
+func TestSwitchPool_GetOneWeighted(t *testing.T) {
+	switchPool := newTestPool(t,
+		&Switch{ID: "vsw-1", Zone: "zone-1", AvailableIPCount: 10},
+		&Switch{ID: "vsw-2", Zone: "zone-1", AvailableIPCount: 10},
+		&Switch{ID: "vsw-3", Zone: "zone-1", AvailableIPCount: 10},
+	)
+
+	count := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		sw, err := switchPool.GetOne(context.Background(), nil, "zone-1", []string{"vsw-1", "vsw-2", "vsw-3"}, &SelectOptions{
+			VSwitchSelectPolicy: VSwitchSelectionPolicyWeighted,
+			Weights:             map[string]int{"vsw-1": 9, "vsw-3": 0},
+		})
+		assert.NoError(t, err)
+		count[sw.ID]++
+	}
+	assert.Greater(t, count["vsw-1"], count["vsw-2"])
+	assert.Equal(t, 0, count["vsw-3"])
+
+	// the vSwitch with weight 0 is used when others are not available
+	switchPool.Block("vsw-1")
+	switchPool.Block("vsw-2")
+	sw, err := switchPool.GetOne(context.Background(), nil, "zone-1", []string{"vsw-1", "vsw-2", "vsw-3"}, &SelectOptions{
+		VSwitchSelectPolicy: VSwitchSelectionPolicyWeighted,
+		Weights:             map[string]int{"vsw-3": 0},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "vsw-3", sw.ID)
+	assert.Equal(t, "weighted: weighted pick with weight 0 in zone zone-1", sw.Reason)
+}
+
+func TestSwitchPool_GetOneLeastUsedByNode(t *testing.T) {
+	switchPool := newTestPool(t,
+		&Switch{ID: "vsw-1", Zone: "zone-1", AvailableIPCount: 10},
+		&Switch{ID: "vsw-2", Zone: "zone-1", AvailableIPCount: 10},
+		&Switch{ID: "vsw-3", Zone: "zone-1", AvailableIPCount: 0},
+	)
+	ids := []string{"vsw-1", "vsw-2", "vsw-3"}
+
+	sw, err := switchPool.GetOne(context.Background(), nil, "zone-1", ids, &SelectOptions{
+		VSwitchSelectPolicy: VSwitchSelectionPolicyLeastUsedByNode,
+		NodeUsage:           map[string]int{"vsw-1": 2, "vsw-2": 1},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "vsw-2", sw.ID)
+	assert.Equal(t, "least-used-by-node: least used by the node with 1 eni in zone zone-1", sw.Reason)
+	// the order of the caller is kept
+	assert.Equal(t, []string{"vsw-1", "vsw-2", "vsw-3"}, ids)
+}
+
+func TestSwitchPool_GetOnePreferCIDR(t *testing.T) {
+	switchPool := newTestPool(t,
+		&Switch{ID: "vsw-1", Zone: "zone-1", AvailableIPCount: 10, IPv4CIDR: "10.0.0.0/24"},
+		&Switch{ID: "vsw-2", Zone: "zone-1", AvailableIPCount: 10, IPv4CIDR: "192.168.1.0/24"},
+		&Switch{ID: "vsw-3", Zone: "zone-1", AvailableIPCount: 10, IPv4CIDR: "192.168.0.0/16"},
+	)
+	opts := &SelectOptions{
+		VSwitchSelectPolicy: VSwitchSelectionPolicyPreferCIDR,
+		PreferCIDRs:         []netip.Prefix{netip.MustParsePrefix("192.168.0.0/20")},
+	}
+
+	sw, err := switchPool.GetOne(context.Background(), nil, "zone-1", []string{"vsw-1", "vsw-3", "vsw-2"}, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, "vsw-2", sw.ID)
+	assert.Equal(t, "prefer-cidr: cidr 192.168.1.0/24 inside the preferred 192.168.0.0/20 in zone zone-1", sw.Reason)
+
+	// fall back to the vSwitch out of the cidrs
+	switchPool.Block("vsw-2")
+	sw, err = switchPool.GetOne(context.Background(), nil, "zone-1", []string{"vsw-1", "vsw-3", "vsw-2"}, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, "vsw-1", sw.ID)
+	assert.Contains(t, sw.Reason, "no available vSwitch inside the preferred cidrs")
+}
+
+func TestSwitchPool_GetOneReason(t *testing.T) {
+	switchPool := newTestPool(t,
+		&Switch{ID: "vsw-1", Zone: "zone-1", AvailableIPCount: 10},
+		&Switch{ID: "vsw-2", Zone: "zone-2", AvailableIPCount: 10},
+	)
+
+	sw, err := switchPool.GetOne(context.Background(), nil, "zone-1", []string{"vsw-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "ordered: first available vSwitch in zone zone-1", sw.Reason)
+
+	// the cached one is not changed
+	cached, err := switchPool.GetByID(context.Background(), nil, "vsw-1")
+	assert.NoError(t, err)
+	assert.Empty(t, cached.Reason)
+
+	sw, err = switchPool.GetOne(context.Background(), nil, "zone-3", []string{"vsw-2"}, &SelectOptions{IgnoreZone: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "no available vSwitch in zone zone-3, fall back to zone zone-2", sw.Reason)
+}
+
+func TestSwitchPool_Failures(t *testing.T) {
+	switchPool := newTestPool(t)
+	assert.Equal(t, 0, switchPool.Failures("vsw-1", time.Minute))
+
+	now := time.Now()
+	switchPool.failures.record("vsw-1", now.Add(-2*time.Minute))
+	switchPool.RecordFailure("vsw-1")
+	switchPool.RecordFailure("vsw-1")
+	assert.Equal(t, 2, switchPool.Failures("vsw-1", time.Minute))
+	assert.Equal(t, 0, switchPool.Failures("vsw-2", time.Minute))
+}
+
+func TestSwitchPool_Capacity(t *testing.T) {
+	openAPI := mocks.NewVPC(t)
+	openAPI.On("DescribeVSwitchByID", mock.Anything, "vsw-1").Return(&vpc.VSwitch{
+		VSwitchId:               "vsw-1",
+		ZoneId:                  "zone-1",
+		AvailableIpAddressCount: 10,
+	}, nil).Twice()
+
+	// the one added without describing is not used
+	switchPool := newTestPool(t, &Switch{ID: "vsw-1", Zone: "zone-1", AvailableIPCount: 100})
+	ctx := context.Background()
+	sw, err := switchPool.Capacity(ctx, openAPI, "vsw-1", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), sw.AvailableIPCount)
+
+	// described within the max age
+	sw, err = switchPool.Capacity(ctx, openAPI, "vsw-1", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), sw.AvailableIPCount)
+
+	// the blocked one is described again
+	switchPool.Block("vsw-1")
+	sw, err = switchPool.GetByID(ctx, openAPI, "vsw-1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), sw.AvailableIPCount)
+	sw, err = switchPool.Capacity(ctx, openAPI, "vsw-1", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), sw.AvailableIPCount)
+}
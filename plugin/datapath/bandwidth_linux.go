@@ -0,0 +1,54 @@
+package datapath
+
+import (
+	"fmt"
+
+	"github.com/vishvananda/netlink"
+
+	"github.com/AliyunContainerService/terway/pkg/tc"
+	"github.com/AliyunContainerService/terway/plugin/driver/types"
+	"github.com/AliyunContainerService/terway/plugin/driver/utils"
+	terwayTypes "github.com/AliyunContainerService/terway/types"
+)
+
+// edtEnabled the bandwidth is limited by edt on the link the pod traffic go through
+func edtEnabled(cfg *types.SetupConfig) bool {
+	return cfg.BandwidthMode == types.BandwidthModeEDT
+}
+
+// edtOnENI the egress is limited by edt on the eni, the mq+fq on the eni conflict with the prio qdisc,
+// tbf on the pod link is used when network priority is enabled
+func edtOnENI(cfg *types.SetupConfig) bool {
+	return edtEnabled(cfg) && !cfg.EnableNetworkPriority
+}
+
+// ensureBandwidth limit the rate of the pod on the link by edt or tbf,return changed and err
+func ensureBandwidth(link netlink.Link, useEDT bool, dir tc.EDTDirection, ipNetSet *terwayTypes.IPNetSet, bandwidthInBytes uint64) (bool, error) {
+	if useEDT {
+		return utils.EnsureEDT(link, dir, ipNetSet, bandwidthInBytes)
+	}
+	if bandwidthInBytes == 0 {
+		return utils.DelTC(link)
+	}
+	return utils.EnsureTC(link, bandwidthInBytes)
+}
+
+// checkBandwidth verify the bandwidth limit for the pod on the link
+func checkBandwidth(cfg *types.CheckConfig, link netlink.Link, useEDT bool, dir tc.EDTDirection, ipNetSet *terwayTypes.IPNetSet, bandwidthInBytes uint64) error {
+	changed, err := ensureBandwidth(link, useEDT, dir, ipNetSet, bandwidthInBytes)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return nil
+	}
+	switch {
+	case useEDT:
+		recorder(cfg)(fmt.Sprintf("link %s set edt %s rate %d", link.Attrs().Name, dir, bandwidthInBytes))
+	case bandwidthInBytes == 0:
+		recorder(cfg)(fmt.Sprintf("link %s del tbf qdisc", link.Attrs().Name))
+	default:
+		recorder(cfg)(fmt.Sprintf("link %s set tbf qdisc rate %d", link.Attrs().Name, bandwidthInBytes))
+	}
+	return nil
+}
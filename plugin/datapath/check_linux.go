@@ -0,0 +1,52 @@
+package datapath
+
+import (
+	"fmt"
+
+	"github.com/AliyunContainerService/terway/plugin/driver/types"
+	"github.com/AliyunContainerService/terway/plugin/driver/utils"
+
+	"github.com/vishvananda/netlink"
+)
+
+// recorder return the func to report the drift repaired in check
+func recorder(cfg *types.CheckConfig) func(msg string) {
+	return func(msg string) {
+		utils.Log.Infof("check: %s", msg)
+		if cfg.RecordPodEvent != nil {
+			cfg.RecordPodEvent(msg)
+		}
+	}
+}
+
+// checkEgressPriority verify the egress priority filter for the pod on the eni
+func checkEgressPriority(cfg *types.CheckConfig, eni netlink.Link) error {
+	setupCfg := cfg.SetupConfig
+	if !setupCfg.EnableNetworkPriority {
+		return nil
+	}
+	changed, err := utils.SetEgressPriority(eni, setupCfg.NetworkPriority, setupCfg.ContainerIPNet)
+	if err != nil {
+		return err
+	}
+	if changed {
+		recorder(cfg)(fmt.Sprintf("link %s set egress priority filter", eni.Attrs().Name))
+	}
+	return nil
+}
+
+// checkVlanTag verify the vlan tag filter for the pod on the trunk eni
+func checkVlanTag(cfg *types.CheckConfig, eni netlink.Link) error {
+	setupCfg := cfg.SetupConfig
+	if !setupCfg.StripVlan {
+		return nil
+	}
+	changed, err := utils.EnsureVlanTag(eni, setupCfg.ContainerIPNet, uint16(setupCfg.Vid))
+	if err != nil {
+		return err
+	}
+	if changed {
+		recorder(cfg)(fmt.Sprintf("link %s set vlan tag filter", eni.Attrs().Name))
+	}
+	return nil
+}
@@ -0,0 +1,435 @@
+//go:build privileged
+
+package datapath
+
+import (
+	"net"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/AliyunContainerService/terway/plugin/driver/types"
+	"github.com/AliyunContainerService/terway/plugin/driver/utils"
+	terwayTypes "github.com/AliyunContainerService/terway/types"
+
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/containernetworking/plugins/pkg/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// corruption break one item of the datapath, check is expected to detect and repair it
+type corruption struct {
+	name string
+	// run in the container netns
+	inContainer bool
+	corrupt     func(t *testing.T)
+}
+
+func newCheckTestNS(t *testing.T) (ns.NetNS, ns.NetNS) {
+	runtime.LockOSThread()
+
+	hostNS, err := testutils.NewNS()
+	require.NoError(t, err)
+
+	containerNS, err := testutils.NewNS()
+	require.NoError(t, err)
+
+	require.NoError(t, hostNS.Set())
+
+	t.Cleanup(func() {
+		assert.NoError(t, containerNS.Close())
+		assert.NoError(t, testutils.UnmountNS(containerNS))
+		assert.NoError(t, hostNS.Close())
+		assert.NoError(t, testutils.UnmountNS(hostNS))
+		runtime.UnlockOSThread()
+	})
+	return hostNS, containerNS
+}
+
+func newCheckConfig(setupCfg *types.SetupConfig, containerNS ns.NetNS, events *[]string) *types.CheckConfig {
+	return &types.CheckConfig{
+		DP:              setupCfg.DP,
+		NetNS:           containerNS,
+		HostVETHName:    setupCfg.HostVETHName,
+		ContainerIfName: setupCfg.ContainerIfName,
+		ContainerIPNet:  setupCfg.ContainerIPNet,
+		HostIPSet:       setupCfg.HostIPSet,
+		GatewayIP:       setupCfg.GatewayIP,
+		ENIIndex:        int32(setupCfg.ENIIndex),
+		MTU:             setupCfg.MTU,
+		DefaultRoute:    setupCfg.DefaultRoute,
+		MultiNetwork:    setupCfg.MultiNetwork,
+		SetupConfig:     setupCfg,
+		RecordPodEvent: func(msg string) {
+			*events = append(*events, msg)
+		},
+	}
+}
+
+// runCorruptions apply each corruption, assert the check report it, and the second check is clean.
+// The cases run inline on the test goroutine, which is locked to the thread in the host netns.
+func runCorruptions(t *testing.T, hostNS, containerNS ns.NetNS, check func(cfg *types.CheckConfig) error, cfg *types.CheckConfig, events *[]string, cases []corruption) {
+	*events = nil
+	require.NoError(t, check(cfg))
+	require.Empty(t, *events, "datapath should be clean after setup")
+
+	for _, c := range cases {
+		if c.inContainer {
+			func() {
+				require.NoError(t, containerNS.Set())
+				defer func() {
+					require.NoError(t, hostNS.Set())
+				}()
+				c.corrupt(t)
+			}()
+		} else {
+			c.corrupt(t)
+		}
+
+		*events = nil
+		assert.NoError(t, check(cfg), c.name)
+		assert.NotEmpty(t, *events, "drift is not detected: %s", c.name)
+
+		*events = nil
+		assert.NoError(t, check(cfg), c.name)
+		assert.Empty(t, *events, "drift is not repaired: %s", c.name)
+	}
+}
+
+func linkDown(name string) func(t *testing.T) {
+	return func(t *testing.T) {
+		link, err := netlink.LinkByName(name)
+		require.NoError(t, err)
+		require.NoError(t, netlink.LinkSetDown(link))
+	}
+}
+
+func linkMTU(name string, mtu int) func(t *testing.T) {
+	return func(t *testing.T) {
+		link, err := netlink.LinkByName(name)
+		require.NoError(t, err)
+		require.NoError(t, netlink.LinkSetMTU(link, mtu))
+	}
+}
+
+func addrDel(name string, ipNet *net.IPNet) func(t *testing.T) {
+	return func(t *testing.T) {
+		link, err := netlink.LinkByName(name)
+		require.NoError(t, err)
+		require.NoError(t, netlink.AddrDel(link, &netlink.Addr{IPNet: ipNet}))
+	}
+}
+
+func routeDel(name string, dst *net.IPNet, table int) func(t *testing.T) {
+	return func(t *testing.T) {
+		link, err := netlink.LinkByName(name)
+		require.NoError(t, err)
+
+		filter := &netlink.Route{LinkIndex: link.Attrs().Index, Dst: dst, Table: table}
+		mask := netlink.RT_FILTER_OIF | netlink.RT_FILTER_DST
+		if table > 0 {
+			mask |= netlink.RT_FILTER_TABLE
+		}
+		if dst.String() == "0.0.0.0/0" || dst.String() == "::/0" {
+			filter.Dst = nil
+		}
+		routes, err := netlink.RouteListFiltered(utils.NetlinkFamily(dst.IP), filter, mask)
+		require.NoError(t, err)
+		require.NotEmpty(t, routes)
+		for _, r := range routes {
+			require.NoError(t, netlink.RouteDel(&r))
+		}
+	}
+}
+
+func neighDel(name string, ip net.IP) func(t *testing.T) {
+	return func(t *testing.T) {
+		link, err := netlink.LinkByName(name)
+		require.NoError(t, err)
+		require.NoError(t, netlink.NeighDel(&netlink.Neigh{LinkIndex: link.Attrs().Index, IP: ip}))
+	}
+}
+
+func ruleDel(rule *netlink.Rule) func(t *testing.T) {
+	return func(t *testing.T) {
+		rules, err := utils.FindIPRule(rule)
+		require.NoError(t, err)
+		require.NotEmpty(t, rules)
+		for _, r := range rules {
+			require.NoError(t, netlink.RuleDel(&r))
+		}
+	}
+}
+
+func rootQdiscDel(name string) func(t *testing.T) {
+	return func(t *testing.T) {
+		link, err := netlink.LinkByName(name)
+		require.NoError(t, err)
+		qds, err := netlink.QdiscList(link)
+		require.NoError(t, err)
+		for _, q := range qds {
+			if q.Attrs().Parent == netlink.HANDLE_ROOT {
+				require.NoError(t, netlink.QdiscDel(q))
+			}
+		}
+	}
+}
+
+func sysctlSet(path, value string) func(t *testing.T) {
+	return func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte(value), 0644))
+	}
+}
+
+func TestCheckPolicyRoute(t *testing.T) {
+	hostNS, containerNS := newCheckTestNS(t)
+
+	err := netlink.LinkAdd(&netlink.Dummy{
+		LinkAttrs: netlink.LinkAttrs{Name: "eni"},
+	})
+	require.NoError(t, err)
+	eni, err := netlink.LinkByName("eni")
+	require.NoError(t, err)
+
+	setupCfg := &types.SetupConfig{
+		DP:              types.PolicyRoute,
+		HostVETHName:    "hostveth",
+		ContainerIfName: "eth0",
+		ContainerIPNet: &terwayTypes.IPNetSet{
+			IPv4: containerIPNet,
+			IPv6: containerIPNetIPv6,
+		},
+		GatewayIP: &terwayTypes.IPSet{
+			IPv4: ipv4GW,
+			IPv6: ipv6GW,
+		},
+		MTU:      1499,
+		ENIIndex: eni.Attrs().Index,
+		Ingress:  1000000,
+		Egress:   1000000,
+		HostIPSet: &terwayTypes.IPNetSet{
+			IPv4: eth0IPNet,
+			IPv6: eth0IPNetIPv6,
+		},
+		DefaultRoute: true,
+	}
+
+	d := NewPolicyRoute()
+	require.NoError(t, d.Setup(setupCfg, containerNS))
+
+	var events []string
+	cfg := newCheckConfig(setupCfg, containerNS, &events)
+
+	table := utils.GetRouteTableID(eni.Attrs().Index)
+	v4 := utils.NewIPNetWithMaxMask(containerIPNet)
+
+	toContainerRule := netlink.NewRule()
+	toContainerRule.Dst = v4
+	toContainerRule.Table = unix.RT_TABLE_MAIN
+	toContainerRule.Priority = toContainerPriority
+
+	fromContainerRule := netlink.NewRule()
+	fromContainerRule.Src = v4
+	fromContainerRule.Table = table
+	fromContainerRule.Priority = fromContainerPriority
+
+	runCorruptions(t, hostNS, containerNS, d.Check, cfg, &events, []corruption{
+		{name: "container link down", inContainer: true, corrupt: linkDown("eth0")},
+		{name: "container link mtu", inContainer: true, corrupt: linkMTU("eth0", 1400)},
+		{name: "container addr", inContainer: true, corrupt: addrDel("eth0", v4)},
+		{name: "container default route", inContainer: true, corrupt: routeDel("eth0", defaultRoute, 0)},
+		{name: "container neigh", inContainer: true, corrupt: neighDel("eth0", LinkIP)},
+		{name: "container egress qdisc", inContainer: true, corrupt: rootQdiscDel("eth0")},
+		{name: "container sysctl", inContainer: true, corrupt: sysctlSet("/proc/sys/net/ipv6/conf/eth0/accept_ra", "1")},
+		{name: "host veth down", corrupt: linkDown("hostveth")},
+		{name: "host veth route", corrupt: routeDel("hostveth", v4, 0)},
+		{name: "host to container rule", corrupt: ruleDel(toContainerRule)},
+		{name: "host from container rule", corrupt: ruleDel(fromContainerRule)},
+		{name: "host ingress qdisc", corrupt: rootQdiscDel("hostveth")},
+		{name: "host veth sysctl", corrupt: sysctlSet("/proc/sys/net/ipv6/conf/hostveth/forwarding", "0")},
+		{name: "eni route table", corrupt: routeDel("eni", defaultRoute, table)},
+		{name: "eni mtu", corrupt: linkMTU("eni", 1400)},
+	})
+}
+
+func TestCheckIPVlan(t *testing.T) {
+	hostNS, containerNS := newCheckTestNS(t)
+
+	err := netlink.LinkAdd(&netlink.Dummy{
+		LinkAttrs: netlink.LinkAttrs{Name: "eni"},
+	})
+	require.NoError(t, err)
+	eni, err := netlink.LinkByName("eni")
+	require.NoError(t, err)
+
+	setupCfg := &types.SetupConfig{
+		DP:              types.IPVlan,
+		HostVETHName:    "hostipvl",
+		ContainerIfName: "eth0",
+		ContainerIPNet: &terwayTypes.IPNetSet{
+			IPv4: containerIPNet,
+			IPv6: containerIPNetIPv6,
+		},
+		GatewayIP: &terwayTypes.IPSet{
+			IPv4: ipv4GW,
+			IPv6: ipv6GW,
+		},
+		MTU:      1499,
+		ENIIndex: eni.Attrs().Index,
+		Egress:   1000000,
+		ServiceCIDR: &terwayTypes.IPNetSet{
+			IPv4: serviceCIDR,
+			IPv6: serviceCIDRIPv6,
+		},
+		HostIPSet: &terwayTypes.IPNetSet{
+			IPv4: eth0IPNet,
+			IPv6: eth0IPNetIPv6,
+		},
+		DefaultRoute: true,
+	}
+
+	d := NewIPVlanDriver()
+	require.NoError(t, d.Setup(setupCfg, containerNS))
+
+	var events []string
+	cfg := newCheckConfig(setupCfg, containerNS, &events)
+
+	slaveName := d.initSlaveName(eni.Attrs().Index)
+	v4 := utils.NewIPNetWithMaxMask(containerIPNet)
+
+	runCorruptions(t, hostNS, containerNS, d.Check, cfg, &events, []corruption{
+		{name: "container link down", inContainer: true, corrupt: linkDown("eth0")},
+		{name: "container link mtu", inContainer: true, corrupt: linkMTU("eth0", 1400)},
+		{name: "container addr", inContainer: true, corrupt: addrDel("eth0", containerIPNet)},
+		{name: "container default route", inContainer: true, corrupt: routeDel("eth0", defaultRoute, 0)},
+		{name: "container route to host", inContainer: true, corrupt: routeDel("eth0", utils.NewIPNetWithMaxMask(eth0IPNet), 0)},
+		{name: "container neigh", inContainer: true, corrupt: neighDel("eth0", eth0IPNet.IP)},
+		{name: "container egress qdisc", inContainer: true, corrupt: rootQdiscDel("eth0")},
+		{name: "container sysctl", inContainer: true, corrupt: sysctlSet("/proc/sys/net/ipv6/conf/eth0/accept_ra", "1")},
+		{name: "eni down", corrupt: linkDown("eni")},
+		{name: "slave link removed", corrupt: func(t *testing.T) {
+			link, err := netlink.LinkByName(slaveName)
+			require.NoError(t, err)
+			require.NoError(t, netlink.LinkDel(link))
+		}},
+		{name: "slave addr", corrupt: addrDel(slaveName, utils.NewIPNetWithMaxMask(eth0IPNet))},
+		{name: "slave route", corrupt: routeDel(slaveName, v4, 0)},
+		{name: "redirect filter", corrupt: func(t *testing.T) {
+			parent := uint32(netlink.HANDLE_CLSACT&0xffff0000 | netlink.HANDLE_MIN_EGRESS&0x0000ffff)
+			filters, err := netlink.FilterList(eni, parent)
+			require.NoError(t, err)
+			require.NotEmpty(t, filters)
+			for _, f := range filters {
+				require.NoError(t, netlink.FilterDel(f))
+			}
+		}},
+	})
+}
+
+func TestCheckVPCRoute(t *testing.T) {
+	hostNS, containerNS := newCheckTestNS(t)
+
+	setupCfg := &types.SetupConfig{
+		DP:              types.VPCRoute,
+		HostVETHName:    "veth1",
+		ContainerIfName: "eth0",
+		ContainerIPNet: &terwayTypes.IPNetSet{
+			IPv4: containerIPNet,
+		},
+		GatewayIP: &terwayTypes.IPSet{
+			IPv4: ipv4GW,
+		},
+		MTU:     1499,
+		Ingress: 1000000,
+	}
+
+	d := NewVPCRoute()
+	require.NoError(t, d.Setup(setupCfg, containerNS))
+
+	var events []string
+	// the pod cidr is passed in check, the ip is read from the container link
+	checkSetupCfg := *setupCfg
+	checkSetupCfg.ContainerIPNet = &terwayTypes.IPNetSet{
+		IPv4: &net.IPNet{IP: containerIPNet.IP.Mask(containerIPNet.Mask), Mask: containerIPNet.Mask},
+	}
+	cfg := newCheckConfig(&checkSetupCfg, containerNS, &events)
+
+	runCorruptions(t, hostNS, containerNS, d.Check, cfg, &events, []corruption{
+		{name: "container link down", inContainer: true, corrupt: linkDown("eth0")},
+		{name: "container link mtu", inContainer: true, corrupt: linkMTU("eth0", 1400)},
+		{name: "container default route", inContainer: true, corrupt: routeDel("eth0", defaultRoute, 0)},
+		{name: "container neigh", inContainer: true, corrupt: neighDel("eth0", LinkIP)},
+		{name: "host veth route", corrupt: routeDel("veth1", utils.NewIPNetWithMaxMask(containerIPNet), 0)},
+		{name: "host veth mtu", corrupt: linkMTU("veth1", 1400)},
+		{name: "host ingress qdisc", corrupt: rootQdiscDel("veth1")},
+	})
+
+	// the address can not be repaired
+	_ = containerNS.Do(func(netNS ns.NetNS) error {
+		addrDel("eth0", utils.NewIPNetWithMaxMask(containerIPNet))(t)
+		return nil
+	})
+	assert.Error(t, d.Check(cfg))
+}
+
+func TestCheckExclusiveENI(t *testing.T) {
+	hostNS, containerNS := newCheckTestNS(t)
+
+	err := netlink.LinkAdd(&netlink.Dummy{
+		LinkAttrs: netlink.LinkAttrs{Name: "eni"},
+	})
+	require.NoError(t, err)
+	eni, err := netlink.LinkByName("eni")
+	require.NoError(t, err)
+
+	setupCfg := &types.SetupConfig{
+		DP:              types.ExclusiveENI,
+		HostVETHName:    "hostveth",
+		ContainerIfName: "eth0",
+		ContainerIPNet: &terwayTypes.IPNetSet{
+			IPv4: containerIPNet,
+			IPv6: containerIPNetIPv6,
+		},
+		GatewayIP: &terwayTypes.IPSet{
+			IPv4: ipv4GW,
+			IPv6: ipv6GW,
+		},
+		MTU:      1499,
+		ENIIndex: eni.Attrs().Index,
+		Egress:   1000000,
+		ServiceCIDR: &terwayTypes.IPNetSet{
+			IPv4: serviceCIDR,
+			IPv6: serviceCIDRIPv6,
+		},
+		HostIPSet: &terwayTypes.IPNetSet{
+			IPv4: eth0IPNet,
+			IPv6: eth0IPNetIPv6,
+		},
+		DefaultRoute: true,
+	}
+
+	d := NewExclusiveENIDriver()
+	require.NoError(t, d.Setup(setupCfg, containerNS))
+
+	var events []string
+	cfg := newCheckConfig(setupCfg, containerNS, &events)
+
+	v4 := utils.NewIPNetWithMaxMask(containerIPNet)
+
+	runCorruptions(t, hostNS, containerNS, d.Check, cfg, &events, []corruption{
+		{name: "container link down", inContainer: true, corrupt: linkDown("eth0")},
+		{name: "container link mtu", inContainer: true, corrupt: linkMTU("eth0", 1400)},
+		{name: "container addr", inContainer: true, corrupt: addrDel("eth0", v4)},
+		{name: "container default route", inContainer: true, corrupt: routeDel("eth0", defaultRoute, 0)},
+		{name: "container egress qdisc", inContainer: true, corrupt: rootQdiscDel("eth0")},
+		{name: "container sysctl", inContainer: true, corrupt: sysctlSet("/proc/sys/net/ipv6/conf/eth0/accept_ra", "1")},
+		{name: "veth1 down", inContainer: true, corrupt: linkDown(defaultVethForENI)},
+		{name: "veth1 service route", inContainer: true, corrupt: routeDel(defaultVethForENI, serviceCIDR, 0)},
+		{name: "veth1 neigh", inContainer: true, corrupt: neighDel(defaultVethForENI, LinkIP)},
+		{name: "host peer addr", corrupt: addrDel("hostveth", LinkIPNet)},
+		{name: "host peer route", corrupt: routeDel("hostveth", v4, 0)},
+	})
+}
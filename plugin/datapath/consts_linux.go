@@ -11,6 +11,12 @@ import (
 const (
 	toContainerPriority   = 512
 	fromContainerPriority = 2048
+
+	// redirectFilterPriority is the priority of the ipvlan redirect filter on the parent link
+	redirectFilterPriority = 40000
+
+	// VlanTagFilterPriority is the priority of the filter push vlan tag for trunk pods, see utils.EnsureVlanTag
+	VlanTagFilterPriority = 50001
 )
 
 // default addrs
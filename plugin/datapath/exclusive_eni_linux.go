@@ -5,7 +5,9 @@ import (
 	"net"
 
 	terwayIP "github.com/AliyunContainerService/terway/pkg/ip"
+	"github.com/AliyunContainerService/terway/pkg/tc"
 	"github.com/AliyunContainerService/terway/plugin/driver/nic"
+	"github.com/AliyunContainerService/terway/plugin/driver/portmap"
 	"github.com/AliyunContainerService/terway/plugin/driver/types"
 	"github.com/AliyunContainerService/terway/plugin/driver/utils"
 	"github.com/AliyunContainerService/terway/plugin/driver/veth"
@@ -346,11 +348,9 @@ func (r *ExclusiveENI) Setup(cfg *types.SetupConfig, netNS ns.NetNS) error {
 			return err
 		}
 
-		if cfg.Egress > 0 {
-			err = utils.SetupTC(contLink, cfg.Egress)
-			if err != nil {
-				return err
-			}
+		_, err = ensureBandwidth(contLink, edtEnabled(cfg), tc.EDTEgress, cfg.ContainerIPNet, cfg.Egress)
+		if err != nil {
+			return err
 		}
 
 		// for now we only create slave link for eth0
@@ -390,6 +390,9 @@ func (r *ExclusiveENI) Setup(cfg *types.SetupConfig, netNS ns.NetNS) error {
 	}
 
 	if cfg.DisableCreatePeer {
+		if len(cfg.RuntimeConfig.PortMaps) > 0 {
+			utils.Log.Warnf("host port is ignored, as the host peer is disabled")
+		}
 		return nil
 	}
 
@@ -403,31 +406,84 @@ func (r *ExclusiveENI) Setup(cfg *types.SetupConfig, netNS ns.NetNS) error {
 		return fmt.Errorf("error set up hostpeer, %w", err)
 	}
 
+	// host port rely on the route to the container through the host peer
+	if cfg.DefaultRoute {
+		err = portmap.Setup(cfg.ContainerIPNet, cfg.RuntimeConfig.PortMaps)
+		if err != nil {
+			return fmt.Errorf("error setup host port, %w", err)
+		}
+	}
+
 	return nil
 }
 
+func (r *ExclusiveENI) Teardown(cfg *types.TeardownCfg, netNS ns.NetNS) error {
+	if cfg.ContainerIPNet == nil {
+		return nil
+	}
+	err := portmap.Teardown(cfg.ContainerIPNet, cfg.RuntimeConfig.PortMaps)
+	if err != nil {
+		return fmt.Errorf("error teardown host port, %w", err)
+	}
+	return utils.DelEDT(cfg.ContainerIPNet)
+}
+
 func (r *ExclusiveENI) Check(cfg *types.CheckConfig) error {
+	setupCfg := cfg.SetupConfig
+	checkPeer := !setupCfg.DisableCreatePeer && cfg.ContainerIfName == "eth0"
+
+	var hostPeer netlink.Link
+	if checkPeer {
+		var err error
+		hostPeer, err = netlink.LinkByName(setupCfg.HostVETHName)
+		if err != nil {
+			return fmt.Errorf("error get host veth %s, %w", setupCfg.HostVETHName, err)
+		}
+	}
+
 	err := cfg.NetNS.Do(func(netNS ns.NetNS) error {
-		link, err := netlink.LinkByName(cfg.ContainerIfName)
+		contLink, err := netlink.LinkByName(cfg.ContainerIfName)
 		if err != nil {
 			return err
 		}
-		changed, err := utils.EnsureLinkUp(link)
+
+		contCfg := generateContCfgForExclusiveENI(setupCfg, contLink)
+		err = nic.Check(contLink, contCfg, recorder(cfg))
 		if err != nil {
 			return err
 		}
-		if changed {
-			cfg.RecordPodEvent(fmt.Sprintf("link %s set up", cfg.ContainerIfName))
-		}
-		changed, err = utils.EnsureLinkMTU(link, cfg.MTU)
+
+		err = checkBandwidth(cfg, contLink, edtEnabled(setupCfg), tc.EDTEgress, setupCfg.ContainerIPNet, setupCfg.Egress)
 		if err != nil {
 			return err
 		}
 
-		if changed {
-			cfg.RecordPodEvent(fmt.Sprintf("link %s set mtu to %v", cfg.ContainerIfName, cfg.MTU))
+		if !checkPeer {
+			return nil
 		}
-		return nil
+		veth1, err := netlink.LinkByName(defaultVethForENI)
+		if err != nil {
+			return fmt.Errorf("error get %s, %w", defaultVethForENI, err)
+		}
+		veth1Cfg := generateVeth1Cfg(setupCfg, veth1, hostPeer.Attrs().HardwareAddr)
+		return nic.Check(veth1, veth1Cfg, recorder(cfg))
 	})
-	return err
+	if err != nil {
+		return fmt.Errorf("error check container link/address/route, %w", err)
+	}
+
+	if !checkPeer {
+		return nil
+	}
+
+	hostPeerCfg := generateHostSlaveCfg(setupCfg, hostPeer)
+	err = nic.Check(hostPeer, hostPeerCfg, recorder(cfg))
+	if err != nil {
+		return fmt.Errorf("error check hostpeer, %w", err)
+	}
+
+	if setupCfg.DefaultRoute {
+		return portmap.Check(setupCfg.ContainerIPNet, setupCfg.RuntimeConfig.PortMaps)
+	}
+	return nil
 }
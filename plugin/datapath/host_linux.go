@@ -0,0 +1,143 @@
+package datapath
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/AliyunContainerService/terway/plugin/driver/utils"
+)
+
+// the entries in host netns created by the datapath, shared with the daemon datapath reconciler
+
+// HostRulesForPolicy return the ip rules in host netns for the pod ip in policy route datapath
+func HostRulesForPolicy(ip *net.IPNet, table int) []*netlink.Rule {
+	dst := utils.NewIPNetWithMaxMask(ip)
+
+	toContainerRule := netlink.NewRule()
+	toContainerRule.Dst = dst
+	toContainerRule.Table = unix.RT_TABLE_MAIN
+	toContainerRule.Priority = toContainerPriority
+
+	fromContainerRule := netlink.NewRule()
+	fromContainerRule.Src = dst
+	fromContainerRule.Table = table
+	fromContainerRule.Priority = fromContainerPriority
+
+	return []*netlink.Rule{toContainerRule, fromContainerRule}
+}
+
+// IsHostRuleForPolicy report whether the rule in host netns is created by policy route datapath,
+// return the pod ip the rule belong to
+func IsHostRuleForPolicy(rule *netlink.Rule) (net.IP, bool) {
+	if rule.IifName != "" || rule.OifName != "" || rule.Mark > 0 {
+		return nil, false
+	}
+	switch rule.Priority {
+	case toContainerPriority:
+		if rule.Dst == nil || rule.Src != nil || rule.Table != unix.RT_TABLE_MAIN {
+			return nil, false
+		}
+		if !isHostMask(rule.Dst) {
+			return nil, false
+		}
+		return rule.Dst.IP, true
+	case fromContainerPriority:
+		if rule.Src == nil || rule.Dst != nil {
+			return nil, false
+		}
+		if !isHostMask(rule.Src) {
+			return nil, false
+		}
+		return rule.Src.IP, true
+	}
+	return nil, false
+}
+
+// IPVlanSlaveName return the ipvlan slave link name in host netns for the parent link
+func IPVlanSlaveName(parentIndex int) string {
+	return fmt.Sprintf("ipvl_%d", parentIndex)
+}
+
+// IPVlanRedirectFilter return the u32 filter on the parent link egress, which redirect the traffic to ip
+// to the slave link
+func IPVlanRedirectFilter(parentIndex int, ip *net.IPNet, slaveIndex int) (*netlink.U32, error) {
+	rule, err := dstIPRule(parentIndex, ip, slaveIndex, netlink.TCA_INGRESS_REDIR)
+	if err != nil {
+		return nil, err
+	}
+	u32 := rule.toU32Filter()
+	u32.Parent = uint32(netlink.HANDLE_CLSACT&0xffff0000 | netlink.HANDLE_MIN_EGRESS&0x0000ffff)
+	return u32, nil
+}
+
+// ParseIPVlanRedirectFilter report whether the filter is created by ipvlan datapath,
+// return the dst cidr and the slave link index
+func ParseIPVlanRedirectFilter(filter netlink.Filter) (*net.IPNet, int, bool) {
+	u32, ok := filter.(*netlink.U32)
+	if !ok {
+		return nil, 0, false
+	}
+	if u32.Priority != redirectFilterPriority || u32.Protocol != unix.ETH_P_IP {
+		return nil, 0, false
+	}
+	if u32.Sel == nil || len(u32.Sel.Keys) != 1 || len(u32.Actions) != 3 {
+		return nil, 0, false
+	}
+	mirred, ok := u32.Actions[2].(*netlink.MirredAction)
+	if !ok || mirred.MirredAction != netlink.TCA_INGRESS_REDIR {
+		return nil, 0, false
+	}
+
+	key := u32.Sel.Keys[0]
+	ip := make(net.IP, 4)
+	binary.BigEndian.PutUint32(ip, key.Val)
+	mask := make(net.IPMask, 4)
+	binary.BigEndian.PutUint32(mask, key.Mask)
+
+	rule := &redirectRule{
+		index:    u32.LinkIndex,
+		proto:    unix.ETH_P_IP,
+		offset:   16,
+		value:    key.Val,
+		mask:     key.Mask,
+		redir:    netlink.TCA_INGRESS_REDIR,
+		dstIndex: mirred.Ifindex,
+	}
+	if !rule.isMatch(filter) {
+		return nil, 0, false
+	}
+	return &net.IPNet{IP: ip, Mask: mask}, mirred.Ifindex, true
+}
+
+// ParseVlanTagFilter report whether the filter push the vlan tag for trunk pods, return the src ip and the vlan id
+func ParseVlanTagFilter(filter netlink.Filter) (net.IP, uint16, bool) {
+	u32, ok := filter.(*netlink.U32)
+	if !ok {
+		return nil, 0, false
+	}
+	if u32.Priority != VlanTagFilterPriority {
+		return nil, 0, false
+	}
+	if u32.Sel == nil || len(u32.Sel.Keys) != 1 || len(u32.Actions) != 1 {
+		return nil, 0, false
+	}
+	vlan, ok := u32.Actions[0].(*netlink.VlanAction)
+	if !ok {
+		return nil, 0, false
+	}
+	if u32.Sel.Keys[0].Off != 12 {
+		return nil, 0, false
+	}
+	ip := make(net.IP, 4)
+	binary.BigEndian.PutUint32(ip, u32.Sel.Keys[0].Val)
+	return ip, vlan.Vid, true
+}
+
+func isHostMask(ipNet *net.IPNet) bool {
+	ones, bits := ipNet.Mask.Size()
+	return ones == bits && bits != 0
+}
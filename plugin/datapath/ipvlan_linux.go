@@ -8,8 +8,10 @@ import (
 	"strconv"
 	"syscall"
 
+	"github.com/AliyunContainerService/terway/pkg/tc"
 	"github.com/AliyunContainerService/terway/plugin/driver/ipvlan"
 	"github.com/AliyunContainerService/terway/plugin/driver/nic"
+	"github.com/AliyunContainerService/terway/plugin/driver/portmap"
 	"github.com/AliyunContainerService/terway/plugin/driver/types"
 	"github.com/AliyunContainerService/terway/plugin/driver/utils"
 	terwayTypes "github.com/AliyunContainerService/terway/types"
@@ -263,14 +265,22 @@ func (d *IPvlanDriver) Setup(cfg *types.SetupConfig, netNS ns.NetNS) error {
 	}
 
 	if cfg.EnableNetworkPriority {
-		err = utils.SetEgressPriority(parentLink, cfg.NetworkPriority, cfg.ContainerIPNet)
+		_, err = utils.SetEgressPriority(parentLink, cfg.NetworkPriority, cfg.ContainerIPNet)
 		if err != nil {
 			return err
 		}
 	}
 
 	if cfg.StripVlan {
-		err = utils.EnsureVlanTag(parentLink, cfg.ContainerIPNet, uint16(cfg.Vid))
+		_, err = utils.EnsureVlanTag(parentLink, cfg.ContainerIPNet, uint16(cfg.Vid))
+		if err != nil {
+			return err
+		}
+	}
+
+	// the ipvlan slave transmit by the parent, so the egress of the pod is paced by the fq on the eni
+	if edtOnENI(cfg) {
+		_, err = ensureBandwidth(parentLink, true, tc.EDTEgress, cfg.ContainerIPNet, cfg.Egress)
 		if err != nil {
 			return err
 		}
@@ -297,13 +307,11 @@ func (d *IPvlanDriver) Setup(cfg *types.SetupConfig, netNS ns.NetNS) error {
 		if err != nil {
 			return err
 		}
-		if cfg.Egress == 0 && cfg.Ingress == 0 {
+		if edtOnENI(cfg) {
 			return nil
 		}
-		if cfg.BandwidthMode == "edt" {
-			return ensureFQ(contLink)
-		}
-		return utils.SetupTC(contLink, cfg.Egress)
+		_, err = ensureBandwidth(contLink, false, tc.EDTEgress, cfg.ContainerIPNet, cfg.Egress)
+		return err
 	})
 	if err != nil {
 		return fmt.Errorf("error set container link/address/route, %w", err)
@@ -313,6 +321,14 @@ func (d *IPvlanDriver) Setup(cfg *types.SetupConfig, netNS ns.NetNS) error {
 		return fmt.Errorf("error set init namespace, %w", err)
 	}
 
+	// host port is only for the default interface
+	if cfg.DefaultRoute {
+		err = portmap.Setup(cfg.ContainerIPNet, cfg.RuntimeConfig.PortMaps)
+		if err != nil {
+			return fmt.Errorf("error setup host port, %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -322,6 +338,17 @@ func (d *IPvlanDriver) Teardown(cfg *types.TeardownCfg, netNS ns.NetNS) error {
 		return err
 	}
 
+	if cfg.ContainerIPNet != nil {
+		err = portmap.Teardown(cfg.ContainerIPNet, cfg.RuntimeConfig.PortMaps)
+		if err != nil {
+			return fmt.Errorf("error teardown host port, %w", err)
+		}
+		err = utils.DelEDT(cfg.ContainerIPNet)
+		if err != nil {
+			return err
+		}
+	}
+
 	if cfg.EnableNetworkPriority {
 		link, err := netlink.LinkByIndex(cfg.ENIIndex)
 		if err != nil {
@@ -355,30 +382,35 @@ func (d *IPvlanDriver) Teardown(cfg *types.TeardownCfg, netNS ns.NetNS) error {
 }
 
 func (d *IPvlanDriver) Check(cfg *types.CheckConfig) error {
-	parentLinkIndex := 0
-	// 1. check addr and default route
-	err := cfg.NetNS.Do(func(netNS ns.NetNS) error {
-		link, err := netlink.LinkByName(cfg.ContainerIfName)
-		if err != nil {
-			return err
-		}
-		parentLinkIndex = link.Attrs().ParentIndex
-		changed, err := utils.EnsureLinkUp(link)
+	setupCfg := cfg.SetupConfig
+
+	parentLink, err := netlink.LinkByIndex(setupCfg.ENIIndex)
+	if err != nil {
+		return fmt.Errorf("error get eni by index %d, %w", setupCfg.ENIIndex, err)
+	}
+
+	// 1. check container link, addr, route and neigh
+	err = cfg.NetNS.Do(func(netNS ns.NetNS) error {
+		contLink, err := netlink.LinkByName(cfg.ContainerIfName)
 		if err != nil {
 			return err
 		}
-
-		if changed {
-			cfg.RecordPodEvent(fmt.Sprintf("link %s set to up", cfg.ContainerIfName))
+		// the ipvlan link can not be moved to other parent, recreate is required
+		if contLink.Attrs().ParentIndex != parentLink.Attrs().Index {
+			return fmt.Errorf("link %s parent index %d, expected %d", cfg.ContainerIfName, contLink.Attrs().ParentIndex, parentLink.Attrs().Index)
 		}
 
-		changed, err = utils.EnsureLinkMTU(link, cfg.MTU)
+		contCfg := generateContCfgForIPVlan(setupCfg, contLink)
+		err = nic.Check(contLink, contCfg, recorder(cfg))
 		if err != nil {
 			return err
 		}
 
-		if changed {
-			cfg.RecordPodEvent(fmt.Sprintf("link %s set mtu to %v", cfg.ContainerIfName, cfg.MTU))
+		if !edtOnENI(setupCfg) {
+			err = checkBandwidth(cfg, contLink, false, tc.EDTEgress, setupCfg.ContainerIPNet, setupCfg.Egress)
+			if err != nil {
+				return err
+			}
 		}
 
 		return utils.EnsureNetConfSet(true, false)
@@ -389,28 +421,40 @@ func (d *IPvlanDriver) Check(cfg *types.CheckConfig) error {
 		}
 		return err
 	}
+
 	// 2. check parent link ( this is called in every setup it is safe)
-	utils.Log.Debugf("parent link is %d", parentLinkIndex)
-	parentLink, err := netlink.LinkByIndex(parentLinkIndex)
+	eniCfg := generateENICfgForIPVlan(setupCfg, parentLink)
+	err = nic.Check(parentLink, eniCfg, recorder(cfg))
 	if err != nil {
-		return fmt.Errorf("error get parent link, %w", err)
+		return err
 	}
-	changed, err := utils.EnsureLinkUp(parentLink)
+
+	err = checkEgressPriority(cfg, parentLink)
 	if err != nil {
 		return err
 	}
-	if changed {
-		cfg.RecordPodEvent(fmt.Sprintf("parent link id %d set to up", int(cfg.ENIIndex)))
-	}
-	changed, err = utils.EnsureLinkMTU(parentLink, cfg.MTU)
+
+	err = checkVlanTag(cfg, parentLink)
 	if err != nil {
 		return err
 	}
 
-	if changed {
-		cfg.RecordPodEvent(fmt.Sprintf("link %s set mtu to %v", parentLink.Attrs().Name, cfg.MTU))
+	if edtOnENI(setupCfg) {
+		err = checkBandwidth(cfg, parentLink, true, tc.EDTEgress, setupCfg.ContainerIPNet, setupCfg.Egress)
+		if err != nil {
+			return err
+		}
+	}
+
+	// 3. check slave link and redirect filters in init ns
+	err = d.checkInitNamespace(parentLink, cfg)
+	if err != nil {
+		return fmt.Errorf("error check init namespace, %w", err)
 	}
 
+	if setupCfg.DefaultRoute {
+		return portmap.Check(setupCfg.ContainerIPNet, setupCfg.RuntimeConfig.PortMaps)
+	}
 	return nil
 }
 
@@ -447,22 +491,24 @@ func (d *IPvlanDriver) createSlaveIfNotExist(parentLink netlink.Link, slaveName
 	return link, nil
 }
 
-func (d *IPvlanDriver) setupFilters(link netlink.Link, cidrs []*net.IPNet, dstIndex int) error {
+// setupFilters redirect the traffic to cidrs to the slave link,return changed and err
+func (d *IPvlanDriver) setupFilters(link netlink.Link, cidrs []*net.IPNet, dstIndex int) (bool, error) {
 	parent := uint32(netlink.HANDLE_CLSACT&0xffff0000 | netlink.HANDLE_MIN_EGRESS&0x0000ffff)
 	filters, err := netlink.FilterList(link, parent)
 	if err != nil {
-		return fmt.Errorf("list egress filter for %s error, %w", link.Attrs().Name, err)
+		return false, fmt.Errorf("list egress filter for %s error, %w", link.Attrs().Name, err)
 	}
 
 	ruleInFilter := make(map[*redirectRule]bool)
 	for _, v := range cidrs {
 		rule, err := dstIPRule(link.Attrs().Index, v, dstIndex, netlink.TCA_INGRESS_REDIR)
 		if err != nil {
-			return fmt.Errorf("create redirect rule error, %w", err)
+			return false, fmt.Errorf("create redirect rule error, %w", err)
 		}
 		ruleInFilter[rule] = false
 	}
 
+	changed := false
 	for _, filter := range filters {
 		matchAny := false
 		for rule := range ruleInFilter {
@@ -478,21 +524,23 @@ func (d *IPvlanDriver) setupFilters(link netlink.Link, cidrs []*net.IPNet, dstIn
 		if filter.Attrs() != nil && filter.Attrs().Priority != 4000 {
 			continue
 		}
+		changed = true
 		if err := utils.FilterDel(filter); err != nil {
-			return fmt.Errorf("delete filter of %s error, %w", link.Attrs().Name, err)
+			return changed, fmt.Errorf("delete filter of %s error, %w", link.Attrs().Name, err)
 		}
 	}
 
 	for rule, in := range ruleInFilter {
 		if !in {
+			changed = true
 			u32 := rule.toU32Filter()
 			u32.Parent = parent
 			if err := utils.FilterAdd(u32); err != nil {
-				return fmt.Errorf("add filter for %s error, %w", link.Attrs().Name, err)
+				return changed, fmt.Errorf("add filter for %s error, %w", link.Attrs().Name, err)
 			}
 		}
 	}
-	return nil
+	return changed, nil
 }
 
 func (d *IPvlanDriver) setupInitNamespace(parentLink netlink.Link, cfg *types.SetupConfig) error {
@@ -521,11 +569,56 @@ func (d *IPvlanDriver) setupInitNamespace(parentLink netlink.Link, cfg *types.Se
 	}
 
 	redirectCIDRs := append(cfg.HostStackCIDRs, cfg.ServiceCIDR.IPv4)
-	err = d.setupFilters(parentLink, redirectCIDRs, slaveLink.Attrs().Index)
+	_, err = d.setupFilters(parentLink, redirectCIDRs, slaveLink.Attrs().Index)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d *IPvlanDriver) checkInitNamespace(parentLink netlink.Link, cfg *types.CheckConfig) error {
+	setupCfg := cfg.SetupConfig
+	record := recorder(cfg)
+
+	slaveName := d.initSlaveName(parentLink.Attrs().Index)
+	_, err := netlink.LinkByName(slaveName)
+	if err != nil {
+		if _, ok := err.(netlink.LinkNotFoundError); !ok {
+			return fmt.Errorf("get device %s error, %w", slaveName, err)
+		}
+		record(fmt.Sprintf("link %s is recreated", slaveName))
+	}
+	slaveLink, err := d.createSlaveIfNotExist(parentLink, slaveName, setupCfg.MTU)
+	if err != nil {
+		return err
+	}
+
+	if slaveLink.Attrs().Flags&unix.IFF_NOARP == 0 {
+		if err := netlink.LinkSetARPOff(slaveLink); err != nil {
+			return fmt.Errorf("set device %s noarp error, %w", slaveLink.Attrs().Name, err)
+		}
+		record(fmt.Sprintf("link %s set arp off", slaveName))
+	}
+	slaveCfg := generateSlaveLinkCfgForIPVlan(setupCfg, slaveLink)
+	err = nic.Check(slaveLink, slaveCfg, record)
+	if err != nil {
+		return err
+	}
+
+	err = utils.EnsureClsActQdsic(parentLink)
 	if err != nil {
 		return err
 	}
 
+	redirectCIDRs := append(setupCfg.HostStackCIDRs, setupCfg.ServiceCIDR.IPv4)
+	changed, err := d.setupFilters(parentLink, redirectCIDRs, slaveLink.Attrs().Index)
+	if err != nil {
+		return err
+	}
+	if changed {
+		record(fmt.Sprintf("link %s set redirect filters", parentLink.Attrs().Name))
+	}
 	return nil
 }
 
@@ -567,7 +660,7 @@ func (d *IPvlanDriver) teardownInitNamespace(containerIP *terwayTypes.IPNetSet)
 }
 
 func (d *IPvlanDriver) initSlaveName(parentIndex int) string {
-	return fmt.Sprintf("ipvl_%d", parentIndex)
+	return IPVlanSlaveName(parentIndex)
 }
 
 type redirectRule struct {
@@ -685,7 +778,7 @@ func (rule *redirectRule) toU32Filter() *netlink.U32 {
 	return &netlink.U32{
 		FilterAttrs: netlink.FilterAttrs{
 			LinkIndex: rule.index,
-			Priority:  40000,
+			Priority:  redirectFilterPriority,
 			Protocol:  rule.proto,
 		},
 		Sel: &netlink.TcU32Sel{
@@ -742,38 +835,3 @@ func CheckIPVLanAvailable() (bool, error) {
 	return (major == ipVlanRequirementMajor && minor >= ipVlanRequirementMinor) ||
 		major > ipVlanRequirementMajor, nil
 }
-
-func ensureFQ(link netlink.Link) error {
-	fq := &netlink.GenericQdisc{
-		QdiscAttrs: netlink.QdiscAttrs{
-			LinkIndex: link.Attrs().Index,
-			Parent:    netlink.HANDLE_ROOT,
-			Handle:    netlink.MakeHandle(1, 0),
-		},
-		QdiscType: "fq",
-	}
-	qds, err := netlink.QdiscList(link)
-	if err != nil {
-		return err
-	}
-	found := false
-	for _, qd := range qds {
-		if qd.Attrs().LinkIndex != link.Attrs().Index {
-			continue
-		}
-		if qd.Type() != fq.Type() {
-			continue
-		}
-		if qd.Attrs().Parent != fq.Parent {
-			continue
-		}
-		if qd.Attrs().Handle != fq.Handle {
-			continue
-		}
-		found = true
-	}
-	if found {
-		return nil
-	}
-	return utils.QdiscReplace(fq)
-}
@@ -4,14 +4,15 @@ import (
 	"fmt"
 	"net"
 
+	"github.com/AliyunContainerService/terway/pkg/tc"
 	"github.com/AliyunContainerService/terway/plugin/driver/nic"
+	"github.com/AliyunContainerService/terway/plugin/driver/portmap"
 	"github.com/AliyunContainerService/terway/plugin/driver/types"
 	"github.com/AliyunContainerService/terway/plugin/driver/utils"
 	"github.com/AliyunContainerService/terway/plugin/driver/veth"
 
 	"github.com/containernetworking/plugins/pkg/ns"
 	"github.com/vishvananda/netlink"
-	"golang.org/x/sys/unix"
 )
 
 type PolicyRoute struct{}
@@ -189,19 +190,8 @@ func generateHostPeerCfgForPolicy(cfg *types.SetupConfig, link netlink.Link, tab
 			Dst:       utils.NewIPNetWithMaxMask(cfg.ContainerIPNet.IPv4),
 		})
 
-		v4 := utils.NewIPNetWithMaxMask(cfg.ContainerIPNet.IPv4)
 		// 2. add host to container rule
-		toContainerRule := netlink.NewRule()
-		toContainerRule.Dst = v4
-		toContainerRule.Table = unix.RT_TABLE_MAIN
-		toContainerRule.Priority = toContainerPriority
-
-		fromContainerRule := netlink.NewRule()
-		fromContainerRule.Src = v4
-		fromContainerRule.Table = table
-		fromContainerRule.Priority = fromContainerPriority
-
-		rules = append(rules, toContainerRule, fromContainerRule)
+		rules = append(rules, HostRulesForPolicy(cfg.ContainerIPNet.IPv4, table)...)
 	}
 
 	if cfg.ContainerIPNet.IPv6 != nil {
@@ -217,19 +207,8 @@ func generateHostPeerCfgForPolicy(cfg *types.SetupConfig, link netlink.Link, tab
 			Dst:       utils.NewIPNetWithMaxMask(cfg.ContainerIPNet.IPv6),
 		})
 
-		v6 := utils.NewIPNetWithMaxMask(cfg.ContainerIPNet.IPv6)
 		// 2. add host to container rule
-		toContainerRule := netlink.NewRule()
-		toContainerRule.Dst = v6
-		toContainerRule.Table = unix.RT_TABLE_MAIN
-		toContainerRule.Priority = toContainerPriority
-
-		fromContainerRule := netlink.NewRule()
-		fromContainerRule.Src = v6
-		fromContainerRule.Table = table
-		fromContainerRule.Priority = fromContainerPriority
-
-		rules = append(rules, toContainerRule, fromContainerRule)
+		rules = append(rules, HostRulesForPolicy(cfg.ContainerIPNet.IPv6, table)...)
 
 		sysctl = utils.GenerateIPv6Sysctl(link.Attrs().Name, true, true)
 	}
@@ -330,10 +309,11 @@ func (d *PolicyRoute) Setup(cfg *types.SetupConfig, netNS ns.NetNS) error {
 		if err != nil {
 			return err
 		}
-		if cfg.Egress > 0 {
-			return utils.SetupTC(contLink, cfg.Egress)
+		if edtOnENI(cfg) {
+			return nil
 		}
-		return nil
+		_, err = ensureBandwidth(contLink, false, tc.EDTEgress, cfg.ContainerIPNet, cfg.Egress)
+		return err
 	})
 	if err != nil {
 		return fmt.Errorf("setup container, %w", err)
@@ -345,7 +325,7 @@ func (d *PolicyRoute) Setup(cfg *types.SetupConfig, netNS ns.NetNS) error {
 	}
 
 	if cfg.EnableNetworkPriority {
-		err = utils.SetEgressPriority(eni, cfg.NetworkPriority, cfg.ContainerIPNet)
+		_, err = utils.SetEgressPriority(eni, cfg.NetworkPriority, cfg.ContainerIPNet)
 		if err != nil {
 			return err
 		}
@@ -360,7 +340,14 @@ func (d *PolicyRoute) Setup(cfg *types.SetupConfig, netNS ns.NetNS) error {
 	}
 
 	if cfg.StripVlan {
-		err = utils.EnsureVlanTag(eni, cfg.ContainerIPNet, uint16(cfg.Vid))
+		_, err = utils.EnsureVlanTag(eni, cfg.ContainerIPNet, uint16(cfg.Vid))
+		if err != nil {
+			return err
+		}
+	}
+
+	if edtOnENI(cfg) {
+		_, err = ensureBandwidth(eni, true, tc.EDTEgress, cfg.ContainerIPNet, cfg.Egress)
 		if err != nil {
 			return err
 		}
@@ -372,36 +359,94 @@ func (d *PolicyRoute) Setup(cfg *types.SetupConfig, netNS ns.NetNS) error {
 		return fmt.Errorf("setup host veth config, %w", err)
 	}
 
-	if cfg.Ingress > 0 {
-		return utils.SetupTC(hostVETH, cfg.Ingress)
+	_, err = ensureBandwidth(hostVETH, edtEnabled(cfg), tc.EDTIngress, cfg.ContainerIPNet, cfg.Ingress)
+	if err != nil {
+		return err
+	}
+
+	// host port is only for the default interface
+	if cfg.DefaultRoute {
+		err = portmap.Setup(cfg.ContainerIPNet, cfg.RuntimeConfig.PortMaps)
+		if err != nil {
+			return fmt.Errorf("error setup host port, %w", err)
+		}
 	}
 	return nil
 }
 
 func (d *PolicyRoute) Check(cfg *types.CheckConfig) error {
-	err := cfg.NetNS.Do(func(netNS ns.NetNS) error {
-		link, err := netlink.LinkByName(cfg.ContainerIfName)
+	setupCfg := cfg.SetupConfig
+
+	hostVETH, err := netlink.LinkByName(setupCfg.HostVETHName)
+	if err != nil {
+		return fmt.Errorf("error get host veth %s, %w", setupCfg.HostVETHName, err)
+	}
+
+	err = cfg.NetNS.Do(func(netNS ns.NetNS) error {
+		contLink, err := netlink.LinkByName(cfg.ContainerIfName)
 		if err != nil {
 			return err
 		}
-		changed, err := utils.EnsureLinkUp(link)
+
+		contCfg := generateContCfgForPolicy(setupCfg, contLink, hostVETH.Attrs().HardwareAddr)
+		err = nic.Check(contLink, contCfg, recorder(cfg))
 		if err != nil {
 			return err
 		}
-		if changed {
-			cfg.RecordPodEvent(fmt.Sprintf("link %s set up", cfg.ContainerIfName))
+		if edtOnENI(setupCfg) {
+			return nil
 		}
-		changed, err = utils.EnsureLinkMTU(link, cfg.MTU)
+		return checkBandwidth(cfg, contLink, false, tc.EDTEgress, setupCfg.ContainerIPNet, setupCfg.Egress)
+	})
+	if err != nil {
+		return fmt.Errorf("check container, %w", err)
+	}
+
+	eni, err := netlink.LinkByIndex(setupCfg.ENIIndex)
+	if err != nil {
+		return err
+	}
+
+	err = checkEgressPriority(cfg, eni)
+	if err != nil {
+		return err
+	}
+
+	table := utils.GetRouteTableID(eni.Attrs().Index)
+
+	eniCfg := generateENICfgForPolicy(setupCfg, eni, table)
+	err = nic.Check(eni, eniCfg, recorder(cfg))
+	if err != nil {
+		return fmt.Errorf("check eni config, %w", err)
+	}
+
+	err = checkVlanTag(cfg, eni)
+	if err != nil {
+		return err
+	}
+
+	if edtOnENI(setupCfg) {
+		err = checkBandwidth(cfg, eni, true, tc.EDTEgress, setupCfg.ContainerIPNet, setupCfg.Egress)
 		if err != nil {
 			return err
 		}
+	}
 
-		if changed {
-			cfg.RecordPodEvent(fmt.Sprintf("link %s set mtu to %v", cfg.ContainerIfName, cfg.MTU))
-		}
-		return nil
-	})
-	return err
+	hostVETHCfg := generateHostPeerCfgForPolicy(setupCfg, hostVETH, table)
+	err = nic.Check(hostVETH, hostVETHCfg, recorder(cfg))
+	if err != nil {
+		return fmt.Errorf("check host veth config, %w", err)
+	}
+
+	err = checkBandwidth(cfg, hostVETH, edtEnabled(setupCfg), tc.EDTIngress, setupCfg.ContainerIPNet, setupCfg.Ingress)
+	if err != nil {
+		return err
+	}
+
+	if setupCfg.DefaultRoute {
+		return portmap.Check(setupCfg.ContainerIPNet, setupCfg.RuntimeConfig.PortMaps)
+	}
+	return nil
 }
 
 func (d *PolicyRoute) Teardown(cfg *types.TeardownCfg, netNS ns.NetNS) error {
@@ -409,6 +454,16 @@ func (d *PolicyRoute) Teardown(cfg *types.TeardownCfg, netNS ns.NetNS) error {
 		return nil
 	}
 
+	err := portmap.Teardown(cfg.ContainerIPNet, cfg.RuntimeConfig.PortMaps)
+	if err != nil {
+		return fmt.Errorf("error teardown host port, %w", err)
+	}
+
+	err = utils.DelEDT(cfg.ContainerIPNet)
+	if err != nil {
+		return err
+	}
+
 	extender := utils.NewIPNet(cfg.ContainerIPNet)
 	// delete ip rule by ip
 	exec := func(rule *netlink.Rule) error {
@@ -0,0 +1,79 @@
+package datapath
+
+import (
+	"fmt"
+
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/vishvananda/netlink"
+
+	"github.com/AliyunContainerService/terway/pkg/tc"
+	"github.com/AliyunContainerService/terway/plugin/driver/types"
+	"github.com/AliyunContainerService/terway/plugin/driver/utils"
+)
+
+// UpdateQoS apply the bandwidth limit and the egress priority of a running pod in place,
+// only the qos related config in cfg is used, the links of the pod is not recreated
+func UpdateQoS(cfg *types.SetupConfig, netNS ns.NetNS) error {
+	// the egress is limited on the eni when edt is used, the pod link use tbf otherwise
+	edtENI := false
+	contEDT := edtEnabled(cfg)
+	ingressOnHost := false
+	priority := false
+	switch cfg.DP {
+	case types.PolicyRoute:
+		edtENI, contEDT, ingressOnHost, priority = edtOnENI(cfg), false, true, true
+	case types.IPVlan:
+		edtENI, contEDT, priority = edtOnENI(cfg), false, true
+	case types.VPCRoute:
+		ingressOnHost = true
+	case types.Vlan:
+		priority = true
+	}
+
+	err := netNS.Do(func(_ ns.NetNS) error {
+		contLink, err := netlink.LinkByName(cfg.ContainerIfName)
+		if err != nil {
+			return fmt.Errorf("error find link %s in container, %w", cfg.ContainerIfName, err)
+		}
+		if edtENI {
+			_, err = utils.DelTC(contLink)
+			return err
+		}
+		_, err = ensureBandwidth(contLink, contEDT, tc.EDTEgress, cfg.ContainerIPNet, cfg.Egress)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("update egress bandwidth in container, %w", err)
+	}
+
+	if edtENI || (priority && cfg.EnableNetworkPriority) {
+		eni, err := netlink.LinkByIndex(cfg.ENIIndex)
+		if err != nil {
+			return fmt.Errorf("error get eni by index %d, %w", cfg.ENIIndex, err)
+		}
+		if edtENI {
+			_, err = ensureBandwidth(eni, true, tc.EDTEgress, cfg.ContainerIPNet, cfg.Egress)
+			if err != nil {
+				return err
+			}
+		}
+		if priority && cfg.EnableNetworkPriority {
+			_, err = utils.SetEgressPriority(eni, cfg.NetworkPriority, cfg.ContainerIPNet)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if ingressOnHost {
+		hostVETH, err := netlink.LinkByName(cfg.HostVETHName)
+		if err != nil {
+			return fmt.Errorf("error get host veth %s, %w", cfg.HostVETHName, err)
+		}
+		_, err = ensureBandwidth(hostVETH, edtEnabled(cfg), tc.EDTIngress, cfg.ContainerIPNet, cfg.Ingress)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package datapath
 import (
 	"fmt"
 
+	"github.com/AliyunContainerService/terway/pkg/tc"
 	"github.com/AliyunContainerService/terway/plugin/driver/nic"
 	"github.com/AliyunContainerService/terway/plugin/driver/types"
 	"github.com/AliyunContainerService/terway/plugin/driver/utils"
@@ -154,7 +155,7 @@ func (d *Vlan) Setup(cfg *types.SetupConfig, netNS ns.NetNS) error {
 	}
 
 	if cfg.EnableNetworkPriority {
-		err = utils.SetEgressPriority(master, cfg.NetworkPriority, cfg.ContainerIPNet)
+		_, err = utils.SetEgressPriority(master, cfg.NetworkPriority, cfg.ContainerIPNet)
 		if err != nil {
 			return err
 		}
@@ -189,10 +190,8 @@ func (d *Vlan) Setup(cfg *types.SetupConfig, netNS ns.NetNS) error {
 		if err != nil {
 			return err
 		}
-		if cfg.Egress > 0 {
-			return utils.SetupTC(contLink, cfg.Egress)
-		}
-		return nil
+		_, err = ensureBandwidth(contLink, edtEnabled(cfg), tc.EDTEgress, cfg.ContainerIPNet, cfg.Egress)
+		return err
 	})
 	if err != nil {
 		return fmt.Errorf("setup container, %w", err)
@@ -201,27 +200,43 @@ func (d *Vlan) Setup(cfg *types.SetupConfig, netNS ns.NetNS) error {
 }
 
 func (d *Vlan) Check(cfg *types.CheckConfig) error {
-	err := cfg.NetNS.Do(func(netNS ns.NetNS) error {
-		link, err := netlink.LinkByName(cfg.ContainerIfName)
-		if err != nil {
-			return err
-		}
-		changed, err := utils.EnsureLinkUp(link)
+	setupCfg := cfg.SetupConfig
+
+	master, err := netlink.LinkByIndex(setupCfg.ENIIndex)
+	if err != nil {
+		return fmt.Errorf("error get link by index %d, %w", setupCfg.ENIIndex, err)
+	}
+
+	err = checkEgressPriority(cfg, master)
+	if err != nil {
+		return err
+	}
+
+	eniCfg := generateENICfgForVlan(setupCfg)
+	err = nic.Check(master, eniCfg, recorder(cfg))
+	if err != nil {
+		return fmt.Errorf("check eni config, %w", err)
+	}
+
+	err = cfg.NetNS.Do(func(netNS ns.NetNS) error {
+		contLink, err := netlink.LinkByName(cfg.ContainerIfName)
 		if err != nil {
 			return err
 		}
-		if changed {
-			cfg.RecordPodEvent(fmt.Sprintf("link %s set up", cfg.ContainerIfName))
+		vlanLink, ok := contLink.(*netlink.Vlan)
+		if !ok || vlanLink.VlanId != setupCfg.Vid {
+			return fmt.Errorf("link %s is not vlan %d", cfg.ContainerIfName, setupCfg.Vid)
 		}
-		changed, err = utils.EnsureLinkMTU(link, cfg.MTU)
+
+		contCfg := generateContCfgForVlan(setupCfg, contLink)
+		err = nic.Check(contLink, contCfg, recorder(cfg))
 		if err != nil {
 			return err
 		}
-
-		if changed {
-			cfg.RecordPodEvent(fmt.Sprintf("link %s set mtu to %v", cfg.ContainerIfName, cfg.MTU))
-		}
-		return nil
+		return checkBandwidth(cfg, contLink, edtEnabled(setupCfg), tc.EDTEgress, setupCfg.ContainerIPNet, setupCfg.Egress)
 	})
-	return err
+	if err != nil {
+		return fmt.Errorf("check container, %w", err)
+	}
+	return nil
 }
@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"net"
 
+	"github.com/AliyunContainerService/terway/pkg/tc"
 	"github.com/AliyunContainerService/terway/plugin/driver/nic"
 	"github.com/AliyunContainerService/terway/plugin/driver/types"
 	"github.com/AliyunContainerService/terway/plugin/driver/utils"
 	"github.com/AliyunContainerService/terway/plugin/driver/veth"
+	terwayTypes "github.com/AliyunContainerService/terway/types"
 
 	"github.com/containernetworking/plugins/pkg/ns"
 	"github.com/vishvananda/netlink"
@@ -106,10 +108,8 @@ func (d *VPCRoute) Setup(cfg *types.SetupConfig, netNS ns.NetNS) error {
 		if err != nil {
 			return err
 		}
-		if cfg.Egress > 0 {
-			return utils.SetupTC(contLink, cfg.Egress)
-		}
-		return nil
+		_, err = ensureBandwidth(contLink, edtEnabled(cfg), tc.EDTEgress, cfg.ContainerIPNet, cfg.Egress)
+		return err
 	})
 	if err != nil {
 		return err
@@ -121,10 +121,58 @@ func (d *VPCRoute) Setup(cfg *types.SetupConfig, netNS ns.NetNS) error {
 		return fmt.Errorf("setup host veth config, %w", err)
 	}
 
-	if cfg.Ingress > 0 {
-		return utils.SetupTC(hostVETH, cfg.Ingress)
-	}
-	return nil
+	_, err = ensureBandwidth(hostVETH, edtEnabled(cfg), tc.EDTIngress, cfg.ContainerIPNet, cfg.Ingress)
+	return err
 }
 
-func (d *VPCRoute) Check(cfg *types.CheckConfig) error { return nil }
+// Check verify the datapath, the container ip is allocated by the delegate ipam, so it is read from the container link
+func (d *VPCRoute) Check(cfg *types.CheckConfig) error {
+	setupCfg := *cfg.SetupConfig
+
+	hostVETH, err := netlink.LinkByName(setupCfg.HostVETHName)
+	if err != nil {
+		return fmt.Errorf("error get host veth %s, %w", setupCfg.HostVETHName, err)
+	}
+
+	err = cfg.NetNS.Do(func(_ ns.NetNS) error {
+		contLink, err := netlink.LinkByName(cfg.ContainerIfName)
+		if err != nil {
+			return err
+		}
+
+		addrs, err := netlink.AddrList(contLink, netlink.FAMILY_V4)
+		if err != nil {
+			return fmt.Errorf("error list address from if %s, %w", cfg.ContainerIfName, err)
+		}
+		var containerIPNet *net.IPNet
+		for _, addr := range addrs {
+			if addr.IP.IsGlobalUnicast() {
+				containerIPNet = addr.IPNet
+				break
+			}
+		}
+		// the address can not be repaired, as the delegate ipam is not called in check
+		if containerIPNet == nil {
+			return fmt.Errorf("no ipv4 address found on %s", cfg.ContainerIfName)
+		}
+		setupCfg.ContainerIPNet = &terwayTypes.IPNetSet{IPv4: containerIPNet}
+
+		contCfg := generateContCfgForVPCRoute(&setupCfg, contLink, hostVETH.Attrs().HardwareAddr)
+		err = nic.Check(contLink, contCfg, recorder(cfg))
+		if err != nil {
+			return err
+		}
+		return checkBandwidth(cfg, contLink, edtEnabled(&setupCfg), tc.EDTEgress, setupCfg.ContainerIPNet, setupCfg.Egress)
+	})
+	if err != nil {
+		return fmt.Errorf("check container, %w", err)
+	}
+
+	hostVETHCfg := generateHostPeerCfgForVPCRoute(&setupCfg, hostVETH)
+	err = nic.Check(hostVETH, hostVETHCfg, recorder(cfg))
+	if err != nil {
+		return fmt.Errorf("check host veth config, %w", err)
+	}
+
+	return checkBandwidth(cfg, hostVETH, edtEnabled(&setupCfg), tc.EDTIngress, setupCfg.ContainerIPNet, setupCfg.Ingress)
+}
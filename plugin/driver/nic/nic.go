@@ -19,7 +19,6 @@ package nic
 import (
 	"fmt"
 
-	terwaySysctl "github.com/AliyunContainerService/terway/pkg/sysctl"
 	"github.com/AliyunContainerService/terway/plugin/driver/utils"
 
 	"github.com/vishvananda/netlink"
@@ -41,72 +40,112 @@ type Conf struct {
 }
 
 func Setup(link netlink.Link, conf *Conf) error {
-	var err error
+	return ensure(link, conf, func(msg string) {})
+}
+
+// Check verify the link is configured as the conf, the drift is repaired and reported by record
+func Check(link netlink.Link, conf *Conf, record func(msg string)) error {
+	if record == nil {
+		record = func(msg string) {}
+	}
+	return ensure(link, conf, record)
+}
+
+func ensure(link netlink.Link, conf *Conf, record func(msg string)) error {
 	if conf.IfName != "" {
 		changed, err := utils.EnsureLinkName(link, conf.IfName)
 		if err != nil {
 			return err
 		}
 		if changed {
+			record(fmt.Sprintf("link %s set name to %s", link.Attrs().Name, conf.IfName))
 			link, err = netlink.LinkByIndex(link.Attrs().Index)
 			if err != nil {
 				return err
 			}
 		}
 	}
+	name := link.Attrs().Name
 
 	if conf.MTU > 0 {
-		_, err = utils.EnsureLinkMTU(link, conf.MTU)
+		changed, err := utils.EnsureLinkMTU(link, conf.MTU)
 		if err != nil {
 			return err
 		}
+		if changed {
+			record(fmt.Sprintf("link %s set mtu to %d", name, conf.MTU))
+		}
 	}
 
 	for _, v := range conf.SysCtl {
 		if len(v) != 2 {
 			return fmt.Errorf("sysctl config err")
 		}
-		err = terwaySysctl.EnsureConf(v[0], v[1])
+		changed, err := utils.EnsureSysctl(v[0], v[1])
 		if err != nil {
 			return err
 		}
+		if changed {
+			record(fmt.Sprintf("sysctl %s set to %s", v[0], v[1]))
+		}
 	}
 
 	for _, addr := range conf.Addrs {
-		_, err = utils.EnsureAddr(link, addr)
+		changed, err := utils.EnsureAddr(link, addr)
 		if err != nil {
 			return err
 		}
+		if changed {
+			record(fmt.Sprintf("link %s set addr %s", name, addr.IPNet))
+		}
 	}
 
-	_, err = utils.EnsureLinkUp(link)
+	changed, err := utils.EnsureLinkUp(link)
 	if err != nil {
 		return err
 	}
+	if changed {
+		record(fmt.Sprintf("link %s set up", name))
+	}
 
 	for _, neigh := range conf.Neighs {
-		_, err = utils.EnsureNeigh(neigh)
+		changed, err = utils.EnsureNeigh(neigh)
 		if err != nil {
 			return err
 		}
+		if changed {
+			record(fmt.Sprintf("link %s set neigh %s lladdr %s", name, neigh.IP, neigh.HardwareAddr))
+		}
 	}
 
 	for _, route := range conf.Routes {
-		_, err = utils.EnsureRoute(route)
+		changed, err = utils.EnsureRoute(route)
 		if err != nil {
 			return err
 		}
+		if changed {
+			record(fmt.Sprintf("link %s set route %s", name, route))
+		}
 	}
 
 	for _, rule := range conf.Rules {
-		_, err = utils.EnsureIPRule(rule)
+		changed, err = utils.EnsureIPRule(rule)
 		if err != nil {
 			return err
 		}
+		if changed {
+			record(fmt.Sprintf("set rule %s", rule))
+		}
 	}
 
 	if conf.StripVlan {
-		return utils.EnsureVlanUntagger(link)
+		changed, err = utils.EnsureVlanUntagger(link)
+		if err != nil {
+			return err
+		}
+		if changed {
+			record(fmt.Sprintf("link %s set vlan untag filter", name))
+		}
 	}
 	return nil
 }
@@ -0,0 +1,294 @@
+package portmap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/coreos/go-iptables/iptables"
+
+	"github.com/AliyunContainerService/terway/plugin/terway/cni"
+	terwayTypes "github.com/AliyunContainerService/terway/types"
+)
+
+// the hostPort is implemented by DNAT the traffic to local address, and SNAT the hairpin traffic.
+// localhost (127.0.0.1) is not supported as host ip.
+const (
+	natTable = "nat"
+
+	dnatChain = "TERWAY-HOSTPORTS-DNAT"
+	snatChain = "TERWAY-HOSTPORTS-SNAT"
+
+	// per pod chain prefix, the chain name must be less than 29 chars
+	podDNATChainPrefix = "TERWAY-DN-"
+	podSNATChainPrefix = "TERWAY-SN-"
+)
+
+type iptablesInterface interface {
+	ChainExists(table, chain string) (bool, error)
+	ClearChain(table, chain string) error
+	DeleteChain(table, chain string) error
+	AppendUnique(table, chain string, rulespec ...string) error
+	Exists(table, chain string, rulespec ...string) (bool, error)
+	DeleteIfExists(table, chain string, rulespec ...string) error
+	List(table, chain string) ([]string, error)
+}
+
+var newIPTables = func(ipv6 bool) (iptablesInterface, error) {
+	if ipv6 {
+		return iptables.NewWithProtocol(iptables.ProtocolIPv6)
+	}
+	return iptables.NewWithProtocol(iptables.ProtocolIPv4)
+}
+
+// rule is an iptables rule in the chain
+type rule struct {
+	chain string
+	spec  []string
+}
+
+func (r rule) String() string {
+	return fmt.Sprintf("-A %s %s", r.chain, strings.Join(r.spec, " "))
+}
+
+// podChainName return the per pod chain name for the pod ip
+func podChainName(prefix string, ip net.IP) string {
+	h := sha256.Sum256([]byte(ip.String()))
+	return prefix + strings.ToUpper(hex.EncodeToString(h[:8]))
+}
+
+// topRules is the jump rules from the builtin chain
+func topRules() []rule {
+	return []rule{
+		{chain: "PREROUTING", spec: []string{"-m", "comment", "--comment", "terway hostport dnat", "-m", "addrtype", "--dst-type", "LOCAL", "-j", dnatChain}},
+		{chain: "OUTPUT", spec: []string{"-m", "comment", "--comment", "terway hostport dnat", "-m", "addrtype", "--dst-type", "LOCAL", "-j", dnatChain}},
+		{chain: "POSTROUTING", spec: []string{"-m", "comment", "--comment", "terway hostport snat", "-j", snatChain}},
+	}
+}
+
+// jumpRules is the rules jump to the per pod chains
+func jumpRules(ip net.IP) []rule {
+	comment := fmt.Sprintf("terway hostport %s", ip.String())
+	return []rule{
+		{chain: dnatChain, spec: []string{"-m", "comment", "--comment", comment, "-j", podChainName(podDNATChainPrefix, ip)}},
+		{chain: snatChain, spec: []string{"-m", "comment", "--comment", comment, "-j", podChainName(podSNATChainPrefix, ip)}},
+	}
+}
+
+// podRules is the dnat and snat rules in the per pod chains
+func podRules(ip net.IP, mappings []cni.RuntimePortMapEntry) ([]rule, error) {
+	dnat := podChainName(podDNATChainPrefix, ip)
+	snat := podChainName(podSNATChainPrefix, ip)
+	ipStr := ip.String()
+
+	var rules []rule
+	for _, m := range mappings {
+		if m.HostPort <= 0 || m.HostPort > 65535 || m.ContainerPort <= 0 || m.ContainerPort > 65535 {
+			return nil, fmt.Errorf("invalid port mapping %d:%d", m.HostPort, m.ContainerPort)
+		}
+
+		protocol := strings.ToLower(m.Protocol)
+		switch protocol {
+		case "":
+			protocol = "tcp"
+		case "tcp", "udp", "sctp":
+		default:
+			return nil, fmt.Errorf("unsupported protocol %s", m.Protocol)
+		}
+
+		dnatSpec := []string{"-p", protocol}
+		if m.HostIP != "" {
+			hostIP := net.ParseIP(m.HostIP)
+			if hostIP == nil {
+				return nil, fmt.Errorf("invalid host ip %s", m.HostIP)
+			}
+			// the host ip is for the other family
+			if (hostIP.To4() == nil) != (ip.To4() == nil) {
+				continue
+			}
+			if !hostIP.IsUnspecified() {
+				dnatSpec = append(dnatSpec, "-d", hostIP.String())
+			}
+		}
+		dnatSpec = append(dnatSpec, "--dport", strconv.Itoa(m.HostPort),
+			"-j", "DNAT", "--to-destination", net.JoinHostPort(ipStr, strconv.Itoa(m.ContainerPort)))
+
+		rules = append(rules, rule{chain: dnat, spec: dnatSpec},
+			rule{chain: snat, spec: []string{"-p", protocol, "-s", ipStr, "-d", ipStr, "--dport", strconv.Itoa(m.ContainerPort), "-j", "MASQUERADE"}})
+	}
+	return rules, nil
+}
+
+func podIPs(ipNet *terwayTypes.IPNetSet) []net.IP {
+	var ips []net.IP
+	if ipNet == nil {
+		return nil
+	}
+	if ipNet.IPv4 != nil {
+		ips = append(ips, ipNet.IPv4.IP)
+	}
+	if ipNet.IPv6 != nil {
+		ips = append(ips, ipNet.IPv6.IP)
+	}
+	return ips
+}
+
+// Setup program the dnat and snat rules for the host ports of the pod
+func Setup(ipNet *terwayTypes.IPNetSet, mappings []cni.RuntimePortMapEntry) error {
+	if len(mappings) == 0 {
+		return nil
+	}
+
+	for _, ip := range podIPs(ipNet) {
+		ipt, err := newIPTables(ip.To4() == nil)
+		if err != nil {
+			return err
+		}
+
+		rules, err := podRules(ip, mappings)
+		if err != nil {
+			return err
+		}
+
+		// 1. top level chain
+		for _, chain := range []string{dnatChain, snatChain} {
+			exist, err := ipt.ChainExists(natTable, chain)
+			if err != nil {
+				return err
+			}
+			if !exist {
+				err = ipt.ClearChain(natTable, chain)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		for _, r := range topRules() {
+			err = ipt.AppendUnique(natTable, r.chain, r.spec...)
+			if err != nil {
+				return fmt.Errorf("error add rule %s, %w", r, err)
+			}
+		}
+
+		// 2. per pod chain, always flush the chain to remove stale rules
+		for _, chain := range []string{podChainName(podDNATChainPrefix, ip), podChainName(podSNATChainPrefix, ip)} {
+			err = ipt.ClearChain(natTable, chain)
+			if err != nil {
+				return err
+			}
+		}
+		for _, r := range rules {
+			err = ipt.AppendUnique(natTable, r.chain, r.spec...)
+			if err != nil {
+				return fmt.Errorf("error add rule %s, %w", r, err)
+			}
+		}
+
+		// 3. jump to the pod chain
+		for _, r := range jumpRules(ip) {
+			err = ipt.AppendUnique(natTable, r.chain, r.spec...)
+			if err != nil {
+				return fmt.Errorf("error add rule %s, %w", r, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Teardown remove the rules for the pod, nothing is done if no host port is configured
+func Teardown(ipNet *terwayTypes.IPNetSet, mappings []cni.RuntimePortMapEntry) error {
+	if len(mappings) == 0 {
+		return nil
+	}
+
+	for _, ip := range podIPs(ipNet) {
+		ipt, err := newIPTables(ip.To4() == nil)
+		if err != nil {
+			// iptables is not available, nothing to clean
+			return nil
+		}
+
+		exist, err := ipt.ChainExists(natTable, dnatChain)
+		if err != nil {
+			return err
+		}
+		if !exist {
+			continue
+		}
+
+		for _, r := range jumpRules(ip) {
+			err = ipt.DeleteIfExists(natTable, r.chain, r.spec...)
+			if err != nil {
+				return fmt.Errorf("error delete rule %s, %w", r, err)
+			}
+		}
+
+		for _, chain := range []string{podChainName(podDNATChainPrefix, ip), podChainName(podSNATChainPrefix, ip)} {
+			exist, err = ipt.ChainExists(natTable, chain)
+			if err != nil {
+				return err
+			}
+			if !exist {
+				continue
+			}
+			err = ipt.ClearChain(natTable, chain)
+			if err != nil {
+				return err
+			}
+			err = ipt.DeleteChain(natTable, chain)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Check verify all rules for the host ports exist, and no extra rule in the per pod chain
+func Check(ipNet *terwayTypes.IPNetSet, mappings []cni.RuntimePortMapEntry) error {
+	if len(mappings) == 0 {
+		return nil
+	}
+
+	for _, ip := range podIPs(ipNet) {
+		ipt, err := newIPTables(ip.To4() == nil)
+		if err != nil {
+			return err
+		}
+
+		rules, err := podRules(ip, mappings)
+		if err != nil {
+			return err
+		}
+
+		expected := append(append(topRules(), jumpRules(ip)...), rules...)
+		for _, r := range expected {
+			exist, err := ipt.Exists(natTable, r.chain, r.spec...)
+			if err != nil {
+				return fmt.Errorf("error check rule %s, %w", r, err)
+			}
+			if !exist {
+				return fmt.Errorf("hostport rule %s not found", r)
+			}
+		}
+
+		count := make(map[string]int)
+		for _, r := range rules {
+			count[r.chain]++
+		}
+		for _, chain := range []string{podChainName(podDNATChainPrefix, ip), podChainName(podSNATChainPrefix, ip)} {
+			list, err := ipt.List(natTable, chain)
+			if err != nil {
+				return err
+			}
+			// the first line is the chain declaration
+			if len(list)-1 != count[chain] {
+				return fmt.Errorf("hostport chain %s has %d rules, expected %d", chain, len(list)-1, count[chain])
+			}
+		}
+	}
+	return nil
+}
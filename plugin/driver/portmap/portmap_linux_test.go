@@ -0,0 +1,158 @@
+package portmap
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/AliyunContainerService/terway/plugin/terway/cni"
+	terwayTypes "github.com/AliyunContainerService/terway/types"
+)
+
+// fakeIPTables keep the rules in memory, rule spec is compared as string
+type fakeIPTables struct {
+	chains map[string][]string
+}
+
+func newFakeIPTables() *fakeIPTables {
+	return &fakeIPTables{chains: map[string][]string{
+		"PREROUTING":  nil,
+		"OUTPUT":      nil,
+		"POSTROUTING": nil,
+	}}
+}
+
+func (f *fakeIPTables) ChainExists(table, chain string) (bool, error) {
+	_, ok := f.chains[chain]
+	return ok, nil
+}
+
+func (f *fakeIPTables) ClearChain(table, chain string) error {
+	f.chains[chain] = nil
+	return nil
+}
+
+func (f *fakeIPTables) DeleteChain(table, chain string) error {
+	delete(f.chains, chain)
+	return nil
+}
+
+func (f *fakeIPTables) AppendUnique(table, chain string, rulespec ...string) error {
+	exist, _ := f.Exists(table, chain, rulespec...)
+	if !exist {
+		f.chains[chain] = append(f.chains[chain], strings.Join(rulespec, " "))
+	}
+	return nil
+}
+
+func (f *fakeIPTables) Exists(table, chain string, rulespec ...string) (bool, error) {
+	for _, r := range f.chains[chain] {
+		if r == strings.Join(rulespec, " ") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeIPTables) DeleteIfExists(table, chain string, rulespec ...string) error {
+	var rules []string
+	for _, r := range f.chains[chain] {
+		if r != strings.Join(rulespec, " ") {
+			rules = append(rules, r)
+		}
+	}
+	f.chains[chain] = rules
+	return nil
+}
+
+func (f *fakeIPTables) List(table, chain string) ([]string, error) {
+	list := []string{"-N " + chain}
+	for _, r := range f.chains[chain] {
+		list = append(list, "-A "+chain+" "+r)
+	}
+	return list, nil
+}
+
+func setupFakeIPTables(t *testing.T) (*fakeIPTables, *fakeIPTables) {
+	v4, v6 := newFakeIPTables(), newFakeIPTables()
+	origin := newIPTables
+	newIPTables = func(ipv6 bool) (iptablesInterface, error) {
+		if ipv6 {
+			return v6, nil
+		}
+		return v4, nil
+	}
+	t.Cleanup(func() {
+		newIPTables = origin
+	})
+	return v4, v6
+}
+
+func TestSetupAndTeardown(t *testing.T) {
+	v4, v6 := setupFakeIPTables(t)
+
+	ipNet := &terwayTypes.IPNetSet{
+		IPv4: &net.IPNet{IP: net.ParseIP("192.168.0.10"), Mask: net.CIDRMask(32, 32)},
+		IPv6: &net.IPNet{IP: net.ParseIP("fd00::10"), Mask: net.CIDRMask(128, 128)},
+	}
+	mappings := []cni.RuntimePortMapEntry{
+		{HostPort: 8080, ContainerPort: 80, Protocol: "TCP"},
+		{HostPort: 5353, ContainerPort: 53, Protocol: "udp", HostIP: "10.0.0.1"},
+	}
+
+	err := Setup(ipNet, mappings)
+	assert.NoError(t, err)
+	assert.NoError(t, Check(ipNet, mappings))
+
+	dnat := podChainName(podDNATChainPrefix, ipNet.IPv4.IP)
+	assert.Equal(t, []string{
+		"-p tcp --dport 8080 -j DNAT --to-destination 192.168.0.10:80",
+		"-p udp -d 10.0.0.1 --dport 5353 -j DNAT --to-destination 192.168.0.10:53",
+	}, v4.chains[dnat])
+
+	// the v4 host ip is skipped for ipv6
+	dnat6 := podChainName(podDNATChainPrefix, ipNet.IPv6.IP)
+	assert.Equal(t, []string{
+		"-p tcp --dport 8080 -j DNAT --to-destination [fd00::10]:80",
+	}, v6.chains[dnat6])
+
+	// setup again is idempotent
+	assert.NoError(t, Setup(ipNet, mappings))
+	assert.Len(t, v4.chains["PREROUTING"], 1)
+	assert.Len(t, v4.chains[dnatChain], 1)
+
+	// drift is detected
+	v4.chains[dnat] = v4.chains[dnat][:1]
+	assert.Error(t, Check(ipNet, mappings))
+
+	// iptables is untouched without host port
+	assert.NoError(t, Teardown(ipNet, nil))
+	assert.Len(t, v4.chains[dnat], 1)
+
+	assert.NoError(t, Teardown(ipNet, mappings))
+	_, ok := v4.chains[dnat]
+	assert.False(t, ok)
+	assert.Len(t, v4.chains[dnatChain], 0)
+	assert.Len(t, v6.chains[snatChain], 0)
+}
+
+func TestPodRulesInvalid(t *testing.T) {
+	ip := net.ParseIP("192.168.0.10")
+
+	_, err := podRules(ip, []cni.RuntimePortMapEntry{{HostPort: 0, ContainerPort: 80}})
+	assert.Error(t, err)
+
+	_, err = podRules(ip, []cni.RuntimePortMapEntry{{HostPort: 80, ContainerPort: 80, Protocol: "icmp"}})
+	assert.Error(t, err)
+
+	_, err = podRules(ip, []cni.RuntimePortMapEntry{{HostPort: 80, ContainerPort: 80, HostIP: "foo"}})
+	assert.Error(t, err)
+}
+
+func TestPodChainName(t *testing.T) {
+	name := podChainName(podDNATChainPrefix, net.ParseIP("fd00::10"))
+	assert.LessOrEqual(t, len(name), 28)
+	assert.NotEqual(t, name, podChainName(podDNATChainPrefix, net.ParseIP("fd00::11")))
+}
@@ -1,10 +1,12 @@
 package types
 
 import (
+	"fmt"
 	"net"
 	"strings"
 
 	"github.com/AliyunContainerService/terway/plugin/terway/cni"
+	"github.com/AliyunContainerService/terway/rpc"
 	terwayTypes "github.com/AliyunContainerService/terway/types"
 
 	cniTypes "github.com/containernetworking/cni/pkg/types"
@@ -33,6 +35,7 @@ type CNIConf struct {
 	// RuntimeConfig represents the options to be passed in by the runtime.
 	RuntimeConfig cni.RuntimeConfig `json:"runtimeConfig"`
 
+	// BandwidthMode how the bandwidth is limited, tc or edt
 	BandwidthMode string `json:"bandwidth_mode"`
 
 	// EnableNetworkPriority by enable priority control, eni qdisc is replaced with tc_prio
@@ -40,12 +43,23 @@ type CNIConf struct {
 
 	// Debug
 	Debug bool `json:"debug"`
+
+	// OTLPEndpoint is the otlp grpc endpoint (host:port) the spans of cmdAdd are exported to, empty for disabled
+	OTLPEndpoint string `json:"otlp_endpoint"`
 }
 
 func (n *CNIConf) IPVlan() bool {
 	return strings.ToLower(n.ENIIPVirtualType) == "ipvlan"
 }
 
+// bandwidth mode
+const (
+	// BandwidthModeTC use tbf qdisc on the link of the pod
+	BandwidthModeTC = "tc"
+	// BandwidthModeEDT stamp the departure time by bpf and paced by fq qdisc
+	BandwidthModeEDT = "edt"
+)
+
 // VlanStripType how datapath handle vlan
 type VlanStripType string
 
@@ -66,6 +80,26 @@ const (
 	Vlan
 )
 
+// GetDataPath return the datapath for the ip type, the IPVlan falls back to PolicyRoute if ipvlan is not enabled or available
+func GetDataPath(ipType rpc.IPType, vlanStripType VlanStripType, trunk bool) DataPath {
+	switch ipType {
+	case rpc.IPType_TypeVPCIP:
+		return VPCRoute
+	case rpc.IPType_TypeVPCENI:
+		if trunk {
+			return Vlan
+		}
+		return ExclusiveENI
+	case rpc.IPType_TypeENIMultiIP:
+		if trunk && vlanStripType == VlanStripTypeVlan {
+			return Vlan
+		}
+		return IPVlan
+	default:
+		panic(fmt.Sprintf("unsupported ipType %s", ipType))
+	}
+}
+
 // K8SArgs is cni args of kubernetes
 type K8SArgs struct {
 	cniTypes.CommonArgs
@@ -134,4 +168,6 @@ type TeardownCfg struct {
 	ServiceCIDR *terwayTypes.IPNetSet
 
 	EnableNetworkPriority bool
+
+	RuntimeConfig cni.RuntimeConfig
 }
@@ -26,4 +26,7 @@ type CheckConfig struct {
 
 	DefaultRoute bool
 	MultiNetwork bool
+
+	// SetupConfig is the config used in cni add, the datapath state is verified against it
+	SetupConfig *SetupConfig
 }
@@ -18,6 +18,10 @@ import (
 
 const (
 	fileLockTimeOut = 11 * time.Second
+
+	// CNILockPath is the file lock held by the cni while changing the datapath,
+	// the daemon hold it as well before changing the datapath of the pods
+	CNILockPath = "/var/run/eni/terway_cni.lock"
 )
 
 // Log for default log
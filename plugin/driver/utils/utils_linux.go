@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"strings"
 
 	terwayIP "github.com/AliyunContainerService/terway/pkg/ip"
 	terwaySysctl "github.com/AliyunContainerService/terway/pkg/sysctl"
@@ -375,13 +376,14 @@ func EnsureNetConfSet(ipv4, ipv6 bool) error {
 	return err
 }
 
-func EnsureVlanUntagger(link netlink.Link) error {
+// EnsureVlanUntagger ensure the tc filter to pop the vlan tag,return changed and err
+func EnsureVlanUntagger(link netlink.Link) (bool, error) {
 	if err := EnsureClsActQdsic(link); err != nil {
-		return fmt.Errorf("error ensure cls act qdisc for %s vlan untag, %w", link.Attrs().Name, err)
+		return false, fmt.Errorf("error ensure cls act qdisc for %s vlan untag, %w", link.Attrs().Name, err)
 	}
 	filters, err := netlink.FilterList(link, netlink.HANDLE_MIN_INGRESS)
 	if err != nil {
-		return fmt.Errorf("list ingress filter for %s error, %w", link.Attrs().Name, err)
+		return false, fmt.Errorf("list ingress filter for %s error, %w", link.Attrs().Name, err)
 	}
 	for _, filter := range filters {
 		if u32, ok := filter.(*netlink.U32); ok {
@@ -391,7 +393,7 @@ func EnsureVlanUntagger(link netlink.Link) error {
 				len(u32.Actions) == 1 {
 				if action, ok := u32.Actions[0].(*netlink.VlanAction); ok {
 					if action.Action == netlink.TCA_VLAN_KEY_POP {
-						return nil
+						return false, nil
 					}
 				}
 			}
@@ -422,23 +424,24 @@ func EnsureVlanUntagger(link netlink.Link) error {
 	}
 	err = netlink.FilterAdd(u32)
 	if err != nil {
-		return fmt.Errorf("error add filter for vlan untag, %w", err)
+		return false, fmt.Errorf("error add filter for vlan untag, %w", err)
 	}
-	return nil
+	return true, nil
 }
 
-// EnsureVlanTag use tc-vlan set vlan tag
-func EnsureVlanTag(link netlink.Link, ipNetSet *terwayTypes.IPNetSet, vid uint16) error {
+// EnsureVlanTag use tc-vlan set vlan tag,return changed and err
+func EnsureVlanTag(link netlink.Link, ipNetSet *terwayTypes.IPNetSet, vid uint16) (bool, error) {
 	err := EnsureClsActQdsic(link)
 	if err != nil {
-		return fmt.Errorf("error ensure cls act qdisc for %s vlan tag, %w", link.Attrs().Name, err)
+		return false, fmt.Errorf("error ensure cls act qdisc for %s vlan tag, %w", link.Attrs().Name, err)
 	}
 
 	filters, err := netlink.FilterList(link, netlink.HANDLE_MIN_EGRESS)
 	if err != nil {
-		return fmt.Errorf("list ingress filter for %s error, %w", link.Attrs().Name, err)
+		return false, fmt.Errorf("list ingress filter for %s error, %w", link.Attrs().Name, err)
 	}
 
+	changed := false
 	exec := func(ipNet *net.IPNet) error {
 		vlanAct := netlink.NewVlanKeyAction()
 		vlanAct.Attrs().Action = netlink.TC_ACT_PIPE
@@ -474,6 +477,7 @@ func EnsureVlanTag(link netlink.Link, ipNetSet *terwayTypes.IPNetSet, vid uint16
 				continue
 			}
 			if act.Vid != vid {
+				changed = true
 				err = FilterDel(u32)
 				if err != nil {
 					return err
@@ -483,19 +487,20 @@ func EnsureVlanTag(link netlink.Link, ipNetSet *terwayTypes.IPNetSet, vid uint16
 			return nil
 		}
 
+		changed = true
 		return FilterAdd(expect)
 	}
 
 	if ipNetSet.IPv4 != nil {
 		err = exec(NewIPNetWithMaxMask(ipNetSet.IPv4))
 		if err != nil {
-			return err
+			return changed, err
 		}
 	}
 	if ipNetSet.IPv6 != nil {
 		err = exec(NewIPNetWithMaxMask(ipNetSet.IPv6))
 	}
-	return err
+	return changed, err
 }
 
 func EnsureClsActQdsic(link netlink.Link) error {
@@ -601,8 +606,9 @@ func FilterDel(filter netlink.Filter) error {
 	return nil
 }
 
-// SetFilter write u32 filter
-func SetFilter(link netlink.Link, parentID, classID uint32, ipNetSet *terwayTypes.IPNetSet) error {
+// SetFilter write u32 filter,return changed and err
+func SetFilter(link netlink.Link, parentID, classID uint32, ipNetSet *terwayTypes.IPNetSet) (bool, error) {
+	changed := false
 	exec := func(ipNet *net.IPNet) error {
 		found, err := tc.FilterBySrcIP(link, parentID, ipNet)
 		if err != nil {
@@ -611,6 +617,15 @@ func SetFilter(link netlink.Link, parentID, classID uint32, ipNetSet *terwayType
 		if found != nil && found.ClassId == classID {
 			return nil
 		}
+		changed = true
+
+		// the priority of the pod is changed
+		if found != nil {
+			err = FilterDel(found)
+			if err != nil {
+				return err
+			}
+		}
 
 		u32 := &netlink.U32{
 			FilterAttrs: netlink.FilterAttrs{
@@ -629,16 +644,16 @@ func SetFilter(link netlink.Link, parentID, classID uint32, ipNetSet *terwayType
 	if ipNetSet.IPv4 != nil {
 		err := exec(NewIPNetWithMaxMask(ipNetSet.IPv4))
 		if err != nil {
-			return err
+			return changed, err
 		}
 	}
 	if ipNetSet.IPv6 != nil {
 		err := exec(NewIPNetWithMaxMask(ipNetSet.IPv6))
 		if err != nil {
-			return err
+			return changed, err
 		}
 	}
-	return nil
+	return changed, nil
 }
 
 // DelFilter del u32 filter by pod ip
@@ -674,32 +689,34 @@ func DelFilter(link netlink.Link, parentID uint32, ipNetSet *terwayTypes.IPNetSe
 	return nil
 }
 
-// SetEgressPriority write egress priority rule for pod
-func SetEgressPriority(link netlink.Link, classID uint32, ipNetSet *terwayTypes.IPNetSet) error {
+// SetEgressPriority write egress priority rule for pod,return changed and err
+func SetEgressPriority(link netlink.Link, classID uint32, ipNetSet *terwayTypes.IPNetSet) (bool, error) {
 	err := EnsureMQQdisc(link)
 	if err != nil {
-		return err
+		return false, err
 	}
 	err = EnsurePrioQdiscAt10(link)
 	if err != nil {
-		return err
+		return false, err
 	}
 	qds, err := netlink.QdiscList(link)
 	if err != nil {
-		return fmt.Errorf("list qdisc for dev %s error, %w", link.Attrs().Name, err)
+		return false, fmt.Errorf("list qdisc for dev %s error, %w", link.Attrs().Name, err)
 	}
+	changed := false
 	for _, q := range qds {
 		_, ok := q.(*netlink.Prio)
 		if !ok {
 			continue
 		}
 
-		err = SetFilter(link, q.Attrs().Handle, classID, ipNetSet)
+		filterChanged, err := SetFilter(link, q.Attrs().Handle, classID, ipNetSet)
+		changed = changed || filterChanged
 		if err != nil {
-			return err
+			return changed, err
 		}
 	}
-	return nil
+	return changed, nil
 }
 
 func DelEgressPriority(link netlink.Link, ipNetSet *terwayTypes.IPNetSet) error {
@@ -727,6 +744,192 @@ func SetupTC(link netlink.Link, bandwidthInBytes uint64) error {
 	return tc.SetRule(link, rule)
 }
 
+// EnsureTC setup the tbf qdisc if the root qdisc is not tbf with the rate,return changed and err
+func EnsureTC(link netlink.Link, bandwidthInBytes uint64) (bool, error) {
+	qds, err := netlink.QdiscList(link)
+	if err != nil {
+		return false, fmt.Errorf("list qdisc for dev %s error, %w", link.Attrs().Name, err)
+	}
+	for _, q := range qds {
+		tbf, ok := q.(*netlink.Tbf)
+		if !ok || tbf.Parent != netlink.HANDLE_ROOT {
+			continue
+		}
+		if tbf.Rate == bandwidthInBytes {
+			return false, nil
+		}
+	}
+	return true, SetupTC(link, bandwidthInBytes)
+}
+
+// DelTC remove the root tbf qdisc for the bandwidth limit,return changed and err
+func DelTC(link netlink.Link) (bool, error) {
+	qds, err := netlink.QdiscList(link)
+	if err != nil {
+		return false, fmt.Errorf("list qdisc for dev %s error, %w", link.Attrs().Name, err)
+	}
+	for _, q := range qds {
+		tbf, ok := q.(*netlink.Tbf)
+		if !ok || tbf.Parent != netlink.HANDLE_ROOT {
+			continue
+		}
+		return true, QdiscDel(tbf)
+	}
+	return false, nil
+}
+
+// EnsureFQ set the fq qdisc for the edt, multi queue link use mq as root and fq for each tx queue,
+// return changed and err
+func EnsureFQ(link netlink.Link) (bool, error) {
+	qds, err := netlink.QdiscList(link)
+	if err != nil {
+		return false, fmt.Errorf("list qdisc for dev %s error, %w", link.Attrs().Name, err)
+	}
+
+	if link.Attrs().NumTxQueues <= 1 {
+		for _, q := range qds {
+			if q.Type() == "fq" && q.Attrs().Parent == netlink.HANDLE_ROOT {
+				return false, nil
+			}
+		}
+		return true, QdiscReplace(&netlink.GenericQdisc{
+			QdiscAttrs: netlink.QdiscAttrs{
+				LinkIndex: link.Attrs().Index,
+				Parent:    netlink.HANDLE_ROOT,
+				Handle:    netlink.MakeHandle(1, 0),
+			},
+			QdiscType: "fq",
+		})
+	}
+
+	changed := false
+	mqFound := false
+	for _, q := range qds {
+		if q.Type() == "mq" && q.Attrs().Parent == netlink.HANDLE_ROOT && q.Attrs().Handle == netlink.MakeHandle(1, 0) {
+			mqFound = true
+		}
+	}
+	if !mqFound {
+		changed = true
+		err = EnsureMQQdisc(link)
+		if err != nil {
+			return changed, err
+		}
+		qds, err = netlink.QdiscList(link)
+		if err != nil {
+			return changed, fmt.Errorf("list qdisc for dev %s error, %w", link.Attrs().Name, err)
+		}
+	}
+
+	// mq create the child qdisc for each tx queue at 1:1 ... 1:n
+	for _, q := range qds {
+		major, minor := netlink.MajorMinor(q.Attrs().Parent)
+		if major != 1 || minor == 0 {
+			continue
+		}
+		if q.Type() == "fq" {
+			continue
+		}
+		changed = true
+		err = QdiscReplace(&netlink.GenericQdisc{
+			QdiscAttrs: netlink.QdiscAttrs{
+				LinkIndex: link.Attrs().Index,
+				Parent:    q.Attrs().Parent,
+			},
+			QdiscType: "fq",
+		})
+		if err != nil {
+			return changed, err
+		}
+	}
+	return changed, nil
+}
+
+// EnsureEDT shape the traffic of the pod by the edt program on the link egress, the rate 0 remove the limit,
+// return changed and err
+func EnsureEDT(link netlink.Link, dir tc.EDTDirection, ipNetSet *terwayTypes.IPNetSet, bandwidthInBytes uint64) (bool, error) {
+	if bandwidthInBytes == 0 {
+		return false, delEDTRate(dir, ipNetSet)
+	}
+
+	changed, err := EnsureFQ(link)
+	if err != nil {
+		return changed, err
+	}
+	err = EnsureClsActQdsic(link)
+	if err != nil {
+		return changed, err
+	}
+	filterChanged, err := tc.EnsureEDTFilter(link, dir)
+	changed = changed || filterChanged
+	if err != nil {
+		return changed, err
+	}
+
+	exec := func(ip net.IP) error {
+		rate, ok, err := tc.GetEDTRate(dir, ip)
+		if err != nil {
+			return err
+		}
+		if ok && rate == bandwidthInBytes {
+			return nil
+		}
+		changed = true
+		Log.Infof("set edt %s rate %d for %s", dir, bandwidthInBytes, ip)
+		return tc.SetEDTRate(dir, ip, bandwidthInBytes)
+	}
+	if ipNetSet.IPv4 != nil {
+		err = exec(ipNetSet.IPv4.IP)
+		if err != nil {
+			return changed, err
+		}
+	}
+	if ipNetSet.IPv6 != nil {
+		err = exec(ipNetSet.IPv6.IP)
+		if err != nil {
+			return changed, err
+		}
+	}
+	return changed, nil
+}
+
+func delEDTRate(dir tc.EDTDirection, ipNetSet *terwayTypes.IPNetSet) error {
+	if ipNetSet.IPv4 != nil {
+		err := tc.DelEDTRate(dir, ipNetSet.IPv4.IP)
+		if err != nil {
+			return err
+		}
+	}
+	if ipNetSet.IPv6 != nil {
+		err := tc.DelEDTRate(dir, ipNetSet.IPv6.IP)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DelEDT remove the edt rate for the pod in both direction
+func DelEDT(ipNetSet *terwayTypes.IPNetSet) error {
+	if ipNetSet == nil {
+		return nil
+	}
+	err := delEDTRate(tc.EDTEgress, ipNetSet)
+	if err != nil {
+		return err
+	}
+	return delEDTRate(tc.EDTIngress, ipNetSet)
+}
+
+// EnsureSysctl write the sysctl if the value is not expected,return changed and err
+func EnsureSysctl(fPath, value string) (bool, error) {
+	content, err := os.ReadFile(fPath)
+	if err == nil && strings.TrimSpace(string(content)) == value {
+		return false, nil
+	}
+	return true, terwaySysctl.EnsureConf(fPath, value)
+}
+
 // GenericTearDown target to clean all related resource as much as possible
 func GenericTearDown(netNS ns.NetNS) error {
 	var errList []error
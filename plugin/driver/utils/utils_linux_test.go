@@ -59,7 +59,7 @@ func TestEnsureVlanUntagger(t *testing.T) {
 	eni, err := netlink.LinkByName("eni")
 	assert.NoError(t, err)
 
-	err = EnsureVlanUntagger(eni)
+	_, err = EnsureVlanUntagger(eni)
 	if err != nil {
 		t.Errorf("error ensure vlan untagger, %v", err)
 		t.Fail()
@@ -137,7 +137,7 @@ var _ = Describe("Test TC filter", func() {
 			eni, err := netlink.LinkByName(nicName)
 			Expect(err).NotTo(HaveOccurred())
 
-			err = SetEgressPriority(eni, netlink.MakeHandle(1, 1), &terwayTypes.IPNetSet{
+			_, err = SetEgressPriority(eni, netlink.MakeHandle(1, 1), &terwayTypes.IPNetSet{
 				IPv4: &net.IPNet{
 					IP:   net.ParseIP("192.168.1.1"),
 					Mask: net.CIDRMask(32, 32),
@@ -184,7 +184,7 @@ var _ = Describe("Test TC filter", func() {
 			Expect(err).NotTo(HaveOccurred())
 
 			By("add new ip", func() {
-				err = EnsureVlanTag(eni, &terwayTypes.IPNetSet{
+				_, err = EnsureVlanTag(eni, &terwayTypes.IPNetSet{
 					IPv4: &net.IPNet{
 						IP:   net.ParseIP("192.168.1.1"),
 						Mask: net.CIDRMask(32, 32),
@@ -203,7 +203,7 @@ var _ = Describe("Test TC filter", func() {
 			})
 
 			By("re-add same ip shoud succeed", func() {
-				err = EnsureVlanTag(eni, &terwayTypes.IPNetSet{
+				_, err = EnsureVlanTag(eni, &terwayTypes.IPNetSet{
 					IPv4: &net.IPNet{
 						IP:   net.ParseIP("192.168.1.1"),
 						Mask: net.CIDRMask(32, 32),
@@ -222,7 +222,7 @@ var _ = Describe("Test TC filter", func() {
 			})
 
 			By("add new rule should success", func() {
-				err = EnsureVlanTag(eni, &terwayTypes.IPNetSet{
+				_, err = EnsureVlanTag(eni, &terwayTypes.IPNetSet{
 					IPv4: &net.IPNet{
 						IP:   net.ParseIP("192.168.1.2"),
 						Mask: net.CIDRMask(32, 32),
@@ -260,7 +260,7 @@ var _ = Describe("Test TC filter", func() {
 			})
 
 			By("add same ip vid should be updated", func() {
-				err = EnsureVlanTag(eni, &terwayTypes.IPNetSet{
+				_, err = EnsureVlanTag(eni, &terwayTypes.IPNetSet{
 					IPv4: &net.IPNet{
 						IP:   net.ParseIP("192.168.1.1"),
 						Mask: net.CIDRMask(32, 32),
@@ -7,10 +7,14 @@ import (
 	"runtime"
 	"time"
 
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc/backoff"
 	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/AliyunContainerService/terway/pkg/link"
+	"github.com/AliyunContainerService/terway/pkg/telemetry"
 	"github.com/AliyunContainerService/terway/plugin/datapath"
 	"github.com/AliyunContainerService/terway/plugin/driver/types"
 	"github.com/AliyunContainerService/terway/plugin/driver/utils"
@@ -27,10 +31,10 @@ import (
 
 const (
 	defaultSocketPath   = "/var/run/eni/eni.socket"
-	defaultVethPrefix   = "cali"
 	defaultDialTimeout  = 10 * time.Second
 	defaultCniTimeout   = 120 * time.Second
 	defaultEventTimeout = 10 * time.Second
+	defaultFlushTimeout = 2 * time.Second
 	delegateIpam        = "host-local"
 	defaultMTU          = 1500
 	delegateConf        = `
@@ -48,7 +52,7 @@ const (
 }
 `
 
-	terwayCNILock = "/var/run/eni/terway_cni.lock"
+	terwayCNILock = utils.CNILockPath
 )
 
 func init() {
@@ -84,6 +88,24 @@ func cmdAdd(args *skel.CmdArgs) error {
 	ctx, cancel := context.WithTimeout(context.Background(), defaultCniTimeout)
 	defer cancel()
 
+	shutdown, err := telemetry.Setup(ctx, "terway-cni", conf.OTLPEndpoint)
+	if err != nil {
+		logger.WithError(err).Warn("error setup tracing")
+	} else {
+		defer func() {
+			flushCtx, flushCancel := context.WithTimeout(context.Background(), defaultFlushTimeout)
+			defer flushCancel()
+			_ = shutdown(flushCtx)
+		}()
+	}
+	ctx, span := telemetry.Start(ctx, "cmdAdd", trace.WithAttributes(
+		attribute.String("podName", string(k8sConfig.K8S_POD_NAME)),
+		attribute.String("podNamespace", string(k8sConfig.K8S_POD_NAMESPACE)),
+		attribute.String("containerID", string(k8sConfig.K8S_POD_INFRA_CONTAINER_ID))))
+	defer func() {
+		telemetry.End(span, err)
+	}()
+
 	client, conn, err := getNetworkClient(ctx)
 	if err != nil {
 		return err
@@ -227,6 +249,7 @@ func getNetworkClient(ctx context.Context) (rpc.TerwayBackendClient, *grpc.Clien
 			return d.DialContext(ctx, "unix", unixAddr.String())
 		}),
 		grpc.WithBlock(),
+		grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor()),
 		grpc.WithConnectParams(grpc.ConnectParams{
 			Backoff: backoff.Config{
 				BaseDelay:  time.Second,
@@ -244,6 +267,16 @@ func getNetworkClient(ctx context.Context) (rpc.TerwayBackendClient, *grpc.Clien
 	return client, conn, nil
 }
 
+// traceSetup run the datapath setup of the interface in a span of ctx
+func traceSetup(ctx context.Context, dp, ifName string, setup func() error) error {
+	_, span := telemetry.Start(ctx, "datapath.Setup", trace.WithAttributes(
+		attribute.String("datapath", dp),
+		attribute.String("ifName", ifName)))
+	err := setup()
+	telemetry.End(span, err)
+	return err
+}
+
 func parseSetupConf(args *skel.CmdArgs, alloc *rpc.NetConf, conf *types.CNIConf, ipType rpc.IPType) (*types.SetupConfig, error) {
 	var (
 		err            error
@@ -352,7 +385,7 @@ func parseSetupConf(args *skel.CmdArgs, alloc *rpc.NetConf, conf *types.CNIConf,
 		routes = append(routes, route)
 	}
 
-	dp := getDatePath(ipType, conf.VlanStripType, trunkENI)
+	dp := types.GetDataPath(ipType, conf.VlanStripType, trunkENI)
 	return &types.SetupConfig{
 		DP:                    dp,
 		ContainerIfName:       name,
@@ -420,13 +453,14 @@ func parseTearDownConf(alloc *rpc.NetConf, conf *types.CNIConf, ipType rpc.IPTyp
 		}
 	}
 
-	dp := getDatePath(ipType, conf.VlanStripType, false)
+	dp := types.GetDataPath(ipType, conf.VlanStripType, false)
 	return &types.TeardownCfg{
 		DP:                    dp,
 		ContainerIPNet:        containerIPNet,
 		ServiceCIDR:           serviceCIDR,
 		ENIIndex:              int(eniIndex),
 		EnableNetworkPriority: conf.EnableNetworkPriority,
+		RuntimeConfig:         conf.RuntimeConfig,
 	}, nil
 }
 
@@ -469,7 +503,7 @@ func parseCheckConf(args *skel.CmdArgs, alloc *rpc.NetConf, conf *types.CNIConf,
 		name = args.IfName
 	}
 
-	dp := getDatePath(ipType, conf.VlanStripType, trunkENI)
+	dp := types.GetDataPath(ipType, conf.VlanStripType, trunkENI)
 	return &types.CheckConfig{
 		DP:              dp,
 		ContainerIfName: name,
@@ -481,22 +515,3 @@ func parseCheckConf(args *skel.CmdArgs, alloc *rpc.NetConf, conf *types.CNIConf,
 		DefaultRoute:    alloc.GetDefaultRoute(),
 	}, nil
 }
-
-func getDatePath(ipType rpc.IPType, vlanStripType types.VlanStripType, trunk bool) types.DataPath {
-	switch ipType {
-	case rpc.IPType_TypeVPCIP:
-		return types.VPCRoute
-	case rpc.IPType_TypeVPCENI:
-		if trunk {
-			return types.Vlan
-		}
-		return types.ExclusiveENI
-	case rpc.IPType_TypeENIMultiIP:
-		if trunk && vlanStripType == types.VlanStripTypeVlan {
-			return types.Vlan
-		}
-		return types.IPVlan
-	default:
-		panic(fmt.Sprintf("unsupported ipType %s", ipType))
-	}
-}
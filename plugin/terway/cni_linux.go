@@ -189,7 +189,7 @@ func doCmdAdd(ctx context.Context, logger *logrus.Entry, client rpc.TerwayBacken
 			err = fmt.Errorf("error parse config, %w", err)
 			return
 		}
-		setupCfg.HostVETHName, _ = link.VethNameForPod(string(k8sConfig.K8S_POD_NAME), string(k8sConfig.K8S_POD_NAMESPACE), netConf.IfName, defaultVethPrefix)
+		setupCfg.HostVETHName, _ = link.VethNameForPod(string(k8sConfig.K8S_POD_NAME), string(k8sConfig.K8S_POD_NAMESPACE), netConf.IfName, link.HostVethPrefix)
 		setupCfg.HostIPSet = hostIPSet
 		setupCfg.MultiNetwork = multiNetwork
 		logger.Debugf("setupCfg %#v", setupCfg)
@@ -233,7 +233,9 @@ func doCmdAdd(ctx context.Context, logger *logrus.Entry, client rpc.TerwayBacken
 				setupCfg.ContainerIPNet = containerIPNet
 				setupCfg.GatewayIP = gatewayIPSet
 
-				return datapath.NewVPCRoute().Setup(setupCfg, cniNetns)
+				return traceSetup(ctx, "vpcRoute", setupCfg.ContainerIfName, func() error {
+					return datapath.NewVPCRoute().Setup(setupCfg, cniNetns)
+				})
 			}()
 			if err != nil {
 				return
@@ -252,7 +254,9 @@ func doCmdAdd(ctx context.Context, logger *logrus.Entry, client rpc.TerwayBacken
 						containerIPNet = setupCfg.ContainerIPNet
 						gatewayIPSet = setupCfg.GatewayIP
 					}
-					err = datapath.NewIPVlanDriver().Setup(setupCfg, cniNetns)
+					err = traceSetup(ctx, "ipvlan", setupCfg.ContainerIfName, func() error {
+						return datapath.NewIPVlanDriver().Setup(setupCfg, cniNetns)
+					})
 					if err != nil {
 						return
 					}
@@ -275,7 +279,9 @@ func doCmdAdd(ctx context.Context, logger *logrus.Entry, client rpc.TerwayBacken
 				containerIPNet = setupCfg.ContainerIPNet
 				gatewayIPSet = setupCfg.GatewayIP
 			}
-			err = datapath.NewPolicyRoute().Setup(setupCfg, cniNetns)
+			err = traceSetup(ctx, "policyRoute", setupCfg.ContainerIfName, func() error {
+				return datapath.NewPolicyRoute().Setup(setupCfg, cniNetns)
+			})
 			if err != nil {
 				return
 			}
@@ -287,7 +293,9 @@ func doCmdAdd(ctx context.Context, logger *logrus.Entry, client rpc.TerwayBacken
 				gatewayIPSet = setupCfg.GatewayIP
 			}
 
-			err = datapath.NewExclusiveENIDriver().Setup(setupCfg, cniNetns)
+			err = traceSetup(ctx, "exclusiveENI", setupCfg.ContainerIfName, func() error {
+				return datapath.NewExclusiveENIDriver().Setup(setupCfg, cniNetns)
+			})
 			if err != nil {
 				return
 			}
@@ -298,7 +306,9 @@ func doCmdAdd(ctx context.Context, logger *logrus.Entry, client rpc.TerwayBacken
 				containerIPNet = setupCfg.ContainerIPNet
 				gatewayIPSet = setupCfg.GatewayIP
 			}
-			err = datapath.NewVlan().Setup(setupCfg, cniNetns)
+			err = traceSetup(ctx, "vlan", setupCfg.ContainerIfName, func() error {
+				return datapath.NewVlan().Setup(setupCfg, cniNetns)
+			})
 			if err != nil {
 				err = fmt.Errorf("setup, %w", err)
 				return
@@ -367,6 +377,10 @@ func doCmdDel(ctx context.Context, logger *logrus.Entry, client rpc.TerwayBacken
 				if err != nil {
 					return fmt.Errorf("teardown network ipam for pod: %s-%s, %w", string(k8sConfig.K8S_POD_NAMESPACE), string(k8sConfig.K8S_POD_NAME), err)
 				}
+				err = utils.DelEDT(teardownCfg.ContainerIPNet)
+				if err != nil {
+					return err
+				}
 			case types.IPVlan:
 				utils.Hook.AddExtraInfo("dp", "ipvlan")
 
@@ -391,6 +405,12 @@ func doCmdDel(ctx context.Context, logger *logrus.Entry, client rpc.TerwayBacken
 				if err != nil {
 					return err
 				}
+			case types.ExclusiveENI:
+				utils.Hook.AddExtraInfo("dp", "exclusiveENI")
+				err = datapath.NewExclusiveENIDriver().Teardown(teardownCfg, cniNetns)
+				if err != nil {
+					return err
+				}
 			}
 		}
 		return nil
@@ -439,6 +459,8 @@ func doCmdCheck(ctx context.Context, logger *logrus.Entry, client rpc.TerwayBack
 		return fmt.Errorf("error setup host ns configs, %w", err)
 	}
 
+	multiNetwork := len(getResult.NetConfs) > 1
+
 	l, err := utils.GrabFileLock(terwayCNILock)
 	if err != nil {
 		return err
@@ -452,8 +474,18 @@ func doCmdCheck(ctx context.Context, logger *logrus.Entry, client rpc.TerwayBack
 			return fmt.Errorf("error parse config, %w", err)
 		}
 		checkCfg.NetNS = cniNetns
-		checkCfg.HostVETHName, _ = link.VethNameForPod(string(k8sConfig.K8S_POD_NAME), string(k8sConfig.K8S_POD_NAMESPACE), netConf.IfName, defaultVethPrefix)
+		checkCfg.HostVETHName, _ = link.VethNameForPod(string(k8sConfig.K8S_POD_NAME), string(k8sConfig.K8S_POD_NAMESPACE), netConf.IfName, link.HostVethPrefix)
 		checkCfg.HostIPSet = hostIPSet
+		checkCfg.MultiNetwork = multiNetwork
+
+		// the expected state is generated the same way as cni add
+		checkCfg.SetupConfig, err = parseSetupConf(args, netConf, conf, getResult.IPType)
+		if err != nil {
+			return fmt.Errorf("error parse config, %w", err)
+		}
+		checkCfg.SetupConfig.HostVETHName = checkCfg.HostVETHName
+		checkCfg.SetupConfig.HostIPSet = hostIPSet
+		checkCfg.SetupConfig.MultiNetwork = multiNetwork
 		checkCfg.RecordPodEvent = func(msg string) {
 			eventCtx, cancel := context.WithTimeout(ctx, defaultEventTimeout)
 			defer cancel()
@@ -469,6 +501,13 @@ func doCmdCheck(ctx context.Context, logger *logrus.Entry, client rpc.TerwayBack
 		}
 
 		switch checkCfg.DP {
+		case types.VPCRoute:
+			utils.Hook.AddExtraInfo("dp", "vpcRoute")
+
+			err = datapath.NewVPCRoute().Check(checkCfg)
+			if err != nil {
+				return err
+			}
 		case types.IPVlan:
 			utils.Hook.AddExtraInfo("dp", "ipvlan")
 
@@ -164,7 +164,7 @@ func doCmdAdd(ctx context.Context, logger *logrus.Entry, client rpc.TerwayBacken
 			err = fmt.Errorf("error parse config, %w", err)
 			return
 		}
-		setupCfg.HostVETHName, _ = link.VethNameForPod(string(k8sConfig.K8S_POD_NAME), string(k8sConfig.K8S_POD_NAMESPACE), netConf.IfName, defaultVethPrefix)
+		setupCfg.HostVETHName, _ = link.VethNameForPod(string(k8sConfig.K8S_POD_NAME), string(k8sConfig.K8S_POD_NAMESPACE), netConf.IfName, link.HostVethPrefix)
 		setupCfg.MultiNetwork = multiNetwork
 		logger.Debugf("setupCfg %#v", setupCfg)
 
@@ -208,7 +208,9 @@ func doCmdAdd(ctx context.Context, logger *logrus.Entry, client rpc.TerwayBacken
 				setupCfg.ContainerIPNet = containerIPNet
 				setupCfg.GatewayIP = gatewayIPSet
 
-				return datapath.NewVPCRoute().Setup(ctx, setupCfg, args.ContainerID, args.Netns)
+				return traceSetup(ctx, "vpcRoute", setupCfg.ContainerIfName, func() error {
+					return datapath.NewVPCRoute().Setup(ctx, setupCfg, args.ContainerID, args.Netns)
+				})
 			}()
 			if err != nil {
 				return
@@ -257,7 +259,9 @@ func doCmdAdd(ctx context.Context, logger *logrus.Entry, client rpc.TerwayBacken
 					IPv4: gateway,
 				}
 
-				return datapath.NewPolicyRoute().Setup(ctx, setupCfg, args.ContainerID, args.Netns)
+				return traceSetup(ctx, "policyRoute", setupCfg.ContainerIfName, func() error {
+					return datapath.NewPolicyRoute().Setup(ctx, setupCfg, args.ContainerID, args.Netns)
+				})
 			}()
 			if err != nil {
 				return
@@ -304,7 +308,9 @@ func doCmdAdd(ctx context.Context, logger *logrus.Entry, client rpc.TerwayBacken
 					IPv4: gateway,
 				}
 
-				return datapath.NewExclusiveENIDriver().Setup(ctx, setupCfg, args.ContainerID, args.Netns)
+				return traceSetup(ctx, "exclusiveENI", setupCfg.ContainerIfName, func() error {
+					return datapath.NewExclusiveENIDriver().Setup(ctx, setupCfg, args.ContainerID, args.Netns)
+				})
 			}()
 			if err != nil {
 				return
@@ -354,7 +360,7 @@ func doCmdDel(ctx context.Context, logger *logrus.Entry, client rpc.TerwayBacken
 			return nil
 		}
 		teardownCfg.ContainerIfName = netConf.IfName
-		teardownCfg.HostVETHName, _ = link.VethNameForPod(string(k8sConfig.K8S_POD_NAME), string(k8sConfig.K8S_POD_NAMESPACE), netConf.IfName, defaultVethPrefix)
+		teardownCfg.HostVETHName, _ = link.VethNameForPod(string(k8sConfig.K8S_POD_NAME), string(k8sConfig.K8S_POD_NAMESPACE), netConf.IfName, link.HostVethPrefix)
 
 		switch teardownCfg.DP {
 		case types.VPCRoute:
@@ -442,7 +448,7 @@ func doCmdCheck(ctx context.Context, logger *logrus.Entry, client rpc.TerwayBack
 		if err != nil {
 			return fmt.Errorf("error parse config, %w", err)
 		}
-		checkCfg.HostVETHName, _ = link.VethNameForPod(string(k8sConfig.K8S_POD_NAME), string(k8sConfig.K8S_POD_NAMESPACE), netConf.IfName, defaultVethPrefix)
+		checkCfg.HostVETHName, _ = link.VethNameForPod(string(k8sConfig.K8S_POD_NAME), string(k8sConfig.K8S_POD_NAMESPACE), netConf.IfName, link.HostVethPrefix)
 		checkCfg.RecordPodEvent = func(msg string) {
 			eventCtx, cancel := context.WithTimeout(ctx, defaultEventTimeout)
 			defer cancel()
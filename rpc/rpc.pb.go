@@ -207,6 +207,55 @@ func (EventType) EnumDescriptor() ([]byte, []int) {
 	return file_rpc_proto_rawDescGZIP(), []int{3}
 }
 
+type PodEventType int32
+
+const (
+	PodEventType_PodEventAllocate PodEventType = 0
+	PodEventType_PodEventRelease  PodEventType = 1
+	PodEventType_PodEventGC       PodEventType = 2 // released by the daemon, the pod is deleted or the ip held is expired
+)
+
+// Enum value maps for PodEventType.
+var (
+	PodEventType_name = map[int32]string{
+		0: "PodEventAllocate",
+		1: "PodEventRelease",
+		2: "PodEventGC",
+	}
+	PodEventType_value = map[string]int32{
+		"PodEventAllocate": 0,
+		"PodEventRelease":  1,
+		"PodEventGC":       2,
+	}
+)
+
+func (x PodEventType) Enum() *PodEventType {
+	p := new(PodEventType)
+	*p = x
+	return p
+}
+
+func (x PodEventType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (PodEventType) Descriptor() protoreflect.EnumDescriptor {
+	return file_rpc_proto_enumTypes[4].Descriptor()
+}
+
+func (PodEventType) Type() protoreflect.EnumType {
+	return &file_rpc_proto_enumTypes[4]
+}
+
+func (x PodEventType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use PodEventType.Descriptor instead.
+func (PodEventType) EnumDescriptor() ([]byte, []int) {
+	return file_rpc_proto_rawDescGZIP(), []int{4}
+}
+
 // IPSet declare a string set contain v4 v6 info
 type IPSet struct {
 	state         protoimpl.MessageState
@@ -1227,6 +1276,212 @@ func (x *EventReply) GetError() string {
 	return ""
 }
 
+type WatchEventsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// ResumeToken of the last event received, start with the current pods if empty
+	ResumeToken string `protobuf:"bytes,1,opt,name=ResumeToken,proto3" json:"ResumeToken,omitempty"`
+}
+
+func (x *WatchEventsRequest) Reset() {
+	*x = WatchEventsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpc_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WatchEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchEventsRequest) ProtoMessage() {}
+
+func (x *WatchEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchEventsRequest.ProtoReflect.Descriptor instead.
+func (*WatchEventsRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *WatchEventsRequest) GetResumeToken() string {
+	if x != nil {
+		return x.ResumeToken
+	}
+	return ""
+}
+
+type PodEventNetwork struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	IfName string `protobuf:"bytes,1,opt,name=IfName,proto3" json:"IfName,omitempty"`
+	PodIP  *IPSet `protobuf:"bytes,2,opt,name=PodIP,proto3" json:"PodIP,omitempty"`
+	MAC    string `protobuf:"bytes,3,opt,name=MAC,proto3" json:"MAC,omitempty"`  // mac of the eni
+	Vid    uint32 `protobuf:"varint,4,opt,name=Vid,proto3" json:"Vid,omitempty"` // vlan ID
+}
+
+func (x *PodEventNetwork) Reset() {
+	*x = PodEventNetwork{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpc_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PodEventNetwork) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PodEventNetwork) ProtoMessage() {}
+
+func (x *PodEventNetwork) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PodEventNetwork.ProtoReflect.Descriptor instead.
+func (*PodEventNetwork) Descriptor() ([]byte, []int) {
+	return file_rpc_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *PodEventNetwork) GetIfName() string {
+	if x != nil {
+		return x.IfName
+	}
+	return ""
+}
+
+func (x *PodEventNetwork) GetPodIP() *IPSet {
+	if x != nil {
+		return x.PodIP
+	}
+	return nil
+}
+
+func (x *PodEventNetwork) GetMAC() string {
+	if x != nil {
+		return x.MAC
+	}
+	return ""
+}
+
+func (x *PodEventNetwork) GetVid() uint32 {
+	if x != nil {
+		return x.Vid
+	}
+	return 0
+}
+
+type PodEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Type            PodEventType       `protobuf:"varint,1,opt,name=Type,proto3,enum=rpc.PodEventType" json:"Type,omitempty"`
+	ResumeToken     string             `protobuf:"bytes,2,opt,name=ResumeToken,proto3" json:"ResumeToken,omitempty"`
+	K8SPodName      string             `protobuf:"bytes,3,opt,name=K8sPodName,proto3" json:"K8sPodName,omitempty"`
+	K8SPodNamespace string             `protobuf:"bytes,4,opt,name=K8sPodNamespace,proto3" json:"K8sPodNamespace,omitempty"`
+	Networks        []*PodEventNetwork `protobuf:"bytes,5,rep,name=Networks,proto3" json:"Networks,omitempty"`
+	Timestamp       int64              `protobuf:"varint,6,opt,name=Timestamp,proto3" json:"Timestamp,omitempty"` // unix time in seconds
+}
+
+func (x *PodEvent) Reset() {
+	*x = PodEvent{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpc_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PodEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PodEvent) ProtoMessage() {}
+
+func (x *PodEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PodEvent.ProtoReflect.Descriptor instead.
+func (*PodEvent) Descriptor() ([]byte, []int) {
+	return file_rpc_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *PodEvent) GetType() PodEventType {
+	if x != nil {
+		return x.Type
+	}
+	return PodEventType_PodEventAllocate
+}
+
+func (x *PodEvent) GetResumeToken() string {
+	if x != nil {
+		return x.ResumeToken
+	}
+	return ""
+}
+
+func (x *PodEvent) GetK8SPodName() string {
+	if x != nil {
+		return x.K8SPodName
+	}
+	return ""
+}
+
+func (x *PodEvent) GetK8SPodNamespace() string {
+	if x != nil {
+		return x.K8SPodNamespace
+	}
+	return ""
+}
+
+func (x *PodEvent) GetNetworks() []*PodEventNetwork {
+	if x != nil {
+		return x.Networks
+	}
+	return nil
+}
+
+func (x *PodEvent) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
 var File_rpc_proto protoreflect.FileDescriptor
 
 var file_rpc_proto_rawDesc = []byte{
@@ -1364,36 +1619,70 @@ var file_rpc_proto_rawDesc = []byte{
 	0x74, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x18, 0x0a, 0x07, 0x53, 0x75, 0x63, 0x63, 0x65, 0x65,
 	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x53, 0x75, 0x63, 0x63, 0x65, 0x65, 0x64,
 	0x12, 0x14, 0x0a, 0x05, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
-	0x05, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x2a, 0x3b, 0x0a, 0x06, 0x49, 0x50, 0x54, 0x79, 0x70, 0x65,
-	0x12, 0x0d, 0x0a, 0x09, 0x54, 0x79, 0x70, 0x65, 0x56, 0x50, 0x43, 0x49, 0x50, 0x10, 0x00, 0x12,
-	0x0e, 0x0a, 0x0a, 0x54, 0x79, 0x70, 0x65, 0x56, 0x50, 0x43, 0x45, 0x4e, 0x49, 0x10, 0x01, 0x12,
-	0x12, 0x0a, 0x0e, 0x54, 0x79, 0x70, 0x65, 0x45, 0x4e, 0x49, 0x4d, 0x75, 0x6c, 0x74, 0x69, 0x49,
-	0x50, 0x10, 0x02, 0x2a, 0x29, 0x0a, 0x05, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x0c, 0x0a, 0x08,
-	0x45, 0x72, 0x72, 0x4e, 0x6f, 0x45, 0x72, 0x72, 0x10, 0x00, 0x12, 0x12, 0x0a, 0x0e, 0x45, 0x72,
-	0x72, 0x43, 0x52, 0x44, 0x4e, 0x6f, 0x74, 0x46, 0x6f, 0x75, 0x6e, 0x64, 0x10, 0x01, 0x2a, 0x36,
-	0x0a, 0x0b, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x12, 0x13, 0x0a,
-	0x0f, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x4e, 0x6f, 0x64, 0x65,
-	0x10, 0x00, 0x12, 0x12, 0x0a, 0x0e, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x54, 0x61, 0x72, 0x67, 0x65,
-	0x74, 0x50, 0x6f, 0x64, 0x10, 0x01, 0x2a, 0x36, 0x0a, 0x09, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x54,
-	0x79, 0x70, 0x65, 0x12, 0x13, 0x0a, 0x0f, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65,
-	0x4e, 0x6f, 0x72, 0x6d, 0x61, 0x6c, 0x10, 0x00, 0x12, 0x14, 0x0a, 0x10, 0x45, 0x76, 0x65, 0x6e,
-	0x74, 0x54, 0x79, 0x70, 0x65, 0x57, 0x61, 0x72, 0x6e, 0x69, 0x6e, 0x67, 0x10, 0x01, 0x32, 0xeb,
-	0x01, 0x0a, 0x0d, 0x54, 0x65, 0x72, 0x77, 0x61, 0x79, 0x42, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64,
-	0x12, 0x33, 0x0a, 0x07, 0x41, 0x6c, 0x6c, 0x6f, 0x63, 0x49, 0x50, 0x12, 0x13, 0x2e, 0x72, 0x70,
-	0x63, 0x2e, 0x41, 0x6c, 0x6c, 0x6f, 0x63, 0x49, 0x50, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
-	0x1a, 0x11, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x41, 0x6c, 0x6c, 0x6f, 0x63, 0x49, 0x50, 0x52, 0x65,
-	0x70, 0x6c, 0x79, 0x22, 0x00, 0x12, 0x39, 0x0a, 0x09, 0x52, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65,
-	0x49, 0x50, 0x12, 0x15, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x52, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65,
-	0x49, 0x50, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x13, 0x2e, 0x72, 0x70, 0x63, 0x2e,
-	0x52, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x49, 0x50, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00,
-	0x12, 0x35, 0x0a, 0x09, 0x47, 0x65, 0x74, 0x49, 0x50, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x13, 0x2e,
-	0x72, 0x70, 0x63, 0x2e, 0x47, 0x65, 0x74, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x71, 0x75, 0x65,
-	0x73, 0x74, 0x1a, 0x11, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x47, 0x65, 0x74, 0x49, 0x6e, 0x66, 0x6f,
-	0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x12, 0x33, 0x0a, 0x0b, 0x52, 0x65, 0x63, 0x6f, 0x72,
-	0x64, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x11, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x45, 0x76, 0x65,
-	0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0f, 0x2e, 0x72, 0x70, 0x63, 0x2e,
-	0x45, 0x76, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x42, 0x08, 0x5a, 0x06,
-	0x2e, 0x2f, 0x3b, 0x72, 0x70, 0x63, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x05, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x22, 0x36, 0x0a, 0x12, 0x57, 0x61, 0x74, 0x63, 0x68, 0x45,
+	0x76, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x20, 0x0a, 0x0b,
+	0x52, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0b, 0x52, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x22, 0x6f,
+	0x0a, 0x0f, 0x50, 0x6f, 0x64, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72,
+	0x6b, 0x12, 0x16, 0x0a, 0x06, 0x49, 0x66, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x49, 0x66, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x20, 0x0a, 0x05, 0x50, 0x6f, 0x64,
+	0x49, 0x50, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0a, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x49,
+	0x50, 0x53, 0x65, 0x74, 0x52, 0x05, 0x50, 0x6f, 0x64, 0x49, 0x50, 0x12, 0x10, 0x0a, 0x03, 0x4d,
+	0x41, 0x43, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x4d, 0x41, 0x43, 0x12, 0x10, 0x0a,
+	0x03, 0x56, 0x69, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x03, 0x56, 0x69, 0x64, 0x22,
+	0xed, 0x01, 0x0a, 0x08, 0x50, 0x6f, 0x64, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x25, 0x0a, 0x04,
+	0x54, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x11, 0x2e, 0x72, 0x70, 0x63,
+	0x2e, 0x50, 0x6f, 0x64, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x52, 0x04, 0x54,
+	0x79, 0x70, 0x65, 0x12, 0x20, 0x0a, 0x0b, 0x52, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x54, 0x6f, 0x6b,
+	0x65, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x52, 0x65, 0x73, 0x75, 0x6d, 0x65,
+	0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x12, 0x1e, 0x0a, 0x0a, 0x4b, 0x38, 0x73, 0x50, 0x6f, 0x64, 0x4e,
+	0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x4b, 0x38, 0x73, 0x50, 0x6f,
+	0x64, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x28, 0x0a, 0x0f, 0x4b, 0x38, 0x73, 0x50, 0x6f, 0x64, 0x4e,
+	0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0f,
+	0x4b, 0x38, 0x73, 0x50, 0x6f, 0x64, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x12,
+	0x30, 0x0a, 0x08, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x14, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x50, 0x6f, 0x64, 0x45, 0x76, 0x65, 0x6e, 0x74,
+	0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x52, 0x08, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b,
+	0x73, 0x12, 0x1c, 0x0a, 0x09, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x06,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x2a,
+	0x3b, 0x0a, 0x06, 0x49, 0x50, 0x54, 0x79, 0x70, 0x65, 0x12, 0x0d, 0x0a, 0x09, 0x54, 0x79, 0x70,
+	0x65, 0x56, 0x50, 0x43, 0x49, 0x50, 0x10, 0x00, 0x12, 0x0e, 0x0a, 0x0a, 0x54, 0x79, 0x70, 0x65,
+	0x56, 0x50, 0x43, 0x45, 0x4e, 0x49, 0x10, 0x01, 0x12, 0x12, 0x0a, 0x0e, 0x54, 0x79, 0x70, 0x65,
+	0x45, 0x4e, 0x49, 0x4d, 0x75, 0x6c, 0x74, 0x69, 0x49, 0x50, 0x10, 0x02, 0x2a, 0x29, 0x0a, 0x05,
+	0x45, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x0c, 0x0a, 0x08, 0x45, 0x72, 0x72, 0x4e, 0x6f, 0x45, 0x72,
+	0x72, 0x10, 0x00, 0x12, 0x12, 0x0a, 0x0e, 0x45, 0x72, 0x72, 0x43, 0x52, 0x44, 0x4e, 0x6f, 0x74,
+	0x46, 0x6f, 0x75, 0x6e, 0x64, 0x10, 0x01, 0x2a, 0x36, 0x0a, 0x0b, 0x45, 0x76, 0x65, 0x6e, 0x74,
+	0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x12, 0x13, 0x0a, 0x0f, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x54,
+	0x61, 0x72, 0x67, 0x65, 0x74, 0x4e, 0x6f, 0x64, 0x65, 0x10, 0x00, 0x12, 0x12, 0x0a, 0x0e, 0x45,
+	0x76, 0x65, 0x6e, 0x74, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x50, 0x6f, 0x64, 0x10, 0x01, 0x2a,
+	0x36, 0x0a, 0x09, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x12, 0x13, 0x0a, 0x0f,
+	0x45, 0x76, 0x65, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x4e, 0x6f, 0x72, 0x6d, 0x61, 0x6c, 0x10,
+	0x00, 0x12, 0x14, 0x0a, 0x10, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x57, 0x61,
+	0x72, 0x6e, 0x69, 0x6e, 0x67, 0x10, 0x01, 0x2a, 0x49, 0x0a, 0x0c, 0x50, 0x6f, 0x64, 0x45, 0x76,
+	0x65, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x12, 0x14, 0x0a, 0x10, 0x50, 0x6f, 0x64, 0x45, 0x76,
+	0x65, 0x6e, 0x74, 0x41, 0x6c, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x65, 0x10, 0x00, 0x12, 0x13, 0x0a,
+	0x0f, 0x50, 0x6f, 0x64, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65,
+	0x10, 0x01, 0x12, 0x0e, 0x0a, 0x0a, 0x50, 0x6f, 0x64, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x47, 0x43,
+	0x10, 0x02, 0x32, 0xa6, 0x02, 0x0a, 0x0d, 0x54, 0x65, 0x72, 0x77, 0x61, 0x79, 0x42, 0x61, 0x63,
+	0x6b, 0x65, 0x6e, 0x64, 0x12, 0x33, 0x0a, 0x07, 0x41, 0x6c, 0x6c, 0x6f, 0x63, 0x49, 0x50, 0x12,
+	0x13, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x41, 0x6c, 0x6c, 0x6f, 0x63, 0x49, 0x50, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x11, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x41, 0x6c, 0x6c, 0x6f, 0x63,
+	0x49, 0x50, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x12, 0x39, 0x0a, 0x09, 0x52, 0x65, 0x6c,
+	0x65, 0x61, 0x73, 0x65, 0x49, 0x50, 0x12, 0x15, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x52, 0x65, 0x6c,
+	0x65, 0x61, 0x73, 0x65, 0x49, 0x50, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x13, 0x2e,
+	0x72, 0x70, 0x63, 0x2e, 0x52, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x49, 0x50, 0x52, 0x65, 0x70,
+	0x6c, 0x79, 0x22, 0x00, 0x12, 0x35, 0x0a, 0x09, 0x47, 0x65, 0x74, 0x49, 0x50, 0x49, 0x6e, 0x66,
+	0x6f, 0x12, 0x13, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x47, 0x65, 0x74, 0x49, 0x6e, 0x66, 0x6f, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x11, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x47, 0x65, 0x74,
+	0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x12, 0x33, 0x0a, 0x0b, 0x52,
+	0x65, 0x63, 0x6f, 0x72, 0x64, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x11, 0x2e, 0x72, 0x70, 0x63,
+	0x2e, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0f, 0x2e,
+	0x72, 0x70, 0x63, 0x2e, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00,
+	0x12, 0x39, 0x0a, 0x0b, 0x57, 0x61, 0x74, 0x63, 0x68, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x12,
+	0x17, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x45, 0x76, 0x65, 0x6e, 0x74,
+	0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0d, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x50,
+	0x6f, 0x64, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x22, 0x00, 0x30, 0x01, 0x42, 0x08, 0x5a, 0x06, 0x2e,
+	0x2f, 0x3b, 0x72, 0x70, 0x63, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -1408,61 +1697,70 @@ func file_rpc_proto_rawDescGZIP() []byte {
 	return file_rpc_proto_rawDescData
 }
 
-var file_rpc_proto_enumTypes = make([]protoimpl.EnumInfo, 4)
-var file_rpc_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
+var file_rpc_proto_enumTypes = make([]protoimpl.EnumInfo, 5)
+var file_rpc_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
 var file_rpc_proto_goTypes = []interface{}{
-	(IPType)(0),              // 0: rpc.IPType
-	(Error)(0),               // 1: rpc.Error
-	(EventTarget)(0),         // 2: rpc.EventTarget
-	(EventType)(0),           // 3: rpc.EventType
-	(*IPSet)(nil),            // 4: rpc.IPSet
-	(*AllocIPRequest)(nil),   // 5: rpc.AllocIPRequest
-	(*NetConf)(nil),          // 6: rpc.NetConf
-	(*AllocIPReply)(nil),     // 7: rpc.AllocIPReply
-	(*BasicInfo)(nil),        // 8: rpc.BasicInfo
-	(*ENIInfo)(nil),          // 9: rpc.ENIInfo
-	(*Route)(nil),            // 10: rpc.Route
-	(*Pod)(nil),              // 11: rpc.Pod
-	(*ReleaseIPRequest)(nil), // 12: rpc.ReleaseIPRequest
-	(*ReleaseIPReply)(nil),   // 13: rpc.ReleaseIPReply
-	(*GetInfoRequest)(nil),   // 14: rpc.GetInfoRequest
-	(*GetInfoReply)(nil),     // 15: rpc.GetInfoReply
-	(*EventRequest)(nil),     // 16: rpc.EventRequest
-	(*EventReply)(nil),       // 17: rpc.EventReply
+	(IPType)(0),                // 0: rpc.IPType
+	(Error)(0),                 // 1: rpc.Error
+	(EventTarget)(0),           // 2: rpc.EventTarget
+	(EventType)(0),             // 3: rpc.EventType
+	(PodEventType)(0),          // 4: rpc.PodEventType
+	(*IPSet)(nil),              // 5: rpc.IPSet
+	(*AllocIPRequest)(nil),     // 6: rpc.AllocIPRequest
+	(*NetConf)(nil),            // 7: rpc.NetConf
+	(*AllocIPReply)(nil),       // 8: rpc.AllocIPReply
+	(*BasicInfo)(nil),          // 9: rpc.BasicInfo
+	(*ENIInfo)(nil),            // 10: rpc.ENIInfo
+	(*Route)(nil),              // 11: rpc.Route
+	(*Pod)(nil),                // 12: rpc.Pod
+	(*ReleaseIPRequest)(nil),   // 13: rpc.ReleaseIPRequest
+	(*ReleaseIPReply)(nil),     // 14: rpc.ReleaseIPReply
+	(*GetInfoRequest)(nil),     // 15: rpc.GetInfoRequest
+	(*GetInfoReply)(nil),       // 16: rpc.GetInfoReply
+	(*EventRequest)(nil),       // 17: rpc.EventRequest
+	(*EventReply)(nil),         // 18: rpc.EventReply
+	(*WatchEventsRequest)(nil), // 19: rpc.WatchEventsRequest
+	(*PodEventNetwork)(nil),    // 20: rpc.PodEventNetwork
+	(*PodEvent)(nil),           // 21: rpc.PodEvent
 }
 var file_rpc_proto_depIdxs = []int32{
-	8,  // 0: rpc.NetConf.BasicInfo:type_name -> rpc.BasicInfo
-	9,  // 1: rpc.NetConf.ENIInfo:type_name -> rpc.ENIInfo
-	11, // 2: rpc.NetConf.Pod:type_name -> rpc.Pod
-	10, // 3: rpc.NetConf.ExtraRoutes:type_name -> rpc.Route
+	9,  // 0: rpc.NetConf.BasicInfo:type_name -> rpc.BasicInfo
+	10, // 1: rpc.NetConf.ENIInfo:type_name -> rpc.ENIInfo
+	12, // 2: rpc.NetConf.Pod:type_name -> rpc.Pod
+	11, // 3: rpc.NetConf.ExtraRoutes:type_name -> rpc.Route
 	0,  // 4: rpc.AllocIPReply.IPType:type_name -> rpc.IPType
-	6,  // 5: rpc.AllocIPReply.NetConfs:type_name -> rpc.NetConf
-	4,  // 6: rpc.BasicInfo.PodIP:type_name -> rpc.IPSet
-	4,  // 7: rpc.BasicInfo.PodCIDR:type_name -> rpc.IPSet
-	4,  // 8: rpc.BasicInfo.GatewayIP:type_name -> rpc.IPSet
-	4,  // 9: rpc.BasicInfo.ServiceCIDR:type_name -> rpc.IPSet
-	4,  // 10: rpc.ENIInfo.GatewayIP:type_name -> rpc.IPSet
+	7,  // 5: rpc.AllocIPReply.NetConfs:type_name -> rpc.NetConf
+	5,  // 6: rpc.BasicInfo.PodIP:type_name -> rpc.IPSet
+	5,  // 7: rpc.BasicInfo.PodCIDR:type_name -> rpc.IPSet
+	5,  // 8: rpc.BasicInfo.GatewayIP:type_name -> rpc.IPSet
+	5,  // 9: rpc.BasicInfo.ServiceCIDR:type_name -> rpc.IPSet
+	5,  // 10: rpc.ENIInfo.GatewayIP:type_name -> rpc.IPSet
 	0,  // 11: rpc.ReleaseIPRequest.IPType:type_name -> rpc.IPType
-	4,  // 12: rpc.ReleaseIPRequest.IPv4Addr:type_name -> rpc.IPSet
-	4,  // 13: rpc.ReleaseIPReply.IPv4Addr:type_name -> rpc.IPSet
+	5,  // 12: rpc.ReleaseIPRequest.IPv4Addr:type_name -> rpc.IPSet
+	5,  // 13: rpc.ReleaseIPReply.IPv4Addr:type_name -> rpc.IPSet
 	0,  // 14: rpc.GetInfoReply.IPType:type_name -> rpc.IPType
-	6,  // 15: rpc.GetInfoReply.NetConfs:type_name -> rpc.NetConf
+	7,  // 15: rpc.GetInfoReply.NetConfs:type_name -> rpc.NetConf
 	1,  // 16: rpc.GetInfoReply.Error:type_name -> rpc.Error
 	2,  // 17: rpc.EventRequest.EventTarget:type_name -> rpc.EventTarget
 	3,  // 18: rpc.EventRequest.EventType:type_name -> rpc.EventType
-	5,  // 19: rpc.TerwayBackend.AllocIP:input_type -> rpc.AllocIPRequest
-	12, // 20: rpc.TerwayBackend.ReleaseIP:input_type -> rpc.ReleaseIPRequest
-	14, // 21: rpc.TerwayBackend.GetIPInfo:input_type -> rpc.GetInfoRequest
-	16, // 22: rpc.TerwayBackend.RecordEvent:input_type -> rpc.EventRequest
-	7,  // 23: rpc.TerwayBackend.AllocIP:output_type -> rpc.AllocIPReply
-	13, // 24: rpc.TerwayBackend.ReleaseIP:output_type -> rpc.ReleaseIPReply
-	15, // 25: rpc.TerwayBackend.GetIPInfo:output_type -> rpc.GetInfoReply
-	17, // 26: rpc.TerwayBackend.RecordEvent:output_type -> rpc.EventReply
-	23, // [23:27] is the sub-list for method output_type
-	19, // [19:23] is the sub-list for method input_type
-	19, // [19:19] is the sub-list for extension type_name
-	19, // [19:19] is the sub-list for extension extendee
-	0,  // [0:19] is the sub-list for field type_name
+	5,  // 19: rpc.PodEventNetwork.PodIP:type_name -> rpc.IPSet
+	4,  // 20: rpc.PodEvent.Type:type_name -> rpc.PodEventType
+	20, // 21: rpc.PodEvent.Networks:type_name -> rpc.PodEventNetwork
+	6,  // 22: rpc.TerwayBackend.AllocIP:input_type -> rpc.AllocIPRequest
+	13, // 23: rpc.TerwayBackend.ReleaseIP:input_type -> rpc.ReleaseIPRequest
+	15, // 24: rpc.TerwayBackend.GetIPInfo:input_type -> rpc.GetInfoRequest
+	17, // 25: rpc.TerwayBackend.RecordEvent:input_type -> rpc.EventRequest
+	19, // 26: rpc.TerwayBackend.WatchEvents:input_type -> rpc.WatchEventsRequest
+	8,  // 27: rpc.TerwayBackend.AllocIP:output_type -> rpc.AllocIPReply
+	14, // 28: rpc.TerwayBackend.ReleaseIP:output_type -> rpc.ReleaseIPReply
+	16, // 29: rpc.TerwayBackend.GetIPInfo:output_type -> rpc.GetInfoReply
+	18, // 30: rpc.TerwayBackend.RecordEvent:output_type -> rpc.EventReply
+	21, // 31: rpc.TerwayBackend.WatchEvents:output_type -> rpc.PodEvent
+	27, // [27:32] is the sub-list for method output_type
+	22, // [22:27] is the sub-list for method input_type
+	22, // [22:22] is the sub-list for extension type_name
+	22, // [22:22] is the sub-list for extension extendee
+	0,  // [0:22] is the sub-list for field type_name
 }
 
 func init() { file_rpc_proto_init() }
@@ -1639,14 +1937,50 @@ func file_rpc_proto_init() {
 				return nil
 			}
 		}
+		file_rpc_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WatchEventsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rpc_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PodEventNetwork); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rpc_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PodEvent); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_rpc_proto_rawDesc,
-			NumEnums:      4,
-			NumMessages:   14,
+			NumEnums:      5,
+			NumMessages:   17,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -23,6 +23,7 @@ const (
 	TerwayBackend_ReleaseIP_FullMethodName   = "/rpc.TerwayBackend/ReleaseIP"
 	TerwayBackend_GetIPInfo_FullMethodName   = "/rpc.TerwayBackend/GetIPInfo"
 	TerwayBackend_RecordEvent_FullMethodName = "/rpc.TerwayBackend/RecordEvent"
+	TerwayBackend_WatchEvents_FullMethodName = "/rpc.TerwayBackend/WatchEvents"
 )
 
 // TerwayBackendClient is the client API for TerwayBackend service.
@@ -33,6 +34,7 @@ type TerwayBackendClient interface {
 	ReleaseIP(ctx context.Context, in *ReleaseIPRequest, opts ...grpc.CallOption) (*ReleaseIPReply, error)
 	GetIPInfo(ctx context.Context, in *GetInfoRequest, opts ...grpc.CallOption) (*GetInfoReply, error)
 	RecordEvent(ctx context.Context, in *EventRequest, opts ...grpc.CallOption) (*EventReply, error)
+	WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (TerwayBackend_WatchEventsClient, error)
 }
 
 type terwayBackendClient struct {
@@ -79,6 +81,38 @@ func (c *terwayBackendClient) RecordEvent(ctx context.Context, in *EventRequest,
 	return out, nil
 }
 
+func (c *terwayBackendClient) WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (TerwayBackend_WatchEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &TerwayBackend_ServiceDesc.Streams[0], TerwayBackend_WatchEvents_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &terwayBackendWatchEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type TerwayBackend_WatchEventsClient interface {
+	Recv() (*PodEvent, error)
+	grpc.ClientStream
+}
+
+type terwayBackendWatchEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *terwayBackendWatchEventsClient) Recv() (*PodEvent, error) {
+	m := new(PodEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // TerwayBackendServer is the server API for TerwayBackend service.
 // All implementations must embed UnimplementedTerwayBackendServer
 // for forward compatibility
@@ -87,6 +121,7 @@ type TerwayBackendServer interface {
 	ReleaseIP(context.Context, *ReleaseIPRequest) (*ReleaseIPReply, error)
 	GetIPInfo(context.Context, *GetInfoRequest) (*GetInfoReply, error)
 	RecordEvent(context.Context, *EventRequest) (*EventReply, error)
+	WatchEvents(*WatchEventsRequest, TerwayBackend_WatchEventsServer) error
 	mustEmbedUnimplementedTerwayBackendServer()
 }
 
@@ -106,6 +141,9 @@ func (UnimplementedTerwayBackendServer) GetIPInfo(context.Context, *GetInfoReque
 func (UnimplementedTerwayBackendServer) RecordEvent(context.Context, *EventRequest) (*EventReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RecordEvent not implemented")
 }
+func (UnimplementedTerwayBackendServer) WatchEvents(*WatchEventsRequest, TerwayBackend_WatchEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchEvents not implemented")
+}
 func (UnimplementedTerwayBackendServer) mustEmbedUnimplementedTerwayBackendServer() {}
 
 // UnsafeTerwayBackendServer may be embedded to opt out of forward compatibility for this service.
@@ -191,6 +229,27 @@ func _TerwayBackend_RecordEvent_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _TerwayBackend_WatchEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TerwayBackendServer).WatchEvents(m, &terwayBackendWatchEventsServer{stream})
+}
+
+type TerwayBackend_WatchEventsServer interface {
+	Send(*PodEvent) error
+	grpc.ServerStream
+}
+
+type terwayBackendWatchEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *terwayBackendWatchEventsServer) Send(m *PodEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // TerwayBackend_ServiceDesc is the grpc.ServiceDesc for TerwayBackend service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -215,6 +274,12 @@ var TerwayBackend_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _TerwayBackend_RecordEvent_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchEvents",
+			Handler:       _TerwayBackend_WatchEvents_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "rpc.proto",
 }
@@ -11,6 +11,10 @@ const (
 
 	// TagTerwayController terway controller
 	TagTerwayController = "terway-controller"
+	// TagTerwayIPReservation the eni holding the ips of IPReservation
+	TagTerwayIPReservation = "terway-ip-reservation"
+	// TagIPReservation the IPReservation the eni belong to
+	TagIPReservation = "ip-reservation"
 
 	TagENIAllocPolicy = "eni-alloc-policy"
 
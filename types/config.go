@@ -1,6 +1,8 @@
 package types
 
 import (
+	"net/netip"
+
 	"github.com/AliyunContainerService/terway/pkg/vswitch"
 )
 
@@ -22,6 +24,9 @@ type ENIConfig struct {
 	VSwitchSelectionPolicy vswitch.SelectionPolicy
 	EniSelectionPolicy     EniSelectionPolicy
 
+	VSwitchWeights     map[string]int
+	VSwitchPreferCIDRs []netip.Prefix
+
 	ResourceGroupID string
 
 	EniTypeAttr Feat
@@ -42,6 +47,21 @@ type PoolConfig struct {
 	MaxIPPerENI   int
 	BatchSize     int
 
+	IPv4PrefixPerENI int // the max ipv4 prefix per eni, 0 for prefix disabled
+
+	MaxPoolSize int
+	MinPoolSize int
+
+	WarmENITarget   int // the eni count keep attached and ready, without secondary ip assigned
+	MinimumIPTarget int // the min ip count (idle and in use) the pool hold
+
+	SecurityGroupPools []SecurityGroupPoolConfig // the pools of the eni in other security groups
+}
+
+// SecurityGroupPoolConfig the idle ip targets of the eni pool in the security groups
+type SecurityGroupPoolConfig struct {
+	SecurityGroupIDs []string
+
 	MaxPoolSize int
 	MinPoolSize int
 }
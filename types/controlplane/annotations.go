@@ -93,6 +93,9 @@ func ParseAllocationType(old *v1beta1.AllocationType) (*v1beta1.AllocationType,
 			}
 			res.ReleaseAfter = old.ReleaseAfter
 		}
+		if old.ZoneMigration == v1beta1.ZoneMigrationAllowed {
+			res.ZoneMigration = v1beta1.ZoneMigrationAllowed
+		}
 	}
 	return res, nil
 }
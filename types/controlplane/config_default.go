@@ -66,6 +66,20 @@ type Config struct {
 	VSwitchPoolSize int    `json:"vSwitchPoolSize" validate:"gt=0" mod:"default=1000"`
 	VSwitchCacheTTL string `json:"vSwitchCacheTTL" mod:"default=20m0s"`
 
+	// PodNetworkingSyncPeriod the period the vSwitch capacity in PodNetworking status is refreshed,
+	// the allocation failures in the AllocationFailureWindow are counted
+	PodNetworkingSyncPeriod string `json:"podNetworkingSyncPeriod" mod:"default=1m0s"`
+	AllocationFailureWindow string `json:"allocationFailureWindow" mod:"default=10m0s"`
+
+	// vSwitch capacity forecast, the vSwitch is exhausting if it runs out of ip within the threshold
+	VSwitchForecastInterval string `json:"vSwitchForecastInterval" mod:"default=5m0s"`
+	VSwitchForecastWindow   string `json:"vSwitchForecastWindow" mod:"default=6h0m0s"`
+	VSwitchExhaustThreshold string `json:"vSwitchExhaustThreshold" mod:"default=24h0m0s"`
+	// VSwitchReserveTags the tags of the spare vSwitches in the vpc. One in the same zone is appended to the
+	// PodNetworking and the eni-config using the exhausting vSwitch if VSwitchAutoExpand is enabled
+	VSwitchReserveTags map[string]string `json:"vSwitchReserveTags"`
+	VSwitchAutoExpand  bool              `json:"vSwitchAutoExpand"`
+
 	CustomStatefulWorkloadKinds []string `json:"customStatefulWorkloadKinds"`
 
 	BackoffOverride map[string]wait.Backoff `json:"backoffOverride,omitempty"`
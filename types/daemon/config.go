@@ -2,8 +2,10 @@ package daemon
 
 import (
 	"fmt"
+	"net/netip"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/AliyunContainerService/terway/types/secret"
 
@@ -65,6 +67,29 @@ type Config struct {
 	KubeClientQPS               float32                 `json:"kube_client_qps"`
 	KubeClientBurst             int                     `json:"kube_client_burst"`
 	ResourceGroupID             string                  `json:"resource_group_id"`
+	EnableIPv4Prefix            bool                    `json:"enable_ipv4_prefix"` // assign /28 ipv4 prefix to eni instead of secondary ip
+	WarmENITarget               int                     `json:"warm_eni_target"`    // eni count keep attached without pod on it
+	WarmIPTarget                int                     `json:"warm_ip_target"`     // idle ip count keep in the pool
+	MinimumIPTarget             int                     `json:"minimum_ip_target"`  // min ip count (idle and in use) hold by the node
+	EnablePodPrewarm            bool                    `json:"enable_pod_prewarm"` // allocate ip ahead for the pending pods on this node
+	DatapathReconcile           string                  `json:"datapath_reconcile"` // enforce, dry-run or metrics, empty for disabled
+	IPStickTime                 string                  `json:"ip_stick_time"`      // time the ip of the stateful pod is held after the pod is deleted, e.g. 10m
+	OTLPEndpoint                string                  `json:"otlp_endpoint"`      // otlp grpc endpoint (host:port) the allocation spans are exported to, empty for disabled
+	// eni pools for the pods use other security groups
+	SecurityGroupPools []SecurityGroupPool `json:"security_group_pools,omitempty"`
+	// weight of the vSwitch for the weighted vswitch_selection_policy, default is 1
+	VSwitchWeights map[string]int `json:"vswitch_weights,omitempty"`
+	// cidrs the vSwitch inside is picked first by the prefer-cidr vswitch_selection_policy
+	VSwitchPreferCIDRs []string `json:"vswitch_prefer_cidrs,omitempty"`
+	// apply the bandwidth and priority annotation changes to the running pods, disabled by default
+	EnablePodQoSUpdate bool `json:"enable_pod_qos_update"`
+}
+
+// SecurityGroupPool the idle ip targets of the eni pool in the security groups
+type SecurityGroupPool struct {
+	SecurityGroupIDs []string `json:"security_group_ids"`
+	MaxPoolSize      int      `json:"max_pool_size"`
+	MinPoolSize      int      `json:"min_pool_size"`
 }
 
 func (c *Config) GetSecurityGroups() []string {
@@ -92,6 +117,18 @@ func (c *Config) GetExtraRoutes() []string {
 	return vsws
 }
 
+// GetIPStickTime return the time the ip of the stateful pod is held, DefaultIPStickTime if not set
+func (c *Config) GetIPStickTime() time.Duration {
+	if c.IPStickTime == "" {
+		return DefaultIPStickTime
+	}
+	d, err := time.ParseDuration(c.IPStickTime)
+	if err != nil || d < 0 {
+		return DefaultIPStickTime
+	}
+	return d
+}
+
 func (c *Config) Populate() {
 	if c.EniCapRatio == 0 {
 		c.EniCapRatio = 1
@@ -113,6 +150,31 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("unsupported ipStack %s in configMap", c.IPStack)
 	}
 
+	switch c.DatapathReconcile {
+	case "", DatapathReconcileEnforce, DatapathReconcileDryRun, DatapathReconcileMetrics:
+	default:
+		return fmt.Errorf("unsupported datapath_reconcile %s in configMap", c.DatapathReconcile)
+	}
+
+	if c.IPStickTime != "" {
+		d, err := time.ParseDuration(c.IPStickTime)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid ip_stick_time %s in configMap", c.IPStickTime)
+		}
+	}
+
+	for id, w := range c.VSwitchWeights {
+		if w < 0 {
+			return fmt.Errorf("invalid weight %d of vSwitch %s in configMap", w, id)
+		}
+	}
+	for _, cidr := range c.VSwitchPreferCIDRs {
+		_, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return fmt.Errorf("invalid vswitch_prefer_cidrs %s in configMap", cidr)
+		}
+	}
+
 	if len(c.SecurityGroups) > 5 {
 		return fmt.Errorf("security groups should not be more than 5, current %d", len(c.SecurityGroups))
 	}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -105,3 +106,44 @@ func TestGetAddonSecret(t *testing.T) {
 	assert.Equal(t, "key", ak)
 	assert.Equal(t, "secret", sk)
 }
+
+func TestConfigValidateDatapathReconcile(t *testing.T) {
+	for _, mode := range []string{"", DatapathReconcileEnforce, DatapathReconcileDryRun, DatapathReconcileMetrics} {
+		cfg := &Config{DatapathReconcile: mode}
+		assert.NoError(t, cfg.Validate(), mode)
+	}
+
+	cfg := &Config{DatapathReconcile: "foo"}
+	assert.Error(t, cfg.Validate())
+}
+
+func TestConfigIPStickTime(t *testing.T) {
+	cfg := &Config{}
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, DefaultIPStickTime, cfg.GetIPStickTime())
+
+	cfg = &Config{IPStickTime: "10m"}
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, 10*time.Minute, cfg.GetIPStickTime())
+
+	cfg = &Config{IPStickTime: "0s"}
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, time.Duration(0), cfg.GetIPStickTime())
+
+	cfg = &Config{IPStickTime: "foo"}
+	assert.Error(t, cfg.Validate())
+}
+
+func TestConfigValidateVSwitchSelection(t *testing.T) {
+	cfg := &Config{
+		VSwitchWeights:     map[string]int{"vsw-1": 3, "vsw-2": 0},
+		VSwitchPreferCIDRs: []string{"192.168.0.0/16"},
+	}
+	assert.NoError(t, cfg.Validate())
+
+	cfg = &Config{VSwitchWeights: map[string]int{"vsw-1": -1}}
+	assert.Error(t, cfg.Validate())
+
+	cfg = &Config{VSwitchPreferCIDRs: []string{"192.168.0.0"}}
+	assert.Error(t, cfg.Validate())
+}
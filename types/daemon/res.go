@@ -1,7 +1,10 @@
 package daemon
 
 import (
+	"fmt"
 	"net"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/AliyunContainerService/terway/types"
@@ -33,12 +36,51 @@ type PodInfo struct {
 	PodIP           string      // used for eip and mip
 	PodIPs          types.IPSet // used for eip and mip
 	SandboxExited   bool
+	SandboxPending  bool       // pod is scheduled but the sandbox is not created yet
 	EipInfo         PodEipInfo // deprecated
 	IPStickTime     time.Duration
+	IPReleaseAt     time.Time // the deadline the ip held for the deleted pod is released, zero if not scheduled
 	PodENI          bool
 	PodUID          string
 	NetworkPriority string
 	ERdma           bool
+	HostPorts       []HostPortMapping
+	PodNetworks     []PodNetwork // the networks the pod attached, parsed from the pod-networks annotation
+	PodNetworking   string       // the podNetworking the pod matched
+	SecurityGroups  []string     // the security groups of the shared eni the pod ip allocated from
+	StatefulSet     string       // the StatefulSet owns the pod
+}
+
+// PodNetwork is a network the pod attached, the interface is created in the vSwitches and security groups
+type PodNetwork struct {
+	Interface        string   `json:"interface"`
+	VSwitchOptions   []string `json:"vSwitchOptions"`
+	SecurityGroupIDs []string `json:"securityGroupIDs"`
+	ExtraRoutes      []string `json:"extraRoutes"`
+}
+
+// HostPortMapping the host port declared in the pod spec
+type HostPortMapping struct {
+	HostPort      int32  `json:"host_port"`
+	ContainerPort int32  `json:"container_port"`
+	Protocol      string `json:"protocol"`
+	HostIP        string `json:"host_ip"`
+}
+
+func (h HostPortMapping) String() string {
+	return fmt.Sprintf("%s/%s->%d", strings.ToLower(h.Protocol), net.JoinHostPort(h.HostIP, strconv.Itoa(int(h.HostPort))), h.ContainerPort)
+}
+
+// IPHoldRemaining return the time left before the ip held for the deleted pod is released
+func IPHoldRemaining(releaseAt, now time.Time) string {
+	if releaseAt.IsZero() {
+		return ""
+	}
+	d := releaseAt.Sub(now).Round(time.Second)
+	if d <= 0 {
+		return "expired"
+	}
+	return d.String()
 }
 
 // ExtraEipInfo store extra eip info
@@ -59,6 +101,13 @@ type ResourceItem struct {
 	ENIMAC string `json:"eni_mac"`
 	IPv4   string `json:"ipv4"`
 	IPv6   string `json:"ipv6"`
+
+	// IfName the interface in the pod the ip is used, empty for the default interface
+	IfName string `json:"if_name,omitempty"`
+	// Reserved the ipv4 is reserved by IPReservation and given back once released
+	Reserved bool `json:"reserved,omitempty"`
+	// ReservedHolder the eni the reserved ipv4 is given back to
+	ReservedHolder string `json:"reserved_holder,omitempty"`
 }
 
 // PodResources pod resources related
@@ -76,7 +125,7 @@ func (p PodResources) GetResourceItemByType(resType string) []ResourceItem {
 	var ret []ResourceItem
 	for _, r := range p.Resources {
 		if resType == r.Type {
-			ret = append(ret, ResourceItem{Type: resType, ID: r.ID, ENIID: r.ENIID, ENIMAC: r.ENIMAC, IPv4: r.IPv4, IPv6: r.IPv6})
+			ret = append(ret, ResourceItem{Type: resType, ID: r.ID, ENIID: r.ENIID, ENIMAC: r.ENIMAC, IPv4: r.IPv4, IPv6: r.IPv6, IfName: r.IfName, Reserved: r.Reserved, ReservedHolder: r.ReservedHolder})
 		}
 	}
 	return ret
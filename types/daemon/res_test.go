@@ -0,0 +1,20 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostPortMappingString(t *testing.T) {
+	assert.Equal(t, "tcp/:8080->80", HostPortMapping{HostPort: 8080, ContainerPort: 80, Protocol: "TCP"}.String())
+	assert.Equal(t, "udp/[fd00::1]:53->5353", HostPortMapping{HostPort: 53, ContainerPort: 5353, Protocol: "UDP", HostIP: "fd00::1"}.String())
+}
+
+func TestIPHoldRemaining(t *testing.T) {
+	now := time.Now()
+	assert.Equal(t, "", IPHoldRemaining(time.Time{}, now))
+	assert.Equal(t, "4m30s", IPHoldRemaining(now.Add(4*time.Minute+30*time.Second+100*time.Millisecond), now))
+	assert.Equal(t, "expired", IPHoldRemaining(now.Add(-time.Second), now))
+}
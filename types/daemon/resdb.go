@@ -0,0 +1,60 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/AliyunContainerService/terway/pkg/storage"
+)
+
+const (
+	ResDBPath = "/var/lib/cni/terway/ResRelation.db"
+	ResDBName = "relation"
+)
+
+// ResDBSchema is the schema of the pod resource db.
+// Register a new migration if PodResources or ResourceItem is changed in an incompatible way.
+var ResDBSchema = storage.NewSchema().
+	Register(1, "normalize pod resources", migratePodResourcesV1)
+
+// NewResDB open the pod resource db, the db is migrated to the latest schema
+func NewResDB(path string) (storage.Storage, error) {
+	return storage.NewDiskStorageWithSchema(ResDBName, path, ResDBSchema, SerializePodResources, DeserializePodResources)
+}
+
+// OpenResDB open the pod resource db with the options, the db opened read only is migrated in memory only
+func OpenResDB(path string, options *bolt.Options) (storage.Storage, error) {
+	return storage.NewDiskStorageWithOptions(ResDBName, path, ResDBSchema, options, SerializePodResources, DeserializePodResources)
+}
+
+// SerializePodResources is the storage.Serializer for PodResources
+func SerializePodResources(item interface{}) ([]byte, error) {
+	return json.Marshal(item)
+}
+
+// DeserializePodResources is the storage.Deserializer for PodResources
+func DeserializePodResources(data []byte) (interface{}, error) {
+	resourceRel := &PodResources{}
+	err := json.Unmarshal(data, resourceRel)
+	if err != nil {
+		return nil, err
+	}
+	return *resourceRel, nil
+}
+
+// migratePodResourcesV1 re-encode the legacy record written before the schema is introduced.
+// Record can't be decoded is refused, so the pod to ip mapping is never lost silently.
+// Record without the pod info can't be used by the daemon, it is quarantined.
+func migratePodResourcesV1(key string, value []byte) ([]byte, error) {
+	res := &PodResources{}
+	err := json.Unmarshal(value, res)
+	if err != nil {
+		return nil, err
+	}
+	if res.PodInfo == nil {
+		return nil, fmt.Errorf("%w, pod info is missing for %s", storage.ErrQuarantine, key)
+	}
+	return json.Marshal(res)
+}
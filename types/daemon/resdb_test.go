@@ -0,0 +1,58 @@
+package daemon
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AliyunContainerService/terway/pkg/storage"
+)
+
+func TestNewResDB_QuarantineRecordWithoutPodInfo(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	// the db written before the schema is introduced
+	legacy, err := storage.NewDiskStorage(ResDBName, path, SerializePodResources, DeserializePodResources)
+	require.NoError(t, err)
+	require.NoError(t, legacy.Put("default/pod-1", PodResources{PodInfo: &PodInfo{Namespace: "default", Name: "pod-1"}}))
+	require.NoError(t, legacy.Put("default/pod-2", PodResources{}))
+	require.NoError(t, legacy.(*storage.DiskStorage).Close())
+
+	s, err := NewResDB(path)
+	require.NoError(t, err)
+	defer s.(*storage.DiskStorage).Close()
+
+	list, err := s.List()
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "pod-1", list[0].(PodResources).PodInfo.Name)
+
+	quarantined, err := s.(*storage.DiskStorage).Bucket(storage.QuarantineBucket(ResDBName), json.Marshal, func(data []byte) (interface{}, error) {
+		return string(data), nil
+	})
+	require.NoError(t, err)
+	_, err = quarantined.Get("default/pod-2")
+	assert.NoError(t, err)
+}
+
+func TestOpenResDB_ReadOnlySkipRecordWithoutPodInfo(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	legacy, err := storage.NewDiskStorage(ResDBName, path, SerializePodResources, DeserializePodResources)
+	require.NoError(t, err)
+	require.NoError(t, legacy.Put("default/pod-1", PodResources{PodInfo: &PodInfo{Namespace: "default", Name: "pod-1"}}))
+	require.NoError(t, legacy.Put("default/pod-2", PodResources{}))
+	require.NoError(t, legacy.(*storage.DiskStorage).Close())
+
+	s, err := OpenResDB(path, &bolt.Options{ReadOnly: true})
+	require.NoError(t, err)
+	defer s.(*storage.DiskStorage).Close()
+
+	list, err := s.List()
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+}
@@ -2,6 +2,7 @@ package daemon
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/AliyunContainerService/terway/types"
 )
@@ -40,6 +41,19 @@ const (
 	VSwitchSelectionPolicyOrdered = "ordered"
 )
 
+// DatapathReconcile how the daemon handle the drift of the datapath in host netns
+const (
+	// DatapathReconcileEnforce repair the drift
+	DatapathReconcileEnforce = "enforce"
+	// DatapathReconcileDryRun log the drift, nothing is changed
+	DatapathReconcileDryRun = "dry-run"
+	// DatapathReconcileMetrics only report the drift by metrics
+	DatapathReconcileMetrics = "metrics"
+)
+
+// DefaultIPStickTime is the time the ip of the stateful pod is held for a reuse after the pod is deleted
+const DefaultIPStickTime = 5 * time.Minute
+
 // ENICapPolicy how eni cap is calculated
 type ENICapPolicy string
 
@@ -39,9 +39,15 @@ const (
 	// PodIPReservation whether pod's IP will be reserved for a reuse
 	PodIPReservation = AnnotationPrefix + "pod-ip-reservation"
 
+	// PodIPStickTime the time the pod's IP is held for a reuse after the pod is deleted, e.g. 10m
+	PodIPStickTime = AnnotationPrefix + "pod-ip-stick-time"
+
 	// PodNetworks for additional net config
 	PodNetworks = AnnotationPrefix + "pod-networks"
 
+	// PodSecurityGroups the comma separated security groups of the shared eni the pod ip allocated from
+	PodSecurityGroups = AnnotationPrefix + "security-group-ids"
+
 	// PodAllocType for additional net config
 	PodAllocType = AnnotationPrefix + "pod-alloc-type"
 
@@ -66,6 +72,9 @@ const (
 // FinalizerPodENI finalizer for podENI resource
 const FinalizerPodENI = "pod-eni"
 
+// FinalizerIPReservation finalizer for IPReservation resource
+const FinalizerIPReservation = "ip-reservation"
+
 // events for control plane
 const (
 	EventCreateENISucceed = "CreateENISucceed"
@@ -82,6 +91,15 @@ const (
 
 	EventSyncPodNetworkingSucceed = "SyncPodNetworkingSucceed"
 	EventSyncPodNetworkingFailed  = "SyncPodNetworkingFailed"
+
+	EventSyncIPReservationSucceed = "SyncIPReservationSucceed"
+	EventSyncIPReservationFailed  = "SyncIPReservationFailed"
+
+	EventVSwitchExhausting = "VSwitchExhausting"
+	EventVSwitchExpanded   = "VSwitchExpanded"
+
+	EventZoneMigrationSucceed = "ZoneMigrationSucceed"
+	EventZoneMigrationFailed  = "ZoneMigrationFailed"
 )
 
 // PodUseENI whether pod is use podENI cr res